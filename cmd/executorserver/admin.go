@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+	ginzap "github.com/gin-contrib/zap"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// initAdminHTTPServer serves admin endpoints on -admin-addr, they are not
+// registered on the http server in that case
+func initAdminHTTPServer(conf *config.Config, work worker.Worker, envPool pool.Pool, fs filestore.FileStore, reloader *configReloader, bench *benchmark) initFunc {
+	return func() (start func(), cleanUp stopFunc) {
+		if conf.AdminAddr == "" {
+			return nil, nil
+		}
+		srv := &http.Server{
+			Addr:    conf.AdminAddr,
+			Handler: initAdminHTTPMux(conf, work, envPool, fs, reloader, bench),
+		}
+		return func() {
+				serveHTTP("admin", srv, splitAddrs(conf.AdminAddr))
+			}, func(ctx context.Context) error {
+				logger.Sugar().Info("Admin http server shutdown")
+				return srv.Shutdown(ctx)
+			}
+	}
+}
+
+func initAdminHTTPMux(conf *config.Config, work worker.Worker, envPool pool.Pool, fs filestore.FileStore, reloader *configReloader, bench *benchmark) http.Handler {
+	r := gin.New()
+	r.Use(ginzap.Ginzap(logger, "", false))
+	r.Use(ginzap.RecoveryWithZap(logger, true))
+
+	r.GET("/healthz", generateHandleHealth(fs, envPool))
+	if conf.EnableMetrics {
+		r.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	}
+	if conf.EnableDebug {
+		mux := http.NewServeMux()
+		initDebugRoute(mux, work, envPool)
+		r.Any("/debug/*path", gin.WrapH(mux))
+	}
+
+	if len(conf.AuthToken) > 0 || conf.AdminToken != "" {
+		r.Use(tokenAuth(conf.AuthToken, conf.AdminToken))
+	}
+	initAdminRoute(r, conf, work, envPool, fs, reloader, bench)
+	initNoRoute(r)
+	return r
+}
+
+// initAdminRoute registers the endpoints restricted to admin
+func initAdminRoute(r *gin.Engine, conf *config.Config, work worker.Worker, envPool pool.Pool, fs filestore.FileStore, reloader *configReloader, bench *benchmark) {
+	// Reload handle
+	r.POST("/admin/reload", generateHandleReload(reloader))
+
+	// Environment pool rebuild handle
+	r.POST("/admin/envpool/rebuild", generateHandleEnvPoolRebuild(conf, work, envPool))
+	r.DELETE("/admin/env/:id", generateHandleEnvDestroy(envPool))
+
+	// Warmup handle
+	r.POST("/admin/warmup", generateHandleWarmup(conf, work, envPool))
+	r.POST("/admin/preset-check", generateHandlePresetCheck(conf, work, envPool))
+
+	// Running jobs handle
+	r.GET("/admin/jobs", generateHandleJobs(work))
+	r.DELETE("/admin/jobs/:id", generateHandleJobKill(work))
+
+	// Usage handle
+	r.GET("/admin/usage", generateHandleUsage(work))
+
+	// Benchmark handle
+	r.GET("/admin/bench", generateHandleBench(bench))
+
+	// State export handle
+	r.GET("/admin/export", generateHandleExport(reloader.exec))
+
+	// Self test handle
+	r.POST("/selftest", generateHandleSelfTest(conf, work))
+}
+
+// serveHTTP serves on all the addresses until srv is shut down or any of them
+// failed
+func serveHTTP(name string, srv *http.Server, addrs []string) {
+	errCh := make(chan error, len(addrs))
+	for _, addr := range addrs {
+		lis, err := listen(name, addr)
+		if err != nil {
+			logger.Sugar().Errorf("%s server listen at %s failed: %v", name, addr, err)
+			srv.Close()
+			return
+		}
+		logger.Sugar().Infof("Starting %s server at %s with listener %s", name, addr, printListener(lis))
+		go func() {
+			errCh <- srv.Serve(lis)
+		}()
+	}
+	if err := <-errCh; errors.Is(err, http.ErrServerClosed) {
+		logger.Sugar().Infof("%s server stopped: %v", name, err)
+	} else {
+		logger.Sugar().Errorf("%s server stopped: %v", name, err)
+	}
+}
+
+// splitAddrs splits comma separated addresses
+func splitAddrs(s string) []string {
+	var rt []string
+	for _, a := range strings.Split(s, ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			rt = append(rt, a)
+		}
+	}
+	return rt
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/gin-gonic/gin"
+)
+
+func isAdminPath(p string) bool {
+	return p == "/healthz" || p == "/metrics" || p == "/selftest" ||
+		strings.HasPrefix(p, "/admin/") || strings.HasPrefix(p, "/debug/")
+}
+
+func TestAdminRoutes(t *testing.T) {
+	fs := filestore.NewFileLocalStore(t.TempDir())
+	maxRequestSize := envexec.Size(1 << 20)
+	conf := &config.Config{
+		AdminAddr:      "127.0.0.1:0",
+		EnableMetrics:  true,
+		EnableDebug:    true,
+		MaxRequestSize: &maxRequestSize,
+	}
+	reloader := &configReloader{conf: conf}
+	adminRoutes := func(h http.Handler) map[string]bool {
+		rt := make(map[string]bool)
+		for _, r := range h.(*gin.Engine).Routes() {
+			if isAdminPath(r.Path) {
+				rt[r.Method+" "+r.Path] = true
+			}
+		}
+		return rt
+	}
+
+	admin := adminRoutes(initAdminHTTPMux(conf, nil, unavailablePool{}, fs, reloader, nil))
+	for _, r := range []string{"GET /healthz", "GET /metrics", "GET /debug/*path", "POST /admin/reload", "GET /admin/jobs", "POST /selftest"} {
+		if !admin[r] {
+			t.Errorf("%s is not served by the admin listener: %v", r, admin)
+		}
+	}
+	if r := adminRoutes(initHTTPMux(conf, nil, unavailablePool{}, fs, reloader, nil, nil, nil)); len(r) != 0 {
+		t.Errorf("%v are served by the http listener as well", r)
+	}
+
+	// served by the http listener without the admin listener
+	conf.AdminAddr = ""
+	served := adminRoutes(initHTTPMux(conf, nil, unavailablePool{}, fs, reloader, nil, nil, nil))
+	for _, r := range []string{"POST /admin/reload", "GET /admin/jobs", "POST /selftest"} {
+		if !served[r] {
+			t.Errorf("%s is not served without the admin listener: %v", r, served)
+		}
+	}
+}
+
+// freeAddrs returns the local addresses not listened on
+func freeAddrs(t *testing.T, n int) []string {
+	t.Helper()
+	var rt []string
+	for i := 0; i < n; i++ {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		rt = append(rt, lis.Addr().String())
+		lis.Close()
+	}
+	return rt
+}
+
+func TestAdminShutdown(t *testing.T) {
+	addrs := freeAddrs(t, 2)
+	conf := &config.Config{AdminAddr: strings.Join(addrs, ",")}
+	start, stop := initAdminHTTPServer(conf, nil, unavailablePool{}, filestore.NewFileLocalStore(t.TempDir()), &configReloader{conf: conf}, nil)()
+	stopped := make(chan struct{})
+	go func() {
+		start()
+		close(stopped)
+	}()
+
+	get := func(addr string) (*http.Response, error) {
+		c := &http.Client{Timeout: time.Second}
+		return c.Get("http://" + addr + "/healthz")
+	}
+	for _, addr := range addrs {
+		var (
+			resp *http.Response
+			err  error
+		)
+		for i := 0; i < 100; i++ {
+			if resp, err = get(addr); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatalf("%s is not served: %v", addr, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("%s: status %d", addr, resp.StatusCode)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := stop(ctx); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		t.Fatal("admin server is not stopped")
+	}
+	for _, addr := range addrs {
+		if resp, err := get(addr); err == nil {
+			resp.Body.Close()
+			t.Errorf("%s is still served after shutdown", addr)
+		}
+	}
+}
+
+// the admin listener serves none of the other routes
+func TestAdminNoRun(t *testing.T) {
+	conf := &config.Config{AdminAddr: "127.0.0.1:0"}
+	h := initAdminHTTPMux(conf, nil, unavailablePool{}, filestore.NewFileLocalStore(t.TempDir()), &configReloader{conf: conf}, nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/run", strings.NewReader("{}")))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("/run on the admin listener: status %d", w.Code)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/worker"
+)
+
+// auditSocketPrefix prefixes the unix socket address of -audit-log
+const auditSocketPrefix = "unix:"
+
+// auditEntry defines the line written to the audit log for each request. The
+// content of inline files is never written, only the ids of the files in the
+// file store are recorded.
+type auditEntry struct {
+	RequestID  string        `json:"requestId,omitempty"`
+	Client     string        `json:"client"` // file store namespace of the auth token
+	StartedAt  time.Time     `json:"startedAt"`
+	FinishedAt time.Time     `json:"finishedAt"`
+	Cmd        []auditCmd    `json:"cmd"`
+	Results    []auditResult `json:"results,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+type auditCmd struct {
+	Args   []string    `json:"args"`
+	Limits auditLimits `json:"limits"`
+	// FileIDs maps the files and copyIn names (fd index for files) to the
+	// referenced ids in the file store
+	FileIDs     map[string]string `json:"fileIds,omitempty"`
+	MountFiles  map[string]string `json:"mountFiles,omitempty"`
+	DiskWorkDir bool              `json:"diskWorkDir,omitempty"`
+	ScratchFile string            `json:"scratchFile,omitempty"`
+}
+
+type auditLimits struct {
+	CPU          time.Duration `json:"cpuLimit"`
+	Clock        time.Duration `json:"clockLimit"`
+	Memory       uint64        `json:"memoryLimit"`
+	Stack        uint64        `json:"stackLimit,omitempty"`
+	Output       uint64        `json:"outputLimit,omitempty"`
+	Proc         uint64        `json:"procLimit"`
+	OpenFile     uint64        `json:"openFileLimit,omitempty"`
+	CPURate      uint64        `json:"cpuRateLimit,omitempty"`
+	CPUSet       string        `json:"cpuSetLimit,omitempty"`
+	StrictMemory bool          `json:"strictMemoryLimit,omitempty"`
+	IO           uint64        `json:"ioLimit,omitempty"`
+}
+
+type auditResult struct {
+	Status     string            `json:"status"`
+	ExitStatus int               `json:"exitStatus"`
+	Error      string            `json:"error,omitempty"`
+	Time       time.Duration     `json:"time"`
+	RunTime    time.Duration     `json:"runTime"`
+	Memory     uint64            `json:"memory"`
+	FileIDs    map[string]string `json:"fileIds,omitempty"`
+	GroupID    string            `json:"groupId,omitempty"`
+	CoreFileID string            `json:"coreFileId,omitempty"`
+}
+
+// auditLog writes the audit entries asynchronously to the file or unix
+// socket, entries are dropped when the buffer is full so that requests are
+// never blocked by the audit log. The file is reopened on reopen (SIGUSR1)
+// for rotation and the socket is redialed after write failures.
+type auditLog struct {
+	sink *asyncSink[auditEntry]
+}
+
+// newAuditLog opens the audit log of -audit-log, nil if not configured
+func newAuditLog(conf *config.Config) *auditLog {
+	if conf.AuditLog == "" {
+		return nil
+	}
+	w := &auditWriter{addr: conf.AuditLog}
+	if addr, ok := strings.CutPrefix(w.addr, auditSocketPrefix); ok {
+		w.addr, w.socket = addr, true
+	}
+	if err := w.open(); err != nil {
+		if !w.socket {
+			logger.Sugar().Fatal("Failed to open audit log: ", err)
+		}
+		logger.Sugar().Warn("Failed to connect audit log, retry on next entry: ", err)
+	}
+	a := &auditLog{sink: newAsyncSink[auditEntry]("audit log", conf.AuditLogBuffer, w, auditDroppedCount)}
+	reopen := make(chan os.Signal, 1)
+	notifyAuditReopen(reopen)
+	go onSinkEvent(a.sink, reopen, w.reopen)
+	return a
+}
+
+// observe converts the record into entry and queues it, it is dropped if the
+// buffer is full
+func (a *auditLog) observe(r worker.AuditRecord) {
+	a.sink.observe(convertAuditRecord(r))
+}
+
+// auditWriter writes the entries of the audit log as JSON lines, the writer
+// is dropped on failure and opened again for the next entry
+type auditWriter struct {
+	addr   string
+	socket bool
+
+	w  io.WriteCloser
+	bw *bufio.Writer
+}
+
+func (a *auditWriter) open() error {
+	var (
+		w   io.WriteCloser
+		err error
+	)
+	if a.socket {
+		w, err = net.Dial("unix", a.addr)
+	} else {
+		w, err = os.OpenFile(a.addr, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	}
+	if err != nil {
+		return err
+	}
+	a.w, a.bw = w, bufio.NewWriter(w)
+	return nil
+}
+
+func (a *auditWriter) reopen() {
+	if err := a.close(); err != nil {
+		logger.Sugar().Error("Failed to write audit log: ", err)
+	}
+	if err := a.open(); err != nil {
+		logger.Sugar().Error("Failed to reopen audit log: ", err)
+	} else {
+		logger.Sugar().Info("Audit log reopened")
+	}
+}
+
+func (a *auditWriter) write(e auditEntry) error {
+	if a.w == nil {
+		// reconnect the socket or retry the file failed to reopen
+		if err := a.open(); err != nil {
+			return err
+		}
+	}
+	if err := writeJSONLine(a.bw, e); err != nil {
+		a.close()
+		return err
+	}
+	return nil
+}
+
+func (a *auditWriter) flush() error {
+	if a.bw == nil {
+		return nil
+	}
+	if err := a.bw.Flush(); err != nil {
+		a.close()
+		return err
+	}
+	return nil
+}
+
+// close flushes and closes the writer, it is opened again for the next entry
+func (a *auditWriter) close() error {
+	if a.w == nil {
+		return nil
+	}
+	err := a.bw.Flush()
+	a.w.Close()
+	a.w, a.bw = nil, nil
+	return err
+}
+
+func initAuditLog(audit *auditLog, work worker.Worker) initFunc {
+	return func() (start func(), cleanUp stopFunc) {
+		if audit == nil {
+			return nil, nil
+		}
+		return nil, func(ctx context.Context) error {
+			// requests finished during shutdown are still audited
+			work.Shutdown()
+			audit.sink.close()
+			logger.Sugar().Infof("Audit log closed, %d entries dropped", audit.sink.dropCount())
+			return nil
+		}
+	}
+}
+
+func convertAuditRecord(r worker.AuditRecord) auditEntry {
+	e := auditEntry{
+		RequestID:  r.Request.RequestID,
+		Client:     r.Client,
+		StartedAt:  r.StartedAt,
+		FinishedAt: r.FinishedAt,
+		Cmd:        make([]auditCmd, 0, len(r.Request.Cmd)),
+	}
+	if e.Client == "" {
+		e.Client = "default"
+	}
+	for _, c := range r.Request.Cmd {
+		e.Cmd = append(e.Cmd, convertAuditCmd(c))
+	}
+	for _, rt := range r.Response.Results {
+		e.Results = append(e.Results, auditResult{
+			Status:     rt.Status.String(),
+			ExitStatus: rt.ExitStatus,
+			Error:      rt.Error,
+			Time:       rt.Time,
+			RunTime:    rt.RunTime,
+			Memory:     rt.Memory.Byte(),
+			FileIDs:    rt.FileIDs,
+			GroupID:    rt.GroupID,
+			CoreFileID: rt.CoreFileID,
+		})
+	}
+	if r.Response.Error != nil {
+		e.Error = r.Response.Error.Error()
+	}
+	return e
+}
+
+func convertAuditCmd(c worker.Cmd) auditCmd {
+	rt := auditCmd{
+		Args: c.Args,
+		Limits: auditLimits{
+			CPU:          c.CPULimit,
+			Clock:        c.ClockLimit,
+			Memory:       c.MemoryLimit.Byte(),
+			Stack:        c.StackLimit.Byte(),
+			Output:       c.OutputLimit.Byte(),
+			Proc:         c.ProcLimit,
+			OpenFile:     c.OpenFileLimit,
+			CPURate:      c.CPURateLimit,
+			CPUSet:       c.CPUSetLimit,
+			StrictMemory: c.StrictMemoryLimit,
+			IO:           c.IOLimit.Byte(),
+		},
+		MountFiles:  c.MountFiles,
+		DiskWorkDir: c.DiskWorkDir,
+		ScratchFile: c.ScratchFile,
+	}
+	fileIDs := make(map[string]string)
+	for i, f := range c.Files {
+		if cf, ok := f.(*worker.CachedFile); ok {
+			fileIDs[strconv.Itoa(i)] = cf.FileID
+		}
+	}
+	for fd, f := range c.ExtraFiles {
+		if cf, ok := f.(*worker.CachedFile); ok {
+			fileIDs[strconv.Itoa(fd)] = cf.FileID
+		}
+	}
+	for name, f := range c.CopyIn {
+		switch cf := f.(type) {
+		case *worker.CachedFile:
+			fileIDs[name] = cf.FileID
+		case *worker.GroupFile:
+			fileIDs[name] = cf.GroupID
+		}
+	}
+	if len(fileIDs) > 0 {
+		rt.FileIDs = fileIDs
+	}
+	return rt
+}
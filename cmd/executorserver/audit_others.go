@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// notifyAuditReopen reopens the audit log on SIGUSR1 (e.g. by logrotate)
+func notifyAuditReopen(ch chan os.Signal) {
+	signal.Notify(ch, syscall.SIGUSR1)
+}
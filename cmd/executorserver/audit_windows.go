@@ -0,0 +1,6 @@
+package main
+
+import "os"
+
+// notifyAuditReopen is not supported since there is no SIGUSR1 on windows
+func notifyAuditReopen(ch chan os.Signal) {}
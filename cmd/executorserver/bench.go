@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	restexecutor "github.com/criyle/go-judge/cmd/executorserver/rest_executor"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	benchTarget          = 20 * time.Millisecond // cpu time of the calibrated benchmark
+	benchStartIterations = 10000
+	benchMaxIterations   = 1 << 26
+	benchBaselineRuns    = 5
+	benchSampleRuns      = 3
+	benchMaxSamples      = 1440 // a day of samples every minute
+
+	benchCPULimit    = time.Second
+	benchClockLimit  = 2 * time.Second
+	benchMemoryLimit = 64 << 20
+	benchOutputMax   = 4 << 10
+)
+
+var errBenchBusy = errors.New("no worker is free or requests are waiting")
+
+// benchSample is the benchmark measured at a time, durations are in ns
+type benchSample struct {
+	Time     time.Time     `json:"time"`
+	CPUTime  time.Duration `json:"cpuTime"` // median of the runs
+	RunTime  time.Duration `json:"runTime"`
+	Drift    float64       `json:"drift"` // cpuTime / baseline - 1
+	Shedding bool          `json:"shedding,omitempty"`
+}
+
+// benchStatus is the response of GET /admin/bench, the samples are omitted
+// from /version
+type benchStatus struct {
+	Interval   time.Duration `json:"interval"`
+	Iterations int           `json:"iterations"` // 0 if not calibrated yet
+	Baseline   time.Duration `json:"baseline"`
+	Drift      float64       `json:"drift"` // of the last sample
+	Shedding   bool          `json:"shedding"`
+	Skipped    uint64        `json:"skipped"` // checks skipped since no worker was free or requests were waiting
+	Error      string        `json:"error,omitempty"`
+	Samples    []benchSample `json:"samples,omitempty"`
+}
+
+// benchmark runs a spin loop calibrated to benchTarget of cpu time in an idle
+// environment every -bench-interval, and tracks the drift of its cpu time
+// from the baseline measured after startup, e.g. as the cpu frequency drops
+// by thermal throttling under sustained load. It is submitted to the worker
+// queue at low priority only while a worker loop is free and no request is
+// waiting, so that it samples under load without delaying a real run. While
+// the drift is above -bench-shed-drift, the worker loops are capped to
+// -bench-shed-parallelism.
+type benchmark struct {
+	work            worker.Worker
+	interval        time.Duration
+	shedDrift       float64
+	shedParallelism int
+
+	mu      sync.Mutex
+	status  benchStatus
+	samples []benchSample // ring of benchMaxSamples
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// newBenchmark creates the benchmark of -bench-interval, nil if disabled
+func newBenchmark(conf *config.Config, work worker.Worker) *benchmark {
+	if conf.BenchInterval <= 0 {
+		return nil
+	}
+	if conf.BenchShedDrift < 0 {
+		logger.Sugar().Fatalf("Invalid bench shed drift %v, it should not be negative", conf.BenchShedDrift)
+	}
+	if conf.BenchShedParallelism < 0 {
+		logger.Sugar().Fatalf("Invalid bench shed parallelism %d, it should not be negative", conf.BenchShedParallelism)
+	}
+	shed := conf.BenchShedParallelism
+	if shed == 0 {
+		shed = (conf.Parallelism + 1) / 2
+	}
+	return &benchmark{
+		work:            work,
+		interval:        conf.BenchInterval,
+		shedDrift:       conf.BenchShedDrift,
+		shedParallelism: shed,
+		status:          benchStatus{Interval: conf.BenchInterval},
+		done:            make(chan struct{}),
+	}
+}
+
+func benchCmd(iterations int) worker.Cmd {
+	return worker.Cmd{
+		Args: []string{"/bin/sh", "-c", "i=0; while [ $i -lt " + strconv.Itoa(iterations) + " ]; do i=$((i+1)); done"},
+		Env:  warmupEnv,
+		Files: []worker.CmdFile{
+			&worker.MemoryFile{Content: nil},
+			&worker.Collector{Name: "stdout", Max: benchOutputMax},
+			&worker.Collector{Name: "stderr", Max: benchOutputMax},
+		},
+		CPULimit:    benchCPULimit,
+		ClockLimit:  benchClockLimit,
+		MemoryLimit: benchMemoryLimit,
+		ProcLimit:   1,
+	}
+}
+
+// idle reports whether a worker loop is free and no request is waiting
+func (b *benchmark) idle() bool {
+	c := b.work.Contention()
+	return c.Busy < c.Parallelism && c.Waiting == 0
+}
+
+// run submits the benchmark once to the worker queue at low priority, so that
+// it takes the free worker loop and the requests submitted meanwhile are not
+// queued behind it
+func (b *benchmark) run(ctx context.Context, iterations int) (worker.Result, error) {
+	if !b.idle() {
+		return worker.Result{}, errBenchBusy
+	}
+	ch, _ := b.work.Submit(ctx, &worker.Request{
+		Priority: worker.PriorityLow,
+		Cmd:      []worker.Cmd{benchCmd(iterations)},
+	})
+	res := <-ch
+	if res.Error != nil {
+		return worker.Result{}, res.Error
+	}
+	r := res.Results[0]
+	for _, f := range r.Files {
+		f.Close()
+	}
+	if r.Status != envexec.StatusAccepted {
+		return r, fmt.Errorf("benchmark %s: %s", r.Status, r.Error)
+	}
+	return r, nil
+}
+
+// measure runs the benchmark n times and returns the run of the median cpu time
+func (b *benchmark) measure(ctx context.Context, iterations, n int) (worker.Result, error) {
+	rt := make([]worker.Result, 0, n)
+	for i := 0; i < n; i++ {
+		r, err := b.run(ctx, iterations)
+		if err != nil {
+			return r, err
+		}
+		rt = append(rt, r)
+	}
+	sort.Slice(rt, func(i, j int) bool { return rt[i].Time < rt[j].Time })
+	return rt[n/2], nil
+}
+
+// calibrate finds the iterations taking about benchTarget of cpu time and
+// measures the baseline
+func (b *benchmark) calibrate(ctx context.Context) (int, time.Duration, error) {
+	n := benchStartIterations
+	for {
+		r, err := b.run(ctx, n)
+		if err != nil {
+			return 0, 0, err
+		}
+		if r.Time >= benchTarget/2 || n >= benchMaxIterations {
+			break
+		}
+		// scales toward the target, at most 10 times at once
+		scale := 10.0
+		if r.Time > 0 && float64(benchTarget)/float64(r.Time) < scale {
+			scale = float64(benchTarget) / float64(r.Time)
+		}
+		n = int(float64(n) * scale)
+		if n > benchMaxIterations {
+			n = benchMaxIterations
+		}
+	}
+	r, err := b.measure(ctx, n, benchBaselineRuns)
+	if err != nil {
+		return 0, 0, err
+	}
+	return n, r.Time, nil
+}
+
+func (b *benchmark) loop(ctx context.Context) {
+	defer close(b.done)
+
+	ticker := time.NewTicker(b.interval)
+	defer ticker.Stop()
+	for {
+		b.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// check calibrates the benchmark if not yet, or takes a sample otherwise
+func (b *benchmark) check(ctx context.Context) {
+	b.mu.Lock()
+	iterations, baseline := b.status.Iterations, b.status.Baseline
+	b.mu.Unlock()
+
+	if iterations == 0 {
+		n, base, err := b.calibrate(ctx)
+		if b.observeError(ctx, err) {
+			return
+		}
+		b.mu.Lock()
+		b.status.Iterations, b.status.Baseline = n, base
+		b.mu.Unlock()
+		benchBaselineSeconds.Set(base.Seconds())
+		logger.Sugar().Infof("Benchmark calibrated to %d iterations, baseline %v", n, base)
+		return
+	}
+
+	r, err := b.measure(ctx, iterations, benchSampleRuns)
+	if b.observeError(ctx, err) {
+		return
+	}
+	s := benchSample{
+		Time:    time.Now(),
+		CPUTime: r.Time,
+		RunTime: r.RunTime,
+		Drift:   float64(r.Time)/float64(baseline) - 1,
+	}
+
+	b.mu.Lock()
+	switch {
+	case b.shedDrift > 0 && !b.status.Shedding && s.Drift >= b.shedDrift:
+		b.status.Shedding = true
+		b.work.SetParallelismCap(b.shedParallelism)
+		logger.Sugar().Warnf("Benchmark drift %.2f exceeds %.2f, parallelism capped to %d", s.Drift, b.shedDrift, b.shedParallelism)
+	case b.status.Shedding && s.Drift < b.shedDrift/2:
+		b.status.Shedding = false
+		b.work.SetParallelismCap(0)
+		logger.Sugar().Infof("Benchmark drift %.2f recovered, parallelism restored", s.Drift)
+	}
+	s.Shedding = b.status.Shedding
+	b.status.Drift = s.Drift
+	b.status.Error = ""
+	if len(b.samples) >= benchMaxSamples {
+		b.samples = append(b.samples[:0], b.samples[1:]...)
+	}
+	b.samples = append(b.samples, s)
+	b.mu.Unlock()
+
+	benchCPUTimeSeconds.Set(r.Time.Seconds())
+	benchDrift.Set(s.Drift)
+	if s.Shedding {
+		benchShedding.Set(1)
+	} else {
+		benchShedding.Set(0)
+	}
+}
+
+// observeError counts the skipped check or records the error, it returns
+// false if err is nil
+func (b *benchmark) observeError(ctx context.Context, err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, errBenchBusy):
+		b.mu.Lock()
+		b.status.Skipped++
+		b.mu.Unlock()
+		benchSkippedCount.Inc()
+	case ctx.Err() == nil:
+		b.mu.Lock()
+		if b.status.Error != err.Error() {
+			logger.Sugar().Warn("Benchmark failed: ", err)
+		}
+		b.status.Error = err.Error()
+		b.mu.Unlock()
+	}
+	return true
+}
+
+// snapshot returns the status with the samples if requested
+func (b *benchmark) snapshot(samples bool) benchStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rt := b.status
+	if samples {
+		rt.Samples = append([]benchSample(nil), b.samples...)
+	}
+	return rt
+}
+
+func initBenchmark(bench *benchmark) initFunc {
+	return func() (start func(), cleanUp stopFunc) {
+		if bench == nil {
+			return nil, nil
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		bench.cancel = cancel
+		go bench.loop(ctx)
+		logger.Sugar().Infof("Benchmark every %v, shedding load above drift %v to parallelism %d",
+			bench.interval, bench.shedDrift, bench.shedParallelism)
+		return nil, func(ctx context.Context) error {
+			bench.cancel()
+			<-bench.done
+			return nil
+		}
+	}
+}
+
+func generateHandleBench(bench *benchmark) func(*gin.Context) {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			abortNotAdmin(c)
+			return
+		}
+		if bench == nil {
+			restexecutor.AbortError(c, http.StatusNotFound, model.CodeFeatureDisabled, "benchmark is not enabled by -bench-interval")
+			return
+		}
+		c.JSON(http.StatusOK, bench.snapshot(true))
+	}
+}
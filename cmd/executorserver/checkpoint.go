@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	restexecutor "github.com/criyle/go-judge/cmd/executorserver/rest_executor"
+)
+
+const (
+	checkpointDir    = "checkpoint"
+	checkpointSuffix = ".json"
+)
+
+// checkpointStore stores the items of the batches of POST /runs submitted with
+// Idempotency-Key under the checkpoint directory of -history-db, one directory
+// per batch named by the hash of the owner and the key, and one file per item
+// named by its index. The batches not used within -history-retention are
+// pruned with the history.
+type checkpointStore struct {
+	dir string
+}
+
+func openCheckpointStore(dir string) (*checkpointStore, error) {
+	dir = filepath.Join(dir, checkpointDir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &checkpointStore{dir: dir}, nil
+}
+
+func (s *checkpointStore) batchDir(owner, key string) string {
+	sum := sha256.Sum256([]byte(owner + "\x00" + key))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:]))
+}
+
+// Load returns the items stored, the temporary and malformed files are
+// skipped. The batch is touched so that it is kept while in use.
+func (s *checkpointStore) Load(owner, key string) (map[int]restexecutor.CheckpointItem, error) {
+	dir := s.batchDir(owner, key)
+	ents, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	os.Chtimes(dir, now, now)
+	rt := make(map[int]restexecutor.CheckpointItem)
+	for _, e := range ents {
+		name, ok := strings.CutSuffix(e.Name(), checkpointSuffix)
+		if !ok {
+			continue
+		}
+		i, err := strconv.Atoi(name)
+		if err != nil || i < 0 {
+			continue
+		}
+		var it restexecutor.CheckpointItem
+		if err := readJSONFile(filepath.Join(dir, e.Name()), &it); err != nil {
+			continue
+		}
+		rt[i] = it
+	}
+	return rt, nil
+}
+
+// Store writes the item into a temporary file renamed once synced, so that the
+// item is either stored entirely or not at all
+func (s *checkpointStore) Store(owner, key string, index int, item restexecutor.CheckpointItem) error {
+	dir := s.batchDir(owner, key)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	b, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err = f.Write(b); err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), filepath.Join(dir, strconv.Itoa(index)+checkpointSuffix))
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+// prune removes the batches not used since before
+func (s *checkpointStore) prune(before time.Time) error {
+	ents, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	for _, e := range ents {
+		fi, err := e.Info()
+		if err != nil || !e.IsDir() || !fi.ModTime().Before(before) {
+			continue
+		}
+		if err := os.RemoveAll(filepath.Join(s.dir, e.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -12,39 +12,164 @@ import (
 // Config defines executor server configuration
 type Config struct {
 	// container
+	Sandbox            string `flagUsage:"specifies the sandbox running the programs: default (the sandbox of the platform) / fake (child processes in temp dirs without isolation, reporting canned usage for tests)" default:"default"`
 	ContainerInitPath  string `flagUsage:"container init path"`
 	PreFork            int    `flagUsage:"control # of the prefork workers" default:"1"`
 	TmpFsParam         string `flagUsage:"tmpfs mount data (only for default mount with no mount.yaml)" default:"size=128m,nr_inodes=4k"`
 	NetShare           bool   `flagUsage:"share net namespace with host"`
 	MountConf          string `flagUsage:"specifies mount configuration file" default:"mount.yaml"`
+	StaticContainer    bool   `flagUsage:"mounts only the tmpfs work directory, /proc and /dev/null without reading the mount configuration, so that the server has no host dependencies (e.g. runs from a scratch image) for statically linked programs. Options binding host paths and warmup presets are rejected (linux only)"`
 	SeccompConf        string `flagUsage:"specifies seccomp filter" default:"seccomp.yaml"`
+	VerdictConf        string `flagUsage:"specifies verdict rules configuration file, the rules rewrite the results of requests selecting them by verdict" default:"verdict.yaml"`
+	PolicyConf         string `flagUsage:"specifies policy configuration file restricting the features, mount profiles and limits of the requests of auth tokens, reloaded with the config" default:"policy.yaml"`
 	Parallelism        int    `flagUsage:"control the # of concurrency execution (default equal to number of cpu)"`
 	CgroupPrefix       string `flagUsage:"control cgroup prefix" default:"executor_server"`
+	CgroupMount        bool   `flagUsage:"mount read-only view of the container cgroup at /sys/fs/cgroup (cgroup v2 only)"`
 	ContainerCredStart int    `flagUsage:"control the start uid&gid for container (0 uses unprivileged root)" default:"0"`
+	CredPinStart       int    `flagUsage:"control the start uid&gid that requests could pin (should not overlap with generated ones)"`
+	CredPinCount       int    `flagUsage:"control the number of uid&gid that requests could pin from cred pin start (0 disables)" default:"0"`
+	VerifyReset        bool   `flagUsage:"verify work dir and tmp are empty and no process is left after environment reset (debug)"`
+	CheckFds           bool   `flagUsage:"fail the run with internal error if the program would inherit fds other than its files before execve (debug)"`
+	RandomizeWorkDir   bool   `flagUsage:"mount work dir tmpfs at randomized path for every command, environments are not reused (linux only)"`
+
+	AutoParallelism         bool          `flagUsage:"adjusts the # of concurrency execution within parallelism min and max by the queue wait of requests and the host cpu utilization, parallelism is the initial one"`
+	ParallelismMin          int           `flagUsage:"specifies the min # of concurrency execution of auto parallelism" default:"1"`
+	ParallelismMax          int           `flagUsage:"specifies the max # of concurrency execution of auto parallelism (default twice the number of cpu)"`
+	AutoParallelismInterval time.Duration `flagUsage:"specifies the interval auto parallelism is re-evaluated with hysteresis" default:"10s"`
+
+	FakeTime   time.Duration `flagUsage:"specifies cpu time and run time reported by every run of -sandbox fake" default:"1ms"`
+	FakeMemory *envexec.Size `flagUsage:"specifies memory reported by every run of -sandbox fake" default:"1m"`
+
+	ShmSize        *envexec.Size `flagUsage:"specifies size of the tmpfs mounted at /dev/shm by the default mount (no mount.yaml), also the max shmSize of commands (0 disables)" default:"64m"`
+	ShmSizeDefault *envexec.Size `flagUsage:"specifies shmSize of commands when not specified" default:"16m"`
+	RandomSize     *envexec.Size `flagUsage:"specifies size of the deterministic /dev/urandom and /dev/random of commands with randomSeed, reads past it get EOF (linux only)" default:"1m"`
+
+	Warmup []string `flagUsage:"runs the warmup presets (c, cpp, java, python3) once in each prefork environment at startup (example: -warmup=cpp,java)"`
+
+	PresetCheck       bool   `flagUsage:"runs each warmup preset once through the sandbox at startup and after config reload, reporting the broken toolchains in /version"`
+	PresetCheckPolicy string `flagUsage:"specifies how the presets failing -preset-check are handled: flag reports them in /version, disable also rejects them in warmup with 503 (flag, disable)" default:"flag"`
+
+	Dataset []string `flagUsage:"specifies read-only datasets mounted at /data/<name> when requested, directory must be owned by root (example: -dataset=problem1=/srv/data/problem1) (linux only)"`
+
+	Emulator         []string `flagUsage:"specifies emulators in the container prepended to args of programs of foreign arch when arch is set (example: -emulator=riscv64=/usr/bin/qemu-riscv64-static) (linux only)"`
+	EmulatorBaseline bool     `flagUsage:"measures memory of each emulator by running an empty program at startup and subtracts it from memory of emulated runs (linux only)"`
+
+	MountFilesDir      string        `flagUsage:"specifies host directory caching the files of mountFiles mounted read-only at /files, empty disables mountFiles (linux only)"`
+	MountFilesCacheMax *envexec.Size `flagUsage:"specifies max total size of cached files of mountFiles not in use" default:"4g"`
+
+	AllowHostExec []string `flagUsage:"specifies host directories whose files could be referenced by absolute paths in args, bind mounted read-only at /host/<path> for each run (example: -allow-host-exec=/opt/judge/checkers) (linux only)"`
+
+	AllowDiskWorkdir bool          `flagUsage:"allows commands with diskWorkDir to have /w and /tmp bind mounted from a directory on the host disk instead of tmpfs (linux only)"`
+	DiskWorkdirPath  string        `flagUsage:"specifies host directory where the directories of diskWorkDir are created (default: under -dir if specified, otherwise the system temp dir)"`
+	DiskWorkdirMax   *envexec.Size `flagUsage:"specifies max bytes left in /w and /tmp of commands with diskWorkDir after the run" default:"4g"`
+
+	AllowScratch bool          `flagUsage:"allows commands with scratchFile to have a file on the host disk mounted in /w, so that fsync and O_DIRECT reach the device (linux only)"`
+	ScratchPath  string        `flagUsage:"specifies host directory backed by disk where the files of scratchFile are created (default: under -dir if specified, otherwise the system temp dir)"`
+	ScratchMax   *envexec.Size `flagUsage:"specifies max and default scratchSize of commands with scratchFile" default:"64m"`
+
+	SnapshotMax *envexec.Size `flagUsage:"specifies max total size of the work dir snapshots saved by snapshotWorkDir of a request, they are held in memory until the request finished (0 disables snapshotWorkDir)" default:"256m"`
+
+	CollectorDir string `flagUsage:"specifies host directory where the outputs of collectors not cached are buffered as anonymous files instead of -dir, e.g. on disk to keep large outputs out of memory when -dir is not specified (/dev/shm)"`
+
+	Scheduler      string        `flagUsage:"specifies the order of waiting requests: fifo (in order of submission) / fair (clients in turn) / sjf (shortest estimatedDuration first)" default:"fifo"`
+	SchedulerShare []string      `flagUsage:"specifies the weights of auth tokens for fair scheduler, 1 by default (example: -scheduler-share=token1=3,token2=1)"`
+	PriorityAging  time.Duration `flagUsage:"raises the priority of waiting requests by one level for every duration waited so that low priority requests are not starved (0 disables)" default:"1s"`
+	TotalMemory    *envexec.Size `flagUsage:"holds waiting requests until the memory limits of their commands fit into the total memory with the running requests (0 disables)" default:"0"`
+	EstimateFactor float64       `flagUsage:"counts requests that ran longer than the factor times their estimatedDuration in metrics (0 disables)" default:"5"`
+
+	IdempotencyTTL      time.Duration `flagUsage:"specifies duration the responses of requests with idempotencyKey are kept for retries (0 disables idempotencyKey)" default:"10m"`
+	IdempotencyCacheMax *envexec.Size `flagUsage:"specifies max total size of inline files of the kept responses" default:"256m"`
+
+	UsageSaveInterval time.Duration `flagUsage:"specifies interval the usage of auth tokens is saved into -dir to survive restarts (0 disables saving)" default:"1m"`
+	QuotaCPU          []string      `flagUsage:"specifies cpu time quota of auth tokens in each quota period (example: -quota-cpu=token1=1h,token2=30m)"`
+	QuotaRuns         []string      `flagUsage:"specifies quota of number of requests executed of auth tokens in each quota period (example: -quota-runs=token1=10000)"`
+	QuotaPeriod       time.Duration `flagUsage:"specifies period the quota usage is reset, aligned to UTC midnight for 24h (0 never resets)" default:"24h"`
+
+	CgroupRetry        int           `flagUsage:"control the # of retries when creating cgroup or attaching process fails with EBUSY / EAGAIN (0 disables)" default:"3"`
+	CgroupRetryBackoff time.Duration `flagUsage:"control the backoff before the first cgroup retry, doubled after each retry" default:"10ms"`
+
+	MountCheckInterval time.Duration `flagUsage:"specifies interval idle environments are checked for stale mounts (e.g. host libraries upgraded) and replaced in background, they are always checked on checkout (0 disables the background check)"`
+
+	InitLogSize *envexec.Size `flagUsage:"specifies size of the stderr of the container init kept for each environment, shown by /debug/initlog and in Internal Error results (0 writes it to stderr) (linux only)" default:"16k"`
 
 	// file store
 	SrcPrefix []string `flagUsage:"specifies directory prefix for source type copyin (example: -src-prefix=/home,/usr)"`
 	Dir       string   `flagUsage:"specifies directory to store file upload / download (in memory by default)"`
+	StoreSync bool     `flagUsage:"fsync files and directory of file store when files are added (disable for benchmark)" default:"true"`
+
+	ImportState string `flagUsage:"imports the state exported by /admin/export of the previous instance sharing -dir from the file before serving, fails to start if it could not be imported"`
 
 	// runner limit
 	TimeLimitCheckerInterval time.Duration `flagUsage:"specifies time limit checker interval" default:"100ms"`
 	ExtraMemoryLimit         *envexec.Size `flagUsage:"specifies extra memory buffer for check memory limit" default:"16k"`
 	OutputLimit              *envexec.Size `flagUsage:"specifies POSIX rlimit for output for each command" default:"256m"`
 	CopyOutLimit             *envexec.Size `flagUsage:"specifies default file copy out max" default:"256m"`
+	CopyOutMaxFiles          int           `flagUsage:"specifies max number of copy out files for each command (0 unlimited)" default:"4096"`
+	CopyOutMaxTotal          *envexec.Size `flagUsage:"specifies max total size of copy out files for each command (0 unlimited)" default:"1g"`
 	OpenFileLimit            int           `flagUsage:"specifies max open file count" default:"256"`
+	ArchiveMaxCount          int           `flagUsage:"specifies max number of entries (files, directories and symbolic links) extracted from copyIn archive" default:"4096"`
+	ArchiveMaxSize           *envexec.Size `flagUsage:"specifies max total size extracted from copyIn archive" default:"128m"`
+	InlineContentMax         *envexec.Size `flagUsage:"specifies max size of each inline content of copyIn and files (0 unlimited)" default:"64m"`
+	StderrMax                *envexec.Size `flagUsage:"specifies default max of stderr collector (files[2]) when not specified" default:"64k"`
+	StderrCacheTruncated     bool          `flagUsage:"cache the full output of stderr collector into file store when it exceeds max by default"`
+	IOLimitStrict            bool          `flagUsage:"reject commands with ioLimit when io accounting (io / blkio cgroup) is not available instead of ignoring the limit"`
+	CoreFileMax              *envexec.Size `flagUsage:"specifies max size of core file collected for commands with collectCore (0 disables collectCore)" default:"0"`
+	MaxCPU                   time.Duration `flagUsage:"specifies max cpuLimit of each command (0 unlimited)"`
+	MaxClock                 time.Duration `flagUsage:"specifies max clockLimit of each command, also caps cpuLimit (0 unlimited)"`
+	MaxMemory                *envexec.Size `flagUsage:"specifies max memoryLimit of each command, also used when not specified (0 unlimited)" default:"0"`
+	MaxProc                  int           `flagUsage:"specifies max procLimit of each command, also used when not specified (0 unlimited)"`
+	MaxEnv                   int           `flagUsage:"specifies max number of env of each command, the size of args and env is limited by ARG_MAX of the host (0 unlimited)" default:"1024"`
+	LimitPolicy              string        `flagUsage:"specifies how commands exceeding -max-cpu / -max-clock / -max-memory / -max-proc are handled: reject (400) / clamp (lowered to the max, echoed in limits)" default:"reject"`
+	CalibrateMax             int           `flagUsage:"specifies max repeat of POST /calibrate, which runs a request back-to-back in one environment to calibrate time limits (0 disables /calibrate)" default:"20"`
+	EnableTrace              bool          `flagUsage:"allows commands with trace to count their syscalls by ptrace (linux amd64 only)"`
+	ProcInfoCPU              int           `flagUsage:"specifies number of processors in /proc/cpuinfo of commands with procInfo when not specified" default:"1"`
 	Cpuset                   string        `flagUsage:"control the usage of cpuset for all containerd process"`
+	NUMAAware                bool          `flagUsage:"shard environments by NUMA node, each run is bound to the cpus and memory of one node (linux only, ignored with single node)"`
 	EnableCPURate            bool          `flagUsage:"enable cpu cgroup rate control"`
 	CPUCfsPeriod             time.Duration `flagUsage:"set cpu.cfs_period" default:"100ms"`
 	FileTimeout              time.Duration `flagUsage:"specified timeout for filestore files"`
+	StrictLeakCheck          bool          `flagUsage:"report unhealthy and log the stack when fds or goroutines of a run are not released after it finished"`
+	PanicThreshold           int           `flagUsage:"exits the process when more runs than the number panicked within a minute, which usually means something systemic (0 never exits)"`
+	Strict                   bool          `flagUsage:"exit at startup (and reject config reload) if any requested isolation feature (cgroup controller, namespace, credential, seccomp, swap accounting) is not active"`
+
+	NoisyNeighborMajorFaults int           `flagUsage:"flags the runs with more major page faults than the number as noisy neighbor in results and metrics (0 disables)"`
+	NoisyNeighborWriteback   *envexec.Size `flagUsage:"flags the runs writing more bytes back to block devices (io / blkio cgroup) than the size as noisy neighbor in results and metrics (0 disables)" default:"0"`
+
+	DefaultCPU     time.Duration `flagUsage:"specifies cpuLimit of commands when not specified, lowered to -max-cpu / -max-clock and the policy max (0 disables the default)" default:"1s"`
+	DefaultClock   time.Duration `flagUsage:"specifies clockLimit of commands when not specified (0 follows cpuLimit)"`
+	DefaultMemory  *envexec.Size `flagUsage:"specifies memoryLimit of commands when not specified, lowered to -max-memory and the policy max (0 uses -max-memory)" default:"256m"`
+	DefaultStack   *envexec.Size `flagUsage:"specifies stackLimit of commands when not specified, not above memoryLimit (0 follows memoryLimit)" default:"0"`
+	DefaultProc    int           `flagUsage:"specifies procLimit of commands when not specified, lowered to -max-proc and the policy max (0 uses -max-proc)" default:"50"`
+	AllowUnlimited bool          `flagUsage:"allows commands to set cpuLimit, clockLimit, memoryLimit, stackLimit and procLimit to \"unlimited\" (REST and WebSocket), the clients with policy need the unlimited feature and the max limits still apply"`
+
+	BenchInterval        time.Duration `flagUsage:"runs a spin benchmark calibrated at startup through the worker queue at low priority every interval while a worker is free and no request is waiting, reporting the drift of its cpu time from the baseline (e.g. by thermal throttling) in metrics, /version and GET /admin/bench (0 disables)"`
+	BenchShedDrift       float64       `flagUsage:"caps the parallelism to -bench-shed-parallelism while the benchmark drift is at least the fraction (example: 0.1 for 10% slower), restored once it falls below half of it (0 never sheds)"`
+	BenchShedParallelism int           `flagUsage:"specifies the parallelism while shedding load by -bench-shed-drift (0 for half of -parallelism)"`
 
 	// server config
-	HTTPAddr      string `flagUsage:"specifies the http binding address"`
-	EnableGRPC    bool   `flagUsage:"enable gRPC endpoint"`
-	GRPCAddr      string `flagUsage:"specifies the grpc binding address"`
-	MonitorAddr   string `flagUsage:"specifies the metrics binding address"`
-	AuthToken     string `flagUsage:"bearer token auth for REST / gRPC"`
-	EnableDebug   bool   `flagUsage:"enable debug endpoint"`
-	EnableMetrics bool   `flagUsage:"enable promethus metrics endpoint"`
+	HTTPAddr      string   `flagUsage:"specifies the http binding address (comma separated for multiple addresses)"`
+	EnableGRPC    bool     `flagUsage:"enable gRPC endpoint"`
+	GRPCAddr      string   `flagUsage:"specifies the grpc binding address"`
+	MonitorAddr   string   `flagUsage:"specifies the metrics binding address"`
+	AdminAddr     string   `flagUsage:"specifies the binding address of /healthz, /metrics, /debug/*, /admin/* and /selftest, they are not served on other addresses if set"`
+	AuthToken     []string `flagUsage:"bearer token auth for REST / gRPC, files are isolated per token (example: -auth-token=token1,token2)"`
+	AdminToken    string   `flagUsage:"bearer token auth for REST / gRPC that can access files of all tokens, its uploads are shared"`
+	EnableDebug   bool     `flagUsage:"enable debug endpoint"`
+	EnableMetrics bool     `flagUsage:"enable promethus metrics endpoint"`
+	EnableUI      bool     `flagUsage:"enable web UI for manual testing at /ui"`
+	InstanceID    string   `flagUsage:"specifies instance id reported in results and metrics label (hostname by default)"`
+
+	MaxRequestSize *envexec.Size `flagUsage:"specifies max body size of /run request and /file upload (0 unlimited)" default:"1g"`
+
+	AuditLog       string `flagUsage:"writes one JSON line for each executed request to the file (reopened on SIGUSR1), or to the unix socket if prefixed by unix: (example: -audit-log=unix:/run/audit.sock)"`
+	AuditLogBuffer int    `flagUsage:"specifies max number of audit log entries buffered, entries are dropped when it is full" default:"4096"`
+
+	HistoryDB        string        `flagUsage:"records the summary and results of every run into the directory queried by GET /history, the contents of inline files and outputs are recorded by size and sha256 only"`
+	HistoryRetention time.Duration `flagUsage:"specifies duration the runs are kept in -history-db before pruned (0 keeps forever)" default:"168h"`
+	HistoryBuffer    int           `flagUsage:"specifies max number of history entries buffered, entries are dropped when it is full" default:"4096"`
+
+	RecordDir    string  `flagUsage:"writes the sampled /run requests and their responses as pairs of JSON files keyed by request ID into the directory, to be replayed by the replay subcommand (inline contents are recorded by size and sha256 only)"`
+	RecordSample float64 `flagUsage:"specifies fraction of /run requests recorded into -record-dir, within (0, 1]" default:"1"`
 
 	// logger config
 	Release bool `flagUsage:"release level of logs"`
@@ -84,5 +209,8 @@ func (c *Config) Load() error {
 	if c.Parallelism <= 0 {
 		c.Parallelism = runtime.NumCPU()
 	}
+	if c.ParallelismMax <= 0 {
+		c.ParallelismMax = 2 * runtime.NumCPU()
+	}
 	return cl.Load(c)
 }
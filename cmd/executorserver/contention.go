@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/worker"
+)
+
+// envPoolStat is the response of /debug/envpool, durations are in ns
+type envPoolStat struct {
+	Parallelism int `json:"parallelism"`
+	Busy        int `json:"busy"`
+	Waiting     int `json:"waiting"`
+
+	// waits of the requests finished within the last minute
+	Runs       int           `json:"runs"`
+	QueueWait  time.Duration `json:"queueWait"`
+	EnvWait    time.Duration `json:"envWait"`
+	RunTime    time.Duration `json:"runTime"`
+	Index      float64       `json:"index"`
+	QueueIndex float64       `json:"queueIndex"`
+	EnvIndex   float64       `json:"envIndex"`
+
+	Idle      int            `json:"idle"`
+	Available string         `json:"available,omitempty"` // error of the last environment creation
+	NUMA      []numaNodeStat `json:"numa,omitempty"`
+}
+
+type numaNodeStat struct {
+	Node  int `json:"node"`
+	InUse int `json:"inUse"`
+	Idle  int `json:"idle"`
+}
+
+// generateHandleEnvPool reports the contention of the worker loops and the
+// environments, so that -parallelism could be tuned by it
+func generateHandleEnvPool(work worker.Worker, envPool pool.Pool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		c := work.Contention()
+		rt := envPoolStat{
+			Parallelism: c.Parallelism,
+			Busy:        c.Busy,
+			Waiting:     c.Waiting,
+			Runs:        c.Runs,
+			QueueWait:   c.QueueWait,
+			EnvWait:     c.EnvWait,
+			RunTime:     c.RunTime,
+			Index:       c.Index(),
+			QueueIndex:  c.QueueIndex(),
+			EnvIndex:    c.EnvIndex(),
+			Idle:        envPool.Idle(),
+		}
+		if err := envPool.Available(); err != nil {
+			rt.Available = err.Error()
+		}
+		if p, ok := envPool.(pool.NUMAPool); ok {
+			for _, n := range p.NUMAStat() {
+				rt.NUMA = append(rt.NUMA, numaNodeStat{Node: n.ID, InUse: n.InUse, Idle: n.Idle})
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rt)
+	}
+}
+
+// logParallelism logs the changes of -auto-parallelism
+func logParallelism(from, to int, c worker.Contention, cpu float64) {
+	logger.Sugar().Infof("Auto parallelism %d -> %d: queue index %.2f, env index %.2f, runs %d, waiting %d, cpu %.2f",
+		from, to, c.QueueIndex(), c.EnvIndex(), c.Runs, c.Waiting, cpu)
+}
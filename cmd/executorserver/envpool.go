@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	restexecutor "github.com/criyle/go-judge/cmd/executorserver/rest_executor"
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+// maxRebuildPrefork limits the number of replacements created by one rebuild
+const maxRebuildPrefork = 64
+
+// envPoolRebuildRequest defines parameters of POST /admin/envpool/rebuild
+type envPoolRebuildRequest struct {
+	Prefork int `json:"prefork"` // number of replacements to create
+}
+
+type envPoolRebuildResult struct {
+	Idle         int    `json:"idle"`  // idle environments destroyed
+	InUse        int    `json:"inUse"` // environments destroyed when put back
+	Preforked    int    `json:"preforked"`
+	PreforkError string `json:"preforkError,omitempty"`
+
+	// Warmup reports the -warmup presets run in the replacements
+	Warmup *warmupResult `json:"warmup,omitempty"`
+}
+
+func generateHandleEnvPoolRebuild(conf *config.Config, work worker.Worker, envPool pool.Pool) func(*gin.Context) {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			abortNotAdmin(c)
+			return
+		}
+		var req envPoolRebuildRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				restexecutor.AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, err.Error())
+				return
+			}
+		}
+		if req.Prefork > maxRebuildPrefork {
+			req.Prefork = maxRebuildPrefork
+		}
+
+		st, err := envPool.Rebuild()
+		if err != nil {
+			logger.Sugar().Error("Environment pool rebuild failed: ", err)
+			restexecutor.AbortError(c, http.StatusInternalServerError, model.CodeInternal, err.Error())
+			return
+		}
+		rt := envPoolRebuildResult{Idle: st.Idle, InUse: st.InUse}
+		rt.Preforked, err = preforkReplacements(c.Request.Context(), envPool, req.Prefork)
+		if err != nil {
+			rt.PreforkError = err.Error()
+		}
+		if rt.Preforked > 0 {
+			rt.Warmup = warmupConfigured(conf, work, envPool)
+		}
+		logger.Sugar().Infof("Environment pool rebuilt: idle=%d, inUse=%d, preforked=%d", rt.Idle, rt.InUse, rt.Preforked)
+		c.JSON(http.StatusOK, rt)
+	}
+}
+
+type envDestroyResult struct {
+	InUse bool `json:"inUse"` // destroyed when put back
+}
+
+// generateHandleEnvDestroy destroys the environment by the environmentId
+// reported in the results without cycling the whole pool
+func generateHandleEnvDestroy(envPool pool.Pool) func(*gin.Context) {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			abortNotAdmin(c)
+			return
+		}
+		id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+		if err != nil {
+			restexecutor.AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, "invalid environment id",
+				model.FieldError{Field: "id", Message: err.Error()})
+			return
+		}
+		st := envPool.DestroyEnv(id)
+		if !st.Found {
+			restexecutor.AbortError(c, http.StatusNotFound, model.CodeNotFound, fmt.Sprintf("environment %d not found", id))
+			return
+		}
+		logger.Sugar().Infof("Environment %d destroyed by /admin/env: inUse=%v", id, st.InUse)
+		c.JSON(http.StatusOK, envDestroyResult{InUse: st.InUse})
+	}
+}
+
+// preforkReplacements creates up to n new environments into the pool and
+// returns the number created before the first failure
+func preforkReplacements(ctx context.Context, envPool pool.Pool, n int) (int, error) {
+	m := make([]envexec.Environment, 0, n)
+	defer func() {
+		for _, e := range m {
+			envPool.Put(e)
+		}
+	}()
+	for i := 0; i < n; i++ {
+		e, err := envPool.Get(ctx, worker.EnvironmentOptions{})
+		if err != nil {
+			return len(m), err
+		}
+		m = append(m, e)
+	}
+	return len(m), nil
+}
@@ -0,0 +1,185 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/criyle/go-judge/env"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+)
+
+// Config defines the config of the executor, the fields are the same as the
+// flags of the executor server without the server, logger and auth ones.
+// Unlike the flags, the zero values are not replaced by their defaults except
+// the ones documented, e.g. DefaultCPU 0 disables the default cpuLimit.
+type Config struct {
+	// sandbox
+	Sandbox            string // default (empty) / fake
+	ContainerInitPath  string
+	PreFork            int
+	TmpFsParam         string
+	NetShare           bool
+	MountConf          string
+	StaticContainer    bool
+	SeccompConf        string
+	VerdictConf        string
+	CgroupPrefix       string
+	CgroupMount        bool
+	CgroupRetry        int
+	CgroupRetryBackoff time.Duration
+	ContainerCredStart int
+	CredPinStart       int
+	CredPinCount       int
+	VerifyReset        bool
+	CheckFds           bool
+	RandomizeWorkDir   bool
+	Cpuset             string
+	NUMAAware          bool
+	EnableCPURate      bool
+	CPUCfsPeriod       time.Duration
+	InitLogSize        envexec.Size
+	MountCheckInterval time.Duration
+	Strict             bool
+
+	FakeTime   time.Duration
+	FakeMemory envexec.Size
+
+	ShmSize        envexec.Size
+	ShmSizeDefault envexec.Size
+	RandomSize     envexec.Size
+
+	Dataset          []string // name=path
+	Emulator         []string // arch=path
+	EmulatorBaseline bool
+
+	MountFilesDir      string
+	MountFilesCacheMax envexec.Size
+	AllowHostExec      []string
+
+	// DiskWorkdirPath and ScratchPath are under Dir if empty, or the system
+	// temp dir if Dir is empty
+	AllowDiskWorkdir bool
+	DiskWorkdirPath  string
+	DiskWorkdirMax   envexec.Size
+	AllowScratch     bool
+	ScratchPath      string
+	ScratchMax       envexec.Size
+
+	// worker, Parallelism is the number of cpu if not positive
+	Parallelism             int
+	AutoParallelism         bool
+	ParallelismMin          int
+	ParallelismMax          int
+	AutoParallelismInterval time.Duration
+
+	Scheduler     string // fifo (empty) / fair / sjf
+	PriorityAging time.Duration
+	TotalMemory   envexec.Size
+
+	IdempotencyTTL      time.Duration
+	IdempotencyCacheMax envexec.Size
+	UsageSaveInterval   time.Duration
+	QuotaPeriod         time.Duration
+
+	// InstanceID is the hostname if empty
+	InstanceID string
+
+	// file store, a temporary directory removed by Shutdown is used if Dir is
+	// empty
+	Dir         string
+	StoreSync   bool
+	FileTimeout time.Duration
+	ImportState string
+
+	// runner limit
+	TimeLimitCheckerInterval time.Duration
+	ExtraMemoryLimit         envexec.Size
+	OutputLimit              envexec.Size
+	CopyOutLimit             envexec.Size
+	CopyOutMaxFiles          int
+	CopyOutMaxTotal          envexec.Size
+	OpenFileLimit            int
+	ArchiveMaxCount          int
+	ArchiveMaxSize           envexec.Size
+	InlineContentMax         envexec.Size
+	StderrMax                envexec.Size
+	StderrCacheTruncated     bool
+	IOLimitStrict            bool
+	CoreFileMax              envexec.Size
+	SnapshotMax              envexec.Size
+	CollectorDir             string
+	EnableTrace              bool
+	ProcInfoCPU              int
+	CalibrateMax             int
+
+	MaxCPU         time.Duration
+	MaxClock       time.Duration
+	MaxMemory      envexec.Size
+	MaxProc        int
+	MaxEnv         int
+	LimitPolicy    string // reject (empty) / clamp
+	AllowUnlimited bool
+
+	DefaultCPU    time.Duration
+	DefaultClock  time.Duration
+	DefaultMemory envexec.Size
+	DefaultStack  envexec.Size
+	DefaultProc   int
+
+	NoisyNeighborMajorFaults int
+	NoisyNeighborWriteback   envexec.Size
+}
+
+// ArgLimit returns the limits of the args and env of the commands queried from
+// the host, the programs of -sandbox fake are not run in the container
+func (c *Config) ArgLimit() worker.ArgLimit {
+	max, strMax := env.ArgLimit(c.Sandbox != sandboxFake)
+	return worker.ArgLimit{
+		Max:    max,
+		StrMax: strMax,
+		EnvMax: c.MaxEnv,
+	}
+}
+
+// LimitMax returns the max limits of the commands
+func (c *Config) LimitMax() worker.LimitMax {
+	return worker.LimitMax{
+		CPU:    c.MaxCPU,
+		Clock:  c.MaxClock,
+		Memory: c.MaxMemory,
+		Proc:   uint64(c.MaxProc),
+	}
+}
+
+// LimitDefault returns the limits of the commands not setting them
+func (c *Config) LimitDefault() worker.LimitDefault {
+	return worker.LimitDefault{
+		CPU:    c.DefaultCPU,
+		Clock:  c.DefaultClock,
+		Memory: c.DefaultMemory,
+		Stack:  c.DefaultStack,
+		Proc:   uint64(c.DefaultProc),
+	}
+}
+
+// LimitClamp reports whether the limits above the max are clamped
+func (c *Config) LimitClamp() (bool, error) {
+	switch c.LimitPolicy {
+	case "", "reject":
+		return false, nil
+	case "clamp":
+		return true, nil
+	}
+	return false, fmt.Errorf("limit policy is not supported: %s", c.LimitPolicy)
+}
+
+// InstanceID returns the instance id, or hostname if empty
+func InstanceID(id string) string {
+	if id != "" {
+		return id
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
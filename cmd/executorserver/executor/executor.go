@@ -0,0 +1,725 @@
+// Package executor sets up the file store, the environment pool and the worker
+// of the executor server from its config, so that the executor could be
+// embedded into other programs without the HTTP and gRPC servers.
+//
+// On Linux the container init is started by re-executing the program itself
+// unless the container init path is configured, importing this package
+// registers the container init for the re-executed program.
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/criyle/go-judge/env"
+	"github.com/criyle/go-judge/env/fake"
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+	"go.uber.org/zap"
+)
+
+// usageFileName is the name of the usage file in the file store directory
+const usageFileName = filestore.StateFilePrefix + "-usage.json"
+
+// Values of Config.Sandbox
+const (
+	sandboxDefault = "default"
+	sandboxFake    = "fake"
+)
+
+// abortGrace is the time before the deadline of Shutdown to kill the running
+// requests, so that their results reporting the shutdown are still sent
+const abortGrace = time.Second
+
+// Options defines the hooks of the executor, the zero value is usable
+type Options struct {
+	// Logger is the logger of the executor, logs are discarded if nil
+	Logger *zap.Logger
+
+	// WrapFileStore wraps the local file store before the timeout and health check
+	WrapFileStore func(filestore.FileStore) filestore.FileStore
+	// WrapEnvBuilder wraps the environment builder, it is also used on reload
+	WrapEnvBuilder func(pool.EnvBuilder) pool.EnvBuilder
+	// WrapEnvPool wraps the environment pool used by the worker
+	WrapEnvPool func(pool.Pool) pool.Pool
+	// IsolationProbe detects the isolation features, the host is probed if nil
+	IsolationProbe env.IsolationProbe
+
+	// InitLogOverflow is called with the number of container init logs dropped
+	InitLogOverflow func(dropped int)
+	// OnStale is called when a stale environment is destroyed
+	OnStale func(error)
+
+	// Quotas, Policies and SchedulerShares are keyed by the file store namespace
+	Quotas          map[string]worker.Quota
+	Policies        map[string]worker.Policy
+	SchedulerShares map[string]int
+
+	ExecObserver   func(worker.Response)
+	AuditObserver  func(worker.AuditRecord)
+	LeakObserver   func(requestID string, leak envexec.Leak)
+	CancelObserver func(running bool)
+	PanicObserver  func(requestID string, value any, stack []byte)
+
+	// ParallelismObserver is called when -auto-parallelism changed the
+	// number of worker loops
+	ParallelismObserver func(from, to int, c worker.Contention, cpu float64)
+}
+
+// Executor runs the requests with the sandboxed environments
+type Executor struct {
+	conf   *Config
+	opt    Options
+	logger *zap.Logger
+
+	fs           filestore.FileStore
+	fsCleanUp    func() error
+	root         *env.ContainerRoot
+	envPool      pool.Pool
+	builderParam map[string]any
+	work         worker.Worker
+
+	done         chan struct{}
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// New creates the executor and starts its worker. The defaults of conf are
+// filled in the config returned by Config, e.g. the temporary file store
+// directory.
+func New(conf Config, opt Options) (*Executor, error) {
+	if conf.Parallelism <= 0 {
+		conf.Parallelism = runtime.NumCPU()
+	}
+	e := &Executor{
+		conf:   &conf,
+		opt:    opt,
+		logger: opt.Logger,
+		done:   make(chan struct{}),
+	}
+	if e.logger == nil {
+		e.logger = zap.NewNop()
+	}
+	if err := e.init(); err != nil {
+		e.close()
+		return nil, err
+	}
+	go e.usageSaveLoop()
+	go e.mountCheckLoop()
+	return e, nil
+}
+
+func (e *Executor) init() error {
+	conf := e.conf
+	if err := checkStaticContainer(conf); err != nil {
+		return err
+	}
+	if err := e.initFileStore(); err != nil {
+		return err
+	}
+	if conf.AllowDiskWorkdir && conf.DiskWorkdirPath == "" {
+		// the temporary file store directory could be in memory
+		if e.fsCleanUp == nil {
+			conf.DiskWorkdirPath = filepath.Join(conf.Dir, filestore.StateFilePrefix+"-workdir")
+		} else {
+			conf.DiskWorkdirPath = filepath.Join(os.TempDir(), "executorserver-workdir")
+		}
+	}
+	if conf.AllowScratch && conf.ScratchPath == "" {
+		if e.fsCleanUp == nil {
+			conf.ScratchPath = filepath.Join(conf.Dir, filestore.StateFilePrefix+"-scratch")
+		} else {
+			conf.ScratchPath = filepath.Join(os.TempDir(), "executorserver-scratch")
+		}
+	}
+	if err := e.initContainerRoot(); err != nil {
+		return err
+	}
+
+	b, param, err := e.NewEnvBuilder()
+	if err != nil {
+		return fmt.Errorf("create environment builder failed: %w", err)
+	}
+	if err := e.checkIsolation(param); err != nil {
+		return err
+	}
+	e.builderParam = param
+	e.envPool = e.newEnvPool(b)
+	if err := e.prefork(); err != nil {
+		return err
+	}
+
+	e.work, err = NewWorker(conf, e.envPool, e.fs, e.opt)
+	if err != nil {
+		return err
+	}
+	if conf.ImportState != "" {
+		if err := e.importStateFile(conf.ImportState); err != nil {
+			return err
+		}
+	}
+	e.work.Start()
+	return nil
+}
+
+// Run runs the request and waits for its result, the error of the response
+// is returned as error
+func (e *Executor) Run(ctx context.Context, req *worker.Request) (worker.Response, error) {
+	ch, _ := e.work.Submit(ctx, req)
+	rt := <-ch
+	return rt, rt.Error
+}
+
+// Shutdown waits for the running requests to finish, saves the usage and
+// removes the temporary file store and container root. The resources are
+// still released in background if ctx is done before the requests finished.
+// The requests still running shortly before the deadline of ctx are aborted
+// with the cancel reason shutdown.
+func (e *Executor) Shutdown(ctx context.Context) error {
+	if deadline, ok := ctx.Deadline(); ok {
+		t := time.AfterFunc(time.Until(deadline)-abortGrace, e.work.Abort)
+		defer t.Stop()
+	}
+	finished := make(chan struct{})
+	go func() {
+		e.shutdownOnce.Do(func() {
+			close(e.done)
+			e.work.Shutdown()
+			e.logger.Sugar().Info("Worker shutdown")
+			if e.conf.UsageSaveInterval > 0 {
+				e.shutdownErr = e.work.SaveUsage()
+			}
+			e.shutdownErr = errors.Join(e.shutdownErr, e.close())
+		})
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return e.shutdownErr
+	case <-ctx.Done():
+		e.work.Abort()
+		return ctx.Err()
+	}
+}
+
+// close removes the temporary file store and container root
+func (e *Executor) close() error {
+	var err error
+	if e.fsCleanUp != nil {
+		err = e.fsCleanUp()
+		e.logger.Sugar().Info("FileStore cleaned up")
+	}
+	if e.root != nil {
+		if rerr := e.root.Close(); rerr != nil {
+			e.logger.Sugar().Warn("Failed to remove container root: ", rerr)
+		}
+	}
+	return err
+}
+
+// Config returns the config of the executor with the defaults filled
+func (e *Executor) Config() Config {
+	return *e.conf
+}
+
+// FileStore returns the file store of the executor
+func (e *Executor) FileStore() filestore.FileStore {
+	return e.fs
+}
+
+// Worker returns the worker of the executor
+func (e *Executor) Worker() worker.Worker {
+	return e.work
+}
+
+// EnvPool returns the environment pool of the worker
+func (e *Executor) EnvPool() pool.Pool {
+	return e.envPool
+}
+
+// BuilderParam returns the runner config of the environment builder created
+// by New
+func (e *Executor) BuilderParam() map[string]any {
+	return e.builderParam
+}
+
+func (e *Executor) initFileStore() error {
+	const (
+		timeoutCheckInterval = 15 * time.Second
+		healthCheckInterval  = 5 * time.Second
+	)
+	conf := e.conf
+	if conf.Dir == "" {
+		if runtime.GOOS == "linux" {
+			conf.Dir = "/dev/shm"
+		} else {
+			conf.Dir = os.TempDir()
+		}
+		dir, err := os.MkdirTemp(conf.Dir, "executorserver")
+		if err != nil {
+			return fmt.Errorf("failed to create file store temp dir: %w", err)
+		}
+		conf.Dir = dir
+		e.fsCleanUp = func() error {
+			return os.RemoveAll(dir)
+		}
+		// usage is not kept in the temporary file store directory
+		conf.UsageSaveInterval = 0
+	}
+	os.MkdirAll(conf.Dir, 0755)
+	local := filestore.NewFileLocalStoreSync(conf.Dir, conf.StoreSync)
+	fs := local
+	if e.opt.WrapFileStore != nil {
+		fs = e.opt.WrapFileStore(fs)
+	}
+	if conf.FileTimeout > 0 {
+		fs = filestore.NewTimeout(fs, conf.FileTimeout, timeoutCheckInterval)
+	}
+	if checker, ok := local.(filestore.Checker); ok {
+		fs = filestore.NewHealth(fs, checker, healthCheckInterval)
+	}
+	e.fs = fs
+	return nil
+}
+
+// initContainerRoot removes the container roots left by crashed instances and
+// creates the one of this instance
+func (e *Executor) initContainerRoot() error {
+	removed, err := env.CleanContainerRoots("")
+	if err != nil {
+		e.logger.Sugar().Warn("Failed to remove stale container roots: ", err)
+	}
+	if len(removed) > 0 {
+		e.logger.Sugar().Info("Removed stale container roots: ", removed)
+	}
+	e.root, err = env.NewContainerRoot("")
+	if err != nil {
+		return fmt.Errorf("failed to create container root: %w", err)
+	}
+	return nil
+}
+
+// NewEnvBuilder creates the environment builder with the current config
+// files, it is used to reload the config
+func (e *Executor) NewEnvBuilder() (pool.EnvBuilder, map[string]any, error) {
+	conf := e.conf
+	if conf.Sandbox == sandboxFake {
+		return e.newFakeEnvBuilder()
+	}
+	if conf.Sandbox != "" && conf.Sandbox != sandboxDefault {
+		return nil, nil, fmt.Errorf("invalid sandbox %q: expected %s or %s", conf.Sandbox, sandboxDefault, sandboxFake)
+	}
+	datasets, err := parseDatasets(conf.Dataset)
+	if err != nil {
+		return nil, nil, err
+	}
+	emulators, err := parseEmulators(conf.Emulator)
+	if err != nil {
+		return nil, nil, err
+	}
+	ec := env.Config{
+		ContainerInitPath:  conf.ContainerInitPath,
+		ContainerRoot:      e.root.Path(),
+		MountConf:          conf.MountConf,
+		Static:             conf.StaticContainer,
+		TmpFsParam:         conf.TmpFsParam,
+		ShmSize:            conf.ShmSize.Byte(),
+		NetShare:           conf.NetShare,
+		CgroupPrefix:       conf.CgroupPrefix,
+		CgroupMount:        conf.CgroupMount,
+		Cpuset:             conf.Cpuset,
+		ContainerCredStart: conf.ContainerCredStart,
+		CredPinStart:       conf.CredPinStart,
+		CredPinCount:       conf.CredPinCount,
+		VerifyReset:        conf.VerifyReset,
+		CheckFds:           conf.CheckFds,
+		CgroupRetry:        conf.CgroupRetry,
+		CgroupRetryBackoff: conf.CgroupRetryBackoff,
+		EnableCPURate:      conf.EnableCPURate,
+		CPUCfsPeriod:       conf.CPUCfsPeriod,
+		SeccompConf:        conf.SeccompConf,
+		Datasets:           datasets,
+		Emulators:          emulators,
+		InitLogSize:        int(conf.InitLogSize.Byte()),
+		InitLogOverflow:    e.opt.InitLogOverflow,
+		RandomSize:         conf.RandomSize.Byte(),
+		Logger:             e.logger.Sugar(),
+		Probe:              e.opt.IsolationProbe,
+	}
+	b, param, err := env.NewBuilder(ec)
+	if err != nil {
+		return nil, nil, err
+	}
+	param["isolation"] = env.CheckIsolation(ec)
+	if conf.EmulatorBaseline {
+		baseline, errs := env.CalibrateEmulators(b)
+		for arch, err := range errs {
+			e.logger.Sugar().Warnf("Failed to calibrate the emulator of %s: %v", arch, err)
+		}
+		if len(baseline) > 0 {
+			e.logger.Sugar().Info("Emulator memory baseline: ", baseline)
+		}
+		param["emulatorBaseline"] = baseline
+	}
+	if e.opt.WrapEnvBuilder != nil {
+		b = e.opt.WrapEnvBuilder(b)
+	}
+	return b, param, nil
+}
+
+// newFakeEnvBuilder creates the builder of -sandbox fake, it reports no
+// isolation so that the strict mode refuses it
+func (e *Executor) newFakeEnvBuilder() (pool.EnvBuilder, map[string]any, error) {
+	conf := e.conf
+	var b pool.EnvBuilder = fake.NewBuilder(fake.Config{
+		Dir:    e.root.Path(),
+		Time:   conf.FakeTime,
+		Memory: conf.FakeMemory,
+	})
+	e.logger.Sugar().Warn("Using fake sandbox, programs run as child processes without isolation")
+	param := map[string]any{
+		"sandbox": sandboxFake,
+		"isolation": env.IsolationReport{{
+			Name:   "sandbox",
+			Detail: "fake sandbox provides no isolation",
+		}},
+	}
+	if e.opt.WrapEnvBuilder != nil {
+		b = e.opt.WrapEnvBuilder(b)
+	}
+	return b, param, nil
+}
+
+// checkIsolation reports the isolation features requested but not active,
+// it fails in strict mode
+func (e *Executor) checkIsolation(param map[string]any) error {
+	report, _ := param["isolation"].(env.IsolationReport)
+	degraded := report.Degraded()
+	if len(degraded) == 0 {
+		return nil
+	}
+	if e.conf.Strict {
+		return fmt.Errorf("isolation features are not active in strict mode: %v\n%s", degraded, report)
+	}
+	e.logger.Sugar().Warnf("Isolation features are not active: %v\n%s", degraded, report)
+	return nil
+}
+
+func (e *Executor) newEnvPool(b pool.EnvBuilder) pool.Pool {
+	p := pool.NewPool(b)
+	if e.conf.NUMAAware {
+		p = e.newNUMAEnvPool(b)
+	}
+	if e.opt.OnStale != nil {
+		p.OnStale(e.opt.OnStale)
+	}
+	if e.opt.WrapEnvPool != nil {
+		p = e.opt.WrapEnvPool(p)
+	}
+	return p
+}
+
+// newNUMAEnvPool shards the environments by the NUMA nodes, it falls back to
+// the single pool on single node machines or when the nodes are not available
+func (e *Executor) newNUMAEnvPool(b pool.EnvBuilder) pool.Pool {
+	nodes, err := env.NUMANodes(e.conf.Cpuset)
+	if err != nil {
+		e.logger.Sugar().Warn("Failed to detect NUMA nodes: ", err)
+	}
+	p := pool.NewNUMAPool(b, nodes)
+	if np, ok := p.(pool.NUMAPool); ok {
+		e.logger.Sugar().Infof("Environments sharded by NUMA node: %v", nodes)
+		return np
+	}
+	e.logger.Sugar().Debugf("NUMA sharding is not used with nodes: %v", nodes)
+	return p
+}
+
+func (e *Executor) prefork() error {
+	n := e.conf.PreFork
+	if n <= 0 {
+		return nil
+	}
+	e.logger.Sugar().Info("create ", n, " prefork containers")
+	envs := make([]envexec.Environment, 0, n)
+	defer func() {
+		for _, en := range envs {
+			e.envPool.Put(en)
+		}
+	}()
+	for i := 0; i < n; i++ {
+		en, err := e.envPool.Get(context.Background(), worker.EnvironmentOptions{})
+		if err != nil {
+			return fmt.Errorf("prefork environment failed: %w", err)
+		}
+		envs = append(envs, en)
+	}
+	return nil
+}
+
+// usageSaveLoop saves the usage of the worker periodically
+func (e *Executor) usageSaveLoop() {
+	if e.conf.UsageSaveInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(e.conf.UsageSaveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+		}
+		if err := e.work.SaveUsage(); err != nil {
+			e.logger.Sugar().Error("Failed to save usage: ", err)
+		}
+	}
+}
+
+// mountCheckLoop replaces the idle environments with stale mounts in
+// background, so that the requests after the host upgrade do not wait for new
+// environments to be created
+func (e *Executor) mountCheckLoop() {
+	if e.conf.MountCheckInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(e.conf.MountCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-e.done:
+			return
+		case <-ticker.C:
+		}
+		n := e.envPool.DestroyStale()
+		if n == 0 {
+			continue
+		}
+		envs := make([]envexec.Environment, 0, n)
+		for i := 0; i < n; i++ {
+			en, err := e.envPool.Get(context.Background(), worker.EnvironmentOptions{})
+			if err != nil {
+				e.logger.Sugar().Warn("Failed to replace stale environment: ", err)
+				break
+			}
+			envs = append(envs, en)
+		}
+		for _, en := range envs {
+			e.envPool.Put(en)
+		}
+		e.logger.Sugar().Infof("Replaced %d of %d stale environments", len(envs), n)
+	}
+}
+
+// NewWorker creates the worker with the config, the worker is not started
+func NewWorker(conf *Config, envPool worker.EnvironmentPool, fs filestore.FileStore, opt Options) (worker.Worker, error) {
+	var scheduler worker.Scheduler
+	switch conf.Scheduler {
+	case "", "fifo":
+		scheduler = worker.SchedulerFIFO
+	case "fair":
+		scheduler = worker.SchedulerFair
+	case "sjf":
+		if conf.PriorityAging <= 0 {
+			return nil, fmt.Errorf("sjf scheduler requires priority aging to avoid starvation")
+		}
+		scheduler = worker.SchedulerSJF
+	default:
+		return nil, fmt.Errorf("scheduler is not supported: %s", conf.Scheduler)
+	}
+	auto, err := autoParallelism(conf)
+	if err != nil {
+		return nil, err
+	}
+	limitClamp, err := conf.LimitClamp()
+	if err != nil {
+		return nil, err
+	}
+	verdicts, err := readVerdictConf(conf.VerdictConf)
+	if err != nil {
+		return nil, err
+	}
+	if conf.DefaultProc < 0 {
+		return nil, fmt.Errorf("negative default proc %d", conf.DefaultProc)
+	}
+	if conf.NoisyNeighborMajorFaults < 0 {
+		return nil, fmt.Errorf("negative noisy neighbor major faults %d", conf.NoisyNeighborMajorFaults)
+	}
+	return worker.New(worker.Config{
+		FileStore:             fs,
+		EnvironmentPool:       envPool,
+		Parallelism:           conf.Parallelism,
+		WorkDir:               conf.Dir,
+		TimeLimitTickInterval: conf.TimeLimitCheckerInterval,
+		ExtraMemoryLimit:      conf.ExtraMemoryLimit,
+		OutputLimit:           conf.OutputLimit,
+		CopyOutLimit:          conf.CopyOutLimit,
+		CopyOutMaxFiles:       conf.CopyOutMaxFiles,
+		CopyOutMaxTotal:       conf.CopyOutMaxTotal,
+		RandomizeWorkDir:      conf.RandomizeWorkDir,
+		OpenFileLimit:         uint64(conf.OpenFileLimit),
+		ArchiveMaxCount:       conf.ArchiveMaxCount,
+		ArchiveMaxSize:        conf.ArchiveMaxSize,
+		InlineContentMax:      conf.InlineContentMax,
+		StderrMax:             conf.StderrMax,
+		StderrCacheTruncated:  conf.StderrCacheTruncated,
+		IOLimitStrict:         conf.IOLimitStrict,
+		CoreFileMax:           conf.CoreFileMax,
+		EnableTrace:           conf.EnableTrace,
+		ProcInfoCPUs:          uint64(conf.ProcInfoCPU),
+		ShmSizeMax:            conf.ShmSize,
+		ShmSizeDefault:        conf.ShmSizeDefault,
+		LimitMax:              conf.LimitMax(),
+		LimitClamp:            limitClamp,
+		LimitDefault:          conf.LimitDefault(),
+		AllowUnlimited:        conf.AllowUnlimited,
+		RepeatMax:             conf.CalibrateMax,
+		SnapshotMax:           conf.SnapshotMax,
+		ArgLimit:              conf.ArgLimit(),
+		CollectorDir:          conf.CollectorDir,
+		NoisyNeighbor:         worker.NoisyNeighbor{MajorFaults: uint64(conf.NoisyNeighborMajorFaults), Writeback: conf.NoisyNeighborWriteback},
+		Verdicts:              verdicts,
+		MountFilesDir:         conf.MountFilesDir,
+		MountFilesCacheMax:    conf.MountFilesCacheMax,
+		HostExecDirs:          conf.AllowHostExec,
+		DiskWorkDir:           diskWorkDir(conf),
+		DiskWorkDirMax:        conf.DiskWorkdirMax,
+		ScratchDir:            scratchDir(conf),
+		ScratchMax:            conf.ScratchMax,
+		IdempotencyTTL:        conf.IdempotencyTTL,
+		IdempotencyCacheMax:   conf.IdempotencyCacheMax,
+		UsageFile:             usageFile(conf),
+		Quotas:                opt.Quotas,
+		Policies:              opt.Policies,
+		QuotaPeriod:           conf.QuotaPeriod,
+		InstanceID:            InstanceID(conf.InstanceID),
+		ExecObserver:          opt.ExecObserver,
+		AuditObserver:         opt.AuditObserver,
+		LeakObserver:          opt.LeakObserver,
+		CancelObserver:        opt.CancelObserver,
+		PanicObserver:         opt.PanicObserver,
+		Scheduler:             scheduler,
+		SchedulerShares:       opt.SchedulerShares,
+		PriorityAging:         conf.PriorityAging,
+		TotalMemory:           conf.TotalMemory,
+		AutoParallelism:       auto,
+		ParallelismObserver:   opt.ParallelismObserver,
+	}), nil
+}
+
+// autoParallelism returns the bounds of -auto-parallelism, the initial
+// parallelism must be within
+func autoParallelism(conf *Config) (worker.AutoParallelism, error) {
+	if !conf.AutoParallelism {
+		return worker.AutoParallelism{}, nil
+	}
+	if conf.ParallelismMin < 1 || conf.ParallelismMax < conf.ParallelismMin {
+		return worker.AutoParallelism{}, fmt.Errorf("invalid auto parallelism bounds [%d, %d]", conf.ParallelismMin, conf.ParallelismMax)
+	}
+	if conf.Parallelism < conf.ParallelismMin || conf.Parallelism > conf.ParallelismMax {
+		return worker.AutoParallelism{}, fmt.Errorf("parallelism %d is not within the auto parallelism bounds [%d, %d]", conf.Parallelism, conf.ParallelismMin, conf.ParallelismMax)
+	}
+	return worker.AutoParallelism{
+		Min:      conf.ParallelismMin,
+		Max:      conf.ParallelismMax,
+		Interval: conf.AutoParallelismInterval,
+	}, nil
+}
+
+// checkStaticContainer rejects the options of -static-container which bind
+// host paths into the container or run the toolchains of the host
+func checkStaticContainer(conf *Config) error {
+	if !conf.StaticContainer {
+		return nil
+	}
+	for _, o := range []struct {
+		name string
+		set  bool
+	}{
+		{"dataset", len(conf.Dataset) > 0},
+		{"emulator", len(conf.Emulator) > 0},
+		{"allow-host-exec", len(conf.AllowHostExec) > 0},
+		{"mount-files-dir", conf.MountFilesDir != ""},
+		{"allow-disk-workdir", conf.AllowDiskWorkdir},
+		{"allow-scratch", conf.AllowScratch},
+	} {
+		if o.set {
+			return fmt.Errorf("-%s could not be used with -static-container", o.name)
+		}
+	}
+	return nil
+}
+
+// diskWorkDir returns the directory of diskWorkDir, empty if not allowed
+func diskWorkDir(conf *Config) string {
+	if !conf.AllowDiskWorkdir {
+		return ""
+	}
+	return conf.DiskWorkdirPath
+}
+
+// scratchDir returns the directory of scratchFile, empty if not allowed
+func scratchDir(conf *Config) string {
+	if !conf.AllowScratch {
+		return ""
+	}
+	return conf.ScratchPath
+}
+
+// usageFile returns the path of the usage file, empty if it is not saved
+func usageFile(conf *Config) string {
+	if conf.UsageSaveInterval <= 0 {
+		return ""
+	}
+	return filepath.Join(conf.Dir, usageFileName)
+}
+
+// parseEmulators parses the arch=path of emulators
+func parseEmulators(s []string) (map[string]string, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	rt := make(map[string]string, len(s))
+	for _, d := range s {
+		arch, p, ok := strings.Cut(d, "=")
+		if !ok || !strings.HasPrefix(p, "/") {
+			return nil, fmt.Errorf("invalid emulator (expected arch=/path/in/container): %s", d)
+		}
+		if !envexec.KnownArch(arch) {
+			return nil, fmt.Errorf("invalid emulator arch %q", arch)
+		}
+		if _, ok := rt[arch]; ok {
+			return nil, fmt.Errorf("duplicated emulator: %s", arch)
+		}
+		rt[arch] = p
+	}
+	return rt, nil
+}
+
+// parseDatasets parses the name=path of datasets
+func parseDatasets(s []string) (map[string]string, error) {
+	if len(s) == 0 {
+		return nil, nil
+	}
+	rt := make(map[string]string, len(s))
+	for _, d := range s {
+		name, dir, ok := strings.Cut(d, "=")
+		if !ok || name == "" || dir == "" {
+			return nil, fmt.Errorf("invalid dataset (expected name=path): %s", d)
+		}
+		if _, ok := rt[name]; ok {
+			return nil, fmt.Errorf("duplicated dataset: %s", name)
+		}
+		rt[name] = dir
+	}
+	return rt, nil
+}
@@ -0,0 +1,52 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/criyle/go-judge/env"
+)
+
+// missingProbe simulates the host without the user namespace
+type missingProbe struct{}
+
+func (missingProbe) CgroupControllers(string) (map[string]bool, error) {
+	return map[string]bool{"cpuacct": true, "memory": true, "pids": true, "cpuset": true}, nil
+}
+
+func (missingProbe) Namespace(name string) error {
+	if name == "user" {
+		return errors.New("unsupported")
+	}
+	return nil
+}
+
+func (missingProbe) Root() bool            { return true }
+func (missingProbe) SwapAccounting() error { return nil }
+func (missingProbe) Seccomp() error        { return nil }
+
+func TestStrictIsolation(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("not running as root")
+	}
+	// the missing feature is only warned without the strict mode
+	conf := Config{Dir: t.TempDir()}
+	e, err := New(conf, Options{IsolationProbe: missingProbe{}})
+	if err != nil {
+		t.Skip("sandbox is not available: ", err)
+	}
+	r, _ := e.builderParam["isolation"].(env.IsolationReport)
+	e.Shutdown(context.Background())
+	if d := r.Degraded(); len(d) != 1 || d[0] != "namespace user" {
+		t.Fatalf("degraded %v, want the user namespace\n%s", d, r)
+	}
+
+	conf = Config{Dir: t.TempDir(), Strict: true}
+	_, err = New(conf, Options{IsolationProbe: missingProbe{}})
+	if err == nil || !strings.Contains(err.Error(), "[ ] namespace user: unsupported") {
+		t.Fatalf("got %v, want the missing namespace refused", err)
+	}
+}
@@ -0,0 +1,178 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+)
+
+// newTestExecutor creates the executor running the programs in the fake
+// sandbox, it is shut down after the test
+func newTestExecutor(t *testing.T, conf Config) *Executor {
+	t.Helper()
+	conf.Sandbox = sandboxFake
+	conf.FakeTime = time.Millisecond
+	conf.DefaultCPU = time.Second
+	conf.DefaultMemory = 64 << 20
+	conf.DefaultProc = 16
+	e, err := New(conf, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { e.Shutdown(context.Background()) })
+	return e
+}
+
+// echoRequest echoes the string by /bin/sh with stdout collected
+func echoRequest(s string) *worker.Request {
+	return &worker.Request{Cmd: []worker.Cmd{{
+		Args: []string{"/bin/sh", "-c", "echo " + s},
+		Env:  []string{"PATH=/usr/bin:/bin"},
+		Files: []worker.CmdFile{
+			&worker.MemoryFile{Content: []byte{}},
+			&worker.Collector{Name: "stdout", Max: 1 << 10},
+			&worker.Collector{Name: "stderr", Max: 1 << 10},
+		},
+		CopyOut: []worker.CmdCopyOutFile{{Name: "stdout"}},
+	}}}
+}
+
+// stdout reads the stdout of the only result and closes its files
+func stdout(t *testing.T, rt worker.Response) string {
+	t.Helper()
+	if len(rt.Results) != 1 {
+		t.Fatalf("got %d results, want 1", len(rt.Results))
+	}
+	r := rt.Results[0]
+	defer func() {
+		for _, f := range r.Files {
+			f.Close()
+		}
+	}()
+	if r.Status != envexec.StatusAccepted {
+		t.Fatalf("status %v: %s", r.Status, r.Error)
+	}
+	f, ok := r.Files["stdout"]
+	if !ok {
+		t.Fatal("stdout is not copied out")
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRunConcurrently(t *testing.T) {
+	e := newTestExecutor(t, Config{Dir: t.TempDir(), Parallelism: 4})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			rt, err := e.Run(context.Background(), echoRequest(fmt.Sprint(i)))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if out := stdout(t, rt); out != fmt.Sprintln(i) {
+				t.Errorf("run %d: stdout %q", i, out)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestTemporaryFileStore(t *testing.T) {
+	e := newTestExecutor(t, Config{Parallelism: 1})
+	dir := e.Config().Dir
+	if dir == "" {
+		t.Fatal("file store directory is not filled")
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Fatal(err)
+	}
+	if e.Config().UsageSaveInterval != 0 {
+		t.Error("usage is saved into the temporary file store")
+	}
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("temporary file store %s is not removed: %v", dir, err)
+	}
+}
+
+func TestRunAfterShutdown(t *testing.T) {
+	e := newTestExecutor(t, Config{Dir: t.TempDir()})
+	if e.Config().Parallelism <= 0 {
+		t.Errorf("parallelism %d is not filled", e.Config().Parallelism)
+	}
+	if err := e.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Run(context.Background(), echoRequest("a")); !errors.Is(err, worker.ErrShutdown) {
+		t.Fatalf("got %v, want %v", err, worker.ErrShutdown)
+	}
+}
+
+func TestNewInvalidConfig(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		conf Config
+		err  string
+	}{
+		{"sandbox", Config{Sandbox: "other"}, "invalid sandbox"},
+		{"limit policy", Config{Sandbox: sandboxFake, LimitPolicy: "other"}, "limit policy"},
+		{"scheduler", Config{Sandbox: sandboxFake, Scheduler: "other"}, "scheduler"},
+		{"sjf without aging", Config{Sandbox: sandboxFake, Scheduler: "sjf"}, "priority aging"},
+		{"auto parallelism", Config{Sandbox: sandboxFake, AutoParallelism: true, Parallelism: 4, ParallelismMin: 1, ParallelismMax: 2}, "not within"},
+		{"static container", Config{StaticContainer: true, Dataset: []string{"a=/a"}}, "-dataset"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			c.conf.Dir = t.TempDir()
+			e, err := New(c.conf, Options{})
+			if err == nil {
+				e.Shutdown(context.Background())
+				t.Fatal("no error")
+			}
+			if !strings.Contains(err.Error(), c.err) {
+				t.Fatalf("error %q, want %q", err, c.err)
+			}
+		})
+	}
+}
+
+func TestConfigLimits(t *testing.T) {
+	conf := Config{
+		MaxCPU:        2 * time.Second,
+		MaxMemory:     256 << 20,
+		MaxProc:       32,
+		DefaultCPU:    time.Second,
+		DefaultMemory: 128 << 20,
+		DefaultProc:   16,
+		LimitPolicy:   "clamp",
+	}
+	if got, want := conf.LimitMax(), (worker.LimitMax{CPU: 2 * time.Second, Memory: 256 << 20, Proc: 32}); got != want {
+		t.Errorf("max %+v, want %+v", got, want)
+	}
+	if got, want := conf.LimitDefault(), (worker.LimitDefault{CPU: time.Second, Memory: 128 << 20, Proc: 16}); got != want {
+		t.Errorf("default %+v, want %+v", got, want)
+	}
+	if clamp, err := conf.LimitClamp(); err != nil || !clamp {
+		t.Errorf("clamp %v %v, want true", clamp, err)
+	}
+}
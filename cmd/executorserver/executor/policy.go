@@ -0,0 +1,76 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+	"gopkg.in/yaml.v2"
+)
+
+// policy is the policy of an auth token in the policy config, features are
+// named as in worker.PolicyFeatures and sizes have units (e.g. 256m)
+type policy struct {
+	Features []string `yaml:"features"`
+	Profiles []string `yaml:"profiles"`
+
+	CPULimit    time.Duration `yaml:"cpuLimit"`
+	ClockLimit  time.Duration `yaml:"clockLimit"`
+	MemoryLimit string        `yaml:"memoryLimit"`
+	ProcLimit   uint64        `yaml:"procLimit"`
+}
+
+// ReadPolicyConf reads the policies of the auth tokens from the config file
+// keyed by the token, no policy is configured if the file does not exist
+func ReadPolicyConf(p string) (map[string]worker.Policy, error) {
+	d, err := os.ReadFile(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var c map[string]policy
+	if err := yaml.UnmarshalStrict(d, &c); err != nil {
+		return nil, fmt.Errorf("policy config %s: %v", p, err)
+	}
+	features := make(map[string]bool, len(worker.PolicyFeatures))
+	for _, f := range worker.PolicyFeatures {
+		features[f] = true
+	}
+	rt := make(map[string]worker.Policy, len(c))
+	for token, pc := range c {
+		po := worker.Policy{
+			Features: make(map[string]bool, len(pc.Features)),
+			Profiles: make(map[string]bool, len(pc.Profiles)),
+			LimitMax: worker.LimitMax{
+				CPU:   pc.CPULimit,
+				Clock: pc.ClockLimit,
+				Proc:  pc.ProcLimit,
+			},
+		}
+		for _, f := range pc.Features {
+			if !features[f] {
+				return nil, fmt.Errorf("policy config %s: unknown feature %q (available: %v)", p, f, worker.PolicyFeatures)
+			}
+			po.Features[f] = true
+		}
+		for _, n := range pc.Profiles {
+			po.Profiles[n] = true
+		}
+		if pc.MemoryLimit != "" {
+			var m envexec.Size
+			if err := m.Set(pc.MemoryLimit); err != nil {
+				return nil, fmt.Errorf("policy config %s: invalid memoryLimit %q: %v", p, pc.MemoryLimit, err)
+			}
+			po.LimitMax.Memory = m
+		}
+		if po.LimitMax.CPU < 0 || po.LimitMax.Clock < 0 {
+			return nil, fmt.Errorf("policy config %s: negative limit", p)
+		}
+		rt[token] = po
+	}
+	return rt, nil
+}
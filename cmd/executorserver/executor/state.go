@@ -0,0 +1,194 @@
+package executor
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+)
+
+// StateVersion is the version of State, states of other versions are never
+// imported
+const StateVersion = 1
+
+// maxStateFileErrors limits the files reported by a failed import
+const maxStateFileErrors = 10
+
+// State defines the snapshot exported by /admin/export for a new instance
+// sharing the file store directory. The content of the files is not included.
+type State struct {
+	Version    int       `json:"version"`
+	CreatedAt  time.Time `json:"createdAt"`
+	InstanceID string    `json:"instanceId,omitempty"`
+	Dir        string    `json:"dir"`
+
+	// Files are the metadata of the files in Dir by id
+	Files map[string]FileState `json:"files"`
+
+	// Config is the sha256 of the config files by their flag names, so that
+	// the differences of the new instance are reported
+	Config map[string]string `json:"config,omitempty"`
+
+	worker.State
+}
+
+// FileState defines the metadata of a file in the file store
+type FileState struct {
+	filestore.FileMeta
+	Size int64 `json:"size"`
+}
+
+// ImportStat reports the imported state
+type ImportStat struct {
+	Files       int      `json:"files"`
+	Usage       int      `json:"usage"`
+	Idempotency int      `json:"idempotency"`
+	Config      []string `json:"config,omitempty"` // config files differ from the exported ones
+}
+
+// ExportState returns the snapshot of the file store metadata, the usage and
+// the stored responses of the idempotency keys
+func (e *Executor) ExportState() (*State, error) {
+	if e.fsCleanUp != nil {
+		return nil, errors.New("export: the file store is in a temporary directory which is not shared with other instances")
+	}
+	dir, err := filepath.Abs(e.conf.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("export: %w", err)
+	}
+	rt := &State{
+		Version:    StateVersion,
+		CreatedAt:  time.Now(),
+		InstanceID: InstanceID(e.conf.InstanceID),
+		Dir:        dir,
+		Files:      make(map[string]FileState),
+		Config:     configHashes(e.conf.MountConf, e.conf.SeccompConf, e.conf.VerdictConf),
+		State:      e.work.ExportState(),
+	}
+	for id, m := range e.fs.ListMeta() {
+		fi, err := os.Stat(filepath.Join(dir, id))
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		rt.Files[id] = FileState{FileMeta: m, Size: fi.Size()}
+	}
+	return rt, nil
+}
+
+// ImportState restores the state exported by another instance sharing the
+// file store directory. Nothing is imported unless the version, directory and
+// all the files match.
+func (e *Executor) ImportState(s *State) (ImportStat, error) {
+	if s.Version != StateVersion {
+		return ImportStat{}, fmt.Errorf("import: state version %d is not supported, expected %d", s.Version, StateVersion)
+	}
+	if e.fsCleanUp != nil {
+		return ImportStat{}, errors.New("import: -dir is not specified, the state is only imported into the file store directory it was exported from")
+	}
+	dir, err := filepath.Abs(e.conf.Dir)
+	if err != nil {
+		return ImportStat{}, fmt.Errorf("import: %w", err)
+	}
+	if dir != s.Dir {
+		return ImportStat{}, fmt.Errorf("import: state is exported from -dir %s, not %s", s.Dir, dir)
+	}
+	if len(s.Idempotency) > 0 && e.conf.IdempotencyTTL <= 0 {
+		return ImportStat{}, errors.New("import: responses of idempotency keys could not be imported since -idempotency-ttl is 0")
+	}
+	if err := checkStateFiles(dir, s.Files); err != nil {
+		return ImportStat{}, err
+	}
+
+	for id, f := range s.Files {
+		if err := filestore.ImportMeta(e.fs, id, f.FileMeta, f.Size); err != nil {
+			return ImportStat{}, err
+		}
+	}
+	if err := e.work.ImportState(s.State); err != nil {
+		return ImportStat{}, err
+	}
+	rt := ImportStat{
+		Files:       len(s.Files),
+		Usage:       len(s.Usage),
+		Idempotency: len(s.Idempotency),
+	}
+	current := configHashes(e.conf.MountConf, e.conf.SeccompConf, e.conf.VerdictConf)
+	for name, h := range s.Config {
+		if current[name] != h {
+			rt.Config = append(rt.Config, name)
+		}
+	}
+	sort.Strings(rt.Config)
+	return rt, nil
+}
+
+// importStateFile imports the state from the file of -import-state
+func (e *Executor) importStateFile(p string) error {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	var s State
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("import: invalid state %s: %w", p, err)
+	}
+	st, err := e.ImportState(&s)
+	if err != nil {
+		return err
+	}
+	e.logger.Sugar().Infof("Imported state of %s exported at %s: files=%d, usage=%d, idempotency=%d",
+		s.InstanceID, s.CreatedAt.Format(time.RFC3339), st.Files, st.Usage, st.Idempotency)
+	if len(st.Config) > 0 {
+		e.logger.Sugar().Warn("Config files differ from the exported state: ", st.Config)
+	}
+	return nil
+}
+
+// checkStateFiles checks all the files exist in dir with the exported size
+func checkStateFiles(dir string, files map[string]FileState) error {
+	var errs []error
+	for id, f := range files {
+		if filepath.Base(id) != id {
+			errs = append(errs, fmt.Errorf("invalid file id %q", id))
+		} else if fi, err := os.Stat(filepath.Join(dir, id)); err != nil {
+			errs = append(errs, err)
+		} else if !fi.Mode().IsRegular() || fi.Size() != f.Size {
+			errs = append(errs, fmt.Errorf("file %s is %d bytes, expected %d", id, fi.Size(), f.Size))
+		}
+		if len(errs) == maxStateFileErrors {
+			break
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("import: files do not match the state: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// configHashes returns the sha256 of the config files by their flag names,
+// empty for the missing ones
+func configHashes(mountConf, seccompConf, verdictConf string) map[string]string {
+	rt := make(map[string]string)
+	for name, p := range map[string]string{
+		"mount-conf":   mountConf,
+		"seccomp-conf": seccompConf,
+		"verdict-conf": verdictConf,
+	} {
+		b, err := os.ReadFile(p)
+		if err != nil {
+			rt[name] = ""
+			continue
+		}
+		h := sha256.Sum256(b)
+		rt[name] = hex.EncodeToString(h[:])
+	}
+	return rt
+}
@@ -0,0 +1,90 @@
+package executor
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+	"gopkg.in/yaml.v2"
+)
+
+// verdictRule is the verdict rule in the verdict config, statuses are named
+// as in the results (e.g. Nonzero Exit Status)
+type verdictRule struct {
+	Status     []string `yaml:"status"`
+	ExitStatus []int    `yaml:"exitStatus"`
+	Signal     []int    `yaml:"signal"`
+
+	SetStatus string `yaml:"setStatus"`
+	ClampTime bool   `yaml:"clampTime"`
+	Message   string `yaml:"message"`
+}
+
+// readVerdictConf reads the named verdict rules from the config file, no rule
+// is configured if the file does not exist
+func readVerdictConf(p string) (map[string][]worker.VerdictRule, error) {
+	d, err := os.ReadFile(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var c map[string][]verdictRule
+	if err := yaml.UnmarshalStrict(d, &c); err != nil {
+		return nil, fmt.Errorf("verdict config %s: %v", p, err)
+	}
+	rt := make(map[string][]worker.VerdictRule, len(c))
+	for name, rules := range c {
+		if name == "" {
+			return nil, fmt.Errorf("verdict config %s: empty name", p)
+		}
+		// the set without rules selects the results untouched
+		s := make([]worker.VerdictRule, 0, len(rules))
+		for i, r := range rules {
+			vr, err := parseVerdictRule(r)
+			if err != nil {
+				return nil, fmt.Errorf("verdict config %s: %s[%d]: %v", p, name, i, err)
+			}
+			s = append(s, vr)
+		}
+		rt[name] = s
+	}
+	return rt, nil
+}
+
+func parseVerdictRule(r verdictRule) (worker.VerdictRule, error) {
+	rt := worker.VerdictRule{
+		ExitStatus: r.ExitStatus,
+		Signal:     r.Signal,
+		ClampTime:  r.ClampTime,
+		Message:    r.Message,
+	}
+	for _, s := range r.Status {
+		st, err := parseVerdictStatus(s)
+		if err != nil {
+			return rt, err
+		}
+		rt.Status = append(rt.Status, st)
+	}
+	if r.SetStatus != "" {
+		st, err := parseVerdictStatus(r.SetStatus)
+		if err != nil {
+			return rt, err
+		}
+		rt.SetStatus = st
+	}
+	if rt.SetStatus == envexec.StatusInvalid && !rt.ClampTime && rt.Message == "" {
+		return rt, fmt.Errorf("rule has none of setStatus, clampTime and message")
+	}
+	return rt, nil
+}
+
+func parseVerdictStatus(s string) (envexec.Status, error) {
+	st := envexec.StatusFromName(s)
+	if st == envexec.StatusUnknown || st == envexec.StatusInvalid {
+		return st, fmt.Errorf("invalid status %q", s)
+	}
+	return st, nil
+}
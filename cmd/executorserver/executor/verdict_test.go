@@ -0,0 +1,65 @@
+package executor
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+)
+
+func TestReadVerdictConf(t *testing.T) {
+	dir := t.TempDir()
+	if v, err := readVerdictConf(filepath.Join(dir, "missing.yaml")); v != nil || err != nil {
+		t.Fatalf("got %v %v, want no rule without the file", v, err)
+	}
+
+	p := filepath.Join(dir, "verdict.yaml")
+	write := func(s string) {
+		t.Helper()
+		if err := os.WriteFile(p, []byte(s), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the order of the rules is kept for the precedence
+	write(`
+course:
+  - exitStatus: [3]
+    setStatus: Wrong Answer
+  - status: [Nonzero Exit Status, Signalled]
+    message: runtime error
+empty: []
+`)
+	v, err := readVerdictConf(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]worker.VerdictRule{
+		"course": {
+			{ExitStatus: []int{3}, SetStatus: envexec.StatusWrongAnswer},
+			{Status: []envexec.Status{envexec.StatusNonzeroExitStatus, envexec.StatusSignalled}, Message: "runtime error"},
+		},
+		"empty": {},
+	}
+	if !reflect.DeepEqual(v, want) {
+		t.Fatalf("got %+v, want %+v", v, want)
+	}
+
+	for _, c := range []struct {
+		conf string
+		err  string
+	}{
+		{"course:\n  - exitCode: [3]\n    message: x\n", "exitCode"},
+		{"course:\n  - status: [Runtime Error]\n    message: x\n", `invalid status "Runtime Error"`},
+		{"course:\n  - setStatus: Wrong\n", `invalid status "Wrong"`},
+		{"course:\n  - exitStatus: [3]\n", "course[0]: rule has none of"},
+	} {
+		write(c.conf)
+		if _, err := readVerdictConf(p); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%q: got %v, want %s", c.conf, err, c.err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package grpcexecutor
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
@@ -45,6 +46,10 @@ type execServer struct {
 }
 
 func (e *execServer) Exec(ctx context.Context, req *pb.Request) (*pb.Response, error) {
+	// fail fast since runs are doomed to fail
+	if err := filestore.Available(e.fs); err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
 	r, si, so, err := convertPBRequest(req, e.srcPrefix)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, err.Error())
@@ -57,6 +62,33 @@ func (e *execServer) Exec(ctx context.Context, req *pb.Request) (*pb.Response, e
 	rt := <-rtCh
 	e.logger.Sugar().Debugf("response: %+v", rt)
 	if rt.Error != nil {
+		var envErr *worker.EnvironmentError
+		switch {
+		case errors.Is(rt.Error, worker.ErrInvalidCredential), errors.Is(rt.Error, worker.ErrInvalidDataset),
+			errors.Is(rt.Error, worker.ErrInvalidProfile), errors.Is(rt.Error, worker.ErrIOLimitNotSupported),
+			errors.Is(rt.Error, worker.ErrCollectCoreDisabled), errors.Is(rt.Error, worker.ErrInvalidMountFiles),
+			errors.Is(rt.Error, worker.ErrInvalidTrace), errors.Is(rt.Error, worker.ErrInvalidProcInfo),
+			errors.Is(rt.Error, worker.ErrInvalidArch), errors.Is(rt.Error, worker.ErrInvalidNUMANode),
+			errors.Is(rt.Error, worker.ErrInvalidShmSize), errors.Is(rt.Error, worker.ErrInvalidDiskWorkDir),
+			errors.Is(rt.Error, worker.ErrInlineContentTooLarge), errors.Is(rt.Error, worker.ErrLimitExceeded),
+			errors.Is(rt.Error, worker.ErrInvalidSnapshot), errors.Is(rt.Error, worker.ErrArgsTooLarge),
+			errors.Is(rt.Error, worker.ErrInvalidVerdict), errors.Is(rt.Error, worker.ErrInvalidHostExec),
+			errors.Is(rt.Error, worker.ErrInvalidRandom), errors.Is(rt.Error, worker.ErrInvalidScratch),
+			errors.Is(rt.Error, worker.ErrInvalidExtraFiles), errors.Is(rt.Error, worker.ErrInvalidRandomWorkDir),
+			errors.Is(rt.Error, worker.ErrInvalidCopyOutGroup), errors.Is(rt.Error, worker.ErrInvalidRelativeLimit),
+			errors.Is(rt.Error, worker.ErrUnlimitedDisabled):
+			return nil, status.Error(codes.InvalidArgument, rt.Error.Error())
+		case errors.Is(rt.Error, worker.ErrFileNotFound):
+			return nil, status.Error(codes.NotFound, rt.Error.Error())
+		case errors.Is(rt.Error, worker.ErrPolicyDenied):
+			return nil, status.Error(codes.PermissionDenied, rt.Error.Error())
+		case errors.As(rt.Error, &envErr), errors.Is(rt.Error, worker.ErrShutdown):
+			return nil, status.Error(codes.Unavailable, rt.Error.Error())
+		case errors.Is(rt.Error, worker.ErrQueueFull), errors.Is(rt.Error, worker.ErrQuotaExceeded):
+			return nil, status.Error(codes.ResourceExhausted, rt.Error.Error())
+		case errors.Is(rt.Error, worker.ErrCancelled):
+			return nil, status.Error(codes.Canceled, rt.Error.Error())
+		}
 		return nil, status.Error(codes.Internal, rt.Error.Error())
 	}
 	ret, err := model.ConvertResponse(rt, false)
@@ -72,12 +104,13 @@ func (e *execServer) Exec(ctx context.Context, req *pb.Request) (*pb.Response, e
 
 func (e *execServer) FileList(c context.Context, n *emptypb.Empty) (*pb.FileListType, error) {
 	return &pb.FileListType{
-		FileIDs: e.fs.List(),
+		FileIDs: filestore.FromContext(c, e.fs).List(),
 	}, nil
 }
 
 func (e *execServer) FileGet(c context.Context, f *pb.FileID) (*pb.FileContent, error) {
-	name, file := e.fs.Get(f.GetFileID())
+	fs := filestore.FromContext(c, e.fs)
+	name, file := fs.Get(f.GetFileID())
 	if file == nil {
 		return nil, status.Errorf(codes.NotFound, "file %v not found", f.GetFileID())
 	}
@@ -88,6 +121,10 @@ func (e *execServer) FileGet(c context.Context, f *pb.FileID) (*pb.FileContent,
 	defer r.Close()
 
 	content, err := io.ReadAll(r)
+	if errors.Is(err, envexec.ErrCacheCorrupt) {
+		fs.Remove(f.GetFileID())
+		return nil, status.Error(codes.DataLoss, err.Error())
+	}
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -98,7 +135,8 @@ func (e *execServer) FileGet(c context.Context, f *pb.FileID) (*pb.FileContent,
 }
 
 func (e *execServer) FileAdd(c context.Context, fc *pb.FileContent) (*pb.FileID, error) {
-	f, err := e.fs.New()
+	fs := filestore.FromContext(c, e.fs)
+	f, err := fs.New()
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -107,7 +145,7 @@ func (e *execServer) FileAdd(c context.Context, fc *pb.FileContent) (*pb.FileID,
 	if _, err := f.Write(fc.GetContent()); err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
-	fid, err := e.fs.Add(fc.GetName(), f.Name())
+	fid, err := fs.Add(fc.GetName(), f.Name())
 	if err != nil {
 		return nil, status.Error(codes.Internal, err.Error())
 	}
@@ -117,7 +155,7 @@ func (e *execServer) FileAdd(c context.Context, fc *pb.FileContent) (*pb.FileID,
 }
 
 func (e *execServer) FileDelete(c context.Context, f *pb.FileID) (*emptypb.Empty, error) {
-	ok := e.fs.Remove(f.GetFileID())
+	ok := filestore.FromContext(c, e.fs).Remove(f.GetFileID())
 	if !ok {
 		return nil, status.Errorf(codes.NotFound, "file id does not exists for %v", f.GetFileID())
 	}
@@ -126,9 +164,15 @@ func (e *execServer) FileDelete(c context.Context, f *pb.FileID) (*emptypb.Empty
 
 func convertPBResponse(r model.Response) (*pb.Response, error) {
 	res := &pb.Response{
-		RequestID: r.RequestID,
-		Results:   make([]*pb.Response_Result, 0, len(r.Results)),
-		Error:     r.ErrorMsg,
+		RequestID:    r.RequestID,
+		Results:      make([]*pb.Response_Result, 0, len(r.Results)),
+		Error:        r.ErrorMsg,
+		Note:         r.Note,
+		CancelReason: r.CancelReason,
+
+		EstimatedDuration: r.EstimatedDuration,
+		Duration:          r.Duration,
+		Usage:             convertPBUsage(r.Usage),
 	}
 	for _, c := range r.Results {
 		rt, err := convertPBResult(c)
@@ -142,18 +186,165 @@ func convertPBResponse(r model.Response) (*pb.Response, error) {
 
 func convertPBResult(r model.Result) (*pb.Response_Result, error) {
 	return &pb.Response_Result{
-		Status:     pb.Response_Result_StatusType(r.Status),
-		ExitStatus: int32(r.ExitStatus),
-		Error:      r.Error,
-		Time:       uint64(r.Time),
-		RunTime:    uint64(r.RunTime),
-		Memory:     uint64(r.Memory),
-		Files:      r.Buffs,
-		FileIDs:    r.FileIDs,
-		FileError:  convertPBFileError(r.FileError),
+		Status:       pb.Response_Result_StatusType(r.Status),
+		ExitStatus:   int32(r.ExitStatus),
+		Signal:       int32(r.Signal),
+		TerminatedBy: r.TerminatedBy,
+		CancelReason: r.CancelReason,
+		Error:        r.Error,
+		Time:         uint64(r.Time),
+		RunTime:      uint64(r.RunTime),
+		Memory:       uint64(r.Memory),
+		MemoryStat:   convertPBMemoryStat(r.MemoryStat),
+		IoStat:       convertPBIOStat(r.IOStat),
+		Throttling:   convertPBThrottling(r.Throttling),
+		ProcPeak:     r.ProcPeak,
+		StrayProcs:   r.StrayProcs,
+		Arch:         r.Arch,
+		Emulated:     r.Emulated,
+		RandomSeed:   r.RandomSeed,
+		RandomOpened: r.RandomOpened,
+		ShmUsage:     uint64(r.ShmUsage),
+		DiskUsage:    uint64(r.DiskUsage),
+		WorkDirUsage: r.WorkDirUsage,
+		WorkDirSize:  r.WorkDirSize,
+		InitLog:      r.InitLog,
+		WorkDir:      r.WorkDir,
+		PipeBytes:    convertPBPipeBytes(r.PipeBytes),
+		OutputStat:   convertPBOutputStat(r.OutputStat),
+		StartedAt:    r.StartedAt,
+		Hostname:     r.Hostname,
+		InstanceID:   r.InstanceID,
+		CoreFileID:   r.CoreFileID,
+		CoreSize:     r.CoreSize,
+		SyscallCount: r.SyscallCount,
+		FileStat:     convertPBFileStat(r.FileStat),
+		FileType:     convertPBFileType(r.FileType),
+		Files:        r.Buffs,
+		FileIDs:      r.FileIDs,
+		GroupID:      r.GroupID,
+		FileSize:     r.FileSize,
+		Warning:      r.Warning,
+		Message:      r.Message,
+		Raw:          convertPBRawResult(r.Raw),
+		FileError:    convertPBFileError(r.FileError),
+		Extracted:    convertPBExtracted(r.Extracted),
+
+		OverheadCpuTime: uint64(r.OverheadCPUTime),
+		EnvironmentID:   r.EnvironmentID,
+		CgroupID:        r.CgroupID,
+		NoisyNeighbor:   r.NoisyNeighbor,
 	}, nil
 }
 
+func convertPBRawResult(r *model.RawResult) *pb.Response_RawResult {
+	if r == nil {
+		return nil
+	}
+	return &pb.Response_RawResult{
+		Status:  pb.Response_Result_StatusType(r.Status),
+		Time:    r.Time,
+		RunTime: r.RunTime,
+	}
+}
+
+func convertPBPipeBytes(p map[int]int64) map[int32]int64 {
+	if p == nil {
+		return nil
+	}
+	rt := make(map[int32]int64, len(p))
+	for k, v := range p {
+		rt[int32(k)] = v
+	}
+	return rt
+}
+
+func convertPBFileStat(s map[string]model.FileStat) map[string]*pb.Response_FileStat {
+	if s == nil {
+		return nil
+	}
+	rt := make(map[string]*pb.Response_FileStat, len(s))
+	for k, v := range s {
+		rt[k] = &pb.Response_FileStat{Size: v.Size, Mode: v.Mode, Mtime: v.ModTime}
+	}
+	return rt
+}
+
+func convertPBFileType(t map[string]model.FileType) map[string]*pb.Response_FileType {
+	if t == nil {
+		return nil
+	}
+	rt := make(map[string]*pb.Response_FileType, len(t))
+	for k, v := range t {
+		rt[k] = &pb.Response_FileType{ContentType: v.ContentType, IsBinary: v.IsBinary}
+	}
+	return rt
+}
+
+func convertPBOutputStat(s map[string]model.OutputStat) map[string]*pb.Response_OutputStat {
+	if s == nil {
+		return nil
+	}
+	rt := make(map[string]*pb.Response_OutputStat, len(s))
+	for k, v := range s {
+		rt[k] = &pb.Response_OutputStat{Bytes: v.Bytes, Lines: v.Lines}
+	}
+	return rt
+}
+
+func convertPBMemoryStat(s *model.MemoryStat) *pb.Response_MemoryStat {
+	if s == nil {
+		return nil
+	}
+	return &pb.Response_MemoryStat{
+		Cache:      s.Cache,
+		Rss:        s.RSS,
+		MappedFile: s.MappedFile,
+		Pgfault:    s.PageFault,
+		Pgmajfault: s.MajorFault,
+	}
+}
+
+func convertPBUsage(u *model.RequestUsage) *pb.Response_Usage {
+	if u == nil {
+		return nil
+	}
+	return &pb.Response_Usage{
+		CpuTime:       u.CPUTime,
+		Memory:        u.Memory,
+		MemorySeconds: u.MemorySeconds,
+		WallTime:      u.WallTime,
+		CopyIn:        u.CopyIn,
+		CopyOut:       u.CopyOut,
+		Cached:        u.Cached,
+	}
+}
+
+func convertPBIOStat(s *model.IOStat) *pb.Response_IOStat {
+	if s == nil {
+		return nil
+	}
+	return &pb.Response_IOStat{Read: s.Read, Write: s.Write}
+}
+
+func convertPBThrottling(s *model.Throttling) *pb.Response_Throttling {
+	if s == nil {
+		return nil
+	}
+	return &pb.Response_Throttling{Periods: s.Periods, Throttled: s.Throttled, ThrottledTime: s.ThrottledTime}
+}
+
+func convertPBExtracted(e map[string]int) map[string]uint32 {
+	if e == nil {
+		return nil
+	}
+	rt := make(map[string]uint32, len(e))
+	for k, v := range e {
+		rt[k] = uint32(v)
+	}
+	return rt
+}
+
 func convertPBFileError(fe []envexec.FileError) []*pb.Response_FileError {
 	rt := make([]*pb.Response_FileError, 0, len(fe))
 	for _, e := range fe {
@@ -183,6 +374,20 @@ func convertPBRequest(r *pb.Request, srcPrefix []string) (req *worker.Request, s
 		RequestID:   r.RequestID,
 		Cmd:         make([]worker.Cmd, 0, len(r.Cmd)),
 		PipeMapping: make([]worker.PipeMap, 0, len(r.PipeMapping)),
+		RunMode:     worker.RunMode(r.GetRunMode()),
+		SkipVerify:  r.GetSkipVerify(),
+		KillOnLimit: r.GetKillOnLimit(),
+		Priority:    worker.Priority(r.GetPriority()),
+
+		IdempotencyKey: r.GetIdempotencyKey(),
+		Verdict:        r.GetVerdict(),
+		IncludeRaw:     r.GetIncludeRaw(),
+
+		EstimatedDuration: time.Duration(r.GetEstimatedDuration()),
+	}
+	if r.NumaNode != nil {
+		node := int(r.GetNumaNode())
+		req.NUMANode = &node
 	}
 	for _, c := range r.Cmd {
 		cm, si, so, err := convertPBCmd(c, srcPrefix)
@@ -191,6 +396,10 @@ func convertPBRequest(r *pb.Request, srcPrefix []string) (req *worker.Request, s
 		if err != nil {
 			return nil, streamIn, streamOut, err
 		}
+		if r.GetCopyOutStat() {
+			setStatOnly(cm.CopyOut)
+			setStatOnly(cm.CopyOutCached)
+		}
 		req.Cmd = append(req.Cmd, cm)
 	}
 	for _, p := range r.PipeMapping {
@@ -205,14 +414,19 @@ func convertPBPipeMap(p *pb.Request_PipeMap) worker.PipeMap {
 		In: worker.PipeIndex{
 			Index: int(p.GetIn().GetIndex()),
 			Fd:    int(p.GetIn().GetFd()),
+			Fifo:  p.GetIn().GetFifo(),
 		},
 		Out: worker.PipeIndex{
 			Index: int(p.GetOut().GetIndex()),
 			Fd:    int(p.GetOut().GetFd()),
+			Fifo:  p.GetOut().GetFifo(),
 		},
-		Proxy: p.GetProxy(),
-		Name:  p.GetName(),
-		Limit: worker.Size(p.Max),
+		Proxy:      p.GetProxy(),
+		Name:       p.GetName(),
+		Limit:      worker.Size(p.Max),
+		BufferSize: worker.Size(p.GetBufferSize()),
+
+		TransferLimit: worker.Size(p.GetTransferLimit()),
 	}
 }
 
@@ -230,54 +444,111 @@ func convertPBCmd(c *pb.Request_CmdType, srcPrefix []string) (cm worker.Cmd, str
 		}
 	}()
 	cm = worker.Cmd{
-		Args:              c.GetArgs(),
-		Env:               c.GetEnv(),
-		TTY:               c.GetTty(),
-		CPULimit:          time.Duration(c.GetCpuTimeLimit()),
-		ClockLimit:        time.Duration(c.GetClockTimeLimit()),
-		MemoryLimit:       envexec.Size(c.GetMemoryLimit()),
-		StackLimit:        envexec.Size(c.GetStackLimit()),
-		ProcLimit:         c.GetProcLimit(),
-		CPURateLimit:      c.GetCpuRateLimit(),
-		CPUSetLimit:       c.GetCpuSetLimit(),
-		StrictMemoryLimit: c.GetStrictMemoryLimit(),
-		CopyOut:           convertCopyOut(c.GetCopyOut()),
-		CopyOutCached:     convertCopyOut(c.GetCopyOutCached()),
-		CopyOutMax:        c.GetCopyOutMax(),
-		CopyOutDir:        c.GetCopyOutDir(),
-		Symlinks:          c.GetSymlinks(),
+		Args:               c.GetArgs(),
+		Env:                c.GetEnv(),
+		TTY:                c.GetTty(),
+		CPULimit:           time.Duration(c.GetCpuTimeLimit()),
+		ClockLimit:         time.Duration(c.GetClockTimeLimit()),
+		MemoryLimit:        envexec.Size(c.GetMemoryLimit()),
+		StackLimit:         envexec.Size(c.GetStackLimit()),
+		ProcLimit:          c.GetProcLimit(),
+		CPURateLimit:       c.GetCpuRateLimit(),
+		CPUSetLimit:        c.GetCpuSetLimit(),
+		StrictMemoryLimit:  c.GetStrictMemoryLimit(),
+		DetailedMemory:     c.GetDetailedMemory(),
+		Credential:         worker.NewCredential(c.GetUid(), c.GetGid()),
+		RandomizeWorkDir:   c.GetRandomizeWorkDir(),
+		Datasets:           c.GetDatasets(),
+		Profile:            c.GetProfile(),
+		IOLimit:            envexec.Size(c.GetIoLimit()),
+		CollectCore:        c.GetCollectCore(),
+		Trace:              c.GetTrace(),
+		ProcInfo:           c.GetProcInfo(),
+		ProcInfoCPUs:       c.GetProcInfoCpus(),
+		RandomSeed:         c.RandomSeed,
+		Arch:               c.GetArch(),
+		ShmSize:            worker.Size(c.GetShmSize()),
+		DiskWorkDir:        c.GetDiskWorkDir(),
+		ScratchFile:        c.GetScratchFile(),
+		ScratchSize:        worker.Size(c.GetScratchSize()),
+		ReportTiming:       c.GetReportTiming(),
+		SnapshotWorkDir:    c.GetSnapshotWorkDir(),
+		MountFiles:         c.GetMountFiles(),
+		CopyOut:            convertCopyOut(c.GetCopyOut()),
+		CopyOutCached:      convertCopyOut(c.GetCopyOutCached()),
+		CopyOutCachedGroup: convertCopyOut(c.GetCopyOutCachedGroup()),
+		CopyOutMax:         c.GetCopyOutMax(),
+		CopyOutDir:         c.GetCopyOutDir(),
+		CopyOutMaxFiles:    int(c.GetCopyOutMaxFiles()),
+		CopyOutMaxTotal:    c.GetCopyOutMaxTotal(),
+		Symlinks:           c.GetSymlinks(),
 	}
-	for _, f := range c.GetFiles() {
-		var cf worker.CmdFile
+	convertFile := func(f *pb.Request_File) (worker.CmdFile, error) {
 		switch fi := f.File.(type) {
 		case *pb.Request_File_StreamIn:
 			si := newFileStreamIn(fi.StreamIn.GetName(), c.GetTty())
 			streamIn = append(streamIn, si)
-			cf = si
+			return si, nil
 
 		case *pb.Request_File_StreamOut:
 			so := newFileStreamOut(fi.StreamOut.GetName())
 			streamOut = append(streamOut, so)
-			cf = so
+			return so, nil
 
 		default:
-			cf, err = convertPBFile(f, srcPrefix)
+			return convertPBFile(f, srcPrefix)
 		}
+	}
+	for _, f := range c.GetFiles() {
+		cf, err := convertFile(f)
 		if err != nil {
 			return cm, streamIn, streamOut, err
 		}
 		cm.Files = append(cm.Files, cf)
 	}
+	if extraFiles := c.GetExtraFiles(); len(extraFiles) > 0 {
+		cm.ExtraFiles = make(map[int]worker.CmdFile, len(extraFiles))
+		for fd, f := range extraFiles {
+			cf, err := convertFile(f)
+			if err != nil {
+				return cm, streamIn, streamOut, err
+			}
+			cm.ExtraFiles[int(fd)] = cf
+		}
+	}
 	if copyIn := c.GetCopyIn(); copyIn != nil {
 		cm.CopyIn = make(map[string]worker.CmdFile)
 		for k, f := range copyIn {
+			if f.GetFifo() {
+				cm.CopyIn[k] = &worker.FifoFile{}
+				continue
+			}
+			if g := f.GetGroup(); g != nil {
+				if !f.GetExtract() {
+					return cm, streamIn, streamOut, fmt.Errorf("copyIn (%s): group requires extract", k)
+				}
+				cm.CopyIn[k] = &worker.GroupFile{GroupID: g.GetGroupID()}
+				continue
+			}
 			cf, err := convertPBFile(f, srcPrefix)
 			if err != nil {
 				return cm, streamIn, streamOut, err
 			}
+			if a := f.GetArchive(); a != "" {
+				format, err := model.ParseArchiveFormat(a)
+				if err != nil {
+					return cm, streamIn, streamOut, err
+				}
+				cf = &worker.ArchiveFile{File: cf, Format: format}
+			}
 			cm.CopyIn[k] = cf
 		}
 	}
+	if c.GetExpand() {
+		if err := worker.Expand(&cm); err != nil {
+			return cm, streamIn, streamOut, err
+		}
+	}
 	return cm, streamIn, streamOut, nil
 }
 
@@ -301,7 +572,17 @@ func convertPBFile(c *pb.Request_File, srcPrefix []string) (worker.CmdFile, erro
 	case *pb.Request_File_Cached:
 		return &worker.CachedFile{FileID: c.Cached.GetFileID()}, nil
 	case *pb.Request_File_Pipe:
-		return &worker.Collector{Name: c.Pipe.GetName(), Max: envexec.Size(c.Pipe.GetMax()), Pipe: c.Pipe.GetPipe()}, nil
+		if c.Pipe.GetDiscard() && (c.Pipe.GetCache() || c.Pipe.GetCacheTruncated()) {
+			return nil, fmt.Errorf("collector (%s) could not both discard and cache", c.Pipe.GetName())
+		}
+		return &worker.Collector{
+			Name:           c.Pipe.GetName(),
+			Max:            envexec.Size(c.Pipe.GetMax()),
+			Pipe:           c.Pipe.GetPipe(),
+			Cache:          c.Pipe.GetCache(),
+			Discard:        c.Pipe.GetDiscard(),
+			CacheTruncated: c.Pipe.CacheTruncated,
+		}, nil
 	}
 	return nil, fmt.Errorf("request file type not supported yet %v", c)
 }
@@ -312,7 +593,14 @@ func convertCopyOut(copyOut []*pb.Request_CmdCopyOutFile) []worker.CmdCopyOutFil
 		rt = append(rt, worker.CmdCopyOutFile{
 			Name:     n.GetName(),
 			Optional: n.GetOptional(),
+			StatOnly: n.GetStatOnly(),
 		})
 	}
 	return rt
 }
+
+func setStatOnly(copyOut []worker.CmdCopyOutFile) {
+	for i := range copyOut {
+		copyOut[i].StatOnly = true
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/criyle/go-judge/filestore"
 	"github.com/criyle/go-judge/pb"
 	"github.com/criyle/go-judge/worker"
 	"go.uber.org/zap"
@@ -15,6 +16,9 @@ import (
 )
 
 func (e *execServer) ExecStream(es pb.Executor_ExecStreamServer) error {
+	if err := filestore.Available(e.fs); err != nil {
+		return status.Error(codes.Unavailable, err.Error())
+	}
 	msg, err := es.Recv()
 	if err != nil {
 		return status.Error(codes.InvalidArgument, err.Error())
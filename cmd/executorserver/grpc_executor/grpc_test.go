@@ -0,0 +1,43 @@
+package grpcexecutor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/pb"
+)
+
+// TestStatusType checks every status returned by the executor has the same
+// code in the gRPC StatusType
+func TestStatusType(t *testing.T) {
+	for code := 0; envexec.Status(code).Known(); code++ {
+		s := envexec.Status(code)
+		name, ok := pb.Response_Result_StatusType_name[int32(code)]
+		if s == envexec.Status(14) || s == envexec.Status(15) {
+			// CGroup Error and Container Error are reserved, never returned
+			if ok {
+				t.Errorf("%d: reserved code is %s", code, name)
+			}
+			continue
+		}
+		want := strings.ReplaceAll(s.String(), " ", "")
+		if !ok || !strings.EqualFold(name, want) {
+			t.Errorf("%d: StatusType %q, want %q", code, name, want)
+			continue
+		}
+		r, err := convertPBResult(model.Result{Status: model.Status(s)})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int(r.Status) != code {
+			t.Errorf("%v: converted to %v", s, r.Status)
+		}
+	}
+	for code, name := range pb.Response_Result_StatusType_name {
+		if !envexec.Status(code).Known() {
+			t.Errorf("%d: StatusType %s is not a status", code, name)
+		}
+	}
+}
@@ -0,0 +1,579 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	restexecutor "github.com/criyle/go-judge/cmd/executorserver/rest_executor"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// historySegment is the duration of runs kept in each segment of the
+	// history store, the store is pruned by whole segments
+	historySegment = time.Hour
+
+	historySegmentPrefix = "history-"
+	historySegmentSuffix = ".jsonl"
+	historySegmentLayout = "20060102T15"
+
+	historyDefaultLimit = 100
+	historyMaxLimit     = 1000
+)
+
+// historyEntry defines the run recorded in the history store, it extends the
+// audit entry with the sizes and hashes of the inline files and the outputs
+// whose contents are never stored
+type historyEntry struct {
+	RequestID  string          `json:"requestId,omitempty"`
+	Client     string          `json:"client"`
+	StartedAt  time.Time       `json:"startedAt"`
+	FinishedAt time.Time       `json:"finishedAt"`
+	Cmd        []historyCmd    `json:"cmd"`
+	Results    []historyResult `json:"results,omitempty"`
+	Error      string          `json:"error,omitempty"`
+}
+
+type historyCmd struct {
+	auditCmd
+	// Inline maps the files and copyIn names (fd index for files) with inline
+	// content to its size and hash
+	Inline map[string]*historyFile `json:"inline,omitempty"`
+}
+
+type historyResult struct {
+	auditResult
+	Signal int `json:"signal,omitempty"`
+	// Files maps the names of the outputs returned in the response to their
+	// sizes and hashes
+	Files map[string]*historyFile `json:"files,omitempty"`
+}
+
+type historyFile struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256,omitempty"` // empty if failed to read
+}
+
+// historyStore stores the history entries, append is only called by the
+// writer loop while query could be called concurrently
+type historyStore interface {
+	append(e *historyEntry) error
+	// flush makes the appended entries visible to query
+	flush() error
+	// query returns the entries matched by the filter, the latest first
+	query(f historyFilter) ([]historyEntry, error)
+	// prune removes the entries finished before
+	prune(before time.Time) error
+	close() error
+}
+
+// historyFilter selects the entries of GET /history, the zero fields match
+// all the entries
+type historyFilter struct {
+	Client    string
+	RequestID string
+	Status    string // any of the results has the status
+	Since     time.Time
+	Limit     int
+}
+
+func (f *historyFilter) match(e *historyEntry) bool {
+	if f.Client != "" && e.Client != f.Client {
+		return false
+	}
+	if f.RequestID != "" && e.RequestID != f.RequestID {
+		return false
+	}
+	if !f.Since.IsZero() && e.FinishedAt.Before(f.Since) {
+		return false
+	}
+	if f.Status == "" {
+		return true
+	}
+	for _, r := range e.Results {
+		if r.Status == f.Status {
+			return true
+		}
+	}
+	return false
+}
+
+// historyPending is the entry queued to the writer loop with the files to be
+// hashed in background
+type historyPending struct {
+	e     *historyEntry
+	files []historyPendingFile
+}
+
+type historyPendingFile struct {
+	f       *historyFile
+	content []byte
+	file    *os.File // duplicated from the result, closed once hashed
+}
+
+func (p *historyPending) hash() {
+	for _, pf := range p.files {
+		if pf.file == nil {
+			sum := sha256.Sum256(pf.content)
+			pf.f.SHA256 = hex.EncodeToString(sum[:])
+			continue
+		}
+		h := sha256.New()
+		// the offset is shared with the file read by the response
+		if _, err := io.Copy(h, io.NewSectionReader(pf.file, 0, pf.f.Size)); err == nil {
+			pf.f.SHA256 = hex.EncodeToString(h.Sum(nil))
+		}
+		pf.file.Close()
+	}
+}
+
+func (p *historyPending) discard() {
+	for _, pf := range p.files {
+		if pf.file != nil {
+			pf.file.Close()
+		}
+	}
+}
+
+// history records the runs asynchronously into the store of -history-db,
+// the entries are dropped when the buffer is full so that the worker is never
+// blocked, and the entries older than -history-retention are pruned
+type history struct {
+	store       historyStore
+	checkpoints *checkpointStore // of POST /runs with Idempotency-Key
+	retention   time.Duration
+	sink        *asyncSink[historyPending]
+}
+
+// newHistory opens the history store of -history-db, nil if not configured
+func newHistory(conf *config.Config) *history {
+	if conf.HistoryDB == "" {
+		return nil
+	}
+	s, err := openHistoryFileStore(conf.HistoryDB)
+	if err != nil {
+		logger.Sugar().Fatal("Failed to open history db: ", err)
+	}
+	cs, err := openCheckpointStore(conf.HistoryDB)
+	if err != nil {
+		logger.Sugar().Fatal("Failed to open checkpoint store: ", err)
+	}
+	h := &history{
+		store:       s,
+		checkpoints: cs,
+		retention:   conf.HistoryRetention,
+	}
+	h.prune()
+	h.sink = newAsyncSink[historyPending]("history db", conf.HistoryBuffer, historyWriter{s}, historyDroppedCount)
+	if h.retention > 0 {
+		t := time.NewTicker(historySegment)
+		go func() {
+			defer t.Stop()
+			onSinkEvent(h.sink, t.C, h.prune)
+		}()
+	}
+	return h
+}
+
+// observe converts the record into entry and queues it, it is dropped if the
+// buffer is full. The outputs are duplicated to be hashed in background since
+// they are closed once the response is sent.
+func (h *history) observe(r worker.AuditRecord) {
+	h.sink.observe(convertHistoryRecord(r))
+}
+
+// historyWriter hashes the files of the entries and appends them to the store
+type historyWriter struct {
+	store historyStore
+}
+
+func (w historyWriter) write(p historyPending) error {
+	p.hash()
+	return w.store.append(p.e)
+}
+
+func (w historyWriter) flush() error {
+	return w.store.flush()
+}
+
+func (w historyWriter) close() error {
+	return w.store.close()
+}
+
+func (w historyWriter) discard(p historyPending) {
+	p.discard()
+}
+
+func (h *history) prune() {
+	if h.retention <= 0 {
+		return
+	}
+	before := time.Now().Add(-h.retention)
+	if err := h.store.prune(before); err != nil {
+		logger.Sugar().Error("Failed to prune history db: ", err)
+	}
+	if err := h.checkpoints.prune(before); err != nil {
+		logger.Sugar().Error("Failed to prune checkpoint store: ", err)
+	}
+}
+
+// checkpoint returns the checkpoint of POST /runs, nil if -history-db is not
+// configured
+func (h *history) checkpoint() restexecutor.Checkpoint {
+	if h == nil {
+		return nil
+	}
+	return h.checkpoints
+}
+
+// query returns the matched entries within the retention
+func (h *history) query(f historyFilter) ([]historyEntry, error) {
+	if h.retention > 0 {
+		if since := time.Now().Add(-h.retention); f.Since.Before(since) {
+			f.Since = since
+		}
+	}
+	return h.store.query(f)
+}
+
+func initHistory(hist *history, work worker.Worker) initFunc {
+	return func() (start func(), cleanUp stopFunc) {
+		if hist == nil {
+			return nil, nil
+		}
+		return nil, func(ctx context.Context) error {
+			// requests finished during shutdown are still recorded
+			work.Shutdown()
+			hist.sink.close()
+			logger.Sugar().Infof("History db closed, %d entries dropped", hist.sink.dropCount())
+			return nil
+		}
+	}
+}
+
+func convertHistoryRecord(r worker.AuditRecord) historyPending {
+	a := convertAuditRecord(r)
+	e := &historyEntry{
+		RequestID:  a.RequestID,
+		Client:     a.Client,
+		StartedAt:  a.StartedAt,
+		FinishedAt: a.FinishedAt,
+		Cmd:        make([]historyCmd, 0, len(a.Cmd)),
+		Error:      a.Error,
+	}
+	p := historyPending{e: e}
+	for i, c := range r.Request.Cmd {
+		hc := historyCmd{auditCmd: a.Cmd[i]}
+		addInline := func(name string, f worker.CmdFile) {
+			mf, ok := f.(*worker.MemoryFile)
+			if !ok {
+				return
+			}
+			if hc.Inline == nil {
+				hc.Inline = make(map[string]*historyFile)
+			}
+			hf := &historyFile{Size: int64(len(mf.Content))}
+			hc.Inline[name] = hf
+			p.files = append(p.files, historyPendingFile{f: hf, content: mf.Content})
+		}
+		for i, f := range c.Files {
+			addInline(strconv.Itoa(i), f)
+		}
+		for fd, f := range c.ExtraFiles {
+			addInline(strconv.Itoa(fd), f)
+		}
+		for name, f := range c.CopyIn {
+			addInline(name, f)
+		}
+		e.Cmd = append(e.Cmd, hc)
+	}
+	for i, rt := range r.Response.Results {
+		hr := historyResult{auditResult: a.Results[i], Signal: rt.Signal}
+		for name, f := range rt.Files {
+			fi, err := f.Stat()
+			if err != nil {
+				continue
+			}
+			if hr.Files == nil {
+				hr.Files = make(map[string]*historyFile)
+			}
+			hf := &historyFile{Size: fi.Size()}
+			hr.Files[name] = hf
+			if df, err := dupFile(f); err == nil {
+				p.files = append(p.files, historyPendingFile{f: hf, file: df})
+			}
+		}
+		e.Results = append(e.Results, hr)
+	}
+	return p
+}
+
+// historyFileStore stores the entries as JSON lines in the segment files of
+// the directory, named by the UTC hour the runs finished in
+type historyFileStore struct {
+	dir string
+
+	mu      sync.Mutex // guards the segment being written against prune
+	segment time.Time
+	f       *os.File
+	bw      *bufio.Writer
+}
+
+func openHistoryFileStore(dir string) (*historyFileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &historyFileStore{dir: dir}, nil
+}
+
+func (s *historyFileStore) segmentPath(t time.Time) string {
+	return filepath.Join(s.dir, historySegmentPrefix+t.Format(historySegmentLayout)+historySegmentSuffix)
+}
+
+// segments returns the start of the segments in the directory, the earliest
+// first
+func (s *historyFileStore) segments() ([]time.Time, error) {
+	ents, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var rt []time.Time
+	for _, e := range ents {
+		name, ok := strings.CutPrefix(e.Name(), historySegmentPrefix)
+		if !ok || !strings.HasSuffix(name, historySegmentSuffix) {
+			continue
+		}
+		t, err := time.Parse(historySegmentLayout, strings.TrimSuffix(name, historySegmentSuffix))
+		if err != nil {
+			continue
+		}
+		rt = append(rt, t)
+	}
+	sort.Slice(rt, func(i, j int) bool { return rt[i].Before(rt[j]) })
+	return rt, nil
+}
+
+func (s *historyFileStore) append(e *historyEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seg := e.FinishedAt.UTC().Truncate(historySegment)
+	if s.f == nil || !seg.Equal(s.segment) {
+		if err := s.closeSegment(); err != nil {
+			return err
+		}
+		f, err := os.OpenFile(s.segmentPath(seg), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+		if err != nil {
+			return err
+		}
+		s.segment, s.f, s.bw = seg, f, bufio.NewWriter(f)
+	}
+	return writeJSONLine(s.bw, e)
+}
+
+func (s *historyFileStore) flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.bw == nil {
+		return nil
+	}
+	return s.bw.Flush()
+}
+
+func (s *historyFileStore) closeSegment() error {
+	if s.f == nil {
+		return nil
+	}
+	err := s.bw.Flush()
+	if cerr := s.f.Close(); err == nil {
+		err = cerr
+	}
+	s.f, s.bw = nil, nil
+	return err
+}
+
+func (s *historyFileStore) query(f historyFilter) ([]historyEntry, error) {
+	segs, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+	var rt []historyEntry
+	for i := len(segs) - 1; i >= 0 && len(rt) < f.Limit; i-- {
+		if !f.Since.IsZero() && segs[i].Add(historySegment).Before(f.Since) {
+			break
+		}
+		ents, err := s.readSegment(segs[i])
+		if err != nil {
+			return nil, err
+		}
+		for j := len(ents) - 1; j >= 0 && len(rt) < f.Limit; j-- {
+			if f.match(&ents[j]) {
+				rt = append(rt, ents[j])
+			}
+		}
+	}
+	return rt, nil
+}
+
+// readSegment reads the flushed entries of the segment in the order written,
+// the lines being written are skipped
+func (s *historyFileStore) readSegment(seg time.Time) ([]historyEntry, error) {
+	d, err := os.ReadFile(s.segmentPath(seg))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var rt []historyEntry
+	for len(d) > 0 {
+		line := d
+		if i := bytes.IndexByte(d, '\n'); i >= 0 {
+			line, d = d[:i], d[i+1:]
+		} else {
+			break
+		}
+		var e historyEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			continue
+		}
+		rt = append(rt, e)
+	}
+	return rt, nil
+}
+
+// prune removes the segments ended before, the segment being written is kept
+func (s *historyFileStore) prune(before time.Time) error {
+	segs, err := s.segments()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, seg := range segs {
+		if !seg.Add(historySegment).Before(before) {
+			break
+		}
+		if s.f != nil && seg.Equal(s.segment) {
+			continue
+		}
+		if err := os.Remove(s.segmentPath(seg)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *historyFileStore) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closeSegment()
+}
+
+// historyResponse defines the response of GET /history
+type historyResponse struct {
+	Entries []historyEntry `json:"entries"`
+}
+
+// generateHandleHistory queries the history, the runs of other tokens are
+// only visible to the admin
+func generateHandleHistory(hist *history, owners map[string]string) func(*gin.Context) {
+	return func(c *gin.Context) {
+		owner, restricted := filestore.NamespaceFromContext(c.Request.Context())
+		if t := c.Query("token"); restricted && t != "" && owners[t] != owner {
+			// not telling whether the token is configured
+			restexecutor.AbortError(c, http.StatusForbidden, model.CodeForbidden, "history of other tokens requires the admin token")
+			return
+		}
+		f, err := parseHistoryFilter(c, owners)
+		if err != nil {
+			restexecutor.AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, err.Error())
+			return
+		}
+		if restricted {
+			f.Client = owner
+		}
+		ents, err := hist.query(f)
+		if err != nil {
+			restexecutor.AbortError(c, http.StatusInternalServerError, model.CodeInternal, err.Error())
+			return
+		}
+		if ents == nil {
+			ents = []historyEntry{}
+		}
+		c.JSON(http.StatusOK, historyResponse{Entries: ents})
+	}
+}
+
+func parseHistoryFilter(c *gin.Context, owners map[string]string) (historyFilter, error) {
+	f := historyFilter{
+		RequestID: c.Query("requestId"),
+		Limit:     historyDefaultLimit,
+	}
+	if t := c.Query("token"); t != "" {
+		owner, ok := owners[t]
+		if !ok {
+			return f, fmt.Errorf("token: not configured")
+		}
+		if owner == "" {
+			owner = "default"
+		}
+		f.Client = owner
+	}
+	if s := c.Query("status"); s != "" {
+		st := envexec.StatusFromName(s)
+		if st == envexec.StatusUnknown || st == envexec.StatusInvalid {
+			return f, fmt.Errorf("status: invalid status %q", s)
+		}
+		f.Status = st.String()
+	}
+	if s := c.Query("since"); s != "" {
+		t, err := parseHistorySince(s)
+		if err != nil {
+			return f, err
+		}
+		f.Since = t
+	}
+	if s := c.Query("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 {
+			return f, fmt.Errorf("limit: invalid limit %q", s)
+		}
+		if n > historyMaxLimit {
+			n = historyMaxLimit
+		}
+		f.Limit = n
+	}
+	return f, nil
+}
+
+// parseHistorySince parses since as RFC 3339 time or the duration before now
+func parseHistorySince(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return time.Time{}, fmt.Errorf("since: invalid time or duration %q", s)
+	}
+	return time.Now().Add(-d), nil
+}
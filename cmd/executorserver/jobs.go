@@ -0,0 +1,83 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	restexecutor "github.com/criyle/go-judge/cmd/executorserver/rest_executor"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+// jobResult defines the entry of GET /admin/jobs, durations are in ns
+type jobResult struct {
+	ID        string         `json:"id"`
+	RequestID string         `json:"requestId,omitempty"`
+	Owner     string         `json:"owner,omitempty"`
+	Worker    int            `json:"worker"`
+	Running   bool           `json:"running"`
+	Wait      time.Duration  `json:"wait"`
+	Elapsed   time.Duration  `json:"elapsed"`
+	Cmd       []jobCmdResult `json:"cmd"`
+}
+
+type jobCmdResult struct {
+	Args        []string      `json:"args"`
+	CPUTime     time.Duration `json:"cpuTime"`
+	CPULimit    time.Duration `json:"cpuLimit"`
+	ClockLimit  time.Duration `json:"clockLimit"`
+	MemoryLimit uint64        `json:"memoryLimit"`
+	ProcLimit   uint64        `json:"procLimit"`
+}
+
+func generateHandleJobs(work worker.Worker) func(*gin.Context) {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			abortNotAdmin(c)
+			return
+		}
+		jobs := work.Jobs()
+		rt := make([]jobResult, 0, len(jobs))
+		for _, j := range jobs {
+			cmd := make([]jobCmdResult, 0, len(j.Cmd))
+			for _, jc := range j.Cmd {
+				cmd = append(cmd, jobCmdResult{
+					Args:        jc.Args,
+					CPUTime:     jc.CPUTime,
+					CPULimit:    jc.CPULimit,
+					ClockLimit:  jc.ClockLimit,
+					MemoryLimit: uint64(jc.MemoryLimit),
+					ProcLimit:   jc.ProcLimit,
+				})
+			}
+			rt = append(rt, jobResult{
+				ID:        j.ID,
+				RequestID: j.RequestID,
+				Owner:     j.Owner,
+				Worker:    j.Worker,
+				Running:   j.Running,
+				Wait:      j.Wait,
+				Elapsed:   j.Elapsed,
+				Cmd:       cmd,
+			})
+		}
+		c.JSON(http.StatusOK, rt)
+	}
+}
+
+func generateHandleJobKill(work worker.Worker) func(*gin.Context) {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			abortNotAdmin(c)
+			return
+		}
+		id := c.Param("id")
+		if !work.Kill(id) {
+			restexecutor.AbortError(c, http.StatusNotFound, model.CodeNotFound, "job "+id+" not found")
+			return
+		}
+		logger.Sugar().Info("Job killed by /admin/jobs: ", id)
+		c.Status(http.StatusOK)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// leakReporter logs the fds and goroutines that are not released after runs.
+// In strict mode the stack is logged and the first leak marks the server as
+// degraded until restart.
+type leakReporter struct {
+	strict bool
+
+	mu  sync.Mutex
+	err error
+}
+
+var leaks leakReporter
+
+func (l *leakReporter) observe(requestID string, leak envexec.Leak) {
+	execLeakCount.Inc()
+	if !l.strict {
+		logger.Sugar().Warnf("Run of request %q leaked %d files and %d goroutines",
+			requestID, len(leak.Files), len(leak.Goroutines))
+		return
+	}
+	logger.Sugar().Errorf("Run of request %q leaked %d files and %d goroutines:\n%v",
+		requestID, len(leak.Files), len(leak.Goroutines), leak)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.err == nil {
+		l.err = fmt.Errorf("run of request %q leaked %d files and %d goroutines",
+			requestID, len(leak.Files), len(leak.Goroutines))
+	}
+}
+
+// Available returns the first leak found in strict mode
+func (l *leakReporter) Available() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.err
+}
@@ -19,6 +19,15 @@ type acceptResult struct {
 	err  error
 }
 
+// listen uses the listener passed by systemd socket activation with the name
+// if exists, otherwise listens on addr
+func listen(name, addr string) (net.Listener, error) {
+	if lis := getActivatedListener(name); lis != nil {
+		return lis, nil
+	}
+	return newListener(addr)
+}
+
 func newListener(addr string) (net.Listener, error) {
 	host, port, err := net.SplitHostPort(addr)
 	if err != nil {
@@ -5,7 +5,9 @@ package main
 import (
 	"context"
 	crypto_rand "crypto/rand"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
 	"errors"
 	"flag"
 	"fmt"
@@ -17,12 +19,18 @@ import (
 	"os/signal"
 	"runtime"
 	"runtime/debug"
+	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/cmd/executorserver/executor"
 	grpcexecutor "github.com/criyle/go-judge/cmd/executorserver/grpc_executor"
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/criyle/go-judge/cmd/executorserver/openapi"
 	restexecutor "github.com/criyle/go-judge/cmd/executorserver/rest_executor"
+	"github.com/criyle/go-judge/cmd/executorserver/ui"
 	"github.com/criyle/go-judge/cmd/executorserver/version"
 	wsexecutor "github.com/criyle/go-judge/cmd/executorserver/ws_executor"
 	"github.com/criyle/go-judge/env"
@@ -51,6 +59,9 @@ import (
 var logger *zap.Logger
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		os.Exit(replayMain(os.Args[2:]))
+	}
 	conf := loadConf()
 	if conf.Version {
 		fmt.Print(version.Version)
@@ -61,23 +72,61 @@ func main() {
 	logger.Sugar().Infof("config loaded: %+v", conf)
 	initRand()
 	warnIfNotLinux()
+	registerMetrics(instanceID(conf))
+	leaks.strict = conf.StrictLeakCheck
+	panics.threshold = conf.PanicThreshold
+	disable, err := parsePresetCheckPolicy(conf.PresetCheckPolicy)
+	if err != nil {
+		logger.Sugar().Fatal("invalid preset check policy: ", err)
+	}
+	presetChecks.disable = disable
 
-	// Init environment pool
-	fs, fsCleanUp := newFilsStore(conf)
-	b, builderParam := newEnvBuilder(conf)
-	envPool := newEnvPool(b, conf.EnableMetrics)
-	prefork(envPool, conf.PreFork)
-	work := newWorker(conf, envPool, fs)
-	work.Start()
-	logger.Sugar().Infof("Started worker with parallelism=%d, workdir=%s, timeLimitCheckInterval=%v",
-		conf.Parallelism, conf.Dir, conf.TimeLimitCheckerInterval)
+	if len(conf.Warmup) > 0 {
+		cmds, err := warmupCmds(conf.Warmup, nil)
+		if err != nil {
+			logger.Sugar().Fatal("invalid warmup: ", err)
+		}
+		ec := executorConfig(conf)
+		if err := checkWarmupLimits(ec.LimitMax(), cmds); err != nil {
+			logger.Sugar().Fatal("invalid warmup: ", err)
+		}
+	}
+	audit, hist, rec := newAuditLog(conf), newHistory(conf), newRecorder(conf)
+	if err := checkStaticContainer(conf); err != nil {
+		logger.Sugar().Fatal("init executor failed: ", err)
+	}
+	exec, err := executor.New(executorConfig(conf), executorOptions(conf, audit, hist))
+	if err != nil {
+		logger.Sugar().Fatal("init executor failed: ", err)
+	}
+	// the temporary file store directory if not specified
+	conf.Dir = exec.Config().Dir
+	work, envPool, fs := exec.Worker(), exec.EnvPool(), exec.FileStore()
+	reloader := newConfigReloader(conf, exec)
+	if conf.PresetCheck {
+		presetChecks.check(context.Background(), work, envPool)
+	}
+	warmupConfigured(conf, work, envPool)
+	bench := newBenchmark(conf, work)
+	registerWorkerMetrics(instanceID(conf), work)
+	logger.Sugar().Infof("Started worker with parallelism=%d, workdir=%s, timeLimitCheckInterval=%v, scheduler=%s",
+		conf.Parallelism, conf.Dir, conf.TimeLimitCheckerInterval, conf.Scheduler)
+	if conf.AutoParallelism {
+		logger.Sugar().Infof("Auto parallelism within [%d, %d] re-evaluated every %v",
+			conf.ParallelismMin, conf.ParallelismMax, conf.AutoParallelismInterval)
+	}
 
 	servers := []initFunc{
-		cleanUpWorker(work),
-		cleanUpFs(fsCleanUp),
-		initHTTPServer(conf, work, fs, builderParam),
-		initMonitorHTTPServer(conf),
+		shutdownExecutor(exec),
+		initAuditLog(audit, work),
+		initHistory(hist, work),
+		initRecorder(rec, work),
+		initBenchmark(bench),
+		initHTTPServer(conf, work, envPool, fs, reloader, hist, rec, bench),
+		initMonitorHTTPServer(conf, work, envPool),
+		initAdminHTTPServer(conf, work, envPool, fs, reloader, bench),
 		initGRPCServer(conf, work, fs),
+		initReloadSignal(reloader),
 	}
 
 	// Gracefully shutdown, with signal / HTTP server / gRPC server / Monitor HTTP server
@@ -101,12 +150,19 @@ func main() {
 	// background force GC worker
 	newForceGCWorker(conf)
 
+	// notify systemd when started by it
+	if err := sdNotify("READY=1"); err != nil {
+		logger.Sugar().Warn("Failed to notify systemd: ", err)
+	}
+	newWatchdogWorker(work)
+
 	// Graceful shutdown...
-	signal.Notify(sig, os.Interrupt)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
 	<-sig
-	signal.Reset(os.Interrupt)
+	signal.Reset(os.Interrupt, syscall.SIGTERM)
 
 	logger.Sugar().Info("Shutting Down...")
+	sdNotify("STOPPING=1")
 
 	ctx, cancel := context.WithTimeout(context.TODO(), time.Second*3)
 	defer cancel()
@@ -148,50 +204,25 @@ func loadConf() *config.Config {
 type stopFunc func(ctx context.Context) error
 type initFunc func() (start func(), cleanUp stopFunc)
 
-func cleanUpWorker(work worker.Worker) initFunc {
+// shutdownExecutor waits for the running requests and releases the file
+// store and container root
+func shutdownExecutor(exec *executor.Executor) initFunc {
 	return func() (start func(), cleanUp stopFunc) {
-		return nil, func(ctx context.Context) error {
-			work.Shutdown()
-			logger.Sugar().Info("Worker shutdown")
-			return nil
-		}
+		return nil, exec.Shutdown
 	}
 }
 
-func cleanUpFs(fsCleanUp func() error) initFunc {
-	return func() (start func(), cleanUp stopFunc) {
-		if fsCleanUp == nil {
-			return nil, nil
-		}
-		return nil, func(ctx context.Context) error {
-			err := fsCleanUp()
-			logger.Sugar().Info("FileStore cleaned up")
-			return err
-		}
-	}
-}
-
-func initHTTPServer(conf *config.Config, work worker.Worker, fs filestore.FileStore, builderParam map[string]any) initFunc {
+func initHTTPServer(conf *config.Config, work worker.Worker, envPool pool.Pool, fs filestore.FileStore, reloader *configReloader, hist *history, rec *recorder, bench *benchmark) initFunc {
 	return func() (start func(), cleanUp stopFunc) {
 		// Init http handle
-		r := initHTTPMux(conf, work, fs, builderParam)
+		r := initHTTPMux(conf, work, envPool, fs, reloader, hist, rec, bench)
 		srv := http.Server{
 			Addr:    conf.HTTPAddr,
 			Handler: r,
 		}
 
 		return func() {
-				lis, err := newListener(conf.HTTPAddr)
-				if err != nil {
-					logger.Sugar().Error("Http server listen failed: ", err)
-					return
-				}
-				logger.Sugar().Info("Starting http server at ", conf.HTTPAddr, " with listener ", printListener(lis))
-				if err := srv.Serve(lis); errors.Is(err, http.ErrServerClosed) {
-					logger.Sugar().Info("Http server stopped: ", err)
-				} else {
-					logger.Sugar().Error("Http server stopped: ", err)
-				}
+				serveHTTP("http", &srv, splitAddrs(conf.HTTPAddr))
 			}, func(ctx context.Context) error {
 				logger.Sugar().Info("Http server shutdown")
 				return srv.Shutdown(ctx)
@@ -199,10 +230,14 @@ func initHTTPServer(conf *config.Config, work worker.Worker, fs filestore.FileSt
 	}
 }
 
-func initMonitorHTTPServer(conf *config.Config) initFunc {
+func initMonitorHTTPServer(conf *config.Config, work worker.Worker, envPool pool.Pool) initFunc {
 	return func() (start func(), cleanUp stopFunc) {
+		// metrics and debug endpoints are served by admin server if enabled
+		if conf.AdminAddr != "" {
+			return nil, nil
+		}
 		// Init monitor HTTP server
-		mr := initMonitorHTTPMux(conf)
+		mr := initMonitorHTTPMux(conf, work, envPool)
 		if mr == nil {
 			return nil, nil
 		}
@@ -211,7 +246,7 @@ func initMonitorHTTPServer(conf *config.Config) initFunc {
 			Handler: mr,
 		}
 		return func() {
-				lis, err := newListener(conf.MonitorAddr)
+				lis, err := listen("monitor", conf.MonitorAddr)
 				if err != nil {
 					logger.Sugar().Error("Monitoring http listen failed: ", err)
 					return
@@ -235,7 +270,7 @@ func initGRPCServer(conf *config.Config, work worker.Worker, fs filestore.FileSt
 		grpcServer := newGRPCServer(conf, esServer)
 
 		return func() {
-				lis, err := newListener(conf.GRPCAddr)
+				lis, err := listen("grpc", conf.GRPCAddr)
 				if err != nil {
 					logger.Sugar().Error("gRPC listen failed: ", err)
 					return
@@ -283,25 +318,12 @@ func initRand() {
 	math_rand.Seed(sd)
 }
 
-func prefork(envPool worker.EnvironmentPool, prefork int) {
-	if prefork <= 0 {
-		return
-	}
-	logger.Sugar().Info("create ", prefork, " prefork containers")
-	m := make([]envexec.Environment, 0, prefork)
-	for i := 0; i < prefork; i++ {
-		e, err := envPool.Get()
-		if err != nil {
-			log.Fatalln("prefork environment failed ", err)
-		}
-		m = append(m, e)
-	}
-	for _, e := range m {
-		envPool.Put(e)
-	}
+// instanceID returns the instance id from config, or hostname if not set
+func instanceID(conf *config.Config) string {
+	return executor.InstanceID(conf.InstanceID)
 }
 
-func initHTTPMux(conf *config.Config, work worker.Worker, fs filestore.FileStore, builderParam map[string]any) http.Handler {
+func initHTTPMux(conf *config.Config, work worker.Worker, envPool pool.Pool, fs filestore.FileStore, reloader *configReloader, hist *history, rec *recorder, bench *benchmark) http.Handler {
 	var r *gin.Engine
 	if conf.Release {
 		gin.SetMode(gin.ReleaseMode)
@@ -316,29 +338,67 @@ func initHTTPMux(conf *config.Config, work worker.Worker, fs filestore.FileStore
 	}
 
 	// Version handle
-	r.GET("/version", generateHandleVersion(conf, builderParam))
+	r.GET("/version", generateHandleVersion(conf, reloader, bench))
 
 	// Config handle
-	r.GET("/config", generateHandleConfig(conf, builderParam))
+	r.GET("/config", generateHandleConfig(conf, reloader))
+
+	// Health handle
+	r.GET("/health", generateHandleHealth(fs, envPool))
+
+	// API schema handle
+	r.GET("/openapi.json", handleOpenAPI)
+	r.GET("/example/run", handleExampleRun)
+
+	// Web UI handle, the page asks for the token to call the REST API
+	if conf.EnableUI {
+		r.GET("/ui", handleUI)
+	}
 
 	// Add auth token
-	if conf.AuthToken != "" {
-		r.Use(tokenAuth(conf.AuthToken))
+	if len(conf.AuthToken) > 0 || conf.AdminToken != "" {
+		r.Use(tokenAuth(conf.AuthToken, conf.AdminToken))
 		logger.Sugar().Info("Attach token auth with token: ", conf.AuthToken)
 	}
 
+	// Record the sampled requests after auth, so that they are owned
+	if rec != nil {
+		r.Use(rec.handle)
+	}
+
 	// Rest Handle
-	restHandle := restexecutor.New(work, fs, conf.SrcPrefix, logger)
+	restHandle := restexecutor.New(work, fs, conf.SrcPrefix, int64(*conf.MaxRequestSize), hist.checkpoint(), logger)
 	restHandle.Register(r)
 
 	// WebSocket Handle
 	wsHandle := wsexecutor.New(work, conf.SrcPrefix, logger)
 	wsHandle.Register(r)
 
+	// History handle
+	if hist != nil {
+		r.GET("/history", generateHandleHistory(hist, tokenOwners(conf.AuthToken, conf.AdminToken)))
+	}
+
+	// Admin handles are served by admin server if enabled
+	if conf.AdminAddr == "" {
+		initAdminRoute(r, conf, work, envPool, fs, reloader, bench)
+	}
+
+	// Unknown routes and methods respond in the error envelope
+	initNoRoute(r)
+
 	return r
 }
 
-func initMonitorHTTPMux(conf *config.Config) http.Handler {
+// initNoRoute responds 404 and 405 in the error envelope instead of the
+// plain text of gin
+func initNoRoute(r *gin.Engine) {
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(restexecutor.HandleNoRoute)
+	r.NoMethod(restexecutor.HandleNoMethod)
+}
+
+func initMonitorHTTPMux(conf *config.Config, work worker.Worker, envPool pool.Pool) http.Handler {
 	if !conf.EnableMetrics && !conf.EnableDebug {
 		return nil
 	}
@@ -347,17 +407,36 @@ func initMonitorHTTPMux(conf *config.Config) http.Handler {
 		mux.Handle("/metrics", promhttp.Handler())
 	}
 	if conf.EnableDebug {
-		initDebugRoute(mux)
+		initDebugRoute(mux, work, envPool)
 	}
 	return mux
 }
 
-func initDebugRoute(mux *http.ServeMux) {
+func initDebugRoute(mux *http.ServeMux, work worker.Worker, envPool pool.Pool) {
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
 	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
 	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
 	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.HandleFunc("/debug/initlog", handleInitLog)
+	mux.HandleFunc("/debug/envpool", generateHandleEnvPool(work, envPool))
+}
+
+// handleInitLog writes the stderr of the container init kept by each
+// environment not destroyed, the empty ones are skipped unless all=1
+func handleInitLog(w http.ResponseWriter, r *http.Request) {
+	all := r.URL.Query().Get("all") == "1"
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	for _, l := range env.InitLogs() {
+		if len(l.Log) == 0 && l.Dropped == 0 && !all {
+			continue
+		}
+		fmt.Fprintf(w, "=== environment %d (%d bytes dropped) ===\n", l.ID, l.Dropped)
+		w.Write(l.Log)
+		if len(l.Log) > 0 && l.Log[len(l.Log)-1] != '\n' {
+			fmt.Fprintln(w)
+		}
+	}
 }
 
 func newGRPCServer(conf *config.Config, esServer pb.ExecutorServer) *grpc.Server {
@@ -372,8 +451,8 @@ func newGRPCServer(conf *config.Config, esServer pb.ExecutorServer) *grpc.Server
 		grpc_zap.UnaryServerInterceptor(logger),
 		grpc_recovery.UnaryServerInterceptor(),
 	}
-	if conf.AuthToken != "" {
-		authFunc := grpcTokenAuth(conf.AuthToken)
+	if len(conf.AuthToken) > 0 || conf.AdminToken != "" {
+		authFunc := grpcTokenAuth(conf.AuthToken, conf.AdminToken)
 		streamMiddleware = append(streamMiddleware, grpc_auth.StreamServerInterceptor(authFunc))
 		unaryMiddleware = append(unaryMiddleware, grpc_auth.UnaryServerInterceptor(authFunc))
 	}
@@ -395,106 +474,311 @@ func initGinMetrics(r *gin.Engine) {
 	r.Use(p.HandlerFunc())
 }
 
-func tokenAuth(token string) gin.HandlerFunc {
+// tokenOwners maps each auth token to its file store namespace, the admin
+// token maps to empty namespace that is not restricted
+func tokenOwners(tokens []string, adminToken string) map[string]string {
+	owners := make(map[string]string, len(tokens)+1)
+	for _, t := range tokens {
+		sum := sha256.Sum256([]byte(t))
+		owners[t] = hex.EncodeToString(sum[:8])
+	}
+	if adminToken != "" {
+		owners[adminToken] = ""
+	}
+	return owners
+}
+
+// withTokenNamespace attaches file store namespace of the token owner into the context
+func withTokenNamespace(ctx context.Context, owner string) context.Context {
+	if owner == "" {
+		return ctx
+	}
+	return filestore.NewNamespaceContext(ctx, owner)
+}
+
+// isAdmin reports whether the request is not restricted to a file store
+// namespace, i.e. authenticated by admin token or auth is not enabled
+func isAdmin(c *gin.Context) bool {
+	_, ok := filestore.NamespaceFromContext(c.Request.Context())
+	return !ok
+}
+
+// abortNotAdmin aborts the admin endpoints requested by the tokens restricted
+// to a namespace
+func abortNotAdmin(c *gin.Context) {
+	restexecutor.AbortError(c, http.StatusForbidden, model.CodeForbidden, "admin token required")
+}
+
+func tokenAuth(tokens []string, adminToken string) gin.HandlerFunc {
 	const bearer = "Bearer "
+	owners := tokenOwners(tokens, adminToken)
 	return func(c *gin.Context) {
 		reqToken := c.GetHeader("Authorization")
-		if strings.HasPrefix(reqToken, bearer) && reqToken[len(bearer):] == token {
-			c.Next()
-			return
+		if strings.HasPrefix(reqToken, bearer) {
+			if owner, ok := owners[reqToken[len(bearer):]]; ok {
+				c.Request = c.Request.WithContext(withTokenNamespace(c.Request.Context(), owner))
+				c.Next()
+				return
+			}
 		}
-		c.AbortWithStatus(http.StatusUnauthorized)
+		restexecutor.AbortError(c, http.StatusUnauthorized, model.CodeUnauthorized, "invalid or missing bearer token")
 	}
 }
 
-func grpcTokenAuth(token string) func(context.Context) (context.Context, error) {
+func grpcTokenAuth(tokens []string, adminToken string) func(context.Context) (context.Context, error) {
+	owners := tokenOwners(tokens, adminToken)
 	return func(ctx context.Context) (context.Context, error) {
 		reqToken, err := grpc_auth.AuthFromMD(ctx, "bearer")
 		if err != nil {
 			return nil, err
 		}
-		if reqToken != token {
+		owner, ok := owners[reqToken]
+		if !ok {
 			return nil, status.Errorf(codes.Unauthenticated, "invalid auth token: %v", err)
 		}
-		return ctx, nil
-	}
-}
-
-func newFilsStore(conf *config.Config) (filestore.FileStore, func() error) {
-	const timeoutCheckInterval = 15 * time.Second
-	var cleanUp func() error
-
-	var fs filestore.FileStore
-	if conf.Dir == "" {
-		if runtime.GOOS == "linux" {
-			conf.Dir = "/dev/shm"
-		} else {
-			conf.Dir = os.TempDir()
-		}
-		var err error
-		conf.Dir, err = os.MkdirTemp(conf.Dir, "executorserver")
-		if err != nil {
-			logger.Sugar().Fatal("failed to create file store temp dir", err)
-		}
-		cleanUp = func() error {
-			return os.RemoveAll(conf.Dir)
-		}
-	}
-	os.MkdirAll(conf.Dir, 0755)
-	fs = filestore.NewFileLocalStore(conf.Dir)
-	if conf.EnableDebug {
-		fs = newMetricsFileStore(fs)
+		return withTokenNamespace(ctx, owner), nil
 	}
-	if conf.FileTimeout > 0 {
-		fs = filestore.NewTimeout(fs, conf.FileTimeout, timeoutCheckInterval)
-	}
-	return fs, cleanUp
 }
 
-func newEnvBuilder(conf *config.Config) (pool.EnvBuilder, map[string]any) {
-	b, param, err := env.NewBuilder(env.Config{
+// executorConfig returns the executor config of the flags
+func executorConfig(conf *config.Config) executor.Config {
+	return executor.Config{
+		Sandbox:            conf.Sandbox,
 		ContainerInitPath:  conf.ContainerInitPath,
-		MountConf:          conf.MountConf,
+		PreFork:            conf.PreFork,
 		TmpFsParam:         conf.TmpFsParam,
 		NetShare:           conf.NetShare,
+		MountConf:          conf.MountConf,
+		StaticContainer:    conf.StaticContainer,
+		SeccompConf:        conf.SeccompConf,
+		VerdictConf:        conf.VerdictConf,
 		CgroupPrefix:       conf.CgroupPrefix,
-		Cpuset:             conf.Cpuset,
+		CgroupMount:        conf.CgroupMount,
+		CgroupRetry:        conf.CgroupRetry,
+		CgroupRetryBackoff: conf.CgroupRetryBackoff,
 		ContainerCredStart: conf.ContainerCredStart,
+		CredPinStart:       conf.CredPinStart,
+		CredPinCount:       conf.CredPinCount,
+		VerifyReset:        conf.VerifyReset,
+		CheckFds:           conf.CheckFds,
+		RandomizeWorkDir:   conf.RandomizeWorkDir,
+		Cpuset:             conf.Cpuset,
+		NUMAAware:          conf.NUMAAware,
 		EnableCPURate:      conf.EnableCPURate,
 		CPUCfsPeriod:       conf.CPUCfsPeriod,
-		SeccompConf:        conf.SeccompConf,
-		Logger:             logger.Sugar(),
-	})
+		InitLogSize:        *conf.InitLogSize,
+		MountCheckInterval: conf.MountCheckInterval,
+		Strict:             conf.Strict,
+
+		FakeTime:   conf.FakeTime,
+		FakeMemory: *conf.FakeMemory,
+
+		ShmSize:        *conf.ShmSize,
+		ShmSizeDefault: *conf.ShmSizeDefault,
+		RandomSize:     *conf.RandomSize,
+
+		Dataset:          conf.Dataset,
+		Emulator:         conf.Emulator,
+		EmulatorBaseline: conf.EmulatorBaseline,
+
+		MountFilesDir:      conf.MountFilesDir,
+		MountFilesCacheMax: *conf.MountFilesCacheMax,
+		AllowHostExec:      conf.AllowHostExec,
+
+		AllowDiskWorkdir: conf.AllowDiskWorkdir,
+		DiskWorkdirPath:  conf.DiskWorkdirPath,
+		DiskWorkdirMax:   *conf.DiskWorkdirMax,
+		AllowScratch:     conf.AllowScratch,
+		ScratchPath:      conf.ScratchPath,
+		ScratchMax:       *conf.ScratchMax,
+
+		Parallelism:             conf.Parallelism,
+		AutoParallelism:         conf.AutoParallelism,
+		ParallelismMin:          conf.ParallelismMin,
+		ParallelismMax:          conf.ParallelismMax,
+		AutoParallelismInterval: conf.AutoParallelismInterval,
+
+		Scheduler:     conf.Scheduler,
+		PriorityAging: conf.PriorityAging,
+		TotalMemory:   *conf.TotalMemory,
+
+		IdempotencyTTL:      conf.IdempotencyTTL,
+		IdempotencyCacheMax: *conf.IdempotencyCacheMax,
+		UsageSaveInterval:   conf.UsageSaveInterval,
+		QuotaPeriod:         conf.QuotaPeriod,
+
+		InstanceID: conf.InstanceID,
+
+		Dir:         conf.Dir,
+		StoreSync:   conf.StoreSync,
+		FileTimeout: conf.FileTimeout,
+		ImportState: conf.ImportState,
+
+		TimeLimitCheckerInterval: conf.TimeLimitCheckerInterval,
+		ExtraMemoryLimit:         *conf.ExtraMemoryLimit,
+		OutputLimit:              *conf.OutputLimit,
+		CopyOutLimit:             *conf.CopyOutLimit,
+		CopyOutMaxFiles:          conf.CopyOutMaxFiles,
+		CopyOutMaxTotal:          *conf.CopyOutMaxTotal,
+		OpenFileLimit:            conf.OpenFileLimit,
+		ArchiveMaxCount:          conf.ArchiveMaxCount,
+		ArchiveMaxSize:           *conf.ArchiveMaxSize,
+		InlineContentMax:         *conf.InlineContentMax,
+		StderrMax:                *conf.StderrMax,
+		StderrCacheTruncated:     conf.StderrCacheTruncated,
+		IOLimitStrict:            conf.IOLimitStrict,
+		CoreFileMax:              *conf.CoreFileMax,
+		SnapshotMax:              *conf.SnapshotMax,
+		CollectorDir:             conf.CollectorDir,
+		EnableTrace:              conf.EnableTrace,
+		ProcInfoCPU:              conf.ProcInfoCPU,
+		CalibrateMax:             conf.CalibrateMax,
+
+		MaxCPU:         conf.MaxCPU,
+		MaxClock:       conf.MaxClock,
+		MaxMemory:      *conf.MaxMemory,
+		MaxProc:        conf.MaxProc,
+		MaxEnv:         conf.MaxEnv,
+		LimitPolicy:    conf.LimitPolicy,
+		AllowUnlimited: conf.AllowUnlimited,
+
+		DefaultCPU:    conf.DefaultCPU,
+		DefaultClock:  conf.DefaultClock,
+		DefaultMemory: *conf.DefaultMemory,
+		DefaultStack:  *conf.DefaultStack,
+		DefaultProc:   conf.DefaultProc,
+
+		NoisyNeighborMajorFaults: conf.NoisyNeighborMajorFaults,
+		NoisyNeighborWriteback:   *conf.NoisyNeighborWriteback,
+	}
+}
+
+// checkStaticContainer rejects the server options of -static-container which
+// run the toolchains of the host, the executor rejects the ones binding host
+// paths into the container
+func checkStaticContainer(conf *config.Config) error {
+	if !conf.StaticContainer {
+		return nil
+	}
+	for _, o := range []struct {
+		name string
+		set  bool
+	}{
+		{"warmup", len(conf.Warmup) > 0},
+		{"preset-check", conf.PresetCheck},
+		{"bench-interval", conf.BenchInterval > 0},
+	} {
+		if o.set {
+			return fmt.Errorf("-%s could not be used with -static-container", o.name)
+		}
+	}
+	return nil
+}
+
+// executorOptions hooks the metrics, audit log, history and leak reporter into
+// the executor, and maps the quotas, policies and scheduler shares of the auth
+// tokens
+func executorOptions(conf *config.Config, audit *auditLog, hist *history) executor.Options {
+	tokenPolicies, err := policies(conf)
 	if err != nil {
-		logger.Sugar().Fatal("create environment builder failed ", err)
+		logger.Sugar().Fatal("Failed to read policy config: ", err)
+	}
+	opt := executor.Options{
+		Logger:          logger,
+		InitLogOverflow: envInitLogObserve,
+		OnStale:         envStaleObserve,
+		Quotas:          quotas(conf),
+		Policies:        tokenPolicies,
+		SchedulerShares: schedulerShares(conf),
+		ExecObserver:    execObserver(conf.EstimateFactor),
+		LeakObserver:    leaks.observe,
+		CancelObserver:  execCancelObserve,
+		PanicObserver:   panics.observe,
+
+		ParallelismObserver: logParallelism,
+		WrapEnvPool: func(p pool.Pool) pool.Pool {
+			return metricsPool(conf, p)
+		},
+	}
+	switch {
+	case audit != nil && hist != nil:
+		opt.AuditObserver = func(r worker.AuditRecord) {
+			audit.observe(r)
+			hist.observe(r)
+		}
+	case audit != nil:
+		opt.AuditObserver = audit.observe
+	case hist != nil:
+		opt.AuditObserver = hist.observe
+	}
+	if conf.EnableDebug {
+		opt.WrapFileStore = newMetricsFileStore
 	}
 	if conf.EnableMetrics {
-		b = &metriceEnvBuilder{b}
+		opt.WrapEnvBuilder = func(b pool.EnvBuilder) pool.EnvBuilder {
+			return &metriceEnvBuilder{b}
+		}
 	}
-	return b, param
+	return opt
 }
 
-func newEnvPool(b pool.EnvBuilder, enableMetrics bool) worker.EnvironmentPool {
-	p := pool.NewPool(b)
-	if enableMetrics {
+// metricsPool registers the NUMA metrics and collects the metrics of the
+// environment pool if enabled
+func metricsPool(conf *config.Config, p pool.Pool) pool.Pool {
+	np, numa := p.(pool.NUMAPool)
+	if numa {
+		registerNUMAMetrics(instanceID(conf), np)
+	}
+	if conf.EnableMetrics {
+		if numa {
+			return &metricsNUMAEnvPool{metricsEnvPool{np}, np}
+		}
 		p = &metricsEnvPool{p}
 	}
 	return p
 }
 
-func newWorker(conf *config.Config, envPool worker.EnvironmentPool, fs filestore.FileStore) worker.Worker {
-	return worker.New(worker.Config{
-		FileStore:             fs,
-		EnvironmentPool:       envPool,
-		Parallelism:           conf.Parallelism,
-		WorkDir:               conf.Dir,
-		TimeLimitTickInterval: conf.TimeLimitCheckerInterval,
-		ExtraMemoryLimit:      *conf.ExtraMemoryLimit,
-		OutputLimit:           *conf.OutputLimit,
-		CopyOutLimit:          *conf.CopyOutLimit,
-		OpenFileLimit:         uint64(conf.OpenFileLimit),
-		ExecObserver:          execObserve,
-	})
+// schedulerShares maps the weights of auth tokens into the weights of their
+// file store namespace, which identifies the client in the worker
+func schedulerShares(conf *config.Config) map[string]int {
+	owners := tokenOwners(conf.AuthToken, conf.AdminToken)
+	shares := make(map[string]int, len(conf.SchedulerShare))
+	for _, s := range conf.SchedulerShare {
+		i := strings.LastIndexByte(s, '=')
+		if i < 0 {
+			logger.Sugar().Fatal("invalid scheduler share (expected token=weight): ", s)
+		}
+		owner, ok := owners[s[:i]]
+		if !ok {
+			logger.Sugar().Fatal("scheduler share of unknown auth token")
+		}
+		w, err := strconv.Atoi(s[i+1:])
+		if err != nil || w <= 0 {
+			logger.Sugar().Fatal("invalid scheduler share weight: ", s[i+1:])
+		}
+		shares[owner] = w
+	}
+	return shares
+}
+
+// newWatchdogWorker pings systemd watchdog when enabled as long as the worker
+// loops are alive, so that a stuck worker gets the process restarted
+func newWatchdogWorker(work worker.Worker) {
+	timeout := sdWatchdogInterval()
+	if timeout <= 0 {
+		return
+	}
+	logger.Sugar().Info("Enable systemd watchdog with timeout ", timeout)
+	go func() {
+		ticker := time.NewTicker(timeout / 2)
+		for range ticker.C {
+			if !work.Alive(timeout) {
+				logger.Sugar().Error("Worker loop is stuck, stop pinging watchdog")
+				continue
+			}
+			sdNotify("WATCHDOG=1")
+		}
+	}()
 }
 
 func newForceGCWorker(conf *config.Config) {
@@ -514,22 +798,68 @@ func newForceGCWorker(conf *config.Config) {
 	}()
 }
 
-func generateHandleVersion(conf *config.Config, builderParam map[string]any) func(*gin.Context) {
+func generateHandleVersion(conf *config.Config, reloader *configReloader, bench *benchmark) func(*gin.Context) {
 	return func(c *gin.Context) {
+		gen, builderParam := reloader.Current()
+		report, _ := builderParam["isolation"].(env.IsolationReport)
+		rt := gin.H{
+			"buildVersion":     version.Version,
+			"goVersion":        runtime.Version(),
+			"platform":         runtime.GOARCH,
+			"os":               runtime.GOOS,
+			"copyOutOptional":  true,
+			"pipeProxy":        true,
+			"symlink":          true,
+			"configGeneration": gen,
+			"degradedFeatures": report.Degraded(),
+		}
+		if r := presetChecks.Results(); r != nil {
+			rt["presetCheck"] = r
+		}
+		if bench != nil {
+			rt["bench"] = bench.snapshot(false)
+		}
+		c.JSON(http.StatusOK, rt)
+	}
+}
+
+func handleOpenAPI(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json; charset=utf-8", openapi.Document)
+}
+
+func handleExampleRun(c *gin.Context) {
+	c.JSON(http.StatusOK, openapi.ExampleRun)
+}
+
+func handleUI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", ui.Page)
+}
+
+func generateHandleHealth(fs filestore.FileStore, envPool pool.Pool) func(*gin.Context) {
+	return func(c *gin.Context) {
+		if err := filestore.Available(fs); err != nil {
+			restexecutor.AbortError(c, http.StatusServiceUnavailable, model.CodeStoreUnavailable, err.Error())
+			return
+		}
+		// the same as /run failed by the environment error
+		var envErr *worker.EnvironmentError
+		if err := envPool.Available(); errors.As(err, &envErr) {
+			restexecutor.AbortRunError(c, err)
+			return
+		}
+		if err := leaks.Available(); err != nil {
+			restexecutor.AbortError(c, http.StatusServiceUnavailable, model.CodeResourceLeak, err.Error())
+			return
+		}
 		c.JSON(http.StatusOK, gin.H{
-			"buildVersion":    version.Version,
-			"goVersion":       runtime.Version(),
-			"platform":        runtime.GOARCH,
-			"os":              runtime.GOOS,
-			"copyOutOptional": true,
-			"pipeProxy":       true,
-			"symlink":         true,
+			"status": "ok",
 		})
 	}
 }
 
-func generateHandleConfig(conf *config.Config, builderParam map[string]any) func(*gin.Context) {
+func generateHandleConfig(conf *config.Config, reloader *configReloader) func(*gin.Context) {
 	return func(c *gin.Context) {
+		_, builderParam := reloader.Current()
 		c.JSON(http.StatusOK, gin.H{
 			"copyOutOptional": true,
 			"pipeProxy":       true,
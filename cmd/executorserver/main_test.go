@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func TestMain(m *testing.M) {
+	logger = zap.NewNop()
+	gin.SetMode(gin.TestMode)
+	os.Exit(m.Run())
+}
+
+// unavailableStore is the file store of the broken backing storage
+type unavailableStore struct {
+	filestore.FileStore
+}
+
+func (unavailableStore) Available() error {
+	return fmt.Errorf("%w: disk is gone", filestore.ErrUnavailable)
+}
+
+// unavailablePool is the pool failed to create new environments
+type unavailablePool struct {
+	pool.Pool
+	err error
+}
+
+func (p unavailablePool) Available() error {
+	return p.err
+}
+
+// checkEnvelope checks the response is in the error envelope of the status
+// and code
+func checkEnvelope(t *testing.T, w *httptest.ResponseRecorder, status int, code model.ErrorCode) model.ErrorBody {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status %d, want %d: %s", w.Code, status, w.Body)
+	}
+	err := model.ParseError(w.Code, w.Body.Bytes())
+	var apiErr *model.APIError
+	if !errors.Is(err, code) || !errors.As(err, &apiErr) || apiErr.Message == "" {
+		t.Fatalf("body %s is not in the error envelope of %s", w.Body, code)
+	}
+	return apiErr.ErrorBody
+}
+
+func TestHealthEnvelope(t *testing.T) {
+	fs := filestore.NewFileLocalStore(t.TempDir())
+	envErr := &worker.EnvironmentError{Reason: worker.EnvironmentNamespaceLimit, Errno: syscall.ENOSPC, Err: errors.New("clone")}
+	defer func() { leaks.err = nil }()
+
+	for _, c := range []struct {
+		name    string
+		fs      filestore.FileStore
+		envPool pool.Pool
+		leak    error
+		code    model.ErrorCode
+	}{
+		{"store", unavailableStore{fs}, unavailablePool{}, nil, model.CodeStoreUnavailable},
+		{"environment", fs, unavailablePool{err: envErr}, nil, model.CodeEnvironmentUnavailable},
+		{"leak", fs, unavailablePool{}, errors.New("leaked"), model.CodeResourceLeak},
+		{"ok", fs, unavailablePool{}, nil, ""},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			leaks.err = c.leak
+			r := gin.New()
+			r.GET("/health", generateHandleHealth(c.fs, c.envPool))
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/health", nil))
+
+			if c.code == "" {
+				if w.Code != http.StatusOK || w.Body.String() != `{"status":"ok"}` {
+					t.Fatalf("got %d %s, want 200 ok", w.Code, w.Body)
+				}
+				return
+			}
+			e := checkEnvelope(t, w, http.StatusServiceUnavailable, c.code)
+			if want := c.code == model.CodeEnvironmentUnavailable; (e.EnvironmentError != nil) != want {
+				t.Fatalf("environmentError %+v, want set %v", e.EnvironmentError, want)
+			} else if want && (e.EnvironmentError.Reason != string(worker.EnvironmentNamespaceLimit) || e.EnvironmentError.Errno != uint64(syscall.ENOSPC)) {
+				t.Fatalf("environmentError %+v", e.EnvironmentError)
+			}
+		})
+	}
+}
+
+func TestAdminErrorEnvelope(t *testing.T) {
+	policyConf := filepath.Join(t.TempDir(), "policy.yaml")
+	if err := os.WriteFile(policyConf, []byte("not: [valid"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	conf := &config.Config{
+		AuthToken:  []string{"user"},
+		AdminToken: "admin",
+		PolicyConf: policyConf,
+	}
+	r := gin.New()
+	r.Use(tokenAuth(conf.AuthToken, conf.AdminToken))
+	r.GET("/admin/bench", generateHandleBench(nil))
+	r.POST("/admin/reload", generateHandleReload(&configReloader{conf: conf}))
+	initNoRoute(r)
+
+	for _, c := range []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+		code   model.ErrorCode
+	}{
+		{"no token", http.MethodGet, "/admin/bench", "", http.StatusUnauthorized, model.CodeUnauthorized},
+		{"invalid token", http.MethodGet, "/admin/bench", "other", http.StatusUnauthorized, model.CodeUnauthorized},
+		{"not admin", http.MethodGet, "/admin/bench", "user", http.StatusForbidden, model.CodeForbidden},
+		{"disabled", http.MethodGet, "/admin/bench", "admin", http.StatusNotFound, model.CodeFeatureDisabled},
+		{"invalid config", http.MethodPost, "/admin/reload", "admin", http.StatusUnprocessableEntity, model.CodeInvalidConfig},
+		{"no route", http.MethodGet, "/admin/nope", "admin", http.StatusNotFound, model.CodeNotFound},
+		{"no method", http.MethodDelete, "/admin/bench", "admin", http.StatusMethodNotAllowed, model.CodeMethodNotAllowed},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(c.method, c.path, nil)
+			if c.token != "" {
+				req.Header.Set("Authorization", "Bearer "+c.token)
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			checkEnvelope(t, w, c.status, c.code)
+		})
+	}
+}
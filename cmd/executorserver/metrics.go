@@ -1,7 +1,10 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"os"
+	"strconv"
 	"sync"
 
 	"github.com/criyle/go-judge/env/pool"
@@ -16,6 +19,10 @@ const (
 	execSubsystem        = "exec"
 	filestoreSubsystem   = "file"
 	environmentSubsystem = "environment"
+	usageSubsystem       = "usage"
+	auditSubsystem       = "audit"
+	historySubsystem     = "history"
+	benchSubsystem       = "bench"
 )
 
 var (
@@ -45,6 +52,61 @@ var (
 		Buckets:   timeBuckets,
 	}, []string{"status"})
 
+	execLeakCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: execSubsystem,
+		Name:      "leak_count",
+		Help:      "Number of runs that did not release their fds or goroutines after finished",
+	})
+
+	execEstimateExceededCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: execSubsystem,
+		Name:      "estimate_exceeded_count",
+		Help:      "Number of requests that ran longer than -estimate-factor times their estimated duration",
+	})
+
+	execPanicCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: execSubsystem,
+		Name:      "panic_count",
+		Help:      "Number of runs that panicked and failed with Internal Error",
+	})
+
+	execNoisyNeighborCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: execSubsystem,
+		Name:      "noisy_neighbor_count",
+		Help:      "Number of runs exceeding the -noisy-neighbor-* thresholds, by the usage exceeded",
+	}, []string{"usage"})
+
+	execClientCancelledCount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: execSubsystem,
+		Name:      "client_cancelled_count",
+		Help:      "Number of requests cancelled by the client before finished, by whether it was queued or running",
+	}, []string{"state"})
+
+	execFiles = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: execSubsystem,
+		Name:      "current_files_count",
+		Help:      "Total number of fds currently opened by runs",
+	}, func() float64 {
+		files, _ := envexec.ResourceUsage()
+		return float64(files)
+	})
+
+	execGoroutines = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: execSubsystem,
+		Name:      "current_goroutines_count",
+		Help:      "Total number of goroutines currently running for runs",
+	}, func() float64 {
+		_, goroutines := envexec.ResourceUsage()
+		return float64(goroutines)
+	})
+
 	execMemHist = prometheus.NewHistogramVec(prometheus.HistogramOpts{
 		Namespace: metricsNamespace,
 		Subsystem: execSubsystem,
@@ -75,6 +137,13 @@ var (
 		Help:      "Total size of current files in the file store",
 	})
 
+	fsCorruptedCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: filestoreSubsystem,
+		Name:      "corrupted_count",
+		Help:      "Number of cached files found corrupted on copy in",
+	})
+
 	envCreated = prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: metricsNamespace,
 		Subsystem: environmentSubsystem,
@@ -88,14 +157,319 @@ var (
 		Name:      "current_count",
 		Help:      "Total number of environment currently in use",
 	})
+
+	envCgroupRetry = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: environmentSubsystem,
+		Name:      "cgroup_retry_count",
+		Help:      "Number of commands retried on transient cgroup failures, by whether it started after retries",
+	}, []string{"result"})
+
+	envStaleCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: environmentSubsystem,
+		Name:      "stale_count",
+		Help:      "Number of idle environments destroyed since their mounts have gone stale",
+	})
+
+	envInitLogDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: environmentSubsystem,
+		Name:      "init_log_dropped_bytes_count",
+		Help:      "Total bytes of container init stderr dropped since the buffer of the environment is full",
+	})
+
+	auditDroppedCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: auditSubsystem,
+		Name:      "dropped_count",
+		Help:      "Number of audit log entries dropped since the buffer is full or failed to write",
+	})
+
+	historyDroppedCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: historySubsystem,
+		Name:      "dropped_count",
+		Help:      "Number of history entries dropped since the buffer is full or failed to write",
+	})
+
+	benchBaselineSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: benchSubsystem,
+		Name:      "baseline_seconds",
+		Help:      "CPU time of the benchmark measured after startup",
+	})
+
+	benchCPUTimeSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: benchSubsystem,
+		Name:      "cpu_time_seconds",
+		Help:      "CPU time of the last benchmark sample",
+	})
+
+	benchDrift = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: benchSubsystem,
+		Name:      "drift",
+		Help:      "Drift of the cpu time of the last benchmark sample from the baseline, 0.1 for 10% slower",
+	})
+
+	benchShedding = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Subsystem: benchSubsystem,
+		Name:      "shedding",
+		Help:      "1 if the parallelism is capped since the benchmark drift exceeded -bench-shed-drift",
+	})
+
+	benchSkippedCount = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Subsystem: benchSubsystem,
+		Name:      "skipped_count",
+		Help:      "Number of benchmark checks skipped since no worker was free or requests were waiting",
+	})
 )
 
-func init() {
-	prometheus.MustRegister(execErrorCount)
-	prometheus.MustRegister(execTimeHist)
-	prometheus.MustRegister(execMemHist)
-	prometheus.MustRegister(fsSizeHist, fsCurrentTotalCount, fsCurrentTotalSize)
-	prometheus.MustRegister(envCreated, envInUse)
+// registerMetrics registers the metrics with instance_id label
+func registerMetrics(instanceID string) {
+	r := prometheus.WrapRegistererWith(prometheus.Labels{"instance_id": instanceID}, prometheus.DefaultRegisterer)
+	r.MustRegister(execErrorCount)
+	r.MustRegister(execTimeHist)
+	r.MustRegister(execMemHist)
+	r.MustRegister(execLeakCount, execFiles, execGoroutines, execPanicCount)
+	r.MustRegister(execClientCancelledCount, execEstimateExceededCount, execNoisyNeighborCount)
+	r.MustRegister(fsSizeHist, fsCurrentTotalCount, fsCurrentTotalSize, fsCorruptedCount)
+	r.MustRegister(envCreated, envInUse, envCgroupRetry, envStaleCount, envInitLogDropped)
+	r.MustRegister(auditDroppedCount, historyDroppedCount)
+	r.MustRegister(benchBaselineSeconds, benchCPUTimeSeconds, benchDrift, benchShedding, benchSkippedCount)
+}
+
+// registerNUMAMetrics registers the utilization of each NUMA node
+func registerNUMAMetrics(instanceID string, p pool.NUMAPool) {
+	r := prometheus.WrapRegistererWith(prometheus.Labels{"instance_id": instanceID}, prometheus.DefaultRegisterer)
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, environmentSubsystem, name), help, []string{"node"}, nil)
+	}
+	r.MustRegister(&numaCollector{
+		pool:  p,
+		inUse: desc("numa_in_use_count", "Number of environments currently in use by NUMA node"),
+		idle:  desc("numa_idle_count", "Number of idle environments by NUMA node"),
+	})
+}
+
+// registerWorkerMetrics registers the metrics reported by the worker
+func registerWorkerMetrics(instanceID string, work worker.Worker) {
+	r := prometheus.WrapRegistererWith(prometheus.Labels{"instance_id": instanceID}, prometheus.DefaultRegisterer)
+	r.MustRegister(&queueDepthCollector{
+		work: work,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, execSubsystem, "queue_depth"),
+			"Number of requests waiting in the worker queue by client",
+			[]string{"client"}, nil),
+	})
+	r.MustRegister(newUsageCollector(work))
+	r.MustRegister(newContentionCollector(work))
+	r.MustRegister(&priorityDepthCollector{
+		work: work,
+		desc: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, execSubsystem, "queue_priority_depth"),
+			"Number of requests waiting in the worker queue by priority",
+			[]string{"priority"}, nil),
+	})
+	r.MustRegister(&memoryReservationCollector{
+		work: work,
+		total: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, execSubsystem, "memory_total_bytes"),
+			"Total memory the reservations of running requests fit into, 0 if not held by reservation",
+			nil, nil),
+		reserved: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, execSubsystem, "memory_reserved_bytes"),
+			"Memory reserved by the memory limits of running requests",
+			nil, nil),
+		used: prometheus.NewDesc(
+			prometheus.BuildFQName(metricsNamespace, execSubsystem, "memory_used_bytes"),
+			"Memory used by the running commands so far",
+			nil, nil),
+	})
+}
+
+// contentionCollector reports the worker loops and how long the requests
+// waited for them and for the environments
+type contentionCollector struct {
+	work                           worker.Worker
+	parallelism, busy, index, wait *prometheus.Desc
+}
+
+func newContentionCollector(work worker.Worker) *contentionCollector {
+	desc := func(name, help string, labels ...string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, execSubsystem, name), help, labels, nil)
+	}
+	return &contentionCollector{
+		work:        work,
+		parallelism: desc("parallelism", "Number of worker loops, changed by -auto-parallelism"),
+		busy:        desc("busy_count", "Number of worker loops running requests"),
+		index:       desc("contention_index", "Share of the latency of the requests finished within the last minute spent waiting for a worker loop (queue) or the environments (env)", "wait"),
+		wait:        desc("wait_seconds_total", "Time the requests run by the worker loops waited for a loop (queue) or the environments (env)", "wait"),
+	}
+}
+
+func (c *contentionCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.parallelism
+	ch <- c.busy
+	ch <- c.index
+	ch <- c.wait
+}
+
+func (c *contentionCollector) Collect(ch chan<- prometheus.Metric) {
+	r := c.work.Contention()
+	ch <- prometheus.MustNewConstMetric(c.parallelism, prometheus.GaugeValue, float64(r.Parallelism))
+	ch <- prometheus.MustNewConstMetric(c.busy, prometheus.GaugeValue, float64(r.Busy))
+	ch <- prometheus.MustNewConstMetric(c.index, prometheus.GaugeValue, r.QueueIndex(), "queue")
+	ch <- prometheus.MustNewConstMetric(c.index, prometheus.GaugeValue, r.EnvIndex(), "env")
+	ch <- prometheus.MustNewConstMetric(c.wait, prometheus.CounterValue, r.QueueWaitTotal.Seconds(), "queue")
+	ch <- prometheus.MustNewConstMetric(c.wait, prometheus.CounterValue, r.EnvWaitTotal.Seconds(), "env")
+}
+
+// queueDepthCollector reports the queue depth of each client, client is the
+// file store namespace of the auth token and "default" for the admin token or
+// when auth is not enabled
+type queueDepthCollector struct {
+	work worker.Worker
+	desc *prometheus.Desc
+}
+
+func (c *queueDepthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *queueDepthCollector) Collect(ch chan<- prometheus.Metric) {
+	for client, depth := range c.work.QueueDepth() {
+		if client == "" {
+			client = "default"
+		}
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(depth), client)
+	}
+}
+
+// priorityDepthCollector reports the queue depth of each priority
+type priorityDepthCollector struct {
+	work worker.Worker
+	desc *prometheus.Desc
+}
+
+func (c *priorityDepthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *priorityDepthCollector) Collect(ch chan<- prometheus.Metric) {
+	for p, depth := range c.work.PriorityDepth() {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(depth), p.String())
+	}
+}
+
+// memoryReservationCollector reports the memory reserved against used by the
+// running requests
+type memoryReservationCollector struct {
+	work                  worker.Worker
+	total, reserved, used *prometheus.Desc
+}
+
+func (c *memoryReservationCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.total
+	ch <- c.reserved
+	ch <- c.used
+}
+
+func (c *memoryReservationCollector) Collect(ch chan<- prometheus.Metric) {
+	r := c.work.MemoryReservation()
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(r.Total))
+	ch <- prometheus.MustNewConstMetric(c.reserved, prometheus.GaugeValue, float64(r.Reserved))
+	ch <- prometheus.MustNewConstMetric(c.used, prometheus.GaugeValue, float64(r.Used))
+}
+
+// numaCollector reports the environments of each NUMA node
+type numaCollector struct {
+	pool        pool.NUMAPool
+	inUse, idle *prometheus.Desc
+}
+
+func (c *numaCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.inUse
+	ch <- c.idle
+}
+
+func (c *numaCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, s := range c.pool.NUMAStat() {
+		node := strconv.Itoa(s.ID)
+		ch <- prometheus.MustNewConstMetric(c.inUse, prometheus.GaugeValue, float64(s.InUse), node)
+		ch <- prometheus.MustNewConstMetric(c.idle, prometheus.GaugeValue, float64(s.Idle), node)
+	}
+}
+
+// usageCollector reports the usage of each client, client is the same as
+// queueDepthCollector
+type usageCollector struct {
+	work                                    worker.Worker
+	runs, cpuTime, memorySeconds, fileBytes *prometheus.Desc
+}
+
+func newUsageCollector(work worker.Worker) *usageCollector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(metricsNamespace, usageSubsystem, name), help, []string{"client"}, nil)
+	}
+	return &usageCollector{
+		work:          work,
+		runs:          desc("runs_count", "Total number of requests executed by client"),
+		cpuTime:       desc("cpu_seconds", "Total cpu time consumed by client"),
+		memorySeconds: desc("memory_byte_seconds", "Total peak memory times run time consumed by client"),
+		fileBytes:     desc("file_bytes", "Size of files owned in the file store by client"),
+	}
+}
+
+func (c *usageCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.runs
+	ch <- c.cpuTime
+	ch <- c.memorySeconds
+	ch <- c.fileBytes
+}
+
+func (c *usageCollector) Collect(ch chan<- prometheus.Metric) {
+	for client, u := range c.work.Usage() {
+		if client == "" {
+			client = "default"
+		}
+		ch <- prometheus.MustNewConstMetric(c.runs, prometheus.CounterValue, float64(u.Total.Runs), client)
+		ch <- prometheus.MustNewConstMetric(c.cpuTime, prometheus.CounterValue, u.Total.CPUTime.Seconds(), client)
+		ch <- prometheus.MustNewConstMetric(c.memorySeconds, prometheus.CounterValue, u.Total.MemorySeconds, client)
+		ch <- prometheus.MustNewConstMetric(c.fileBytes, prometheus.GaugeValue, float64(u.FileBytes), client)
+	}
+}
+
+func envStaleObserve(err error) {
+	logger.Sugar().Warn("Environment destroyed: ", err)
+	envStaleCount.Inc()
+}
+
+func envInitLogObserve(dropped int) {
+	envInitLogDropped.Add(float64(dropped))
+}
+
+func execCancelObserve(running bool) {
+	state := "queued"
+	if running {
+		state = "running"
+	}
+	execClientCancelledCount.WithLabelValues(state).Inc()
+}
+
+// execObserver returns the observer of the responses, which counts the
+// requests exceeding factor times their estimated duration if factor > 0
+func execObserver(factor float64) func(worker.Response) {
+	return func(res worker.Response) {
+		execObserve(res)
+		if factor > 0 && res.EstimatedDuration > 0 && float64(res.Duration) > factor*float64(res.EstimatedDuration) {
+			execEstimateExceededCount.Inc()
+		}
+	}
 }
 
 func execObserve(res worker.Response) {
@@ -109,6 +483,27 @@ func execObserve(res worker.Response) {
 
 		execTimeHist.WithLabelValues(status).Observe(time)
 		execMemHist.WithLabelValues(status).Observe(memory)
+
+		if r.Status == envexec.StatusInternalError && r.EnvironmentID > 0 {
+			logger.Sugar().Warnf("Internal Error on environment %d cgroup %d: %s", r.EnvironmentID, r.CgroupID, r.Error)
+		}
+		if r.AttachRetries > 0 {
+			result := "recovered"
+			if r.Status == envexec.StatusInternalError {
+				result = "failed"
+			}
+			envCgroupRetry.WithLabelValues(result).Inc()
+		}
+
+		for _, u := range r.NoisyNeighbor {
+			execNoisyNeighborCount.WithLabelValues(u).Inc()
+		}
+
+		for _, e := range r.FileError {
+			if e.Type == envexec.ErrCopyInCorrupted {
+				fsCorruptedCount.Inc()
+			}
+		}
 	}
 }
 
@@ -128,13 +523,17 @@ func newMetricsFileStore(fs filestore.FileStore) filestore.FileStore {
 }
 
 func (m *metricsFileStore) Add(name, path string) (string, error) {
-	id, err := m.FileStore.Add(name, path)
+	return m.AddMeta(filestore.FileMeta{Name: name}, path)
+}
+
+func (m *metricsFileStore) AddMeta(meta filestore.FileMeta, path string) (string, error) {
+	// stat before added since the file store may rename it
+	fi, statErr := os.Stat(path)
+	id, err := m.FileStore.AddMeta(meta, path)
 	if err != nil {
 		return "", err
 	}
-
-	fi, err := os.Stat(path)
-	if err != nil {
+	if statErr != nil {
 		return id, nil
 	}
 
@@ -152,6 +551,23 @@ func (m *metricsFileStore) Add(name, path string) (string, error) {
 	return id, nil
 }
 
+func (m *metricsFileStore) ImportMeta(id string, meta filestore.FileMeta, size int64) error {
+	if err := filestore.ImportMeta(m.FileStore, id, meta, size); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.fileSize[id]; ok {
+		return nil
+	}
+	m.fileSize[id] = size
+	fsCurrentTotalSize.Add(float64(size))
+	fsCurrentTotalCount.Inc()
+	return nil
+}
+
 func (m *metricsFileStore) Remove(id string) bool {
 	success := m.FileStore.Remove(id)
 
@@ -186,14 +602,65 @@ func (b *metriceEnvBuilder) Build() (pool.Environment, error) {
 	return e, nil
 }
 
-var _ worker.EnvironmentPool = &metricsEnvPool{}
+var errOptionsNotSupported = errors.New("environment options are not supported")
+
+// BuildOptions forwards pool.OptionsEnvBuilder of the wrapped builder so that
+// the environments with options are counted
+func (b *metriceEnvBuilder) BuildOptions(opts envexec.EnvironmentOptions) (pool.Environment, error) {
+	ob, ok := b.EnvBuilder.(pool.OptionsEnvBuilder)
+	if !ok {
+		return nil, errOptionsNotSupported
+	}
+	e, err := ob.BuildOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	envCreated.Inc()
+	return e, nil
+}
+
+func (b *metriceEnvBuilder) ValidOptions(opts envexec.EnvironmentOptions) error {
+	ob, ok := b.EnvBuilder.(pool.OptionsEnvBuilder)
+	if !ok {
+		return errOptionsNotSupported
+	}
+	return ob.ValidOptions(opts)
+}
+
+func (b *metriceEnvBuilder) IOAccounting() bool {
+	ib, ok := b.EnvBuilder.(pool.IOEnvBuilder)
+	return ok && ib.IOAccounting()
+}
+
+func (b *metriceEnvBuilder) Prepare() error {
+	if pb, ok := b.EnvBuilder.(pool.PrepareEnvBuilder); ok {
+		return pb.Prepare()
+	}
+	return nil
+}
+
+// NUMANode binds the wrapped builder so that the environments of the node
+// are still counted
+func (b *metriceEnvBuilder) NUMANode(n pool.NUMANode) (pool.EnvBuilder, bool) {
+	nb, ok := b.EnvBuilder.(pool.NUMAEnvBuilder)
+	if !ok {
+		return nil, false
+	}
+	e, ok := nb.NUMANode(n)
+	if !ok {
+		return nil, false
+	}
+	return &metriceEnvBuilder{e}, true
+}
+
+var _ pool.Pool = &metricsEnvPool{}
 
 type metricsEnvPool struct {
-	worker.EnvironmentPool
+	pool.Pool
 }
 
-func (p *metricsEnvPool) Get() (envexec.Environment, error) {
-	e, err := p.EnvironmentPool.Get()
+func (p *metricsEnvPool) Get(ctx context.Context, opts worker.EnvironmentOptions) (envexec.Environment, error) {
+	e, err := p.Pool.Get(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
@@ -202,6 +669,35 @@ func (p *metricsEnvPool) Get() (envexec.Environment, error) {
 }
 
 func (p *metricsEnvPool) Put(env envexec.Environment) {
-	p.EnvironmentPool.Put(env)
+	p.Pool.Put(env)
+	envInUse.Dec()
+}
+
+func (p *metricsEnvPool) Discard(env envexec.Environment) {
+	p.Pool.Discard(env)
 	envInUse.Dec()
 }
+
+var _ pool.NUMAPool = &metricsNUMAEnvPool{}
+
+// metricsNUMAEnvPool counts the environments got from the pools of the nodes
+type metricsNUMAEnvPool struct {
+	metricsEnvPool
+	numa pool.NUMAPool
+}
+
+func (p *metricsNUMAEnvPool) NUMANodes() []int {
+	return p.numa.NUMANodes()
+}
+
+func (p *metricsNUMAEnvPool) NUMANode(node int) worker.EnvironmentPool {
+	return p.NodePool(node)
+}
+
+func (p *metricsNUMAEnvPool) NodePool(node int) pool.Pool {
+	return &metricsEnvPool{p.numa.NodePool(node)}
+}
+
+func (p *metricsNUMAEnvPool) NUMAStat() []pool.NUMANodeStat {
+	return p.numa.NUMAStat()
+}
@@ -0,0 +1,150 @@
+package model
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/criyle/go-judge/worker"
+)
+
+// CalibrateRequest defines the body of POST /calibrate, the request of a
+// single command is run warmup + repeat times back-to-back in the same
+// environment, which is capped by -calibrate-max
+type CalibrateRequest struct {
+	Request Request `json:"request"`
+	Repeat  int     `json:"repeat"`           // repetitions measured
+	Warmup  int     `json:"warmup,omitempty"` // repetitions run before the measured ones and discarded
+}
+
+// Calibration defines the response of POST /calibrate, the times are in ns
+type Calibration struct {
+	Repeat    int          `json:"repeat"` // samples measured, fewer than requested if cancelled or a repetition failed to run
+	CPUTime   Distribution `json:"cpuTime"`
+	ClockTime Distribution `json:"clockTime"`
+
+	// Stability is 1 - the coefficient of variation of the cpu time (0 if
+	// it varies more than its mean), 1 if all samples are the same
+	Stability float64 `json:"stability"`
+
+	Status       map[string]int         `json:"status"` // number of samples of each status
+	Samples      []CalibrationSample    `json:"samples"`
+	Limits       *Limits                `json:"limits,omitempty"` // limits applied to the command
+	Environment  CalibrationEnvironment `json:"environment"`
+	CancelReason string                 `json:"cancelReason,omitempty"`
+}
+
+// Distribution defines the statistics of the samples
+type Distribution struct {
+	Min    uint64 `json:"min"`
+	Median uint64 `json:"median"`
+	P95    uint64 `json:"p95"`
+	Max    uint64 `json:"max"`
+	Mean   uint64 `json:"mean"`
+	StdDev uint64 `json:"stdDev"`
+}
+
+// CalibrationSample defines the result of each measured repetition
+type CalibrationSample struct {
+	Status     Status `json:"status"`
+	ExitStatus int    `json:"exitStatus"`
+	Time       uint64 `json:"time"`
+	RunTime    uint64 `json:"runTime"`
+	Memory     uint64 `json:"memory"`
+	Error      string `json:"error,omitempty"`
+}
+
+// CalibrationEnvironment describes where the repetitions ran
+type CalibrationEnvironment struct {
+	Hostname   string `json:"hostname,omitempty"`
+	InstanceID string `json:"instanceId,omitempty"`
+	Worker     int    `json:"worker"`             // index of the worker loop
+	NUMANode   *int   `json:"numaNode,omitempty"` // pinned by the request
+	CPUSet     string `json:"cpuSet,omitempty"`   // cpuSetLimit of the command
+	CPUModel   string `json:"cpuModel,omitempty"` // model name of the host processors
+	Arch       string `json:"arch,omitempty"`
+}
+
+// ConvertCalibration summarizes the results of the repeated request after
+// the warmup ones, the files of the results are closed
+func ConvertCalibration(r worker.Response, req *worker.Request, warmup int, cpuModel string) Calibration {
+	rt := Calibration{
+		Status:       make(map[string]int),
+		Samples:      make([]CalibrationSample, 0, len(r.Results)),
+		CancelReason: string(r.CancelReason),
+		Environment: CalibrationEnvironment{
+			Worker:   r.Worker,
+			NUMANode: req.NUMANode,
+			CPUModel: cpuModel,
+		},
+	}
+	if len(req.Cmd) > 0 {
+		rt.Limits = convertLimits(&req.Cmd[0])
+		rt.Environment.CPUSet = req.Cmd[0].CPUSetLimit
+	}
+	var cpu, clock []time.Duration
+	for i, res := range r.Results {
+		for _, f := range res.Files {
+			f.Close()
+		}
+		rt.Environment.Hostname = res.Hostname
+		rt.Environment.InstanceID = res.InstanceID
+		if res.Arch != "" {
+			rt.Environment.Arch = res.Arch
+		}
+		if i < warmup {
+			continue
+		}
+		rt.Samples = append(rt.Samples, CalibrationSample{
+			Status:     Status(res.Status),
+			ExitStatus: res.ExitStatus,
+			Time:       uint64(res.Time),
+			RunTime:    uint64(res.RunTime),
+			Memory:     uint64(res.Memory),
+			Error:      res.Error,
+		})
+		rt.Status[res.Status.String()]++
+		cpu = append(cpu, res.Time)
+		clock = append(clock, res.RunTime)
+	}
+	rt.Repeat = len(rt.Samples)
+	rt.CPUTime = distribution(cpu)
+	rt.ClockTime = distribution(clock)
+	if rt.CPUTime.Mean > 0 {
+		rt.Stability = math.Max(0, 1-float64(rt.CPUTime.StdDev)/float64(rt.CPUTime.Mean))
+	} else if rt.Repeat > 0 {
+		rt.Stability = 1
+	}
+	return rt
+}
+
+// distribution sorts the samples and returns their statistics, the p95 is
+// the nearest rank
+func distribution(s []time.Duration) Distribution {
+	if len(s) == 0 {
+		return Distribution{}
+	}
+	sort.Slice(s, func(i, j int) bool { return s[i] < s[j] })
+	var sum float64
+	for _, t := range s {
+		sum += float64(t)
+	}
+	mean := sum / float64(len(s))
+	var sq float64
+	for _, t := range s {
+		sq += (float64(t) - mean) * (float64(t) - mean)
+	}
+	n := len(s)
+	median := s[n/2]
+	if n%2 == 0 {
+		median = (s[n/2-1] + s[n/2]) / 2
+	}
+	return Distribution{
+		Min:    uint64(s[0]),
+		Median: uint64(median),
+		P95:    uint64(s[int(math.Ceil(0.95*float64(n)))-1]),
+		Max:    uint64(s[n-1]),
+		Mean:   uint64(mean),
+		StdDev: uint64(math.Sqrt(sq / float64(n))),
+	}
+}
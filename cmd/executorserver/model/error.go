@@ -0,0 +1,155 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrorCode is the machine readable code of the error responses of the REST
+// API. The codes are stable, the messages are not. It is also an error so that
+// the APIError of the code could be tested by errors.Is, e.g.
+//
+//	errors.Is(err, model.CodeQueueFull)
+type ErrorCode string
+
+// Error codes of the REST API
+const (
+	// CodeValidationFailed is the request malformed or rejected by the checks
+	CodeValidationFailed ErrorCode = "validationFailed"
+	// CodeRequestTooLarge is the request body or inline content over the limit
+	CodeRequestTooLarge ErrorCode = "requestTooLarge"
+	// CodeChecksumMismatch is the uploaded file not matching its sha256
+	CodeChecksumMismatch ErrorCode = "checksumMismatch"
+	// CodeUnauthorized is the auth token missing or invalid
+	CodeUnauthorized ErrorCode = "unauthorized"
+	// CodeForbidden is the endpoint restricted to the admin token
+	CodeForbidden ErrorCode = "forbidden"
+	// CodePolicyDenied is the request denied by the policy of the auth token
+	CodePolicyDenied ErrorCode = "policyDenied"
+	// CodeFeatureDisabled is the feature not enabled in the server config
+	CodeFeatureDisabled ErrorCode = "featureDisabled"
+	// CodeNotFound is the route or the resource (e.g. job) not found
+	CodeNotFound ErrorCode = "notFound"
+	// CodeMethodNotAllowed is the route not serving the method
+	CodeMethodNotAllowed ErrorCode = "methodNotAllowed"
+	// CodeFileNotFound is the file not found in the file store
+	CodeFileNotFound ErrorCode = "fileNotFound"
+	// CodeConflict is the request conflicting with the state of the server
+	CodeConflict ErrorCode = "conflict"
+	// CodeInvalidConfig is the config reloaded rejected
+	CodeInvalidConfig ErrorCode = "invalidConfig"
+	// CodeQueueFull is the worker queue full, retry later
+	CodeQueueFull ErrorCode = "queueFull"
+	// CodeQuotaExceeded is the quota of the auth token exhausted, see quotaError
+	CodeQuotaExceeded ErrorCode = "quotaExceeded"
+	// CodeStoreUnavailable is the file store not available
+	CodeStoreUnavailable ErrorCode = "storeUnavailable"
+	// CodeEnvironmentUnavailable is the environment could not be created, see
+	// environmentError
+	CodeEnvironmentUnavailable ErrorCode = "environmentUnavailable"
+	// CodeResourceLeak is the server degraded by the resources leaked by a run
+	// with -strict-leak-check, reported by /health
+	CodeResourceLeak ErrorCode = "resourceLeak"
+	// CodeShuttingDown is the server shutting down
+	CodeShuttingDown ErrorCode = "shuttingDown"
+	// CodeCancelled is the request cancelled before it finished
+	CodeCancelled ErrorCode = "cancelled"
+	// CodeInternal is any other error of the server
+	CodeInternal ErrorCode = "internal"
+)
+
+// ErrorCodes lists the error codes of the REST API
+var ErrorCodes = []ErrorCode{
+	CodeValidationFailed, CodeRequestTooLarge, CodeChecksumMismatch, CodeUnauthorized, CodeForbidden,
+	CodePolicyDenied, CodeFeatureDisabled, CodeNotFound, CodeMethodNotAllowed, CodeFileNotFound,
+	CodeConflict, CodeInvalidConfig, CodeQueueFull, CodeQuotaExceeded, CodeStoreUnavailable,
+	CodeEnvironmentUnavailable, CodeResourceLeak, CodeShuttingDown, CodeCancelled, CodeInternal,
+}
+
+func (c ErrorCode) Error() string {
+	return string(c)
+}
+
+// ErrorResponse defines the body of every non-200 response of the REST API
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody defines the error of ErrorResponse
+type ErrorBody struct {
+	Code    ErrorCode    `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"` // the fields of the request at fault if known
+
+	EnvironmentError *EnvironmentError `json:"environmentError,omitempty"` // set for environmentUnavailable
+	QuotaError       *QuotaError       `json:"quotaError,omitempty"`       // set for quotaExceeded
+}
+
+// FieldError defines the field of the request at fault, e.g. cmd[0].cpuLimit
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// NewErrorResponse creates the error response of the code
+func NewErrorResponse(code ErrorCode, msg string, fields ...FieldError) ErrorResponse {
+	return ErrorResponse{Error: ErrorBody{Code: code, Message: msg, Fields: fields}}
+}
+
+// APIError is the error response received by the client
+type APIError struct {
+	StatusCode int
+	ErrorBody
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// Unwrap returns the code, so that errors.Is matches the code
+func (e *APIError) Unwrap() error {
+	return e.Code
+}
+
+// ParseError parses the body of the non-200 response into APIError. The body
+// not in the envelope, e.g. from a proxy or an older server, is taken as the
+// message with the code guessed from the status.
+func ParseError(statusCode int, body []byte) error {
+	var r ErrorResponse
+	if err := json.Unmarshal(body, &r); err == nil && r.Error.Code != "" {
+		return &APIError{StatusCode: statusCode, ErrorBody: r.Error}
+	}
+	msg := strings.TrimSpace(string(body))
+	var s string
+	if err := json.Unmarshal(body, &s); err == nil {
+		msg = s
+	}
+	return &APIError{
+		StatusCode: statusCode,
+		ErrorBody:  ErrorBody{Code: statusErrorCode(statusCode), Message: msg},
+	}
+}
+
+func statusErrorCode(statusCode int) ErrorCode {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return CodeValidationFailed
+	case http.StatusUnauthorized:
+		return CodeUnauthorized
+	case http.StatusForbidden:
+		return CodeForbidden
+	case http.StatusNotFound:
+		return CodeNotFound
+	case http.StatusMethodNotAllowed:
+		return CodeMethodNotAllowed
+	case http.StatusConflict:
+		return CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		return CodeRequestTooLarge
+	case http.StatusTooManyRequests:
+		return CodeQueueFull
+	}
+	return CodeInternal
+}
@@ -0,0 +1,466 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/criyle/go-judge/worker"
+)
+
+// Duration is a time limit in nanoseconds. It is decoded from a number of
+// nanoseconds or a string with unit (e.g. "2s", "1500ms", "1.5s") and encoded
+// as the string, so that clients using the type never send 2ns for 2s.
+type Duration uint64
+
+// Size is a size limit in bytes. It is decoded from a number of bytes or a
+// string with binary unit (e.g. "256m", "1g", "512kib") and encoded as the
+// string.
+type Size uint64
+
+var (
+	durationType = reflect.TypeOf(Duration(0))
+	sizeType     = reflect.TypeOf(Size(0))
+
+	errUnitRequired = errors.New("unit is required for string")
+)
+
+// ParseDuration parses the duration with unit, a number without unit is
+// rejected since it is ambiguous
+func ParseDuration(s string) (Duration, error) {
+	if s == "" || strings.TrimLeft(s, "0123456789.") == "" {
+		return 0, errUnitRequired
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 {
+		return 0, errors.New("negative duration")
+	}
+	return Duration(d), nil
+}
+
+// String formats the duration as time.Duration does, e.g. 1.5s
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+// MarshalJSON encodes the duration as the string with unit
+func (d Duration) MarshalJSON() ([]byte, error) {
+	if d > math.MaxInt64 {
+		return json.Marshal(uint64(d))
+	}
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON decodes the duration from nanoseconds or the string with unit
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	n, s, err := decodeLimit(b)
+	if err != nil || s == "" {
+		*d = Duration(n)
+		return err
+	}
+	v, err := ParseDuration(s)
+	if err != nil {
+		return &LimitError{Value: string(b), Err: err, Example: `"2s" or "1500ms"`}
+	}
+	*d = v
+	return nil
+}
+
+// sizeUnits are the shifts of the binary units without b / ib suffix
+var sizeUnits = map[string]uint{
+	"":  0,
+	"k": 10,
+	"m": 20,
+	"g": 30,
+	"t": 40,
+}
+
+// ParseSize parses the size with binary unit (case insensitive b, k, m, g, t
+// with optional b or ib suffix), the value must be whole bytes and a number
+// without unit is rejected since it is ambiguous
+func ParseSize(s string) (Size, error) {
+	l := strings.ToLower(s)
+	i := strings.IndexFunc(l, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		return 0, errUnitRequired
+	}
+	num, unit := l[:i], l[i:]
+	switch {
+	case unit == "b":
+		unit = ""
+	case strings.HasSuffix(unit, "ib"):
+		unit = strings.TrimSuffix(unit, "ib")
+	default:
+		unit = strings.TrimSuffix(unit, "b")
+	}
+	shift, ok := sizeUnits[unit]
+	if !ok || (unit == "" && l[i:] != "b") {
+		return 0, fmt.Errorf("invalid size unit %q", l[i:])
+	}
+	r, ok := new(big.Rat).SetString(num)
+	if !ok {
+		return 0, fmt.Errorf("invalid size number %q", num)
+	}
+	r.Mul(r, new(big.Rat).SetInt(new(big.Int).Lsh(big.NewInt(1), shift)))
+	if !r.IsInt() {
+		return 0, errors.New("size is not whole bytes")
+	}
+	if !r.Num().IsUint64() {
+		return 0, errors.New("size overflows")
+	}
+	return Size(r.Num().Uint64()), nil
+}
+
+// String formats the size with the largest unit that divides it, e.g. 256m
+func (s Size) String() string {
+	for _, u := range []struct {
+		unit  string
+		shift uint
+	}{{"t", 40}, {"g", 30}, {"m", 20}, {"k", 10}} {
+		if s != 0 && s&(1<<u.shift-1) == 0 {
+			return strconv.FormatUint(uint64(s>>u.shift), 10) + u.unit
+		}
+	}
+	return strconv.FormatUint(uint64(s), 10) + "b"
+}
+
+// MarshalJSON encodes the size as the string with unit
+func (s Size) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.String())
+}
+
+// UnmarshalJSON decodes the size from bytes or the string with unit
+func (s *Size) UnmarshalJSON(b []byte) error {
+	n, str, err := decodeLimit(b)
+	if err != nil || str == "" {
+		*s = Size(n)
+		return err
+	}
+	v, err := ParseSize(str)
+	if err != nil {
+		return &LimitError{Value: string(b), Err: err, Example: `"256m" or "1g"`}
+	}
+	*s = v
+	return nil
+}
+
+// LimitError reports the malformed limit
+type LimitError struct {
+	Field   string // json name of the field, set by Cmd
+	Value   string // json value
+	Err     error
+	Example string
+}
+
+func (e *LimitError) Error() string {
+	msg := "invalid limit"
+	if e.Field != "" {
+		msg += " " + e.Field
+	}
+	msg += ": " + e.Value + " (" + e.Err.Error()
+	if e.Example != "" {
+		msg += ", e.g. " + e.Example
+	}
+	return msg + ")"
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// decodeLimit decodes the number or returns the string to parse, null keeps
+// zero as the plain uint64 does
+func decodeLimit(b []byte) (uint64, string, error) {
+	if bytes.Equal(b, []byte("null")) {
+		return 0, "", nil
+	}
+	if len(b) > 0 && b[0] == '"' {
+		var s string
+		if err := json.Unmarshal(b, &s); err != nil {
+			return 0, "", err
+		}
+		if s == "" {
+			return 0, "", &LimitError{Value: string(b), Err: errUnitRequired}
+		}
+		return 0, s, nil
+	}
+	n, err := strconv.ParseUint(string(b), 10, 64)
+	if err != nil {
+		return 0, "", &LimitError{Value: string(b), Err: errors.New("not a non-negative integer")}
+	}
+	return n, "", nil
+}
+
+// limitField finds the json name of the limit field of the struct with the
+// value in the object, since the json decoder does not add the field name to
+// the errors of the unmarshalers. The fields of the same value fail in the
+// same way, so that the first one is reported.
+func limitField(b []byte, t reflect.Type, value string) string {
+	var m map[string]json.RawMessage
+	if json.Unmarshal(b, &m) != nil {
+		return ""
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Type != durationType && f.Type != sizeType {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if v, ok := m[name]; ok && string(v) == value {
+			return name
+		}
+	}
+	return ""
+}
+
+// Limits echoes the limits of the command applied by the server in numbers
+// (nanoseconds and bytes), the omitted ones resolved to the server defaults.
+// The limits above the server max are lowered to the max with -limit-policy
+// clamp.
+type Limits struct {
+	CPULimit        LimitValue `json:"cpuLimit"`
+	ClockLimit      LimitValue `json:"clockLimit"` // realCpuLimit if set, not less than cpuLimit
+	MemoryLimit     LimitValue `json:"memoryLimit"`
+	ProcLimit       LimitValue `json:"procLimit"`
+	StackLimit      LimitValue `json:"stackLimit"`
+	IOLimit         uint64     `json:"ioLimit,omitempty"`
+	ShmSize         uint64     `json:"shmSize,omitempty"`
+	CopyOutMax      uint64     `json:"copyOutMax,omitempty"`
+	CopyOutMaxTotal uint64     `json:"copyOutMaxTotal,omitempty"`
+}
+
+// LimitValue is the limit echoed in numbers, encoded as "unlimited" for no
+// limit
+type LimitValue uint64
+
+// MarshalJSON encodes the limit as the number or "unlimited"
+func (v LimitValue) MarshalJSON() ([]byte, error) {
+	if v == worker.Unlimited {
+		return json.Marshal(unlimitedValue)
+	}
+	return json.Marshal(uint64(v))
+}
+
+// UnmarshalJSON decodes the limit from the number or "unlimited"
+func (v *LimitValue) UnmarshalJSON(b []byte) error {
+	if string(b) == `"`+unlimitedValue+`"` {
+		*v = worker.Unlimited
+		return nil
+	}
+	return json.Unmarshal(b, (*uint64)(v))
+}
+
+// unlimitedValue is the value of the limits requesting no limit
+const unlimitedValue = "unlimited"
+
+// explicitLimitFields are the limits of Cmd taking the server default when
+// omitted (or null) and accepting "unlimited", 0 is rejected so that it is
+// never taken for either of them
+var explicitLimitFields = []string{"cpuLimit", "realCpuLimit", "clockLimit", "memoryLimit", "stackLimit", "procLimit"}
+
+var errZeroLimit = errors.New(`zero limit, omit it for the server default or use "unlimited"`)
+
+// splitUnlimited removes the limits of the command given as "unlimited" and
+// notes them in Unlimited
+func (c *Cmd) splitUnlimited(b []byte) ([]byte, error) {
+	if !bytes.Contains(b, []byte(`"`+unlimitedValue+`"`)) {
+		return b, nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return b, nil // reported by the decode of the command
+	}
+	c.Unlimited = nil
+	for _, name := range explicitLimitFields {
+		if string(bytes.TrimSpace(m[name])) == `"`+unlimitedValue+`"` {
+			c.Unlimited = append(c.Unlimited, name)
+			delete(m, name)
+		}
+	}
+	if len(c.Unlimited) == 0 {
+		return b, nil
+	}
+	return json.Marshal(m)
+}
+
+// checkZeroLimits rejects the limits of the decoded command given as 0, the
+// omitted ones are 0 as well so that they are told apart by the json
+func (c *Cmd) checkZeroLimits(b []byte) error {
+	if c.CPULimit != 0 && c.RealCPULimit != 0 && c.ClockLimit != 0 &&
+		c.MemoryLimit != 0 && c.StackLimit != 0 && c.ProcLimit != 0 {
+		return nil
+	}
+	var set struct {
+		CPULimit     json.RawMessage `json:"cpuLimit"`
+		RealCPULimit json.RawMessage `json:"realCpuLimit"`
+		ClockLimit   json.RawMessage `json:"clockLimit"`
+		MemoryLimit  json.RawMessage `json:"memoryLimit"`
+		StackLimit   json.RawMessage `json:"stackLimit"`
+		ProcLimit    json.RawMessage `json:"procLimit"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return err
+	}
+	for _, l := range []struct {
+		name     string
+		value    json.RawMessage
+		zero     bool
+		relative bool
+	}{
+		{"cpuLimit", set.CPULimit, c.CPULimit == 0, c.RelativeCPULimit != nil},
+		{"realCpuLimit", set.RealCPULimit, c.RealCPULimit == 0, false},
+		{"clockLimit", set.ClockLimit, c.ClockLimit == 0, c.RelativeClockLimit != nil},
+		{"memoryLimit", set.MemoryLimit, c.MemoryLimit == 0, c.RelativeMemoryLimit != nil},
+		{"stackLimit", set.StackLimit, c.StackLimit == 0, false},
+		{"procLimit", set.ProcLimit, c.ProcLimit == 0, false},
+	} {
+		if !l.zero || l.relative || l.value == nil || string(l.value) == "null" {
+			continue
+		}
+		return &LimitError{Field: l.name, Value: string(l.value), Err: errZeroLimit}
+	}
+	return nil
+}
+
+func convertLimits(c *worker.Cmd) *Limits {
+	// the worker waits for the cpu limit at least
+	clockLimit := c.ClockLimit
+	if clockLimit < c.CPULimit {
+		clockLimit = c.CPULimit
+	}
+	return &Limits{
+		CPULimit:        LimitValue(c.CPULimit),
+		ClockLimit:      LimitValue(clockLimit),
+		MemoryLimit:     LimitValue(c.MemoryLimit),
+		ProcLimit:       LimitValue(c.ProcLimit),
+		StackLimit:      LimitValue(c.StackLimit),
+		IOLimit:         uint64(c.IOLimit),
+		ShmSize:         uint64(c.ShmSize),
+		CopyOutMax:      uint64(c.CopyOutMax),
+		CopyOutMaxTotal: uint64(c.CopyOutMaxTotal),
+	}
+}
+
+// SetLimits echoes the limits of the commands of the converted request into
+// their results after it was run, so that the limits lowered by the worker are
+// reported
+func (r *Response) SetLimits(req *worker.Request) {
+	for i := range r.Results {
+		if i < len(req.Cmd) {
+			r.Results[i].Limits = convertLimits(&req.Cmd[i])
+		}
+	}
+}
+
+// SetLimits sets the limits of the commands to the ones resolved by the
+// worker (the defaults, the policy and the max applied), so that the dry run
+// echoes the request as it would be run. The relative limits are resolved
+// when run and left as given.
+func (r *Request) SetLimits(req *worker.Request) {
+	for i := range r.Cmd {
+		if i < len(req.Cmd) {
+			r.Cmd[i].setLimits(&req.Cmd[i])
+		}
+	}
+}
+
+func (c *Cmd) setLimits(w *worker.Cmd) {
+	// the clock limit is echoed as realCpuLimit if it was given so
+	clockName, clockLimit := "clockLimit", &c.ClockLimit
+	if c.RealCPULimit > 0 || c.unlimited("realCpuLimit") {
+		clockName, clockLimit = "realCpuLimit", &c.RealCPULimit
+	}
+	c.Unlimited = nil
+	for _, l := range []struct {
+		name      string
+		unlimited bool
+		relative  bool
+		set       func()
+	}{
+		{"cpuLimit", w.CPULimit == worker.UnlimitedDuration, c.RelativeCPULimit != nil,
+			func() { c.CPULimit = Duration(w.CPULimit) }},
+		{clockName, w.ClockLimit == worker.UnlimitedDuration, c.RelativeClockLimit != nil,
+			func() { *clockLimit = Duration(w.ClockLimit) }},
+		{"memoryLimit", w.MemoryLimit == worker.UnlimitedSize, c.RelativeMemoryLimit != nil,
+			func() { c.MemoryLimit = Size(w.MemoryLimit) }},
+		{"stackLimit", w.StackLimit == worker.UnlimitedSize, false,
+			func() { c.StackLimit = Size(w.StackLimit) }},
+		{"procLimit", w.ProcLimit == worker.UnlimitedProc, false,
+			func() { c.ProcLimit = w.ProcLimit }},
+	} {
+		switch {
+		case l.relative:
+		case l.unlimited:
+			c.Unlimited = append(c.Unlimited, l.name)
+		default:
+			l.set()
+		}
+	}
+}
+
+// unlimited reports whether the limit of the name was given as "unlimited"
+func (c *Cmd) unlimited(name string) bool {
+	for _, n := range c.Unlimited {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// MarshalJSON encodes the limits given as "unlimited" or in the relative form
+// back to them and omits the limits not set, so that the encoded command is
+// decoded as the same
+func (c Cmd) MarshalJSON() ([]byte, error) {
+	type cmd Cmd
+	b, err := json.Marshal(cmd(c))
+	if err != nil || len(c.Unlimited) == 0 && c.CPULimit != 0 && c.RealCPULimit != 0 &&
+		c.ClockLimit != 0 && c.MemoryLimit != 0 && c.StackLimit != 0 && c.ProcLimit != 0 {
+		return b, err
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	for name, zero := range map[string]bool{
+		"cpuLimit":     c.CPULimit == 0,
+		"realCpuLimit": c.RealCPULimit == 0,
+		"clockLimit":   c.ClockLimit == 0,
+		"memoryLimit":  c.MemoryLimit == 0,
+		"stackLimit":   c.StackLimit == 0,
+		"procLimit":    c.ProcLimit == 0,
+	} {
+		if zero {
+			delete(m, name)
+		}
+	}
+	for _, name := range c.Unlimited {
+		m[name] = json.RawMessage(`"` + unlimitedValue + `"`)
+	}
+	relative := make(map[string]any)
+	if c.RelativeCPULimit != nil {
+		relative["cpuLimit"] = c.RelativeCPULimit
+	}
+	if c.RelativeClockLimit != nil {
+		relative["clockLimit"] = c.RelativeClockLimit
+	}
+	if c.RelativeMemoryLimit != nil {
+		relative["memoryLimit"] = c.RelativeMemoryLimit
+	}
+	for name, r := range relative {
+		if m[name], err = json.Marshal(r); err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(m)
+}
@@ -0,0 +1,89 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/worker"
+)
+
+func TestRequestSetLimits(t *testing.T) {
+	var req Request
+	if err := json.Unmarshal([]byte(`{"cmd": [
+		{"args": ["a"], "cpuLimit": "1s", "memoryLimit": "unlimited"},
+		{"args": ["b"], "realCpuLimit": "unlimited"},
+		{"args": ["c"], "cpuLimit": {"fromCmd": 0, "multiplier": 2}}
+	]}`), &req); err != nil {
+		t.Fatal(err)
+	}
+	r, err := ConvertRequest(&req, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// resolved as the worker does with the defaults and the max applied
+	r.Cmd[0].CPULimit = 500 * time.Millisecond
+	r.Cmd[0].ClockLimit = time.Second
+	r.Cmd[0].MemoryLimit = 256 << 20
+	r.Cmd[0].StackLimit = 256 << 20
+	r.Cmd[0].ProcLimit = 50
+	r.Cmd[1].CPULimit = time.Second
+	r.Cmd[2].CPULimit = time.Second
+	r.Cmd[2].ProcLimit = 50
+	req.SetLimits(r)
+
+	b, err := json.Marshal(req.Cmd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []map[string]any
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		cmd   int
+		field string
+		want  any
+	}{
+		{0, "cpuLimit", "500ms"},
+		{0, "clockLimit", "1s"},
+		{0, "memoryLimit", "256m"},
+		{0, "stackLimit", "256m"},
+		{0, "procLimit", 50.0},
+		{1, "cpuLimit", "1s"},
+		{1, "realCpuLimit", "unlimited"},
+		{2, "procLimit", 50.0},
+	} {
+		if v := got[c.cmd][c.field]; v != c.want {
+			t.Errorf("cmd[%d].%s = %v, want %v", c.cmd, c.field, v, c.want)
+		}
+	}
+	// the relative limit is resolved when run
+	if r, ok := got[2]["cpuLimit"].(map[string]any); !ok || r["fromCmd"] != 0.0 || r["multiplier"] != 2.0 {
+		t.Errorf("cmd[2].cpuLimit = %v, want the relative form", got[2]["cpuLimit"])
+	}
+	if _, ok := got[1]["clockLimit"]; ok {
+		t.Errorf("cmd[1].clockLimit = %v, want omitted", got[1]["clockLimit"])
+	}
+
+	// the limit of the max applied is no longer unlimited
+	r.Cmd[1].ClockLimit = 10 * time.Second
+	req.SetLimits(r)
+	if req.Cmd[1].RealCPULimit != Duration(10*time.Second) || len(req.Cmd[1].Unlimited) != 0 {
+		t.Errorf("cmd[1]: realCpuLimit %v, unlimited %v", req.Cmd[1].RealCPULimit, req.Cmd[1].Unlimited)
+	}
+	// the unlimited limit is echoed as "unlimited" and decoded back to it
+	r.Cmd[0].MemoryLimit = worker.UnlimitedSize
+	req.SetLimits(r)
+	b, err = json.Marshal(req.Cmd[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back Cmd
+	if err := json.Unmarshal(b, &back); err != nil {
+		t.Fatal(err)
+	}
+	if len(back.Unlimited) != 1 || back.Unlimited[0] != "memoryLimit" {
+		t.Errorf("decoded unlimited %v, want [memoryLimit]", back.Unlimited)
+	}
+}
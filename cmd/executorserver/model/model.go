@@ -1,9 +1,13 @@
 package model
 
 import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"time"
 
@@ -19,7 +23,18 @@ type CmdFile struct {
 	Name    *string `json:"name"`
 	Max     *int64  `json:"max"`
 	Pipe    bool    `json:"pipe"`
+	Cache   bool    `json:"cache,omitempty"`
+	Discard bool    `json:"discard,omitempty"`
 	Symlink *string `json:"symlink"`
+	Archive *string `json:"archive"`
+	Fifo    bool    `json:"fifo,omitempty"`
+
+	CacheTruncated *bool `json:"cacheTruncated,omitempty"`
+
+	// GroupID extracts the group cached by copyOutCachedGroup into the
+	// directory, only valid in copyIn with extract set
+	GroupID *string `json:"groupId,omitempty"`
+	Extract bool    `json:"extract,omitempty"`
 }
 
 // Cmd defines command and limits to start a program using in envexec
@@ -29,37 +44,169 @@ type Cmd struct {
 	Files []*CmdFile `json:"files,omitempty"`
 	TTY   bool       `json:"tty,omitempty"`
 
-	CPULimit          uint64 `json:"cpuLimit"`
-	RealCPULimit      uint64 `json:"realCpuLimit"`
-	ClockLimit        uint64 `json:"clockLimit"`
-	MemoryLimit       uint64 `json:"memoryLimit"`
-	StackLimit        uint64 `json:"stackLimit"`
-	ProcLimit         uint64 `json:"procLimit"`
-	CPURateLimit      uint64 `json:"cpuRateLimit"`
-	CPUSetLimit       string `json:"cpuSetLimit"`
-	StrictMemoryLimit bool   `json:"strictMemoryLimit"`
+	// ExtraFiles binds files to fds from 3 by fd number, the fds not bound
+	// in between are /dev/null
+	ExtraFiles map[int]*CmdFile `json:"extraFiles,omitempty"`
+
+	CPULimit          Duration `json:"cpuLimit"`
+	RealCPULimit      Duration `json:"realCpuLimit"`
+	ClockLimit        Duration `json:"clockLimit"`
+	MemoryLimit       Size     `json:"memoryLimit"`
+	StackLimit        Size     `json:"stackLimit"`
+	ProcLimit         uint64   `json:"procLimit"`
+	CPURateLimit      uint64   `json:"cpuRateLimit"`
+	CPUSetLimit       string   `json:"cpuSetLimit"`
+	StrictMemoryLimit bool     `json:"strictMemoryLimit"`
+	DetailedMemory    bool     `json:"detailedMemory,omitempty"`
+	UID               uint32   `json:"uid,omitempty"`
+	GID               uint32   `json:"gid,omitempty"`
+	RandomizeWorkDir  bool     `json:"randomizeWorkDir,omitempty"`
+
+	Datasets []string `json:"datasets,omitempty"`
+	Profile  string   `json:"profile,omitempty"`
+	IOLimit  Size     `json:"ioLimit,omitempty"`
+
+	CollectCore bool              `json:"collectCore,omitempty"`
+	Trace       bool              `json:"trace,omitempty"`
+	MountFiles  map[string]string `json:"mountFiles,omitempty"`
+
+	ProcInfo     bool   `json:"procInfo,omitempty"`
+	ProcInfoCPUs uint64 `json:"procInfoCpus,omitempty"`
+
+	// RandomSeed replaces /dev/urandom and /dev/random with the deterministic
+	// stream of the seed
+	RandomSeed *uint64 `json:"randomSeed,omitempty"`
+
+	// Arch is the architecture of the program (x86, x86_64, x32, arm, arm64,
+	// riscv64) or auto to detect it from the executable
+	Arch string `json:"arch,omitempty"`
+
+	// ShmSize limits the bytes left in /dev/shm, the server default if 0
+	ShmSize Size `json:"shmSize,omitempty"`
+
+	// DiskWorkDir backs /w and /tmp by a directory on the host disk instead
+	// of tmpfs
+	DiskWorkDir bool `json:"diskWorkDir,omitempty"`
+
+	// ScratchFile mounts an empty file on the host disk with ScratchSize
+	// allocated at the name in /w, the server max if ScratchSize is 0
+	ScratchFile string `json:"scratchFile,omitempty"`
+	ScratchSize Size   `json:"scratchSize,omitempty"`
+
+	// ReportTiming reports the cpu time spent by the container for the run,
+	// which is not included in time, as overheadCpuTime
+	ReportTiming bool `json:"reportTiming,omitempty"`
+
+	// SnapshotWorkDir is save[:<label>] to save the work directory after the
+	// command finished or restore[:<label>] to restore it before the copy in
+	// of a later command, the request must run sequentially
+	SnapshotWorkDir string `json:"snapshotWorkDir,omitempty"`
+
+	// Expand substitutes the variables in args and env, see worker.Expand
+	Expand bool `json:"expand,omitempty"`
 
 	CopyIn map[string]CmdFile `json:"copyIn"`
 
-	CopyOut       []string `json:"copyOut"`
-	CopyOutCached []string `json:"copyOutCached"`
-	CopyOutMax    uint64   `json:"copyOutMax"`
-	CopyOutDir    string   `json:"copyOutDir"`
+	CopyOut       []CopyOutFile `json:"copyOut"`
+	CopyOutCached []CopyOutFile `json:"copyOutCached"`
+	CopyOutMax    Size          `json:"copyOutMax"`
+	CopyOutDir    string        `json:"copyOutDir"`
+
+	CopyOutMaxFiles int  `json:"copyOutMaxFiles"`
+	CopyOutMaxTotal Size `json:"copyOutMaxTotal"`
+
+	// CopyOutCachedGroup caches the regular files matching the patterns
+	// (e.g. *.class) as one group returned in groupId
+	CopyOutCachedGroup []CopyOutFile `json:"copyOutCachedGroup,omitempty"`
+
+	// cpuLimit, clockLimit and memoryLimit given in the object form, which
+	// are resolved from the usage of the previous commands
+	RelativeCPULimit    *RelativeDuration `json:"-"`
+	RelativeClockLimit  *RelativeDuration `json:"-"`
+	RelativeMemoryLimit *RelativeSize     `json:"-"`
+
+	// Unlimited are the limits given as "unlimited"
+	Unlimited []string `json:"-"`
+}
+
+// UnmarshalJSON names the field of the malformed limit in the error, accepts
+// the object form of the relative limits and "unlimited", and rejects the
+// limits given as 0
+func (c *Cmd) UnmarshalJSON(b []byte) error {
+	type cmd Cmd
+	b, err := c.splitRelativeLimits(b)
+	if err != nil {
+		return err
+	}
+	if b, err = c.splitUnlimited(b); err != nil {
+		return err
+	}
+	err = json.Unmarshal(b, (*cmd)(c))
+	var le *LimitError
+	if errors.As(err, &le) {
+		le.Field = limitField(b, reflect.TypeOf(*c), le.Value)
+	}
+	if err != nil {
+		return err
+	}
+	return c.checkZeroLimits(b)
+}
+
+// CopyOutFile defines a copy out entry, either a file name (with suffix ? for
+// optional) or an object with statOnly to report the FileStat only
+type CopyOutFile struct {
+	Name     string `json:"name"`
+	Optional bool   `json:"optional,omitempty"`
+	StatOnly bool   `json:"statOnly,omitempty"`
+}
+
+// MarshalJSON converts the entry into the file name form if possible
+func (f CopyOutFile) MarshalJSON() ([]byte, error) {
+	if f.StatOnly {
+		type copyOutFile CopyOutFile
+		return json.Marshal(copyOutFile(f))
+	}
+	if f.Optional {
+		return json.Marshal(f.Name + optionalSuffix)
+	}
+	return json.Marshal(f.Name)
+}
+
+// UnmarshalJSON accepts both the file name form and the object form
+func (f *CopyOutFile) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*f = CopyOutFile{Name: strings.TrimSuffix(name, optionalSuffix), Optional: strings.HasSuffix(name, optionalSuffix)}
+		return nil
+	}
+	type copyOutFile CopyOutFile
+	var o copyOutFile
+	if err := json.Unmarshal(b, &o); err != nil {
+		return fmt.Errorf("invalid copy out file: %s", b)
+	}
+	*f = CopyOutFile(o)
+	return nil
 }
 
 // PipeIndex defines indexing for a pipe fd
 type PipeIndex struct {
-	Index int `json:"index"`
-	Fd    int `json:"fd"`
+	Index int    `json:"index"`
+	Fd    int    `json:"fd"`
+	Fifo  string `json:"fifo,omitempty"`
 }
 
 // PipeMap defines in / out pipe for multiple program
 type PipeMap struct {
-	In    PipeIndex `json:"in"`
-	Out   PipeIndex `json:"out"`
-	Name  string    `json:"name"`
-	Max   int64     `json:"max"`
-	Proxy bool      `json:"proxy"`
+	In         PipeIndex `json:"in"`
+	Out        PipeIndex `json:"out"`
+	Name       string    `json:"name"`
+	Max        int64     `json:"max"`
+	Proxy      bool      `json:"proxy"`
+	BufferSize uint64    `json:"bufferSize,omitempty"`
+
+	// TransferLimit kills both commands once more bytes than it are written
+	// into the proxy, requires proxy
+	TransferLimit uint64 `json:"transferLimit,omitempty"`
 }
 
 // Request defines single worker request
@@ -67,52 +214,282 @@ type Request struct {
 	RequestID   string    `json:"requestId"`
 	Cmd         []Cmd     `json:"cmd"`
 	PipeMapping []PipeMap `json:"pipeMapping"`
+	RunMode     string    `json:"runMode"` // parallel (default) / sequential
+	SkipVerify  bool      `json:"skipVerify"`
+	KillOnLimit bool      `json:"killOnLimit,omitempty"`
+	Priority    string    `json:"priority,omitempty"` // normal (default) / low / high
+
+	// EstimatedDuration is the hint of the wall time of the request, the
+	// shorter ones are taken first within the same priority by -scheduler sjf
+	EstimatedDuration Duration `json:"estimatedDuration,omitempty"`
+
+	IdempotencyKey string `json:"idempotencyKey,omitempty"`
+
+	// NUMANode pins the commands to the NUMA node when the environments are
+	// sharded by node (-numa-aware)
+	NUMANode *int `json:"numaNode,omitempty"`
+
+	// CopyOutStat applies statOnly to all copy out entries of the commands
+	CopyOutStat bool `json:"copyOutStat,omitempty"`
+
+	// Detach keeps the request running to completion after the client
+	// disconnected, only applies to the REST /run
+	Detach bool `json:"detach,omitempty"`
+
+	// Verdict names the verdict rules of -verdict-conf rewriting the results,
+	// the default rules apply if empty. IncludeRaw keeps the result before
+	// rewritten in raw.
+	Verdict    string `json:"verdict,omitempty"`
+	IncludeRaw bool   `json:"includeRaw,omitempty"`
+
+	// RawBinary keeps the inline content of binary copy out files as raw
+	// strings instead of base64
+	RawBinary bool `json:"rawBinary,omitempty"`
+
+	// ResponseObject returns the response object with the results and the
+	// usage of the request instead of the array of results, only applies to
+	// the REST /run
+	ResponseObject bool `json:"responseObject,omitempty"`
 }
 
 // Status offers JSON marshal for envexec.Status
 type Status envexec.Status
 
+// Status defines the result status, the codes are the same as envexec.Status
+// and the gRPC StatusType. Clients should handle StatusUnknown for statuses
+// added by newer servers.
+const (
+	StatusUnknown              = Status(envexec.StatusUnknown)
+	StatusInvalid              = Status(envexec.StatusInvalid)
+	StatusAccepted             = Status(envexec.StatusAccepted)
+	StatusWrongAnswer          = Status(envexec.StatusWrongAnswer)
+	StatusPartiallyCorrect     = Status(envexec.StatusPartiallyCorrect)
+	StatusMemoryLimitExceeded  = Status(envexec.StatusMemoryLimitExceeded)
+	StatusTimeLimitExceeded    = Status(envexec.StatusTimeLimitExceeded)
+	StatusOutputLimitExceeded  = Status(envexec.StatusOutputLimitExceeded)
+	StatusFileError            = Status(envexec.StatusFileError)
+	StatusNonzeroExitStatus    = Status(envexec.StatusNonzeroExitStatus)
+	StatusSignalled            = Status(envexec.StatusSignalled)
+	StatusDangerousSyscall     = Status(envexec.StatusDangerousSyscall)
+	StatusJudgementFailed      = Status(envexec.StatusJudgementFailed)
+	StatusInvalidInteraction   = Status(envexec.StatusInvalidInteraction)
+	StatusInternalError        = Status(envexec.StatusInternalError)
+	StatusProcessLimitExceeded = Status(envexec.StatusProcessLimitExceeded)
+
+	StatusUnsupportedArchitecture = Status(envexec.StatusUnsupportedArchitecture)
+	StatusPipeLimitExceeded       = Status(envexec.StatusPipeLimitExceeded)
+	StatusDiskLimitExceeded       = Status(envexec.StatusDiskLimitExceeded)
+	StatusSkipped                 = Status(envexec.StatusSkipped)
+)
+
+func (s Status) String() string {
+	return envexec.Status(s).String()
+}
+
+// Code returns the integer code of the status
+func (s Status) Code() int {
+	return int(s)
+}
+
 // MarshalJSON convert status into string
 func (s Status) MarshalJSON() ([]byte, error) {
 	return []byte("\"" + (envexec.Status)(s).String() + "\""), nil
 }
 
-// UnmarshalJSON convert string into status
+// UnmarshalJSON convert string name or integer code into status, name or code
+// not known by this version is converted into StatusUnknown
 func (s *Status) UnmarshalJSON(b []byte) error {
-	str := string(b)
-	v, err := envexec.StringToStatus(str)
-	if err != nil {
-		return err
+	var code int
+	if err := json.Unmarshal(b, &code); err == nil {
+		*s = Status(envexec.StatusFromCode(code))
+		return nil
 	}
-	*s = Status(v)
+	var name string
+	if err := json.Unmarshal(b, &name); err != nil {
+		return fmt.Errorf("invalid status: %s", b)
+	}
+	*s = Status(envexec.StatusFromName(name))
 	return nil
 }
 
 // Result defines single command result
 type Result struct {
-	Status     Status              `json:"status"`
-	ExitStatus int                 `json:"exitStatus"`
-	Error      string              `json:"error,omitempty"`
-	Time       uint64              `json:"time"`
-	Memory     uint64              `json:"memory"`
-	RunTime    uint64              `json:"runTime"`
-	Files      map[string]string   `json:"files,omitempty"`
-	FileIDs    map[string]string   `json:"fileIds,omitempty"`
-	FileError  []envexec.FileError `json:"fileError,omitempty"`
+	Status       Status                `json:"status"`
+	StatusCode   int                   `json:"statusCode"`
+	ExitStatus   int                   `json:"exitStatus"`
+	Signal       int                   `json:"signal"`
+	TerminatedBy string                `json:"terminatedBy"`
+	CancelReason string                `json:"cancelReason,omitempty"`
+	Error        string                `json:"error,omitempty"`
+	Time         uint64                `json:"time"`
+	Memory       uint64                `json:"memory"`
+	MemoryStat   *MemoryStat           `json:"memoryStat,omitempty"`
+	IOStat       *IOStat               `json:"ioStat,omitempty"`
+	Throttling   *Throttling           `json:"throttling,omitempty"`
+	ProcPeak     uint64                `json:"procPeak,omitempty"`
+	StrayProcs   uint64                `json:"strayProcs,omitempty"`
+	Arch         string                `json:"arch,omitempty"`
+	Emulated     bool                  `json:"emulated,omitempty"`
+	RandomSeed   *uint64               `json:"randomSeed,omitempty"`
+	RandomOpened bool                  `json:"randomOpened,omitempty"`
+	ShmUsage     uint64                `json:"shmUsage,omitempty"`
+	DiskUsage    uint64                `json:"diskUsage,omitempty"`
+	WorkDirUsage uint64                `json:"workDirUsage,omitempty"`
+	WorkDirSize  uint64                `json:"workDirSize,omitempty"`
+	InitLog      string                `json:"initLog,omitempty"`
+	RunTime      uint64                `json:"runTime"`
+	Files        map[string]string     `json:"files,omitempty"`
+	FileIDs      map[string]string     `json:"fileIds,omitempty"`
+	GroupID      string                `json:"groupId,omitempty"`
+	FileSize     map[string]int64      `json:"fileSize,omitempty"`
+	FileType     map[string]FileType   `json:"fileType,omitempty"`
+	FileError    []envexec.FileError   `json:"fileError,omitempty"`
+	Extracted    map[string]int        `json:"extracted,omitempty"`
+	Warning      string                `json:"warning,omitempty"`
+	Message      string                `json:"message,omitempty"` // attached by the verdict rule
+	WorkDir      string                `json:"workDir,omitempty"`
+	PipeBytes    map[int]int64         `json:"pipeBytes,omitempty"`
+	OutputStat   map[string]OutputStat `json:"outputStat,omitempty"`
+	StartedAt    string                `json:"startedAt,omitempty"` // RFC3339Nano
+	Hostname     string                `json:"hostname,omitempty"`
+	InstanceID   string                `json:"instanceId,omitempty"`
+
+	CoreFileID string `json:"coreFileId,omitempty"`
+	CoreSize   int64  `json:"coreSize,omitempty"`
+
+	SyscallCount map[string]uint64   `json:"syscallCount,omitempty"`
+	FileStat     map[string]FileStat `json:"fileStat,omitempty"`
+
+	OverheadCPUTime uint64 `json:"overheadCpuTime,omitempty"` // reportTiming only
+
+	// environment and cgroup that ran the command, 0 if not available
+	EnvironmentID uint64 `json:"environmentId,omitempty"`
+	CgroupID      uint64 `json:"cgroupId,omitempty"`
+
+	// NoisyNeighbor are the -noisy-neighbor-* thresholds exceeded by the run
+	NoisyNeighbor []string `json:"noisyNeighbor,omitempty"`
+
+	// Limits echoes the limits of the command in numbers, only set by the
+	// REST and WebSocket API
+	Limits *Limits `json:"limits,omitempty"`
+
+	// RelativeLimits echoes the limits resolved from the previous commands
+	RelativeLimits []ResolvedLimit `json:"relativeLimits,omitempty"`
+
+	// Raw is the result before rewritten by the verdict rules if includeRaw
+	Raw *RawResult `json:"raw,omitempty"`
 
 	files []string
 	Buffs map[string][]byte `json:"-"`
 }
 
+// RawResult defines the result before rewritten by the verdict rules
+type RawResult struct {
+	Status     Status `json:"status"`
+	StatusCode int    `json:"statusCode"`
+	Time       uint64 `json:"time"`
+	RunTime    uint64 `json:"runTime"`
+}
+
+// MemoryStat defines memory statistics breakdown of the cgroup
+type MemoryStat struct {
+	Cache      uint64 `json:"cache"`
+	RSS        uint64 `json:"rss"`
+	MappedFile uint64 `json:"mapped_file"`
+	PageFault  uint64 `json:"pgfault"`
+	MajorFault uint64 `json:"pgmajfault"`
+}
+
+// IOStat defines bytes read and written to block devices
+type IOStat struct {
+	Read  uint64 `json:"read"`
+	Write uint64 `json:"write"`
+}
+
+// Throttling defines the cfs throttling of the cpu rate limit, zero if no
+// cpuRateLimit is applied
+type Throttling struct {
+	Periods       uint64 `json:"periods"`       // enforcement periods elapsed
+	Throttled     uint64 `json:"throttled"`     // periods throttled
+	ThrottledTime uint64 `json:"throttledTime"` // ns
+}
+
+// OutputStat defines bytes and newline terminated lines written to collector
+type OutputStat struct {
+	Bytes int64 `json:"bytes"`
+	Lines int64 `json:"lines"`
+}
+
+// FileType defines the type of a copy out file detected from its first 512
+// bytes. The inline content of binary files is base64 encoded unless the
+// request has rawBinary.
+type FileType struct {
+	ContentType string `json:"contentType"`
+	IsBinary    bool   `json:"isBinary"`
+	Encoding    string `json:"encoding,omitempty"` // base64 if the content in files is encoded
+}
+
+// encodingBase64 is the FileType.Encoding of the base64 encoded content
+const encodingBase64 = "base64"
+
+// FileStat defines the metadata of a copy out file with statOnly
+type FileStat struct {
+	Size    int64  `json:"size"`
+	Mode    uint32 `json:"mode"`  // permission bits
+	ModTime string `json:"mtime"` // RFC3339Nano
+}
+
 // Response defines worker response for single request
 type Response struct {
 	RequestID string   `json:"requestId"`
 	Results   []Result `json:"results"`
 	ErrorMsg  string   `json:"error,omitempty"`
+	Note      string   `json:"note,omitempty"`
+
+	// CancelReason is one of shutdown, clientGone, cancelled, queueTimeout if
+	// the request was terminated by the server
+	CancelReason string `json:"cancelReason,omitempty"`
+
+	// EstimatedDuration echoes the hint of the request and Duration is the
+	// wall time it ran in ns, only set if estimatedDuration is given
+	EstimatedDuration uint64 `json:"estimatedDuration,omitempty"`
+	Duration          uint64 `json:"duration,omitempty"`
+
+	// Usage sums the commands of the request executed, nil if failed
+	Usage *RequestUsage `json:"usage,omitempty"`
 
 	mmap bool
 }
 
+// RequestUsage sums the resources consumed by the commands of the request,
+// durations are in ns and sizes are in bytes. CPU time, memory seconds and
+// copy sizes are summed over the commands, the skipped ones are not counted.
+// Memory is the max of the commands run one after another (sequential run
+// mode, repeat) and the sum of the ones run at the same time (parallel run
+// mode, pipe mapping). It is the usage accounted to the token.
+type RequestUsage struct {
+	CPUTime       uint64  `json:"cpuTime"`
+	Memory        uint64  `json:"memory"`
+	MemorySeconds float64 `json:"memorySeconds"`
+	WallTime      uint64  `json:"wallTime"` // from submitted to the response, including the wait in queue
+	CopyIn        uint64  `json:"copyIn"`   // including the extracted archives
+	CopyOut       uint64  `json:"copyOut"`  // outputs returned, excluding the cached ones
+	Cached        uint64  `json:"cached"`   // added to the file store by copyOutCached, cached collectors and core files
+}
+
+// ConvertRequestUsage converts the usage of the response executed
+func ConvertRequestUsage(u worker.RequestUsage) *RequestUsage {
+	return &RequestUsage{
+		CPUTime:       uint64(u.CPUTime),
+		Memory:        uint64(u.Memory),
+		MemorySeconds: u.MemorySeconds,
+		WallTime:      uint64(u.WallTime),
+		CopyIn:        uint64(u.CopyIn),
+		CopyOut:       uint64(u.CopyOut),
+		Cached:        uint64(u.Cached),
+	}
+}
+
 func (r *Response) Close() {
 	if !r.mmap {
 		return
@@ -133,6 +510,80 @@ func (r *Result) Close() {
 	}
 }
 
+// EnvironmentError defines the body of the response when environment could
+// not be created
+type EnvironmentError struct {
+	Error  string `json:"error"`
+	Reason string `json:"reason"`
+	Errno  uint64 `json:"errno,omitempty"`
+}
+
+// QuotaError defines the body of the response when the quota of the auth
+// token is exhausted
+type QuotaError struct {
+	Error    string  `json:"error"`
+	Resource string  `json:"resource"` // runs / cpuTime
+	Limit    float64 `json:"limit"`    // count for runs, seconds for cpuTime
+	Used     float64 `json:"used"`
+	ResetAt  string  `json:"resetAt,omitempty"` // RFC3339
+}
+
+// BatchResult defines the result of each request of POST /runs, Status is
+// the http status code POST /run would respond with and the results are only
+// set if it is 200, otherwise the error POST /run would respond with is set
+type BatchResult struct {
+	Index            int               `json:"index"`
+	Status           int               `json:"status"`
+	Results          []Result          `json:"results,omitempty"`
+	Note             string            `json:"note,omitempty"`
+	Code             ErrorCode         `json:"code,omitempty"`
+	Error            string            `json:"error,omitempty"`
+	Fields           []FieldError      `json:"fields,omitempty"`
+	CancelReason     string            `json:"cancelReason,omitempty"`
+	EnvironmentError *EnvironmentError `json:"environmentError,omitempty"`
+	QuotaError       *QuotaError       `json:"quotaError,omitempty"`
+
+	EstimatedDuration uint64        `json:"estimatedDuration,omitempty"`
+	Duration          uint64        `json:"duration,omitempty"`
+	Usage             *RequestUsage `json:"usage,omitempty"`
+
+	// Checkpointed is set for the result stored by a previous submission of
+	// the batch with the same Idempotency-Key, which is not run again
+	Checkpointed bool `json:"checkpointed,omitempty"`
+}
+
+// BatchSummary defines the last line of POST /runs streamed as NDJSON
+type BatchSummary struct {
+	Done         bool `json:"done"` // always true, tells the summary from the results
+	Total        int  `json:"total"`
+	Succeeded    int  `json:"succeeded"`
+	Failed       int  `json:"failed"`
+	Checkpointed int  `json:"checkpointed,omitempty"` // succeeded ones returned from the checkpoint
+}
+
+// ConvertQuotaError converts worker.QuotaError into json model
+func ConvertQuotaError(e *worker.QuotaError) QuotaError {
+	rt := QuotaError{
+		Error:    e.Error(),
+		Resource: e.Resource,
+		Limit:    e.Limit,
+		Used:     e.Used,
+	}
+	if !e.ResetAt.IsZero() {
+		rt.ResetAt = e.ResetAt.Format(time.RFC3339)
+	}
+	return rt
+}
+
+// ConvertEnvironmentError converts worker.EnvironmentError into json model
+func ConvertEnvironmentError(e *worker.EnvironmentError) EnvironmentError {
+	return EnvironmentError{
+		Error:  e.Error(),
+		Reason: string(e.Reason),
+		Errno:  uint64(e.Errno),
+	}
+}
+
 // ConvertResponse converts
 func ConvertResponse(r worker.Response, mmap bool) (ret Response, err error) {
 	// in error case, release all resources
@@ -157,9 +608,14 @@ func ConvertResponse(r worker.Response, mmap bool) (ret Response, err error) {
 	}()
 
 	ret = Response{
-		RequestID: r.RequestID,
-		Results:   make([]Result, 0, len(r.Results)),
-		mmap:      mmap,
+		RequestID:    r.RequestID,
+		Results:      make([]Result, 0, len(r.Results)),
+		Note:         r.Note,
+		CancelReason: string(r.CancelReason),
+		mmap:         mmap,
+
+		EstimatedDuration: uint64(r.EstimatedDuration),
+		Duration:          uint64(r.Duration),
 	}
 	for _, r := range r.Results {
 		res, err := convertResult(r, mmap)
@@ -170,22 +626,47 @@ func ConvertResponse(r worker.Response, mmap bool) (ret Response, err error) {
 	}
 	if r.Error != nil {
 		ret.ErrorMsg = r.Error.Error()
+	} else {
+		ret.Usage = ConvertRequestUsage(r.Usage)
 	}
 	return ret, nil
 }
 
 // ConvertRequest converts json request into worker request
 func ConvertRequest(r *Request, srcPrefix []string) (*worker.Request, error) {
+	runMode, err := ParseRunMode(r.RunMode)
+	if err != nil {
+		return nil, err
+	}
+	priority, err := ParsePriority(r.Priority)
+	if err != nil {
+		return nil, err
+	}
 	req := &worker.Request{
 		RequestID:   r.RequestID,
 		Cmd:         make([]worker.Cmd, 0, len(r.Cmd)),
 		PipeMapping: make([]worker.PipeMap, 0, len(r.PipeMapping)),
+		RunMode:     runMode,
+		SkipVerify:  r.SkipVerify,
+		KillOnLimit: r.KillOnLimit,
+		Priority:    priority,
+
+		IdempotencyKey: r.IdempotencyKey,
+		NUMANode:       r.NUMANode,
+		Verdict:        r.Verdict,
+		IncludeRaw:     r.IncludeRaw,
+
+		EstimatedDuration: time.Duration(r.EstimatedDuration),
 	}
 	for _, c := range r.Cmd {
 		wc, err := convertCmd(c, srcPrefix)
 		if err != nil {
 			return nil, err
 		}
+		if r.CopyOutStat {
+			setStatOnly(wc.CopyOut)
+			setStatOnly(wc.CopyOutCached)
+		}
 		req.Cmd = append(req.Cmd, wc)
 	}
 	for _, p := range r.PipeMapping {
@@ -196,14 +677,57 @@ func ConvertRequest(r *Request, srcPrefix []string) (*worker.Request, error) {
 
 func convertResult(r worker.Result, mmap bool) (Result, error) {
 	res := Result{
-		Status:     Status(r.Status),
-		ExitStatus: r.ExitStatus,
-		Error:      r.Error,
-		Time:       uint64(r.Time),
-		RunTime:    uint64(r.RunTime),
-		Memory:     uint64(r.Memory),
-		FileIDs:    r.FileIDs,
-		FileError:  r.FileError,
+		Status:       Status(r.Status),
+		StatusCode:   int(r.Status),
+		ExitStatus:   r.ExitStatus,
+		Signal:       r.Signal,
+		TerminatedBy: r.TerminatedBy.String(),
+		CancelReason: string(r.CancelReason),
+		Error:        r.Error,
+		Time:         uint64(r.Time),
+		RunTime:      uint64(r.RunTime),
+		Memory:       uint64(r.Memory),
+		MemoryStat:   convertMemoryStat(r.MemoryStat),
+		IOStat:       convertIOStat(r.IOStat),
+		Throttling:   convertThrottling(r.Throttling),
+		ProcPeak:     r.ProcPeak,
+		StrayProcs:   r.StrayProcs,
+		Arch:         r.Arch,
+		Emulated:     r.Emulated,
+		RandomSeed:   r.RandomSeed,
+		RandomOpened: r.RandomOpened,
+		ShmUsage:     uint64(r.ShmUsage),
+		DiskUsage:    uint64(r.DiskUsage),
+		WorkDirUsage: uint64(r.WorkDirUsage),
+		WorkDirSize:  uint64(r.WorkDirSize),
+		InitLog:      r.InitLog,
+		FileIDs:      r.FileIDs,
+		GroupID:      r.GroupID,
+		FileSize:     r.FileSize,
+		FileType:     convertFileType(r.FileType),
+		FileError:    r.FileError,
+		Extracted:    r.Extracted,
+		Warning:      r.Warning,
+		Message:      r.Message,
+		WorkDir:      r.WorkDir,
+		PipeBytes:    r.PipeBytes,
+		OutputStat:   convertOutputStat(r.OutputStat),
+		Hostname:     r.Hostname,
+		InstanceID:   r.InstanceID,
+		CoreFileID:   r.CoreFileID,
+		CoreSize:     r.CoreSize,
+		SyscallCount: r.SyscallCount,
+		FileStat:     convertFileStat(r.FileStat),
+
+		OverheadCPUTime: uint64(r.OverheadCPUTime),
+		EnvironmentID:   r.EnvironmentID,
+		CgroupID:        r.CgroupID,
+		NoisyNeighbor:   r.NoisyNeighbor,
+		RelativeLimits:  convertResolvedLimits(r.RelativeLimits),
+		Raw:             convertRawResult(r.Raw),
+	}
+	if !r.StartedAt.IsZero() {
+		res.StartedAt = r.StartedAt.Format(time.RFC3339Nano)
 	}
 	if r.Files != nil {
 		res.Files = make(map[string]string)
@@ -213,7 +737,13 @@ func convertResult(r worker.Result, mmap bool) (Result, error) {
 			if err != nil {
 				return res, err
 			}
-			res.Files[k] = byteArrayToString(b)
+			if t, ok := res.FileType[k]; ok && t.IsBinary {
+				res.Files[k] = base64.StdEncoding.EncodeToString(b)
+				t.Encoding = encodingBase64
+				res.FileType[k] = t
+			} else {
+				res.Files[k] = byteArrayToString(b)
+			}
 
 			res.files = append(res.files, f.Name())
 			res.Buffs[k] = b
@@ -222,19 +752,126 @@ func convertResult(r worker.Result, mmap bool) (Result, error) {
 	return res, nil
 }
 
+func convertFileType(t map[string]worker.FileType) map[string]FileType {
+	if t == nil {
+		return nil
+	}
+	rt := make(map[string]FileType, len(t))
+	for k, v := range t {
+		rt[k] = FileType{
+			ContentType: v.ContentType,
+			IsBinary:    v.Binary,
+		}
+	}
+	return rt
+}
+
+// RawBinary keeps the inline content of the binary files as raw strings
+// instead of base64, which could be mangled by the JSON encoding
+func (r *Response) RawBinary() {
+	for i := range r.Results {
+		res := &r.Results[i]
+		for k, t := range res.FileType {
+			if t.Encoding != encodingBase64 {
+				continue
+			}
+			res.Files[k] = byteArrayToString(res.Buffs[k])
+			t.Encoding = ""
+			res.FileType[k] = t
+		}
+	}
+}
+
+func convertRawResult(r *worker.RawResult) *RawResult {
+	if r == nil {
+		return nil
+	}
+	return &RawResult{
+		Status:     Status(r.Status),
+		StatusCode: int(r.Status),
+		Time:       uint64(r.Time),
+		RunTime:    uint64(r.RunTime),
+	}
+}
+
+func convertFileStat(s map[string]envexec.FileStat) map[string]FileStat {
+	if s == nil {
+		return nil
+	}
+	rt := make(map[string]FileStat, len(s))
+	for k, v := range s {
+		rt[k] = FileStat{
+			Size:    v.Size,
+			Mode:    uint32(v.Mode.Perm()),
+			ModTime: v.ModTime.Format(time.RFC3339Nano),
+		}
+	}
+	return rt
+}
+
+func convertOutputStat(s map[string]envexec.OutputStat) map[string]OutputStat {
+	if s == nil {
+		return nil
+	}
+	rt := make(map[string]OutputStat, len(s))
+	for k, v := range s {
+		rt[k] = OutputStat{Bytes: v.Bytes, Lines: v.Lines}
+	}
+	return rt
+}
+
+func convertMemoryStat(s *envexec.MemoryStat) *MemoryStat {
+	if s == nil {
+		return nil
+	}
+	return &MemoryStat{
+		Cache:      uint64(s.Cache),
+		RSS:        uint64(s.RSS),
+		MappedFile: uint64(s.MappedFile),
+		PageFault:  s.PageFault,
+		MajorFault: s.MajorPageFault,
+	}
+}
+
+func convertIOStat(s *envexec.IOStat) *IOStat {
+	if s == nil {
+		return nil
+	}
+	return &IOStat{
+		Read:  uint64(s.Read),
+		Write: uint64(s.Write),
+	}
+}
+
+func convertThrottling(s *envexec.CPUThrottling) *Throttling {
+	if s == nil {
+		return nil
+	}
+	return &Throttling{
+		Periods:       s.Periods,
+		Throttled:     s.Throttled,
+		ThrottledTime: uint64(s.ThrottledTime),
+	}
+}
+
 func convertPipe(p PipeMap) worker.PipeMap {
 	return worker.PipeMap{
 		In: worker.PipeIndex{
 			Index: p.In.Index,
 			Fd:    p.In.Fd,
+			Fifo:  p.In.Fifo,
 		},
 		Out: worker.PipeIndex{
 			Index: p.Out.Index,
 			Fd:    p.Out.Fd,
+			Fifo:  p.Out.Fifo,
 		},
-		Proxy: p.Proxy,
-		Name:  p.Name,
-		Limit: worker.Size(p.Max),
+		Proxy:      p.Proxy,
+		Name:       p.Name,
+		Limit:      worker.Size(p.Max),
+		BufferSize: worker.Size(p.BufferSize),
+
+		TransferLimit: worker.Size(p.TransferLimit),
 	}
 }
 
@@ -244,30 +881,87 @@ func convertCmd(c Cmd, srcPrefix []string) (worker.Cmd, error) {
 		clockLimit = c.RealCPULimit
 	}
 	w := worker.Cmd{
-		Args:              c.Args,
-		Env:               c.Env,
-		Files:             make([]worker.CmdFile, 0, len(c.Files)),
-		TTY:               c.TTY,
-		CPULimit:          time.Duration(c.CPULimit),
-		ClockLimit:        time.Duration(clockLimit),
-		MemoryLimit:       envexec.Size(c.MemoryLimit),
-		StackLimit:        envexec.Size(c.StackLimit),
-		ProcLimit:         c.ProcLimit,
-		CPURateLimit:      c.CPURateLimit,
-		CPUSetLimit:       c.CPUSetLimit,
-		StrictMemoryLimit: c.StrictMemoryLimit,
-		CopyOut:           convertCopyOut(c.CopyOut),
-		CopyOutCached:     convertCopyOut(c.CopyOutCached),
-		CopyOutMax:        c.CopyOutMax,
-		CopyOutDir:        c.CopyOutDir,
-	}
-	for _, f := range c.Files {
+		Args:               c.Args,
+		Env:                c.Env,
+		Files:              make([]worker.CmdFile, 0, len(c.Files)),
+		TTY:                c.TTY,
+		CPULimit:           time.Duration(c.CPULimit),
+		ClockLimit:         time.Duration(clockLimit),
+		MemoryLimit:        envexec.Size(c.MemoryLimit),
+		StackLimit:         envexec.Size(c.StackLimit),
+		ProcLimit:          c.ProcLimit,
+		CPURateLimit:       c.CPURateLimit,
+		CPUSetLimit:        c.CPUSetLimit,
+		StrictMemoryLimit:  c.StrictMemoryLimit,
+		DetailedMemory:     c.DetailedMemory,
+		Credential:         worker.NewCredential(c.UID, c.GID),
+		RandomizeWorkDir:   c.RandomizeWorkDir,
+		Datasets:           c.Datasets,
+		Profile:            c.Profile,
+		IOLimit:            envexec.Size(c.IOLimit),
+		CollectCore:        c.CollectCore,
+		Trace:              c.Trace,
+		ProcInfo:           c.ProcInfo,
+		ProcInfoCPUs:       c.ProcInfoCPUs,
+		RandomSeed:         c.RandomSeed,
+		Arch:               c.Arch,
+		ShmSize:            worker.Size(c.ShmSize),
+		DiskWorkDir:        c.DiskWorkDir,
+		ScratchFile:        c.ScratchFile,
+		ScratchSize:        worker.Size(c.ScratchSize),
+		ReportTiming:       c.ReportTiming,
+		SnapshotWorkDir:    c.SnapshotWorkDir,
+		MountFiles:         c.MountFiles,
+		CopyOut:            convertCopyOut(c.CopyOut),
+		CopyOutCached:      convertCopyOut(c.CopyOutCached),
+		CopyOutCachedGroup: convertCopyOut(c.CopyOutCachedGroup),
+		CopyOutMax:         uint64(c.CopyOutMax),
+		CopyOutDir:         c.CopyOutDir,
+		CopyOutMaxFiles:    c.CopyOutMaxFiles,
+		CopyOutMaxTotal:    uint64(c.CopyOutMaxTotal),
+		RelativeLimits: worker.RelativeLimits{
+			CPU:    c.RelativeCPULimit.convert(),
+			Clock:  c.RelativeClockLimit.convert(),
+			Memory: c.RelativeMemoryLimit.convert(),
+		},
+	}
+	for _, name := range c.Unlimited {
+		switch name {
+		case "cpuLimit":
+			w.CPULimit = worker.UnlimitedDuration
+		case "realCpuLimit", "clockLimit":
+			w.ClockLimit = worker.UnlimitedDuration
+		case "memoryLimit":
+			w.MemoryLimit = worker.UnlimitedSize
+		case "stackLimit":
+			w.StackLimit = worker.UnlimitedSize
+		case "procLimit":
+			w.ProcLimit = worker.UnlimitedProc
+		}
+	}
+	for i, f := range c.Files {
+		// max 0 of stderr collector uses the server default
+		if i == 2 && f != nil && f.Name != nil && f.Max == nil && !f.Discard {
+			sf := *f
+			sf.Max = new(int64)
+			f = &sf
+		}
 		cf, err := convertCmdFile(f, srcPrefix)
 		if err != nil {
 			return w, err
 		}
 		w.Files = append(w.Files, cf)
 	}
+	if len(c.ExtraFiles) > 0 {
+		w.ExtraFiles = make(map[int]worker.CmdFile, len(c.ExtraFiles))
+		for fd, f := range c.ExtraFiles {
+			cf, err := convertCmdFile(f, srcPrefix)
+			if err != nil {
+				return w, err
+			}
+			w.ExtraFiles[fd] = cf
+		}
+	}
 	if c.CopyIn != nil {
 		w.CopyIn = make(map[string]worker.CmdFile)
 		w.Symlinks = make(map[string]string)
@@ -276,13 +970,36 @@ func convertCmd(c Cmd, srcPrefix []string) (worker.Cmd, error) {
 				w.Symlinks[k] = *f.Symlink
 				continue
 			}
+			if f.Fifo {
+				w.CopyIn[k] = &worker.FifoFile{}
+				continue
+			}
+			if f.GroupID != nil {
+				if !f.Extract {
+					return w, fmt.Errorf("copyIn (%s): groupId requires extract", k)
+				}
+				w.CopyIn[k] = &worker.GroupFile{GroupID: *f.GroupID}
+				continue
+			}
 			cf, err := convertCmdFile(&f, srcPrefix)
 			if err != nil {
 				return w, err
 			}
+			if f.Archive != nil {
+				format, err := ParseArchiveFormat(*f.Archive)
+				if err != nil {
+					return w, err
+				}
+				cf = &worker.ArchiveFile{File: cf, Format: format}
+			}
 			w.CopyIn[k] = cf
 		}
 	}
+	if c.Expand {
+		if err := worker.Expand(&w); err != nil {
+			return w, err
+		}
+	}
 	return w, nil
 }
 
@@ -305,8 +1022,17 @@ func convertCmdFile(f *CmdFile, srcPrefix []string) (worker.CmdFile, error) {
 		return &worker.MemoryFile{Content: []byte(*f.Content)}, nil
 	case f.FileID != nil:
 		return &worker.CachedFile{FileID: *f.FileID}, nil
+	case f.Name != nil && f.Discard:
+		if f.Cache || f.CacheTruncated != nil && *f.CacheTruncated {
+			return nil, fmt.Errorf("collector (%s) could not both discard and cache", *f.Name)
+		}
+		var max int64
+		if f.Max != nil {
+			max = *f.Max
+		}
+		return &worker.Collector{Name: *f.Name, Max: envexec.Size(max), Pipe: f.Pipe, Discard: true}, nil
 	case f.Max != nil && f.Name != nil:
-		return &worker.Collector{Name: *f.Name, Max: envexec.Size(*f.Max), Pipe: f.Pipe}, nil
+		return &worker.Collector{Name: *f.Name, Max: envexec.Size(*f.Max), Pipe: f.Pipe, Cache: f.Cache, CacheTruncated: f.CacheTruncated}, nil
 	default:
 		return nil, fmt.Errorf("file is not valid for cmd")
 	}
@@ -336,21 +1062,62 @@ func checkPathPrefix(path, prefix string) (bool, error) {
 	return strings.HasPrefix(filepath.Join(wd, path), prefix), nil
 }
 
+// ParseRunMode converts run mode name into worker.RunMode
+func ParseRunMode(s string) (worker.RunMode, error) {
+	switch s {
+	case "", "parallel":
+		return worker.RunModeParallel, nil
+	case "sequential":
+		return worker.RunModeSequential, nil
+	default:
+		return 0, fmt.Errorf("run mode (%s) is not supported", s)
+	}
+}
+
+// ParsePriority converts priority name into worker.Priority
+func ParsePriority(s string) (worker.Priority, error) {
+	switch s {
+	case "", "normal":
+		return worker.PriorityNormal, nil
+	case "low":
+		return worker.PriorityLow, nil
+	case "high":
+		return worker.PriorityHigh, nil
+	default:
+		return 0, fmt.Errorf("priority (%s) is not supported", s)
+	}
+}
+
+// ParseArchiveFormat converts archive format name into envexec.ArchiveFormat
+func ParseArchiveFormat(s string) (envexec.ArchiveFormat, error) {
+	switch s {
+	case "tar":
+		return envexec.ArchiveTar, nil
+	case "tar.gz", "tgz":
+		return envexec.ArchiveTarGz, nil
+	case "zip":
+		return envexec.ArchiveZip, nil
+	default:
+		return 0, fmt.Errorf("archive format (%s) is not supported", s)
+	}
+}
+
 const optionalSuffix = "?"
 
-func convertCopyOut(copyOut []string) []worker.CmdCopyOutFile {
+func convertCopyOut(copyOut []CopyOutFile) []worker.CmdCopyOutFile {
 	rt := make([]worker.CmdCopyOutFile, 0, len(copyOut))
-	for _, n := range copyOut {
-		if strings.HasSuffix(n, optionalSuffix) {
-			rt = append(rt, worker.CmdCopyOutFile{
-				Name:     strings.TrimSuffix(n, optionalSuffix),
-				Optional: true,
-			})
-			continue
-		}
+	for _, f := range copyOut {
 		rt = append(rt, worker.CmdCopyOutFile{
-			Name: n,
+			Name:     f.Name,
+			Optional: f.Optional,
+			StatOnly: f.StatOnly,
 		})
 	}
 	return rt
 }
+
+func setStatOnly(copyOut []worker.CmdCopyOutFile) {
+	for i := range copyOut {
+		copyOut[i].StatOnly = true
+	}
+}
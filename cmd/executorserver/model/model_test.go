@@ -0,0 +1,21 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// TestConvertThrottling covers the keys of the throttling, which are the same
+// for cgroup v1 and v2 with the throttled time in ns
+func TestConvertThrottling(t *testing.T) {
+	b, err := json.Marshal(convertThrottling(&envexec.CPUThrottling{Periods: 22, Throttled: 21, ThrottledTime: 1580 * time.Millisecond}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"periods":22,"throttled":21,"throttledTime":1580000000}`; string(b) != want {
+		t.Fatalf("got %s, want %s", b, want)
+	}
+}
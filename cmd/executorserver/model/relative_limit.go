@@ -0,0 +1,138 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/criyle/go-judge/worker"
+)
+
+// RelativeDuration is the object form of cpuLimit and clockLimit resolved from
+// the cpu time or run time of a previous command of the sequential request,
+// e.g. {"fromCmd": 0, "multiplier": 2, "min": "100ms", "max": "5s"}
+type RelativeDuration struct {
+	FromCmd    int      `json:"fromCmd"`
+	Multiplier float64  `json:"multiplier"`
+	Min        Duration `json:"min,omitempty"`
+	Max        Duration `json:"max,omitempty"` // also checked against the server max
+}
+
+// RelativeSize is the object form of memoryLimit resolved from the peak
+// memory of a previous command of the sequential request
+type RelativeSize struct {
+	FromCmd    int     `json:"fromCmd"`
+	Multiplier float64 `json:"multiplier"`
+	Min        Size    `json:"min,omitempty"`
+	Max        Size    `json:"max,omitempty"` // also checked against the server max
+}
+
+// ResolvedLimit echoes the relative limit of the command and the value
+// resolved in numbers (nanoseconds and bytes)
+type ResolvedLimit struct {
+	Limit      string  `json:"limit"` // cpuLimit, clockLimit or memoryLimit
+	FromCmd    int     `json:"fromCmd"`
+	Multiplier float64 `json:"multiplier"`
+	Min        uint64  `json:"min,omitempty"`
+	Max        uint64  `json:"max,omitempty"`
+	Usage      uint64  `json:"usage"` // usage of the command referenced
+	Value      uint64  `json:"value"` // limit applied after the server max
+}
+
+// relativeLimitFields are the limits of Cmd accepting the object form
+var relativeLimitFields = []string{"cpuLimit", "clockLimit", "memoryLimit"}
+
+// splitRelativeLimits decodes the limits of the command in the object form
+// and replaces them by their max, so that the max is checked as the static
+// limit before the request runs
+func (c *Cmd) splitRelativeLimits(b []byte) ([]byte, error) {
+	if !bytes.Contains(b, []byte(`"fromCmd"`)) {
+		return b, nil
+	}
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(b, &m); err != nil {
+		return b, nil // reported by the decode of the command
+	}
+	for _, name := range relativeLimitFields {
+		v := bytes.TrimSpace(m[name])
+		if len(v) == 0 || v[0] != '{' {
+			continue
+		}
+		var (
+			static uint64
+			err    error
+		)
+		switch name {
+		case "cpuLimit":
+			c.RelativeCPULimit = new(RelativeDuration)
+			err = decodeStrict(v, c.RelativeCPULimit)
+			static = uint64(c.RelativeCPULimit.Max)
+		case "clockLimit":
+			c.RelativeClockLimit = new(RelativeDuration)
+			err = decodeStrict(v, c.RelativeClockLimit)
+			static = uint64(c.RelativeClockLimit.Max)
+		case "memoryLimit":
+			c.RelativeMemoryLimit = new(RelativeSize)
+			err = decodeStrict(v, c.RelativeMemoryLimit)
+			static = uint64(c.RelativeMemoryLimit.Max)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid relative limit %s: %v", name, err)
+		}
+		mv, err := json.Marshal(static)
+		if err != nil {
+			return nil, err
+		}
+		m[name] = mv
+	}
+	return json.Marshal(m)
+}
+
+func decodeStrict(b []byte, v any) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.DisallowUnknownFields()
+	return d.Decode(v)
+}
+
+func (r *RelativeDuration) convert() *worker.RelativeLimit {
+	if r == nil {
+		return nil
+	}
+	return &worker.RelativeLimit{
+		FromCmd:    r.FromCmd,
+		Multiplier: r.Multiplier,
+		Min:        uint64(r.Min),
+		Max:        uint64(r.Max),
+	}
+}
+
+func (r *RelativeSize) convert() *worker.RelativeLimit {
+	if r == nil {
+		return nil
+	}
+	return &worker.RelativeLimit{
+		FromCmd:    r.FromCmd,
+		Multiplier: r.Multiplier,
+		Min:        uint64(r.Min),
+		Max:        uint64(r.Max),
+	}
+}
+
+func convertResolvedLimits(l []worker.ResolvedLimit) []ResolvedLimit {
+	if l == nil {
+		return nil
+	}
+	rt := make([]ResolvedLimit, 0, len(l))
+	for _, r := range l {
+		rt = append(rt, ResolvedLimit{
+			Limit:      r.Limit,
+			FromCmd:    r.FromCmd,
+			Multiplier: r.Multiplier,
+			Min:        r.Min,
+			Max:        r.Max,
+			Usage:      r.Usage,
+			Value:      r.Value,
+		})
+	}
+	return rt
+}
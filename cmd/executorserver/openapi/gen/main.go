@@ -0,0 +1,602 @@
+// Command gen generates the OpenAPI document of the REST API from the types
+// in the model package, the descriptions are taken from their comments.
+//
+// With -check, it fails if the document is out of date or the example request
+// does not validate against the document.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"log"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/criyle/go-judge/cmd/executorserver/openapi"
+	"github.com/criyle/go-judge/envexec"
+)
+
+type schema = map[string]any
+
+var (
+	output   = flag.String("o", "openapi.json", "output file")
+	check    = flag.Bool("check", false, "check the output file is up to date instead of writing it")
+	modelDir = flag.String("model", "../model", "source directory of the model package")
+)
+
+func main() {
+	flag.Parse()
+
+	g, b, err := generate(*modelDir)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := g.validateExample(); err != nil {
+		log.Fatalln("example:", err)
+	}
+	if *check {
+		old, err := os.ReadFile(*output)
+		if err != nil {
+			log.Fatalln("check:", err)
+		}
+		if !bytes.Equal(old, b) {
+			log.Fatalf("check: %s is out of date, run go generate", *output)
+		}
+		return
+	}
+	if err := os.WriteFile(*output, b, 0644); err != nil {
+		log.Fatalln("write:", err)
+	}
+}
+
+// generate returns the encoded document with the descriptions from the source
+// of the model package in dir
+func generate(dir string) (*generator, []byte, error) {
+	g := &generator{
+		components: make(map[string]schema),
+		types:      make(map[string]reflect.Type),
+	}
+	if err := g.parseComments(dir); err != nil {
+		return nil, nil, fmt.Errorf("parse model: %w", err)
+	}
+	b, err := json.MarshalIndent(g.document(), "", "  ")
+	if err != nil {
+		return nil, nil, fmt.Errorf("marshal: %w", err)
+	}
+	return g, append(b, '\n'), nil
+}
+
+type generator struct {
+	components map[string]schema
+	types      map[string]reflect.Type // detects components with the same name
+
+	typeDoc  map[string]string // type name -> doc comment
+	fieldDoc map[string]string // type.field -> doc or line comment
+}
+
+// parseComments collects the comments of the types and fields in the model
+// package as descriptions
+func (g *generator) parseComments(dir string) error {
+	g.typeDoc = make(map[string]string)
+	g.fieldDoc = make(map[string]string)
+
+	fset := token.NewFileSet()
+	pkgs, err := parser.ParseDir(fset, dir, nil, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		for _, f := range pkg.Files {
+			for _, d := range f.Decls {
+				gd, ok := d.(*ast.GenDecl)
+				if !ok || gd.Tok != token.TYPE {
+					continue
+				}
+				for _, s := range gd.Specs {
+					ts := s.(*ast.TypeSpec)
+					doc := ts.Doc
+					if doc == nil && len(gd.Specs) == 1 {
+						doc = gd.Doc
+					}
+					if doc != nil {
+						g.typeDoc[ts.Name.Name] = description(doc.Text())
+					}
+					st, ok := ts.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+					for _, field := range st.Fields.List {
+						c := field.Doc
+						if c == nil {
+							c = field.Comment
+						}
+						if c == nil {
+							continue
+						}
+						for _, n := range field.Names {
+							g.fieldDoc[ts.Name.Name+"."+n.Name] = description(c.Text())
+						}
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func description(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+var (
+	statusType        = reflect.TypeOf(model.Status(0))
+	copyOutFileType   = reflect.TypeOf(model.CopyOutFile{})
+	fileErrorTypeType = reflect.TypeOf(envexec.FileErrorType(0))
+	errorCodeType     = reflect.TypeOf(model.ErrorCode(""))
+	durationType      = reflect.TypeOf(model.Duration(0))
+	sizeType          = reflect.TypeOf(model.Size(0))
+	limitValueType    = reflect.TypeOf(model.LimitValue(0))
+
+	// relativeLimitTypes are the object forms of the limits of Cmd decoded by
+	// its UnmarshalJSON
+	relativeLimitTypes = map[string]reflect.Type{
+		"Cmd.CPULimit":    reflect.TypeOf(model.RelativeDuration{}),
+		"Cmd.ClockLimit":  reflect.TypeOf(model.RelativeDuration{}),
+		"Cmd.MemoryLimit": reflect.TypeOf(model.RelativeSize{}),
+	}
+
+	// unlimitedFields are the limits of Cmd accepting "unlimited"
+	unlimitedFields = map[string]bool{
+		"Cmd.CPULimit":     true,
+		"Cmd.RealCPULimit": true,
+		"Cmd.ClockLimit":   true,
+		"Cmd.MemoryLimit":  true,
+		"Cmd.StackLimit":   true,
+		"Cmd.ProcLimit":    true,
+	}
+	unlimitedSchema = schema{"type": "string", "enum": []any{"unlimited"}}
+
+	// limitDescription describes the limits accepting "unlimited", which
+	// took the server default for 0 before and reject it now
+	limitDescription = "omitted or null for the server default. 0 is rejected with 400 (it took the server default before), use \"unlimited\" for no limit"
+)
+
+// nonZero raises the minimum of the integer forms of the schema to 1
+func nonZero(p schema) schema {
+	if o, ok := p["oneOf"].([]any); ok {
+		for _, s := range o {
+			nonZero(s.(schema))
+		}
+	}
+	if p["type"] == "integer" {
+		p["minimum"] = 1
+	}
+	return p
+}
+
+// schemaOf returns the schema of the type, named structs are defined in the
+// components and referenced
+func (g *generator) schemaOf(t reflect.Type) schema {
+	switch t {
+	case statusType:
+		var names []any
+		for s := envexec.Status(0); s.Known(); s++ {
+			names = append(names, s.String())
+		}
+		return schema{"type": "string", "enum": names}
+
+	case fileErrorTypeType:
+		var names []any
+		for t := envexec.FileErrorType(0); t.String() != ""; t++ {
+			names = append(names, t.String())
+		}
+		return schema{"type": "string", "enum": names}
+
+	case errorCodeType:
+		var names []any
+		for _, c := range model.ErrorCodes {
+			names = append(names, string(c))
+		}
+		return schema{"type": "string", "enum": names}
+
+	case durationType:
+		// nanoseconds or the string with unit
+		return schema{"oneOf": []any{
+			schema{"type": "integer", "minimum": 0},
+			schema{"type": "string", "pattern": `^[0-9.]+(ns|us|µs|ms|s|m|h)([0-9.]+(ns|us|µs|ms|s|m|h))*$`},
+		}}
+
+	case sizeType:
+		// bytes or the string with binary unit
+		return schema{"oneOf": []any{
+			schema{"type": "integer", "minimum": 0},
+			schema{"type": "string", "pattern": `^[0-9.]+([bB]|[kKmMgGtT]([iI]?[bB])?)$`},
+		}}
+
+	case limitValueType:
+		// number or unlimited
+		return schema{"oneOf": []any{
+			schema{"type": "integer", "minimum": 0},
+			unlimitedSchema,
+		}}
+
+	case copyOutFileType:
+		// file name (with suffix ? for optional) or the object form
+		g.define(t)
+		return schema{"oneOf": []any{
+			schema{"type": "string"},
+			ref(t),
+		}}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.schemaOf(t.Elem())
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return schema{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return schema{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return schema{"type": "number"}
+	case reflect.String:
+		return schema{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return schema{"type": "array", "items": g.schemaOf(t.Elem())}
+	case reflect.Map:
+		return schema{"type": "object", "additionalProperties": g.schemaOf(t.Elem())}
+	case reflect.Struct:
+		g.define(t)
+		return ref(t)
+	}
+	log.Fatalf("unsupported type %v", t)
+	return nil
+}
+
+func ref(t reflect.Type) schema {
+	return schema{"$ref": "#/components/schemas/" + t.Name()}
+}
+
+// define adds the struct into the components
+func (g *generator) define(t reflect.Type) {
+	if o, ok := g.types[t.Name()]; ok {
+		if o != t {
+			log.Fatalf("component %s is defined by both %v and %v", t.Name(), o, t)
+		}
+		return
+	}
+	g.types[t.Name()] = t
+
+	s := schema{"type": "object", "additionalProperties": false}
+	g.components[t.Name()] = s
+	if d := g.typeDoc[t.Name()]; d != "" {
+		s["description"] = d
+	}
+	props := make(map[string]any)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, opts, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		p := g.schemaOf(f.Type)
+		if r, ok := relativeLimitTypes[t.Name()+"."+f.Name]; ok {
+			g.define(r)
+			p = schema{"oneOf": append(p["oneOf"].([]any), ref(r))}
+		}
+		if unlimitedFields[t.Name()+"."+f.Name] {
+			p = nonZero(p)
+			if o, ok := p["oneOf"].([]any); ok {
+				p = schema{"oneOf": append(o, unlimitedSchema)}
+			} else {
+				p = schema{"oneOf": []any{p, unlimitedSchema}}
+			}
+		}
+		// nil pointers, slices and maps are encoded as null
+		switch f.Type.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map:
+			if !strings.Contains(opts, "omitempty") {
+				if _, ok := p["$ref"]; ok {
+					p = schema{"allOf": []any{p}}
+				}
+				p["nullable"] = true
+			}
+		}
+		d := g.fieldDoc[t.Name()+"."+f.Name]
+		if d == "" && unlimitedFields[t.Name()+"."+f.Name] {
+			d = limitDescription
+		}
+		if d != "" {
+			if _, ok := p["$ref"]; ok {
+				p = schema{"allOf": []any{p}}
+			}
+			p["description"] = d
+		}
+		props[name] = p
+	}
+	s["properties"] = props
+}
+
+func jsonContent(s schema) schema {
+	return schema{"application/json": schema{"schema": s}}
+}
+
+func response(desc string, s schema) schema {
+	r := schema{"description": desc}
+	if s != nil {
+		r["content"] = jsonContent(s)
+	}
+	return r
+}
+
+func (g *generator) document() schema {
+	request := g.schemaOf(reflect.TypeOf(model.Request{}))
+	results := g.schemaOf(reflect.TypeOf([]model.Result{}))
+	fileIDs := schema{"type": "object", "additionalProperties": schema{"type": "string"}}
+	errorResponse := g.schemaOf(reflect.TypeOf(model.ErrorResponse{}))
+
+	fileID := schema{
+		"name":     "fid",
+		"in":       "path",
+		"required": true,
+		"schema":   schema{"type": "string"},
+	}
+	return schema{
+		"openapi": "3.0.3",
+		"info": schema{
+			"title":       "go-judge",
+			"description": "REST API of the executor server, see README for the details of the fields. Integer durations are in ns and sizes are in bytes.",
+			"version":     "1",
+		},
+		"paths": schema{
+			"/run": schema{
+				"post": schema{
+					"summary": "Run the commands of the request",
+					"parameters": []any{schema{
+						"name":        "validate",
+						"in":          "query",
+						"description": "validate and return the request with the limits resolved without running it if true (1, t, true), 400 if not a boolean",
+						"schema":      schema{"type": "boolean"},
+					}, schema{
+						"name":        "Idempotency-Key",
+						"in":          "header",
+						"description": "the same as idempotencyKey of the request, 400 if both are given and differ",
+						"schema":      schema{"type": "string"},
+					}},
+					"requestBody": schema{
+						"required": true,
+						"content":  jsonContent(request),
+					},
+					"responses": schema{
+						"200": response("results of the commands, the response object with the usage of the request if responseObject is set", schema{"oneOf": []any{results, g.schemaOf(reflect.TypeOf(model.Response{}))}}),
+						"400": response("invalid request", errorResponse),
+						"403": response("denied by the policy of the token", errorResponse),
+						"413": response("request body or inline content too large", errorResponse),
+						"429": response("queue full or quota exhausted", errorResponse),
+						"503": response("file store not available, environment could not be created or shutting down", errorResponse),
+					},
+				},
+			},
+			"/runs": schema{
+				"post": schema{
+					"summary": "Run the requests together, one result per request in order or one line per request as soon as it finished with Accept: application/x-ndjson followed by the summary",
+					"parameters": []any{schema{
+						"name":        "Idempotency-Key",
+						"in":          "header",
+						"description": "checkpoint the results of the requests as they finished, the requests of the same payload re-submitted with the key are not run again. Each request runs with the idempotencyKey derived from the key and must not set its own. 400 without -history-db",
+						"schema":      schema{"type": "string"},
+					}},
+					"requestBody": schema{
+						"required": true,
+						"content":  jsonContent(schema{"type": "array", "items": request}),
+					},
+					"responses": schema{
+						"200": schema{
+							"description": "results of the requests",
+							"content": schema{
+								"application/json":     schema{"schema": g.schemaOf(reflect.TypeOf([]model.BatchResult{}))},
+								"application/x-ndjson": schema{"schema": schema{"oneOf": []any{g.schemaOf(reflect.TypeOf(model.BatchResult{})), g.schemaOf(reflect.TypeOf(model.BatchSummary{}))}}},
+							},
+						},
+						"400": response("invalid request", errorResponse),
+						"413": response("request body too large", errorResponse),
+						"503": response("file store not available", errorResponse),
+					},
+				},
+			},
+			"/calibrate": schema{
+				"post": schema{
+					"summary": "Run the request of a single command repeatedly in the same environment and return the distribution of its times",
+					"requestBody": schema{
+						"required": true,
+						"content":  jsonContent(g.schemaOf(reflect.TypeOf(model.CalibrateRequest{}))),
+					},
+					"responses": schema{
+						"200": response("distribution of the times", g.schemaOf(reflect.TypeOf(model.Calibration{}))),
+						"400": response("invalid request or repeat above -calibrate-max", errorResponse),
+						"413": response("request body or inline content too large", errorResponse),
+						"429": response("queue full or quota exhausted", errorResponse),
+						"503": response("file store not available, environment could not be created or shutting down", errorResponse),
+					},
+				},
+			},
+			"/file": schema{
+				"get": schema{
+					"summary":   "List the files in the file store",
+					"responses": schema{"200": response("file id -> file name", fileIDs)},
+				},
+				"post": schema{
+					"summary": "Upload files into the file store",
+					"requestBody": schema{
+						"required": true,
+						"content":  schema{"multipart/form-data": schema{"schema": schema{"type": "object"}}},
+					},
+					"responses": schema{
+						"200": response("file id of the uploaded file", schema{"type": "string"}),
+						"400": response("invalid upload", errorResponse),
+						"413": response("request body too large", errorResponse),
+						"422": response("sha256 mismatch", errorResponse),
+					},
+				},
+			},
+			"/file/{fid}": schema{
+				"get": schema{
+					"summary":    "Download the file from the file store",
+					"parameters": []any{fileID},
+					"responses": schema{
+						"200": schema{"description": "file content"},
+						"404": response("file not found", errorResponse),
+					},
+				},
+				"delete": schema{
+					"summary":    "Delete the file from the file store",
+					"parameters": []any{fileID},
+					"responses": schema{
+						"200": schema{"description": "file deleted"},
+						"404": response("file not found", errorResponse),
+					},
+				},
+			},
+			"/openapi.json": schema{
+				"get": schema{
+					"summary":   "This document",
+					"responses": schema{"200": response("OpenAPI document", schema{"type": "object"})},
+				},
+			},
+			"/example/run": schema{
+				"get": schema{
+					"summary":   "Example request of POST /run",
+					"responses": schema{"200": response("example request", request)},
+				},
+			},
+		},
+		"components": schema{"schemas": g.components},
+	}
+}
+
+// validateExample validates the encoded example request against the schema
+func (g *generator) validateExample() error {
+	b, err := json.Marshal(openapi.ExampleRun)
+	if err != nil {
+		return err
+	}
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+	return g.validate(g.schemaOf(reflect.TypeOf(model.Request{})), v, "request")
+}
+
+// validate checks the value decoded from json against the schemas produced
+// by the generator
+func (g *generator) validate(s schema, v any, path string) error {
+	if r, ok := s["$ref"].(string); ok {
+		return g.validate(g.components[strings.TrimPrefix(r, "#/components/schemas/")], v, path)
+	}
+	if v == nil {
+		if s["nullable"] == true {
+			return nil
+		}
+		return fmt.Errorf("%s: unexpected null", path)
+	}
+	if all, ok := s["allOf"].([]any); ok {
+		for _, a := range all {
+			if err := g.validate(a.(schema), v, path); err != nil {
+				return err
+			}
+		}
+	}
+	if one, ok := s["oneOf"].([]any); ok {
+		n := 0
+		for _, o := range one {
+			if g.validate(o.(schema), v, path) == nil {
+				n++
+			}
+		}
+		if n != 1 {
+			return fmt.Errorf("%s: matches %d of oneOf", path, n)
+		}
+	}
+	if enum, ok := s["enum"].([]any); ok {
+		found := false
+		for _, e := range enum {
+			found = found || e == v
+		}
+		if !found {
+			return fmt.Errorf("%s: %v is not in enum", path, v)
+		}
+	}
+	switch s["type"] {
+	case "boolean":
+		if _, ok := v.(bool); !ok {
+			return fmt.Errorf("%s: expected boolean", path)
+		}
+	case "integer", "number":
+		n, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("%s: expected number", path)
+		}
+		if s["type"] == "integer" && n != float64(int64(n)) {
+			return fmt.Errorf("%s: expected integer", path)
+		}
+		// int as generated or float64 as decoded from the document
+		switch m := s["minimum"].(type) {
+		case int:
+			if n < float64(m) {
+				return fmt.Errorf("%s: expected at least %d", path, m)
+			}
+		case float64:
+			if n < m {
+				return fmt.Errorf("%s: expected at least %v", path, m)
+			}
+		}
+	case "string":
+		if _, ok := v.(string); !ok {
+			return fmt.Errorf("%s: expected string", path)
+		}
+	case "array":
+		a, ok := v.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected array", path)
+		}
+		for i, e := range a {
+			if err := g.validate(s["items"].(schema), e, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		o, ok := v.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected object", path)
+		}
+		props, _ := s["properties"].(map[string]any)
+		for k, e := range o {
+			p, ok := props[k]
+			if !ok {
+				ap, ok := s["additionalProperties"].(schema)
+				if !ok {
+					return fmt.Errorf("%s: unknown property %s", path, k)
+				}
+				p = ap
+			}
+			if err := g.validate(p.(schema), e, path+"."+k); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/criyle/go-judge/cmd/executorserver/openapi"
+)
+
+func TestDocumentInSync(t *testing.T) {
+	_, b, err := generate("../../model")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, openapi.Document) {
+		t.Fatal("openapi.json is out of date with the model, run go generate ./cmd/executorserver/openapi")
+	}
+}
+
+// lookup returns the schema at the path of keys in the decoded document
+func lookup(t *testing.T, s schema, keys ...string) schema {
+	t.Helper()
+	for _, k := range keys {
+		v, ok := s[k].(schema)
+		if !ok {
+			t.Fatalf("%s is not found in the document", k)
+		}
+		s = v
+	}
+	return s
+}
+
+func TestServedDocumentValidatesExample(t *testing.T) {
+	var doc schema
+	if err := json.Unmarshal(openapi.Document, &doc); err != nil {
+		t.Fatal(err)
+	}
+	// validated against the served document instead of the generated schemas
+	g := &generator{components: make(map[string]schema)}
+	for name, s := range lookup(t, doc, "components", "schemas") {
+		g.components[name] = s.(schema)
+	}
+	request := lookup(t, doc, "paths", "/run", "post", "requestBody", "content", "application/json", "schema")
+
+	example, err := json.Marshal(openapi.ExampleRun)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		name  string
+		body  string
+		valid bool
+	}{
+		{"example", string(example), true},
+		{"unknown field", `{"cmd":[{"args":["a"],"unknown":1}]}`, false},
+		{"wrong type", `{"cmd":[{"args":"a"}]}`, false},
+		{"negative limit", `{"cmd":[{"args":["a"],"cpuLimit":-1}]}`, false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var v any
+			if err := json.Unmarshal([]byte(c.body), &v); err != nil {
+				t.Fatal(err)
+			}
+			if err := g.validate(request, v, "request"); (err == nil) != c.valid {
+				t.Fatalf("validate %s: %v, want valid %v", c.body, err, c.valid)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Package openapi provides the OpenAPI v3 document of the REST API, which is
+// generated from the types in the model package by go generate, and the
+// canonical example request of POST /run.
+package openapi
+
+import (
+	_ "embed"
+
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+)
+
+//go:generate go run ./gen -o openapi.json
+
+// Document is the generated OpenAPI document served at GET /openapi.json
+//
+//go:embed openapi.json
+var Document []byte
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// ExampleRun is the example request served at GET /example/run, the
+// generator validates it against the document
+var ExampleRun = model.Request{
+	RequestID: "example",
+	Cmd: []model.Cmd{{
+		Args: []string{"/usr/bin/g++", "a.cc", "-o", "a"},
+		Env:  []string{"PATH=/usr/bin:/bin"},
+		Files: []*model.CmdFile{
+			{Content: ptr("")},
+			{Name: ptr("stdout"), Max: ptr(int64(10240))},
+			{Name: ptr("stderr"), Max: ptr(int64(10240))},
+		},
+		CPULimit:          10000000000,
+		RealCPULimit:      10000000000,
+		ClockLimit:        20000000000,
+		MemoryLimit:       268435456,
+		StackLimit:        268435456,
+		ProcLimit:         50,
+		CPURateLimit:      1000,
+		CPUSetLimit:       "0-1",
+		StrictMemoryLimit: false,
+		DetailedMemory:    true,
+		CopyIn: map[string]model.CmdFile{
+			"a.cc": {Content: ptr("#include <iostream>\nint main() { std::cout << 1; }\n")},
+		},
+		CopyOut:       []model.CopyOutFile{{Name: "stdout"}, {Name: "stderr"}, {Name: "a.log", Optional: true}},
+		CopyOutCached: []model.CopyOutFile{{Name: "a"}, {Name: "a.cc", StatOnly: true}},
+		CopyOutMax:    67108864,
+		CopyOutDir:    "/tmp/out",
+
+		CopyOutMaxFiles: 64,
+		CopyOutMaxTotal: 134217728,
+	}},
+	PipeMapping: []model.PipeMap{},
+	RunMode:     "parallel",
+	Priority:    "normal",
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// panicWindow is the period the panics are counted for -panic-threshold
+const panicWindow = time.Minute
+
+// panicReporter logs the runs that panicked with their stack. With threshold,
+// the process exits once more runs than it panicked within panicWindow, since
+// the worker is likely broken rather than a single malformed request.
+type panicReporter struct {
+	threshold int
+
+	mu     sync.Mutex
+	recent []time.Time // panics within panicWindow
+}
+
+var panics panicReporter
+
+func (p *panicReporter) observe(requestID string, value any, stack []byte) {
+	execPanicCount.Inc()
+	logger.Sugar().Errorf("Run of request %q panicked, its environments are destroyed: %v\n%s",
+		requestID, value, stack)
+	if p.threshold <= 0 {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	i := 0
+	for i < len(p.recent) && now.Sub(p.recent[i]) > panicWindow {
+		i++
+	}
+	p.recent = append(p.recent[i:], now)
+	if len(p.recent) > p.threshold {
+		logger.Sugar().Fatalf("%d runs panicked within %v, exceeding -panic-threshold %d",
+			len(p.recent), panicWindow, p.threshold)
+	}
+}
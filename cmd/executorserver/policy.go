@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/cmd/executorserver/executor"
+	"github.com/criyle/go-judge/worker"
+)
+
+// policies reads the policy config and maps the policies of auth tokens into
+// the policies of their file store namespace, which identifies the client in
+// the worker
+func policies(conf *config.Config) (map[string]worker.Policy, error) {
+	p, err := executor.ReadPolicyConf(conf.PolicyConf)
+	if err != nil {
+		return nil, err
+	}
+	owners := tokenOwners(conf.AuthToken, conf.AdminToken)
+	rt := make(map[string]worker.Policy, len(p))
+	for token, po := range p {
+		owner, ok := owners[token]
+		if !ok || owner == "" {
+			return nil, fmt.Errorf("policy config %s: policy of unknown or admin auth token", conf.PolicyConf)
+		}
+		rt[owner] = po
+	}
+	return rt, nil
+}
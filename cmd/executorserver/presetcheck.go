@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	restexecutor "github.com/criyle/go-judge/cmd/executorserver/rest_executor"
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+// presetCheckResult is the result of running the commands of a warmup preset
+// through the sandbox
+type presetCheckResult struct {
+	Name      string        `json:"name"`
+	Pass      bool          `json:"pass"`
+	Duration  time.Duration `json:"duration"`
+	Error     string        `json:"error,omitempty"`
+	CheckedAt time.Time     `json:"checkedAt"`
+}
+
+// presetChecker runs the warmup presets so that the toolchains broken by
+// upgrades of the host are found before the requests need them. With disable,
+// the presets failed are rejected until they pass the next check.
+type presetChecker struct {
+	disable bool
+
+	checkMu sync.Mutex // held by the running check
+
+	mu      sync.Mutex
+	results []presetCheckResult // nil if never checked
+}
+
+var presetChecks presetChecker
+
+// parsePresetCheckPolicy returns whether the presets failed are disabled
+func parsePresetCheckPolicy(s string) (bool, error) {
+	switch s {
+	case "", "flag":
+		return false, nil
+	case "disable":
+		return true, nil
+	}
+	return false, fmt.Errorf("preset check policy is not supported: %s", s)
+}
+
+// check runs each preset in an environment borrowed from the pool and
+// replaces the results
+func (p *presetChecker) check(ctx context.Context, work worker.Worker, envPool pool.Pool) []presetCheckResult {
+	p.checkMu.Lock()
+	defer p.checkMu.Unlock()
+
+	var rt []presetCheckResult
+	for _, name := range warmupPresetNames() {
+		rt = append(rt, checkPreset(ctx, work, envPool, name))
+	}
+	p.mu.Lock()
+	p.results = rt
+	p.mu.Unlock()
+
+	failed := 0
+	for _, r := range rt {
+		if !r.Pass {
+			failed++
+			logger.Sugar().Warnf("Preset check %s failed: %s", r.Name, r.Error)
+		}
+	}
+	logger.Sugar().Infof("Preset check finished: %d presets, %d failed", len(rt), failed)
+	return rt
+}
+
+func checkPreset(ctx context.Context, work worker.Worker, envPool pool.Pool, name string) presetCheckResult {
+	rt := presetCheckResult{Name: name, CheckedAt: time.Now()}
+	env, err := envPool.Get(ctx, worker.EnvironmentOptions{})
+	if err != nil {
+		rt.Error = err.Error()
+		return rt
+	}
+	defer envPool.Put(env)
+
+	for _, c := range warmupPresets[name] {
+		res := work.Warmup(ctx, env, c.workerCmd())
+		if res.Status != envexec.StatusAccepted {
+			rt.Error = fmt.Sprintf("%s: %s", c.Args[0], res.Status)
+			if res.Error != "" {
+				rt.Error += ": " + res.Error
+			} else if res.Status == envexec.StatusNonzeroExitStatus {
+				rt.Error += fmt.Sprintf(" %d", res.ExitStatus)
+			}
+			break
+		}
+	}
+	rt.Duration = time.Since(rt.CheckedAt)
+	rt.Pass = rt.Error == ""
+	return rt
+}
+
+// Results returns the results of the last check, nil if never checked
+func (p *presetChecker) Results() []presetCheckResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.results
+}
+
+// Available returns the error of the last check of the preset if it is
+// disabled
+func (p *presetChecker) Available(name string) error {
+	if !p.disable {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, r := range p.results {
+		if r.Name == name && !r.Pass {
+			return fmt.Errorf("preset %s is disabled since its check at %s failed: %s", name, r.CheckedAt.Format(time.RFC3339), r.Error)
+		}
+	}
+	return nil
+}
+
+func generateHandlePresetCheck(conf *config.Config, work worker.Worker, envPool pool.Pool) func(*gin.Context) {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			abortNotAdmin(c)
+			return
+		}
+		if conf.StaticContainer {
+			restexecutor.AbortError(c, http.StatusBadRequest, model.CodeFeatureDisabled,
+				"presets run the host toolchains which are not mounted by -static-container")
+			return
+		}
+		c.JSON(http.StatusOK, presetChecks.check(worker.DetachContext(c.Request.Context()), work, envPool))
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	math_rand "math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	recordRequestSuffix = ".request.json"
+	recordResultSuffix  = ".result.json"
+
+	// recordBuffer is the max number of records waiting to be written
+	recordBuffer = 256
+)
+
+// recordRequest is the request of POST /run written to -record-dir, the
+// inline contents of the request are replaced by their sizes and hashes
+type recordRequest struct {
+	RequestID  string          `json:"requestId,omitempty"`
+	Client     string          `json:"client"`
+	RecordedAt time.Time       `json:"recordedAt"`
+	FileIDs    []string        `json:"fileIds,omitempty"` // referenced in the file store
+	Request    json.RawMessage `json:"request"`
+}
+
+// recordResult is the response of the recorded request, the results array
+// or the error message
+type recordResult struct {
+	RequestID  string          `json:"requestId,omitempty"`
+	StatusCode int             `json:"statusCode"`
+	Response   json.RawMessage `json:"response"`
+}
+
+// recorder writes the sampled requests of POST /run and their responses as
+// pairs of JSON files into a directory of the client under -record-dir, keyed
+// by the request ID, so that they could be replayed by the replay subcommand.
+// The records are dropped when the buffer is full so that the requests are
+// never blocked.
+type recorder struct {
+	sample float64
+	sink   *asyncSink[recordPending]
+}
+
+type recordPending struct {
+	dir    string // of the client
+	key    string
+	req    recordRequest
+	result recordResult
+}
+
+// newRecorder creates the recorder of -record-dir, nil if not configured
+func newRecorder(conf *config.Config) *recorder {
+	if conf.RecordDir == "" {
+		return nil
+	}
+	if conf.RecordSample <= 0 || conf.RecordSample > 1 {
+		logger.Sugar().Fatalf("Invalid record sample %v, it should be within (0, 1]", conf.RecordSample)
+	}
+	if err := os.MkdirAll(conf.RecordDir, 0700); err != nil {
+		logger.Sugar().Fatal("Failed to create record dir: ", err)
+	}
+	return &recorder{
+		sample: conf.RecordSample,
+		sink:   newAsyncSink[recordPending]("record", recordBuffer, recordDir(conf.RecordDir), nil),
+	}
+}
+
+// recordWriter copies the response written by the handler
+type recordWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *recordWriter) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *recordWriter) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// handle records the sampled requests of POST /run, the validated ones are
+// not recorded since they never run
+func (r *recorder) handle(c *gin.Context) {
+	if c.Request.Method != http.MethodPost || c.Request.URL.Path != "/run" || c.Query("validate") != "" ||
+		math_rand.Float64() >= r.sample {
+		return
+	}
+	var body bytes.Buffer
+	c.Request.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.TeeReader(c.Request.Body, &body), c.Request.Body}
+	w := &recordWriter{ResponseWriter: c.Writer}
+	c.Writer = w
+	c.Next()
+
+	owner, _ := filestore.NamespaceFromContext(c.Request.Context())
+	if owner == "" {
+		owner = "default"
+	}
+	p, ok := convertRecord(body.Bytes(), owner)
+	if !ok {
+		return // not a request, e.g. cut off at the max body size
+	}
+	p.result = recordResult{
+		RequestID:  p.req.RequestID,
+		StatusCode: w.Status(),
+		Response:   recordResponse(w.body.Bytes()),
+	}
+	r.sink.observe(p)
+}
+
+// recordResponse returns the response as JSON, the plain text one is quoted
+func recordResponse(b []byte) json.RawMessage {
+	b = bytes.TrimSpace(b)
+	if json.Valid(b) {
+		return b
+	}
+	q, _ := json.Marshal(string(b))
+	return q
+}
+
+var recordKeyPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,128}$`)
+
+// validRecordKey reports whether the key is safe as a file name
+func validRecordKey(key string) bool {
+	return recordKeyPattern.MatchString(key) && key != "." && key != ".."
+}
+
+// recordClientDir returns the directory name of the client, the hash of the
+// name if it is not safe as a file name
+func recordClientDir(client string) string {
+	if validRecordKey(client) {
+		return client
+	}
+	sum := sha256.Sum256([]byte(client))
+	return hex.EncodeToString(sum[:8])
+}
+
+// convertRecord replaces the inline contents of the request body by their
+// sizes and hashes and notes the referenced file ids
+func convertRecord(body []byte, client string) (recordPending, bool) {
+	var req map[string]any
+	if err := json.Unmarshal(body, &req); err != nil {
+		return recordPending{}, false
+	}
+	p := recordPending{req: recordRequest{Client: client, RecordedAt: time.Now()}}
+	if id, ok := req["requestId"].(string); ok {
+		p.req.RequestID = id
+	}
+	seen := make(map[string]bool)
+	replace := func(v any) {
+		f, ok := v.(map[string]any)
+		if !ok {
+			return
+		}
+		if content, ok := f["content"].(string); ok {
+			sum := sha256.Sum256([]byte(content))
+			delete(f, "content")
+			f["contentSize"] = len(content)
+			f["contentSha256"] = hex.EncodeToString(sum[:])
+		}
+		if id, ok := f["fileId"].(string); ok && !seen[id] {
+			seen[id] = true
+			p.req.FileIDs = append(p.req.FileIDs, id)
+		}
+	}
+	cmds, _ := req["cmd"].([]any)
+	for _, c := range cmds {
+		c, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		if files, ok := c["files"].([]any); ok {
+			for _, f := range files {
+				replace(f)
+			}
+		}
+		for _, k := range []string{"extraFiles", "copyIn"} {
+			if files, ok := c[k].(map[string]any); ok {
+				for _, f := range files {
+					replace(f)
+				}
+			}
+		}
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return recordPending{}, false
+	}
+	p.req.Request = b
+
+	p.dir = recordClientDir(client)
+	p.key = p.req.RequestID
+	if !validRecordKey(p.key) {
+		var id [8]byte
+		rand.Read(id[:])
+		p.key = p.req.RecordedAt.UTC().Format("20060102T150405") + "-" + hex.EncodeToString(id[:])
+	}
+	return p, true
+}
+
+// recordDir writes the records into the directories of the clients under it
+type recordDir string
+
+// write writes the request before the result, so that a request without the
+// result is the incomplete record
+func (d recordDir) write(p recordPending) error {
+	dir := filepath.Join(string(d), p.dir)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	for _, f := range []struct {
+		suffix string
+		v      any
+	}{
+		{recordRequestSuffix, p.req},
+		{recordResultSuffix, p.result},
+	} {
+		b, err := json.MarshalIndent(f.v, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dir, p.key+f.suffix), append(b, '\n'), 0600); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d recordDir) flush() error {
+	return nil
+}
+
+func (d recordDir) close() error {
+	return nil
+}
+
+func initRecorder(rec *recorder, work worker.Worker) initFunc {
+	return func() (start func(), cleanUp stopFunc) {
+		if rec == nil {
+			return nil, nil
+		}
+		return nil, func(ctx context.Context) error {
+			// requests finished during shutdown are still recorded
+			work.Shutdown()
+			rec.sink.close()
+			logger.Sugar().Infof("Record dir closed, %d records dropped", rec.sink.dropCount())
+			return nil
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/gin-gonic/gin"
+)
+
+func TestRecorderClients(t *testing.T) {
+	dir := t.TempDir()
+	rec := newRecorder(&config.Config{RecordDir: dir, RecordSample: 1})
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		if client := c.GetHeader("X-Client"); client != "" {
+			c.Request = c.Request.WithContext(filestore.NewNamespaceContext(c.Request.Context(), client))
+		}
+	}, rec.handle)
+	r.POST("/run", func(c *gin.Context) {
+		io.Copy(io.Discard, c.Request.Body)
+		c.String(http.StatusOK, "[]")
+	})
+	for _, client := range []string{"", "a", "b", "../c"} {
+		req := httptest.NewRequest(http.MethodPost, "/run", strings.NewReader(`{"requestId": "same", "cmd": []}`))
+		req.Header.Set("X-Client", client)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	rec.sink.close()
+
+	for _, client := range []string{"default", "a", "b", recordClientDir("../c")} {
+		for _, suffix := range []string{recordRequestSuffix, recordResultSuffix} {
+			if _, err := os.Stat(filepath.Join(dir, client, "same"+suffix)); err != nil {
+				t.Errorf("record of client %s: %v", client, err)
+			}
+		}
+	}
+	if d := recordClientDir("../c"); strings.ContainsAny(d, "./") {
+		t.Errorf("directory %q of the unsafe client", d)
+	}
+}
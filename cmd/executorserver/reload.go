@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/cmd/executorserver/executor"
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	restexecutor "github.com/criyle/go-judge/cmd/executorserver/rest_executor"
+	"github.com/criyle/go-judge/env"
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/gin-gonic/gin"
+)
+
+// configReloader re-reads mount, seccomp and policy config, switches the
+// environment pool to the new environment builder and the worker to the new
+// policies
+type configReloader struct {
+	conf    *config.Config
+	exec    *executor.Executor
+	envPool pool.Pool
+
+	mu           sync.Mutex
+	generation   uint64
+	builderParam map[string]any
+}
+
+func newConfigReloader(conf *config.Config, exec *executor.Executor) *configReloader {
+	return &configReloader{
+		conf:         conf,
+		exec:         exec,
+		envPool:      exec.EnvPool(),
+		builderParam: exec.BuilderParam(),
+	}
+}
+
+// Reload creates a new environment builder and reads the policies with the
+// current config files.
+// The previous config is kept when any error occurs.
+func (r *configReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tokenPolicies, err := policies(r.conf)
+	if err != nil {
+		return err
+	}
+	b, param, err := r.exec.NewEnvBuilder()
+	if err != nil {
+		return err
+	}
+	if err := checkMissingMount(r.builderParam, param); err != nil {
+		return err
+	}
+	if report, _ := param["isolation"].(env.IsolationReport); r.conf.Strict && len(report.Degraded()) > 0 {
+		return fmt.Errorf("isolation features are not active in strict mode: %v", report.Degraded())
+	}
+	r.envPool.Reload(b)
+	r.exec.Worker().SetPolicies(tokenPolicies)
+	r.builderParam = param
+	r.generation++
+	return nil
+}
+
+// Current returns the config generation and the runner config currently in use
+func (r *configReloader) Current() (uint64, map[string]any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.generation, r.builderParam
+}
+
+// checkMissingMount rejects the new config if it contains bind mount that
+// does not exist while it was not missing in the previous config
+func checkMissingMount(prev, cur map[string]any) error {
+	missing := make(map[string]bool)
+	if m, ok := prev["missingMount"].([]string); ok {
+		for _, s := range m {
+			missing[s] = true
+		}
+	}
+	if m, ok := cur["missingMount"].([]string); ok {
+		for _, s := range m {
+			if !missing[s] {
+				return fmt.Errorf("mount source %s does not exist", s)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *configReloader) reload(from string) error {
+	err := r.Reload()
+	if err != nil {
+		logger.Sugar().Error("Config reload from ", from, " failed, keep previous config: ", err)
+		return err
+	}
+	gen, param := r.Current()
+	logger.Sugar().Infof("Config reloaded from %s: generation=%d, runnerConfig=%+v", from, gen, param)
+	if r.conf.PresetCheck {
+		go presetChecks.check(context.Background(), r.exec.Worker(), r.envPool)
+	}
+	return nil
+}
+
+func initReloadSignal(r *configReloader) initFunc {
+	return func() (start func(), cleanUp stopFunc) {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, syscall.SIGHUP)
+		go func() {
+			for range sig {
+				r.reload("SIGHUP")
+			}
+		}()
+		return nil, nil
+	}
+}
+
+func generateHandleReload(r *configReloader) func(*gin.Context) {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			abortNotAdmin(c)
+			return
+		}
+		if err := r.reload("/admin/reload"); err != nil {
+			restexecutor.AbortError(c, http.StatusUnprocessableEntity, model.CodeInvalidConfig, err.Error())
+			return
+		}
+		gen, _ := r.Current()
+		c.JSON(http.StatusOK, gin.H{
+			"configGeneration": gen,
+		})
+	}
+}
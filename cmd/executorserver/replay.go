@@ -0,0 +1,371 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+)
+
+// replayVolatile are the fields of the results expected to differ between
+// runs, they are not compared unless -strict
+var replayVolatile = map[string]bool{
+	"time": true, "runTime": true, "memory": true, "procPeak": true, "memoryStat": true, "ioStat": true,
+	"throttling": true, "overheadCpuTime": true, "workDirUsage": true, "workDirSize": true, "shmUsage": true,
+	"diskUsage": true, "startedAt": true, "hostname": true, "instanceId": true, "environmentId": true,
+	"cgroupId": true, "fileIds": true, "groupId": true, "coreFileId": true, "workDir": true, "limits": true,
+	"relativeLimits": true, "noisyNeighbor": true, "warning": true,
+}
+
+// replayDiff is a difference between the recorded and the replayed response
+type replayDiff struct {
+	Path     string `json:"path"`
+	Recorded any    `json:"recorded"`
+	Replayed any    `json:"replayed"`
+}
+
+// replayOptions are the flags of the replay subcommand
+type replayOptions struct {
+	addr       string // executorserver running the request
+	token      string
+	fetch      string // executorserver the request was recorded on
+	fetchToken string
+	files      string // directory of the inline contents named by sha256
+	strict     bool
+	stdin      *bufio.Reader
+}
+
+// replayMain runs the replay subcommand:
+//
+//	executorserver replay [flags] <record-dir>/<client>/<key>
+//
+// It reads the request of the record, restores the inline contents from -files
+// (or the paths prompted), uploads the referenced files fetched from -fetch
+// (or the paths prompted) to the executorserver of -addr, runs the request
+// there and prints the differences from the recorded response as JSON lines.
+// It exits with 1 if the responses differ.
+func replayMain(args []string) int {
+	var opt replayOptions
+	fs := flag.NewFlagSet("replay", flag.ContinueOnError)
+	fs.StringVar(&opt.addr, "addr", "http://localhost:5050", "executorserver running the request")
+	fs.StringVar(&opt.token, "token", "", "bearer token of -addr")
+	fs.StringVar(&opt.fetch, "fetch", "", "executorserver the request was recorded on, where the files referenced by fileId are fetched from (prompt for the paths if not set)")
+	fs.StringVar(&opt.fetchToken, "fetch-token", "", "bearer token of -fetch")
+	fs.StringVar(&opt.files, "files", "", "directory of the inline contents named by their sha256 (prompt for the paths of the missing ones)")
+	fs.BoolVar(&opt.strict, "strict", false, "also compare the fields expected to differ between runs (e.g. time, memory)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: executorserver replay [flags] <record-dir>/<client>/<key>")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if err == flag.ErrHelp {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	opt.stdin = bufio.NewReader(os.Stdin)
+
+	diffs, err := replay(&opt, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "replay:", err)
+		return 2
+	}
+	enc := json.NewEncoder(os.Stdout)
+	for _, d := range diffs {
+		enc.Encode(d)
+	}
+	if len(diffs) > 0 {
+		fmt.Fprintf(os.Stderr, "replay: %d differences\n", len(diffs))
+		return 1
+	}
+	fmt.Fprintln(os.Stderr, "replay: same as recorded")
+	return 0
+}
+
+func replay(opt *replayOptions, key string) ([]replayDiff, error) {
+	key = strings.TrimSuffix(strings.TrimSuffix(key, recordRequestSuffix), recordResultSuffix)
+	var (
+		rec    recordRequest
+		result recordResult
+	)
+	if err := readJSONFile(key+recordRequestSuffix, &rec); err != nil {
+		return nil, err
+	}
+	if err := readJSONFile(key+recordResultSuffix, &result); err != nil {
+		return nil, err
+	}
+	var req map[string]any
+	if err := json.Unmarshal(rec.Request, &req); err != nil {
+		return nil, fmt.Errorf("request: %v", err)
+	}
+	if err := opt.restore(req); err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	code, body, err := opt.do(http.MethodPost, opt.addr+"/run", opt.token, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	var recorded, replayed any
+	if err := json.Unmarshal(result.Response, &recorded); err != nil {
+		return nil, fmt.Errorf("recorded response: %v", err)
+	}
+	if err := json.Unmarshal(recordResponse(body), &replayed); err != nil {
+		return nil, fmt.Errorf("replayed response: %v", err)
+	}
+	var diffs []replayDiff
+	if code != result.StatusCode {
+		diffs = append(diffs, replayDiff{Path: "statusCode", Recorded: result.StatusCode, Replayed: code})
+	}
+	return opt.diff("", recorded, replayed, diffs), nil
+}
+
+func readJSONFile(p string, v any) error {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%s: %v", p, err)
+	}
+	return nil
+}
+
+// restore puts back the inline contents and replaces the file ids by the ones
+// uploaded to -addr
+func (opt *replayOptions) restore(req map[string]any) error {
+	uploaded := make(map[string]string)
+	restore := func(where string, v any) error {
+		f, ok := v.(map[string]any)
+		if !ok {
+			return nil
+		}
+		if sum, ok := f["contentSha256"].(string); ok {
+			size, _ := f["contentSize"].(float64)
+			b, err := opt.content(where, sum, int64(size))
+			if err != nil {
+				return err
+			}
+			delete(f, "contentSha256")
+			delete(f, "contentSize")
+			f["content"] = string(b)
+		}
+		if id, ok := f["fileId"].(string); ok {
+			if _, ok := uploaded[id]; !ok {
+				nid, err := opt.upload(where, id)
+				if err != nil {
+					return err
+				}
+				uploaded[id] = nid
+			}
+			f["fileId"] = uploaded[id]
+		}
+		return nil
+	}
+	cmds, _ := req["cmd"].([]any)
+	for i, c := range cmds {
+		c, ok := c.(map[string]any)
+		if !ok {
+			continue
+		}
+		if files, ok := c["files"].([]any); ok {
+			for j, f := range files {
+				if err := restore(fmt.Sprintf("cmd[%d].files[%d]", i, j), f); err != nil {
+					return err
+				}
+			}
+		}
+		for _, k := range []string{"extraFiles", "copyIn"} {
+			files, _ := c[k].(map[string]any)
+			for _, name := range sortedKeys(files) {
+				if err := restore(fmt.Sprintf("cmd[%d].%s[%s]", i, k, name), files[name]); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// content reads the inline content from -files or the path prompted and
+// checks its hash
+func (opt *replayOptions) content(where, sum string, size int64) ([]byte, error) {
+	if opt.files != "" {
+		b, err := os.ReadFile(filepath.Join(opt.files, sum))
+		if err == nil && hashOf(b) == sum {
+			return b, nil
+		}
+	}
+	p, err := opt.prompt(fmt.Sprintf("path of the content of %s (%d bytes, sha256 %s): ", where, size, sum))
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	if h := hashOf(b); h != sum {
+		return nil, fmt.Errorf("%s: sha256 of %s is %s, expected %s", where, p, h, sum)
+	}
+	return b, nil
+}
+
+// upload fetches the file from -fetch or reads it from the path prompted and
+// uploads it to -addr
+func (opt *replayOptions) upload(where, id string) (string, error) {
+	var (
+		content []byte
+		err     error
+	)
+	if opt.fetch != "" {
+		var code int
+		code, content, err = opt.do(http.MethodGet, opt.fetch+"/file/"+id, opt.fetchToken, "", nil)
+		if err == nil && code != http.StatusOK {
+			err = fmt.Errorf("%s: fetch file %s: %w", where, id, model.ParseError(code, content))
+		}
+	} else {
+		var p string
+		if p, err = opt.prompt(fmt.Sprintf("path of the file %s of %s: ", id, where)); err == nil {
+			content, err = os.ReadFile(p)
+		}
+	}
+	if err != nil {
+		return "", err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	w, err := mw.CreateFormFile("file", id)
+	if err != nil {
+		return "", err
+	}
+	w.Write(content)
+	mw.Close()
+	code, b, err := opt.do(http.MethodPost, opt.addr+"/file", opt.token, mw.FormDataContentType(), &body)
+	if err != nil {
+		return "", err
+	}
+	if code != http.StatusOK {
+		return "", fmt.Errorf("%s: upload file %s: %w", where, id, model.ParseError(code, b))
+	}
+	var nid string
+	if err := json.Unmarshal(b, &nid); err != nil {
+		return "", fmt.Errorf("%s: upload file %s: %v", where, id, err)
+	}
+	return nid, nil
+}
+
+func (opt *replayOptions) prompt(msg string) (string, error) {
+	fmt.Fprint(os.Stderr, msg)
+	line, err := opt.stdin.ReadString('\n')
+	line = strings.TrimSpace(line)
+	if line == "" {
+		if err == nil || err == io.EOF {
+			err = fmt.Errorf("no path given")
+		}
+		return "", err
+	}
+	return line, nil
+}
+
+func (opt *replayOptions) do(method, url, token, contentType string, body io.Reader) (int, []byte, error) {
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return 0, nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	return resp.StatusCode, b, err
+}
+
+// diff appends the differences of the JSON values under the path
+func (opt *replayOptions) diff(path string, a, b any, diffs []replayDiff) []replayDiff {
+	switch av := a.(type) {
+	case map[string]any:
+		bv, ok := b.(map[string]any)
+		if !ok {
+			break
+		}
+		keys := sortedKeys(av)
+		for k := range bv {
+			if _, ok := av[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if !opt.strict && replayVolatile[k] {
+				continue
+			}
+			diffs = opt.diff(joinPath(path, k), av[k], bv[k], diffs)
+		}
+		return diffs
+	case []any:
+		bv, ok := b.([]any)
+		if !ok || len(av) != len(bv) {
+			break
+		}
+		for i := range av {
+			diffs = opt.diff(path+"["+strconv.Itoa(i)+"]", av[i], bv[i], diffs)
+		}
+		return diffs
+	}
+	ab, _ := json.Marshal(a)
+	bb, _ := json.Marshal(b)
+	if !bytes.Equal(ab, bb) {
+		diffs = append(diffs, replayDiff{Path: path, Recorded: a, Replayed: b})
+	}
+	return diffs
+}
+
+func joinPath(path, k string) string {
+	if path == "" {
+		return k
+	}
+	return path + "." + k
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func hashOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,84 @@
+package restexecutor
+
+import (
+	"bufio"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/gin-gonic/gin"
+)
+
+// handleCalibrate runs the request of a single command repeatedly in the same
+// environment and responds with the distribution of its times. The request is
+// queued like POST /run and all repetitions run in the worker loop taking it.
+func (h *handle) handleCalibrate(c *gin.Context) {
+	if err := filestore.Available(h.fs); err != nil {
+		c.Error(err)
+		AbortError(c, http.StatusServiceUnavailable, model.CodeStoreUnavailable, err.Error())
+		return
+	}
+	var req model.CalibrateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		abortBodyError(c, err, http.StatusBadRequest, model.CodeValidationFailed)
+		return
+	}
+	if err := checkCalibrateRequest(&req); err != nil {
+		AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, err.Error())
+		return
+	}
+	r, err := model.ConvertRequest(&req.Request, h.srcPrefix)
+	if err != nil {
+		c.Error(err)
+		AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, err.Error(), errorFields(err)...)
+		return
+	}
+	r.Repeat = req.Warmup + req.Repeat
+
+	rtCh, _ := h.worker.Submit(c.Request.Context(), r)
+	rt := <-rtCh
+	if rt.CancelReason != "" {
+		c.Header("X-Cancel-Reason", string(rt.CancelReason))
+	}
+	if rt.Error != nil {
+		AbortRunError(c, rt.Error)
+		return
+	}
+	c.JSON(http.StatusOK, model.ConvertCalibration(rt, r, req.Warmup, h.cpuModel))
+}
+
+func checkCalibrateRequest(req *model.CalibrateRequest) error {
+	switch {
+	case len(req.Request.Cmd) != 1:
+		return errors.New("calibrate requires exactly one cmd")
+	case req.Repeat <= 0:
+		return errors.New("repeat must be positive")
+	case req.Warmup < 0:
+		return errors.New("warmup must not be negative")
+	case req.Request.Detach:
+		return errors.New("detach is not supported by calibrate")
+	}
+	return nil
+}
+
+// hostCPUModel returns the model name of the first processor in /proc/cpuinfo,
+// empty if not known
+func hostCPUModel() string {
+	f, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		k, v, ok := strings.Cut(s.Text(), ":")
+		if ok && strings.TrimSpace(k) == "model name" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,117 @@
+package restexecutor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/gin-gonic/gin"
+)
+
+// headerIdempotencyKey is the idempotencyKey of the request given by the
+// header, which names the checkpoint of the batch of POST /runs
+const headerIdempotencyKey = "Idempotency-Key"
+
+// errCheckpointDisabled is returned for the batch with Idempotency-Key while
+// the checkpoint is not enabled
+var errCheckpointDisabled = errors.New("Idempotency-Key of the batch requires the checkpoint enabled by -history-db")
+
+// idempotencyKey returns the idempotencyKey of POST /run given by the body or
+// Idempotency-Key, which fails if both are given and differ
+func idempotencyKey(c *gin.Context, body string) (string, error) {
+	key := c.GetHeader(headerIdempotencyKey)
+	switch {
+	case key == "":
+		return body, nil
+	case body != "" && body != key:
+		return "", fmt.Errorf("idempotencyKey %q differs from %s %q", body, headerIdempotencyKey, key)
+	}
+	return key, nil
+}
+
+// Checkpoint stores the results of the items of the batches of POST /runs
+// submitted with Idempotency-Key as each of them finished, so that the batch
+// re-submitted with the same key (e.g. after a server restart) runs only the
+// items whose results are not stored or whose payload changed
+type Checkpoint interface {
+	// Load returns the stored items of the batch of the owner by index
+	Load(owner, key string) (map[int]CheckpointItem, error)
+	// Store stores the item of the batch atomically
+	Store(owner, key string, index int, item CheckpointItem) error
+}
+
+// CheckpointItem is the stored result of an item with the hash of its payload
+type CheckpointItem struct {
+	Hash   string          `json:"hash"`
+	Result json.RawMessage `json:"result"`
+}
+
+// batchCheckpoint is the checkpoint of the batch being run
+type batchCheckpoint struct {
+	cp     Checkpoint
+	owner  string
+	key    string
+	hashes []string
+	stored map[int]CheckpointItem
+}
+
+// bindCheckpointRuns decodes the items of the batch with their payload
+// hashes, the hash ignores the whitespace of the JSON
+func bindCheckpointRuns(c *gin.Context) ([]model.Request, []string, error) {
+	var raws []json.RawMessage
+	if err := c.ShouldBindJSON(&raws); err != nil {
+		return nil, nil, err
+	}
+	reqs := make([]model.Request, len(raws))
+	hashes := make([]string, len(raws))
+	for i, raw := range raws {
+		if err := json.Unmarshal(raw, &reqs[i]); err != nil {
+			return nil, nil, fmt.Errorf("request[%d]: %w", i, err)
+		}
+		var b bytes.Buffer
+		if err := json.Compact(&b, raw); err != nil {
+			return nil, nil, fmt.Errorf("request[%d]: %w", i, err)
+		}
+		sum := sha256.Sum256(b.Bytes())
+		hashes[i] = hex.EncodeToString(sum[:])
+	}
+	return reqs, hashes, nil
+}
+
+// itemKey is the idempotencyKey of the item, so that the resubmission joins
+// the item still running instead of running it again. The payload hash is
+// included since the changed item runs again.
+func (b *batchCheckpoint) itemKey(i int) string {
+	return b.key + "/" + strconv.Itoa(i) + "/" + b.hashes[i]
+}
+
+// result returns the stored result of the item if its payload is unchanged
+func (b *batchCheckpoint) result(i int) json.RawMessage {
+	if b == nil {
+		return nil
+	}
+	if it, ok := b.stored[i]; ok && it.Hash == b.hashes[i] {
+		return it.Result
+	}
+	return nil
+}
+
+// store stores the result of the item run successfully, marked as
+// checkpointed for the re-submissions
+func (b *batchCheckpoint) store(rt *model.BatchResult) error {
+	if b == nil {
+		return nil
+	}
+	r := *rt
+	r.Checkpointed = true
+	result, err := json.Marshal(&r)
+	if err != nil {
+		return err
+	}
+	return b.cp.Store(b.owner, b.key, rt.Index, CheckpointItem{Hash: b.hashes[rt.Index], Result: result})
+}
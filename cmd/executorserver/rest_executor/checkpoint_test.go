@@ -0,0 +1,145 @@
+package restexecutor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/criyle/go-judge/filestore"
+)
+
+// memoryCheckpoint stores the checkpoints in memory
+type memoryCheckpoint struct {
+	mu     sync.Mutex
+	stored map[string]map[int]CheckpointItem
+}
+
+func (m *memoryCheckpoint) Load(owner, key string) (map[int]CheckpointItem, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ret := make(map[int]CheckpointItem)
+	for i, it := range m.stored[owner+"/"+key] {
+		ret[i] = it
+	}
+	return ret, nil
+}
+
+func (m *memoryCheckpoint) Store(owner, key string, index int, item CheckpointItem) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stored == nil {
+		m.stored = make(map[string]map[int]CheckpointItem)
+	}
+	if m.stored[owner+"/"+key] == nil {
+		m.stored[owner+"/"+key] = make(map[int]CheckpointItem)
+	}
+	m.stored[owner+"/"+key][index] = item
+	return nil
+}
+
+const testItem = `{"cmd": [{"args": ["/bin/true"], "env": ["PATH=/bin"], "cpuLimit": "1s", "memoryLimit": "64m", "procLimit": 16}]}`
+
+// postRuns posts the batch with the Idempotency-Key
+func postRuns(h http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/runs", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(headerIdempotencyKey, key)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotencyKeyWithoutCheckpoint(t *testing.T) {
+	h := newTestHandle(t, filestore.NewFileLocalStore(t.TempDir()), 0, nil)
+
+	w := postRuns(h, "k", "["+testItem+"]")
+	e := checkEnvelope(t, w, http.StatusBadRequest, model.CodeValidationFailed)
+	if len(e.Fields) != 1 || e.Fields[0].Field != headerIdempotencyKey {
+		t.Errorf("fields %+v, want %s", e.Fields, headerIdempotencyKey)
+	}
+	// without the header the batch runs as usual
+	if w := postRuns(h, "", "["+testItem+"]"); w.Code != http.StatusOK {
+		t.Errorf("status %d, want 200: %s", w.Code, w.Body)
+	}
+}
+
+func TestRunIdempotencyKeyHeader(t *testing.T) {
+	h := newTestHandle(t, filestore.NewFileLocalStore(t.TempDir()), 0, nil)
+	body := func(key string) string {
+		return strings.Replace(testItem, `{"cmd"`, `{"idempotencyKey": "`+key+`", "cmd"`, 1)
+	}
+	for _, c := range []struct {
+		name   string
+		header string
+		body   string
+		status int
+	}{
+		{"header", "a", testItem, http.StatusOK},
+		{"same", "a", body("a"), http.StatusOK},
+		{"differ", "a", body("b"), http.StatusBadRequest},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/run", strings.NewReader(c.body))
+			req.Header.Set(headerIdempotencyKey, c.header)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if c.status != http.StatusOK {
+				e := checkEnvelope(t, w, c.status, model.CodeValidationFailed)
+				if len(e.Fields) != 1 || e.Fields[0].Field != "idempotencyKey" {
+					t.Errorf("fields %+v, want idempotencyKey", e.Fields)
+				}
+				return
+			}
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d, want 200: %s", w.Code, w.Body)
+			}
+		})
+	}
+}
+
+func TestRunsCheckpoint(t *testing.T) {
+	cp := &memoryCheckpoint{}
+	h := newTestHandle(t, filestore.NewFileLocalStore(t.TempDir()), 0, cp)
+	own := strings.Replace(testItem, `{"cmd"`, `{"idempotencyKey": "own", "cmd"`, 1)
+	changed := strings.Replace(testItem, `"procLimit": 16`, `"procLimit": 8`, 1)
+
+	post := func(body string) []model.BatchResult {
+		t.Helper()
+		w := postRuns(h, "batch", body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d, want 200: %s", w.Code, w.Body)
+		}
+		var rt []model.BatchResult
+		if err := json.Unmarshal(w.Body.Bytes(), &rt); err != nil {
+			t.Fatal(err)
+		}
+		return rt
+	}
+
+	rt := post("[" + testItem + "," + testItem + "," + own + "]")
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusBadRequest} {
+		if rt[i].Status != want || rt[i].Checkpointed {
+			t.Fatalf("item %d: status %d checkpointed %v, want %d", i, rt[i].Status, rt[i].Checkpointed, want)
+		}
+	}
+	if len(rt[0].Results) != 1 || rt[0].Results[0].Status != model.StatusAccepted {
+		t.Fatalf("item 0: results %+v, want Accepted", rt[0].Results)
+	}
+	if rt[2].Code != model.CodeValidationFailed || len(rt[2].Fields) != 1 || rt[2].Fields[0].Field != "idempotencyKey" {
+		t.Fatalf("item 2: %+v, want idempotencyKey rejected", rt[2])
+	}
+
+	// the unchanged item is returned from the checkpoint, the changed one runs
+	rt = post("[" + testItem + "," + changed + "]")
+	if rt[0].Status != http.StatusOK || !rt[0].Checkpointed {
+		t.Errorf("item 0: status %d checkpointed %v, want checkpointed", rt[0].Status, rt[0].Checkpointed)
+	}
+	if rt[1].Status != http.StatusOK || rt[1].Checkpointed {
+		t.Errorf("item 1: status %d checkpointed %v, want run again", rt[1].Status, rt[1].Checkpointed)
+	}
+}
@@ -2,7 +2,10 @@ package restexecutor
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
 	"github.com/criyle/go-judge/cmd/executorserver/model"
 	"github.com/criyle/go-judge/filestore"
@@ -13,80 +16,190 @@ import (
 
 // Register registers executor the handler
 //
-// POST /run, GET /file, POST /file, GET /file/:fid, DELETE /file/:fid
+// POST /run, POST /run?validate=1, POST /runs, POST /calibrate, GET /file, POST /file, GET /file/:fid, DELETE /file/:fid
 type Register interface {
 	Register(*gin.Engine)
 }
 
-// New creates new REST API handler
-func New(worker worker.Worker, fs filestore.FileStore, srcPrefix []string, logger *zap.Logger) Register {
+// New creates new REST API handler, bodies of POST /run and POST /file larger
+// than maxRequestSize are rejected with 413 (0 unlimited). The checkpoint of
+// the batches of POST /runs is disabled if checkpoint is nil.
+func New(worker worker.Worker, fs filestore.FileStore, srcPrefix []string, maxRequestSize int64, checkpoint Checkpoint, logger *zap.Logger) Register {
 	return &handle{
-		worker:     worker,
-		fileHandle: fileHandle{fs: fs},
-		srcPrefix:  srcPrefix,
-		logger:     logger,
+		worker:         worker,
+		fileHandle:     fileHandle{fs: fs},
+		srcPrefix:      srcPrefix,
+		maxRequestSize: maxRequestSize,
+		checkpoint:     checkpoint,
+		cpuModel:       hostCPUModel(),
+		logger:         logger,
 	}
 }
 
 type handle struct {
 	worker worker.Worker
 	fileHandle
-	srcPrefix []string
-	logger    *zap.Logger
+	srcPrefix      []string
+	maxRequestSize int64
+	checkpoint     Checkpoint
+	cpuModel       string // reported by POST /calibrate
+	logger         *zap.Logger
 }
 
 func (h *handle) Register(r *gin.Engine) {
 	// Run handle
-	r.POST("/run", h.handleRun)
+	r.POST("/run", h.limitBody, h.handleRun)
+	r.POST("/runs", h.limitBody, h.handleRuns)
+	r.POST("/calibrate", h.limitBody, h.handleCalibrate)
 
 	// File handle
 	r.GET("/file", h.fileGet)
-	r.POST("/file", h.filePost)
+	r.POST("/file", h.limitBody, h.filePost)
 	r.GET("/file/:fid", h.fileIDGet)
 	r.DELETE("/file/:fid", h.fileIDDelete)
 }
 
+// limitBody rejects body with content length over the limit before reading
+// it and cuts off body without content length at the limit
+func (h *handle) limitBody(c *gin.Context) {
+	if h.maxRequestSize <= 0 {
+		return
+	}
+	if c.Request.ContentLength > h.maxRequestSize {
+		// the body is not read, the connection could not be reused
+		c.Header("Connection", "close")
+		abortBodyTooLarge(c, h.maxRequestSize)
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.maxRequestSize)
+}
+
+// queryBool parses the boolean query parameter, it is false if not set
+func queryBool(c *gin.Context, name string) (bool, error) {
+	v := c.Query(name)
+	if v == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s: invalid boolean %q", name, v)
+	}
+	return b, nil
+}
+
+// statusClientClosedRequest is logged for the requests cancelled by the client
+// disconnect, the same as nginx since nobody reads the response
+const statusClientClosedRequest = 499
+
 func (h *handle) handleRun(c *gin.Context) {
+	// fail fast since runs are doomed to fail
+	if err := filestore.Available(h.fs); err != nil {
+		c.Error(err)
+		AbortError(c, http.StatusServiceUnavailable, model.CodeStoreUnavailable, err.Error())
+		return
+	}
+	validate, err := queryBool(c, "validate")
+	if err != nil {
+		AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, err.Error(),
+			model.FieldError{Field: "validate", Message: err.Error()})
+		return
+	}
 	var req model.Request
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.Error(err)
-		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		abortBodyError(c, err, http.StatusBadRequest, model.CodeValidationFailed)
+		return
+	}
+	key, err := idempotencyKey(c, req.IdempotencyKey)
+	if err != nil {
+		AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, err.Error(),
+			model.FieldError{Field: "idempotencyKey", Message: err.Error()})
 		return
 	}
+	req.IdempotencyKey = key
 
 	if len(req.Cmd) == 0 {
-		c.AbortWithStatusJSON(http.StatusBadRequest, "no cmd provided")
+		AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, "no cmd provided",
+			model.FieldError{Field: "cmd", Message: "no cmd provided"})
 		return
 	}
 	r, err := model.ConvertRequest(&req, h.srcPrefix)
 	if err != nil {
 		c.Error(err)
-		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, err.Error(), errorFields(err)...)
 		return
 	}
 	h.logger.Sugar().Debugf("request: %+v", r)
-	rtCh, _ := h.worker.Submit(c.Request.Context(), r)
+
+	// dry run validates the request without submitting it to the worker
+	if validate {
+		if err := h.worker.Validate(c.Request.Context(), r); err != nil {
+			c.Error(err)
+			status, code := http.StatusBadRequest, model.CodeValidationFailed
+			switch {
+			case errors.Is(err, worker.ErrPolicyDenied):
+				status, code = http.StatusForbidden, model.CodePolicyDenied
+			case errors.Is(err, worker.ErrFileNotFound):
+				code = model.CodeFileNotFound
+			}
+			AbortError(c, status, code, err.Error(), errorFields(err)...)
+			return
+		}
+		// echoes the limits resolved by the worker
+		req.SetLimits(r)
+		c.JSON(http.StatusOK, req)
+		return
+	}
+
+	// the request is cancelled once the client disconnected unless detached
+	ctx := c.Request.Context()
+	if req.Detach {
+		ctx = worker.DetachContext(ctx)
+	}
+	rtCh, _ := h.worker.Submit(ctx, r)
 	rt := <-rtCh
 	h.logger.Sugar().Debugf("response: %+v", rt)
+	if rt.CancelReason != "" {
+		c.Header("X-Cancel-Reason", string(rt.CancelReason))
+	}
 	if rt.Error != nil {
-		c.Error(rt.Error)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, rt.Error.Error())
+		AbortRunError(c, rt.Error)
 		return
 	}
 
 	// encode json directly to avoid allocation
 	c.Status(http.StatusOK)
 	c.Header("Content-Type", "application/json; charset=utf-8")
+	if rt.Note != "" {
+		c.Header("X-Run-Note", rt.Note)
+	}
+	if rt.EstimatedDuration > 0 {
+		c.Header("X-Estimated-Duration", strconv.FormatInt(int64(rt.EstimatedDuration), 10))
+		c.Header("X-Duration", strconv.FormatInt(int64(rt.Duration), 10))
+	}
 
 	res, err := model.ConvertResponse(rt, true)
 	if err != nil {
 		c.Error(err)
-		c.AbortWithStatusJSON(http.StatusInternalServerError, err.Error())
+		AbortError(c, http.StatusInternalServerError, model.CodeInternal, err.Error())
 		return
 	}
 	defer res.Close()
+	res.SetLimits(r)
+	if req.RawBinary {
+		res.RawBinary()
+	}
+	// the body is the array of results unless the response object is
+	// requested, the usage of the request goes into the header as the note
+	// does for the array
+	if u, err := json.Marshal(res.Usage); err == nil {
+		c.Header("X-Usage", string(u))
+	}
 
-	if err := json.NewEncoder(c.Writer).Encode(res.Results); err != nil {
+	var body any = res.Results
+	if req.ResponseObject {
+		body = res
+	}
+	if err := json.NewEncoder(c.Writer).Encode(body); err != nil {
 		c.Error(err)
 	}
 }
@@ -0,0 +1,151 @@
+package restexecutor
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+// AbortError aborts with the error envelope of the code, which every non-200
+// response of the REST API is in
+func AbortError(c *gin.Context, status int, code model.ErrorCode, msg string, fields ...model.FieldError) {
+	c.AbortWithStatusJSON(status, model.NewErrorResponse(code, msg, fields...))
+}
+
+// HandleNoRoute responds 404 in the error envelope for the unknown routes
+func HandleNoRoute(c *gin.Context) {
+	AbortError(c, http.StatusNotFound, model.CodeNotFound,
+		fmt.Sprintf("no route for %s %s", c.Request.Method, c.Request.URL.Path))
+}
+
+// HandleNoMethod responds 405 in the error envelope for the routes not
+// serving the method, it requires HandleMethodNotAllowed of the engine
+func HandleNoMethod(c *gin.Context) {
+	AbortError(c, http.StatusMethodNotAllowed, model.CodeMethodNotAllowed,
+		fmt.Sprintf("method %s is not allowed for %s", c.Request.Method, c.Request.URL.Path))
+}
+
+// abortBodyError aborts with 413 if err is caused by the body over the
+// limit, otherwise with status and code
+func abortBodyError(c *gin.Context, err error, status int, code model.ErrorCode) {
+	c.Error(err)
+	var maxErr *http.MaxBytesError
+	if errors.As(err, &maxErr) {
+		abortBodyTooLarge(c, maxErr.Limit)
+		return
+	}
+	AbortError(c, status, code, err.Error(), errorFields(err)...)
+}
+
+func abortBodyTooLarge(c *gin.Context, limit int64) {
+	AbortError(c, http.StatusRequestEntityTooLarge, model.CodeRequestTooLarge,
+		fmt.Sprintf("request body too large: exceeds the limit of %d bytes", limit))
+}
+
+// AbortRunError aborts with the status and code of the request failed by err
+// and the details of the environment or quota error if any
+func AbortRunError(c *gin.Context, err error) {
+	c.Error(err)
+	status, code := runError(err)
+	c.AbortWithStatusJSON(status, runErrorResponse(code, err))
+}
+
+// runErrorResponse creates the error response of the request failed by err
+func runErrorResponse(code model.ErrorCode, err error) model.ErrorResponse {
+	r := model.NewErrorResponse(code, err.Error(), errorFields(err)...)
+	var envErr *worker.EnvironmentError
+	if errors.As(err, &envErr) {
+		e := model.ConvertEnvironmentError(envErr)
+		r.Error.EnvironmentError = &e
+	}
+	var quotaErr *worker.QuotaError
+	if errors.As(err, &quotaErr) {
+		e := model.ConvertQuotaError(quotaErr)
+		r.Error.QuotaError = &e
+	}
+	return r
+}
+
+// runError returns the http status code and the error code of the request
+// failed by err
+func runError(err error) (int, model.ErrorCode) {
+	var envErr *worker.EnvironmentError
+	if errors.As(err, &envErr) {
+		return http.StatusServiceUnavailable, model.CodeEnvironmentUnavailable
+	}
+	var quotaErr *worker.QuotaError
+	if errors.As(err, &quotaErr) {
+		return http.StatusTooManyRequests, model.CodeQuotaExceeded
+	}
+	switch {
+	case errors.Is(err, worker.ErrFileNotFound):
+		return http.StatusBadRequest, model.CodeFileNotFound
+	case errors.Is(err, worker.ErrInvalidCredential), errors.Is(err, worker.ErrInvalidDataset),
+		errors.Is(err, worker.ErrInvalidProfile), errors.Is(err, worker.ErrIOLimitNotSupported),
+		errors.Is(err, worker.ErrCollectCoreDisabled), errors.Is(err, worker.ErrInvalidMountFiles),
+		errors.Is(err, worker.ErrInvalidTrace), errors.Is(err, worker.ErrInvalidProcInfo),
+		errors.Is(err, worker.ErrInvalidArch), errors.Is(err, worker.ErrInvalidNUMANode),
+		errors.Is(err, worker.ErrInvalidShmSize), errors.Is(err, worker.ErrInvalidDiskWorkDir),
+		errors.Is(err, worker.ErrLimitExceeded), errors.Is(err, worker.ErrInvalidRepeat),
+		errors.Is(err, worker.ErrInvalidSnapshot), errors.Is(err, worker.ErrArgsTooLarge),
+		errors.Is(err, worker.ErrInvalidVerdict), errors.Is(err, worker.ErrInvalidHostExec),
+		errors.Is(err, worker.ErrInvalidRandom), errors.Is(err, worker.ErrInvalidScratch),
+		errors.Is(err, worker.ErrInvalidExtraFiles), errors.Is(err, worker.ErrInvalidRandomWorkDir),
+		errors.Is(err, worker.ErrInvalidCopyOutGroup), errors.Is(err, worker.ErrInvalidRelativeLimit),
+		errors.Is(err, worker.ErrUnlimitedDisabled):
+		return http.StatusBadRequest, model.CodeValidationFailed
+	case errors.Is(err, worker.ErrPolicyDenied):
+		return http.StatusForbidden, model.CodePolicyDenied
+	case errors.Is(err, worker.ErrInlineContentTooLarge):
+		return http.StatusRequestEntityTooLarge, model.CodeRequestTooLarge
+	case errors.Is(err, worker.ErrQueueFull):
+		return http.StatusTooManyRequests, model.CodeQueueFull
+	case errors.Is(err, worker.ErrCancelled):
+		return statusClientClosedRequest, model.CodeCancelled
+	case errors.Is(err, worker.ErrShutdown):
+		return http.StatusServiceUnavailable, model.CodeShuttingDown
+	case errors.Is(err, filestore.ErrUnavailable):
+		return http.StatusServiceUnavailable, model.CodeStoreUnavailable
+	}
+	return http.StatusInternalServerError, model.CodeInternal
+}
+
+// cmdIndexPattern matches the index of the cmd prefixed to the worker errors
+var cmdIndexPattern = regexp.MustCompile(`^cmd\[\d+\]`)
+
+// errorFields returns the field of the request at fault in err if known
+func errorFields(err error) []model.FieldError {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []model.FieldError{{
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("cannot decode %s into %s", typeErr.Value, typeErr.Type),
+		}}
+	}
+	var malformedErr *model.LimitError
+	if errors.As(err, &malformedErr) && malformedErr.Field != "" {
+		return []model.FieldError{{
+			Field:   malformedErr.Field,
+			Message: fmt.Sprintf("invalid limit %s: %v", malformedErr.Value, malformedErr.Err),
+		}}
+	}
+	var limitErr *worker.LimitError
+	if errors.As(err, &limitErr) {
+		field := limitErr.Limit
+		if cmd := cmdIndexPattern.FindString(err.Error()); cmd != "" {
+			field = cmd + "." + field
+		}
+		return []model.FieldError{{
+			Field:   field,
+			Message: fmt.Sprintf("%s exceeds the max %s", limitErr.Value, limitErr.Max),
+		}}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+package restexecutor
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/criyle/go-judge/env/fake"
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+// unavailableStore is the file store of the broken backing storage
+type unavailableStore struct {
+	filestore.FileStore
+}
+
+func (unavailableStore) Available() error {
+	return fmt.Errorf("%w: disk is gone", filestore.ErrUnavailable)
+}
+
+// newTestWorker starts the worker running the programs in the fake sandbox
+func newTestWorker(t *testing.T, fs filestore.FileStore, conf func(*worker.Config)) worker.Worker {
+	t.Helper()
+	c := worker.Config{
+		FileStore:       fs,
+		EnvironmentPool: pool.NewPool(fake.NewBuilder(fake.Config{Dir: t.TempDir()})),
+		Parallelism:     1,
+		WorkDir:         "/w",
+	}
+	if conf != nil {
+		conf(&c)
+	}
+	w := worker.New(c)
+	w.Start()
+	t.Cleanup(w.Shutdown)
+	return w
+}
+
+// newTestHandle serves the REST API by the worker running in the fake sandbox
+func newTestHandle(t *testing.T, fs filestore.FileStore, maxRequestSize int64, cp Checkpoint) http.Handler {
+	t.Helper()
+	return newTestRouter(newTestWorker(t, fs, nil), fs, maxRequestSize, cp)
+}
+
+// newTestRouter serves the REST API by the worker
+func newTestRouter(w worker.Worker, fs filestore.FileStore, maxRequestSize int64, cp Checkpoint) http.Handler {
+	r := gin.New()
+	New(w, fs, nil, maxRequestSize, cp, zap.NewNop()).Register(r)
+	r.HandleMethodNotAllowed = true
+	r.NoRoute(HandleNoRoute)
+	r.NoMethod(HandleNoMethod)
+	return r
+}
+
+// checkEnvelope checks the response is in the error envelope of the status
+// and code
+func checkEnvelope(t *testing.T, w *httptest.ResponseRecorder, status int, code model.ErrorCode) model.ErrorBody {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status %d, want %d: %s", w.Code, status, w.Body)
+	}
+	err := model.ParseError(w.Code, w.Body.Bytes())
+	if !errors.Is(err, code) {
+		t.Fatalf("error %v, want code %s", err, code)
+	}
+	var r model.ErrorResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &r); err != nil || r.Error.Message == "" {
+		t.Fatalf("body %s is not in the error envelope", w.Body)
+	}
+	return r.Error
+}
+
+func TestRunErrorEnvelope(t *testing.T) {
+	envErr := &worker.EnvironmentError{Reason: worker.EnvironmentNamespaceLimit, Errno: syscall.ENOSPC, Err: errors.New("clone")}
+	quotaErr := &worker.QuotaError{Resource: "runs", Limit: 10, Used: 10, ResetAt: time.Unix(1700000000, 0)}
+	for _, c := range []struct {
+		err    error
+		status int
+		code   model.ErrorCode
+	}{
+		{fmt.Errorf("cmd[0]: %w", &worker.LimitError{Limit: "cpuLimit", Value: "10s", Max: "1s"}), http.StatusBadRequest, model.CodeValidationFailed},
+		{fmt.Errorf("%w: a", worker.ErrInvalidDataset), http.StatusBadRequest, model.CodeValidationFailed},
+		{&worker.PolicyError{Capability: worker.FeatureRepeat}, http.StatusForbidden, model.CodePolicyDenied},
+		{fmt.Errorf("%w: a", worker.ErrFileNotFound), http.StatusBadRequest, model.CodeFileNotFound},
+		{worker.ErrInlineContentTooLarge, http.StatusRequestEntityTooLarge, model.CodeRequestTooLarge},
+		{worker.ErrQueueFull, http.StatusTooManyRequests, model.CodeQueueFull},
+		{quotaErr, http.StatusTooManyRequests, model.CodeQuotaExceeded},
+		{fmt.Errorf("%w: a", filestore.ErrUnavailable), http.StatusServiceUnavailable, model.CodeStoreUnavailable},
+		{fmt.Errorf("cmd[0]: %w", envErr), http.StatusServiceUnavailable, model.CodeEnvironmentUnavailable},
+		{worker.ErrShutdown, http.StatusServiceUnavailable, model.CodeShuttingDown},
+		{worker.ErrCancelled, statusClientClosedRequest, model.CodeCancelled},
+		{errors.New("other"), http.StatusInternalServerError, model.CodeInternal},
+	} {
+		t.Run(string(c.code), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			AbortRunError(ctx, c.err)
+
+			e := checkEnvelope(t, w, c.status, c.code)
+			if e.Message != c.err.Error() {
+				t.Errorf("message %q, want %q", e.Message, c.err.Error())
+			}
+			var le *worker.LimitError
+			if errors.As(c.err, &le) && (len(e.Fields) != 1 || e.Fields[0].Field != "cmd[0].cpuLimit") {
+				t.Errorf("fields %+v, want cmd[0].cpuLimit", e.Fields)
+			}
+			if want := c.err == quotaErr; (e.QuotaError != nil) != want {
+				t.Errorf("quotaError %+v, want set %v", e.QuotaError, want)
+			} else if want && (e.QuotaError.Resource != "runs" || e.QuotaError.ResetAt == "") {
+				t.Errorf("quotaError %+v", e.QuotaError)
+			}
+			if want := errors.Is(c.err, envErr); (e.EnvironmentError != nil) != want {
+				t.Errorf("environmentError %+v, want set %v", e.EnvironmentError, want)
+			} else if want && (e.EnvironmentError.Reason != string(worker.EnvironmentNamespaceLimit) || e.EnvironmentError.Errno != uint64(syscall.ENOSPC)) {
+				t.Errorf("environmentError %+v", e.EnvironmentError)
+			}
+		})
+	}
+}
+
+func TestHandlerErrorEnvelope(t *testing.T) {
+	const maxRequestSize = 1 << 10
+	h := newTestHandle(t, filestore.NewFileLocalStore(t.TempDir()), maxRequestSize, nil)
+
+	upload := func(sha string) *http.Request {
+		var b bytes.Buffer
+		mw := multipart.NewWriter(&b)
+		fw, _ := mw.CreateFormFile("file", "a.txt")
+		fw.Write([]byte("content"))
+		mw.Close()
+		r := httptest.NewRequest(http.MethodPost, "/file", &b)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+		r.Header.Set(headerContentSHA256, sha)
+		return r
+	}
+	run := func(body string) *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/run", strings.NewReader(body))
+	}
+	for _, c := range []struct {
+		name   string
+		req    *http.Request
+		status int
+		code   model.ErrorCode
+		field  string
+	}{
+		{"no cmd", run(`{"cmd": []}`), http.StatusBadRequest, model.CodeValidationFailed, "cmd"},
+		{"malformed limit", run(`{"cmd": [{"args": ["a"], "cpuLimit": 0}]}`), http.StatusBadRequest, model.CodeValidationFailed, "cpuLimit"},
+		{"body too large", run(`{"cmd": [{"args": ["` + strings.Repeat("a", maxRequestSize) + `"]}]}`), http.StatusRequestEntityTooLarge, model.CodeRequestTooLarge, ""},
+		{"validate file not found", httptest.NewRequest(http.MethodPost, "/run?validate=1",
+			strings.NewReader(`{"cmd": [{"args": ["a"], "files": [{"fileId": "nope"}]}]}`)), http.StatusBadRequest, model.CodeFileNotFound, ""},
+		{"run file not found", run(`{"cmd": [{"args": ["a"], "copyIn": {"a": {"fileId": "nope"}}}]}`), http.StatusBadRequest, model.CodeFileNotFound, ""},
+		{"checksum mismatch", upload(strings.Repeat("0", 64)), http.StatusUnprocessableEntity, model.CodeChecksumMismatch, "file"},
+		{"file not found", httptest.NewRequest(http.MethodGet, "/file/nope", nil), http.StatusNotFound, model.CodeFileNotFound, ""},
+		{"no route", httptest.NewRequest(http.MethodGet, "/nope", nil), http.StatusNotFound, model.CodeNotFound, ""},
+		{"no method", httptest.NewRequest(http.MethodPut, "/run", nil), http.StatusMethodNotAllowed, model.CodeMethodNotAllowed, ""},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, c.req)
+			e := checkEnvelope(t, w, c.status, c.code)
+			if c.field != "" && (len(e.Fields) == 0 || e.Fields[0].Field != c.field) {
+				t.Errorf("fields %+v, want %s", e.Fields, c.field)
+			}
+		})
+	}
+}
+
+func TestStoreUnavailableEnvelope(t *testing.T) {
+	h := newTestHandle(t, unavailableStore{filestore.NewFileLocalStore(t.TempDir())}, 0, nil)
+	for _, path := range []string{"/run", "/runs", "/calibrate"} {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, path, strings.NewReader(`{}`)))
+		checkEnvelope(t, w, http.StatusServiceUnavailable, model.CodeStoreUnavailable)
+	}
+}
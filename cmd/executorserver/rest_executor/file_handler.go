@@ -1,55 +1,237 @@
 package restexecutor
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime"
 	"net/http"
+	"os"
 	"path"
+	"strings"
 
+	"github.com/criyle/go-judge/cmd/executorserver/model"
 	"github.com/criyle/go-judge/envexec"
 	"github.com/criyle/go-judge/filestore"
 	"github.com/gin-gonic/gin"
 )
 
+// headerContentSHA256 carries the hex encoded sha256 digest of the uploaded file
+const headerContentSHA256 = "X-Content-SHA256"
+
 type fileHandle struct {
 	fs filestore.FileStore
 }
 
 func (f *fileHandle) fileGet(c *gin.Context) {
-	ids := f.fs.List()
+	fs := filestore.FromContext(c.Request.Context(), f.fs)
+	if _, ok := c.GetQuery("meta"); ok {
+		c.JSON(http.StatusOK, fs.ListMeta())
+		return
+	}
+	ids := fs.List()
 	c.JSON(http.StatusOK, ids)
 }
 
+// uploadPart is a file part of POST /file stored but not yet added
+type uploadPart struct {
+	name     string // form field name
+	filename string
+	file     *os.File
+	digest   string
+	expected string // from X-Content-SHA256 part header or sha256.<name> field
+}
+
+// maxFormValue limits the size of non-file form fields
+const maxFormValue = 1 << 10
+
+// filePost stores the file parts of the multipart form. A single part named
+// file returns its fileId, otherwise a map of part name -> fileId in the
+// multipart order is returned. Parts are added to the file store only after
+// all of them are received and verified.
 func (f *fileHandle) filePost(c *gin.Context) {
-	fh, err := c.FormFile("file")
+	mr, err := c.Request.MultipartReader()
 	if err != nil {
-		c.AbortWithError(http.StatusBadRequest, err)
+		c.Error(err)
+		AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, err.Error())
 		return
 	}
+	fs := filestore.FromContext(c.Request.Context(), f.fs)
 
-	fi, err := fh.Open()
-	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
-		return
+	var (
+		parts  []*uploadPart
+		fields = make(map[string]string)
+		names  = make(map[string]bool)
+	)
+	// remove the partial objects, close first for platforms that cannot remove opened file
+	removeFiles := func() {
+		for _, p := range parts {
+			p.file.Close()
+			os.Remove(p.file.Name())
+		}
 	}
-	sf, err := f.fs.New()
-	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
-		return
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			removeFiles()
+			abortBodyError(c, err, http.StatusBadRequest, model.CodeValidationFailed)
+			return
+		}
+		name := part.FormName()
+		if part.FileName() == "" {
+			b, err := io.ReadAll(io.LimitReader(part, maxFormValue))
+			part.Close()
+			if err != nil {
+				removeFiles()
+				abortBodyError(c, err, http.StatusBadRequest, model.CodeValidationFailed)
+				return
+			}
+			fields[name] = string(b)
+			continue
+		}
+		if names[name] {
+			part.Close()
+			removeFiles()
+			AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, "duplicated file part: "+name,
+				model.FieldError{Field: name, Message: "duplicated file part"})
+			return
+		}
+		names[name] = true
+
+		sf, err := fs.New()
+		if err != nil {
+			part.Close()
+			removeFiles()
+			c.Error(err)
+			AbortError(c, http.StatusInternalServerError, model.CodeInternal, err.Error())
+			return
+		}
+		p := &uploadPart{
+			name:     name,
+			filename: part.FileName(),
+			file:     sf,
+			expected: part.Header.Get(headerContentSHA256),
+		}
+		parts = append(parts, p)
+
+		h := sha256.New()
+		_, err = sf.ReadFrom(io.TeeReader(part, h))
+		part.Close()
+		if err != nil {
+			removeFiles()
+			abortBodyError(c, err, http.StatusInternalServerError, model.CodeInternal)
+			return
+		}
+		p.digest = hex.EncodeToString(h.Sum(nil))
 	}
-	defer sf.Close()
+	defer func() {
+		for _, p := range parts {
+			p.file.Close()
+		}
+	}()
 
-	if _, err := sf.ReadFrom(fi); err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+	if len(parts) == 0 {
+		c.Error(http.ErrMissingFile)
+		AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, http.ErrMissingFile.Error())
 		return
 	}
-	id, err := f.fs.Add(fh.Filename, sf.Name())
-	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+	single := len(parts) == 1 && parts[0].name == "file"
+
+	// verify all parts before any of them is added
+	for _, p := range parts {
+		expected := p.expected
+		if expected == "" && single {
+			expected = c.GetHeader(headerContentSHA256)
+			if expected == "" {
+				expected = fields["sha256"]
+			}
+		}
+		if expected == "" {
+			expected = fields["sha256."+p.name]
+		}
+		if expected == "" {
+			continue
+		}
+		if b, err := hex.DecodeString(expected); err != nil || len(b) != sha256.Size {
+			removeFiles()
+			AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, "invalid sha256 digest: "+expected,
+				model.FieldError{Field: p.name, Message: "invalid sha256 digest"})
+			return
+		}
+		if !strings.EqualFold(expected, p.digest) {
+			removeFiles()
+			AbortError(c, http.StatusUnprocessableEntity, model.CodeChecksumMismatch,
+				fmt.Sprintf("%s: sha256 mismatch: expected %s, got %s", p.name, strings.ToLower(expected), p.digest),
+				model.FieldError{Field: p.name, Message: "sha256 mismatch"})
+			return
+		}
+	}
+
+	ids := make([]string, 0, len(parts))
+	for i, p := range parts {
+		id, err := fs.AddMeta(filestore.FileMeta{Name: p.filename, SHA256: p.digest}, p.file.Name())
+		if err != nil {
+			for _, id := range ids {
+				fs.Remove(id)
+			}
+			for _, p := range parts[i:] {
+				p.file.Close()
+				os.Remove(p.file.Name())
+			}
+			c.Error(err)
+			AbortError(c, http.StatusInternalServerError, model.CodeInternal, err.Error())
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	if single {
+		c.Header(headerContentSHA256, parts[0].digest)
+		c.JSON(http.StatusOK, ids[0])
 		return
 	}
-	c.JSON(http.StatusOK, id)
+	rt := make(orderedFileIDs, 0, len(parts))
+	for i, p := range parts {
+		rt = append(rt, orderedFileID{name: p.name, id: ids[i]})
+	}
+	c.JSON(http.StatusOK, rt)
+}
+
+type orderedFileID struct {
+	name, id string
+}
+
+// orderedFileIDs encodes into JSON object keeping the multipart order
+type orderedFileIDs []orderedFileID
+
+func (o orderedFileIDs) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(f.id)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
 }
 
 func (f *fileHandle) fileIDGet(c *gin.Context) {
@@ -58,25 +240,36 @@ func (f *fileHandle) fileIDGet(c *gin.Context) {
 	}
 	var uri fileURI
 	if err := c.ShouldBindUri(&uri); err != nil {
-		c.AbortWithError(http.StatusBadRequest, err)
+		c.Error(err)
+		AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, err.Error())
 		return
 	}
 
-	name, file := f.fs.Get(uri.FileID)
+	fs := filestore.FromContext(c.Request.Context(), f.fs)
+	name, file := fs.Get(uri.FileID)
 	if file == nil {
-		c.AbortWithStatus(http.StatusNotFound)
+		abortFileNotFound(c, uri.FileID)
 		return
 	}
 	r, err := envexec.FileToReader(file)
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+		c.Error(err)
+		AbortError(c, http.StatusInternalServerError, model.CodeInternal, err.Error())
 		return
 	}
 	defer r.Close()
 
 	content, err := io.ReadAll(r)
+	if errors.Is(err, envexec.ErrCacheCorrupt) {
+		// evict so that it could be uploaded again
+		fs.Remove(uri.FileID)
+		c.Error(err)
+		AbortError(c, http.StatusInternalServerError, model.CodeInternal, err.Error())
+		return
+	}
 	if err != nil {
-		c.AbortWithError(http.StatusInternalServerError, err)
+		c.Error(err)
+		AbortError(c, http.StatusInternalServerError, model.CodeInternal, err.Error())
 		return
 	}
 
@@ -91,14 +284,20 @@ func (f *fileHandle) fileIDDelete(c *gin.Context) {
 	}
 	var uri fileURI
 	if err := c.ShouldBindUri(&uri); err != nil {
-		c.AbortWithError(http.StatusBadRequest, err)
+		c.Error(err)
+		AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, err.Error())
 		return
 	}
 
-	ok := f.fs.Remove(uri.FileID)
+	fs := filestore.FromContext(c.Request.Context(), f.fs)
+	ok := fs.Remove(uri.FileID)
 	if !ok {
-		c.AbortWithStatus(http.StatusNotFound)
+		abortFileNotFound(c, uri.FileID)
 		return
 	}
 	c.Status(http.StatusOK)
 }
+
+func abortFileNotFound(c *gin.Context, id string) {
+	AbortError(c, http.StatusNotFound, model.CodeFileNotFound, "file not found with id "+id)
+}
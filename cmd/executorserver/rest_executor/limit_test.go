@@ -0,0 +1,106 @@
+package restexecutor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+)
+
+func TestLimitSemantics(t *testing.T) {
+	fs := filestore.NewFileLocalStore(t.TempDir())
+	limits := func(allowUnlimited bool) func(*worker.Config) {
+		return func(c *worker.Config) {
+			c.LimitMax = worker.LimitMax{CPU: 2 * time.Second, Clock: 4 * time.Second, Memory: 512 << 20, Proc: 64}
+			c.LimitDefault = worker.LimitDefault{CPU: time.Second, Memory: 256 << 20, Proc: 50}
+			c.AllowUnlimited = allowUnlimited
+		}
+	}
+	h := newTestRouter(newTestWorker(t, fs, limits(false)), fs, 0, nil)
+	// the max limits still apply to "unlimited", so the handle allowing it has none
+	hu := newTestRouter(newTestWorker(t, fs, func(c *worker.Config) {
+		limits(true)(c)
+		c.LimitMax = worker.LimitMax{}
+	}), fs, 0, nil)
+
+	for _, c := range []struct {
+		name  string
+		h     http.Handler
+		field string
+		value string // omitted if empty
+		want  any    // echoed value, or the field of the 400 if string prefixed by !
+	}{
+		{"cpu omitted", h, "cpuLimit", "", "1s"},
+		{"cpu zero", h, "cpuLimit", "0", "!cpuLimit"},
+		{"cpu zero string", h, "cpuLimit", `"0s"`, "!cpuLimit"},
+		{"cpu negative", h, "cpuLimit", "-1", "!cpuLimit"},
+		{"cpu unlimited disabled", h, "cpuLimit", `"unlimited"`, "!"},
+		{"cpu unlimited", hu, "cpuLimit", `"unlimited"`, "unlimited"},
+		{"cpu over max", h, "cpuLimit", `"3s"`, "!cmd[0].cpuLimit"},
+
+		{"clock omitted", h, "clockLimit", "", nil},
+		{"clock zero", h, "clockLimit", "0", "!clockLimit"},
+		{"clock negative", h, "clockLimit", "-1", "!clockLimit"},
+		{"clock unlimited disabled", h, "clockLimit", `"unlimited"`, "!"},
+		{"clock unlimited", hu, "clockLimit", `"unlimited"`, "unlimited"},
+		{"clock over max", h, "clockLimit", `"5s"`, "!cmd[0].clockLimit"},
+
+		{"memory omitted", h, "memoryLimit", "", "256m"},
+		{"memory zero", h, "memoryLimit", "0", "!memoryLimit"},
+		{"memory negative", h, "memoryLimit", "-1", "!memoryLimit"},
+		{"memory unlimited disabled", h, "memoryLimit", `"unlimited"`, "!"},
+		{"memory unlimited", hu, "memoryLimit", `"unlimited"`, "unlimited"},
+		{"memory over max", h, "memoryLimit", `"1g"`, "!cmd[0].memoryLimit"},
+
+		{"stack omitted", h, "stackLimit", "", "256m"},
+		{"stack zero", h, "stackLimit", "0", "!stackLimit"},
+		{"stack negative", h, "stackLimit", "-1", "!stackLimit"},
+		{"stack unlimited disabled", h, "stackLimit", `"unlimited"`, "!"},
+		// the stack limit is never above the memory limit
+		{"stack unlimited", hu, "stackLimit", `"unlimited"`, "256m"},
+		{"stack over memory", h, "stackLimit", `"1g"`, "256m"},
+
+		{"proc omitted", h, "procLimit", "", 50.0},
+		{"proc zero", h, "procLimit", "0", "!procLimit"},
+		{"proc negative", h, "procLimit", "-1", "!procLimit"},
+		{"proc unlimited disabled", h, "procLimit", `"unlimited"`, "!"},
+		{"proc unlimited", hu, "procLimit", `"unlimited"`, "unlimited"},
+		{"proc over max", h, "procLimit", "65", "!cmd[0].procLimit"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			cmd := `{"args": ["/bin/true"]`
+			if c.value != "" {
+				cmd += `, "` + c.field + `": ` + c.value
+			}
+			body := `{"cmd": [` + cmd + `}]}`
+			w := httptest.NewRecorder()
+			c.h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/run?validate=1", strings.NewReader(body)))
+
+			if field, ok := c.want.(string); ok && strings.HasPrefix(field, "!") {
+				e := checkEnvelope(t, w, http.StatusBadRequest, model.CodeValidationFailed)
+				if field = field[1:]; field != "" && (len(e.Fields) != 1 || e.Fields[0].Field != field) {
+					t.Errorf("fields %+v, want %s", e.Fields, field)
+				}
+				return
+			}
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d, want 200: %s", w.Code, w.Body)
+			}
+			var req struct {
+				Cmd []map[string]any `json:"cmd"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &req); err != nil {
+				t.Fatal(err)
+			}
+			if got := req.Cmd[0][c.field]; got != c.want {
+				t.Errorf("%s echoed %v, want %v", c.field, got, c.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,251 @@
+package restexecutor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/criyle/go-judge/env/fake"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+)
+
+func TestRunFakeSandbox(t *testing.T) {
+	h := newTestHandle(t, filestore.NewFileLocalStore(t.TempDir()), 0, nil)
+	post := func(body string) []model.Result {
+		t.Helper()
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/run", strings.NewReader(body)))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d, want 200: %s", w.Code, w.Body)
+		}
+		var rt []model.Result
+		if err := json.Unmarshal(w.Body.Bytes(), &rt); err != nil {
+			t.Fatal(err)
+		}
+		if len(rt) != 1 {
+			t.Fatalf("got %d results, want 1", len(rt))
+		}
+		return rt
+	}
+
+	rt := post(`{"cmd": [{
+		"args": ["/bin/sh", "-c", "cat in > out && tr a-z A-Z < in"],
+		"env": ["PATH=/usr/bin:/bin"],
+		"cpuLimit": "1s",
+		"memoryLimit": "64m",
+		"procLimit": 16,
+		"files": [{"content": ""}, {"name": "stdout", "max": 1024}, {"name": "stderr", "max": 1024}],
+		"copyIn": {"in": {"content": "abc"}},
+		"copyOut": ["stdout", "stderr"],
+		"copyOutCached": ["out"]
+	}]}`)
+	r := rt[0]
+	if r.Status != model.StatusAccepted {
+		t.Fatalf("status %v: %s", r.Status, r.Error)
+	}
+	// the canned usage is reported for every run
+	if r.Time != uint64(fake.DefaultTime) || r.RunTime != uint64(fake.DefaultTime) || r.Memory != uint64(fake.DefaultMemory) {
+		t.Errorf("time %d runTime %d memory %d, want the canned usage", r.Time, r.RunTime, r.Memory)
+	}
+	if r.Files["stdout"] != "ABC" || r.Files["stderr"] != "" {
+		t.Errorf("files %v", r.Files)
+	}
+	id, ok := r.FileIDs["out"]
+	if !ok {
+		t.Fatalf("out is not cached: %v", r.FileIDs)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/file/"+id, nil))
+	if w.Code != http.StatusOK || w.Body.String() != "abc" {
+		t.Fatalf("GET /file/%s: %d %q, want abc", id, w.Code, w.Body)
+	}
+
+	// the limits are checked against the canned usage
+	rt = post(`{"cmd": [{
+		"args": ["/bin/true"],
+		"files": [{"content": ""}, {"name": "stdout", "max": 1024}, {"name": "stderr", "max": 1024}],
+		"cpuLimit": "1s",
+		"memoryLimit": "512k"
+	}]}`)
+	if rt[0].Status != model.StatusMemoryLimitExceeded {
+		t.Errorf("status %v, want %v", rt[0].Status, model.StatusMemoryLimitExceeded)
+	}
+}
+
+func TestRunValidateQuery(t *testing.T) {
+	h := newTestHandle(t, filestore.NewFileLocalStore(t.TempDir()), 0, nil)
+	body := `{"cmd": [{"args": ["/bin/true"], "cpuLimit": "1s", "memoryLimit": "64m", "procLimit": 16}]}`
+	for _, c := range []struct {
+		query  string
+		dryRun bool
+	}{
+		{"", false},
+		{"?validate=", false},
+		{"?validate=0", false},
+		{"?validate=false", false},
+		{"?validate=1", true},
+		{"?validate=true", true},
+	} {
+		t.Run(c.query, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/run"+c.query, strings.NewReader(body)))
+			if w.Code != http.StatusOK {
+				t.Fatalf("status %d, want 200: %s", w.Code, w.Body)
+			}
+			// the dry run echoes the request instead of the results
+			if dryRun := strings.HasPrefix(w.Body.String(), "{"); dryRun != c.dryRun {
+				t.Fatalf("dry run %v, want %v: %s", dryRun, c.dryRun, w.Body)
+			}
+		})
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/run?validate=maybe", strings.NewReader(body)))
+	if e := checkEnvelope(t, w, http.StatusBadRequest, model.CodeValidationFailed); len(e.Fields) != 1 || e.Fields[0].Field != "validate" {
+		t.Errorf("fields %+v, want validate", e.Fields)
+	}
+}
+
+func TestRunResponseObject(t *testing.T) {
+	h := newTestHandle(t, filestore.NewFileLocalStore(t.TempDir()), 0, nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/run", strings.NewReader(`{
+		"requestId": "a",
+		"responseObject": true,
+		"cmd": [{"args": ["/bin/true"], "cpuLimit": "1s", "memoryLimit": "64m", "procLimit": 16, "copyIn": {"in": {"content": "abc"}}}]
+	}`)))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d, want 200: %s", w.Code, w.Body)
+	}
+	var rt model.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &rt); err != nil {
+		t.Fatalf("response is not an object: %v: %s", err, w.Body)
+	}
+	if rt.RequestID != "a" || len(rt.Results) != 1 || rt.Results[0].Status != model.StatusAccepted {
+		t.Fatalf("response %+v", rt)
+	}
+	u := rt.Usage
+	if u == nil || u.CPUTime != uint64(fake.DefaultTime) || u.Memory != uint64(fake.DefaultMemory) || u.CopyIn != 3 {
+		t.Fatalf("usage %+v, want the canned usage and the copy in", u)
+	}
+	// the same as the header
+	var hu model.RequestUsage
+	if err := json.Unmarshal([]byte(w.Header().Get("X-Usage")), &hu); err != nil || hu != *u {
+		t.Fatalf("header usage %+v %v, want %+v", hu, err, *u)
+	}
+}
+
+// TestRunsCancelReason covers the reasons of the items of a batch terminated
+// by the server, the first runs while the second waits for the only worker
+// loop
+func TestRunsCancelReason(t *testing.T) {
+	fs := filestore.NewFileLocalStore(t.TempDir())
+	w := newTestWorker(t, fs, nil)
+	h := newTestRouter(w, fs, 0, nil)
+	item := func(id string) string {
+		return `{"requestId": "` + id + `", "cmd": [{
+			"args": ["/bin/sh", "-c", "exec sleep 10"],
+			"files": [{"content": ""}, {"name": "stdout", "max": 1024}, {"name": "stderr", "max": 1024}],
+			"cpuLimit": "1s",
+			"clockLimit": "5s",
+			"memoryLimit": "64m",
+			"procLimit": 16
+		}]}`
+	}
+	resp := httptest.NewRecorder()
+	served := make(chan struct{})
+	go func() {
+		defer close(served)
+		h.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/runs", strings.NewReader("["+item("running")+","+item("waiting")+"]")))
+	}()
+
+	// waits for the first running for a while and the second waiting
+	jobs := make(map[string]worker.Job)
+	for start := time.Now(); len(jobs) < 2; time.Sleep(5 * time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatalf("jobs %v are not started", w.Jobs())
+		}
+		jobs = make(map[string]worker.Job)
+		for _, j := range w.Jobs() {
+			if j.RequestID == "waiting" || j.Elapsed > 50*time.Millisecond {
+				jobs[j.RequestID] = j
+			}
+		}
+	}
+	w.Kill(jobs["waiting"].ID)
+	for len(w.Jobs()) > 1 {
+		time.Sleep(5 * time.Millisecond)
+	}
+	w.Abort()
+	<-served
+
+	var rt []model.BatchResult
+	if err := json.Unmarshal(resp.Body.Bytes(), &rt); err != nil {
+		t.Fatalf("%v: %s", err, resp.Body)
+	}
+	if len(rt) != 2 {
+		t.Fatalf("got %d items, want 2: %s", len(rt), resp.Body)
+	}
+	if r := rt[0]; r.Status != http.StatusOK || r.CancelReason != string(worker.CancelShutdown) ||
+		len(r.Results) != 1 || r.Results[0].CancelReason != string(worker.CancelShutdown) {
+		t.Errorf("running item %+v, want its result terminated by shutdown", r)
+	}
+	if r := rt[1]; r.Code != model.CodeCancelled || r.CancelReason != string(worker.CancelCancelled) {
+		t.Errorf("waiting item %+v, want cancelled", r)
+	}
+}
+
+// runVariantPattern matches the fields of the result varying between the runs
+var runVariantPattern = regexp.MustCompile(`"(startedAt|environmentId)":("[^"]*"|\d+)`)
+
+// TestRunVerdictIdentity covers the results are not touched without the
+// verdict rules applied, the responses are byte-identical to the server
+// without verdict rules except the start time and the environment
+func TestRunVerdictIdentity(t *testing.T) {
+	rules := []worker.VerdictRule{{Status: []envexec.Status{envexec.StatusNonzeroExitStatus}, SetStatus: envexec.StatusWrongAnswer}}
+	post := func(verdicts map[string][]worker.VerdictRule, includeRaw bool) string {
+		t.Helper()
+		fs := filestore.NewFileLocalStore(t.TempDir())
+		w := newTestWorker(t, fs, func(c *worker.Config) { c.Verdicts = verdicts })
+		body := `{"cmd": [{
+			"args": ["/bin/sh", "-c", "echo out; echo err >&2; exit 3"],
+			"cpuLimit": "1s",
+			"memoryLimit": "64m",
+			"procLimit": 16,
+			"files": [{"content": ""}, {"name": "stdout", "max": 1024}, {"name": "stderr", "max": 1024}]
+		}], "includeRaw": ` + strconv.FormatBool(includeRaw) + `}`
+		resp := httptest.NewRecorder()
+		newTestRouter(w, fs, 0, nil).ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/run", strings.NewReader(body)))
+		if resp.Code != http.StatusOK {
+			t.Fatalf("status %d: %s", resp.Code, resp.Body)
+		}
+		return runVariantPattern.ReplaceAllString(resp.Body.String(), `"$1":_`)
+	}
+
+	want := post(nil, false)
+	for _, c := range []struct {
+		name       string
+		verdicts   map[string][]worker.VerdictRule
+		includeRaw bool
+	}{
+		{"default not configured", map[string][]worker.VerdictRule{"course": rules}, false},
+		{"empty default", map[string][]worker.VerdictRule{worker.VerdictDefault: {}}, false},
+		{"empty default with raw", map[string][]worker.VerdictRule{worker.VerdictDefault: {}}, true},
+	} {
+		if got := post(c.verdicts, c.includeRaw); got != want {
+			t.Errorf("%s: got\n%s\nwant\n%s", c.name, got, want)
+		}
+	}
+	// the rules of the default apply
+	if got := post(map[string][]worker.VerdictRule{worker.VerdictDefault: rules}, false); got == want {
+		t.Errorf("default rules are not applied: %s", got)
+	}
+}
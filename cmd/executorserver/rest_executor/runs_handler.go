@@ -0,0 +1,237 @@
+package restexecutor
+
+import (
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+const contentTypeNDJSON = "application/x-ndjson"
+
+// batchResult carries the converted response until it is encoded
+type batchResult struct {
+	model.BatchResult
+	res    *model.Response
+	stored json.RawMessage // result of the checkpoint
+}
+
+// value returns the stored result or the result to encode
+func (r *batchResult) value() any {
+	if r.stored != nil {
+		return r.stored
+	}
+	return &r.BatchResult
+}
+
+func (r *batchResult) close() {
+	if r.res != nil {
+		r.res.Close()
+	}
+}
+
+// fail sets the error of the item in the same envelope POST /run responds
+// with, flattened into the item
+func (r *batchResult) fail(status int, e model.ErrorResponse) {
+	r.Status = status
+	r.Code = e.Error.Code
+	r.Error = e.Error.Message
+	r.Fields = e.Error.Fields
+	r.EnvironmentError = e.Error.EnvironmentError
+	r.QuotaError = e.Error.QuotaError
+}
+
+// handleRuns submits the requests together and responds with their results
+// in order, or streams one line per request as soon as it finished if NDJSON
+// is accepted. The requests not finished are cancelled once the client
+// disconnected unless detached. With Idempotency-Key, which requires the
+// checkpoint enabled, the results stored by the previous submissions of the
+// key are returned for the items of the same payload instead of running them
+// again, and each item runs with the idempotencyKey derived from the key.
+func (h *handle) handleRuns(c *gin.Context) {
+	if err := filestore.Available(h.fs); err != nil {
+		c.Error(err)
+		AbortError(c, http.StatusServiceUnavailable, model.CodeStoreUnavailable, err.Error())
+		return
+	}
+	var (
+		reqs []model.Request
+		cp   *batchCheckpoint
+	)
+	if key := c.GetHeader(headerIdempotencyKey); key != "" {
+		if h.checkpoint == nil {
+			AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, errCheckpointDisabled.Error(),
+				model.FieldError{Field: headerIdempotencyKey, Message: errCheckpointDisabled.Error()})
+			return
+		}
+		owner, _ := filestore.NamespaceFromContext(c.Request.Context())
+		cp = &batchCheckpoint{cp: h.checkpoint, owner: owner, key: key}
+		var err error
+		if reqs, cp.hashes, err = bindCheckpointRuns(c); err != nil {
+			abortBodyError(c, err, http.StatusBadRequest, model.CodeValidationFailed)
+			return
+		}
+		if cp.stored, err = h.checkpoint.Load(owner, key); err != nil {
+			c.Error(err)
+			AbortError(c, http.StatusInternalServerError, model.CodeInternal, "checkpoint: "+err.Error())
+			return
+		}
+	} else if err := c.ShouldBindJSON(&reqs); err != nil {
+		abortBodyError(c, err, http.StatusBadRequest, model.CodeValidationFailed)
+		return
+	}
+	if len(reqs) == 0 {
+		AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, "no request provided")
+		return
+	}
+
+	ctx := c.Request.Context()
+	done := make(chan *batchResult, len(reqs))
+	for i := range reqs {
+		req := &reqs[i]
+		rt := &batchResult{BatchResult: model.BatchResult{Index: i}}
+		if stored := cp.result(i); stored != nil {
+			rt.Status = http.StatusOK
+			rt.Checkpointed = true
+			rt.stored = stored
+			done <- rt
+			continue
+		}
+		if len(req.Cmd) == 0 {
+			rt.fail(http.StatusBadRequest, model.NewErrorResponse(model.CodeValidationFailed, "no cmd provided",
+				model.FieldError{Field: "cmd", Message: "no cmd provided"}))
+			done <- rt
+			continue
+		}
+		if cp != nil && req.IdempotencyKey != "" {
+			msg := "idempotencyKey of the item is derived from " + headerIdempotencyKey + " of the batch"
+			rt.fail(http.StatusBadRequest, model.NewErrorResponse(model.CodeValidationFailed, msg,
+				model.FieldError{Field: "idempotencyKey", Message: msg}))
+			done <- rt
+			continue
+		}
+		r, err := model.ConvertRequest(req, h.srcPrefix)
+		if err != nil {
+			rt.fail(http.StatusBadRequest, runErrorResponse(model.CodeValidationFailed, err))
+			done <- rt
+			continue
+		}
+		if cp != nil {
+			r.IdempotencyKey = cp.itemKey(i)
+		}
+		rctx := ctx
+		if req.Detach {
+			rctx = worker.DetachContext(ctx)
+		}
+		rtCh, _ := h.worker.Submit(rctx, r)
+		go func() {
+			resp := <-rtCh
+			rt.CancelReason = string(resp.CancelReason)
+			if resp.Error != nil {
+				status, code := runError(resp.Error)
+				rt.fail(status, runErrorResponse(code, resp.Error))
+				done <- rt
+				return
+			}
+			res, err := model.ConvertResponse(resp, true)
+			if err != nil {
+				rt.fail(http.StatusInternalServerError, model.NewErrorResponse(model.CodeInternal, err.Error()))
+				done <- rt
+				return
+			}
+			res.SetLimits(r)
+			if req.RawBinary {
+				res.RawBinary()
+			}
+			rt.Status = http.StatusOK
+			rt.Results = res.Results
+			rt.Note = resp.Note
+			rt.EstimatedDuration = res.EstimatedDuration
+			rt.Duration = res.Duration
+			rt.Usage = res.Usage
+			rt.res = &res
+			if err := cp.store(&rt.BatchResult); err != nil {
+				h.logger.Sugar().Errorf("Failed to store checkpoint of item %d: %v", rt.Index, err)
+			}
+			done <- rt
+		}()
+	}
+
+	if acceptNDJSON(c.GetHeader("Accept")) {
+		streamRuns(c, done, len(reqs))
+		return
+	}
+
+	results := make([]*batchResult, len(reqs))
+	for range reqs {
+		rt := <-done
+		results[rt.Index] = rt
+	}
+	defer func() {
+		for _, rt := range results {
+			rt.close()
+		}
+	}()
+	items := make([]any, 0, len(results))
+	for _, rt := range results {
+		items = append(items, rt.value())
+	}
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", "application/json; charset=utf-8")
+	if err := json.NewEncoder(c.Writer).Encode(items); err != nil {
+		c.Error(err)
+	}
+}
+
+// streamRuns writes the results as they finished followed by the summary,
+// each line is flushed. The remaining results are drained after the client
+// is gone to release their files.
+func streamRuns(c *gin.Context, done <-chan *batchResult, n int) {
+	c.Status(http.StatusOK)
+	c.Header("Content-Type", contentTypeNDJSON)
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Writer.Flush()
+
+	enc := json.NewEncoder(c.Writer)
+	sum := model.BatchSummary{Done: true, Total: n}
+	var writeErr error
+	for i := 0; i < n; i++ {
+		rt := <-done
+		if rt.Status == http.StatusOK {
+			sum.Succeeded++
+		} else {
+			sum.Failed++
+		}
+		if rt.Checkpointed {
+			sum.Checkpointed++
+		}
+		if writeErr == nil {
+			if writeErr = enc.Encode(rt.value()); writeErr == nil {
+				c.Writer.Flush()
+			}
+		}
+		rt.close()
+	}
+	if writeErr == nil {
+		writeErr = enc.Encode(sum)
+		c.Writer.Flush()
+	}
+	if writeErr != nil {
+		c.Error(writeErr)
+	}
+}
+
+// acceptNDJSON reports whether the Accept header lists NDJSON
+func acceptNDJSON(accept string) bool {
+	for _, a := range strings.Split(accept, ",") {
+		if t, _, err := mime.ParseMediaType(strings.TrimSpace(a)); err == nil && t == contentTypeNDJSON {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	restexecutor "github.com/criyle/go-judge/cmd/executorserver/rest_executor"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultSelfTestRuns        = 10
+	maxSelfTestRuns            = 100
+	defaultSelfTestEnvAcquire  = 500 * time.Millisecond
+	defaultSelfTestOverhead    = 100 * time.Millisecond
+	defaultSelfTestCopyOutTime = 100 * time.Millisecond
+)
+
+// selfTestRequest defines parameters of POST /selftest, durations are in ns
+// and thresholds are compared with p95 latencies
+type selfTestRequest struct {
+	Runs        int           `json:"runs"`
+	Concurrency int           `json:"concurrency"`
+	EnvAcquire  time.Duration `json:"envAcquire"`
+	Overhead    time.Duration `json:"overhead"`
+	CopyOut     time.Duration `json:"copyOut"`
+}
+
+type selfTestLatency struct {
+	P50 time.Duration `json:"p50"`
+	P95 time.Duration `json:"p95"`
+}
+
+type selfTestCaseResult struct {
+	Name       string          `json:"name"`
+	Pass       bool            `json:"pass"`
+	Runs       int             `json:"runs"`
+	Failed     int             `json:"failed"`
+	Error      string          `json:"error,omitempty"`
+	EnvAcquire selfTestLatency `json:"envAcquire"`
+	Overhead   selfTestLatency `json:"overhead"`
+	CopyOut    selfTestLatency `json:"copyOut"`
+}
+
+type selfTestResult struct {
+	Pass  bool                 `json:"pass"`
+	Error string               `json:"error,omitempty"`
+	Cases []selfTestCaseResult `json:"cases"`
+}
+
+type selfTestCase struct {
+	name   string
+	args   []string
+	status envexec.Status
+	limit  time.Duration
+}
+
+// selfTestCases runs through /bin/sh inside the container
+var selfTestCases = []selfTestCase{
+	{name: "empty", args: []string{"/bin/true"}, status: envexec.StatusAccepted, limit: time.Second},
+	{name: "spin100ms", args: []string{"/bin/sh", "-c", "while :; do :; done"}, status: envexec.StatusTimeLimitExceeded, limit: 100 * time.Millisecond},
+	{name: "alloc64m", args: []string{"/bin/sh", "-c", "x=$(head -c 67108864 /dev/zero | tr '\\000' a); echo ${#x}"}, status: envexec.StatusAccepted, limit: 5 * time.Second},
+	{name: "stdout1m", args: []string{"/bin/sh", "-c", "head -c 1048576 /dev/zero"}, status: envexec.StatusAccepted, limit: time.Second},
+	// double-forked sleeper is killed so that the environment could be reused
+	{name: "daemon", args: []string{"/bin/sh", "-c", "(sleep 100 &); true"}, status: envexec.StatusAccepted, limit: time.Second},
+}
+
+func generateHandleSelfTest(conf *config.Config, work worker.Worker) func(*gin.Context) {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			abortNotAdmin(c)
+			return
+		}
+		if conf.StaticContainer {
+			restexecutor.AbortError(c, http.StatusBadRequest, model.CodeFeatureDisabled,
+				"self test runs /bin/sh of the host which is not mounted by -static-container")
+			return
+		}
+		var req selfTestRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				restexecutor.AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, err.Error())
+				return
+			}
+		}
+		c.JSON(http.StatusOK, runSelfTest(c.Request.Context(), conf, work, req))
+	}
+}
+
+func runSelfTest(ctx context.Context, conf *config.Config, work worker.Worker, req selfTestRequest) selfTestResult {
+	if req.Runs <= 0 {
+		req.Runs = defaultSelfTestRuns
+	}
+	if req.Runs > maxSelfTestRuns {
+		req.Runs = maxSelfTestRuns
+	}
+	// runs submitted at a time through the worker queue with low priority,
+	// so that real requests are not starved
+	if req.Concurrency <= 0 {
+		req.Concurrency = 1
+	}
+	if req.Concurrency > conf.Parallelism {
+		req.Concurrency = conf.Parallelism
+	}
+	if req.EnvAcquire <= 0 {
+		req.EnvAcquire = defaultSelfTestEnvAcquire
+	}
+	if req.Overhead <= 0 {
+		req.Overhead = defaultSelfTestOverhead
+	}
+	if req.CopyOut <= 0 {
+		req.CopyOut = defaultSelfTestCopyOutTime
+	}
+
+	rt := selfTestResult{Pass: true}
+	for _, tc := range selfTestCases {
+		r := runSelfTestCase(ctx, work, tc, req)
+		rt.Pass = rt.Pass && r.Pass
+		rt.Cases = append(rt.Cases, r)
+	}
+	return rt
+}
+
+func runSelfTestCase(ctx context.Context, work worker.Worker, tc selfTestCase, req selfTestRequest) selfTestCaseResult {
+	rt := selfTestCaseResult{Name: tc.name, Runs: req.Runs}
+	var (
+		mu         sync.Mutex
+		wg         sync.WaitGroup
+		envAcquire []time.Duration
+		overhead   []time.Duration
+		copyOut    []time.Duration
+	)
+	sem := make(chan struct{}, req.Concurrency)
+	for i := 0; i < req.Runs; i++ {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			ch, _ := work.Submit(ctx, newSelfTestRequest(tc))
+			res := <-ch
+
+			mu.Lock()
+			defer mu.Unlock()
+			if res.Error != nil {
+				rt.Failed++
+				rt.Error = res.Error.Error()
+				return
+			}
+			r := res.Results[0]
+			for _, f := range r.Files {
+				f.Close()
+			}
+			if r.Status != tc.status {
+				rt.Failed++
+				rt.Error = fmt.Sprintf("expected status %v, got %v: %s", tc.status, r.Status, r.Error)
+				return
+			}
+			envAcquire = append(envAcquire, res.EnvWait)
+			if r.RunTime > r.Time {
+				overhead = append(overhead, r.RunTime-r.Time)
+			} else {
+				overhead = append(overhead, 0)
+			}
+			copyOut = append(copyOut, r.CopyOutTime)
+		}()
+	}
+	wg.Wait()
+
+	rt.EnvAcquire = percentiles(envAcquire)
+	rt.Overhead = percentiles(overhead)
+	rt.CopyOut = percentiles(copyOut)
+	rt.Pass = rt.Failed == 0 && rt.EnvAcquire.P95 <= req.EnvAcquire &&
+		rt.Overhead.P95 <= req.Overhead && rt.CopyOut.P95 <= req.CopyOut
+	return rt
+}
+
+func newSelfTestRequest(tc selfTestCase) *worker.Request {
+	const outputMax = 2 << 20
+	return &worker.Request{
+		Priority: worker.PriorityLow,
+		Cmd: []worker.Cmd{{
+			Args: tc.args,
+			Env:  []string{"PATH=/usr/bin:/bin"},
+			Files: []worker.CmdFile{
+				&worker.MemoryFile{Content: []byte{}},
+				&worker.Collector{Name: "stdout", Max: outputMax},
+				&worker.Collector{Name: "stderr", Max: outputMax},
+			},
+			CPULimit:    tc.limit,
+			ClockLimit:  2 * tc.limit,
+			MemoryLimit: 256 << 20,
+			ProcLimit:   50,
+			CopyOut: []worker.CmdCopyOutFile{
+				{Name: "stdout"},
+				{Name: "stderr"},
+			},
+		}},
+	}
+}
+
+func percentiles(d []time.Duration) selfTestLatency {
+	if len(d) == 0 {
+		return selfTestLatency{}
+	}
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	return selfTestLatency{
+		P50: d[(len(d)-1)*50/100],
+		P95: d[(len(d)-1)*95/100],
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// sinkWriter writes the entries taken from asyncSink, its methods are only
+// called by the loop of the sink
+type sinkWriter[T any] interface {
+	// write writes the entry, the entry failed to write is counted as dropped
+	write(e T) error
+	// flush is called once the queued entries are written
+	flush() error
+	// close is called once the queued entries are written after the sink is
+	// closed
+	close() error
+}
+
+// sinkDiscarder is implemented by the sinkWriter of the entries holding
+// resources, discard is called for the entries dropped or observed after close
+type sinkDiscarder[T any] interface {
+	discard(e T)
+}
+
+// asyncSink queues the observed entries to be written by the sinkWriter in
+// background. The entries are dropped when the buffer is full so that the
+// requests are never blocked by the writer.
+type asyncSink[T any] struct {
+	name    string // of the logs, e.g. audit log
+	w       sinkWriter[T]
+	dropped prometheus.Counter // nil if not exported
+
+	mu     sync.Mutex // guards closed against the send on ch
+	closed bool
+	ch     chan T
+	do     chan func()
+	done   chan struct{}
+	drops  uint64 // entries dropped since opened
+}
+
+// newAsyncSink starts the loop writing the entries by w with at most buffer
+// entries waiting
+func newAsyncSink[T any](name string, buffer int, w sinkWriter[T], dropped prometheus.Counter) *asyncSink[T] {
+	s := &asyncSink[T]{
+		name:    name,
+		w:       w,
+		dropped: dropped,
+		ch:      make(chan T, buffer),
+		do:      make(chan func()),
+		done:    make(chan struct{}),
+	}
+	go s.loop()
+	return s
+}
+
+// observe queues the entry, it is dropped if the buffer is full
+func (s *asyncSink[T]) observe(e T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		s.discard(e)
+		return
+	}
+	select {
+	case s.ch <- e:
+	default:
+		s.discard(e)
+		s.drop()
+	}
+}
+
+func (s *asyncSink[T]) discard(e T) {
+	if d, ok := s.w.(sinkDiscarder[T]); ok {
+		d.discard(e)
+	}
+}
+
+func (s *asyncSink[T]) drop() {
+	atomic.AddUint64(&s.drops, 1)
+	if s.dropped != nil {
+		s.dropped.Inc()
+	}
+}
+
+// dropCount returns the number of entries dropped since opened
+func (s *asyncSink[T]) dropCount() uint64 {
+	return atomic.LoadUint64(&s.drops)
+}
+
+func (s *asyncSink[T]) loop() {
+	defer close(s.done)
+	for {
+		select {
+		case e, ok := <-s.ch:
+			if !ok {
+				if err := s.w.close(); err != nil {
+					logger.Sugar().Errorf("Failed to close %s: %v", s.name, err)
+				}
+				return
+			}
+			if err := s.w.write(e); err != nil {
+				logger.Sugar().Errorf("Failed to write %s: %v", s.name, err)
+				s.drop()
+			}
+			// flush once the buffered entries are written
+			if len(s.ch) == 0 {
+				if err := s.w.flush(); err != nil {
+					logger.Sugar().Errorf("Failed to write %s: %v", s.name, err)
+				}
+			}
+		case f := <-s.do:
+			f()
+		}
+	}
+}
+
+// close writes the queued entries and closes the writer, entries observed
+// after close are discarded
+func (s *asyncSink[T]) close() {
+	s.mu.Lock()
+	if !s.closed {
+		s.closed = true
+		close(s.ch)
+	}
+	s.mu.Unlock()
+	<-s.done
+}
+
+// onSinkEvent runs f in the loop of the sink, i.e. not concurrently with the
+// writer, on each event of ch until the sink is closed
+func onSinkEvent[T, E any](s *asyncSink[T], ch <-chan E, f func()) {
+	for {
+		select {
+		case <-ch:
+		case <-s.done:
+			return
+		}
+		select {
+		case s.do <- f:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// writeJSONLine writes the value as a line of JSON
+func writeJSONLine(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/worker"
+)
+
+// memorySink records the entries written, write blocks until the gate is
+// closed
+type memorySink struct {
+	started chan int
+	gate    chan struct{}
+
+	mu        sync.Mutex
+	written   []int
+	discarded []int
+	flushes   int
+	closed    bool
+}
+
+func (m *memorySink) write(e int) error {
+	m.started <- e
+	<-m.gate
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.written = append(m.written, e)
+	return nil
+}
+
+func (m *memorySink) flush() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.flushes++
+	return nil
+}
+
+func (m *memorySink) close() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	return nil
+}
+
+func (m *memorySink) discard(e int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.discarded = append(m.discarded, e)
+}
+
+func TestAsyncSink(t *testing.T) {
+	m := &memorySink{started: make(chan int, 4), gate: make(chan struct{})}
+	s := newAsyncSink[int]("test", 1, m, nil)
+
+	s.observe(1)
+	<-m.started // taken by the writer
+	s.observe(2)
+	s.observe(3) // the buffer is full
+	if n := s.dropCount(); n != 1 {
+		t.Fatalf("dropped %d, want 1", n)
+	}
+
+	// the events are run between the writes
+	events := make(chan struct{})
+	ran := make(chan struct{})
+	go onSinkEvent(s, events, func() { close(ran) })
+	events <- struct{}{}
+	close(m.gate)
+	<-ran
+
+	s.close()
+	s.observe(4)
+	if want := []int{1, 2}; !reflect.DeepEqual(m.written, want) {
+		t.Errorf("written %v, want %v", m.written, want)
+	}
+	if want := []int{3, 4}; !reflect.DeepEqual(m.discarded, want) {
+		t.Errorf("discarded %v, want %v", m.discarded, want)
+	}
+	if m.flushes == 0 || !m.closed {
+		t.Errorf("flushed %d times, closed %v", m.flushes, m.closed)
+	}
+}
+
+func TestHistorySink(t *testing.T) {
+	h := newHistory(&config.Config{
+		HistoryDB:        t.TempDir(),
+		HistoryBuffer:    16,
+		HistoryRetention: time.Hour,
+	})
+	now := time.Now()
+	for _, id := range []string{"a", "b"} {
+		h.observe(worker.AuditRecord{
+			Client:     "client",
+			StartedAt:  now,
+			FinishedAt: now,
+			Request: &worker.Request{RequestID: id, Cmd: []worker.Cmd{{
+				Args:  []string{"true"},
+				Files: []worker.CmdFile{&worker.MemoryFile{Content: []byte("in")}},
+			}}},
+			Response: worker.Response{RequestID: id},
+		})
+	}
+	h.sink.close()
+
+	ents, err := h.query(historyFilter{Limit: historyDefaultLimit})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ents) != 2 || ents[0].RequestID != "b" || ents[1].RequestID != "a" {
+		t.Fatalf("entries %+v, want b and a", ents)
+	}
+	if f := ents[0].Cmd[0].Inline["0"]; f == nil || f.Size != 2 || f.SHA256 == "" {
+		t.Fatalf("inline %+v, want the size and hash of the content", f)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/criyle/go-judge/cmd/executorserver/executor"
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	restexecutor "github.com/criyle/go-judge/cmd/executorserver/rest_executor"
+	"github.com/gin-gonic/gin"
+)
+
+// generateHandleExport exports the state for -import-state of the new
+// instance sharing -dir
+func generateHandleExport(exec *executor.Executor) func(*gin.Context) {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			abortNotAdmin(c)
+			return
+		}
+		s, err := exec.ExportState()
+		if err != nil {
+			restexecutor.AbortError(c, http.StatusConflict, model.CodeConflict, err.Error())
+			return
+		}
+		logger.Sugar().Infof("State exported: files=%d, usage=%d, idempotency=%d", len(s.Files), len(s.Usage), len(s.Idempotency))
+		c.Header("Content-Disposition", `attachment; filename="state.json"`)
+		c.JSON(http.StatusOK, s)
+	}
+}
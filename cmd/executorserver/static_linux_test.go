@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/cmd/executorserver/executor"
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/koding/multiconfig"
+)
+
+// buildHello builds testdata/hello without cgo so that it needs nothing from
+// the host in the container
+func buildHello(t *testing.T) []byte {
+	t.Helper()
+	goBin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not available to build the static program: ", err)
+	}
+	out := filepath.Join(t.TempDir(), "hello")
+	cmd := exec.Command(goBin, "build", "-o", out, "./testdata/hello")
+	cmd.Env = append(os.Environ(), "CGO_ENABLED=0")
+	if b, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("build hello: %v\n%s", err, b)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// postJSON posts the body to the server and decodes the response into v
+func postJSON(t *testing.T, url string, contentType string, body []byte, v any) {
+	t.Helper()
+	resp, err := http.Post(url, contentType, bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var b bytes.Buffer
+		b.ReadFrom(resp.Body)
+		t.Fatalf("%s: status %d: %s", url, resp.StatusCode, b.String())
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestStaticContainer runs the static program uploaded to the server started
+// with -static-container through the HTTP API
+func TestStaticContainer(t *testing.T) {
+	if os.Getuid() != 0 {
+		t.Skip("not running as root")
+	}
+	hello := buildHello(t)
+
+	conf := &config.Config{}
+	if err := (&multiconfig.TagLoader{}).Load(conf); err != nil {
+		t.Fatal(err)
+	}
+	conf.StaticContainer = true
+	conf.Dir = t.TempDir()
+	conf.Parallelism = 1
+	if err := checkStaticContainer(conf); err != nil {
+		t.Fatal(err)
+	}
+	e, err := executor.New(executorConfig(conf), executorOptions(conf, nil, nil))
+	if err != nil {
+		t.Skip("sandbox is not available: ", err)
+	}
+	defer e.Shutdown(context.Background())
+	if static, _ := e.BuilderParam()["static"].(bool); !static {
+		t.Fatalf("builder param %v, want static", e.BuilderParam())
+	}
+	srv := httptest.NewServer(initHTTPMux(conf, e.Worker(), e.EnvPool(), e.FileStore(), newConfigReloader(conf, e), nil, nil, nil))
+	defer srv.Close()
+
+	// upload the program
+	var form bytes.Buffer
+	mw := multipart.NewWriter(&form)
+	fw, err := mw.CreateFormFile("file", "hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fw.Write(hello)
+	mw.Close()
+	var fileID string
+	postJSON(t, srv.URL+"/file", mw.FormDataContentType(), form.Bytes(), &fileID)
+
+	// run it
+	req, err := json.Marshal(map[string]any{"cmd": []any{map[string]any{
+		"args": []string{"hello"},
+		"files": []any{
+			map[string]any{"content": ""},
+			map[string]any{"name": "stdout", "max": 10240},
+			map[string]any{"name": "stderr", "max": 10240},
+		},
+		"cpuLimit":    1e9,
+		"memoryLimit": 256 << 20,
+		"procLimit":   50,
+		"copyIn":      map[string]any{"hello": map[string]any{"fileId": fileID}},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []model.Result
+	postJSON(t, srv.URL+"/run", "application/json", req, &results)
+	if len(results) != 1 {
+		t.Fatalf("got %d results, want 1", len(results))
+	}
+	r := results[0]
+	if r.Status != model.Status(envexec.StatusAccepted) {
+		t.Fatalf("status %v: %s\n%s", r.Status, r.Error, r.Files["stderr"])
+	}
+	if out := r.Files["stdout"]; out != "hello, world\n" {
+		t.Fatalf("stdout %q, want hello, world", out)
+	}
+	// only the work dir, /proc and /dev are in the container
+	if root := strings.Fields(r.Files["stderr"]); strings.Join(root, " ") != "dev proc w" {
+		t.Fatalf("container root %v, want only dev proc w", root)
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// listenFdsStart is the first file descriptor passed by systemd socket activation
+const listenFdsStart = 3
+
+var (
+	activatedOnce      sync.Once
+	activatedListeners []activatedListener
+	activatedMu        sync.Mutex
+)
+
+type activatedListener struct {
+	name string
+	lis  net.Listener
+}
+
+// loadActivatedListeners takes the listening sockets passed by systemd through
+// LISTEN_PID, LISTEN_FDS and LISTEN_FDNAMES, see sd_listen_fds(3)
+func loadActivatedListeners() {
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return
+	}
+	n, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || n <= 0 {
+		return
+	}
+	names := strings.Split(os.Getenv("LISTEN_FDNAMES"), ":")
+	for i := 0; i < n; i++ {
+		fd := listenFdsStart + i
+		syscall.CloseOnExec(fd)
+
+		name := ""
+		if i < len(names) {
+			name = names[i]
+		}
+		f := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		lis, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			logger.Sugar().Warn("systemd socket activation fd ", fd, " is not a listener: ", err)
+			continue
+		}
+		activatedListeners = append(activatedListeners, activatedListener{name: name, lis: lis})
+	}
+}
+
+// activatedNames are FileDescriptorName= of the socket unit recognized by the servers
+var activatedNames = map[string]bool{"http": true, "grpc": true, "monitor": true, "admin": true}
+
+// getActivatedListener returns the socket activated listener by the
+// FileDescriptorName= of the socket unit. Sockets with other names (e.g. the
+// default socket unit name) are used for http.
+func getActivatedListener(name string) net.Listener {
+	activatedOnce.Do(loadActivatedListeners)
+
+	activatedMu.Lock()
+	defer activatedMu.Unlock()
+
+	for i, l := range activatedListeners {
+		if l.name == name || (name == "http" && !activatedNames[l.name]) {
+			activatedListeners = append(activatedListeners[:i], activatedListeners[i+1:]...)
+			return l.lis
+		}
+	}
+	return nil
+}
+
+// sdNotify sends state to the systemd notify socket, see sd_notify(3)
+func sdNotify(state string) error {
+	addr := os.Getenv("NOTIFY_SOCKET")
+	if addr == "" {
+		return nil
+	}
+	if addr[0] == '@' {
+		addr = "\x00" + addr[1:]
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: addr, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
+
+// sdWatchdogInterval returns the watchdog timeout set by systemd, 0 if not enabled
+func sdWatchdogInterval() time.Duration {
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if err != nil || usec <= 0 {
+		return 0
+	}
+	if p := os.Getenv("WATCHDOG_PID"); p != "" {
+		if pid, err := strconv.Atoi(p); err != nil || pid != os.Getpid() {
+			return 0
+		}
+	}
+	return time.Duration(usec) * time.Microsecond
+}
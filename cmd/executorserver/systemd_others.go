@@ -0,0 +1,20 @@
+//go:build !linux
+
+package main
+
+import (
+	"net"
+	"time"
+)
+
+func getActivatedListener(name string) net.Listener {
+	return nil
+}
+
+func sdNotify(state string) error {
+	return nil
+}
+
+func sdWatchdogInterval() time.Duration {
+	return 0
+}
@@ -0,0 +1,20 @@
+// Command hello prints hello and the root entries of the container, it is
+// built statically by the test of -static-container
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+func main() {
+	fmt.Println("hello, world")
+	entries, err := os.ReadDir("/")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	for _, e := range entries {
+		fmt.Fprintln(os.Stderr, e.Name())
+	}
+}
@@ -0,0 +1,13 @@
+// Package ui provides the single page served at GET /ui for manual testing,
+// it runs commands through /run and browses the file store through /file
+// with the auth token entered in the page.
+package ui
+
+import (
+	_ "embed"
+)
+
+// Page is the self-contained page without external resources
+//
+//go:embed index.html
+var Page []byte
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+// usageResult defines the entry of GET /admin/usage, cpuTime is in ns and
+// memorySeconds is in byte seconds
+type usageResult struct {
+	Total       worker.Usage `json:"total"`
+	Period      worker.Usage `json:"period"`
+	PeriodStart string       `json:"periodStart,omitempty"` // RFC3339
+	Quota       *quotaResult `json:"quota,omitempty"`
+	FileBytes   int64        `json:"fileBytes"`
+}
+
+type quotaResult struct {
+	Runs    uint64        `json:"runs,omitempty"`
+	CPUTime time.Duration `json:"cpuTime,omitempty"`
+}
+
+// quotas maps the quotas of auth tokens into the quotas of their file store
+// namespace, which identifies the client in the worker
+func quotas(conf *config.Config) map[string]worker.Quota {
+	owners := tokenOwners(conf.AuthToken, conf.AdminToken)
+	rt := make(map[string]worker.Quota)
+	parse := func(name string, s []string, set func(*worker.Quota, string) error) {
+		for _, q := range s {
+			i := strings.LastIndexByte(q, '=')
+			if i < 0 {
+				logger.Sugar().Fatalf("invalid %s (expected token=limit): %s", name, q)
+			}
+			owner, ok := owners[q[:i]]
+			if !ok || owner == "" {
+				logger.Sugar().Fatalf("%s of unknown or admin auth token", name)
+			}
+			v := rt[owner]
+			if err := set(&v, q[i+1:]); err != nil {
+				logger.Sugar().Fatalf("invalid %s limit %s: %v", name, q[i+1:], err)
+			}
+			rt[owner] = v
+		}
+	}
+	parse("quota cpu", conf.QuotaCPU, func(v *worker.Quota, s string) (err error) {
+		v.CPUTime, err = time.ParseDuration(s)
+		return err
+	})
+	parse("quota runs", conf.QuotaRuns, func(v *worker.Quota, s string) (err error) {
+		v.Runs, err = strconv.ParseUint(s, 10, 64)
+		return err
+	})
+	return rt
+}
+
+func generateHandleUsage(work worker.Worker) func(*gin.Context) {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			abortNotAdmin(c)
+			return
+		}
+		usage := work.Usage()
+		rt := make(map[string]usageResult, len(usage))
+		for owner, u := range usage {
+			r := usageResult{
+				Total:     u.Total,
+				Period:    u.Period,
+				FileBytes: u.FileBytes,
+			}
+			if !u.PeriodStart.IsZero() {
+				r.PeriodStart = u.PeriodStart.Format(time.RFC3339)
+			}
+			if u.Quota != nil {
+				r.Quota = &quotaResult{Runs: u.Quota.Runs, CPUTime: u.Quota.CPUTime}
+			}
+			if owner == "" {
+				owner = "default"
+			}
+			rt[owner] = r
+		}
+		c.JSON(http.StatusOK, rt)
+	}
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/criyle/go-judge/cmd/executorserver/config"
+	"github.com/criyle/go-judge/cmd/executorserver/model"
+	restexecutor "github.com/criyle/go-judge/cmd/executorserver/rest_executor"
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+	"github.com/gin-gonic/gin"
+)
+
+// limits of the warmup commands, they only need to load the toolchains
+const (
+	warmupCPULimit    = 10 * time.Second
+	warmupClockLimit  = 20 * time.Second
+	warmupMemoryLimit = 1 << 30
+	warmupProcLimit   = 128
+	warmupOutputMax   = 4 << 10
+)
+
+var warmupEnv = []string{"PATH=/usr/local/bin:/usr/bin:/bin", "HOME=/tmp"}
+
+// warmupCmd defines the command run once in each environment, its outputs
+// are discarded
+type warmupCmd struct {
+	Name    string   `json:"name,omitempty"`
+	Args    []string `json:"args"`
+	Env     []string `json:"env,omitempty"`     // warmupEnv if empty
+	Content string   `json:"content,omitempty"` // stdin
+}
+
+// warmupPresets defines the commands to load the toolchains, the compilers
+// read a small program from stdin and write the output to /dev/null
+var warmupPresets = map[string][]warmupCmd{
+	"c": {{
+		Args:    []string{"/usr/bin/gcc", "-x", "c", "-O2", "-o", "/dev/null", "-"},
+		Content: "#include <stdio.h>\nint main(void) { printf(\"1\"); return 0; }\n",
+	}},
+	"cpp": {{
+		Args:    []string{"/usr/bin/g++", "-x", "c++", "-O2", "-o", "/dev/null", "-"},
+		Content: "#include <bits/stdc++.h>\nint main() { std::cout << 1; }\n",
+	}},
+	"java": {
+		{Args: []string{"/usr/bin/javac", "-version"}},
+		{Args: []string{"/usr/bin/java", "-version"}},
+	},
+	"python3": {{Args: []string{"/usr/bin/python3", "-c", "import sys, os, re, collections"}}},
+}
+
+// warmupRequest defines parameters of POST /admin/warmup
+type warmupRequest struct {
+	Presets []string    `json:"presets"`
+	Cmd     []warmupCmd `json:"cmd"` // raw commands run after the presets
+}
+
+type warmupResult struct {
+	Environments []warmupEnvResult `json:"environments"`
+	Failed       int               `json:"failed"` // environments with any command failed
+}
+
+type warmupEnvResult struct {
+	NUMANode *int              `json:"numaNode,omitempty"`
+	Error    string            `json:"error,omitempty"` // failed to borrow the environment
+	Results  []warmupCmdResult `json:"results,omitempty"`
+}
+
+type warmupCmdResult struct {
+	Name    string        `json:"name"`
+	Status  string        `json:"status"`
+	RunTime time.Duration `json:"runTime"`
+	Error   string        `json:"error,omitempty"`
+}
+
+func (r *warmupEnvResult) failed() bool {
+	if r.Error != "" {
+		return true
+	}
+	for _, c := range r.Results {
+		if c.Status != envexec.StatusAccepted.String() {
+			return true
+		}
+	}
+	return false
+}
+
+// warmupCmds expands the presets followed by the raw commands
+func warmupCmds(presets []string, raw []warmupCmd) ([]warmupCmd, error) {
+	var rt []warmupCmd
+	for _, p := range presets {
+		cmds, ok := warmupPresets[p]
+		if !ok {
+			return nil, fmt.Errorf("unknown warmup preset %q (available: %s)", p, strings.Join(warmupPresetNames(), ", "))
+		}
+		for _, c := range cmds {
+			c.Name = p
+			rt = append(rt, c)
+		}
+	}
+	for i, c := range raw {
+		if len(c.Args) == 0 {
+			return nil, fmt.Errorf("cmd[%d]: no args provided", i)
+		}
+		if c.Name == "" {
+			c.Name = fmt.Sprintf("cmd[%d]", i)
+		}
+		rt = append(rt, c)
+	}
+	if len(rt) == 0 {
+		return nil, errors.New("no warmup preset or cmd provided")
+	}
+	return rt, nil
+}
+
+// checkWarmupLimits checks the limits of the warmup commands are within the
+// max limits of the server, so that a typo of the max is caught at startup
+// rather than failing the warmup or clamping it silently
+func checkWarmupLimits(limitMax worker.LimitMax, cmds []warmupCmd) error {
+	for _, c := range cmds {
+		wc := c.workerCmd()
+		if err := limitMax.Apply(&wc, false); err != nil {
+			return fmt.Errorf("warmup %s: %w", c.Name, err)
+		}
+	}
+	return nil
+}
+
+func warmupPresetNames() []string {
+	rt := make([]string, 0, len(warmupPresets))
+	for n := range warmupPresets {
+		rt = append(rt, n)
+	}
+	sort.Strings(rt)
+	return rt
+}
+
+func (c warmupCmd) workerCmd() worker.Cmd {
+	env := c.Env
+	if len(env) == 0 {
+		env = warmupEnv
+	}
+	return worker.Cmd{
+		Args: c.Args,
+		Env:  env,
+		Files: []worker.CmdFile{
+			&worker.MemoryFile{Content: []byte(c.Content)},
+			&worker.Collector{Name: "stdout", Max: warmupOutputMax},
+			&worker.Collector{Name: "stderr", Max: warmupOutputMax},
+		},
+		CPULimit:    warmupCPULimit,
+		ClockLimit:  warmupClockLimit,
+		MemoryLimit: warmupMemoryLimit,
+		ProcLimit:   warmupProcLimit,
+	}
+}
+
+// warmupPool borrows the idle environments of the pool (of each NUMA node)
+// and runs the commands once in each of them with at most parallelism
+// environments at the same time. The environments are held until all of them
+// are warmed up, since the pool would return the one just put back, so that
+// requests running meanwhile get other environments.
+func warmupPool(ctx context.Context, work worker.Worker, envPool pool.Pool, cmds []warmupCmd, parallelism int) warmupResult {
+	type borrowed struct {
+		env  envexec.Environment
+		node *int
+	}
+	var envs []borrowed
+	var rt warmupResult
+	borrow := func(p pool.Pool, node *int) {
+		for n := p.Idle(); n > 0; n-- {
+			e, err := p.Get(ctx, worker.EnvironmentOptions{})
+			if err != nil {
+				rt.Environments = append(rt.Environments, warmupEnvResult{NUMANode: node, Error: err.Error()})
+				return
+			}
+			envs = append(envs, borrowed{env: e, node: node})
+		}
+	}
+	if np, ok := envPool.(pool.NUMAPool); ok {
+		for _, id := range np.NUMANodes() {
+			id := id
+			borrow(np.NodePool(id), &id)
+		}
+	} else {
+		borrow(envPool, nil)
+	}
+	defer func() {
+		for _, e := range envs {
+			envPool.Put(e.env)
+		}
+	}()
+
+	results := make([]warmupEnvResult, len(envs))
+	sem := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, e := range envs {
+		wg.Add(1)
+		go func(r *warmupEnvResult, e borrowed) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			r.NUMANode = e.node
+			for _, c := range cmds {
+				res := work.Warmup(ctx, e.env, c.workerCmd())
+				r.Results = append(r.Results, warmupCmdResult{
+					Name:    c.Name,
+					Status:  res.Status.String(),
+					RunTime: res.RunTime,
+					Error:   res.Error,
+				})
+			}
+		}(&results[i], e)
+	}
+	wg.Wait()
+
+	rt.Environments = append(results, rt.Environments...)
+	for i := range rt.Environments {
+		if rt.Environments[i].failed() {
+			rt.Failed++
+		}
+	}
+	return rt
+}
+
+// warmupConfigured runs the -warmup presets in the idle environments, e.g.
+// the pre-forked ones
+func warmupConfigured(conf *config.Config, work worker.Worker, envPool pool.Pool) *warmupResult {
+	var presets []string
+	for _, p := range conf.Warmup {
+		if err := presetChecks.Available(p); err != nil {
+			logger.Sugar().Warn("Warmup skipped: ", err)
+			continue
+		}
+		presets = append(presets, p)
+	}
+	if len(presets) == 0 {
+		return nil
+	}
+	cmds, err := warmupCmds(presets, nil)
+	if err != nil {
+		logger.Sugar().Error("Warmup skipped: ", err)
+		return nil
+	}
+	rt := warmupPool(context.Background(), work, envPool, cmds, conf.Parallelism)
+	logger.Sugar().Infof("Warmup %v finished in %d environments, %d failed", presets, len(rt.Environments), rt.Failed)
+	return &rt
+}
+
+func generateHandleWarmup(conf *config.Config, work worker.Worker, envPool pool.Pool) func(*gin.Context) {
+	return func(c *gin.Context) {
+		if !isAdmin(c) {
+			abortNotAdmin(c)
+			return
+		}
+		var req warmupRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			restexecutor.AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, err.Error())
+			return
+		}
+		if conf.StaticContainer && len(req.Presets) > 0 {
+			restexecutor.AbortError(c, http.StatusBadRequest, model.CodeFeatureDisabled,
+				"warmup presets run the host toolchains which are not mounted by -static-container")
+			return
+		}
+		cmds, err := warmupCmds(req.Presets, req.Cmd)
+		if err != nil {
+			restexecutor.AbortError(c, http.StatusBadRequest, model.CodeValidationFailed, err.Error())
+			return
+		}
+		for _, p := range req.Presets {
+			if err := presetChecks.Available(p); err != nil {
+				restexecutor.AbortError(c, http.StatusServiceUnavailable, model.CodeFeatureDisabled, err.Error())
+				return
+			}
+		}
+		rt := warmupPool(c.Request.Context(), work, envPool, cmds, conf.Parallelism)
+		logger.Sugar().Infof("Warmup finished in %d environments, %d failed", len(rt.Environments), rt.Failed)
+		c.JSON(http.StatusOK, rt)
+	}
+}
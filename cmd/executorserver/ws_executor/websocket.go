@@ -2,12 +2,14 @@ package wsexecutor
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"sync"
 	"time"
 
 	"github.com/criyle/go-judge/cmd/executorserver/model"
+	"github.com/criyle/go-judge/filestore"
 	"github.com/criyle/go-judge/worker"
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
@@ -32,6 +34,13 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		return true
 	},
+	// the failed handshake responds in the error envelope as the REST API
+	Error: func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.Header().Set("Sec-Websocket-Version", "13")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(model.NewErrorResponse(model.CodeValidationFailed, reason.Error()))
+	},
 }
 
 const (
@@ -58,8 +67,9 @@ func (h *wsHandle) Register(r *gin.Engine) {
 func (h *wsHandle) handleWS(c *gin.Context) {
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
+		// the response is written by the upgrader
 		c.Error(err)
-		c.AbortWithStatusJSON(http.StatusBadRequest, err.Error())
+		c.Abort()
 		return
 	}
 	resultCh := make(chan model.Response, 128)
@@ -76,7 +86,7 @@ func (h *wsHandle) handleWS(c *gin.Context) {
 			return fmt.Errorf("ws convert error: %v", err)
 		}
 
-		ctx, cancel := context.WithCancel(baseCtx)
+		ctx, cancel := context.WithCancelCause(baseCtx)
 		if err := cm.Add(r.RequestID, cancel); err != nil {
 			select {
 			case <-baseCtx.Done():
@@ -85,7 +95,7 @@ func (h *wsHandle) handleWS(c *gin.Context) {
 				ErrorMsg:  err.Error(),
 			}:
 			}
-			cancel()
+			cancel(nil)
 			h.logger.Sugar().Debugf("ws request error: %v", err)
 			return nil
 		}
@@ -105,8 +115,9 @@ func (h *wsHandle) handleWS(c *gin.Context) {
 					ret = <-retCh
 				default: // not started
 					ret = worker.Response{
-						RequestID: r.RequestID,
-						Error:     fmt.Errorf("request cancelled before execute"),
+						RequestID:    r.RequestID,
+						Error:        fmt.Errorf("request cancelled before execute"),
+						CancelReason: worker.CancelCancelled,
 					}
 				}
 			case ret = <-retCh:
@@ -120,6 +131,10 @@ func (h *wsHandle) handleWS(c *gin.Context) {
 					ErrorMsg:  resp.ErrorMsg,
 				}
 			}
+			resp.SetLimits(r)
+			if req.RawBinary {
+				resp.RawBinary()
+			}
 			select {
 			case <-baseCtx.Done():
 			case resultCh <- resp:
@@ -137,7 +152,12 @@ func (h *wsHandle) handleWS(c *gin.Context) {
 			return nil
 		})
 
-		baseCtx, baseCancel := context.WithCancel(context.TODO())
+		ctx := context.TODO()
+		// keeps the file store namespace of the authenticated connection
+		if owner, ok := filestore.NamespaceFromContext(c.Request.Context()); ok {
+			ctx = filestore.NewNamespaceContext(ctx, owner)
+		}
+		baseCtx, baseCancel := context.WithCancel(ctx)
 		defer baseCancel()
 
 		for {
@@ -177,15 +197,15 @@ func (h *wsHandle) handleWS(c *gin.Context) {
 }
 
 type contextMap struct {
-	m  map[string]context.CancelFunc
+	m  map[string]context.CancelCauseFunc
 	mu sync.Mutex
 }
 
 func newContextMap() *contextMap {
-	return &contextMap{m: make(map[string]context.CancelFunc)}
+	return &contextMap{m: make(map[string]context.CancelCauseFunc)}
 }
 
-func (c *contextMap) Add(reqId string, cancel context.CancelFunc) error {
+func (c *contextMap) Add(reqId string, cancel context.CancelCauseFunc) error {
 	if reqId == "" {
 		return fmt.Errorf("empty request id")
 	}
@@ -205,6 +225,6 @@ func (c *contextMap) Remove(reqId string) {
 
 	if cancel, exist := c.m[reqId]; exist {
 		delete(c.m, reqId)
-		cancel()
+		cancel(worker.ErrCancelRequested)
 	}
 }
@@ -135,6 +135,10 @@ func Exec(e *C.char) *C.char {
 		return nil
 	}
 	defer ret.Close()
+	ret.SetLimits(r)
+	if req.RawBinary {
+		ret.RawBinary()
+	}
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(ret); err != nil {
 		return nil
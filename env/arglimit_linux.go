@@ -0,0 +1,43 @@
+package env
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+const (
+	// argStackMax is the cap of ARG_MAX derived from the stack limit (3/4
+	// of _STK_LIM)
+	argStackMax = 6 << 20
+
+	// containerArgMax is the max total size of args and env of the programs
+	// in the container. The execve parameters are sent to the container init
+	// in a single message of 16 KiB (bufferSize of go-sandbox container)
+	// with the rlimits and the seccomp filter, which take the rest.
+	containerArgMax = 15 << 10
+)
+
+// ArgLimit returns the max total size of args and env (ARG_MAX) of the
+// programs running with the stack limit of the current process, and the max
+// size of each of them (MAX_ARG_STRLEN = 32 pages) as execve measures them.
+// Both are lowered to what the container could receive if container.
+func ArgLimit(container bool) (max, strMax envexec.Size) {
+	strMax = envexec.Size(32 * os.Getpagesize())
+	max = argStackMax
+	var r syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_STACK, &r); err == nil && r.Cur/4 < uint64(max) {
+		max = envexec.Size(r.Cur / 4)
+	}
+	if max < strMax {
+		max = strMax
+	}
+	if container {
+		max = containerArgMax
+		if strMax > max {
+			strMax = max
+		}
+	}
+	return max, strMax
+}
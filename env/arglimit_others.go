@@ -0,0 +1,11 @@
+//go:build !linux
+
+package env
+
+import "github.com/criyle/go-judge/envexec"
+
+// ArgLimit returns no limit since the args and env are not measured as linux
+// execve on this platform
+func ArgLimit(container bool) (max, strMax envexec.Size) {
+	return 0, 0
+}
@@ -13,14 +13,37 @@ type Logger interface {
 // Config defines parameters to create environment builder
 type Config struct {
 	ContainerInitPath  string
+	ContainerRoot      string // directory of the temp container roots, the temp dir if empty
 	TmpFsParam         string
+	ShmSize            uint64 // size of the tmpfs mounted at /dev/shm by default mount, 0 disables
 	NetShare           bool
 	MountConf          string
+	Static             bool // mounts only the tmpfs work directory, /proc and /dev/null instead of MountConf (Linux only)
 	SeccompConf        string
 	CgroupPrefix       string
+	CgroupMount        bool
 	Cpuset             string
 	ContainerCredStart int
+	CredPinStart       int
+	CredPinCount       int
 	EnableCPURate      bool
 	CPUCfsPeriod       time.Duration
+	VerifyReset        bool
+	CheckFds           bool
+	CgroupRetry        int
+	CgroupRetryBackoff time.Duration
+	Datasets           map[string]string // name -> host directory
+	Emulators          map[string]string // arch -> emulator path in the container
+	InitLogSize        int               // bytes of container init stderr kept for each environment, 0 writes to stderr
+	RandomSize         uint64            // bytes of the deterministic /dev/urandom of commands with random seed
+	InitLogOverflow    func(dropped int)
+	Probe              IsolationProbe // detects isolation features, nil probes the host
 	Logger
 }
+
+// InitLogStat reports the stderr of the container init of an environment
+type InitLogStat struct {
+	ID      uint64
+	Log     []byte
+	Dropped uint64 // bytes dropped since created or recycled
+}
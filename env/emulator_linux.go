@@ -0,0 +1,14 @@
+package env
+
+import (
+	"github.com/criyle/go-judge/env/linuxcontainer"
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
+)
+
+// CalibrateEmulators measures the memory of the emulators by running an
+// empty program of each emulated architecture once, which is subtracted from
+// the memory of the emulated runs of the environments built by b
+func CalibrateEmulators(b pool.EnvBuilder) (map[string]envexec.Size, map[string]error) {
+	return linuxcontainer.CalibrateEmulators(b)
+}
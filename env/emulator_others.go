@@ -0,0 +1,14 @@
+//go:build !linux
+
+package env
+
+import (
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
+)
+
+// CalibrateEmulators does nothing since the programs are not emulated on
+// this platform
+func CalibrateEmulators(b pool.EnvBuilder) (map[string]envexec.Size, map[string]error) {
+	return nil, nil
+}
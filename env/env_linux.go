@@ -3,11 +3,14 @@ package env
 import (
 	"fmt"
 	"os"
+	"path"
+	"sync"
 	"sync/atomic"
 	"syscall"
 
 	"github.com/criyle/go-judge/env/linuxcontainer"
 	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
 	"github.com/criyle/go-sandbox/container"
 	"github.com/criyle/go-sandbox/pkg/cgroup"
 	"github.com/criyle/go-sandbox/pkg/forkexec"
@@ -28,14 +31,19 @@ func NewBuilder(c Config) (pool.EnvBuilder, map[string]any, error) {
 		mountBuilder  *mount.Builder
 		symbolicLinks []container.SymbolicLink
 		maskPaths     []string
+		mc            *Mounts
+		err           error
 	)
-	mc, err := readMountConfig(c.MountConf)
-	if err != nil {
+	if c.Static {
+		// the mount config is not read so that no host path is touched
+		c.Info("Static container: only the tmpfs work directory, /proc and /dev/null are mounted")
+		mountBuilder = getMinimalMount(defaultWorkDir, c.TmpFsParam)
+	} else if mc, err = readMountConfig(c.MountConf); err != nil {
 		if !os.IsNotExist(err) {
 			return nil, nil, err
 		}
 		c.Info("Mount.yaml(", c.MountConf, ") does not exists, use the default container mount")
-		mountBuilder = getDefaultMount(c.TmpFsParam)
+		mountBuilder = getDefaultMount(c.TmpFsParam, c.ShmSize)
 	} else {
 		mountBuilder, err = parseMountConfig(mc)
 		if err != nil {
@@ -50,13 +58,32 @@ func NewBuilder(c Config) (pool.EnvBuilder, map[string]any, error) {
 	} else {
 		symbolicLinks = defaultSymLinks
 	}
-	if mc != nil && len(mc.MaskPaths) > 0 {
+	switch {
+	case mc != nil && len(mc.MaskPaths) > 0:
 		maskPaths = mc.MaskPaths
-	} else {
+	case c.Static:
+		maskPaths = procMaskPaths(defaultMaskPaths)
+	default:
 		maskPaths = defaultMaskPaths
 	}
+	missingMounts := missingBindSources(mountBuilder)
 	m := mountBuilder.FilterNotExist().Mounts
 	c.Info("Created container mount at:", mountBuilder)
+	for _, mt := range m {
+		if linuxcontainer.IsWritableDirMount(mt) {
+			c.Warn("Writable bind mount could not be cleaned between runs, environments will not be reused: ", mt)
+		}
+	}
+
+	if c.Static && (len(c.Datasets) > 0 || len(c.Emulators) > 0) {
+		return nil, nil, fmt.Errorf("static container could not mount datasets or emulators from the host")
+	}
+	if err := checkDatasets(c.Datasets); err != nil {
+		return nil, nil, err
+	}
+	for name, dir := range c.Datasets {
+		c.Info("Dataset ", name, " from ", dir, " is mounted at /data/", name, " when requested")
+	}
 
 	seccomp, err := readSeccompConf(c.SeccompConf)
 	if err != nil {
@@ -79,7 +106,7 @@ func NewBuilder(c Config) (pool.EnvBuilder, map[string]any, error) {
 	// use setuid container only if running in root privilege
 	var credGen container.CredGenerator
 	if os.Getuid() == 0 && c.ContainerCredStart > 0 {
-		credGen = newCredGen(uint32(c.ContainerCredStart))
+		credGen = getCredGen(uint32(c.ContainerCredStart))
 	}
 
 	hostName := containerName
@@ -97,6 +124,7 @@ func NewBuilder(c Config) (pool.EnvBuilder, map[string]any, error) {
 	c.Info("Creating container builder: hostName=", hostName, ", domainName=", domainName, ", workDir=", workDir)
 
 	b := &container.Builder{
+		Root:          c.ContainerRoot,
 		TmpRoot:       "executorserver",
 		Mounts:        m,
 		SymbolicLinks: symbolicLinks,
@@ -135,31 +163,89 @@ func NewBuilder(c Config) (pool.EnvBuilder, map[string]any, error) {
 		cg.Destroy()
 	}
 
-	var cgroupPool linuxcontainer.CgroupPool
+	var (
+		cgroupPool   linuxcontainer.CgroupPool
+		ioController *linuxcontainer.IOController
+	)
 	if cgb != nil {
-		cgroupPool = linuxcontainer.NewFakeCgroupPool(cgb, c.CPUCfsPeriod)
+		// the prefix is created by the tested cgroup
+		if ioController, err = linuxcontainer.NewIOController(t, c.CgroupPrefix); err != nil {
+			c.Warn("IO accounting is disabled: ", err)
+		} else {
+			c.Info("Enable io accounting by the io / blkio controller")
+		}
+		cgroupPool = linuxcontainer.NewFakeCgroupPool(cgb, c.CPUCfsPeriod, ioController)
+	}
+	var cgroupMount *linuxcontainer.CgroupMount
+	if c.CgroupMount || (mc != nil && mc.Cgroup) {
+		switch {
+		case cgb == nil:
+			c.Warn("Cgroup mount is disabled since cgroup is not available")
+		case t != cgroup.CgroupTypeV2:
+			c.Warn("Cgroup mount is disabled since it is only supported by cgroup v2")
+		case unshareFlags&unix.CLONE_NEWCGROUP == 0:
+			c.Warn("Cgroup mount is disabled since cgroup namespace is not available")
+		default:
+			c.Info("Enable read-only cgroup mount at /sys/fs/cgroup")
+			cgroupMount = &linuxcontainer.CgroupMount{
+				Builder:   cgb,
+				Path:      path.Join("/sys/fs/cgroup", c.CgroupPrefix),
+				CfsPeriod: c.CPUCfsPeriod,
+				IO:        ioController,
+			}
+		}
 	}
 	cgroupType := int(t)
 	if cgb == nil {
 		cgroupType = 0
 	}
 	return linuxcontainer.NewEnvBuilder(linuxcontainer.Config{
-			Builder:    b,
-			CgroupPool: cgroupPool,
-			WorkDir:    workDir,
-			Cpuset:     c.Cpuset,
-			CPURate:    c.EnableCPURate,
-			Seccomp:    seccomp,
+			Builder:     b,
+			CgroupPool:  cgroupPool,
+			CgroupMount: cgroupMount,
+			WorkDir:     workDir,
+			Cpuset:      c.Cpuset,
+			CPURate:     c.EnableCPURate,
+			Seccomp:     seccomp,
+
+			CredPinStart: uint32(c.CredPinStart),
+			CredPinCount: uint32(c.CredPinCount),
+			VerifyReset:  c.VerifyReset,
+			CheckFds:     c.CheckFds,
+			AttachRetry: linuxcontainer.AttachRetry{
+				Attempts: c.CgroupRetry,
+				Backoff:  c.CgroupRetryBackoff,
+			},
+			Datasets: c.Datasets,
+			Profiles: map[string]linuxcontainer.Profile{
+				envexec.ProfileMinimal: getMinimalProfile(m, workDir, c.TmpFsParam, maskPaths),
+			},
+			IO: ioController != nil,
+			InitLog: linuxcontainer.InitLog{
+				Size:       c.InitLogSize,
+				OnOverflow: c.InitLogOverflow,
+			},
+			Emulators: c.Emulators,
+			RandomSize: c.RandomSize,
 		}), map[string]any{
 			"cgroupType":   cgroupType,
+			"cgroupMount":  cgroupMount != nil,
 			"mount":        m,
+			"missingMount": missingMounts,
 			"symbolicLink": symbolicLinks,
 			"maskedPaths":  maskPaths,
 			"hostName":     hostName,
 			"domainName":   domainName,
 			"workDir":      workDir,
+			"static":       c.Static,
 			"uid":          cUID,
 			"gid":          cGID,
+			"credPinStart": c.CredPinStart,
+			"credPinCount": c.CredPinCount,
+			"datasets":     c.Datasets,
+			"ioAccounting": ioController != nil,
+			"initLogSize":  c.InitLogSize,
+			"emulators":    c.Emulators,
 		}, nil
 }
 
@@ -167,10 +253,29 @@ type credGen struct {
 	cur uint32
 }
 
+var (
+	// credGens keeps credential generators across builder reloads so that
+	// containers created after reload do not reuse credentials of running ones
+	credGens   = make(map[uint32]*credGen)
+	credGensMu sync.Mutex
+)
+
 func newCredGen(start uint32) *credGen {
 	return &credGen{cur: start}
 }
 
+func getCredGen(start uint32) *credGen {
+	credGensMu.Lock()
+	defer credGensMu.Unlock()
+
+	g, ok := credGens[start]
+	if !ok {
+		g = newCredGen(start)
+		credGens[start] = g
+	}
+	return g
+}
+
 func (c *credGen) Get() syscall.Credential {
 	n := atomic.AddUint32(&c.cur, 1)
 	return syscall.Credential{
@@ -0,0 +1,232 @@
+package env
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/container"
+	"go.uber.org/zap"
+	"golang.org/x/sys/unix"
+)
+
+// spawnThreadsEnv makes the test binary copied into the container spawn the
+// number of threads and exit
+const spawnThreadsEnv = "GO_JUDGE_TEST_SPAWN_THREADS"
+
+// spinEnv makes the test binary copied into the container spin until it used
+// the number of milliseconds of cpu time and exit
+const spinEnv = "GO_JUDGE_TEST_SPIN_MS"
+
+// getppidEnv makes the test binary copied into the container call getppid
+// the number of times and exit
+const getppidEnv = "GO_JUDGE_TEST_GETPPID"
+
+func TestMain(m *testing.M) {
+	// the test binary is the container init as well
+	container.Init()
+	if n, err := strconv.Atoi(os.Getenv(spawnThreadsEnv)); err == nil {
+		spawnThreads(n)
+		os.Exit(0)
+	}
+	if n, err := strconv.Atoi(os.Getenv(spinEnv)); err == nil {
+		spin(time.Duration(n) * time.Millisecond)
+		os.Exit(0)
+	}
+	if n, err := strconv.Atoi(os.Getenv(getppidEnv)); err == nil {
+		for i := 0; i < n; i++ {
+			unix.Getppid()
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func spawnThreads(n int) {
+	var started, done sync.WaitGroup
+	started.Add(n)
+	done.Add(n)
+	for i := 0; i < n; i++ {
+		go func() {
+			defer done.Done()
+			runtime.LockOSThread()
+			started.Done()
+			started.Wait()
+			time.Sleep(300 * time.Millisecond)
+		}()
+	}
+	done.Wait()
+}
+
+func spin(d time.Duration) {
+	var ts unix.Timespec
+	for {
+		if err := unix.ClockGettime(unix.CLOCK_PROCESS_CPUTIME_ID, &ts); err != nil || time.Duration(ts.Nano()) >= d {
+			return
+		}
+	}
+}
+
+// sampleWaiter samples the usage as the worker does, the peak of processes
+// is sampled from pids.current without pids.peak (cgroup v1)
+func sampleWaiter(ctx context.Context, p envexec.Process) bool {
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-p.Done():
+			return false
+		case <-ticker.C:
+			p.Usage()
+		}
+	}
+}
+
+// newTestPool creates the pool of the linux container with the config
+// modified by the options, it skips the test if the container or cgroup could
+// not be created
+func newTestPool(t *testing.T, options ...func(*Config)) pool.Pool {
+	if os.Getuid() != 0 {
+		t.Skip("container requires root")
+	}
+	c := Config{
+		ContainerRoot: t.TempDir(),
+		TmpFsParam:    "size=64m,nr_inodes=4k",
+		CgroupPrefix:  "gojudge-test",
+		Logger:        zap.NewNop().Sugar(),
+	}
+	for _, o := range options {
+		o(&c)
+	}
+	b, param, err := NewBuilder(c)
+	if err != nil {
+		t.Skip("container is not available: ", err)
+	}
+	if param["cgroupType"] == 0 {
+		t.Skip("cgroup is not available")
+	}
+	p := pool.NewPool(b)
+	t.Cleanup(func() { p.Rebuild() })
+	return p
+}
+
+func TestProcPeakThreads(t *testing.T) {
+	const threads = 8
+	p := newTestPool(t)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Skip("environment is not available: ", err)
+	}
+	defer p.Put(m)
+
+	// the run in the reused environment reports its own peak as well
+	for i := 0; i < 2; i++ {
+		r, err := (&envexec.Single{Cmd: &envexec.Cmd{
+			Environment: m,
+			CopyIn:      map[string]envexec.File{"t": envexec.NewFileInput(exe)},
+			Args:        []string{"t"},
+			Env:         []string{spawnThreadsEnv + "=" + strconv.Itoa(threads)},
+			TimeLimit:   5 * time.Second,
+			MemoryLimit: 256 << 20,
+			ProcLimit:   64,
+			Waiter:      sampleWaiter,
+		}}).Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Status != envexec.StatusAccepted {
+			t.Fatalf("run %d: %v: %s", i, r.Status, r.Error)
+		}
+		if r.ProcPeak < threads {
+			t.Fatalf("run %d: procPeak %d, want at least %d", i, r.ProcPeak, threads)
+		}
+	}
+}
+
+func TestProcLimitExceededThreads(t *testing.T) {
+	const procLimit = 4
+	p := newTestPool(t)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Skip("environment is not available: ", err)
+	}
+	defer p.Put(m)
+
+	r, err := (&envexec.Single{Cmd: &envexec.Cmd{
+		Environment: m,
+		CopyIn:      map[string]envexec.File{"t": envexec.NewFileInput(exe)},
+		Args:        []string{"t"},
+		Env:         []string{spawnThreadsEnv + "=16"},
+		TimeLimit:   5 * time.Second,
+		MemoryLimit: 256 << 20,
+		ProcLimit:   procLimit,
+		Waiter:      sampleWaiter,
+	}}).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != envexec.StatusProcessLimitExceeded || int(r.Status) != 16 {
+		t.Fatalf("got %v (%d), want %v (16): %s", r.Status, int(r.Status), envexec.StatusProcessLimitExceeded, r.Error)
+	}
+	if r.ProcPeak < procLimit {
+		t.Fatalf("procPeak %d, want at least %d", r.ProcPeak, procLimit)
+	}
+}
+
+func TestResetIsolatesRuns(t *testing.T) {
+	p := newTestPool(t)
+	run := func(m envexec.Environment, script string) *envexec.Result {
+		t.Helper()
+		r, err := (&envexec.Single{Cmd: &envexec.Cmd{
+			Environment: m,
+			Args:        []string{"/bin/sh", "-c", script},
+			Env:         []string{"PATH=/usr/bin:/bin"},
+			TimeLimit:   5 * time.Second,
+			MemoryLimit: 256 << 20,
+			ProcLimit:   64,
+			Waiter:      sampleWaiter,
+		}}).Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &r
+	}
+
+	m, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Skip("environment is not available: ", err)
+	}
+	if r := run(m, "echo a > /tmp/a && echo a > /w/a"); r.Status != envexec.StatusAccepted {
+		t.Fatalf("run A: %v: %s", r.Status, r.Error)
+	}
+	p.Put(m)
+
+	// run B gets the same environment from the pool
+	m1, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Put(m1)
+	if m1 != m {
+		t.Fatal("environment is not reused")
+	}
+	if r := run(m1, "test ! -e /tmp/a && test ! -e /w/a"); r.Status != envexec.StatusAccepted {
+		t.Fatalf("run B sees the files of run A: %v", r.Status)
+	}
+}
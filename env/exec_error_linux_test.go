@@ -0,0 +1,58 @@
+package env
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// TestExecError covers the execve failures reported by the container init
+func TestExecError(t *testing.T) {
+	p := newTestPool(t)
+	m, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Skip("environment is not available: ", err)
+	}
+	defer p.Put(m)
+
+	for _, c := range []struct {
+		name   string
+		args   []string
+		copyIn string // content of the file "a" copied in with the exec bit
+		typ    envexec.FileErrorType
+	}{
+		{"missing executable", []string{"/nonexistent"}, "", envexec.ErrExecNotFound},
+		{"missing in PATH", []string{"nonexistent"}, "", envexec.ErrExecNotFound},
+		{"missing interpreter", []string{"./a"}, "#!/nonexistent\n", envexec.ErrExecMissingInterpreter},
+		{"directory", []string{"/tmp"}, "", envexec.ErrExecNotExecutable},
+		{"invalid format", []string{"./a"}, "\x00garbage", envexec.ErrExecInvalidFormat},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			cmd := &envexec.Cmd{
+				Environment: m,
+				Args:        c.args,
+				Env:         []string{"PATH=/usr/bin:/bin"},
+				TimeLimit:   5 * time.Second,
+				MemoryLimit: 256 << 20,
+				ProcLimit:   64,
+				Waiter:      sampleWaiter,
+			}
+			if c.copyIn != "" {
+				cmd.CopyIn = map[string]envexec.File{"a": envexec.NewFileReader(strings.NewReader(c.copyIn), false)}
+			}
+			r, err := (&envexec.Single{Cmd: cmd}).Run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Status != envexec.StatusFileError {
+				t.Fatalf("%v: %s, want %v", r.Status, r.Error, envexec.StatusFileError)
+			}
+			if len(r.FileError) != 1 || r.FileError[0].Type != c.typ || r.FileError[0].Name != c.args[0] {
+				t.Fatalf("file error %+v, want %v of %s", r.FileError, c.typ, c.args[0])
+			}
+		})
+	}
+}
@@ -0,0 +1,73 @@
+// Package fake provides the environment that runs the programs as child
+// processes of the server in temporary work directories without any
+// isolation, for the tests of the code calling the worker on hosts without
+// root and cgroups.
+//
+// The results are deterministic: every run reports the canned cpu time, run
+// time and memory instead of the measured ones, so that the exact results
+// could be asserted. Only the runs killed (e.g. by the clock limit or the
+// cancellation) report the measured run time. Nothing other than the clock
+// limit is enforced, the canned cpu time and memory are checked against the
+// limits to simulate the limit exceeded.
+package fake
+
+import (
+	"os"
+	"time"
+
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
+)
+
+// Default canned usage reported for every run
+const (
+	DefaultTime   = time.Millisecond
+	DefaultMemory = envexec.Size(1 << 20)
+)
+
+// Config defines the fake environment builder
+type Config struct {
+	Dir    string        // parent directory of the work directories, the temp dir if empty
+	Time   time.Duration // cpu time and run time reported, DefaultTime if 0
+	Memory envexec.Size  // memory reported, DefaultMemory if 0
+}
+
+var _ pool.EnvBuilder = &Builder{}
+
+// Builder creates the fake environments
+type Builder struct {
+	dir    string
+	time   time.Duration
+	memory envexec.Size
+}
+
+// NewBuilder creates the fake environment builder
+func NewBuilder(c Config) *Builder {
+	b := &Builder{dir: c.Dir, time: c.Time, memory: c.Memory}
+	if b.time == 0 {
+		b.time = DefaultTime
+	}
+	if b.memory == 0 {
+		b.memory = DefaultMemory
+	}
+	return b
+}
+
+// Build creates a fake environment with an empty work directory
+func (b *Builder) Build() (pool.Environment, error) {
+	wd, err := os.MkdirTemp(b.dir, "es-fake")
+	if err != nil {
+		return nil, err
+	}
+	wdf, err := os.Open(wd)
+	if err != nil {
+		os.RemoveAll(wd)
+		return nil, err
+	}
+	return &environment{
+		wdPath: wd,
+		wd:     wdf,
+		time:   b.time,
+		memory: b.memory,
+	}, nil
+}
@@ -0,0 +1,85 @@
+package fake
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
+)
+
+var _ pool.Environment = &environment{}
+
+type environment struct {
+	wdPath string
+	wd     *os.File
+	time   time.Duration
+	memory envexec.Size
+}
+
+func (e *environment) WorkDir() *os.File {
+	return e.wd
+}
+
+func (e *environment) Open(p string, flags int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(filepath.Join(e.wdPath, p), flags, perm)
+}
+
+func (e *environment) MkdirAll(p string, perm os.FileMode) error {
+	return os.MkdirAll(filepath.Join(e.wdPath, p), perm)
+}
+
+func (e *environment) Symlink(oldName, newName string) error {
+	return os.Symlink(oldName, filepath.Join(e.wdPath, newName))
+}
+
+func (e *environment) Destroy() error {
+	e.wd.Close()
+	return os.RemoveAll(e.wdPath)
+}
+
+// Reset removes the contents of the work directory
+func (e *environment) Reset() error {
+	d, err := os.Open(e.wdPath)
+	if err != nil {
+		return err
+	}
+	names, err := d.Readdirnames(-1)
+	d.Close()
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if err := os.RemoveAll(filepath.Join(e.wdPath, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// usage returns the canned usage reported during the run
+func (e *environment) usage() envexec.Usage {
+	return envexec.Usage{Time: e.time, Memory: e.memory}
+}
+
+var _ envexec.Process = &process{}
+
+type process struct {
+	done   chan struct{}
+	result envexec.RunnerResult
+	usage  envexec.Usage
+}
+
+func (p *process) Done() <-chan struct{} {
+	return p.done
+}
+
+func (p *process) Result() envexec.RunnerResult {
+	<-p.done
+	return p.result
+}
+
+func (p *process) Usage() envexec.Usage {
+	return p.usage
+}
@@ -0,0 +1,133 @@
+//go:build !windows
+
+package fake
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/runner"
+)
+
+func (e *environment) Execve(ctx context.Context, param envexec.ExecveParam) (envexec.Process, error) {
+	if len(param.Args) == 0 {
+		return nil, errors.New("no args provided")
+	}
+	path, err := e.lookPath(param)
+	if err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	pid, err := syscall.ForkExec(path, param.Args, &syscall.ProcAttr{
+		Dir:   e.wdPath,
+		Env:   param.Env,
+		Files: param.Files,
+		Sys:   &syscall.SysProcAttr{Setpgid: true},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("execve: %w", err)
+	}
+
+	p := &process{done: make(chan struct{}), usage: e.usage()}
+	go func() {
+		defer close(p.done)
+
+		stop := make(chan struct{})
+		defer close(stop)
+		go func() {
+			select {
+			case <-ctx.Done():
+				syscall.Kill(-pid, syscall.SIGKILL)
+			case <-stop:
+			}
+		}()
+		// kills the processes left in the process group
+		defer syscall.Kill(-pid, syscall.SIGKILL)
+
+		var wstatus syscall.WaitStatus
+		for {
+			_, err := syscall.Wait4(pid, &wstatus, 0, nil)
+			if err == syscall.EINTR {
+				continue
+			}
+			if err != nil {
+				p.result = runner.Result{Status: runner.StatusRunnerError, Error: err.Error()}
+				return
+			}
+			break
+		}
+		p.result = e.result(wstatus, time.Since(start))
+	}()
+	return p, nil
+}
+
+// result returns the canned result by the wait status, the run killed by
+// signal reports the measured run time
+func (e *environment) result(wstatus syscall.WaitStatus, runTime time.Duration) runner.Result {
+	rt := runner.Result{
+		Status:      runner.StatusNormal,
+		Time:        e.time,
+		RunningTime: e.time,
+		Memory:      runner.Size(e.memory),
+	}
+	switch {
+	case wstatus.Exited():
+		if status := wstatus.ExitStatus(); status != 0 {
+			rt.Status = runner.StatusNonzeroExitStatus
+			rt.ExitStatus = status
+		}
+	case wstatus.Signaled():
+		sig := wstatus.Signal()
+		rt.ExitStatus = int(sig)
+		rt.RunningTime = runTime
+		if sig == syscall.SIGKILL {
+			rt.Status = runner.StatusTimeLimitExceeded
+		} else {
+			rt.Status = runner.StatusSignalled
+		}
+	}
+	return rt
+}
+
+// lookPath returns the path of the executable, the name without slash is
+// searched in the PATH of the program and the relative path is relative to
+// the work directory
+func (e *environment) lookPath(param envexec.ExecveParam) (string, error) {
+	if param.ExecFile > 0 {
+		if runtime.GOOS == "linux" {
+			return fmt.Sprintf("/proc/self/fd/%d", param.ExecFile), nil
+		}
+		return fmt.Sprintf("/dev/fd/%d", param.ExecFile), nil
+	}
+	name := param.Args[0]
+	if strings.Contains(name, "/") {
+		if !filepath.IsAbs(name) {
+			name = filepath.Join(e.wdPath, name)
+		}
+		return name, nil
+	}
+	var path string
+	for _, env := range param.Env {
+		if v, ok := strings.CutPrefix(env, "PATH="); ok {
+			path = v
+		}
+	}
+	for _, dir := range filepath.SplitList(path) {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(e.wdPath, dir)
+		}
+		p := filepath.Join(dir, name)
+		if fi, err := os.Stat(p); err == nil && fi.Mode().IsRegular() && fi.Mode()&0o111 != 0 {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("execve: %s: %w", name, syscall.ENOENT)
+}
@@ -0,0 +1,12 @@
+package fake
+
+import (
+	"context"
+	"errors"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+func (e *environment) Execve(ctx context.Context, param envexec.ExecveParam) (envexec.Process, error) {
+	return nil, errors.New("fake environment is not supported on windows")
+}
@@ -0,0 +1,72 @@
+package env
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// TestNoInheritedFds lists the fds of the program, only the mapped files and
+// the fd opened by ls itself to read the directory are expected
+func TestNoInheritedFds(t *testing.T) {
+	p := newTestPool(t, func(c *Config) { c.CheckFds = true })
+	m, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Skip("environment is not available: ", err)
+	}
+	defer p.Put(m)
+
+	stdin := func() envexec.File { return envexec.NewFileReader(strings.NewReader(""), false) }
+	for _, c := range []struct {
+		name  string
+		files func(stdout *bytes.Buffer) []envexec.File
+		want  string
+	}{
+		{
+			"stdio", func(stdout *bytes.Buffer) []envexec.File {
+				return []envexec.File{stdin(), envexec.NewFileWriter(stdout, 4096), envexec.NewFileWriter(&bytes.Buffer{}, 4096)}
+			},
+			"0 1 2 3",
+		},
+		{
+			// the gap of fd 2 is /dev/null
+			"extra fd", func(stdout *bytes.Buffer) []envexec.File {
+				return []envexec.File{stdin(), envexec.NewFileWriter(stdout, 4096), nil, envexec.NewFileWriter(&bytes.Buffer{}, 4096)}
+			},
+			"0 1 2 3 4",
+		},
+		{
+			// stderr is closed rather than inherited from the container init
+			"no stderr", func(stdout *bytes.Buffer) []envexec.File {
+				return []envexec.File{stdin(), envexec.NewFileWriter(stdout, 4096)}
+			},
+			"0 1 2",
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+			r, err := (&envexec.Single{Cmd: &envexec.Cmd{
+				Environment: m,
+				Args:        []string{"/bin/ls", "/proc/self/fd"},
+				Files:       c.files(&stdout),
+				TimeLimit:   time.Second,
+				MemoryLimit: 64 << 20,
+				ProcLimit:   16,
+				Waiter:      sampleWaiter,
+			}}).Run(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r.Status != envexec.StatusAccepted {
+				t.Fatalf("%v: %s", r.Status, r.Error)
+			}
+			if fds := strings.Join(strings.Fields(stdout.String()), " "); fds != c.want {
+				t.Fatalf("fds %q, want %q", fds, c.want)
+			}
+		})
+	}
+}
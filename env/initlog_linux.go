@@ -0,0 +1,14 @@
+package env
+
+import "github.com/criyle/go-judge/env/linuxcontainer"
+
+// InitLogs returns the stderr of the container init kept by each environment
+// not destroyed, in order of creation
+func InitLogs() []InitLogStat {
+	logs := linuxcontainer.InitLogs()
+	rt := make([]InitLogStat, 0, len(logs))
+	for _, l := range logs {
+		rt = append(rt, InitLogStat{ID: l.ID, Log: l.Log, Dropped: l.Dropped})
+	}
+	return rt
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package env
+
+// InitLogs returns nothing since the environments have no container init on
+// this platform
+func InitLogs() []InitLogStat {
+	return nil
+}
@@ -0,0 +1,133 @@
+package env
+
+import (
+	"errors"
+	"os"
+	"strings"
+)
+
+// IsolationProbe detects the isolation features provided by the host, it
+// could be replaced by Config.Probe to simulate missing features
+type IsolationProbe interface {
+	// CgroupControllers returns the controllers of the cgroups that could be
+	// created under the prefix
+	CgroupControllers(prefix string) (map[string]bool, error)
+	// Namespace returns nil if the namespace (e.g. net) could be unshared
+	Namespace(name string) error
+	// Root reports whether the server is privileged to run containers by
+	// the other credentials
+	Root() bool
+	// SwapAccounting returns nil if the swap usage is limited together with
+	// the memory usage (or there is no swap)
+	SwapAccounting() error
+	// Seccomp returns nil if the seccomp filters could be loaded
+	Seccomp() error
+}
+
+// IsolationFeature reports whether an isolation feature requested by the
+// config is active
+type IsolationFeature struct {
+	Name   string `json:"name"`
+	Active bool   `json:"active"`
+	Detail string `json:"detail,omitempty"` // reason if not active
+}
+
+// IsolationReport lists the isolation features requested by the config
+type IsolationReport []IsolationFeature
+
+// isolationNamespaces are the namespaces unshared by the container
+var isolationNamespaces = []string{"cgroup", "ipc", "mnt", "net", "pid", "user", "uts"}
+
+// CheckIsolation checks the isolation features requested by the config by
+// c.Probe, or the host if it is nil. It returns nil on the platforms without
+// the isolation features.
+func CheckIsolation(c Config) IsolationReport {
+	p := c.Probe
+	if p == nil {
+		p = newHostProbe()
+	}
+	if p == nil {
+		return nil
+	}
+	var rt IsolationReport
+	add := func(name string, err error) {
+		f := IsolationFeature{Name: name, Active: err == nil}
+		if err != nil {
+			f.Detail = err.Error()
+		}
+		rt = append(rt, f)
+	}
+
+	controllers := []string{"cpuacct", "memory", "pids", "cpuset"}
+	if c.EnableCPURate {
+		controllers = append(controllers, "cpu")
+	}
+	m, cgErr := p.CgroupControllers(c.CgroupPrefix)
+	for _, name := range controllers {
+		err := cgErr
+		if err == nil && !m[name] {
+			err = errNotEnabled
+		}
+		add("cgroup "+name, err)
+	}
+	if cgErr == nil && m["memory"] {
+		add("memory swap accounting", p.SwapAccounting())
+	}
+
+	for _, name := range isolationNamespaces {
+		if name == "net" && c.NetShare {
+			continue
+		}
+		add("namespace "+name, p.Namespace(name))
+	}
+
+	if c.ContainerCredStart > 0 {
+		var err error
+		if !p.Root() {
+			err = errNotRoot
+		}
+		add("container credential", err)
+	}
+
+	if c.SeccompConf != "" {
+		if _, err := os.Stat(c.SeccompConf); err == nil {
+			add("seccomp", p.Seccomp())
+		}
+	}
+	return rt
+}
+
+// Degraded returns the names of the features not active
+func (r IsolationReport) Degraded() []string {
+	rt := make([]string, 0)
+	for _, f := range r {
+		if !f.Active {
+			rt = append(rt, f.Name)
+		}
+	}
+	return rt
+}
+
+// String prints the report as a checklist
+func (r IsolationReport) String() string {
+	var sb strings.Builder
+	for _, f := range r {
+		if f.Active {
+			sb.WriteString("[x] ")
+		} else {
+			sb.WriteString("[ ] ")
+		}
+		sb.WriteString(f.Name)
+		if f.Detail != "" {
+			sb.WriteString(": ")
+			sb.WriteString(f.Detail)
+		}
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+var (
+	errNotEnabled = errors.New("not enabled")
+	errNotRoot    = errors.New("not running as root")
+)
@@ -0,0 +1,106 @@
+package env
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/criyle/go-sandbox/pkg/cgroup"
+	"golang.org/x/sys/unix"
+)
+
+type hostProbe struct{}
+
+func newHostProbe() IsolationProbe {
+	return hostProbe{}
+}
+
+// CgroupControllers tests a cgroup created in the same way as the builder
+func (hostProbe) CgroupControllers(prefix string) (map[string]bool, error) {
+	t := cgroup.DetectType()
+	cgb, err := cgroup.NewBuilder(prefix).WithType(t).WithCPUAcct().WithMemory().WithPids().WithCPUSet().WithCPU().FilterByEnv()
+	if err != nil {
+		return nil, err
+	}
+	cg, err := cgb.Random("")
+	if err != nil {
+		return nil, err
+	}
+	cg.Destroy()
+	return map[string]bool{
+		// cpu usage is always accounted by cpu.stat in cgroup v2
+		"cpuacct": cgb.CPUAcct || t == cgroup.CgroupTypeV2,
+		"memory":  cgb.Memory,
+		"pids":    cgb.Pids,
+		"cpuset":  cgb.CPUSet,
+		"cpu":     cgb.CPU,
+	}, nil
+}
+
+func (hostProbe) Namespace(name string) error {
+	if _, err := os.Stat(filepath.Join("/proc/self/ns", name)); err != nil {
+		return errors.New("not supported by the kernel")
+	}
+	if name == "user" {
+		b, err := os.ReadFile("/proc/sys/user/max_user_namespaces")
+		if err == nil && strings.TrimSpace(string(b)) == "0" {
+			return errors.New("disabled by user.max_user_namespaces")
+		}
+	}
+	return nil
+}
+
+func (hostProbe) Root() bool {
+	return os.Getuid() == 0
+}
+
+func (hostProbe) SwapAccounting() error {
+	// the swap usage is not limited without swap
+	if b, err := os.ReadFile("/proc/swaps"); err == nil && bytes.Count(bytes.TrimSpace(b), []byte{'\n'}) == 0 {
+		return nil
+	}
+	switch cgroup.DetectType() {
+	case cgroup.CgroupTypeV2:
+		if swapMaxV2() {
+			return nil
+		}
+	default:
+		if _, err := os.Stat("/sys/fs/cgroup/memory/memory.memsw.limit_in_bytes"); err == nil {
+			return nil
+		}
+	}
+	return errors.New("not enabled (swapaccount=1)")
+}
+
+// swapMaxV2 reports whether memory.swap.max exists in the cgroup of the
+// process or, if it is the root, one of its children
+func swapMaxV2() bool {
+	b, err := os.ReadFile("/proc/self/cgroup")
+	if err != nil {
+		return false
+	}
+	dir := "/sys/fs/cgroup"
+	for _, l := range strings.Split(string(b), "\n") {
+		if p, ok := strings.CutPrefix(l, "0::"); ok {
+			dir = filepath.Join(dir, p)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "memory.swap.max")); err == nil {
+		return true
+	}
+	m, _ := filepath.Glob(filepath.Join(dir, "*", "memory.swap.max"))
+	return len(m) > 0
+}
+
+func (hostProbe) Seccomp() error {
+	if !seccompBuilt {
+		return errors.New("not built with the seccomp build tag")
+	}
+	if _, err := unix.PrctlRetInt(unix.PR_GET_SECCOMP, 0, 0, 0, 0); err != nil {
+		return fmt.Errorf("not supported by the kernel: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !linux && !windows
+
+package env
+
+// newHostProbe returns nil since the isolation features are not checked on
+// this platform
+func newHostProbe() IsolationProbe {
+	return nil
+}
@@ -0,0 +1,152 @@
+package env
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeProbe simulates the host with the features listed missing
+type fakeProbe struct {
+	controllers map[string]bool
+	cgroupErr   error
+	namespaces  map[string]error
+	root        bool
+	swapErr     error
+	seccompErr  error
+}
+
+func (p *fakeProbe) CgroupControllers(string) (map[string]bool, error) {
+	return p.controllers, p.cgroupErr
+}
+
+func (p *fakeProbe) Namespace(name string) error { return p.namespaces[name] }
+func (p *fakeProbe) Root() bool                  { return p.root }
+func (p *fakeProbe) SwapAccounting() error       { return p.swapErr }
+func (p *fakeProbe) Seccomp() error              { return p.seccompErr }
+
+// fullProbe simulates the host with every feature
+func fullProbe() *fakeProbe {
+	return &fakeProbe{
+		controllers: map[string]bool{"cpu": true, "cpuacct": true, "cpuset": true, "memory": true, "pids": true},
+		root:        true,
+	}
+}
+
+func TestCheckIsolation(t *testing.T) {
+	seccomp := filepath.Join(t.TempDir(), "seccomp.yaml")
+	if err := os.WriteFile(seccomp, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	errMissing := errors.New("missing")
+
+	for _, c := range []struct {
+		name     string
+		conf     Config
+		probe    func(*fakeProbe)
+		degraded []string
+	}{
+		{"all active", Config{EnableCPURate: true, ContainerCredStart: 10000, SeccompConf: seccomp}, nil, []string{}},
+		{
+			"cpu controller", Config{EnableCPURate: true},
+			func(p *fakeProbe) { p.controllers["cpu"] = false },
+			[]string{"cgroup cpu"},
+		},
+		{
+			"cpu controller not requested", Config{},
+			func(p *fakeProbe) { p.controllers["cpu"] = false },
+			[]string{},
+		},
+		{
+			// swap accounting is not checked without the memory controller
+			"memory controller", Config{},
+			func(p *fakeProbe) { p.controllers["memory"] = false; p.swapErr = errMissing },
+			[]string{"cgroup memory"},
+		},
+		{
+			"swap accounting", Config{},
+			func(p *fakeProbe) { p.swapErr = errMissing },
+			[]string{"memory swap accounting"},
+		},
+		{
+			"no cgroup", Config{},
+			func(p *fakeProbe) { p.cgroupErr = errMissing },
+			[]string{"cgroup cpuacct", "cgroup memory", "cgroup pids", "cgroup cpuset"},
+		},
+		{
+			"namespace", Config{},
+			func(p *fakeProbe) { p.namespaces = map[string]error{"user": errMissing} },
+			[]string{"namespace user"},
+		},
+		{
+			"net namespace shared", Config{NetShare: true},
+			func(p *fakeProbe) { p.namespaces = map[string]error{"net": errMissing} },
+			[]string{},
+		},
+		{
+			"not root", Config{ContainerCredStart: 10000},
+			func(p *fakeProbe) { p.root = false },
+			[]string{"container credential"},
+		},
+		{
+			"credential not requested", Config{},
+			func(p *fakeProbe) { p.root = false },
+			[]string{},
+		},
+		{
+			"seccomp", Config{SeccompConf: seccomp},
+			func(p *fakeProbe) { p.seccompErr = errMissing },
+			[]string{"seccomp"},
+		},
+		{
+			// the seccomp config is optional
+			"seccomp config missing", Config{SeccompConf: seccomp + ".missing"},
+			func(p *fakeProbe) { p.seccompErr = errMissing },
+			[]string{},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			p := fullProbe()
+			if c.probe != nil {
+				c.probe(p)
+			}
+			c.conf.Probe = p
+			r := CheckIsolation(c.conf)
+			if d := r.Degraded(); !reflect.DeepEqual(d, c.degraded) {
+				t.Fatalf("degraded %v, want %v\n%s", d, c.degraded, r)
+			}
+			for _, name := range c.degraded {
+				if !strings.Contains(r.String(), "[ ] "+name+": ") {
+					t.Fatalf("%s is not listed with the reason\n%s", name, r)
+				}
+			}
+		})
+	}
+}
+
+func TestIsolationReportString(t *testing.T) {
+	r := CheckIsolation(Config{
+		Probe: &fakeProbe{
+			controllers: map[string]bool{"cpuacct": true, "pids": true, "cpuset": true},
+			namespaces:  map[string]error{"cgroup": errors.New("unsupported")},
+		},
+		NetShare: true,
+	})
+	want := `[x] cgroup cpuacct
+[ ] cgroup memory: not enabled
+[x] cgroup pids
+[x] cgroup cpuset
+[ ] namespace cgroup: unsupported
+[x] namespace ipc
+[x] namespace mnt
+[x] namespace pid
+[x] namespace user
+[x] namespace uts
+`
+	if s := r.String(); s != want {
+		t.Fatalf("got\n%s\nwant\n%s", s, want)
+	}
+}
@@ -0,0 +1,39 @@
+package env
+
+import "errors"
+
+// windowsProbe reports the features of the linux container as not active,
+// the limits are enforced by the job object instead of cgroup and the
+// programs are not isolated by namespaces or seccomp
+type windowsProbe struct{}
+
+var (
+	errJobObject   = errors.New("not supported on windows, cpu time, memory and process count are limited by job object")
+	errUnsupported = errors.New("not supported on windows")
+)
+
+func newHostProbe() IsolationProbe {
+	return windowsProbe{}
+}
+
+func (windowsProbe) CgroupControllers(prefix string) (map[string]bool, error) {
+	return nil, errJobObject
+}
+
+func (windowsProbe) Namespace(name string) error {
+	return errUnsupported
+}
+
+// Root returns true since the programs run by the restricted token rather
+// than the container credential
+func (windowsProbe) Root() bool {
+	return true
+}
+
+func (windowsProbe) SwapAccounting() error {
+	return errUnsupported
+}
+
+func (windowsProbe) Seccomp() error {
+	return errUnsupported
+}
@@ -0,0 +1,131 @@
+package linuxcontainer
+
+import (
+	"encoding/binary"
+	"io"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/container"
+	"golang.org/x/sys/unix"
+)
+
+// The address space of 32-bit programs is at most 4 GiB. The mmap area is
+// placed below the stack gap sized by the stack limit, so that a stack limit
+// as large as the memory limit leaves little space for the heap.
+const (
+	arch32AddressSpace = 4 << 30
+	arch32StackMax     = 1 << 30
+)
+
+// ELF header fields
+const (
+	elfHeaderSize = 20 // up to e_machine
+	elfClass32    = 1
+	elfClass64    = 2
+	elfData2LSB   = 1
+
+	emI386    = 3
+	emARM     = 40
+	emX86_64  = 62
+	emAARCH64 = 183
+	emRISCV   = 243
+)
+
+var hostArch = map[string]string{
+	"386":     envexec.ArchX86,
+	"amd64":   envexec.ArchX64,
+	"arm":     envexec.ArchARM,
+	"arm64":   envexec.ArchARM64,
+	"riscv64": envexec.ArchRISCV64,
+}[runtime.GOARCH]
+
+// elfArch returns the architecture from the ELF header, empty if it is not
+// an ELF file (e.g. script) or not known
+func elfArch(h []byte) string {
+	if len(h) < elfHeaderSize || string(h[:4]) != "\x7fELF" {
+		return ""
+	}
+	var order binary.ByteOrder = binary.BigEndian
+	if h[5] == elfData2LSB {
+		order = binary.LittleEndian
+	}
+	class := h[4]
+	switch order.Uint16(h[18:]) {
+	case emI386:
+		return envexec.ArchX86
+	case emX86_64:
+		if class == elfClass32 {
+			return envexec.ArchX32
+		}
+		return envexec.ArchX64
+	case emARM:
+		return envexec.ArchARM
+	case emAARCH64:
+		return envexec.ArchARM64
+	case emRISCV:
+		if class == elfClass64 {
+			return envexec.ArchRISCV64
+		}
+	}
+	return ""
+}
+
+// detectArch reads the ELF header of the executable inside the container,
+// it is looked up in the work directory and PATH in the same way as execve
+func (c *environ) detectArch(param envexec.ExecveParam) string {
+	h := make([]byte, elfHeaderSize)
+	if param.ExecFile > 0 {
+		n, err := unix.Pread(int(param.ExecFile), h, 0)
+		if err != nil {
+			return ""
+		}
+		return elfArch(h[:n])
+	}
+	if len(param.Args) == 0 {
+		return ""
+	}
+	for _, p := range execCandidates(param.Args[0], param.Env) {
+		fs, err := c.Environment.Open([]container.OpenCmd{{
+			Path: p,
+			// fifo must not block the container
+			Flag: syscall.O_RDONLY | syscall.O_CLOEXEC | syscall.O_NONBLOCK,
+		}})
+		if err != nil {
+			continue
+		}
+		n, _ := io.ReadFull(fs[0], h)
+		fs[0].Close()
+		return elfArch(h[:n])
+	}
+	return ""
+}
+
+// execCandidates returns the paths that execve would try for name
+func execCandidates(name string, env []string) []string {
+	if filepath.Base(name) != name {
+		return []string{name}
+	}
+	rt := []string{name}
+	for i := len(env) - 1; i >= 0; i-- {
+		if p, ok := strings.CutPrefix(env[i], "PATH="); ok {
+			for _, dir := range filepath.SplitList(p) {
+				if dir == "" {
+					dir = "."
+				}
+				rt = append(rt, filepath.Join(dir, name))
+			}
+			break
+		}
+	}
+	return rt
+}
+
+// archUnsupported reports the run failed as the host could not execute the
+// foreign architecture (e.g. without IA32 emulation)
+func archUnsupported(arch string, err string) bool {
+	return arch != "" && arch != hostArch && strings.Contains(err, syscall.ENOEXEC.Error())
+}
@@ -8,11 +8,13 @@ var _ CgroupPool = &FakeCgroupPool{}
 type FakeCgroupPool struct {
 	builder   CgroupBuilder
 	cfsPeriod time.Duration
+	io        *IOController
 }
 
-// NewFakeCgroupPool creates FakeCgroupPool
-func NewFakeCgroupPool(builder CgroupBuilder, cfsPeriod time.Duration) CgroupPool {
-	return &FakeCgroupPool{builder: builder, cfsPeriod: cfsPeriod}
+// NewFakeCgroupPool creates FakeCgroupPool, io accounting is enabled if io is
+// not nil
+func NewFakeCgroupPool(builder CgroupBuilder, cfsPeriod time.Duration, io *IOController) CgroupPool {
+	return &FakeCgroupPool{builder: builder, cfsPeriod: cfsPeriod, io: io}
 }
 
 // Get gets new cgroup
@@ -21,7 +23,7 @@ func (f *FakeCgroupPool) Get() (Cgroup, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &wCgroup{cg: cg, cfsPeriod: f.cfsPeriod}, nil
+	return newCgroup(cg, f.cfsPeriod, f.io)
 }
 
 // Put destroy the cgroup
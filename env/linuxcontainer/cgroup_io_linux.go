@@ -0,0 +1,181 @@
+package linuxcontainer
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/pkg/cgroup"
+)
+
+var errIONotAvailable = errors.New("io accounting is not available")
+
+// v1AccountingBps is set as the throttle limit of every block device for the
+// blkio cgroup, since the throttle stats of cgroup v1 only account the bios
+// of devices with a throttle rule on recent kernels. It is high enough not to
+// slow down anything.
+const v1AccountingBps = "1125899906842624" // 1 PiB/s
+
+// IOController accounts the bytes read and written to block devices by each
+// cgroup through the io controller (v2) or the blkio controller (v1). Since
+// the cgroup builder does not create blkio cgroups for v1, a blkio cgroup is
+// created along with each cgroup under the same prefix.
+type IOController struct {
+	v1Path    string   // blkio path of the prefix for cgroup v1, empty for v2
+	v1Devices []string // major:minor of block devices for cgroup v1
+}
+
+// NewIOController enables the io controller for the cgroups under the prefix,
+// the prefix must have been created by the cgroup builder
+func NewIOController(t cgroup.CgroupType, prefix string) (*IOController, error) {
+	switch t {
+	case cgroup.CgroupTypeV2:
+		// controller is enabled for the children by subtree_control of each
+		// ancestor
+		current := cgroupV1Base
+		for _, e := range strings.Split(prefix, "/") {
+			if err := os.WriteFile(path.Join(current, "cgroup.subtree_control"), []byte("+io"), 0644); err != nil {
+				return nil, fmt.Errorf("io controller: failed to enable at %s: %w", current, err)
+			}
+			current = path.Join(current, e)
+		}
+		return &IOController{}, nil
+
+	case cgroup.CgroupTypeV1:
+		p := path.Join(cgroupV1Base, "blkio", prefix)
+		if err := os.MkdirAll(p, 0755); err != nil {
+			return nil, fmt.Errorf("io controller: failed to create blkio cgroup %w", err)
+		}
+		devices, err := blockDevices()
+		if err != nil {
+			return nil, fmt.Errorf("io controller: failed to list block devices %w", err)
+		}
+		return &IOController{v1Path: p, v1Devices: devices}, nil
+
+	default:
+		return nil, fmt.Errorf("io controller: unknown cgroup type %v", t)
+	}
+}
+
+// newCgroup wraps the cgroup built by the builder, creating the blkio cgroup
+// of the same name for cgroup v1 if io is not nil
+func newCgroup(cg cgroup.Cgroup, cfsPeriod time.Duration, io *IOController) (*wCgroup, error) {
+	c := &wCgroup{id: atomic.AddUint64(&cgroupID, 1), cg: cg, cfsPeriod: cfsPeriod, io: io}
+	if io == nil || io.v1Path == "" {
+		return c, nil
+	}
+	p, err := os.MkdirTemp(io.v1Path, "")
+	if err != nil {
+		cg.Destroy()
+		return nil, fmt.Errorf("io controller: failed to create blkio cgroup %w", err)
+	}
+	// best effort, bios of devices without the rule are not accounted
+	for _, d := range io.v1Devices {
+		for _, f := range []string{"blkio.throttle.read_bps_device", "blkio.throttle.write_bps_device"} {
+			os.WriteFile(filepath.Join(p, f), []byte(d+" "+v1AccountingBps), 0644)
+		}
+	}
+	c.blkioPath = p
+	return c, nil
+}
+
+// blockDevices returns major:minor of the block devices in /sys/block, bios
+// to partitions are accounted on the whole device
+func blockDevices() ([]string, error) {
+	entries, err := os.ReadDir("/sys/block")
+	if err != nil {
+		return nil, err
+	}
+	var rt []string
+	for _, e := range entries {
+		b, err := os.ReadFile(filepath.Join("/sys/block", e.Name(), "dev"))
+		if err != nil {
+			continue
+		}
+		rt = append(rt, strings.TrimSpace(string(b)))
+	}
+	return rt, nil
+}
+
+// readIOStat reads the bytes accumulated since the creation of the cgroup
+func (c *wCgroup) readIOStat() (envexec.IOStat, error) {
+	if c.io == nil {
+		return envexec.IOStat{}, errIONotAvailable
+	}
+	if c.blkioPath != "" {
+		b, err := os.ReadFile(filepath.Join(c.blkioPath, "blkio.throttle.io_service_bytes"))
+		if err != nil {
+			return envexec.IOStat{}, err
+		}
+		return parseBlkioServiceBytes(b), nil
+	}
+	cg, ok := c.cg.(*cgroup.CgroupV2)
+	if !ok {
+		return envexec.IOStat{}, errIONotAvailable
+	}
+	b, err := cg.ReadFile("io.stat")
+	if err != nil {
+		return envexec.IOStat{}, err
+	}
+	return parseIOStat(b), nil
+}
+
+// parseIOStat sums rbytes and wbytes of all devices in io.stat (v2), e.g.
+// `8:0 rbytes=1024 wbytes=4096 rios=1 wios=1 dbytes=0 dios=0`
+func parseIOStat(b []byte) envexec.IOStat {
+	var rt envexec.IOStat
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) == 0 {
+			continue
+		}
+		for _, kv := range f[1:] {
+			k, v, _ := strings.Cut(kv, "=")
+			n, err := strconv.ParseUint(v, 10, 64)
+			if err != nil {
+				continue
+			}
+			switch k {
+			case "rbytes":
+				rt.Read += envexec.Size(n)
+			case "wbytes":
+				rt.Write += envexec.Size(n)
+			}
+		}
+	}
+	return rt
+}
+
+// parseBlkioServiceBytes sums Read and Write of all devices in
+// blkio.throttle.io_service_bytes (v1), e.g. `8:0 Read 1024`
+func parseBlkioServiceBytes(b []byte) envexec.IOStat {
+	var rt envexec.IOStat
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		f := strings.Fields(s.Text())
+		if len(f) != 3 {
+			continue
+		}
+		n, err := strconv.ParseUint(f[2], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch f[1] {
+		case "Read":
+			rt.Read += envexec.Size(n)
+		case "Write":
+			rt.Write += envexec.Size(n)
+		}
+	}
+	return rt
+}
@@ -0,0 +1,133 @@
+package linuxcontainer
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/criyle/go-sandbox/pkg/mount"
+)
+
+const (
+	cgroupMountTarget = "sys/fs/cgroup"
+
+	// envCgroupRun is the cgroup of each run under the environment cgroup
+	envCgroupRun = "run"
+)
+
+// CgroupMount creates a dedicated cgroup for each environment so that the
+// container could have a read-only view of its own cgroup at /sys/fs/cgroup.
+// Only cgroup v2 is supported since the cgroup must be a single directory.
+type CgroupMount struct {
+	Builder   CgroupBuilder
+	Path      string // host path of the cgroup prefix (e.g. /sys/fs/cgroup/executor_server)
+	CfsPeriod time.Duration
+	IO        *IOController // io accounting if not nil
+}
+
+// build creates the dedicated cgroup together with the bind mount for it
+func (m *CgroupMount) build() (*envCgroup, mount.Mount, error) {
+	for range [50]struct{}{} {
+		name := "env" + strconv.Itoa(int(rand.Int31()))
+		cg, err := m.Builder.Build(name)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return nil, mount.Mount{}, err
+		}
+		wcg, err := newCgroup(cg, m.CfsPeriod, nil)
+		if err != nil {
+			cg.Destroy()
+			return nil, mount.Mount{}, err
+		}
+		if err := m.enableControllers(wcg); err != nil {
+			wcg.Destroy()
+			return nil, mount.Mount{}, err
+		}
+		return &envCgroup{wCgroup: wcg, m: m, name: name}, mount.Mount{
+			Source: path.Join(m.Path, name),
+			Target: cgroupMountTarget,
+			Flags:  syscall.MS_BIND | syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_NOEXEC | syscall.MS_RDONLY,
+		}, nil
+	}
+	return nil, mount.Mount{}, fmt.Errorf("cgroup mount: failed to create unique cgroup")
+}
+
+// enableControllers enables the controllers of the environment cgroup for the
+// cgroups of the runs under it
+func (m *CgroupMount) enableControllers(c *wCgroup) error {
+	b, err := os.ReadFile(path.Join(m.Path, "cgroup.subtree_control"))
+	if err != nil {
+		return err
+	}
+	ctrl := strings.Fields(string(b))
+	if len(ctrl) == 0 {
+		return nil
+	}
+	w, ok := c.cg.(cgroupFileWriter)
+	if !ok {
+		return fmt.Errorf("cgroup mount: %T is not cgroup v2", c.cg)
+	}
+	return w.WriteFile("cgroup.subtree_control", []byte("+"+strings.Join(ctrl, " +")))
+}
+
+var (
+	_ Cgroup     = &envCgroup{}
+	_ CgroupPool = &envCgroup{}
+)
+
+// envCgroup is the cgroup dedicated to an environment and mounted inside its
+// container. Since the cgroup counters (e.g. memory.peak, pids.events) cannot
+// be cleared, each run gets a new cgroup under it (visible as
+// /sys/fs/cgroup/run inside the container) which is removed after the run.
+// The limits of the run are also set on the environment cgroup, so that they
+// are found at the root of the view, while it never holds any process.
+type envCgroup struct {
+	*wCgroup
+	m    *CgroupMount
+	name string
+}
+
+type cgroupFileWriter interface {
+	WriteFile(name string, content []byte) error
+}
+
+// cgroupFileReader reads the files of the cgroup, e.g. cgroup v2
+type cgroupFileReader interface {
+	ReadFile(name string) ([]byte, error)
+}
+
+// Get creates the cgroup of the run
+func (c *envCgroup) Get() (Cgroup, error) {
+	cg, err := c.m.Builder.Build(path.Join(c.name, envCgroupRun))
+	if err != nil {
+		return nil, err
+	}
+	return newCgroup(cg, c.m.CfsPeriod, c.m.IO)
+}
+
+// Put removes the cgroup of the run
+func (c *envCgroup) Put(cg Cgroup) {
+	cg.Destroy()
+}
+
+// Reset removes the cgroup of the previous run if it failed to be removed and
+// clears the limits left by the previous run
+func (c *envCgroup) Reset() error {
+	if err := os.Remove(path.Join(c.m.Path, c.name, envCgroupRun)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if w, ok := c.cg.(cgroupFileWriter); ok {
+		w.WriteFile("cpu.max", []byte("max"))
+		w.WriteFile("cpuset.cpus", nil)
+		w.WriteFile("cpuset.mems", nil)
+	}
+	return nil
+}
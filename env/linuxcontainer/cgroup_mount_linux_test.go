@@ -0,0 +1,152 @@
+package linuxcontainer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/pkg/cgroup"
+)
+
+// dirCgroup is a cgroup backed by a plain directory, the values set are
+// written to the files of the same name
+type dirCgroup struct {
+	dir string
+	b   *dirCgroupBuilder
+}
+
+func (c *dirCgroup) WriteFile(name string, content []byte) error {
+	return os.WriteFile(filepath.Join(c.dir, name), content, 0644)
+}
+
+func (c *dirCgroup) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(c.dir, name))
+}
+
+func (c *dirCgroup) readUint(name string) (uint64, error) {
+	b, err := os.ReadFile(filepath.Join(c.dir, name))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(b), 10, 64)
+}
+
+func (c *dirCgroup) writeUint(name string, v uint64) error {
+	return c.WriteFile(name, []byte(strconv.FormatUint(v, 10)))
+}
+
+func (c *dirCgroup) AddProc(pid int) error {
+	c.b.added = append(c.b.added, c.dir)
+	return c.writeUint("cgroup.procs", uint64(pid))
+}
+
+func (c *dirCgroup) Destroy() error                    { return os.RemoveAll(c.dir) }
+func (c *dirCgroup) CPUUsage() (uint64, error)         { return c.readUint("cpu.usage") }
+func (c *dirCgroup) MemoryUsage() (uint64, error)      { return c.readUint("memory.current") }
+func (c *dirCgroup) MemoryMaxUsage() (uint64, error)   { return c.readUint("memory.peak") }
+func (c *dirCgroup) SetCPUBandwidth(_, _ uint64) error { return nil }
+func (c *dirCgroup) SetCPUSet(b []byte) error          { return c.WriteFile("cpuset.cpus", b) }
+func (c *dirCgroup) SetMemoryLimit(v uint64) error     { return c.writeUint("memory.max", v) }
+func (c *dirCgroup) SetProcLimit(v uint64) error       { return c.writeUint("pids.max", v) }
+
+// dirCgroupBuilder builds dirCgroup under base and records the cgroups of
+// the processes added
+type dirCgroupBuilder struct {
+	base  string
+	added []string
+}
+
+func (b *dirCgroupBuilder) Random(string) (cgroup.Cgroup, error) {
+	p, err := os.MkdirTemp(b.base, "")
+	if err != nil {
+		return nil, err
+	}
+	// the counters of a new cgroup start from zero
+	c := &dirCgroup{dir: p, b: b}
+	if err := c.WriteFile("cpu.stat", []byte("nr_periods 0\nnr_throttled 0\nthrottled_usec 0\n")); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (b *dirCgroupBuilder) Build(name string) (cgroup.Cgroup, error) {
+	p := filepath.Join(b.base, name)
+	if err := os.Mkdir(p, 0755); err != nil {
+		return nil, err
+	}
+	return &dirCgroup{dir: p, b: b}, nil
+}
+
+func TestCgroupMountRun(t *testing.T) {
+	base := t.TempDir()
+	if err := os.WriteFile(filepath.Join(base, "cgroup.subtree_control"), []byte("cpu memory pids\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m := &CgroupMount{Builder: &dirCgroupBuilder{base: base}, Path: base}
+	cg, mt, err := m.build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	envDir := filepath.Join(base, cg.name)
+	runDir := filepath.Join(envDir, envCgroupRun)
+	if mt.Source != envDir || mt.Target != cgroupMountTarget {
+		t.Fatalf("mount %s at %s, want %s", mt.Source, mt.Target, envDir)
+	}
+	if b, _ := os.ReadFile(filepath.Join(envDir, "cgroup.subtree_control")); string(b) != "+cpu +memory +pids" {
+		t.Fatalf("controllers %q enabled for the runs", b)
+	}
+
+	// each run gets a new cgroup so that its peak memory is its own
+	for _, peak := range []uint64{200, 100} {
+		run, err := cg.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := run.MaxMemory(); err == nil {
+			t.Fatal("peak memory of the previous run is kept")
+		}
+		run.(*wCgroup).cg.(*dirCgroup).writeUint("memory.peak", peak)
+		if m, err := run.MaxMemory(); err != nil || m != envexec.Size(peak) {
+			t.Fatalf("peak memory %v %v, want %d", m, err, peak)
+		}
+		cg.Put(run)
+		if _, err := os.Stat(runDir); !os.IsNotExist(err) {
+			t.Fatalf("cgroup of the run is not removed: %v", err)
+		}
+	}
+
+	// the limits are set on both the environment cgroup and the run
+	ct := &testContainer{}
+	env := &environ{Environment: ct, cgEnv: cg}
+	p, err := env.Execve(context.Background(), envexec.ExecveParam{Limit: envexec.Limit{Memory: 64 << 20, Proc: 8}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-p.Done()
+	if added := m.Builder.(*dirCgroupBuilder).added; len(added) != 1 || added[0] != runDir {
+		t.Fatalf("process is added to %v, want %s", added, runDir)
+	}
+	if v, err := (&dirCgroup{dir: envDir}).readUint("memory.max"); err != nil || v != 64<<20 {
+		t.Fatalf("memory limit of the environment cgroup %d %v", v, err)
+	}
+	if _, err := os.Stat(runDir); !os.IsNotExist(err) {
+		t.Fatalf("cgroup of the run is not removed: %v", err)
+	}
+
+	// a left cgroup of the run is removed on reset
+	if err := os.Mkdir(runDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := cg.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(runDir); !os.IsNotExist(err) {
+		t.Fatalf("cgroup of the run is not removed on reset: %v", err)
+	}
+	if err := cg.Destroy(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,6 +1,15 @@
 package linuxcontainer
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 	"time"
 
 	"github.com/criyle/go-judge/envexec"
@@ -11,9 +20,35 @@ var (
 	_ Cgroup = &wCgroup{}
 )
 
+const cgroupV1Base = "/sys/fs/cgroup"
+
+var errPeakProcNotAvailable = errors.New("pids.peak is not available in cgroup v1")
+
+// cgroupID is the id of the last cgroup created
+var cgroupID uint64
+
 type wCgroup struct {
+	id        uint64
 	cg        cgroup.Cgroup
 	cfsPeriod time.Duration
+
+	// pids and cpu controller path of cgroup v1, found from the first
+	// process added
+	pidsPath string
+	cpuPath  string
+
+	// cpu throttling counters at the last reset
+	cpuBase envexec.CPUThrottling
+
+	// io accounting, blkioPath is the blkio cgroup for cgroup v1 and ioBase
+	// is the stat at the last reset
+	io        *IOController
+	blkioPath string
+	ioBase    envexec.IOStat
+}
+
+func (c *wCgroup) ID() uint64 {
+	return c.id
 }
 
 func (c *wCgroup) SetCPURate(s uint64) error {
@@ -25,6 +60,17 @@ func (c *wCgroup) SetCpuset(s string) error {
 	return c.cg.SetCPUSet([]byte(s))
 }
 
+func (c *wCgroup) SetCpusetMems(s string) error {
+	switch cg := c.cg.(type) {
+	case *cgroup.CgroupV2:
+		return cg.WriteFile("cpuset.mems", []byte(s))
+	case *cgroup.CgroupV1:
+		return cg.SetCpusetMems([]byte(s))
+	default:
+		return fmt.Errorf("cpuset.mems is not supported for %T", c.cg)
+	}
+}
+
 func (c *wCgroup) SetMemoryLimit(s envexec.Size) error {
 	return c.cg.SetMemoryLimit(uint64(s))
 }
@@ -48,14 +94,267 @@ func (c *wCgroup) MaxMemory() (envexec.Size, error) {
 	return envexec.Size(s), err
 }
 
+// MemoryStat reads memory.stat, fields of cgroup v2 are mapped to the v1 names
+func (c *wCgroup) MemoryStat() (envexec.MemoryStat, error) {
+	switch cg := c.cg.(type) {
+	case *cgroup.CgroupV1:
+		var v [5]uint64
+		for i, p := range []string{"cache", "rss", "mapped_file", "pgfault", "pgmajfault"} {
+			n, err := cg.FindMemoryStatProperty(p)
+			if err != nil {
+				return envexec.MemoryStat{}, err
+			}
+			v[i] = n
+		}
+		return envexec.MemoryStat{
+			Cache:          envexec.Size(v[0]),
+			RSS:            envexec.Size(v[1]),
+			MappedFile:     envexec.Size(v[2]),
+			PageFault:      v[3],
+			MajorPageFault: v[4],
+		}, nil
+
+	case *cgroup.CgroupV2:
+		b, err := cg.ReadFile("memory.stat")
+		if err != nil {
+			return envexec.MemoryStat{}, err
+		}
+		stat := parseFlatKeyed(b)
+		return envexec.MemoryStat{
+			Cache:          envexec.Size(stat["file"]),
+			RSS:            envexec.Size(stat["anon"]),
+			MappedFile:     envexec.Size(stat["file_mapped"]),
+			PageFault:      stat["pgfault"],
+			MajorPageFault: stat["pgmajfault"],
+		}, nil
+
+	default:
+		return envexec.MemoryStat{}, fmt.Errorf("memory stat is not supported for %T", c.cg)
+	}
+}
+
+// parseFlatKeyed parses the flat keyed cgroup file (e.g. memory.stat)
+func parseFlatKeyed(b []byte) map[string]uint64 {
+	rt := make(map[string]uint64)
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		k, v, ok := strings.Cut(s.Text(), " ")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			rt[k] = n
+		}
+	}
+	return rt
+}
+
+func (c *wCgroup) CurrentProc() (uint64, error) {
+	b, err := c.readPidsFile("pids.current")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(bytes.TrimSpace(b)), 10, 64)
+}
+
+func (c *wCgroup) PeakProc() (uint64, error) {
+	if _, ok := c.cg.(*cgroup.CgroupV2); !ok {
+		return 0, errPeakProcNotAvailable
+	}
+	b, err := c.readPidsFile("pids.peak")
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(bytes.TrimSpace(b)), 10, 64)
+}
+
+func (c *wCgroup) ProcLimitHit() (uint64, error) {
+	b, err := c.readPidsFile("pids.events")
+	if err != nil {
+		return 0, err
+	}
+	return parseFlatKeyed(b)["max"], nil
+}
+
+func (c *wCgroup) Procs() ([]int, error) {
+	b, err := c.readPidsFile("cgroup.procs")
+	if err != nil {
+		return nil, err
+	}
+	var rt []int
+	for _, f := range strings.Fields(string(b)) {
+		pid, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("cgroup.procs: invalid pid %q", f)
+		}
+		rt = append(rt, pid)
+	}
+	return rt, nil
+}
+
+// Kill kills the processes by cgroup.kill (cgroup v2, linux 5.14+) which is
+// not affected by pid reuse, or by signaling each process in cgroup.procs
+func (c *wCgroup) Kill() error {
+	if w, ok := c.cg.(cgroupFileWriter); ok {
+		if err := w.WriteFile("cgroup.kill", []byte("1")); err == nil {
+			return nil
+		}
+	}
+	pids, err := c.Procs()
+	if err != nil {
+		return err
+	}
+	for _, pid := range pids {
+		if err := syscall.Kill(pid, syscall.SIGKILL); err != nil && err != syscall.ESRCH {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *wCgroup) readPidsFile(name string) ([]byte, error) {
+	switch cg := c.cg.(type) {
+	case *cgroup.CgroupV2:
+		return cg.ReadFile(name)
+	case *cgroup.CgroupV1:
+		if c.pidsPath == "" {
+			return nil, cgroup.ErrNotInitialized
+		}
+		return os.ReadFile(filepath.Join(c.pidsPath, name))
+	default:
+		return nil, fmt.Errorf("pids is not supported for %T", c.cg)
+	}
+}
+
+// IOStat reads the bytes read and written to block devices since the last
+// reset
+func (c *wCgroup) IOStat() (envexec.IOStat, error) {
+	s, err := c.readIOStat()
+	if err != nil {
+		return envexec.IOStat{}, err
+	}
+	s.Read -= c.ioBase.Read
+	s.Write -= c.ioBase.Write
+	return s, nil
+}
+
+// CPUThrottling reads the throttling counters of cpu.stat since the last
+// reset, cgroup v1 throttled_time (ns) and v2 throttled_usec are both
+// reported as ThrottledTime
+func (c *wCgroup) CPUThrottling() (envexec.CPUThrottling, error) {
+	s, err := c.readCPUThrottling()
+	if err != nil {
+		return envexec.CPUThrottling{}, err
+	}
+	s.Periods -= c.cpuBase.Periods
+	s.Throttled -= c.cpuBase.Throttled
+	s.ThrottledTime -= c.cpuBase.ThrottledTime
+	return s, nil
+}
+
+func (c *wCgroup) readCPUThrottling() (envexec.CPUThrottling, error) {
+	var b []byte
+	var err error
+	switch cg := c.cg.(type) {
+	case *cgroup.CgroupV1:
+		if c.cpuPath == "" {
+			return envexec.CPUThrottling{}, cgroup.ErrNotInitialized
+		}
+		b, err = os.ReadFile(filepath.Join(c.cpuPath, "cpu.stat"))
+	case cgroupFileReader:
+		b, err = cg.ReadFile("cpu.stat")
+	default:
+		return envexec.CPUThrottling{}, fmt.Errorf("cpu stat is not supported for %T", c.cg)
+	}
+	if err != nil {
+		return envexec.CPUThrottling{}, err
+	}
+	return parseCPUThrottling(b), nil
+}
+
+// parseCPUThrottling parses the throttling counters of cpu.stat of either
+// cgroup v1 or v2
+func parseCPUThrottling(b []byte) envexec.CPUThrottling {
+	stat := parseFlatKeyed(b)
+	rt := envexec.CPUThrottling{
+		Periods:       stat["nr_periods"],
+		Throttled:     stat["nr_throttled"],
+		ThrottledTime: time.Duration(stat["throttled_time"]),
+	}
+	if usec, ok := stat["throttled_usec"]; ok {
+		rt.ThrottledTime = time.Duration(usec) * time.Microsecond
+	}
+	return rt
+}
+
 func (c *wCgroup) AddProc(pid int) error {
-	return c.cg.AddProc(pid)
+	if err := c.cg.AddProc(pid); err != nil {
+		return err
+	}
+	if c.blkioPath != "" {
+		if err := os.WriteFile(filepath.Join(c.blkioPath, "cgroup.procs"), []byte(strconv.Itoa(pid)), 0644); err != nil {
+			return err
+		}
+	}
+	if _, ok := c.cg.(*cgroup.CgroupV1); ok && c.pidsPath == "" {
+		var pidsCgroup, cpuCgroup string
+		c.pidsPath, pidsCgroup = findPathV1(pid, "pids")
+		// the cpu controller is only created with the cpu rate enabled,
+		// otherwise the process is in the cpu cgroup of the server
+		if c.cpuPath, cpuCgroup = findPathV1(pid, "cpu"); cpuCgroup != pidsCgroup {
+			c.cpuPath = ""
+		}
+	}
+	return nil
+}
+
+// findPathV1 finds the path of the controller of the process and its cgroup
+// path within the hierarchy through /proc/[pid]/cgroup since it is not
+// exposed by cgroup v1
+func findPathV1(pid int, controller string) (string, string) {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/cgroup")
+	if err != nil {
+		return "", ""
+	}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		// hierarchy-ID:controller-list:cgroup-path
+		f := strings.SplitN(s.Text(), ":", 3)
+		if len(f) != 3 {
+			continue
+		}
+		for _, ctrl := range strings.Split(f[1], ",") {
+			if ctrl == controller {
+				return filepath.Join(cgroupV1Base, f[1], f[2]), f[2]
+			}
+		}
+	}
+	return "", ""
 }
 
+// Reset records the io stat and cpu throttling as the base since the
+// counters could not be cleared for cgroup v2
 func (c *wCgroup) Reset() error {
+	if s, err := c.readCPUThrottling(); err == nil {
+		c.cpuBase = s
+	}
+	if c.io == nil {
+		return nil
+	}
+	s, err := c.readIOStat()
+	if err != nil {
+		return err
+	}
+	c.ioBase = s
 	return nil
 }
 
 func (c *wCgroup) Destroy() error {
-	return c.cg.Destroy()
+	err := c.cg.Destroy()
+	if c.blkioPath != "" {
+		if err1 := os.Remove(c.blkioPath); err == nil {
+			err = err1
+		}
+	}
+	return err
 }
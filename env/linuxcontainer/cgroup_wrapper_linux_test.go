@@ -0,0 +1,62 @@
+package linuxcontainer
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+func TestParseCPUThrottling(t *testing.T) {
+	v1 := "nr_periods 22\nnr_throttled 21\nthrottled_time 1580000000\n"
+	v2 := "usage_usec 2200000\nuser_usec 2100000\nsystem_usec 100000\n" +
+		"nr_periods 22\nnr_throttled 21\nthrottled_usec 1580000\n"
+	want := envexec.CPUThrottling{Periods: 22, Throttled: 21, ThrottledTime: 1580 * time.Millisecond}
+	for name, b := range map[string]string{"v1": v1, "v2": v2} {
+		if s := parseCPUThrottling([]byte(b)); s != want {
+			t.Errorf("%s: %+v, want %+v", name, s, want)
+		}
+	}
+}
+
+// TestCgroupPoolThrottling covers the throttling of the run in the cgroup got
+// from the pool is its own, whether the cgroup is reused or created
+func TestCgroupPoolThrottling(t *testing.T) {
+	for name, newPool := range map[string]func(CgroupBuilder) CgroupPool{
+		"list": func(b CgroupBuilder) CgroupPool { return NewCgroupListPool(b, 100*time.Millisecond, nil) },
+		"fake": func(b CgroupBuilder) CgroupPool { return NewFakeCgroupPool(b, 100*time.Millisecond, nil) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			p := newPool(&dirCgroupBuilder{base: t.TempDir()})
+			// the kernel counters only grow while the cgroup exists
+			run := func(s envexec.CPUThrottling) {
+				t.Helper()
+				cg, err := p.Get()
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got, err := cg.CPUThrottling(); err != nil || got != (envexec.CPUThrottling{}) {
+					t.Fatalf("throttling %+v %v before the run, want zero", got, err)
+				}
+				dir := cg.(*wCgroup).cg.(*dirCgroup)
+				base, err := dir.ReadFile("cpu.stat")
+				if err != nil {
+					t.Fatal(err)
+				}
+				b := parseCPUThrottling(base)
+				stat := fmt.Sprintf("nr_periods %d\nnr_throttled %d\nthrottled_usec %d\n",
+					b.Periods+s.Periods, b.Throttled+s.Throttled, (b.ThrottledTime + s.ThrottledTime).Microseconds())
+				if err := dir.WriteFile("cpu.stat", []byte(stat)); err != nil {
+					t.Fatal(err)
+				}
+				if got, err := cg.CPUThrottling(); err != nil || got != s {
+					t.Fatalf("throttling %+v %v, want %+v", got, err, s)
+				}
+				p.Put(cg)
+			}
+			run(envexec.CPUThrottling{Periods: 10, Throttled: 4, ThrottledTime: 2 * time.Millisecond})
+			run(envexec.CPUThrottling{Periods: 5, Throttled: 2, ThrottledTime: time.Millisecond})
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 // Cgroup defines interface to limit and monitor resources consumption of a process
 type Cgroup interface {
 	SetCpuset(string) error
+	SetCpusetMems(string) error
 	SetMemoryLimit(envexec.Size) error
 	SetProcLimit(uint64) error
 	SetCPURate(uint64) error // 1000 as 1
@@ -17,6 +18,17 @@ type Cgroup interface {
 	CPUUsage() (time.Duration, error)
 	CurrentMemory() (envexec.Size, error)
 	MaxMemory() (envexec.Size, error)
+	MemoryStat() (envexec.MemoryStat, error)
+	IOStat() (envexec.IOStat, error)               // bytes read and written to block devices since reset
+	CPUThrottling() (envexec.CPUThrottling, error) // cpu.stat throttling counters since reset
+
+	CurrentProc() (uint64, error)  // pids.current
+	PeakProc() (uint64, error)     // pids.peak, not available in cgroup v1
+	ProcLimitHit() (uint64, error) // max counter in pids.events
+	Procs() ([]int, error)         // cgroup.procs, exited processes are not listed
+	Kill() error                   // SIGKILL all processes in the cgroup
+
+	ID() uint64 // unique among the cgroups created by the pools
 
 	AddProc(int) error
 	Reset() error
@@ -33,14 +45,16 @@ type CgroupPool interface {
 type CgroupListPool struct {
 	builder   CgroupBuilder
 	cfsPeriod time.Duration
+	io        *IOController
 
 	cgs []Cgroup
 	mu  sync.Mutex
 }
 
-// NewCgroupListPool creates new cgroup pool
-func NewCgroupListPool(builder CgroupBuilder, cfsPeriod time.Duration) CgroupPool {
-	return &CgroupListPool{builder: builder, cfsPeriod: cfsPeriod}
+// NewCgroupListPool creates new cgroup pool, io accounting is enabled if io
+// is not nil
+func NewCgroupListPool(builder CgroupBuilder, cfsPeriod time.Duration, io *IOController) CgroupPool {
+	return &CgroupListPool{builder: builder, cfsPeriod: cfsPeriod, io: io}
 }
 
 // Get gets cgroup from pool, if pool is empty, creates new one
@@ -58,7 +72,7 @@ func (w *CgroupListPool) Get() (Cgroup, error) {
 	if err != nil {
 		return nil, err
 	}
-	return &wCgroup{cg: cg, cfsPeriod: w.cfsPeriod}, nil
+	return newCgroup(cg, w.cfsPeriod, w.io)
 }
 
 // Put puts cgroup into the pool
@@ -0,0 +1,52 @@
+package linuxcontainer
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"syscall"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/pkg/rlimit"
+)
+
+const corePatternPath = "/proc/sys/kernel/core_pattern"
+
+// CoreDumpAvailable checks the core_pattern of the host, which is not
+// namespaced. The core file is only collected if it is a relative file name
+// (e.g. the default `core`), which dumps into the current directory of the
+// process (i.e. the work directory) inside the container.
+func (c *environ) CoreDumpAvailable() error {
+	b, err := os.ReadFile(corePatternPath)
+	if err != nil {
+		return fmt.Errorf("core dump: failed to read core_pattern %w", err)
+	}
+	p := strings.TrimSpace(string(b))
+	switch {
+	case p == "":
+		return fmt.Errorf("core dump: core_pattern is empty")
+	case strings.HasPrefix(p, "|"):
+		return fmt.Errorf("core dump: core_pattern is a pipe handler (%s)", p)
+	case strings.Contains(p, "/"):
+		return fmt.Errorf("core dump: core_pattern is not in the work directory (%s)", p)
+	}
+	return nil
+}
+
+// prepareRLimit raises RLIMIT_CORE to core if it is not 0, within the hard
+// limit of the current process since raising it requires CAP_SYS_RESOURCE
+func prepareRLimit(r rlimit.RLimits, core envexec.Size) []rlimit.RLimit {
+	rt := r.PrepareRLimit()
+	if core == 0 {
+		return rt
+	}
+	l := core.Byte()
+	var cur syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_CORE, &cur); err == nil && cur.Max < l {
+		l = cur.Max
+	}
+	return append(rt, rlimit.RLimit{
+		Res:  syscall.RLIMIT_CORE,
+		Rlim: syscall.Rlimit{Cur: l, Max: l},
+	})
+}
@@ -0,0 +1,93 @@
+package linuxcontainer
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/container"
+	"github.com/criyle/go-sandbox/pkg/mount"
+)
+
+// diskTmpDir is the tmpfs mount replaced by the disk work directory besides
+// the work directory
+const diskTmpDir = "/tmp"
+
+// buildDiskWorkDir creates linux container with the tmpfs mounts of the work
+// directory and /tmp replaced by the writable bind mounts of subdirectories of
+// dir. The subdirectories are owned by the credential the container runs as.
+func (b *environmentBuilder) buildDiskWorkDir(nb *container.Builder, dir string) (pool.Environment, error) {
+	uid, gid := ownerCred(nb)
+
+	nb.Mounts = append(make([]mount.Mount, 0, len(nb.Mounts)), nb.Mounts...)
+	found := false
+	for i, m := range nb.Mounts {
+		if !m.IsTmpFs() {
+			continue
+		}
+		target := filepath.Join("/", m.Target)
+		if target != b.workDir && target != diskTmpDir {
+			continue
+		}
+		src := filepath.Join(dir, filepath.Base(target))
+		if err := mkdirOwned(src, uid, gid, target == diskTmpDir); err != nil {
+			return nil, fmt.Errorf("disk work directory: %v", err)
+		}
+		nb.Mounts[i] = mount.NewBuilder().WithBind(src, m.Target, false).Mounts[0]
+		found = found || target == b.workDir
+	}
+	if !found {
+		return nil, fmt.Errorf("disk work directory requires tmpfs mounted at %s", b.workDir)
+	}
+	e, err := b.build(nb, b.workDir)
+	if err != nil {
+		return nil, err
+	}
+	e.(*environ).diskDir = dir
+	return e, nil
+}
+
+// mkdirOwned creates the directory owned by uid / gid, world writable with
+// sticky bit as /tmp if tmp
+func mkdirOwned(path string, uid, gid int, tmp bool) error {
+	if err := os.Mkdir(path, 0755); err != nil {
+		return err
+	}
+	if tmp {
+		if err := os.Chmod(path, os.ModeSticky|0777); err != nil {
+			return err
+		}
+	}
+	return os.Lchown(path, uid, gid)
+}
+
+// DiskUsage returns the bytes allocated by the files under the directory
+// backing the work directory or the size of the scratch file, false if the
+// work directory is backed by tmpfs without scratch file
+func (c *environ) DiskUsage() (envexec.Size, bool) {
+	if c.scratch != "" {
+		return c.scratchUsage(), true
+	}
+	if c.diskDir == "" {
+		return 0, false
+	}
+	var rt envexec.Size
+	filepath.WalkDir(c.diskDir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+			rt += envexec.Size(st.Blocks) * 512
+		}
+		return nil
+	})
+	return rt, true
+}
@@ -0,0 +1,380 @@
+package linuxcontainer
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/container"
+	"github.com/criyle/go-sandbox/runner"
+)
+
+const binfmtMiscDir = "/proc/sys/fs/binfmt_misc"
+
+// limits of the calibration run of the emulators
+const (
+	calibrateTimeLimit   = 10 * time.Second
+	calibrateMemoryLimit = 1 << 30
+	calibrateProcLimit   = 64
+	calibrateName        = "emulator-calibrate"
+)
+
+// emulation defines how the programs of the foreign architectures are run,
+// it is shared by the environments of the builder
+type emulation struct {
+	// emulators maps the architecture to the path of the emulator in the
+	// container (e.g. qemu-user), which is prepended to the args
+	emulators map[string]string
+	// binfmt maps the architecture to the interpreter of the binfmt_misc
+	// handler registered with the F (fix binary) flag on the host, which the
+	// kernel runs for the program inside the container as well
+	binfmt map[string]string
+	// baseline is the memory of the emulator itself measured by running an
+	// empty program, subtracted from the memory of the emulated runs
+	baseline map[string]envexec.Size
+}
+
+func newEmulation(emulators map[string]string) *emulation {
+	e := &emulation{
+		emulators: emulators,
+		binfmt:    binfmtHandlers(),
+		baseline:  make(map[string]envexec.Size),
+	}
+	if len(e.emulators) == 0 && len(e.binfmt) == 0 {
+		return nil
+	}
+	return e
+}
+
+// emulated reports whether the programs of the architecture are run under
+// an emulator
+func (e *emulation) emulated(arch string) bool {
+	if e == nil || arch == "" || arch == hostArch {
+		return false
+	}
+	_, ok := e.emulators[arch]
+	return ok || e.binfmt[arch] != ""
+}
+
+// emulate returns the args running the program of the foreign architecture
+// under its emulator with the path resolved in the same way as execve. The
+// args are unchanged for the host architecture, the ones run by the binfmt_misc
+// handler and the missing program, so that execve reports the error.
+func (c *environ) emulate(param envexec.ExecveParam, arch string) ([]string, error) {
+	if !c.emulation.emulated(arch) {
+		return param.Args, nil
+	}
+	emu, ok := c.emulation.emulators[arch]
+	if !ok {
+		return param.Args, nil
+	}
+	if param.ExecFile > 0 {
+		return nil, fmt.Errorf("execve: the emulator of %s could not run the exec file passed by fd", arch)
+	}
+	p := c.execPath(param)
+	if p == "" {
+		return param.Args, nil
+	}
+	return append([]string{emu, p}, param.Args[1:]...), nil
+}
+
+// execPath returns the path of the executable that execve would open, empty
+// if not found
+func (c *environ) execPath(param envexec.ExecveParam) string {
+	if len(param.Args) == 0 {
+		return ""
+	}
+	for _, p := range execCandidates(param.Args[0], param.Env) {
+		fs, err := c.Environment.Open([]container.OpenCmd{{
+			Path: p,
+			Flag: syscall.O_RDONLY | syscall.O_CLOEXEC | syscall.O_NONBLOCK,
+		}})
+		if err != nil {
+			continue
+		}
+		fs[0].Close()
+		return p
+	}
+	return ""
+}
+
+// needsEmulation returns the error of the program that failed execve with
+// ENOEXEC for its foreign architecture, nil if it is not an ELF file of a
+// known foreign architecture
+func (c *environ) needsEmulation(param envexec.ExecveParam, name string) *envexec.FileError {
+	arch := c.detectArch(param)
+	if arch == "" || arch == hostArch {
+		return nil
+	}
+	msg := fmt.Sprintf("needs emulation of %s, no emulator configured: %s", arch, name)
+	if c.emulation.emulated(arch) {
+		msg = fmt.Sprintf("needs emulation of %s, set arch to run it with the configured emulator: %s", arch, name)
+	}
+	return &envexec.FileError{Name: name, Type: envexec.ErrExecNeedsEmulation, Message: msg}
+}
+
+// binfmtHandlers returns the interpreters of the enabled binfmt_misc handlers
+// with the F flag matching the ELF header of each foreign architecture
+func binfmtHandlers() map[string]string {
+	entries, err := os.ReadDir(binfmtMiscDir)
+	if err != nil {
+		return nil
+	}
+	rt := make(map[string]string)
+	for _, e := range entries {
+		if e.Name() == "register" || e.Name() == "status" {
+			continue
+		}
+		h, ok := readBinfmtHandler(filepath.Join(binfmtMiscDir, e.Name()))
+		if !ok {
+			continue
+		}
+		for arch := range calibrateCode {
+			if arch == hostArch || rt[arch] != "" {
+				continue
+			}
+			if h.match(calibrateELF(arch)) {
+				rt[arch] = h.interpreter
+			}
+		}
+	}
+	return rt
+}
+
+type binfmtHandler struct {
+	interpreter string
+	offset      int
+	magic, mask []byte
+}
+
+// readBinfmtHandler parses the enabled magic handler with the F flag
+func readBinfmtHandler(p string) (binfmtHandler, bool) {
+	f, err := os.Open(p)
+	if err != nil {
+		return binfmtHandler{}, false
+	}
+	defer f.Close()
+
+	var (
+		h       binfmtHandler
+		enabled bool
+		fix     bool
+	)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		k, v, _ := strings.Cut(s.Text(), " ")
+		switch k {
+		case "enabled":
+			enabled = true
+		case "interpreter":
+			h.interpreter = v
+		case "flags:":
+			fix = strings.Contains(v, "F")
+		case "offset":
+			h.offset, _ = strconv.Atoi(v)
+		case "magic":
+			h.magic, _ = hex.DecodeString(v)
+		case "mask":
+			h.mask, _ = hex.DecodeString(v)
+		}
+	}
+	return h, enabled && fix && h.interpreter != "" && len(h.magic) > 0
+}
+
+func (h binfmtHandler) match(b []byte) bool {
+	if h.offset+len(h.magic) > len(b) {
+		return false
+	}
+	for i, m := range h.magic {
+		v := b[h.offset+i]
+		if i < len(h.mask) {
+			v &= h.mask[i]
+		}
+		if v != m {
+			return false
+		}
+	}
+	return true
+}
+
+// calibrateCode defines the machine code exiting with 0 of each architecture
+var calibrateCode = map[string][]byte{
+	// mov eax, 60; xor edi, edi; syscall
+	envexec.ArchX64: {0xb8, 0x3c, 0, 0, 0, 0x31, 0xff, 0x0f, 0x05},
+	// mov eax, 1; xor ebx, ebx; int 0x80
+	envexec.ArchX86: {0xb8, 0x01, 0, 0, 0, 0x31, 0xdb, 0xcd, 0x80},
+	// mov r0, #0; mov r7, #1; svc #0
+	envexec.ArchARM: {0x00, 0x00, 0xa0, 0xe3, 0x01, 0x70, 0xa0, 0xe3, 0x00, 0x00, 0x00, 0xef},
+	// mov x0, #0; mov x8, #93; svc #0
+	envexec.ArchARM64: {0x00, 0x00, 0x80, 0xd2, 0xa8, 0x0b, 0x80, 0xd2, 0x01, 0x00, 0x00, 0xd4},
+	// li a0, 0; li a7, 93; ecall
+	envexec.ArchRISCV64: {0x13, 0x05, 0x00, 0x00, 0x93, 0x08, 0xd0, 0x05, 0x73, 0x00, 0x00, 0x00},
+}
+
+var calibrateMachine = map[string]uint16{
+	envexec.ArchX64:     emX86_64,
+	envexec.ArchX86:     emI386,
+	envexec.ArchARM:     emARM,
+	envexec.ArchARM64:   emAARCH64,
+	envexec.ArchRISCV64: emRISCV,
+}
+
+// calibrateELF returns the static little endian executable of the machine
+// code of the architecture with a single loadable segment
+func calibrateELF(arch string) []byte {
+	const base = 0x400000
+	code := calibrateCode[arch]
+	le := binary.LittleEndian
+	b := []byte{0x7f, 'E', 'L', 'F', elfClass64, elfData2LSB, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	b = le.AppendUint16(b, 2) // ET_EXEC
+	b = le.AppendUint16(b, calibrateMachine[arch])
+	b = le.AppendUint32(b, 1)
+	var flags uint32
+	if arch == envexec.ArchARM {
+		flags = 0x05000000 // EABI version 5
+	}
+	if envexec.Arch32(arch) {
+		const ehdr, phdr = 52, 32
+		size := uint32(ehdr + phdr + len(code))
+		b[4] = elfClass32
+		b = le.AppendUint32(b, base+ehdr+phdr) // entry
+		b = le.AppendUint32(b, ehdr)           // phoff
+		b = le.AppendUint32(b, 0)              // shoff
+		b = le.AppendUint32(b, flags)
+		b = le.AppendUint16(b, ehdr)
+		b = le.AppendUint16(b, phdr)
+		b = le.AppendUint16(b, 1)
+		b = le.AppendUint16(b, 0)
+		b = le.AppendUint16(b, 0)
+		b = le.AppendUint16(b, 0)
+		b = le.AppendUint32(b, 1) // PT_LOAD
+		b = le.AppendUint32(b, 0) // offset
+		b = le.AppendUint32(b, base)
+		b = le.AppendUint32(b, base)
+		b = le.AppendUint32(b, size)
+		b = le.AppendUint32(b, size)
+		b = le.AppendUint32(b, 5) // PF_R | PF_X
+		b = le.AppendUint32(b, 0x1000)
+		return append(b, code...)
+	}
+	const ehdr, phdr = 64, 56
+	size := uint64(ehdr + phdr + len(code))
+	b = le.AppendUint64(b, base+ehdr+phdr) // entry
+	b = le.AppendUint64(b, ehdr)           // phoff
+	b = le.AppendUint64(b, 0)              // shoff
+	b = le.AppendUint32(b, flags)
+	b = le.AppendUint16(b, ehdr)
+	b = le.AppendUint16(b, phdr)
+	b = le.AppendUint16(b, 1)
+	b = le.AppendUint16(b, 0)
+	b = le.AppendUint16(b, 0)
+	b = le.AppendUint16(b, 0)
+	b = le.AppendUint32(b, 1) // PT_LOAD
+	b = le.AppendUint32(b, 5) // PF_R | PF_X
+	b = le.AppendUint64(b, 0) // offset
+	b = le.AppendUint64(b, base)
+	b = le.AppendUint64(b, base)
+	b = le.AppendUint64(b, size)
+	b = le.AppendUint64(b, size)
+	b = le.AppendUint64(b, 0x1000)
+	return append(b, code...)
+}
+
+// CalibrateEmulators runs an empty program of each emulated architecture in
+// the environment built by the builder of linux container, and subtracts the
+// memory measured from the memory of the later emulated runs. The errors of
+// the architectures failed to calibrate are returned.
+func CalibrateEmulators(pb pool.EnvBuilder) (map[string]envexec.Size, map[string]error) {
+	b, ok := pb.(*environmentBuilder)
+	if !ok || b.emulation == nil {
+		return nil, nil
+	}
+	archs := make(map[string]bool)
+	for arch := range b.emulation.emulators {
+		archs[arch] = true
+	}
+	for arch := range b.emulation.binfmt {
+		archs[arch] = true
+	}
+	rt := make(map[string]envexec.Size)
+	errs := make(map[string]error)
+	for arch := range archs {
+		m, err := b.calibrate(arch)
+		if err != nil {
+			errs[arch] = err
+			continue
+		}
+		rt[arch] = m
+	}
+	// calibrated before any environment is built for the requests
+	for arch, m := range rt {
+		b.emulation.baseline[arch] = m
+	}
+	return rt, errs
+}
+
+// calibrate returns the peak memory of the empty program of the arch
+func (b *environmentBuilder) calibrate(arch string) (envexec.Size, error) {
+	if _, ok := calibrateCode[arch]; !ok {
+		return 0, fmt.Errorf("no calibration program for %s", arch)
+	}
+	pe, err := b.Build()
+	if err != nil {
+		return 0, err
+	}
+	env := pe.(*environ)
+	defer env.Destroy()
+
+	f, err := env.Open(calibrateName, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0777)
+	if err != nil {
+		return 0, err
+	}
+	_, err = f.Write(calibrateELF(arch))
+	f.Close()
+	if err != nil {
+		return 0, err
+	}
+	// the emulator may write to stderr
+	null, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer null.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*calibrateTimeLimit)
+	defer cancel()
+	p, err := env.Execve(ctx, envexec.ExecveParam{
+		Args:  []string{"./" + calibrateName},
+		Files: []uintptr{null.Fd(), null.Fd(), null.Fd()},
+		Arch:  arch,
+		Limit: envexec.Limit{
+			Time:   calibrateTimeLimit,
+			Memory: calibrateMemoryLimit,
+			Proc:   calibrateProcLimit,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	<-p.Done()
+	rt := p.Result()
+	if e, ok := p.(envexec.ExecErrorProcess); ok {
+		if fe := e.ExecError(); fe != nil {
+			return 0, fmt.Errorf("calibration failed to execute: %s", fe.Message)
+		}
+	}
+	if rt.Status != runner.StatusNormal || rt.ExitStatus != 0 {
+		return 0, fmt.Errorf("calibration exited with %v %d: %s", rt.Status, rt.ExitStatus, rt.Error)
+	}
+	return rt.Memory, nil
+}
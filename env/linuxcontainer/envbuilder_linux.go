@@ -1,65 +1,628 @@
 package linuxcontainer
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
 	"github.com/criyle/go-sandbox/container"
+	"github.com/criyle/go-sandbox/pkg/mount"
 )
 
+// datasetDir is the directory inside the container where datasets are mounted
+const datasetDir = "data"
+
+// mountFilesDir is the directory inside the container where the files of
+// mountFiles are mounted
+const mountFilesDir = "files"
+
 // Config specifies configuration to build environment builder
 type Config struct {
-	Builder    EnvironmentBuilder
-	CgroupPool CgroupPool
-	WorkDir    string
-	Seccomp    []syscall.SockFilter
-	Cpuset     string
-	CPURate    bool
+	Builder     EnvironmentBuilder
+	CgroupPool  CgroupPool
+	CgroupMount *CgroupMount
+	WorkDir     string
+	Seccomp     []syscall.SockFilter
+	Cpuset      string
+	CPURate     bool
+
+	// credentials in [CredPinStart, CredPinStart + CredPinCount) could be
+	// pinned by requests
+	CredPinStart uint32
+	CredPinCount uint32
+
+	// VerifyReset checks the environment is clean after each reset
+	VerifyReset bool
+
+	// CheckFds checks the program inherits no fd other than the mapped files
+	// before execve and fails the run otherwise
+	CheckFds bool
+
+	// AttachRetry retries creating the cgroup and attaching the process to
+	// it on transient errors
+	AttachRetry AttachRetry
+
+	// Datasets maps the dataset name to the host directory which is bind
+	// mounted read-only at /data/<name> when requested
+	Datasets map[string]string
+
+	// Profiles maps the profile name to the mounts used instead of the
+	// configured ones when requested
+	Profiles map[string]Profile
+
+	// IO reports the cgroups account the bytes read and written to block
+	// devices (i.e. the cgroup pool is created with IOController)
+	IO bool
+
+	// InitLog keeps the stderr of the container init of each environment
+	InitLog InitLog
+
+	// Emulators maps the foreign architecture to the path of the emulator
+	// in the container (e.g. /usr/bin/qemu-riscv64-static), which runs the
+	// programs of the architecture
+	Emulators map[string]string
+
+	// RandomSize is the size of the deterministic stream of /dev/urandom
+	// and /dev/random of the environments built with random
+	RandomSize uint64
+}
+
+// Profile defines the root filesystem of the container built for a mount
+// profile, the work directory must be a tmpfs
+type Profile struct {
+	Mounts        []mount.Mount
+	SymbolicLinks []container.SymbolicLink
+	MaskPaths     []string
+}
+
+// AttachRetry defines the retry of transient cgroup failures (EBUSY / EAGAIN)
+// before the process starts. The pooled cgroup is destroyed on failure so that
+// the next attempt uses another one.
+type AttachRetry struct {
+	Attempts int           // max number of retries, 0 disables
+	Backoff  time.Duration // doubled after each retry
 }
 
 type environmentBuilder struct {
 	builder EnvironmentBuilder
 	cgPool  CgroupPool
+	cgMount *CgroupMount
 	workDir string
 	seccomp []syscall.SockFilter
 	cpuset  string
+	mems    string // cpuset.mems of the NUMA node bound to
 	cpuRate bool
+
+	credPinStart uint32
+	credPinCount uint32
+	verifyReset  bool
+	checkFds     bool
+	attachRetry  AttachRetry
+	datasets     map[string]string
+	profiles     map[string]Profile
+	io           bool
+	initLog      InitLog
+	emulation    *emulation
+	randomSize   uint64
 }
 
+var (
+	_ pool.OptionsEnvBuilder = &environmentBuilder{}
+	_ pool.PrepareEnvBuilder = &environmentBuilder{}
+	_ pool.IOEnvBuilder      = &environmentBuilder{}
+	_ pool.NUMAEnvBuilder    = &environmentBuilder{}
+)
+
 // NewEnvBuilder creates builder for linux container pools
 func NewEnvBuilder(c Config) pool.EnvBuilder {
 	return &environmentBuilder{
 		builder: c.Builder,
 		cgPool:  c.CgroupPool,
+		cgMount: c.CgroupMount,
 		workDir: c.WorkDir,
 		seccomp: c.Seccomp,
 		cpuset:  c.Cpuset,
 		cpuRate: c.CPURate,
+
+		credPinStart: c.CredPinStart,
+		credPinCount: c.CredPinCount,
+		verifyReset:  c.VerifyReset,
+		checkFds:     c.CheckFds,
+		attachRetry:  c.AttachRetry,
+		datasets:     c.Datasets,
+		profiles:     c.Profiles,
+		io:           c.IO,
+		initLog:      c.InitLog,
+		emulation:    newEmulation(c.Emulators),
+		randomSize:   c.RandomSize,
 	}
 }
 
 // Build creates linux container
 func (b *environmentBuilder) Build() (pool.Environment, error) {
-	m, err := b.builder.Build()
+	return b.build(b.builder, b.workDir)
+}
+
+// Prepare recreates the directory where the temp container roots are created
+// if it was removed (e.g. by tmp cleaner)
+func (b *environmentBuilder) Prepare() error {
+	cb, ok := b.builder.(*container.Builder)
+	if !ok || cb.TmpRoot == "" {
+		return nil
+	}
+	root := cb.Root
+	if root == "" {
+		root = os.TempDir()
+	}
+	if _, err := os.Stat(root); !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return fmt.Errorf("failed to create container root dir %s: %w", root, err)
+	}
+	// shared temp dir is world writable with sticky bit
+	if cb.Root == "" {
+		return os.Chmod(root, os.ModeSticky|0777)
+	}
+	return nil
+}
+
+// BuildOptions creates linux container with the options, the container builder
+// is copied with the mounts of the option and the pinned credential if not nil
+func (b *environmentBuilder) BuildOptions(opts envexec.EnvironmentOptions) (pool.Environment, error) {
+	if err := b.ValidOptions(opts); err != nil {
+		return nil, err
+	}
+	if opts.Plain() && opts.Credential == nil {
+		return b.Build()
+	}
+	nb := *b.builder.(*container.Builder)
+	if opts.Credential != nil {
+		nb.CredGenerator = pinnedCred(*opts.Credential)
+	}
+	switch {
+	case opts.RandomWorkDir:
+		return b.buildRandomWorkDir(&nb)
+	case len(opts.Datasets) > 0:
+		return b.buildDatasets(&nb, opts.Datasets)
+	case opts.Profile != "":
+		return b.buildProfile(&nb, opts.Profile)
+	case opts.MountFiles != "":
+		return b.buildMountFiles(&nb, opts.MountFiles)
+	case len(opts.HostExec) > 0:
+		return b.buildHostExec(&nb, opts.HostExec)
+	case opts.DiskWorkDir != "":
+		return b.buildDiskWorkDir(&nb, opts.DiskWorkDir)
+	case opts.ScratchFile != "":
+		return b.buildScratch(&nb, opts.ScratchFile, opts.ScratchName)
+	case opts.ProcInfo:
+		return b.buildProcInfo(&nb)
+	case opts.Random:
+		return b.buildRandom(&nb)
+	}
+	return b.build(&nb, b.workDir)
+}
+
+// ValidOptions checks whether the credential is within the pinning range and
+// the datasets or profile are configured
+func (b *environmentBuilder) ValidOptions(opts envexec.EnvironmentOptions) error {
+	if opts.Credential != nil {
+		if err := b.validCred(*opts.Credential); err != nil {
+			return err
+		}
+	}
+	if opts.Plain() {
+		return nil
+	}
+	if _, ok := b.builder.(*container.Builder); !ok {
+		return fmt.Errorf("environment options require container builder")
+	}
+	for _, d := range opts.Datasets {
+		if _, ok := b.datasets[d]; !ok {
+			return fmt.Errorf("dataset %q is not configured", d)
+		}
+	}
+	if opts.Profile != "" {
+		if _, ok := b.profiles[opts.Profile]; !ok {
+			return fmt.Errorf("profile %q is not configured", opts.Profile)
+		}
+	}
+	return nil
+}
+
+// buildRandomWorkDir creates linux container with the tmpfs of work directory
+// mounted at a randomized path under the work directory. Absolute paths under
+// the work directory are still resolved relative to the randomized one.
+func (b *environmentBuilder) buildRandomWorkDir(nb *container.Builder) (pool.Environment, error) {
+	var name [8]byte
+	if _, err := rand.Read(name[:]); err != nil {
+		return nil, fmt.Errorf("container: failed to generate work directory %v", err)
+	}
+	workDir := path.Join(b.workDir, hex.EncodeToString(name[:]))
+
+	nb.Mounts = append(make([]mount.Mount, 0, len(nb.Mounts)), nb.Mounts...)
+	found := false
+	for i, m := range nb.Mounts {
+		if m.IsTmpFs() && filepath.Join("/", m.Target) == b.workDir {
+			nb.Mounts[i].Target = strings.TrimPrefix(workDir, "/")
+			found = true
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("randomized work directory requires tmpfs mounted at %s", b.workDir)
+	}
+	nb.WorkDir = workDir
+	return b.build(nb, workDir)
+}
+
+// buildDatasets creates linux container with the datasets bind mounted
+// read-only at /data/<name>
+func (b *environmentBuilder) buildDatasets(nb *container.Builder, datasets []string) (pool.Environment, error) {
+	mb := mount.NewBuilder()
+	for _, d := range datasets {
+		mb.WithBind(b.datasets[d], path.Join(datasetDir, d), true)
+	}
+	nb.Mounts = appendMounts(nb.Mounts, mb.Mounts)
+	return b.build(nb, b.workDir)
+}
+
+// buildProfile creates linux container with only the mounts of the profile
+func (b *environmentBuilder) buildProfile(nb *container.Builder, profile string) (pool.Environment, error) {
+	p := b.profiles[profile]
+	nb.Mounts = p.Mounts
+	nb.SymbolicLinks = p.SymbolicLinks
+	nb.MaskPaths = p.MaskPaths
+	return b.build(nb, b.workDir)
+}
+
+// buildMountFiles creates linux container with the host directory bind mounted
+// read-only at /files
+func (b *environmentBuilder) buildMountFiles(nb *container.Builder, dir string) (pool.Environment, error) {
+	mb := mount.NewBuilder().WithBind(dir, mountFilesDir, true)
+	nb.Mounts = appendMounts(nb.Mounts, mb.Mounts)
+	return b.build(nb, b.workDir)
+}
+
+// buildHostExec creates linux container with the host files (path in the
+// container -> host path) bind mounted read-only
+func (b *environmentBuilder) buildHostExec(nb *container.Builder, files map[string]string) (pool.Environment, error) {
+	mb := mount.NewBuilder()
+	for target, source := range files {
+		mb.WithBind(source, strings.TrimPrefix(target, "/"), true)
+	}
+	nb.Mounts = appendMounts(nb.Mounts, mb.Mounts)
+	return b.build(nb, b.workDir)
+}
+
+// buildProcInfo creates linux container with the synthetic /proc/meminfo and
+// /proc/cpuinfo mounted. The files are generated for each run.
+func (b *environmentBuilder) buildProcInfo(nb *container.Builder) (pool.Environment, error) {
+	proc := ""
+	for _, m := range nb.Mounts {
+		if m.FsType == "proc" {
+			proc = m.Target
+		}
+	}
+	if proc == "" {
+		return nil, fmt.Errorf("proc info requires /proc mounted")
+	}
+	pi, err := newProcInfo()
+	if err != nil {
+		return nil, err
+	}
+	// mounted over the files of the proc mount
+	mb := mount.NewBuilder()
+	for _, n := range procInfoFiles {
+		mb.WithBind(pi.path(n), path.Join(proc, n), true)
+	}
+	nb.Mounts = appendMounts(nb.Mounts, mb.Mounts)
+	e, err := b.build(nb, b.workDir)
 	if err != nil {
+		pi.destroy()
 		return nil, err
 	}
+	if err := pi.unlink(); err != nil {
+		e.Destroy()
+		pi.destroy()
+		return nil, fmt.Errorf("proc info: failed to remove directory %v", err)
+	}
+	env := e.(*environ)
+	env.procInfo = pi
+	env.stamps = withoutStamps(env.stamps, pi.dir)
+	return e, nil
+}
+
+// buildRandom creates linux container with the deterministic /dev/urandom and
+// /dev/random mounted. The stream is generated for each run by its seed.
+func (b *environmentBuilder) buildRandom(nb *container.Builder) (pool.Environment, error) {
+	r, err := newRandom(b.randomSize)
+	if err != nil {
+		return nil, err
+	}
+	// replaces the devices of the configured mounts, the file could not be
+	// mounted over the device mounted before
+	mounts := make([]mount.Mount, 0, len(nb.Mounts)+len(randomDevices))
+	for _, m := range nb.Mounts {
+		if !isRandomDevice(m.Target) {
+			mounts = append(mounts, m)
+		}
+	}
+	for _, d := range randomDevices {
+		mounts = append(mounts, mount.NewBuilder().WithBind(r.path(), d, true).Mounts...)
+	}
+	nb.Mounts = mounts
+	e, err := b.build(nb, b.workDir)
+	if err != nil {
+		r.destroy()
+		return nil, err
+	}
+	if err := r.unlink(); err != nil {
+		e.Destroy()
+		r.destroy()
+		return nil, fmt.Errorf("random: failed to remove directory %v", err)
+	}
+	env := e.(*environ)
+	env.random = r
+	env.stamps = withoutStamps(env.stamps, r.dir)
+	return e, nil
+}
+
+// appendMounts returns a new slice of the configured mounts followed by the
+// mounts of the option, so that the configured ones are not modified
+func appendMounts(mounts, extra []mount.Mount) []mount.Mount {
+	return append(append(make([]mount.Mount, 0, len(mounts)+len(extra)), mounts...), extra...)
+}
+
+// ownerCred returns the host uid / gid the container runs as, the generated
+// credential is pinned so that the owner of the files created for it is known
+func ownerCred(nb *container.Builder) (int, int) {
+	if nb.CredGenerator == nil {
+		return os.Geteuid(), os.Getegid()
+	}
+	c := nb.CredGenerator.Get()
+	nb.CredGenerator = pinnedCred{UID: c.Uid, GID: c.Gid}
+	return int(c.Uid), int(c.Gid)
+}
+
+// IOAccounting reports whether the bytes written to block devices are
+// accounted for ioLimit
+func (b *environmentBuilder) IOAccounting() bool {
+	return b.io
+}
+
+// NUMANode returns the builder of environments running programs on the cpus
+// and memory of the node, the cgroups are still shared with b
+func (b *environmentBuilder) NUMANode(n pool.NUMANode) (pool.EnvBuilder, bool) {
+	nb := *b
+	nb.cpuset = n.CPUs
+	nb.mems = strconv.Itoa(n.ID)
+	return &nb, true
+}
+
+// validCred checks whether the credential is within the pinning range
+func (b *environmentBuilder) validCred(cred envexec.Credential) error {
+	if b.credPinCount == 0 {
+		return fmt.Errorf("credential pinning is not enabled")
+	}
+	cb, ok := b.builder.(*container.Builder)
+	if !ok || cb.CredGenerator == nil {
+		return fmt.Errorf("credential pinning requires container credential start")
+	}
+	for _, id := range []uint32{cred.UID, cred.GID} {
+		if id < b.credPinStart || id-b.credPinStart >= b.credPinCount {
+			return fmt.Errorf("%d is out of the pinning range [%d, %d)", id, b.credPinStart, b.credPinStart+b.credPinCount)
+		}
+	}
+	return nil
+}
+
+// pinnedCred generates the same credential for the container
+type pinnedCred envexec.Credential
+
+func (c pinnedCred) Get() syscall.Credential {
+	return syscall.Credential{
+		Uid: c.UID,
+		Gid: c.GID,
+	}
+}
+
+// build creates the container and opens the work directory at workDir
+func (b *environmentBuilder) build(builder EnvironmentBuilder, workDir string) (pool.Environment, error) {
+	var cg *envCgroup
+	if b.cgMount != nil {
+		var (
+			mt  mount.Mount
+			err error
+		)
+		cg, mt, err = b.cgMount.build()
+		if err != nil {
+			return nil, &envexec.EnvironmentError{
+				Reason: envexec.EnvironmentCgroupFailed,
+				Errno:  errnoOf(err),
+				Err:    fmt.Errorf("container: failed to create cgroup for mount %v", err),
+			}
+		}
+		builder = withMount(builder, mt)
+	}
+	stamps := stampMounts(builder)
+	builder, initLog, initLogW, err := newInitLog(b.initLog, builder)
+	if err != nil {
+		if cg != nil {
+			cg.Destroy()
+		}
+		return nil, &envexec.EnvironmentError{
+			Reason: envexec.EnvironmentForkFailed,
+			Errno:  errnoOf(err),
+			Err:    err,
+		}
+	}
+	m, err := builder.Build()
+	if initLogW != nil {
+		// inherited by the container init
+		initLogW.Close()
+	}
+	if err != nil {
+		if cg != nil {
+			cg.Destroy()
+		}
+		return nil, classifyBuildError(err)
+	}
 	wd, err := m.Open([]container.OpenCmd{{
-		Path: b.workDir,
+		Path: workDir,
 		Flag: syscall.O_CLOEXEC | syscall.O_DIRECTORY,
 		Perm: 0777,
 	}})
 	if err != nil {
-		return nil, fmt.Errorf("container: failed to prepare work directory")
+		m.Destroy()
+		if cg != nil {
+			cg.Destroy()
+		}
+		return nil, hostError(envexec.EnvironmentMountFailed,
+			fmt.Errorf("container: failed to prepare work directory %w", err))
 	}
-	return &environ{
+	e := &environ{
 		Environment: m,
 		cgPool:      b.cgPool,
 		wd:          wd[0],
 		workDir:     b.workDir,
 		cpuset:      b.cpuset,
+		mems:        b.mems,
 		cpuRate:     b.cpuRate,
 		seccomp:     b.seccomp,
-	}, nil
+		reset:       b.resetParam(builder),
+		stamps:      stamps,
+		initLog:     initLog,
+		attachRetry: b.attachRetry,
+		checkFds:    b.checkFds,
+		emulation:   b.emulation,
+	}
+	if cg != nil {
+		e.cgEnv = cg
+	}
+	if initLog != nil {
+		initLog.register()
+	}
+	return e, nil
+}
+
+// resetParam inspects the container mounts for the cleanup between runs
+func (b *environmentBuilder) resetParam(builder EnvironmentBuilder) resetParam {
+	p := resetParam{verify: b.verifyReset}
+	cb, ok := builder.(*container.Builder)
+	if !ok {
+		return p
+	}
+	for _, m := range cb.Mounts {
+		target := filepath.Join("/", m.Target)
+		switch {
+		case m.IsTmpFs() && !m.IsReadOnly():
+			p.tmpfs = append(p.tmpfs, target)
+			p.shm = p.shm || target == shmPath
+		case IsWritableDirMount(m):
+			p.noReuse = true
+		case m.FsType == "proc":
+			p.proc = target
+		}
+	}
+	return p
+}
+
+// IsWritableDirMount returns whether the mount is a writable bind mount of
+// directory (writable device files like /dev/null are fine), such mount could
+// not be cleaned between runs and the environment is not reused
+func IsWritableDirMount(m mount.Mount) bool {
+	if !m.IsBindMount() || m.IsReadOnly() {
+		return false
+	}
+	fi, err := os.Stat(m.Source)
+	return err == nil && fi.IsDir()
+}
+
+// withMount appends mount point to the container builder if possible
+func withMount(builder EnvironmentBuilder, m mount.Mount) EnvironmentBuilder {
+	cb, ok := builder.(*container.Builder)
+	if !ok {
+		return builder
+	}
+	nb := *cb
+	nb.Mounts = append(append(make([]mount.Mount, 0, len(cb.Mounts)+1), cb.Mounts...), m)
+	return &nb
+}
+
+// buildErrnos are errno commonly reported when creating containers. Errors
+// from the container builder and the container init are formatted as string,
+// so that errno is recognized by its message.
+var buildErrnos = []syscall.Errno{
+	syscall.ENOSPC, syscall.EUSERS, syscall.EAGAIN, syscall.ENOMEM,
+	syscall.EPERM, syscall.EACCES, syscall.ENOENT, syscall.ENOTDIR,
+	syscall.EBUSY, syscall.EINVAL, syscall.EROFS, syscall.EMFILE,
+	syscall.ENFILE, syscall.ENODEV,
+}
+
+func errnoOf(err error) syscall.Errno {
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return errno
+	}
+	msg := err.Error()
+	for _, e := range buildErrnos {
+		if strings.Contains(msg, e.Error()) {
+			return e
+		}
+	}
+	return 0
+}
+
+// hostErrnos are the errno of the exhausted host resources, with which every
+// environment creation fails until the host recovers
+var hostErrnos = []syscall.Errno{
+	syscall.ENOMEM, syscall.ENOSPC, syscall.EUSERS, syscall.EAGAIN,
+	syscall.EMFILE, syscall.ENFILE,
+}
+
+// hostError returns envexec.EnvironmentError of the reason if err is caused by
+// the exhausted host resources, otherwise err which is specific to the
+// environment being built (e.g. the mount source of its options is missing)
+func hostError(reason envexec.EnvironmentReason, err error) error {
+	errno := errnoOf(err)
+	for _, e := range hostErrnos {
+		if errno == e {
+			return &envexec.EnvironmentError{Reason: reason, Errno: errno, Err: err}
+		}
+	}
+	return err
+}
+
+// classifyBuildError converts the error of the container builder into
+// envexec.EnvironmentError if the host could not create any container, i.e.
+// clone of the container init failed or the host resources are exhausted
+func classifyBuildError(err error) error {
+	errno := errnoOf(err)
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "failed to start container"):
+		// clone(CLONE_NEW*) fails with ENOSPC / EUSERS when the namespace
+		// count limit (e.g. user.max_user_namespaces) is reached, and EAGAIN
+		// when the process limit is reached
+		reason := envexec.EnvironmentForkFailed
+		if errno == syscall.ENOSPC || errno == syscall.EUSERS {
+			reason = envexec.EnvironmentNamespaceLimit
+		}
+		return &envexec.EnvironmentError{Reason: reason, Errno: errno, Err: err}
+	case strings.HasPrefix(msg, "conf:") || strings.Contains(msg, "failed to make tmp container root"):
+		// container init sets up its root filesystem on conf, and it exits
+		// without reply when any of the mounts failed
+		return hostError(envexec.EnvironmentMountFailed, err)
+	}
+	return hostError(envexec.EnvironmentUnknown, err)
 }
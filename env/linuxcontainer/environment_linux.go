@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -23,37 +25,253 @@ var _ envexec.Environment = &environ{}
 type environ struct {
 	container.Environment
 	cgPool  CgroupPool
-	wd      *os.File // container work dir
+	cgEnv   *envCgroup // dedicated cgroup mounted inside the container
+	wd      *os.File   // container work dir
 	workDir string
 	cpuset  string
+	mems    string
 	seccomp []syscall.SockFilter
 	cpuRate bool
+	reset   resetParam
+	stamps  []mountStamp // sources of the bind mounts checked by Stale
+	initLog *initLog     // stderr of the container init, nil if not kept
+	diskDir string       // host directory backing the work directory, empty for tmpfs
+	scratch string       // host file mounted as the scratch file, empty if not
+
+	procInfo *procInfo // synthetic /proc files mounted, nil if not
+	random   *random   // deterministic random devices mounted, nil if not
+
+	initPid int // host pid of the container init, found on the first overhead measure
+
+	checkFds bool
+
+	attachRetry AttachRetry
+
+	emulation *emulation // nil if no foreign architecture is emulated
+}
+
+// resetParam defines how the environment is cleaned up between runs
+type resetParam struct {
+	tmpfs   []string // writable tmpfs mounts cleaned by the container
+	proc    string   // proc mount of the container pid namespace
+	noReuse bool     // writable bind mounts could not be cleaned
+	shm     bool     // writable tmpfs mounted at /dev/shm
+	verify  bool
 }
 
+var errNotReusable = errors.New("reset: environment with writable bind mount could not be reused")
+
 // Destroy destories the environment
 func (c *environ) Destroy() error {
-	return c.Environment.Destroy()
+	err := c.Environment.Destroy()
+	if c.cgEnv != nil {
+		c.cgEnv.Destroy()
+	}
+	if c.procInfo != nil {
+		c.procInfo.destroy()
+	}
+	if c.random != nil {
+		c.random.destroy()
+	}
+	if c.initLog != nil {
+		c.initLog.unregister()
+	}
+	return err
 }
 
+// Reset cleans up the environment for the next run. The container removes
+// contents of the tmpfs mounts and all processes are killed after each execve.
+// Environment with writable bind mounts could not be cleaned and is not reused.
 func (c *environ) Reset() error {
-	return c.Environment.Reset()
+	if c.reset.noReuse {
+		return errNotReusable
+	}
+	if c.initLog != nil {
+		c.initLog.reset()
+	}
+	if err := c.Environment.Reset(); err != nil {
+		return err
+	}
+	if c.cgEnv != nil {
+		// the sweep after the run failed to empty the cgroup
+		if pids, err := c.cgEnv.Procs(); err != nil || len(pids) > 0 {
+			return fmt.Errorf("reset: processes left in cgroup %v %v", pids, err)
+		}
+		if err := c.cgEnv.Reset(); err != nil {
+			return fmt.Errorf("reset: failed to reset cgroup %v", err)
+		}
+	}
+	if c.reset.verify {
+		return c.verifyReset()
+	}
+	return nil
 }
 
-// Execve execute process inside the environment
+// verifyReset checks the writable mounts are empty and no process other than
+// the container init is left inside the pid namespace
+func (c *environ) verifyReset() error {
+	paths := c.reset.tmpfs
+	if c.reset.proc != "" {
+		paths = append(paths[:len(paths):len(paths)], c.reset.proc)
+	}
+	cmds := make([]container.OpenCmd, 0, len(paths))
+	for _, p := range paths {
+		cmds = append(cmds, container.OpenCmd{
+			Path: p,
+			Flag: syscall.O_CLOEXEC | syscall.O_DIRECTORY | syscall.O_RDONLY,
+		})
+	}
+	fs, err := c.Environment.Open(cmds)
+	if err != nil {
+		return fmt.Errorf("reset: failed to open mounts %v", err)
+	}
+	defer closeFiles(fs)
+
+	for i, f := range fs[:len(c.reset.tmpfs)] {
+		names, err := f.Readdirnames(1)
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("reset: failed to read %s %v", paths[i], err)
+		}
+		if len(names) > 0 {
+			return fmt.Errorf("reset: %s is not empty: %s", paths[i], names[0])
+		}
+	}
+	if c.reset.proc != "" {
+		names, err := fs[len(fs)-1].Readdirnames(-1)
+		if err != nil {
+			return fmt.Errorf("reset: failed to read %s %v", c.reset.proc, err)
+		}
+		for _, n := range names {
+			if pid, err := strconv.Atoi(n); err == nil && pid != 1 {
+				return fmt.Errorf("reset: process %d is left in the container", pid)
+			}
+		}
+	}
+	return nil
+}
+
+func closeFiles(fs []*os.File) {
+	for _, f := range fs {
+		f.Close()
+	}
+}
+
+// Execve execute process inside the environment. Creating the cgroup and
+// attaching the process to it are retried on transient errors before the
+// process starts, so that nothing is charged to the process by the failed
+// attempts.
 func (c *environ) Execve(ctx context.Context, param envexec.ExecveParam) (envexec.Process, error) {
+	backoff := c.attachRetry.Backoff
+	for retries := 0; ; retries++ {
+		proc, err := c.execve(ctx, param)
+		if retries < c.attachRetry.Attempts && isTransientCgroupErr(err, proc) {
+			select {
+			case <-ctx.Done():
+			case <-time.After(backoff):
+				backoff *= 2
+				continue
+			}
+		}
+		if err != nil {
+			if retries > 0 {
+				return nil, &retryError{error: err, retries: retries}
+			}
+			return nil, err
+		}
+		proc.attachRetries = retries
+		return proc, nil
+	}
+}
+
+// retryError reports the number of retries through envexec.RetryProcess
+type retryError struct {
+	error
+	retries int
+}
+
+func (e *retryError) Unwrap() error {
+	return e.error
+}
+
+func (e *retryError) AttachRetries() int {
+	return e.retries
+}
+
+// isTransientCgroupErr reports whether the execve failed to create the cgroup
+// or attach the process with EBUSY / EAGAIN. The attach error is set before
+// the sync is done, and the process failed to attach is waited for since the
+// container runs one process at a time.
+func isTransientCgroupErr(err error, proc *process) bool {
+	if proc == nil {
+		return isTransientErr(err)
+	}
+	if !isTransientErr(proc.attachErr) {
+		return false
+	}
+	<-proc.done
+	return true
+}
+
+func isTransientErr(err error) bool {
+	return errors.Is(err, syscall.EBUSY) || errors.Is(err, syscall.EAGAIN)
+}
+
+func (c *environ) execve(ctx context.Context, param envexec.ExecveParam) (*process, error) {
 	var (
 		cg       Cgroup
 		syncFunc func(int) error
 		err      error
 	)
 
+	if param.Trace {
+		if err := c.TraceAvailable(); err != nil {
+			return nil, err
+		}
+	}
+
+	arch := param.Arch
+	if arch == envexec.ArchAuto {
+		arch = c.detectArch(param)
+	}
+	if param.Args, err = c.emulate(param, arch); err != nil {
+		return nil, err
+	}
+
 	limit := param.Limit
-	if c.cgPool != nil {
-		cg, err = c.cgPool.Get()
+	// the proc info of the previous run must not be seen by the run without
+	if c.procInfo != nil || param.ProcInfo != nil {
+		if c.procInfo == nil || param.ProcInfo == nil {
+			return nil, errNoProcInfo
+		}
+		if err := c.procInfo.write(param.ProcInfo); err != nil {
+			return nil, err
+		}
+	}
+	// the real devices are not mounted in place of the deterministic ones
+	if c.random != nil || param.Random != nil {
+		if c.random == nil || param.Random == nil {
+			return nil, errNoRandom
+		}
+		if err := c.random.write(param.Random); err != nil {
+			return nil, err
+		}
+	}
+	cgPool := c.cgPool
+	if c.cgEnv != nil {
+		// the limits are found at the root of the mounted view while the run
+		// gets a new cgroup under it
+		if err := c.setCgroupLimit(c.cgEnv, limit); err != nil {
+			return nil, err
+		}
+		cgPool = c.cgEnv
+	}
+	if cgPool != nil {
+		cg, err = cgPool.Get()
 		if err != nil {
-			return nil, fmt.Errorf("execve: failed to get cgroup %v", err)
+			return nil, fmt.Errorf("execve: failed to get cgroup %w", err)
 		}
 		if err := c.setCgroupLimit(cg, limit); err != nil {
+			cgPool.Put(cg)
 			return nil, err
 		}
 		syncFunc = cg.AddProc
@@ -64,35 +282,108 @@ func (c *environ) Execve(ctx context.Context, param envexec.ExecveParam) (envexe
 		FileSize:    limit.Output.Byte(),
 		Stack:       limit.Stack.Byte(),
 		OpenFile:    limit.OpenFile,
-		DisableCore: true,
+		DisableCore: limit.Core == 0,
 	}
 
-	if limit.StrictMemory || c.cgPool == nil {
+	if limit.StrictMemory || cg == nil {
 		rLimits.Data = limit.Memory.Byte()
 	}
 
+	if envexec.Arch32(arch) {
+		if rLimits.Data > arch32AddressSpace {
+			rLimits.Data = arch32AddressSpace
+		}
+		if rLimits.Stack > arch32StackMax {
+			rLimits.Stack = arch32StackMax
+		}
+	}
+
+	var t *tracer
+	if param.Trace {
+		t = newTracer()
+	}
+
+	var meter *overheadMeter
+	if param.Overhead {
+		meter = new(overheadMeter)
+		if c.initPid > 0 {
+			meter.start(c.initPid)
+		}
+	}
+
 	// wait for sync or error before turn (avoid file close before pass to child process)
 	syncDone := make(chan struct{})
 
-	p := container.ExecveParam{
-		Args:     param.Args,
-		Env:      param.Env,
-		Files:    param.Files,
-		CTTY:     param.TTY,
-		ExecFile: param.ExecFile,
-		RLimits:  rLimits.PrepareRLimit(),
-		Seccomp:  c.seccomp,
-		SyncFunc: func(pid int) error {
-			defer close(syncDone)
-			if syncFunc != nil {
-				return syncFunc(pid)
-			}
-			return nil
-		},
+	proc := newProcess(func(proc *process) runner.Result {
+		if t != nil {
+			defer func() {
+				proc.syscalls = t.wait()
+			}()
+		}
+		if c.diskDir != "" {
+			// writeback of the dirty pages is accounted to the cgroup
+			// before its io stat is read
+			defer unix.Syncfs(int(c.wd.Fd()))
+		}
+		if c.scratch != "" {
+			defer c.syncScratch()
+		}
+		rt := c.Environment.Execve(ctx, container.ExecveParam{
+			Args:     param.Args,
+			Env:      param.Env,
+			Files:    param.Files,
+			CTTY:     param.TTY,
+			ExecFile: param.ExecFile,
+			RLimits:  prepareRLimit(rLimits, limit.Core),
+			Seccomp:  c.seccomp,
+			SyncFunc: func(pid int) error {
+				defer close(syncDone)
+				if c.checkFds {
+					// the process is killed before execve if failed
+					if err := checkFds(pid, len(param.Files)); err != nil {
+						return err
+					}
+				}
+				if meter != nil {
+					// the container init forked the process
+					if c.initPid == 0 {
+						c.initPid, _ = parentPid(pid)
+					}
+					meter.sync(pid, c.initPid)
+				}
+				if syncFunc != nil {
+					// the process is killed before execve if failed
+					if proc.attachErr = syncFunc(pid); proc.attachErr != nil {
+						return proc.attachErr
+					}
+				}
+				if t != nil {
+					if err := t.seize(pid); err != nil {
+						return err
+					}
+				}
+				proc.watchExit(pid)
+				// execve follows right after the sync is acknowledged
+				proc.execAt = time.Now()
+				return nil
+			},
+		})
+		if meter != nil {
+			proc.overhead, proc.overheadOk = meter.stop()
+		}
+		if c.random != nil {
+			proc.randomOpened = c.random.opened()
+		}
+		return rt
+	}, cg, cgPool, param.DetailedMemory, limit.Proc)
+	proc.arch = arch
+	if c.emulation.emulated(arch) {
+		proc.emulated = true
+		proc.memoryBase = c.emulation.baseline[arch]
+	}
+	proc.execError = func(msg string) *envexec.FileError {
+		return c.execError(param, msg)
 	}
-	proc := newProcess(func() runner.Result {
-		return c.Environment.Execve(ctx, p)
-	}, cg, c.cgPool)
 
 	select {
 	case <-proc.done:
@@ -197,6 +488,26 @@ func (c *environ) Symlink(oldName, newName string) error {
 	return unix.Symlinkat(oldName, int(c.wd.Fd()), newName)
 }
 
+// Mkfifo creates the named pipe in container, the mode is set after creation
+// so that it is not masked by the umask
+func (c *environ) Mkfifo(path string) error {
+	var err error
+	if filepath.IsAbs(path) {
+		path, err = filepath.Rel(c.workDir, path)
+		if err != nil {
+			return &os.PathError{Op: "mkfifo", Path: path, Err: syscall.EINVAL}
+		}
+	}
+	wd := int(c.wd.Fd())
+	if err := unix.Mkfifoat(wd, path, 0666); err != nil {
+		return &os.PathError{Op: "mkfifo", Path: path, Err: err}
+	}
+	if err := unix.Fchmodat(wd, path, 0666, 0); err != nil {
+		return &os.PathError{Op: "chmod", Path: path, Err: err}
+	}
+	return nil
+}
+
 func (c *environ) setCgroupLimit(cg Cgroup, limit envexec.Limit) error {
 	cpuSet := limit.CPUSet
 	if cpuSet == "" {
@@ -207,6 +518,11 @@ func (c *environ) setCgroupLimit(cg Cgroup, limit envexec.Limit) error {
 			return fmt.Errorf("execve: cgroup failed to set cpu_set limit %v", err)
 		}
 	}
+	if c.mems != "" {
+		if err := cg.SetCpusetMems(c.mems); isCgroupSetHasError(err) {
+			return fmt.Errorf("execve: cgroup failed to set cpuset.mems %v", err)
+		}
+	}
 	if c.cpuRate && limit.Rate > 0 {
 		if err := cg.SetCPURate(limit.Rate); isCgroupSetHasError(err) {
 			return fmt.Errorf("execve: cgroup failed to set cpu_rate limit %v", err)
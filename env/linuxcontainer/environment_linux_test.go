@@ -0,0 +1,116 @@
+package linuxcontainer
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/container"
+	"github.com/criyle/go-sandbox/runner"
+)
+
+var errTestCgroup = errors.New("not available")
+
+// testCgroup fails to attach the process busy times with EBUSY
+type testCgroup struct {
+	Cgroup
+	busy     int
+	attached int
+}
+
+func (c *testCgroup) AddProc(int) error {
+	if c.busy > 0 {
+		c.busy--
+		return syscall.EBUSY
+	}
+	c.attached++
+	return nil
+}
+
+func (c *testCgroup) Reset() error                      { return nil }
+func (c *testCgroup) SetMemoryLimit(envexec.Size) error { return nil }
+func (c *testCgroup) SetProcLimit(uint64) error         { return nil }
+func (c *testCgroup) CPUUsage() (time.Duration, error)  { return 0, errTestCgroup }
+func (c *testCgroup) MaxMemory() (envexec.Size, error)  { return 0, errTestCgroup }
+func (c *testCgroup) IOStat() (envexec.IOStat, error)   { return envexec.IOStat{}, errTestCgroup }
+func (c *testCgroup) CPUThrottling() (envexec.CPUThrottling, error) {
+	return envexec.CPUThrottling{}, errTestCgroup
+}
+func (c *testCgroup) ProcLimitHit() (uint64, error) { return 0, errTestCgroup }
+func (c *testCgroup) PeakProc() (uint64, error)     { return 0, errTestCgroup }
+func (c *testCgroup) Procs() ([]int, error)         { return nil, nil }
+func (c *testCgroup) Destroy() error                { return nil }
+
+// testCgroupPool gets the same cgroup for each run
+type testCgroupPool struct {
+	cg Cgroup
+}
+
+func (p *testCgroupPool) Get() (Cgroup, error) { return p.cg, nil }
+func (p *testCgroupPool) Put(Cgroup)           {}
+
+// testContainer runs a short-lived host process for each execve, the process
+// failed to sync is killed and reported some time after the sync like the
+// container does
+type testContainer struct {
+	container.Environment
+	execs int
+}
+
+func (c *testContainer) Execve(_ context.Context, p container.ExecveParam) runner.Result {
+	c.execs++
+	cmd := exec.Command("sleep", "0.01")
+	if err := cmd.Start(); err != nil {
+		return runner.Result{Status: runner.StatusRunnerError, Error: err.Error()}
+	}
+	if err := p.SyncFunc(cmd.Process.Pid); err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		time.Sleep(50 * time.Millisecond)
+		return runner.Result{Status: runner.StatusRunnerError, Error: err.Error()}
+	}
+	cmd.Wait()
+	return runner.Result{Status: runner.StatusNormal}
+}
+
+func TestExecveRetryTransientAttachError(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		busy     int
+		attempts int
+		status   runner.Status
+		retries  int
+	}{
+		{"attached", 0, 3, runner.StatusNormal, 0},
+		{"retried", 2, 3, runner.StatusNormal, 2},
+		{"retries exhausted", 2, 1, runner.StatusRunnerError, 1},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			cg := &testCgroup{busy: c.busy}
+			ct := &testContainer{}
+			env := &environ{
+				Environment: ct,
+				cgPool:      &testCgroupPool{cg: cg},
+				attachRetry: AttachRetry{Attempts: c.attempts, Backoff: time.Millisecond},
+			}
+			p, err := env.Execve(context.Background(), envexec.ExecveParam{Limit: envexec.Limit{Memory: 64 << 20}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			<-p.Done()
+			if rt := p.Result(); rt.Status != c.status {
+				t.Fatalf("status %v, want %v: %s", rt.Status, c.status, rt.Error)
+			}
+			if r := p.(envexec.RetryProcess).AttachRetries(); r != c.retries {
+				t.Fatalf("retries %d, want %d", r, c.retries)
+			}
+			if ct.execs != c.retries+1 {
+				t.Fatalf("executed %d times, want %d", ct.execs, c.retries+1)
+			}
+		})
+	}
+}
@@ -1,33 +1,134 @@
 package linuxcontainer
 
 import (
+	"fmt"
+	"os"
+	"sync"
 	"time"
 
 	"github.com/criyle/go-judge/envexec"
 	"github.com/criyle/go-sandbox/runner"
+	"golang.org/x/sys/unix"
 )
 
-var _ envexec.Process = &process{}
+const (
+	sweepTimeout  = time.Second
+	sweepInterval = 5 * time.Millisecond
+)
+
+var (
+	_ envexec.Process           = &process{}
+	_ envexec.MemoryStatProcess = &process{}
+	_ envexec.ProcStatProcess   = &process{}
+	_ envexec.RetryProcess      = &process{}
+	_ envexec.IOStatProcess     = &process{}
+	_ envexec.ArchProcess       = &process{}
+	_ envexec.EmulatedProcess   = &process{}
+	_ envexec.RandomProcess     = &process{}
+	_ envexec.OverheadProcess   = &process{}
+
+	_ envexec.SyscallTraceProcess  = &process{}
+	_ envexec.CPUThrottlingProcess = &process{}
+	_ envexec.CgroupProcess        = &process{}
+)
 
 // process defines the running process
 type process struct {
 	rt   runner.Result
 	done chan struct{}
 	cg   Cgroup
+
+	// memory stat is only collected when detailed memory is requested. It is
+	// sampled during the run since the memory is freed once the process
+	// exits, the sizes are the largest sampled and the faults the last.
+	// Every field is relative to the base read before the run since the
+	// counters accumulate and the page cache is kept when the cgroup is reused
+	detailedMemory bool
+	memoryStatBase envexec.MemoryStat
+	memoryMu       sync.Mutex
+	memoryStat     envexec.MemoryStat
+	memoryStatOk   bool
+
+	// pids counters accumulate when the cgroup is reused, peak is sampled
+	// during the run and from pids.peak if it increased
+	procLimit     uint64
+	procLimitBase uint64
+	procPeakBase  uint64
+	procMu        sync.Mutex
+	procStat      envexec.ProcStat
+	procStatOk    bool
+	// processes left in the cgroup when the process exited
+	strayAtExit uint64
+	// cgroup still had processes after the sweep
+	sweepErr error
+
+	ioStat   envexec.IOStat
+	ioStatOk bool
+
+	throttling   envexec.CPUThrottling
+	throttlingOk bool
+
+	syscalls map[string]uint64 // nil if not traced
+
+	// cpu time of the container for the run if measured
+	overhead   time.Duration
+	overheadOk bool
+
+	arch string // detected or specified architecture
+
+	// the program ran under the emulator, whose memory measured by the
+	// calibration is subtracted from the memory of the run
+	emulated   bool
+	memoryBase envexec.Size
+
+	// the program opened the deterministic random devices
+	randomOpened bool
+
+	// execError classifies the runner error of the failed execve, it inspects
+	// the executable inside the environment held until the result is collected
+	execError func(msg string) *envexec.FileError
+
+	// attachErr is the error of attaching the process to the cgroup, the
+	// cgroup is destroyed instead of put back if it is transient
+	attachErr     error
+	attachRetries int
+
+	// the clock time is measured from the sync just before execve to the
+	// exit of the process observed by its pidfd, instead of the whole round
+	// trip to the container init which includes the setup and the cleanup
+	execAt time.Time
+	exitAt time.Time
+	exited chan struct{} // nil if the exit is not watched
 }
 
-func newProcess(run func() runner.Result, cg Cgroup, cgPool CgroupPool) *process {
+func newProcess(run func(*process) runner.Result, cg Cgroup, cgPool CgroupPool, detailedMemory bool, procLimit uint64) *process {
 	p := &process{
-		done: make(chan struct{}),
-		cg:   cg,
+		done:           make(chan struct{}),
+		cg:             cg,
+		detailedMemory: detailedMemory && cg != nil,
+		procLimit:      procLimit,
+	}
+	if p.detailedMemory {
+		p.memoryStatBase, _ = cg.MemoryStat()
+	}
+	if cg != nil {
+		p.procLimitBase, _ = cg.ProcLimitHit()
+		p.procPeakBase, _ = cg.PeakProc()
 	}
 	go func() {
 		defer close(p.done)
-		if cgPool != nil {
-			defer cgPool.Put(cg)
-		}
-		p.rt = run()
+		p.rt = run(p)
 		p.collectUsage()
+		p.collectRunningTime()
+		p.sweep()
+		if cgPool == nil {
+			return
+		}
+		if isTransientErr(p.attachErr) || p.sweepErr != nil {
+			cg.Destroy()
+		} else {
+			cgPool.Put(cg)
+		}
 	}()
 	return p
 }
@@ -42,6 +143,194 @@ func (p *process) collectUsage() {
 	if m, err := p.cg.MaxMemory(); err == nil && m > 0 {
 		p.rt.Memory = m
 	}
+	if s, err := p.cg.IOStat(); err == nil {
+		p.ioStat, p.ioStatOk = s, true
+	}
+	if s, err := p.cg.CPUThrottling(); err == nil {
+		p.throttling, p.throttlingOk = s, true
+	}
+	// read before the cgroup is put back to the pool
+	if p.detailedMemory {
+		p.sampleMemoryStat()
+	}
+	if n, err := p.cg.ProcLimitHit(); err == nil {
+		p.procMu.Lock()
+		defer p.procMu.Unlock()
+
+		p.procStat.LimitHit = n > p.procLimitBase
+		if peak, err := p.cg.PeakProc(); err == nil && peak > p.procPeakBase && peak > p.procStat.Peak {
+			p.procStat.Peak = peak
+		}
+		// the limit was reached if it was hit
+		if p.procStat.LimitHit && p.procStat.Peak < p.procLimit {
+			p.procStat.Peak = p.procLimit
+		}
+		// at least the process itself if it was started
+		if p.procStat.Peak == 0 && p.rt.Status != runner.StatusRunnerError {
+			p.procStat.Peak = 1
+		}
+		p.procStatOk = true
+	}
+}
+
+// watchExit records the time when the process exits through its pidfd. It is
+// called on the sync before execve, so that the pid is still alive.
+func (p *process) watchExit(pid int) {
+	fd, err := unix.PidfdOpen(pid, unix.PIDFD_NONBLOCK)
+	if err != nil {
+		// pidfd is not available before linux 5.3
+		return
+	}
+	f := os.NewFile(uintptr(fd), "pidfd")
+	rc, err := f.SyscallConn()
+	if err != nil {
+		f.Close()
+		return
+	}
+	p.exited = make(chan struct{})
+	go func() {
+		defer close(p.exited)
+		defer f.Close()
+
+		// pidfd becomes readable when the process exits, waiting on the
+		// runtime poller instead of a blocked thread
+		err := rc.Read(func(fd uintptr) bool {
+			n, err := unix.Poll([]unix.PollFd{{Fd: int32(fd), Events: unix.POLLIN}}, 0)
+			return err != unix.EINTR && n != 0
+		})
+		if err == nil {
+			p.exitAt = time.Now()
+			// before the container kills the rest of the pid namespace
+			if p.cg != nil {
+				p.countStray(pid)
+			}
+		}
+	}()
+}
+
+// collectRunningTime replaces the clock time measured by the container with
+// the one around execve and exit if both are recorded
+func (p *process) collectRunningTime() {
+	if p.exited == nil || p.execAt.IsZero() {
+		return
+	}
+	<-p.exited
+	if p.exitAt.After(p.execAt) {
+		p.rt.RunningTime = p.exitAt.Sub(p.execAt)
+	}
+}
+
+// sweep kills the processes left in the cgroup and waits for it to be empty.
+// The container kills everything in its pid namespace after the process
+// exits, so it only catches the processes that outlived the container
+// cleanup. The stray count is the larger of the ones observed at the exit
+// and the ones swept.
+func (p *process) sweep() {
+	if p.cg == nil {
+		return
+	}
+	var swept uint64
+	deadline := time.Now().Add(sweepTimeout)
+	for {
+		pids, err := p.cg.Procs()
+		if err != nil {
+			p.sweepErr = err
+			break
+		}
+		if len(pids) == 0 {
+			break
+		}
+		if swept == 0 {
+			swept = uint64(len(pids))
+		}
+		if time.Now().After(deadline) {
+			p.sweepErr = fmt.Errorf("sweep: %d processes left in the cgroup", len(pids))
+			break
+		}
+		if err := p.cg.Kill(); err != nil {
+			p.sweepErr = err
+			break
+		}
+		time.Sleep(sweepInterval)
+	}
+
+	p.procMu.Lock()
+	defer p.procMu.Unlock()
+	p.procStat.Stray = p.strayAtExit
+	if swept > p.procStat.Stray {
+		p.procStat.Stray = swept
+	}
+}
+
+// countStray counts the processes other than the exited one in the cgroup
+func (p *process) countStray(pid int) {
+	pids, err := p.cg.Procs()
+	if err != nil {
+		return
+	}
+	for _, n := range pids {
+		if n != pid {
+			p.strayAtExit++
+		}
+	}
+}
+
+// sampleProc updates the peak by the current number of processes
+func (p *process) sampleProc() {
+	n, err := p.cg.CurrentProc()
+	if err != nil {
+		return
+	}
+	p.procMu.Lock()
+	defer p.procMu.Unlock()
+	if n > p.procStat.Peak {
+		p.procStat.Peak = n
+	}
+}
+
+// sampleMemoryStat updates the memory stat by the current one relative to
+// the base
+func (p *process) sampleMemoryStat() {
+	s, err := p.cg.MemoryStat()
+	if err != nil {
+		return
+	}
+	b := p.memoryStatBase
+	s = envexec.MemoryStat{
+		Cache:          sizeSince(s.Cache, b.Cache),
+		RSS:            sizeSince(s.RSS, b.RSS),
+		MappedFile:     sizeSince(s.MappedFile, b.MappedFile),
+		PageFault:      countSince(s.PageFault, b.PageFault),
+		MajorPageFault: countSince(s.MajorPageFault, b.MajorPageFault),
+	}
+	p.memoryMu.Lock()
+	defer p.memoryMu.Unlock()
+	if s.Cache > p.memoryStat.Cache {
+		p.memoryStat.Cache = s.Cache
+	}
+	if s.RSS > p.memoryStat.RSS {
+		p.memoryStat.RSS = s.RSS
+	}
+	if s.MappedFile > p.memoryStat.MappedFile {
+		p.memoryStat.MappedFile = s.MappedFile
+	}
+	p.memoryStat.PageFault = s.PageFault
+	p.memoryStat.MajorPageFault = s.MajorPageFault
+	p.memoryStatOk = true
+}
+
+func sizeSince(s, base envexec.Size) envexec.Size {
+	if s < base {
+		return 0
+	}
+	return s - base
+}
+
+func countSince(n, base uint64) uint64 {
+	if n < base {
+		return 0
+	}
+	return n - base
 }
 
 func (p *process) Done() <-chan struct{} {
@@ -50,20 +339,114 @@ func (p *process) Done() <-chan struct{} {
 
 func (p *process) Result() envexec.RunnerResult {
 	<-p.done
-	return p.rt
+	rt := p.rt
+	rt.Memory = p.subtractBase(rt.Memory)
+	return rt
+}
+
+// subtractBase subtracts the memory of the emulator from the memory of the
+// emulated run
+func (p *process) subtractBase(m envexec.Size) envexec.Size {
+	if m < p.memoryBase {
+		return 0
+	}
+	return m - p.memoryBase
+}
+
+func (p *process) MemoryStat() (envexec.MemoryStat, bool) {
+	<-p.done
+	p.memoryMu.Lock()
+	defer p.memoryMu.Unlock()
+	return p.memoryStat, p.memoryStatOk
+}
+
+func (p *process) ProcStat() (envexec.ProcStat, bool) {
+	<-p.done
+	p.procMu.Lock()
+	defer p.procMu.Unlock()
+	return p.procStat, p.procStatOk
+}
+
+func (p *process) IOStat() (envexec.IOStat, bool) {
+	<-p.done
+	return p.ioStat, p.ioStatOk
+}
+
+func (p *process) CPUThrottling() (envexec.CPUThrottling, bool) {
+	<-p.done
+	return p.throttling, p.throttlingOk
+}
+
+func (p *process) CgroupID() (uint64, bool) {
+	if p.cg == nil {
+		return 0, false
+	}
+	return p.cg.ID(), true
+}
+
+func (p *process) Arch() (string, bool) {
+	<-p.done
+	unsupported := p.rt.Status == runner.StatusRunnerError && archUnsupported(p.arch, p.rt.Error)
+	return p.arch, unsupported
+}
+
+func (p *process) Emulated() bool {
+	<-p.done
+	return p.emulated
+}
+
+func (p *process) RandomOpened() bool {
+	<-p.done
+	return p.randomOpened
+}
+
+func (p *process) ExecError() *envexec.FileError {
+	<-p.done
+	if p.rt.Status != runner.StatusRunnerError || p.execError == nil {
+		return nil
+	}
+	return p.execError(p.rt.Error)
+}
+
+func (p *process) SyscallCount() (map[string]uint64, bool) {
+	<-p.done
+	return p.syscalls, p.syscalls != nil
 }
 
 func (p *process) Usage() envexec.Usage {
+	u := p.PeekUsage()
+	if p.cg != nil {
+		p.sampleProc()
+	}
+	if p.detailedMemory {
+		p.sampleMemoryStat()
+	}
+	return u
+}
+
+func (p *process) PeekUsage() envexec.Usage {
 	var (
-		t time.Duration
-		m envexec.Size
+		t  time.Duration
+		m  envexec.Size
+		io envexec.IOStat
 	)
 	if p.cg != nil {
 		t, _ = p.cg.CPUUsage()
 		m, _ = p.cg.CurrentMemory()
+		io, _ = p.cg.IOStat()
 	}
 	return envexec.Usage{
-		Time:   t,
-		Memory: m,
+		Time:    t,
+		Memory:  p.subtractBase(m),
+		IOWrite: io.Write,
 	}
 }
+
+func (p *process) OverheadTime() (time.Duration, bool) {
+	<-p.done
+	return p.overhead, p.overheadOk
+}
+
+func (p *process) AttachRetries() int {
+	return p.attachRetries
+}
@@ -0,0 +1,51 @@
+package linuxcontainer
+
+import (
+	"testing"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/runner"
+)
+
+// memoryStatCgroup reads the memory stats in order, the last one is repeated
+type memoryStatCgroup struct {
+	testCgroup
+	stats []envexec.MemoryStat
+}
+
+func (c *memoryStatCgroup) MemoryStat() (envexec.MemoryStat, error) {
+	s := c.stats[0]
+	if len(c.stats) > 1 {
+		c.stats = c.stats[1:]
+	}
+	return s, nil
+}
+
+func (c *memoryStatCgroup) CurrentMemory() (envexec.Size, error) { return 0, errTestCgroup }
+func (c *memoryStatCgroup) CurrentProc() (uint64, error)         { return 0, errTestCgroup }
+
+func TestProcessMemoryStat(t *testing.T) {
+	cg := &memoryStatCgroup{stats: []envexec.MemoryStat{
+		// left by the previous run of the reused cgroup
+		{Cache: 100, MappedFile: 10, PageFault: 1000, MajorPageFault: 10},
+		// sampled during the run
+		{Cache: 150, RSS: 500, MappedFile: 60, PageFault: 1100, MajorPageFault: 12},
+		{Cache: 120, RSS: 800, MappedFile: 30, PageFault: 1200, MajorPageFault: 15},
+		// the memory of the program is freed after it exited
+		{Cache: 90, PageFault: 1250, MajorPageFault: 15},
+	}}
+	p := newProcess(func(p *process) runner.Result {
+		p.Usage()
+		p.Usage()
+		return runner.Result{Status: runner.StatusNormal}
+	}, cg, nil, true, 0)
+
+	s, ok := p.MemoryStat()
+	if !ok {
+		t.Fatal("memory stat is not collected")
+	}
+	want := envexec.MemoryStat{Cache: 50, RSS: 800, MappedFile: 50, PageFault: 250, MajorPageFault: 5}
+	if s != want {
+		t.Fatalf("memory stat %+v, want %+v", s, want)
+	}
+}
@@ -0,0 +1,122 @@
+package linuxcontainer
+
+import (
+	"bufio"
+	"debug/elf"
+	"io"
+	"os/exec"
+	"strings"
+	"syscall"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/container"
+	"golang.org/x/sys/unix"
+)
+
+// shebangMax is the size of the buffer the kernel reads the "#!" line from
+// (BINPRM_BUF_SIZE)
+const shebangMax = 256
+
+// execError classifies the execve failure reported by the container init by
+// its errno, nil if the program was executed. ENOENT is reported for both
+// missing executable and missing interpreter, so that the executable is
+// opened inside the container to find the interpreter of the script or the
+// loader of the ELF file.
+func (c *environ) execError(param envexec.ExecveParam, msg string) *envexec.FileError {
+	name := "execFile"
+	if len(param.Args) > 0 {
+		name = param.Args[0]
+	}
+	switch {
+	// the name without slash is looked up in PATH by the container init
+	case strings.HasSuffix(msg, exec.ErrNotFound.Error()):
+		return &envexec.FileError{Name: name, Type: envexec.ErrExecNotFound, Message: "file not found: " + name}
+	case isExecErrno(msg, syscall.ENOENT):
+		if interp := c.execInterpreter(param); interp != "" {
+			return &envexec.FileError{Name: name, Type: envexec.ErrExecMissingInterpreter, Message: "missing interpreter: " + interp}
+		}
+		return &envexec.FileError{Name: name, Type: envexec.ErrExecNotFound, Message: "file not found: " + name}
+	case isExecErrno(msg, syscall.EACCES):
+		return &envexec.FileError{Name: name, Type: envexec.ErrExecNotExecutable, Message: "not executable: " + name}
+	case isExecErrno(msg, syscall.ENOEXEC):
+		if fe := c.needsEmulation(param, name); fe != nil {
+			return fe
+		}
+		return &envexec.FileError{Name: name, Type: envexec.ErrExecInvalidFormat, Message: "invalid executable format: " + name}
+	}
+	return nil
+}
+
+// isExecErrno reports whether the child failed at execve with errno, the
+// message ends with the error location of go-sandbox forkexec
+func isExecErrno(msg string, errno syscall.Errno) bool {
+	return strings.HasSuffix(msg, "execve: "+errno.Error())
+}
+
+// execInterpreter returns the interpreter of the executable opened in the
+// same way as execve, empty if it could not be opened (i.e. the executable
+// itself is missing) or it has no interpreter
+func (c *environ) execInterpreter(param envexec.ExecveParam) string {
+	if param.ExecFile > 0 {
+		return interpreter(fdReader(param.ExecFile))
+	}
+	if len(param.Args) == 0 {
+		return ""
+	}
+	for _, p := range execCandidates(param.Args[0], param.Env) {
+		fs, err := c.Environment.Open([]container.OpenCmd{{
+			Path: p,
+			Flag: syscall.O_RDONLY | syscall.O_CLOEXEC | syscall.O_NONBLOCK,
+		}})
+		if err != nil {
+			continue
+		}
+		defer fs[0].Close()
+		return interpreter(fs[0])
+	}
+	return ""
+}
+
+// fdReader reads the exec file by pread without taking its ownership
+type fdReader uintptr
+
+func (r fdReader) ReadAt(p []byte, off int64) (int, error) {
+	n, err := unix.Pread(int(r), p, off)
+	if err == nil && n < len(p) {
+		err = io.EOF
+	}
+	if n < 0 {
+		n = 0
+	}
+	return n, err
+}
+
+// interpreter returns the path after "#!" of the script or the PT_INTERP of
+// the ELF file
+func interpreter(f io.ReaderAt) string {
+	h := make([]byte, shebangMax)
+	n, _ := f.ReadAt(h, 0)
+	h = h[:n]
+	if line, ok := strings.CutPrefix(string(h), "#!"); ok {
+		line, _, _ = strings.Cut(line, "\n")
+		if fields := strings.Fields(line); len(fields) > 0 {
+			return fields[0]
+		}
+		return ""
+	}
+	ef, err := elf.NewFile(f)
+	if err != nil {
+		return ""
+	}
+	for _, p := range ef.Progs {
+		if p.Type != elf.PT_INTERP {
+			continue
+		}
+		s, err := bufio.NewReader(p.Open()).ReadString(0)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSuffix(s, "\x00")
+	}
+	return ""
+}
@@ -0,0 +1,56 @@
+package linuxcontainer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// checkFds verifies the fd table of the process stopped before execve, only
+// the mapped files [0, n) could be inherited by the program and the others
+// (e.g. the sync pipe of the container) must be close-on-exec
+func checkFds(pid, n int) error {
+	dir := "/proc/" + strconv.Itoa(pid)
+	f, err := os.Open(dir + "/fd")
+	if err != nil {
+		return fmt.Errorf("failed to check fds %v", err)
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to check fds %v", err)
+	}
+	for _, name := range names {
+		fd, err := strconv.Atoi(name)
+		if err != nil || fd < n {
+			continue
+		}
+		flags, err := fdFlags(dir + "/fdinfo/" + name)
+		if err != nil {
+			return fmt.Errorf("failed to check fd %d %v", fd, err)
+		}
+		if flags&unix.O_CLOEXEC == 0 {
+			target, _ := os.Readlink(dir + "/fd/" + name)
+			return fmt.Errorf("fd %d (%s) would be inherited by the program", fd, target)
+		}
+	}
+	return nil
+}
+
+// fdFlags reads the octal open flags from the fdinfo
+func fdFlags(path string) (int, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	for _, l := range bytes.Split(b, []byte("\n")) {
+		if v, ok := bytes.CutPrefix(l, []byte("flags:")); ok {
+			n, err := strconv.ParseUint(string(bytes.TrimSpace(v)), 8, 32)
+			return int(n), err
+		}
+	}
+	return 0, fmt.Errorf("flags not found in %s", path)
+}
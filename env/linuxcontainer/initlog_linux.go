@@ -0,0 +1,162 @@
+package linuxcontainer
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/criyle/go-sandbox/container"
+)
+
+// InitLog defines the bounded buffer of the stderr of the container init
+type InitLog struct {
+	Size       int               // bytes kept for each environment, 0 writes to the stderr of the builder
+	OnOverflow func(dropped int) // called with the bytes dropped from the buffer
+}
+
+// InitLogStat reports the stderr of the container init of an environment
+type InitLogStat struct {
+	ID      uint64
+	Log     []byte
+	Dropped uint64 // bytes dropped since created or recycled
+}
+
+var (
+	initLogID  uint64
+	initLogs   = make(map[uint64]*initLog) // environments not destroyed
+	initLogsMu sync.Mutex
+)
+
+// InitLogs returns the stderr of the container init of each environment not
+// destroyed, in order of creation
+func InitLogs() []InitLogStat {
+	initLogsMu.Lock()
+	logs := make([]*initLog, 0, len(initLogs))
+	for _, l := range initLogs {
+		logs = append(logs, l)
+	}
+	initLogsMu.Unlock()
+
+	rt := make([]InitLogStat, 0, len(logs))
+	for _, l := range logs {
+		log, dropped := l.get()
+		rt = append(rt, InitLogStat{ID: l.id, Log: log, Dropped: dropped})
+	}
+	sort.Slice(rt, func(i, j int) bool { return rt[i].ID < rt[j].ID })
+	return rt
+}
+
+// initLog keeps the last bytes written by the container init into a ring
+// buffer. The stderr is passed as a pipe, so that the container init never
+// shares it with the programs and the server stderr is not flooded (e.g. by
+// repeated mount errors).
+type initLog struct {
+	id         uint64
+	onOverflow func(int)
+
+	mu      sync.Mutex
+	buf     []byte
+	start   int // offset of the oldest byte
+	n       int
+	dropped uint64
+}
+
+// newInitLog creates the pipe as the stderr of the container to build, the
+// write end is closed by started after the container is built
+func newInitLog(c InitLog, builder EnvironmentBuilder) (EnvironmentBuilder, *initLog, *os.File, error) {
+	cb, ok := builder.(*container.Builder)
+	if !ok || c.Size <= 0 {
+		return builder, nil, nil, nil
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("container: failed to create init stderr pipe %v", err)
+	}
+	l := &initLog{
+		id:         atomic.AddUint64(&initLogID, 1),
+		onOverflow: c.OnOverflow,
+		buf:        make([]byte, c.Size),
+	}
+	go func() {
+		io.Copy(l, r)
+		r.Close()
+	}()
+	nb := *cb
+	nb.Stderr = w
+	return &nb, l, w, nil
+}
+
+// register makes the log listed by InitLogs until unregister
+func (l *initLog) register() {
+	initLogsMu.Lock()
+	initLogs[l.id] = l
+	initLogsMu.Unlock()
+}
+
+func (l *initLog) unregister() {
+	initLogsMu.Lock()
+	delete(initLogs, l.id)
+	initLogsMu.Unlock()
+}
+
+func (l *initLog) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	size := len(l.buf)
+	written := len(p)
+	dropped := 0
+	if len(p) > size {
+		dropped += len(p) - size
+		p = p[len(p)-size:]
+	}
+	if over := l.n + len(p) - size; over > 0 {
+		dropped += over
+		l.start = (l.start + over) % size
+		l.n -= over
+	}
+	end := (l.start + l.n) % size
+	c := copy(l.buf[end:], p)
+	copy(l.buf, p[c:])
+	l.n += len(p)
+	l.dropped += uint64(dropped)
+	l.mu.Unlock()
+
+	if dropped > 0 && l.onOverflow != nil {
+		l.onOverflow(dropped)
+	}
+	return written, nil
+}
+
+// get returns the bytes kept in order
+func (l *initLog) get() ([]byte, uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rt := make([]byte, 0, l.n)
+	end := l.start + l.n
+	if end <= len(l.buf) {
+		rt = append(rt, l.buf[l.start:end]...)
+	} else {
+		rt = append(append(rt, l.buf[l.start:]...), l.buf[:end-len(l.buf)]...)
+	}
+	return rt, l.dropped
+}
+
+// reset discards the bytes kept when the environment is recycled
+func (l *initLog) reset() {
+	l.mu.Lock()
+	l.start, l.n, l.dropped = 0, 0, 0
+	l.mu.Unlock()
+}
+
+// InitLog returns the stderr of the container init written since the
+// environment was created or recycled, nil if it is not kept
+func (c *environ) InitLog() []byte {
+	if c.initLog == nil {
+		return nil
+	}
+	log, _ := c.initLog.get()
+	return log
+}
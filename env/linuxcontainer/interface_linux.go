@@ -13,4 +13,5 @@ type EnvironmentBuilder interface {
 // CgroupBuilder builds cgroup for runner
 type CgroupBuilder interface {
 	Random(string) (cg cgroup.Cgroup, err error)
+	Build(string) (cg cgroup.Cgroup, err error)
 }
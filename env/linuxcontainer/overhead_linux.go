@@ -0,0 +1,113 @@
+package linuxcontainer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// overheadMeter measures the cpu time spent by the container for a run, which
+// is not charged to the cgroup of the process: the container init handling
+// the requests and the forked child before it is attached to the cgroup. The
+// cgroup of the run never contains the container init.
+type overheadMeter struct {
+	initPid  int // host pid of the container init
+	initBase time.Duration
+	child    time.Duration // before attached to the cgroup
+	ok       bool
+}
+
+// start records the cpu time of the container init, it is called before the
+// request is sent if the init is known so that the fork is measured
+func (m *overheadMeter) start(initPid int) {
+	t, err := threadsCPUTime(initPid)
+	m.initPid, m.initBase, m.ok = initPid, t, err == nil
+}
+
+// sync records the cpu time of the child before it is attached, and starts
+// from the sync if the init was not known
+func (m *overheadMeter) sync(pid, initPid int) {
+	if m.initPid == 0 && initPid > 0 {
+		m.start(initPid)
+	}
+	if t, err := taskCPUTime(pid, pid); err == nil {
+		m.child = t
+	}
+}
+
+// stop returns the overhead since start
+func (m *overheadMeter) stop() (time.Duration, bool) {
+	if !m.ok {
+		return 0, false
+	}
+	t, err := threadsCPUTime(m.initPid)
+	if err != nil || t < m.initBase {
+		return 0, false
+	}
+	return t - m.initBase + m.child, true
+}
+
+// parentPid reads the parent pid of the process from /proc/<pid>/stat
+func parentPid(pid int) (int, error) {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/stat")
+	if err != nil {
+		return 0, err
+	}
+	// pid (comm) state ppid ..., comm may contain spaces and parentheses
+	i := bytes.LastIndexByte(b, ')')
+	if i < 0 {
+		return 0, fmt.Errorf("invalid stat of %d", pid)
+	}
+	f := bytes.Fields(b[i+1:])
+	if len(f) < 2 {
+		return 0, fmt.Errorf("invalid stat of %d", pid)
+	}
+	return strconv.Atoi(string(f[1]))
+}
+
+// threadsCPUTime sums the cpu time of all threads of the process in
+// nanoseconds from schedstat, the time of the exited threads is not included
+func threadsCPUTime(pid int) (time.Duration, error) {
+	f, err := os.Open("/proc/" + strconv.Itoa(pid) + "/task")
+	if err != nil {
+		return 0, err
+	}
+	names, err := f.Readdirnames(-1)
+	f.Close()
+	if err != nil {
+		return 0, err
+	}
+	var sum time.Duration
+	for _, n := range names {
+		tid, err := strconv.Atoi(n)
+		if err != nil {
+			continue
+		}
+		t, err := taskCPUTime(pid, tid)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+		sum += t
+	}
+	return sum, nil
+}
+
+// taskCPUTime reads the time spent on the cpu by the thread from the first
+// field of /proc/<pid>/task/<tid>/schedstat
+func taskCPUTime(pid, tid int) (time.Duration, error) {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/task/" + strconv.Itoa(tid) + "/schedstat")
+	if err != nil {
+		return 0, err
+	}
+	f := bytes.Fields(b)
+	if len(f) == 0 {
+		return 0, fmt.Errorf("invalid schedstat of %d", tid)
+	}
+	n, err := strconv.ParseUint(string(f[0]), 10, 64)
+	return time.Duration(n), err
+}
@@ -0,0 +1,152 @@
+package linuxcontainer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// procInfoFiles are the files under /proc replaced by the synthetic ones
+var procInfoFiles = []string{"meminfo", "cpuinfo"}
+
+var errNoProcInfo = errors.New("execve: synthetic proc info is not mounted in the environment")
+
+// procInfo keeps the host files bind mounted read-only over /proc/meminfo and
+// /proc/cpuinfo in the container. The files are rewritten in place before each
+// execve so that the mounts see the limits of the run. They are unlinked once
+// mounted and kept open, so that nothing is left on the host if the
+// environment is not destroyed (e.g. the server exited).
+type procInfo struct {
+	dir   string
+	files map[string]*os.File
+	cpu   []string // lines of the first processor in the host /proc/cpuinfo
+}
+
+// newProcInfo creates the host directory of the synthetic files
+func newProcInfo() (*procInfo, error) {
+	dir, err := os.MkdirTemp("", "go-judge-proc")
+	if err != nil {
+		return nil, fmt.Errorf("proc info: failed to create directory %v", err)
+	}
+	p := &procInfo{dir: dir, files: make(map[string]*os.File), cpu: hostCPUInfo()}
+	for _, n := range procInfoFiles {
+		f, err := os.OpenFile(p.path(n), os.O_RDWR|os.O_CREATE|os.O_EXCL|syscall.O_CLOEXEC, 0644)
+		if err != nil {
+			p.destroy()
+			return nil, fmt.Errorf("proc info: failed to create %s %v", n, err)
+		}
+		p.files[n] = f
+	}
+	return p, nil
+}
+
+// hostCPUInfo returns the lines of the first processor in /proc/cpuinfo, which
+// describes the model of the generated processors
+func hostCPUInfo() []string {
+	b, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return nil
+	}
+	if i := bytes.Index(b, []byte("\n\n")); i >= 0 {
+		b = b[:i]
+	}
+	return strings.Split(strings.TrimSpace(string(b)), "\n")
+}
+
+func (p *procInfo) path(name string) string {
+	return filepath.Join(p.dir, name)
+}
+
+// unlink removes the host directory after the files are mounted
+func (p *procInfo) unlink() error {
+	return os.RemoveAll(p.dir)
+}
+
+// write generates the files for the run
+func (p *procInfo) write(info *envexec.ProcInfo) error {
+	for n, b := range map[string][]byte{
+		"meminfo": formatMemInfo(info.Memory),
+		"cpuinfo": p.formatCPUInfo(info.CPUs),
+	} {
+		f := p.files[n]
+		if err := f.Truncate(0); err != nil {
+			return fmt.Errorf("execve: failed to write %s %v", n, err)
+		}
+		if _, err := f.WriteAt(b, 0); err != nil {
+			return fmt.Errorf("execve: failed to write %s %v", n, err)
+		}
+	}
+	return nil
+}
+
+func (p *procInfo) destroy() {
+	for _, f := range p.files {
+		f.Close()
+	}
+	p.unlink()
+}
+
+// formatMemInfo reports the memory as the total and available memory without
+// swap
+func formatMemInfo(memory envexec.Size) []byte {
+	kb := memory.Byte() >> 10
+	var b bytes.Buffer
+	for _, f := range []struct {
+		name string
+		kb   uint64
+	}{
+		{"MemTotal", kb},
+		{"MemFree", kb},
+		{"MemAvailable", kb},
+		{"Buffers", 0},
+		{"Cached", 0},
+		{"SwapCached", 0},
+		{"Active", 0},
+		{"Inactive", 0},
+		{"SwapTotal", 0},
+		{"SwapFree", 0},
+		{"Shmem", 0},
+	} {
+		fmt.Fprintf(&b, "%-16s%8d kB\n", f.name+":", f.kb)
+	}
+	return b.Bytes()
+}
+
+// formatCPUInfo repeats the host processor for cpus times with the processor
+// and core ids renumbered
+func (p *procInfo) formatCPUInfo(cpus uint64) []byte {
+	if cpus == 0 {
+		cpus = 1
+	}
+	n := strconv.FormatUint(cpus, 10)
+	var b bytes.Buffer
+	for i := uint64(0); i < cpus; i++ {
+		id := strconv.FormatUint(i, 10)
+		if len(p.cpu) == 0 {
+			fmt.Fprintf(&b, "processor\t: %s\n\n", id)
+			continue
+		}
+		for _, l := range p.cpu {
+			k, _, _ := strings.Cut(l, ":")
+			switch strings.TrimSpace(k) {
+			case "processor", "core id", "apicid", "initial apicid":
+				l = k + ": " + id
+			case "siblings", "cpu cores":
+				l = k + ": " + n
+			case "physical id":
+				l = k + ": 0"
+			}
+			b.WriteString(l)
+			b.WriteByte('\n')
+		}
+		b.WriteByte('\n')
+	}
+	return b.Bytes()
+}
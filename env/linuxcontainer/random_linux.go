@@ -0,0 +1,138 @@
+package linuxcontainer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// randomDevices are the devices in the container replaced by the deterministic
+// stream, both are mounted from the same host file
+var randomDevices = []string{"dev/urandom", "dev/random"}
+
+func isRandomDevice(target string) bool {
+	for _, d := range randomDevices {
+		if strings.TrimPrefix(target, "/") == d {
+			return true
+		}
+	}
+	return false
+}
+
+var errNoRandom = errors.New("execve: deterministic random is not mounted in the environment")
+
+// random keeps the host file bind mounted read-only over /dev/urandom and
+// /dev/random in the container. The file is rewritten in place with the
+// stream of the seed before each execve if the seed changed, and it is
+// unlinked once mounted and kept open as the files of procInfo. The opens by
+// the program are observed by inotify on the file, which sees the opens
+// through the bind mounts.
+type random struct {
+	dir     string
+	file    *os.File
+	size    uint64
+	seed    uint64
+	written bool
+	inotify int
+}
+
+// newRandom creates the host file of the stream of size
+func newRandom(size uint64) (*random, error) {
+	dir, err := os.MkdirTemp("", "go-judge-random")
+	if err != nil {
+		return nil, fmt.Errorf("random: failed to create directory %v", err)
+	}
+	r := &random{dir: dir, size: size, inotify: -1}
+	r.file, err = os.OpenFile(r.path(), os.O_RDWR|os.O_CREATE|os.O_EXCL|syscall.O_CLOEXEC, 0644)
+	if err != nil {
+		r.destroy()
+		return nil, fmt.Errorf("random: failed to create file %v", err)
+	}
+	r.inotify, err = syscall.InotifyInit1(syscall.IN_NONBLOCK | syscall.IN_CLOEXEC)
+	if err != nil {
+		r.destroy()
+		return nil, fmt.Errorf("random: failed to create inotify %v", err)
+	}
+	if _, err := syscall.InotifyAddWatch(r.inotify, r.path(), syscall.IN_OPEN); err != nil {
+		r.destroy()
+		return nil, fmt.Errorf("random: failed to watch file %v", err)
+	}
+	return r, nil
+}
+
+func (r *random) path() string {
+	return filepath.Join(r.dir, "random")
+}
+
+// unlink removes the host directory after the file is mounted
+func (r *random) unlink() error {
+	return os.RemoveAll(r.dir)
+}
+
+// write generates the stream of the seed for the run and discards the opens
+// observed before
+func (r *random) write(info *envexec.Random) error {
+	if !r.written || r.seed != info.Seed {
+		r.written = false
+		if err := r.file.Truncate(0); err != nil {
+			return fmt.Errorf("execve: failed to write random %v", err)
+		}
+		if _, err := r.file.WriteAt(randomStream(info.Seed, r.size), 0); err != nil {
+			return fmt.Errorf("execve: failed to write random %v", err)
+		}
+		r.seed, r.written = info.Seed, true
+	}
+	r.opened()
+	return nil
+}
+
+// opened reports whether the file was opened since the last call
+func (r *random) opened() bool {
+	var buf [4096]byte
+	opened := false
+	for {
+		n, err := syscall.Read(r.inotify, buf[:])
+		if err != nil || n <= 0 {
+			return opened
+		}
+		for off := 0; off+syscall.SizeofInotifyEvent <= n; {
+			e := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			if e.Mask&syscall.IN_OPEN != 0 {
+				opened = true
+			}
+			off += syscall.SizeofInotifyEvent + int(e.Len)
+		}
+	}
+}
+
+func (r *random) destroy() {
+	if r.file != nil {
+		r.file.Close()
+	}
+	if r.inotify >= 0 {
+		syscall.Close(r.inotify)
+	}
+	r.unlink()
+}
+
+// randomStream generates the AES-128-CTR key stream keyed by the first 16
+// bytes of the SHA-256 of the little endian seed, with zero iv
+func randomStream(seed, size uint64) []byte {
+	var s [8]byte
+	binary.LittleEndian.PutUint64(s[:], seed)
+	key := sha256.Sum256(s[:])
+	b, _ := aes.NewCipher(key[:16])
+	buf := make([]byte, size)
+	cipher.NewCTR(b, make([]byte, aes.BlockSize)).XORKeyStream(buf, buf)
+	return buf
+}
@@ -0,0 +1,56 @@
+package linuxcontainer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/container"
+	"github.com/criyle/go-sandbox/pkg/mount"
+	"golang.org/x/sys/unix"
+)
+
+// buildScratch creates linux container with the host file bind mounted
+// writable at name in the work directory. The file is owned by the credential
+// the container runs as.
+func (b *environmentBuilder) buildScratch(nb *container.Builder, file, name string) (pool.Environment, error) {
+	uid, gid := ownerCred(nb)
+	if err := os.Lchown(file, uid, gid); err != nil {
+		return nil, fmt.Errorf("scratch file: %v", err)
+	}
+
+	// mounted after the tmpfs of the work directory
+	target := filepath.Join(strings.TrimPrefix(b.workDir, "/"), name)
+	mb := mount.NewBuilder().WithBind(file, target, false)
+	nb.Mounts = appendMounts(nb.Mounts, mb.Mounts)
+	e, err := b.build(nb, b.workDir)
+	if err != nil {
+		return nil, err
+	}
+	e.(*environ).scratch = file
+	return e, nil
+}
+
+// scratchUsage returns the size of the scratch file, its blocks are allocated
+// beforehand so that the size written by the program is checked instead
+func (c *environ) scratchUsage() envexec.Size {
+	fi, err := os.Stat(c.scratch)
+	if err != nil {
+		return 0
+	}
+	return envexec.Size(fi.Size())
+}
+
+// syncScratch writes back the dirty pages of the scratch file left unsynced
+// by the program, so that they are accounted to the cgroup
+func (c *environ) syncScratch() {
+	f, err := os.Open(c.scratch)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	unix.Fdatasync(int(f.Fd()))
+}
@@ -0,0 +1,33 @@
+package linuxcontainer
+
+import (
+	"syscall"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-sandbox/container"
+	"golang.org/x/sys/unix"
+)
+
+const shmPath = "/dev/shm"
+
+// ShmUsage returns the bytes used by the tmpfs mounted at /dev/shm, the
+// content is removed by the reset as other tmpfs mounts
+func (c *environ) ShmUsage() (envexec.Size, bool) {
+	if !c.reset.shm {
+		return 0, false
+	}
+	fs, err := c.Environment.Open([]container.OpenCmd{{
+		Path: shmPath,
+		Flag: syscall.O_CLOEXEC | syscall.O_DIRECTORY | syscall.O_RDONLY,
+	}})
+	if err != nil {
+		return 0, false
+	}
+	defer closeFiles(fs)
+
+	var st unix.Statfs_t
+	if err := unix.Fstatfs(int(fs[0].Fd()), &st); err != nil {
+		return 0, false
+	}
+	return envexec.Size((st.Blocks - st.Bfree) * uint64(st.Bsize)), true
+}
@@ -0,0 +1,65 @@
+package linuxcontainer
+
+import (
+	"fmt"
+	"strings"
+	"syscall"
+
+	"github.com/criyle/go-sandbox/container"
+)
+
+// mountStamp records the device and inode of the host source of a bind mount
+// when the container was created
+type mountStamp struct {
+	path     string
+	dev, ino uint64
+}
+
+// stampMounts records the sources of the bind mounts of the container, the
+// sources not found are skipped
+func stampMounts(builder EnvironmentBuilder) []mountStamp {
+	cb, ok := builder.(*container.Builder)
+	if !ok {
+		return nil
+	}
+	var rt []mountStamp
+	for _, m := range cb.Mounts {
+		if !m.IsBindMount() {
+			continue
+		}
+		var st syscall.Stat_t
+		if err := syscall.Stat(m.Source, &st); err != nil {
+			continue
+		}
+		rt = append(rt, mountStamp{path: m.Source, dev: uint64(st.Dev), ino: uint64(st.Ino)})
+	}
+	return rt
+}
+
+// withoutStamps drops the stamps of the sources under dir (e.g. the files
+// unlinked once mounted)
+func withoutStamps(stamps []mountStamp, dir string) []mountStamp {
+	rt := stamps[:0]
+	for _, s := range stamps {
+		if !strings.HasPrefix(s.path, dir+"/") {
+			rt = append(rt, s)
+		}
+	}
+	return rt
+}
+
+// Stale reports the bind mount whose host source has been replaced (e.g. the
+// shared libraries upgraded or the toolchain directory swapped) since the
+// environment was created, the container still sees the deleted inode
+func (c *environ) Stale() error {
+	for _, s := range c.stamps {
+		var st syscall.Stat_t
+		if err := syscall.Stat(s.path, &st); err != nil {
+			return fmt.Errorf("stale mount: %s %v", s.path, err)
+		}
+		if uint64(st.Dev) != s.dev || uint64(st.Ino) != s.ino {
+			return fmt.Errorf("stale mount: %s is replaced", s.path)
+		}
+	}
+	return nil
+}
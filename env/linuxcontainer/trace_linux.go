@@ -0,0 +1,159 @@
+package linuxcontainer
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+
+	"golang.org/x/sys/unix"
+)
+
+// traceOptions follows the children of the traced process and kills them all
+// if the tracer exits
+const traceOptions = unix.PTRACE_O_TRACESYSGOOD | unix.PTRACE_O_TRACEEXEC | unix.PTRACE_O_TRACEFORK |
+	unix.PTRACE_O_TRACEVFORK | unix.PTRACE_O_TRACECLONE | unix.PTRACE_O_EXITKILL
+
+// TraceAvailable checks the syscall numbers of the architecture are mapped
+func (c *environ) TraceAvailable() error {
+	if syscallNames == nil {
+		return fmt.Errorf("syscall trace: syscall numbers of %s are not mapped", runtime.GOARCH)
+	}
+	return nil
+}
+
+// tracer counts the syscalls of the process and its children through ptrace
+// from the execve of the process. ptrace requests are only accepted from the
+// thread attached to the tracee, so the tracer runs on a locked thread which is
+// terminated once it finished.
+type tracer struct {
+	pid    chan int
+	seized chan error
+	done   chan struct{}
+	counts map[string]uint64
+}
+
+func newTracer() *tracer {
+	t := &tracer{
+		pid:    make(chan int),
+		seized: make(chan error),
+		done:   make(chan struct{}),
+		counts: make(map[string]uint64),
+	}
+	go t.run()
+	return t
+}
+
+// seize attaches the process on the sync before its execve
+func (t *tracer) seize(pid int) error {
+	t.pid <- pid
+	return <-t.seized
+}
+
+// wait returns the counts after all the traced processes exited, it must be
+// called after the process finished whether it was seized or not
+func (t *tracer) wait() map[string]uint64 {
+	close(t.pid)
+	<-t.done
+	return t.counts
+}
+
+func (t *tracer) run() {
+	defer close(t.done)
+	// never unlocked so that the thread is not reused by other goroutines
+	runtime.LockOSThread()
+
+	pid, ok := <-t.pid
+	if !ok {
+		return
+	}
+	_, _, errno := unix.Syscall6(unix.SYS_PTRACE, unix.PTRACE_SEIZE, uintptr(pid), 0, traceOptions, 0, 0)
+	var err error
+	if errno != 0 {
+		err = fmt.Errorf("syscall trace: failed to seize %w", errno)
+	} else if err = unix.PtraceInterrupt(pid); err != nil {
+		err = fmt.Errorf("syscall trace: failed to interrupt %w", err)
+	}
+	t.seized <- err
+	if err == nil {
+		t.trace(pid)
+	}
+}
+
+// trace resumes the tracees until all of them exited. Only the tracees of the
+// current thread are waited (__WNOTHREAD), the other children of the server
+// are never reaped here.
+func (t *tracer) trace(pid int) {
+	var (
+		inSyscall = map[int]bool{pid: false} // tracees by tid, whether stopped in syscall
+		exec      bool                       // the process has called execve
+	)
+	for len(inSyscall) > 0 {
+		var ws unix.WaitStatus
+		tid, err := unix.Wait4(-1, &ws, unix.WALL|unix.WNOTHREAD, nil)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			return
+		}
+		if ws.Exited() || ws.Signaled() {
+			delete(inSyscall, tid)
+			continue
+		}
+		if !ws.Stopped() {
+			continue
+		}
+
+		// children attached by the fork / clone events report their stops
+		// by themselves
+		if _, ok := inSyscall[tid]; !ok {
+			inSyscall[tid] = false
+		}
+		var sig int
+		switch s := ws.StopSignal(); {
+		case s == unix.SIGTRAP|0x80:
+			if !inSyscall[tid] && exec {
+				t.count(tid)
+			}
+			inSyscall[tid] = !inSyscall[tid]
+
+		case s == unix.SIGTRAP && ws.TrapCause() == unix.PTRACE_EVENT_EXEC:
+			// the entry of the first execve is not counted since the sandbox
+			// setup before it is not traced, the exit stop follows
+			if !exec {
+				exec = true
+				t.count(tid)
+			}
+			inSyscall[tid] = true
+
+		case s == unix.SIGTRAP && ws.TrapCause() > 0:
+			// fork / clone events and the initial interrupt
+
+		case isGroupStop(ws):
+			// signal is not injected on group stop
+
+		default:
+			// signal delivery stop
+			sig = int(s)
+		}
+		unix.PtraceSyscall(tid, sig)
+	}
+}
+
+// isGroupStop reports the stop of the seized tracee by a stopping signal,
+// which is PTRACE_EVENT_STOP with the signal instead of SIGTRAP
+func isGroupStop(ws unix.WaitStatus) bool {
+	return ws>>16 == unix.PTRACE_EVENT_STOP
+}
+
+func (t *tracer) count(tid int) {
+	nr, err := syscallNumber(tid)
+	if err != nil {
+		return
+	}
+	name, ok := syscallNames[nr]
+	if !ok {
+		name = "syscall_" + strconv.FormatUint(nr, 10)
+	}
+	t.counts[name]++
+}
@@ -0,0 +1,13 @@
+package linuxcontainer
+
+import "golang.org/x/sys/unix"
+
+// syscallNumber returns the syscall number of the tracee stopped at syscall
+// entry
+func syscallNumber(tid int) (uint64, error) {
+	var regs unix.PtraceRegs
+	if err := unix.PtraceGetRegs(tid, &regs); err != nil {
+		return 0, err
+	}
+	return regs.Orig_rax, nil
+}
@@ -0,0 +1,12 @@
+//go:build !amd64
+
+package linuxcontainer
+
+import "errors"
+
+// syscallNames is not mapped for the architecture, trace is not available
+var syscallNames map[uint64]string
+
+func syscallNumber(tid int) (uint64, error) {
+	return 0, errors.New("syscall numbers are not mapped")
+}
@@ -0,0 +1,20 @@
+package linuxcontainer
+
+import (
+	"github.com/criyle/go-judge/envexec"
+	"golang.org/x/sys/unix"
+)
+
+// WorkDirUsage returns the bytes used and the size of the tmpfs mounted at
+// the work directory, the work directory backed by disk is reported by
+// DiskUsage instead
+func (c *environ) WorkDirUsage() (used, size envexec.Size, ok bool) {
+	if c.diskDir != "" || c.scratch != "" {
+		return 0, 0, false
+	}
+	var st unix.Statfs_t
+	if err := unix.Fstatfs(int(c.WorkDir().Fd()), &st); err != nil || st.Type != unix.TMPFS_MAGIC {
+		return 0, 0, false
+	}
+	return envexec.Size((st.Blocks - st.Bfree) * uint64(st.Bsize)), envexec.Size(st.Blocks * uint64(st.Bsize)), true
+}
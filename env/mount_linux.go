@@ -4,7 +4,11 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"regexp"
+	"strings"
+	"syscall"
 
+	"github.com/criyle/go-judge/env/linuxcontainer"
 	"github.com/criyle/go-sandbox/container"
 	"github.com/criyle/go-sandbox/pkg/mount"
 	"gopkg.in/yaml.v2"
@@ -37,6 +41,7 @@ type Mounts struct {
 	UID        int      `yaml:"uid"`
 	GID        int      `yaml:"gid"`
 	Proc       bool     `yaml:"proc"`
+	Cgroup     bool     `yaml:"cgroup"`
 }
 
 func readMountConfig(p string) (*Mounts, error) {
@@ -81,12 +86,60 @@ func parseMountConfig(m *Mounts) (*mount.Builder, error) {
 	return b, nil
 }
 
-func getDefaultMount(tmpFsConf string) *mount.Builder {
-	return mount.NewBuilder().
+// missingBindSources returns sources of bind mounts that do not exist
+func missingBindSources(b *mount.Builder) []string {
+	var rt []string
+	for _, m := range b.Mounts {
+		if !m.IsBindMount() {
+			continue
+		}
+		if _, err := os.Stat(m.Source); os.IsNotExist(err) {
+			rt = append(rt, m.Source)
+		}
+	}
+	return rt
+}
+
+// datasetName restricts the dataset names to a single path component
+var datasetName = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// checkDatasets ensures the dataset directories could not be modified by the
+// judged programs through ownership or permission outside of the read-only
+// bind mount
+func checkDatasets(datasets map[string]string) error {
+	for name, dir := range datasets {
+		if !datasetName.MatchString(name) {
+			return fmt.Errorf("dataset name %q is invalid", name)
+		}
+		if !path.IsAbs(dir) {
+			return fmt.Errorf("dataset %s: %s is not absolute path", name, dir)
+		}
+		fi, err := os.Stat(dir)
+		if err != nil {
+			return fmt.Errorf("dataset %s: %w", name, err)
+		}
+		if !fi.IsDir() {
+			return fmt.Errorf("dataset %s: %s is not a directory", name, dir)
+		}
+		if st, ok := fi.Sys().(*syscall.Stat_t); !ok || st.Uid != 0 {
+			return fmt.Errorf("dataset %s: %s is not owned by root", name, dir)
+		}
+		if fi.Mode().Perm()&0022 != 0 {
+			return fmt.Errorf("dataset %s: %s is writable by group or others", name, dir)
+		}
+	}
+	return nil
+}
+
+func getDefaultMount(tmpFsConf string, shmSize uint64) *mount.Builder {
+	mb := mount.NewBuilder().
 		// basic exec and lib
 		WithBind("/bin", "bin", true).
 		WithBind("/lib", "lib", true).
 		WithBind("/lib64", "lib64", true).
+		// 32-bit (x86) and x32 libraries
+		WithBind("/lib32", "lib32", true).
+		WithBind("/libx32", "libx32", true).
 		WithBind("/usr", "usr", true).
 		WithBind("/etc/ld.so.cache", "etc/ld.so.cache", true).
 		// java wants /proc/self/exe as it need relative path for lib
@@ -114,6 +167,48 @@ func getDefaultMount(tmpFsConf string) *mount.Builder {
 		WithTmpfs("w", tmpFsConf).
 		// tmp dir
 		WithTmpfs("tmp", tmpFsConf)
+	// python multiprocessing wants /dev/shm
+	if shmSize > 0 {
+		mb.WithTmpfs("dev/shm", fmt.Sprintf("size=%d,nr_inodes=4k", shmSize))
+	}
+	return mb
+}
+
+// getMinimalProfile returns the builtin minimal profile with only the tmpfs
+// work directory, /proc and /dev/null. The tmpfs parameter of the configured
+// work directory is kept if it is a tmpfs.
+func getMinimalProfile(mounts []mount.Mount, workDir, tmpFsConf string, maskPaths []string) linuxcontainer.Profile {
+	for _, m := range mounts {
+		if m.IsTmpFs() && path.Join("/", m.Target) == workDir {
+			tmpFsConf = m.Data
+		}
+	}
+	return linuxcontainer.Profile{
+		Mounts:        getMinimalMount(workDir, tmpFsConf).Mounts,
+		SymbolicLinks: defaultSymLinks,
+		MaskPaths:     procMaskPaths(maskPaths),
+	}
+}
+
+// getMinimalMount mounts only the tmpfs work directory, /proc and /dev/null,
+// which are all the programs linked statically need
+func getMinimalMount(workDir, tmpFsConf string) *mount.Builder {
+	return mount.NewBuilder().
+		WithTmpfs(strings.TrimPrefix(workDir, "/"), tmpFsConf).
+		WithProc().
+		WithBind("/dev/null", "dev/null", false)
+}
+
+// procMaskPaths returns the mask paths under /proc, the only ones exist in
+// the minimal mounts
+func procMaskPaths(maskPaths []string) []string {
+	var rt []string
+	for _, p := range maskPaths {
+		if strings.HasPrefix(p, "/proc/") {
+			rt = append(rt, p)
+		}
+	}
+	return rt
 }
 
 var defaultSymLinks = []container.SymbolicLink{
@@ -0,0 +1,117 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/criyle/go-judge/env/pool"
+)
+
+const numaNodeBase = "/sys/devices/system/node"
+
+// NUMANodes detects the NUMA nodes with cpus available to run programs. If
+// cpuset is not empty, the cpus of each node are restricted to it and the
+// nodes without cpu left are omitted.
+func NUMANodes(cpuset string) ([]pool.NUMANode, error) {
+	var allowed map[int]bool
+	if cpuset != "" {
+		cpus, err := parseCPUList(cpuset)
+		if err != nil {
+			return nil, fmt.Errorf("numa: invalid cpuset %q: %v", cpuset, err)
+		}
+		allowed = make(map[int]bool, len(cpus))
+		for _, c := range cpus {
+			allowed[c] = true
+		}
+	}
+	dirs, err := filepath.Glob(filepath.Join(numaNodeBase, "node[0-9]*"))
+	if err != nil {
+		return nil, err
+	}
+	var rt []pool.NUMANode
+	for _, d := range dirs {
+		id, err := strconv.Atoi(strings.TrimPrefix(filepath.Base(d), "node"))
+		if err != nil {
+			continue
+		}
+		b, err := os.ReadFile(filepath.Join(d, "cpulist"))
+		if err != nil {
+			return nil, err
+		}
+		cpus, err := parseCPUList(strings.TrimSpace(string(b)))
+		if err != nil {
+			return nil, fmt.Errorf("numa: node %d: %v", id, err)
+		}
+		if allowed != nil {
+			n := 0
+			for _, c := range cpus {
+				if allowed[c] {
+					cpus[n] = c
+					n++
+				}
+			}
+			cpus = cpus[:n]
+		}
+		// memory only nodes (e.g. CXL memory) are not used to run programs
+		if len(cpus) == 0 {
+			continue
+		}
+		rt = append(rt, pool.NUMANode{ID: id, CPUs: formatCPUList(cpus)})
+	}
+	sort.Slice(rt, func(i, j int) bool { return rt[i].ID < rt[j].ID })
+	return rt, nil
+}
+
+// parseCPUList parses the cpu list format (e.g. 0-3,8) into sorted cpus
+func parseCPUList(s string) ([]int, error) {
+	var rt []int
+	if s == "" {
+		return rt, nil
+	}
+	for _, r := range strings.Split(s, ",") {
+		lo, hi, ok := strings.Cut(r, "-")
+		l, err := strconv.Atoi(lo)
+		if err != nil {
+			return nil, err
+		}
+		h := l
+		if ok {
+			if h, err = strconv.Atoi(hi); err != nil {
+				return nil, err
+			}
+		}
+		if l < 0 || h < l {
+			return nil, fmt.Errorf("invalid range %q", r)
+		}
+		for c := l; c <= h; c++ {
+			rt = append(rt, c)
+		}
+	}
+	sort.Ints(rt)
+	return rt, nil
+}
+
+// formatCPUList formats the sorted cpus in the cpu list format
+func formatCPUList(cpus []int) string {
+	var sb strings.Builder
+	for i := 0; i < len(cpus); {
+		j := i
+		for j+1 < len(cpus) && cpus[j+1] == cpus[j]+1 {
+			j++
+		}
+		if sb.Len() > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.Itoa(cpus[i]))
+		if j > i {
+			sb.WriteByte('-')
+			sb.WriteString(strconv.Itoa(cpus[j]))
+		}
+		i = j + 1
+	}
+	return sb.String()
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package env
+
+import "github.com/criyle/go-judge/env/pool"
+
+// NUMANodes returns no node since the environments could not be bound to
+// NUMA nodes on this platform
+func NUMANodes(cpuset string) ([]pool.NUMANode, error) {
+	return nil, nil
+}
@@ -0,0 +1,87 @@
+package env
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// deadlineWaiter kills all the processes waiting on it at the same time
+// once the clock time limit passed
+func deadlineWaiter(limit time.Duration) func(context.Context, envexec.Process) bool {
+	var once sync.Once
+	var deadline <-chan time.Time
+	return func(ctx context.Context, p envexec.Process) bool {
+		once.Do(func() { deadline = time.After(limit) })
+		select {
+		case <-ctx.Done():
+			return false
+		case <-p.Done():
+			return false
+		case <-deadline:
+			return true
+		}
+	}
+}
+
+func TestGroupBidirectionalPipe(t *testing.T) {
+	const seed = "ping\n"
+	p := newTestPool(t)
+	var envs []envexec.Environment
+	for i := 0; i < 2; i++ {
+		m, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+		if err != nil {
+			t.Skip("environment is not available: ", err)
+		}
+		defer p.Put(m)
+		envs = append(envs, m)
+	}
+
+	// the seed bounces between the 2 cat until the clock time limit, the one
+	// killed later waits after the end of its input closed by the other
+	wait := deadlineWaiter(500 * time.Millisecond)
+	cmd := func(m envexec.Environment, args ...string) *envexec.Cmd {
+		return &envexec.Cmd{
+			Environment: m,
+			Args:        args,
+			Env:         []string{"PATH=/usr/bin:/bin"},
+			Files:       []envexec.File{nil, nil},
+			TimeLimit:   5 * time.Second,
+			MemoryLimit: 256 << 20,
+			ProcLimit:   64,
+			Waiter:      wait,
+		}
+	}
+	g := &envexec.Group{
+		Cmd: []*envexec.Cmd{
+			cmd(envs[0], "/bin/sh", "-c", "printf '"+seed+"'; cat; exec sleep 10"),
+			cmd(envs[1], "/bin/sh", "-c", "cat; exec sleep 10"),
+		},
+		Pipes: []envexec.Pipe{
+			{In: envexec.PipeIndex{Index: 0, Fd: 1}, Out: envexec.PipeIndex{Index: 1, Fd: 0}, Proxy: true},
+			{In: envexec.PipeIndex{Index: 1, Fd: 1}, Out: envexec.PipeIndex{Index: 0, Fd: 0}, Proxy: true},
+		},
+		KillOnLimit: true,
+	}
+	rt, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range rt {
+		if r.TerminatedBy == envexec.TerminatedByExited || r.TerminatedBy == envexec.TerminatedByInternal {
+			t.Errorf("cmd[%d] is not killed: %v %v: %s", i, r.Status, r.TerminatedBy, r.Error)
+		}
+	}
+
+	// every byte from the first cat is the seed or echoed back by the second
+	forward, backward := rt[0].PipeBytes[1], rt[1].PipeBytes[1]
+	if backward < int64(len(seed)) {
+		t.Fatalf("%d bytes echoed back, want the seed bounced at least once", backward)
+	}
+	if forward < backward || forward > backward+int64(len(seed)) {
+		t.Fatalf("%d bytes forwarded and %d bytes echoed back, want the difference within the seed", forward, backward)
+	}
+}
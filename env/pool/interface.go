@@ -1,7 +1,14 @@
 package pool
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/criyle/go-judge/envexec"
 	"github.com/criyle/go-judge/worker"
@@ -14,49 +21,607 @@ type Environment interface {
 	Destroy() error
 }
 
+// StaleEnvironment defines the environment that could detect its mounts have
+// gone stale (e.g. the host directory was replaced), it is destroyed instead
+// of reused
+type StaleEnvironment interface {
+	Stale() error
+}
+
 // EnvBuilder defines the abstract builder for container environment
 type EnvBuilder interface {
 	Build() (Environment, error)
 }
 
+// OptionsEnvBuilder defines the builder that could build environment with the
+// options, e.g. the pinned credential or the extra mounts
+type OptionsEnvBuilder interface {
+	EnvBuilder
+	BuildOptions(envexec.EnvironmentOptions) (Environment, error)
+	ValidOptions(envexec.EnvironmentOptions) error
+}
+
+// IOEnvBuilder defines the builder that could report whether the io of the
+// environment is accounted
+type IOEnvBuilder interface {
+	EnvBuilder
+	IOAccounting() bool
+}
+
+// PrepareEnvBuilder defines the builder that could restore the host resources
+// required to build environment (e.g. the temp directory of container root)
+type PrepareEnvBuilder interface {
+	EnvBuilder
+	Prepare() error
+}
+
+// RebuildStat reports the environments cycled by Rebuild
+type RebuildStat struct {
+	Idle  int // idle environments destroyed
+	InUse int // environments in use which will be destroyed when put back
+}
+
+// DestroyStat reports the environment destroyed by DestroyEnv
+type DestroyStat struct {
+	Found bool // environment with the id is idle or in use
+	InUse bool // environment in use which will be destroyed when put back
+}
+
+// maxIdleKeyEnv is the max number of idle environments kept for each envKey,
+// i.e. the combination of the reusable options and pinned credential
+const maxIdleKeyEnv = 2
+
+// backoff before the next attempt to create environment of the envKey after
+// the previous one failed with envexec.EnvironmentError, doubled on each
+// consecutive failure
+const (
+	minBuildBackoff = 100 * time.Millisecond
+	maxBuildBackoff = 5 * time.Second
+)
+
+// Pool defines worker.EnvironmentPool that could switch to a new EnvBuilder
+type Pool interface {
+	worker.EnvironmentPool
+	worker.IOEnvironmentPool
+	worker.DiscardEnvironmentPool
+	// Reload switches to the new builder, environments built by the previous
+	// builder are destroyed when they are put back to the pool
+	Reload(EnvBuilder)
+	// Rebuild destroys all idle environments and environments in use when
+	// they are put back, so that new environments are built by the current
+	// builder
+	Rebuild() (RebuildStat, error)
+	// Available returns the error of the last environment creation failed
+	// with envexec.EnvironmentError if no environment of its options has
+	// been got since then
+	Available() error
+	// Idle returns the number of idle environments that Get returns
+	// before building new ones
+	Idle() int
+	// OnStale sets the function called with the reason when an idle
+	// environment is destroyed since its mounts have gone stale
+	OnStale(func(error))
+	// DestroyStale destroys the idle environments with stale mounts and
+	// returns the number of them
+	DestroyStale() int
+	// DestroyEnv destroys the environment with the id reported in the
+	// results, the one in use is destroyed when it is put back
+	DestroyEnv(id uint64) DestroyStat
+}
+
 type pool struct {
 	builder EnvBuilder
+	gen     uint64 // generation of the builder
+
+	env    []*poolEnv
+	keyEnv map[string][]*poolEnv // idle environments with options by envKey
+	mu     sync.Mutex
+	inUse  int // environments of the current generation not put back
+
+	used map[uint64]*poolEnv // environments not put back by id
 
-	env []Environment
-	mu  sync.Mutex
+	buildErr map[string]*buildState // failed environment creation by envKey
+
+	onStale func(error)
+}
+
+// buildState records the environment creation of an envKey failed with
+// envexec.EnvironmentError, the next attempt is delayed by the backoff
+type buildState struct {
+	err      error
+	backoff  time.Duration
+	failedAt time.Time
+	retryAt  time.Time
+}
+
+// envID is the id of the last environment created by the pools
+var envID uint64
+
+// poolEnv records the builder generation of the environment
+type poolEnv struct {
+	Environment
+	id      uint64
+	gen     uint64
+	oneShot bool   // destroyed after use
+	key     string // envKey if built with options
+	owner   *pool  // put back to by numaPool
+	removed bool   // destroyed by DestroyEnv when put back, mu must be held
+}
+
+// EnvironmentID implements envexec.IDEnvironment
+func (e *poolEnv) EnvironmentID() uint64 {
+	return e.id
+}
+
+var errCoreNotSupported = errors.New("core dump is not supported by the environment")
+
+// CoreDumpAvailable forwards envexec.CoreEnvironment of the environment
+func (e *poolEnv) CoreDumpAvailable() error {
+	if c, ok := e.Environment.(envexec.CoreEnvironment); ok {
+		return c.CoreDumpAvailable()
+	}
+	return errCoreNotSupported
+}
+
+var errTraceNotSupported = errors.New("syscall trace is not supported by the environment")
+
+// TraceAvailable forwards envexec.TraceEnvironment of the environment
+func (e *poolEnv) TraceAvailable() error {
+	if c, ok := e.Environment.(envexec.TraceEnvironment); ok {
+		return c.TraceAvailable()
+	}
+	return errTraceNotSupported
+}
+
+var errFifoNotSupported = errors.New("fifo is not supported by the environment")
+
+// Mkfifo forwards envexec.FifoEnvironment of the environment
+func (e *poolEnv) Mkfifo(path string) error {
+	if c, ok := e.Environment.(envexec.FifoEnvironment); ok {
+		return c.Mkfifo(path)
+	}
+	return errFifoNotSupported
+}
+
+// ShmUsage forwards envexec.ShmEnvironment of the environment
+func (e *poolEnv) ShmUsage() (envexec.Size, bool) {
+	if c, ok := e.Environment.(envexec.ShmEnvironment); ok {
+		return c.ShmUsage()
+	}
+	return 0, false
+}
+
+// DiskUsage forwards envexec.DiskEnvironment of the environment
+func (e *poolEnv) DiskUsage() (envexec.Size, bool) {
+	if c, ok := e.Environment.(envexec.DiskEnvironment); ok {
+		return c.DiskUsage()
+	}
+	return 0, false
+}
+
+// WorkDirUsage forwards envexec.WorkDirEnvironment of the environment
+func (e *poolEnv) WorkDirUsage() (envexec.Size, envexec.Size, bool) {
+	if c, ok := e.Environment.(envexec.WorkDirEnvironment); ok {
+		return c.WorkDirUsage()
+	}
+	return 0, 0, false
+}
+
+// InitLog forwards envexec.InitLogEnvironment of the environment
+func (e *poolEnv) InitLog() []byte {
+	if c, ok := e.Environment.(envexec.InitLogEnvironment); ok {
+		return c.InitLog()
+	}
+	return nil
 }
 
 // NewPool returns a pool for EnvBuilder
-func NewPool(builder EnvBuilder) worker.EnvironmentPool {
+func NewPool(builder EnvBuilder) Pool {
 	return &pool{
 		builder: builder,
 	}
 }
 
-func (p *pool) Get() (envexec.Environment, error) {
+func (p *pool) Get(ctx context.Context, opts envexec.EnvironmentOptions) (envexec.Environment, error) {
+	key, oneShot := envKey(opts)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !oneShot {
+		if e := p.getKey(key); e != nil {
+			delete(p.buildErr, key)
+			return e, nil
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	build := p.builder.Build
+	if !opts.Plain() || opts.Credential != nil {
+		b, ok := p.builder.(OptionsEnvBuilder)
+		if !ok {
+			return nil, errOptionsNotSupported
+		}
+		build = func() (Environment, error) { return b.BuildOptions(opts) }
+	}
+	e, err := p.build(key, build)
+	if err != nil {
+		return nil, err
+	}
+	return p.acquire(&poolEnv{Environment: e, id: atomic.AddUint64(&envID, 1), gen: p.gen, oneShot: oneShot, key: key, owner: p}), nil
+}
+
+var errOptionsNotSupported = errors.New("environment options are not supported")
+
+func (p *pool) Valid(opts envexec.EnvironmentOptions) error {
+	if opts.Plain() && opts.Credential == nil {
+		return nil
+	}
+	p.mu.Lock()
+	b, ok := p.builder.(OptionsEnvBuilder)
+	p.mu.Unlock()
+
+	if !ok {
+		return errOptionsNotSupported
+	}
+	return b.ValidOptions(opts)
+}
+
+func (p *pool) IOAccounting() bool {
+	p.mu.Lock()
+	b, ok := p.builder.(IOEnvBuilder)
+	p.mu.Unlock()
+
+	return ok && b.IOAccounting()
+}
+
+// getKey takes the idle environment by envKey, mu must be held
+func (p *pool) getKey(key string) *poolEnv {
+	if key == "" {
+		for len(p.env) > 0 {
+			rt := p.env[len(p.env)-1]
+			p.env = p.env[:len(p.env)-1]
+			if p.stale(rt) {
+				continue
+			}
+			return p.acquire(rt)
+		}
+		return nil
+	}
+	for l := p.keyEnv[key]; len(l) > 0; l = p.keyEnv[key] {
+		rt := l[len(l)-1]
+		p.keyEnv[key] = l[:len(l)-1]
+		if p.stale(rt) {
+			continue
+		}
+		return p.acquire(rt)
+	}
+	return nil
+}
+
+// acquire records the environment is in use, mu must be held
+func (p *pool) acquire(e *poolEnv) *poolEnv {
+	p.inUse++
+	if p.used == nil {
+		p.used = make(map[uint64]*poolEnv)
+	}
+	p.used[e.id] = e
+	return e
+}
+
+// stale destroys the idle environment taken out of the pool if its mounts
+// have gone stale, mu must be held
+func (p *pool) stale(e *poolEnv) bool {
+	s, ok := e.Environment.(StaleEnvironment)
+	if !ok {
+		return false
+	}
+	err := s.Stale()
+	if err == nil {
+		return false
+	}
+	// the container init may take a while to exit
+	go e.Destroy()
+	if p.onStale != nil {
+		p.onStale(fmt.Errorf("environment %d: %w", e.id, err))
+	}
+	return true
+}
+
+func (p *pool) OnStale(f func(error)) {
 	p.mu.Lock()
 	defer p.mu.Unlock()
+	p.onStale = f
+}
 
-	if len(p.env) > 0 {
-		rt := p.env[len(p.env)-1]
-		p.env = p.env[:len(p.env)-1]
-		return rt, nil
+func (p *pool) DestroyStale() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	keep := func(l []*poolEnv) []*poolEnv {
+		n := 0
+		for _, e := range l {
+			if !p.stale(e) {
+				l[n] = e
+				n++
+			}
+		}
+		return l[:n]
+	}
+	rt := p.idle()
+	p.env = keep(p.env)
+	for k, l := range p.keyEnv {
+		p.keyEnv[k] = keep(l)
+	}
+	return rt - p.idle()
+}
+
+// normalizeDatasets returns the sorted datasets without duplication
+func normalizeDatasets(datasets []string) []string {
+	rt := append(make([]string, 0, len(datasets)), datasets...)
+	sort.Strings(rt)
+	n := 0
+	for i, d := range rt {
+		if i > 0 && d == rt[n-1] {
+			continue
+		}
+		rt[n] = d
+		n++
+	}
+	return rt[:n]
+}
+
+// envKey identifies the environments with the options, i.e. the mounts
+// (normalized datasets, profile, proc info, random or the kind of the files
+// of the run) and the pinned credential. oneShot is returned for the options
+// mounting the files of the run which are destroyed after use, so that the
+// key is only used for the backoff of the creation.
+func envKey(opts envexec.EnvironmentOptions) (key string, oneShot bool) {
+	switch {
+	case opts.RandomWorkDir:
+		key, oneShot = "workdir", true
+	case len(opts.Datasets) > 0:
+		key = "data:" + strings.Join(normalizeDatasets(opts.Datasets), "/")
+	case opts.Profile != "":
+		key = "profile:" + opts.Profile
+	case opts.MountFiles != "":
+		key, oneShot = "files", true
+	case len(opts.HostExec) > 0:
+		key, oneShot = "host", true
+	case opts.DiskWorkDir != "":
+		key, oneShot = "disk", true
+	case opts.ScratchFile != "":
+		key, oneShot = "scratch", true
+	case opts.ProcInfo:
+		key = "proc"
+	case opts.Random:
+		key = "random"
+	}
+	if opts.Credential != nil {
+		key += fmt.Sprintf("@%d:%d", opts.Credential.UID, opts.Credential.GID)
+	}
+	return key, oneShot
+}
+
+// build creates new environment unless the previous creation of the key
+// failed within the backoff, mu must be held
+func (p *pool) build(key string, build func() (Environment, error)) (Environment, error) {
+	st := p.buildErr[key]
+	if st != nil && time.Now().Before(st.retryAt) {
+		return nil, st.err
+	}
+	e, err := build()
+	var envErr *envexec.EnvironmentError
+	switch {
+	case errors.As(err, &envErr):
+		if st == nil {
+			st = &buildState{}
+			if p.buildErr == nil {
+				p.buildErr = make(map[string]*buildState)
+			}
+			p.buildErr[key] = st
+		}
+		st.backoff *= 2
+		if st.backoff < minBuildBackoff {
+			st.backoff = minBuildBackoff
+		}
+		if st.backoff > maxBuildBackoff {
+			st.backoff = maxBuildBackoff
+		}
+		st.err = err
+		st.failedAt = time.Now()
+		st.retryAt = st.failedAt.Add(st.backoff)
+	case err == nil:
+		delete(p.buildErr, key)
 	}
-	return p.builder.Build()
+	return e, err
+}
+
+// stat returns the environments in use and idle
+func (p *pool) stat() (inUse, idle int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.inUse, p.idle()
+}
+
+// idle returns the number of all idle environments, mu must be held
+func (p *pool) idle() int {
+	rt := len(p.env)
+	for _, l := range p.keyEnv {
+		rt += len(l)
+	}
+	return rt
+}
+
+func (p *pool) Idle() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.env)
+}
+
+func (p *pool) Available() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var last *buildState
+	for _, st := range p.buildErr {
+		if last == nil || st.failedAt.After(last.failedAt) {
+			last = st
+		}
+	}
+	if last == nil {
+		return nil
+	}
+	return last.err
 }
 
 func (p *pool) Put(env envexec.Environment) {
-	e, ok := env.(Environment)
+	e, ok := env.(*poolEnv)
 	if !ok {
 		panic("invalid environment put")
 	}
-	// If contain died after execution, don't put it into pool
+	// the files mounted for the run should not be reused by the next run
+	if e.oneShot {
+		p.release(e)
+		e.Destroy()
+		return
+	}
+	// If contain died after execution or could not be cleaned for the next
+	// run, don't put it into pool
 	if err := e.Reset(); err != nil {
+		p.release(e)
+		e.Destroy()
 		return
 	}
 
 	p.mu.Lock()
 	defer p.mu.Unlock()
 
+	delete(p.used, e.id)
+	// drain environment built by previous builder or marked by rebuild
+	if e.gen != p.gen {
+		e.Destroy()
+		return
+	}
+	p.inUse--
+	if e.removed {
+		e.Destroy()
+		return
+	}
+	if e.key != "" {
+		l := p.keyEnv[e.key]
+		if len(l) >= maxIdleKeyEnv {
+			e.Destroy()
+			return
+		}
+		if p.keyEnv == nil {
+			p.keyEnv = make(map[string][]*poolEnv)
+		}
+		p.keyEnv[e.key] = append(l, e)
+		return
+	}
 	p.env = append(p.env, e)
 }
+
+func (p *pool) Discard(env envexec.Environment) {
+	e, ok := env.(*poolEnv)
+	if !ok {
+		panic("invalid environment discard")
+	}
+	p.release(e)
+	e.Destroy()
+}
+
+// release records the environment is no longer in use
+func (p *pool) release(e *poolEnv) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.used, e.id)
+	if e.gen == p.gen {
+		p.inUse--
+	}
+}
+
+func (p *pool) DestroyEnv(id uint64) DestroyStat {
+	p.mu.Lock()
+	if e, ok := p.used[id]; ok {
+		e.removed = true
+		p.mu.Unlock()
+		return DestroyStat{Found: true, InUse: true}
+	}
+	take := func(l []*poolEnv) ([]*poolEnv, *poolEnv) {
+		for i, e := range l {
+			if e.id == id {
+				return append(l[:i], l[i+1:]...), e
+			}
+		}
+		return l, nil
+	}
+	var e *poolEnv
+	if p.env, e = take(p.env); e == nil {
+		for k, l := range p.keyEnv {
+			if p.keyEnv[k], e = take(l); e != nil {
+				break
+			}
+		}
+	}
+	p.mu.Unlock()
+
+	if e == nil {
+		return DestroyStat{}
+	}
+	e.Destroy()
+	return DestroyStat{Found: true}
+}
+
+func (p *pool) Reload(builder EnvBuilder) {
+	p.mu.Lock()
+	p.builder = builder
+	idle := p.cycle()
+	p.mu.Unlock()
+
+	for _, e := range idle {
+		e.Destroy()
+	}
+}
+
+func (p *pool) Rebuild() (RebuildStat, error) {
+	p.mu.Lock()
+	b := p.builder
+	p.mu.Unlock()
+
+	if pb, ok := b.(PrepareEnvBuilder); ok {
+		if err := pb.Prepare(); err != nil {
+			return RebuildStat{}, err
+		}
+	}
+
+	p.mu.Lock()
+	st := RebuildStat{InUse: p.inUse}
+	idle := p.cycle()
+	p.mu.Unlock()
+
+	st.Idle = len(idle)
+	for _, e := range idle {
+		e.Destroy()
+	}
+	return st, nil
+}
+
+// cycle starts a new generation so that environments in use are destroyed
+// when put back and returns the idle environments to be destroyed, mu must
+// be held
+func (p *pool) cycle() []*poolEnv {
+	p.gen++
+	p.inUse = 0
+	idle := p.env
+	for _, l := range p.keyEnv {
+		idle = append(idle, l...)
+	}
+	p.env = nil
+	p.keyEnv = nil
+	// the builder may recover from the previous failures
+	p.buildErr = nil
+	return idle
+}
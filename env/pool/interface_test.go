@@ -0,0 +1,175 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+type testEnv struct {
+	envexec.Environment
+	opts      envexec.EnvironmentOptions
+	destroyed bool
+}
+
+func (e *testEnv) Reset() error { return nil }
+
+func (e *testEnv) Destroy() error {
+	e.destroyed = true
+	return nil
+}
+
+// testBuilder fails the builds of the options while fail returns error
+type testBuilder struct {
+	builds int
+	fail   func(envexec.EnvironmentOptions) error
+}
+
+func (b *testBuilder) Build() (Environment, error) {
+	return b.BuildOptions(envexec.EnvironmentOptions{})
+}
+
+func (b *testBuilder) BuildOptions(opts envexec.EnvironmentOptions) (Environment, error) {
+	b.builds++
+	if b.fail != nil {
+		if err := b.fail(opts); err != nil {
+			return nil, err
+		}
+	}
+	return &testEnv{opts: opts}, nil
+}
+
+func (b *testBuilder) ValidOptions(envexec.EnvironmentOptions) error {
+	return nil
+}
+
+var errNamespaceLimit = &envexec.EnvironmentError{
+	Reason: envexec.EnvironmentNamespaceLimit,
+	Errno:  syscall.ENOSPC,
+	Err:    errors.New("failed to start container"),
+}
+
+func TestGetBackoffByKey(t *testing.T) {
+	b := &testBuilder{fail: func(opts envexec.EnvironmentOptions) error {
+		if len(opts.Datasets) > 0 {
+			return errNamespaceLimit
+		}
+		return nil
+	}}
+	p := NewPool(b)
+	ctx := context.Background()
+	data := envexec.EnvironmentOptions{Datasets: []string{"a"}}
+
+	if _, err := p.Get(ctx, data); !errors.Is(err, errNamespaceLimit) {
+		t.Fatalf("datasets: got %v, want %v", err, errNamespaceLimit)
+	}
+	if _, err := p.Get(ctx, data); !errors.Is(err, errNamespaceLimit) {
+		t.Fatalf("datasets within backoff: got %v, want %v", err, errNamespaceLimit)
+	}
+	if b.builds != 1 {
+		t.Fatalf("datasets within backoff: built %d times, want 1", b.builds)
+	}
+	if err := p.Available(); !errors.Is(err, errNamespaceLimit) {
+		t.Fatalf("available: got %v, want %v", err, errNamespaceLimit)
+	}
+	// other options are not affected by the backoff of the datasets
+	e, err := p.Get(ctx, envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Fatalf("plain: %v", err)
+	}
+	p.Put(e)
+	if _, err := p.Get(ctx, envexec.EnvironmentOptions{Profile: "minimal"}); err != nil {
+		t.Fatalf("profile: %v", err)
+	}
+}
+
+func TestGetClearsAvailable(t *testing.T) {
+	var fail error
+	b := &testBuilder{fail: func(envexec.EnvironmentOptions) error { return fail }}
+	p := NewPool(b)
+	ctx := context.Background()
+
+	e, err := p.Get(ctx, envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fail = errNamespaceLimit
+	if _, err := p.Get(ctx, envexec.EnvironmentOptions{}); !errors.Is(err, errNamespaceLimit) {
+		t.Fatalf("got %v, want %v", err, errNamespaceLimit)
+	}
+	if err := p.Available(); err == nil {
+		t.Fatal("available: want error after failed build")
+	}
+	// the idle environment is served while the build is failing
+	p.Put(e)
+	if _, err := p.Get(ctx, envexec.EnvironmentOptions{}); err != nil {
+		t.Fatalf("idle: %v", err)
+	}
+	if err := p.Available(); err != nil {
+		t.Fatalf("available: got %v after idle environment got", err)
+	}
+}
+
+func TestGetNoBackoffForOptionError(t *testing.T) {
+	errMount := errors.New("mount source not found")
+	b := &testBuilder{fail: func(envexec.EnvironmentOptions) error { return errMount }}
+	p := NewPool(b)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if _, err := p.Get(ctx, envexec.EnvironmentOptions{Profile: "bad"}); !errors.Is(err, errMount) {
+			t.Fatalf("got %v, want %v", err, errMount)
+		}
+	}
+	if b.builds != 2 {
+		t.Fatalf("built %d times, want 2 without backoff", b.builds)
+	}
+	if err := p.Available(); err != nil {
+		t.Fatalf("available: got %v, want nil", err)
+	}
+}
+
+func TestGetReuseByKey(t *testing.T) {
+	b := &testBuilder{}
+	p := NewPool(b)
+	ctx := context.Background()
+	cred := &envexec.Credential{UID: 1000, GID: 1000}
+
+	e, err := p.Get(ctx, envexec.EnvironmentOptions{Datasets: []string{"b", "a"}, Credential: cred})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Put(e)
+	// different credential or no credential is not reused
+	for _, opts := range []envexec.EnvironmentOptions{
+		{Datasets: []string{"a", "b"}},
+		{Datasets: []string{"a", "b"}, Credential: &envexec.Credential{UID: 1001, GID: 1001}},
+	} {
+		o, err := p.Get(ctx, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if o == e {
+			t.Fatalf("%+v: reused environment of other key", opts)
+		}
+	}
+	r, err := p.Get(ctx, envexec.EnvironmentOptions{Datasets: []string{"a", "b", "a"}, Credential: cred})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != e {
+		t.Fatal("datasets: idle environment of the same key is not reused")
+	}
+
+	m, err := p.Get(ctx, envexec.EnvironmentOptions{MountFiles: "/tmp/files"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Put(m)
+	if !m.(*poolEnv).Environment.(*testEnv).destroyed {
+		t.Fatal("mount files: environment is not destroyed after put back")
+	}
+}
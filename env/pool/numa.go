@@ -0,0 +1,213 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+)
+
+// NUMANode describes a NUMA node used to run programs
+type NUMANode struct {
+	ID   int    // node id, also the cpuset.mems of the node
+	CPUs string // cpuset.cpus of the node
+}
+
+// NUMAEnvBuilder defines the builder that could build environments running
+// programs on the cpus and memory of a NUMA node
+type NUMAEnvBuilder interface {
+	EnvBuilder
+	// NUMANode returns the builder bound to the node, false if the binding
+	// is not supported
+	NUMANode(NUMANode) (EnvBuilder, bool)
+}
+
+// NUMANodeStat reports the environments of a NUMA node
+type NUMANodeStat struct {
+	ID    int
+	InUse int
+	Idle  int
+}
+
+// NUMAPool defines Pool shards the environments by NUMA node
+type NUMAPool interface {
+	Pool
+	worker.NUMAEnvironmentPool
+	// NodePool returns the pool of the node, the least loaded node if node
+	// is not found (e.g. negative)
+	NodePool(node int) Pool
+	// NUMAStat returns the stat of each node
+	NUMAStat() []NUMANodeStat
+}
+
+// numaPool keeps a pool for each node, the environments from the pool of a
+// node are put back to it by the owner recorded in poolEnv
+type numaPool struct {
+	nodes []*pool
+	numa  []NUMANode
+}
+
+var _ NUMAPool = &numaPool{}
+
+// NewNUMAPool returns a pool with environments bound to each of the nodes.
+// It falls back to NewPool if there is only one node or the builder could not
+// bind environments to the nodes.
+func NewNUMAPool(builder EnvBuilder, nodes []NUMANode) Pool {
+	if len(nodes) < 2 {
+		return NewPool(builder)
+	}
+	p := &numaPool{}
+	for _, n := range nodes {
+		b, ok := bindNUMANode(builder, n)
+		if !ok {
+			return NewPool(builder)
+		}
+		p.nodes = append(p.nodes, &pool{builder: b})
+		p.numa = append(p.numa, n)
+	}
+	return p
+}
+
+func bindNUMANode(builder EnvBuilder, n NUMANode) (EnvBuilder, bool) {
+	b, ok := builder.(NUMAEnvBuilder)
+	if !ok {
+		return nil, false
+	}
+	return b.NUMANode(n)
+}
+
+func (p *numaPool) NUMANodes() []int {
+	rt := make([]int, 0, len(p.numa))
+	for _, n := range p.numa {
+		rt = append(rt, n.ID)
+	}
+	return rt
+}
+
+func (p *numaPool) NUMANode(node int) worker.EnvironmentPool {
+	return p.NodePool(node)
+}
+
+func (p *numaPool) NodePool(node int) Pool {
+	for i, n := range p.numa {
+		if n.ID == node {
+			return p.nodes[i]
+		}
+	}
+	// least loaded, ties are broken by the idle environments to reuse
+	rt := p.nodes[0]
+	inUse, idle := rt.stat()
+	for _, n := range p.nodes[1:] {
+		u, i := n.stat()
+		if u < inUse || (u == inUse && i > idle) {
+			rt, inUse, idle = n, u, i
+		}
+	}
+	return rt
+}
+
+func (p *numaPool) NUMAStat() []NUMANodeStat {
+	rt := make([]NUMANodeStat, 0, len(p.nodes))
+	for i, n := range p.nodes {
+		inUse, idle := n.stat()
+		rt = append(rt, NUMANodeStat{ID: p.numa[i].ID, InUse: inUse, Idle: idle})
+	}
+	return rt
+}
+
+func (p *numaPool) Get(ctx context.Context, opts envexec.EnvironmentOptions) (envexec.Environment, error) {
+	return p.NodePool(-1).Get(ctx, opts)
+}
+
+// the nodes are built by the same builder, so that the first one answers the
+// validations
+
+func (p *numaPool) Valid(opts envexec.EnvironmentOptions) error {
+	return p.nodes[0].Valid(opts)
+}
+
+func (p *numaPool) IOAccounting() bool {
+	return p.nodes[0].IOAccounting()
+}
+
+func (p *numaPool) Put(env envexec.Environment) {
+	e, ok := env.(*poolEnv)
+	if !ok || e.owner == nil {
+		panic("invalid environment put")
+	}
+	e.owner.Put(e)
+}
+
+func (p *numaPool) Discard(env envexec.Environment) {
+	e, ok := env.(*poolEnv)
+	if !ok || e.owner == nil {
+		panic("invalid environment discard")
+	}
+	e.owner.Discard(e)
+}
+
+func (p *numaPool) Reload(builder EnvBuilder) {
+	for i, n := range p.nodes {
+		// the new builder is of the same kind, it is used unbound only if it
+		// could no longer bind environments
+		b, ok := bindNUMANode(builder, p.numa[i])
+		if !ok {
+			b = builder
+		}
+		n.Reload(b)
+	}
+}
+
+func (p *numaPool) Rebuild() (RebuildStat, error) {
+	var rt RebuildStat
+	for i, n := range p.nodes {
+		st, err := n.Rebuild()
+		rt.Idle += st.Idle
+		rt.InUse += st.InUse
+		if err != nil {
+			return rt, fmt.Errorf("numa node %d: %w", p.numa[i].ID, err)
+		}
+	}
+	return rt, nil
+}
+
+func (p *numaPool) Idle() int {
+	rt := 0
+	for _, n := range p.nodes {
+		rt += n.Idle()
+	}
+	return rt
+}
+
+func (p *numaPool) OnStale(f func(error)) {
+	for _, n := range p.nodes {
+		n.OnStale(f)
+	}
+}
+
+func (p *numaPool) DestroyStale() int {
+	rt := 0
+	for _, n := range p.nodes {
+		rt += n.DestroyStale()
+	}
+	return rt
+}
+
+func (p *numaPool) DestroyEnv(id uint64) DestroyStat {
+	for _, n := range p.nodes {
+		if st := n.DestroyEnv(id); st.Found {
+			return st
+		}
+	}
+	return DestroyStat{}
+}
+
+func (p *numaPool) Available() error {
+	for i, n := range p.nodes {
+		if err := n.Available(); err != nil {
+			return fmt.Errorf("numa node %d: %w", p.numa[i].ID, err)
+		}
+	}
+	return nil
+}
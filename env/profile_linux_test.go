@@ -0,0 +1,90 @@
+package env
+
+import (
+	"context"
+	"debug/elf"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// busyboxEnv overrides the path of the static busybox found in PATH
+const busyboxEnv = "GO_JUDGE_TEST_BUSYBOX"
+
+// staticBusybox returns the path of the static busybox, it skips the test if
+// there is none since the minimal profile has no shared library
+func staticBusybox(t *testing.T) string {
+	p := os.Getenv(busyboxEnv)
+	if p == "" {
+		var err error
+		if p, err = exec.LookPath("busybox"); err != nil {
+			t.Skip("busybox is not found")
+		}
+	}
+	f, err := elf.Open(p)
+	if err != nil {
+		t.Skip("busybox is not an elf file: ", err)
+	}
+	defer f.Close()
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_INTERP {
+			t.Skip("busybox is not static: ", p)
+		}
+	}
+	return p
+}
+
+func TestMinimalProfile(t *testing.T) {
+	busybox := staticBusybox(t)
+	p := newTestPool(t)
+	m, err := p.Get(context.Background(), envexec.EnvironmentOptions{Profile: envexec.ProfileMinimal})
+	if err != nil {
+		t.Skip("environment is not available: ", err)
+	}
+	defer p.Put(m)
+
+	dir := t.TempDir()
+	r, err := (&envexec.Single{
+		Cmd: &envexec.Cmd{
+			Environment: m,
+			CopyIn:      map[string]envexec.File{"busybox": envexec.NewFileInput(busybox)},
+			Args:        []string{"busybox", "ls", "-a", "/"},
+			Files:       []envexec.File{nil, envexec.NewFileCollector("stdout", 4096, false)},
+			TimeLimit:   5 * time.Second,
+			MemoryLimit: 256 << 20,
+			ProcLimit:   64,
+			Waiter:      sampleWaiter,
+		},
+		NewStoreFile: func() (*os.File, error) { return os.CreateTemp(dir, "") },
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != envexec.StatusAccepted {
+		t.Fatalf("%v: %s", r.Status, r.Error)
+	}
+	f := r.Files["stdout"]
+	defer f.Close()
+	f.Seek(0, io.SeekStart)
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := make(map[string]bool)
+	for _, n := range strings.Fields(string(b)) {
+		root[n] = true
+	}
+	if root["usr"] || root["bin"] || root["lib"] {
+		t.Fatalf("host directories are mounted in the minimal profile: %s", b)
+	}
+	for _, n := range []string{"w", "proc", "dev"} {
+		if !root[n] {
+			t.Fatalf("/%s is missing in the minimal profile: %s", n, b)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package env
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// runRandom runs the script in the environment with the random seed if not
+// nil and returns its result and stdout
+func runRandom(t *testing.T, m envexec.Environment, seed *uint64, script string) (envexec.Result, []byte) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	c := &envexec.Cmd{
+		Environment: m,
+		Args:        []string{"/bin/sh", "-c", script},
+		Env:         []string{"PATH=/usr/bin:/bin"},
+		Files: []envexec.File{
+			envexec.NewFileReader(strings.NewReader(""), false),
+			envexec.NewFileWriter(&stdout, 4096),
+			envexec.NewFileWriter(&stderr, 4096),
+		},
+		TimeLimit:   time.Second,
+		MemoryLimit: 64 << 20,
+		ProcLimit:   16,
+		Waiter:      sampleWaiter,
+	}
+	if seed != nil {
+		c.Random = &envexec.Random{Seed: *seed}
+	}
+	r, err := (&envexec.Single{Cmd: c}).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Status != envexec.StatusAccepted {
+		t.Fatalf("%s: %v: %s %s", script, r.Status, r.Error, stderr.String())
+	}
+	return r, stdout.Bytes()
+}
+
+func TestRandomSeed(t *testing.T) {
+	p := newTestPool(t, func(c *Config) { c.RandomSize = 1 << 16 })
+	m, err := p.Get(context.Background(), envexec.EnvironmentOptions{Random: true})
+	if err != nil {
+		t.Skip("environment is not available: ", err)
+	}
+	defer p.Put(m)
+
+	const read = "head -c 256 /dev/urandom"
+	seed, other := uint64(42), uint64(7)
+	r, first := runRandom(t, m, &seed, read)
+	if len(first) != 256 || !r.RandomOpened {
+		t.Fatalf("read %d bytes opened %v, want 256 bytes opened", len(first), r.RandomOpened)
+	}
+	if _, b := runRandom(t, m, &other, read); bytes.Equal(b, first) {
+		t.Fatal("different seeds read the same bytes")
+	}
+	// the same seed reads the same bytes after the other seed, on both devices
+	for _, script := range []string{read, "head -c 256 /dev/random"} {
+		if _, b := runRandom(t, m, &seed, script); !bytes.Equal(b, first) {
+			t.Fatalf("%s: the same seed read different bytes", script)
+		}
+	}
+	if r, _ := runRandom(t, m, &seed, "true"); r.RandomOpened {
+		t.Fatal("random opened is reported without the open")
+	}
+}
+
+// TestRandomSeedDevNull covers /dev/null is the real device with or without
+// the deterministic random devices
+func TestRandomSeedDevNull(t *testing.T) {
+	p := newTestPool(t, func(c *Config) { c.RandomSize = 1 << 16 })
+	const script = "test -c /dev/null && echo discarded > /dev/null && cat /dev/null && wc -c < /dev/null"
+	seed := uint64(42)
+	for _, c := range []struct {
+		name   string
+		random bool
+	}{
+		{"random", true},
+		{"real", false},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			m, err := p.Get(context.Background(), envexec.EnvironmentOptions{Random: c.random})
+			if err != nil {
+				t.Skip("environment is not available: ", err)
+			}
+			defer p.Put(m)
+			var s *uint64
+			if c.random {
+				s = &seed
+			}
+			if _, b := runRandom(t, m, s, script); strings.TrimSpace(string(b)) != "0" {
+				t.Fatalf("stdout %q, want /dev/null empty", b)
+			}
+			// the random devices are the real ones without the seed
+			device := "test -c /dev/urandom"
+			if c.random {
+				device = "test -f /dev/urandom"
+			}
+			runRandom(t, m, s, device)
+		})
+	}
+}
@@ -0,0 +1,216 @@
+package env
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	containerRootPrefix = "executorserver-root-"
+	containerRootLock   = ".lock"
+)
+
+// ContainerRoot is the directory of this instance where the temp container
+// roots are created, named by the pid and start time and locked while the
+// instance is alive
+type ContainerRoot struct {
+	dir  string
+	lock *os.File
+}
+
+// NewContainerRoot creates and locks the container root of this instance
+// under parent (the temp dir if empty)
+func NewContainerRoot(parent string) (*ContainerRoot, error) {
+	parent, err := containerRootParent(parent)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(parent, fmt.Sprintf("%s%d-%d", containerRootPrefix, os.Getpid(), time.Now().Unix()))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("container root: %w", err)
+	}
+	// the lock file is closed on exec, so it is only held by this process
+	lock, err := os.OpenFile(filepath.Join(dir, containerRootLock), os.O_CREATE|os.O_RDWR|os.O_TRUNC, 0644)
+	if err != nil {
+		os.Remove(dir)
+		return nil, fmt.Errorf("container root: %w", err)
+	}
+	if err := unix.Flock(int(lock.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		lock.Close()
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("container root: lock %s: %w", dir, err)
+	}
+	fmt.Fprintf(lock, "%d\n", os.Getpid())
+	return &ContainerRoot{dir: dir, lock: lock}, nil
+}
+
+// Path returns the directory, or empty if r is nil
+func (r *ContainerRoot) Path() string {
+	if r == nil {
+		return ""
+	}
+	return r.dir
+}
+
+// Close unmounts anything left under the container root and removes it
+func (r *ContainerRoot) Close() error {
+	if r == nil {
+		return nil
+	}
+	err := removeContainerRoot(r.dir)
+	r.lock.Close()
+	return err
+}
+
+// CleanContainerRoots removes the container roots under parent (the temp dir
+// if empty) left by the instances not alive, after lazily unmounting anything
+// mounted under them. It returns the roots removed.
+func CleanContainerRoots(parent string) ([]string, error) {
+	parent, err := containerRootParent(parent)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		return nil, err
+	}
+	var removed []string
+	var errs []error
+	for _, e := range entries {
+		pid, ok := containerRootPid(e.Name())
+		if !ok || !e.IsDir() {
+			continue
+		}
+		dir := filepath.Join(parent, e.Name())
+		if containerRootAlive(dir, pid) {
+			continue
+		}
+		if err := removeContainerRoot(dir); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		removed = append(removed, dir)
+	}
+	return removed, errors.Join(errs...)
+}
+
+// containerRootParent resolves the parent as the mount points are reported
+func containerRootParent(parent string) (string, error) {
+	if parent == "" {
+		parent = os.TempDir()
+	}
+	p, err := filepath.EvalSymlinks(parent)
+	if err != nil {
+		return "", fmt.Errorf("container root: %w", err)
+	}
+	return filepath.Abs(p)
+}
+
+// containerRootPid parses the pid from the name <prefix><pid>-<start time>
+func containerRootPid(name string) (int, bool) {
+	s, ok := strings.CutPrefix(name, containerRootPrefix)
+	if !ok {
+		return 0, false
+	}
+	p, t, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(p)
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	if _, err := strconv.ParseInt(t, 10, 64); err != nil {
+		return 0, false
+	}
+	return pid, true
+}
+
+// containerRootAlive reports whether the instance of the root is alive by
+// its lock, or its pid if the lock file is missing (e.g. removed by tmp
+// cleaner)
+func containerRootAlive(dir string, pid int) bool {
+	f, err := os.Open(filepath.Join(dir, containerRootLock))
+	if err != nil {
+		return unix.Kill(pid, 0) != unix.ESRCH
+	}
+	defer f.Close()
+	if err := unix.Flock(int(f.Fd()), unix.LOCK_EX|unix.LOCK_NB); err != nil {
+		return true
+	}
+	unix.Flock(int(f.Fd()), unix.LOCK_UN)
+	return false
+}
+
+// removeContainerRoot lazily unmounts the mounts under dir and removes it.
+// It is not removed if any mount is left, so that nothing is removed through
+// a bind mount.
+func removeContainerRoot(dir string) error {
+	mounts, err := mountsUnder(dir)
+	if err != nil {
+		return err
+	}
+	for _, m := range mounts {
+		if err := unix.Unmount(m, unix.MNT_DETACH); err != nil && !errors.Is(err, unix.EINVAL) && !errors.Is(err, unix.ENOENT) {
+			return fmt.Errorf("container root: unmount %s: %w", m, err)
+		}
+	}
+	if mounts, err := mountsUnder(dir); err != nil || len(mounts) > 0 {
+		return fmt.Errorf("container root: %s still has mounts %v (%v)", dir, mounts, err)
+	}
+	return os.RemoveAll(dir)
+}
+
+// mountsUnder returns the mount points under (or at) dir from the deepest
+func mountsUnder(dir string) ([]string, error) {
+	f, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var rt []string
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		// id parent major:minor root mount-point ...
+		fields := strings.Fields(s.Text())
+		if len(fields) < 5 {
+			continue
+		}
+		p := unescapeMountInfo(fields[4])
+		if p == dir || strings.HasPrefix(p, dir+"/") {
+			rt = append(rt, p)
+		}
+	}
+	sort.Slice(rt, func(i, j int) bool { return len(rt[i]) > len(rt[j]) })
+	return rt, s.Err()
+}
+
+// unescapeMountInfo decodes the octal escapes (e.g. \040 for space) of the
+// mount point
+func unescapeMountInfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+4 <= len(s) {
+			if n, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				sb.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		sb.WriteByte(s[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,130 @@
+package env
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+// deadPid returns the pid of a process already exited
+func deadPid(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command("true")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
+// staleRoot fabricates the container root of the instance started at the time
+// with a file in it, with the lock file not held if lock
+func staleRoot(t *testing.T, parent string, pid int, start int64, lock bool) string {
+	t.Helper()
+	dir := filepath.Join(parent, fmt.Sprintf("%s%d-%d", containerRootPrefix, pid, start))
+	if err := os.MkdirAll(filepath.Join(dir, "env"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "env", "a"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if lock {
+		if err := os.WriteFile(filepath.Join(dir, containerRootLock), []byte(fmt.Sprintln(pid)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestCleanContainerRoots(t *testing.T) {
+	parent := t.TempDir()
+	dead := deadPid(t)
+
+	current, err := NewContainerRoot(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	staleLocked := staleRoot(t, parent, dead, 1, true)
+	staleNoLock := staleRoot(t, parent, dead, 2, false)
+	// the lock file is missing and the pid is alive
+	aliveNoLock := staleRoot(t, parent, os.Getpid(), 3, false)
+	other := filepath.Join(parent, containerRootPrefix+"other")
+	if err := os.Mkdir(other, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := CleanContainerRoots(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("removed %v, want the 2 stale roots", removed)
+	}
+	for _, c := range []struct {
+		dir  string
+		kept bool
+	}{
+		{current.Path(), true},
+		{staleLocked, false},
+		{staleNoLock, false},
+		{aliveNoLock, true},
+		{other, true},
+	} {
+		if _, err := os.Stat(c.dir); (err == nil) != c.kept {
+			t.Errorf("%s: kept %v, want %v", filepath.Base(c.dir), err == nil, c.kept)
+		}
+	}
+
+	if err := current.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(current.Path()); !os.IsNotExist(err) {
+		t.Errorf("current root is not removed by close: %v", err)
+	}
+}
+
+func TestCleanContainerRootsUnmount(t *testing.T) {
+	parent := t.TempDir()
+	dir := staleRoot(t, parent, deadPid(t), 1, true)
+	mnt := filepath.Join(dir, "env", "w")
+	if err := os.Mkdir(mnt, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := unix.Mount("tmpfs", mnt, "tmpfs", 0, "size=1m"); err != nil {
+		if errors.Is(err, unix.EPERM) {
+			t.Skip("mount is not permitted: ", err)
+		}
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { unix.Unmount(mnt, unix.MNT_DETACH) })
+	if err := os.WriteFile(filepath.Join(mnt, "a"), []byte("a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := CleanContainerRoots(parent); err != nil {
+		t.Fatal(err)
+	}
+	if mounts, err := mountsUnder(dir); err != nil || len(mounts) > 0 {
+		t.Fatalf("mounts %v left (%v)", mounts, err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Fatalf("stale root is not removed: %v", err)
+	}
+}
+
+func TestUnescapeMountInfo(t *testing.T) {
+	for s, want := range map[string]string{
+		"/tmp/a":          "/tmp/a",
+		`/tmp/a\040b`:     "/tmp/a b",
+		`/tmp/a\011\134b`: "/tmp/a\t\\b",
+		`/tmp/a\04`:       `/tmp/a\04`,
+	} {
+		if got := unescapeMountInfo(s); got != want {
+			t.Errorf("unescape %q = %q, want %q", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+//go:build !linux
+
+package env
+
+// ContainerRoot is not used since there is no container root on this
+// platform
+type ContainerRoot struct{}
+
+// NewContainerRoot returns nil since there is no container root on this
+// platform
+func NewContainerRoot(parent string) (*ContainerRoot, error) {
+	return nil, nil
+}
+
+// Path returns empty
+func (r *ContainerRoot) Path() string {
+	return ""
+}
+
+// Close does nothing
+func (r *ContainerRoot) Close() error {
+	return nil
+}
+
+// CleanContainerRoots does nothing on this platform
+func CleanContainerRoots(parent string) ([]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,77 @@
+package env
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// shmScript passes an object through multiprocessing.Queue, whose semaphore
+// lives in /dev/shm, and leaves a file there
+const shmScript = `
+import multiprocessing as mp
+
+def put(q):
+    q.put(42)
+
+if __name__ == '__main__':
+    q = mp.Queue()
+    p = mp.Process(target=put, args=(q,))
+    p.start()
+    assert q.get() == 42
+    p.join()
+    with open('/dev/shm/left', 'w') as f:
+        f.write('x' * 4096)
+`
+
+func TestShmResetBetweenRuns(t *testing.T) {
+	if _, err := os.Stat("/usr/bin/python3"); err != nil {
+		t.Skip("python3 is not available: ", err)
+	}
+	p := newTestPool(t, func(c *Config) { c.ShmSize = 16 << 20 })
+	run := func(m envexec.Environment, args ...string) envexec.Result {
+		t.Helper()
+		r, err := (&envexec.Single{Cmd: &envexec.Cmd{
+			Environment: m,
+			Args:        args,
+			Env:         []string{"PATH=/usr/bin:/bin"},
+			TimeLimit:   5 * time.Second,
+			MemoryLimit: 256 << 20,
+			ProcLimit:   64,
+			ShmLimit:    16 << 20,
+			Waiter:      sampleWaiter,
+		}}).Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	m, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Skip("environment is not available: ", err)
+	}
+	r := run(m, "/usr/bin/python3", "-c", shmScript)
+	if r.Status != envexec.StatusAccepted {
+		t.Fatalf("multiprocessing: %v: %s", r.Status, r.Error)
+	}
+	if r.ShmUsage < 4096 {
+		t.Fatalf("shm usage %v, want the file left counted", r.ShmUsage)
+	}
+	p.Put(m)
+
+	m1, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Put(m1)
+	if m1 != m {
+		t.Fatal("environment is not reused")
+	}
+	if r := run(m1, "/bin/sh", "-c", `test -d /dev/shm && test -z "$(ls -A /dev/shm)"`); r.Status != envexec.StatusAccepted {
+		t.Fatalf("/dev/shm is not wiped: %v", r.Status)
+	}
+}
@@ -0,0 +1,78 @@
+package env
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// findProcs returns the pids of the host processes with the command line
+func findProcs(t *testing.T, cmdline []byte) []int {
+	t.Helper()
+	paths, err := filepath.Glob("/proc/[0-9]*/cmdline")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rt []int
+	for _, p := range paths {
+		b, err := os.ReadFile(p)
+		if err != nil || !bytes.Equal(b, cmdline) {
+			continue
+		}
+		pid, _ := strconv.Atoi(filepath.Base(filepath.Dir(p)))
+		rt = append(rt, pid)
+	}
+	return rt
+}
+
+func TestResetKillsDoubleForked(t *testing.T) {
+	p := newTestPool(t)
+	run := func(m envexec.Environment, script string) {
+		t.Helper()
+		r, err := (&envexec.Single{Cmd: &envexec.Cmd{
+			Environment: m,
+			Args:        []string{"/bin/sh", "-c", script},
+			Env:         []string{"PATH=/usr/bin:/bin"},
+			TimeLimit:   5 * time.Second,
+			MemoryLimit: 256 << 20,
+			ProcLimit:   64,
+			Waiter:      sampleWaiter,
+		}}).Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Status != envexec.StatusAccepted {
+			t.Fatalf("%v: %s", r.Status, r.Error)
+		}
+	}
+
+	m, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Skip("environment is not available: ", err)
+	}
+	// the sleeper is reparented and outlives the program
+	arg := "100." + strconv.Itoa(os.Getpid())
+	run(m, "(sleep "+arg+" &); sleep 0.1")
+	if pids := findProcs(t, []byte("sleep\x00"+arg+"\x00")); len(pids) > 0 {
+		t.Fatalf("the sleeper %v is left after the run", pids)
+	}
+	p.Put(m)
+
+	// the environment is reused only if no process is left in its cgroup on
+	// reset
+	m1, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Put(m1)
+	if m1 != m {
+		t.Fatal("environment is not reused")
+	}
+	run(m1, "true")
+}
@@ -0,0 +1,140 @@
+package env
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// TestCPUTimeCalibration runs a busy loop of known cpu time, the cpu time
+// reported includes the start up of the program only
+func TestCPUTimeCalibration(t *testing.T) {
+	const (
+		spinTime  = 300 * time.Millisecond
+		tolerance = 5 * time.Millisecond
+	)
+	p := newTestPool(t)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Skip("environment is not available: ", err)
+	}
+	defer p.Put(m)
+
+	for i := 0; i < 5; i++ {
+		r, err := (&envexec.Single{Cmd: &envexec.Cmd{
+			Environment: m,
+			CopyIn:      map[string]envexec.File{"t": envexec.NewFileInput(exe)},
+			Args:        []string{"t"},
+			Env:         []string{spinEnv + "=" + strconv.Itoa(int(spinTime/time.Millisecond))},
+			TimeLimit:   5 * time.Second,
+			MemoryLimit: 256 << 20,
+			ProcLimit:   64,
+			Waiter:      sampleWaiter,
+		}}).Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Status != envexec.StatusAccepted {
+			t.Fatalf("run %d: %v: %s", i, r.Status, r.Error)
+		}
+		if r.Time < spinTime || r.Time > spinTime+tolerance {
+			t.Fatalf("run %d: cpu time %v, want %v within %v", i, r.Time, spinTime, tolerance)
+		}
+	}
+}
+
+// TestEmptyProgramCPUTime runs an empty program repeatedly, the overhead of
+// the container is reported separately instead of charged to the program
+func TestEmptyProgramCPUTime(t *testing.T) {
+	const runs = 100
+	p := newTestPool(t)
+	m, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Skip("environment is not available: ", err)
+	}
+	defer p.Put(m)
+
+	for i := 0; i < runs; i++ {
+		r, err := (&envexec.Single{Cmd: &envexec.Cmd{
+			Environment:  m,
+			Args:         []string{"/bin/true"},
+			TimeLimit:    time.Second,
+			MemoryLimit:  64 << 20,
+			ProcLimit:    16,
+			ReportTiming: true,
+			Waiter:       sampleWaiter,
+		}}).Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Status != envexec.StatusAccepted {
+			t.Fatalf("run %d: %v: %s", i, r.Status, r.Error)
+		}
+		if r.Time >= time.Millisecond {
+			t.Fatalf("run %d: cpu time %v, want less than 1ms (overhead %v)", i, r.Time, r.OverheadTime)
+		}
+		if r.OverheadTime <= 0 {
+			t.Fatalf("run %d: overhead cpu time is not reported", i)
+		}
+	}
+}
+
+// TestCPUThrottling spins under a quarter of a cpu, the throttling is
+// reported for the run only
+func TestCPUThrottling(t *testing.T) {
+	p := newTestPool(t, func(c *Config) {
+		c.EnableCPURate = true
+		c.CPUCfsPeriod = 100 * time.Millisecond
+	})
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Skip("environment is not available: ", err)
+	}
+	defer p.Put(m)
+
+	run := func(rate uint64) envexec.Result {
+		t.Helper()
+		r, err := (&envexec.Single{Cmd: &envexec.Cmd{
+			Environment:  m,
+			CopyIn:       map[string]envexec.File{"t": envexec.NewFileInput(exe)},
+			Args:         []string{"t"},
+			Env:          []string{spinEnv + "=300"},
+			TimeLimit:    5 * time.Second,
+			MemoryLimit:  256 << 20,
+			ProcLimit:    64,
+			CPURateLimit: rate,
+			Waiter:       sampleWaiter,
+		}}).Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Status != envexec.StatusAccepted {
+			t.Fatalf("%v: %s", r.Status, r.Error)
+		}
+		if r.Throttling == nil {
+			t.Skip("cpu throttling is not available")
+		}
+		return r
+	}
+
+	r := run(250)
+	if s := r.Throttling; s.Throttled == 0 || s.ThrottledTime <= 0 || s.Throttled > s.Periods {
+		t.Fatalf("throttling %+v, want throttled under the quota", *s)
+	}
+	// the counters of the previous run are not carried over
+	if s := run(0).Throttling; s.Throttled != 0 || s.ThrottledTime != 0 {
+		t.Fatalf("throttling %+v without the quota, want zero", *s)
+	}
+}
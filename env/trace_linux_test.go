@@ -0,0 +1,71 @@
+package env
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+func TestTraceSyscallCount(t *testing.T) {
+	const calls = 100
+	p := newTestPool(t)
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := p.Get(context.Background(), envexec.EnvironmentOptions{})
+	if err != nil {
+		t.Skip("environment is not available: ", err)
+	}
+	defer p.Put(m)
+	if e, ok := m.(envexec.TraceEnvironment); !ok {
+		t.Skip("trace is not supported")
+	} else if err := e.TraceAvailable(); err != nil {
+		t.Skip("trace is not available: ", err)
+	}
+
+	run := func(args []string, env ...string) envexec.Result {
+		t.Helper()
+		r, err := (&envexec.Single{Cmd: &envexec.Cmd{
+			Environment: m,
+			CopyIn:      map[string]envexec.File{"t": envexec.NewFileInput(exe)},
+			Args:        args,
+			Env:         append([]string{"PATH=/usr/bin:/bin"}, env...),
+			TimeLimit:   5 * time.Second,
+			MemoryLimit: 256 << 20,
+			ProcLimit:   64,
+			Trace:       true,
+			Waiter:      sampleWaiter,
+		}}).Run(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.Status != envexec.StatusAccepted {
+			t.Fatalf("%v: %s", r.Status, r.Error)
+		}
+		return r
+	}
+
+	// the program calls getppid the number of times after its own execve
+	r := run([]string{"t"}, getppidEnv+"="+strconv.Itoa(calls))
+	if n := r.SyscallCount["getppid"]; n != calls {
+		t.Errorf("getppid called %d times, want %d: %v", n, calls, r.SyscallCount)
+	}
+	if n := r.SyscallCount["execve"]; n != 1 {
+		t.Errorf("execve called %d times, want 1: %v", n, r.SyscallCount)
+	}
+
+	// the children are traced as well, the shell itself calls getppid for
+	// $PPID on start
+	r = run([]string{"/bin/sh", "-c", "./t; ./t"}, getppidEnv+"="+strconv.Itoa(calls))
+	if n := r.SyscallCount["getppid"]; n < 2*calls || n > 2*calls+1 {
+		t.Errorf("getppid called %d times by the children, want %d: %v", n, 2*calls, r.SyscallCount)
+	}
+	if n := r.SyscallCount["execve"]; n < 3 {
+		t.Errorf("execve called %d times, want the shell and both children: %v", n, r.SyscallCount)
+	}
+}
@@ -0,0 +1,163 @@
+//go:build windows
+
+package winc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// testModeEnv makes the test binary copied into the work directory run as
+// the program of the mode instead of the tests
+const testModeEnv = "GO_JUDGE_WINC_TEST"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(testModeEnv) {
+	case "echo":
+		fmt.Print("hello")
+		os.Exit(0)
+	case "spin":
+		for {
+		}
+	case "write":
+		if err := os.WriteFile("out", []byte("copied"), 0644); err != nil {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// limitWaiter kills the process once its cpu time exceeded the limit or the
+// clock time exceeded the limit of 3 times
+func limitWaiter(limit time.Duration) func(context.Context, envexec.Process) bool {
+	return func(ctx context.Context, p envexec.Process) bool {
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		start := time.Now()
+		for {
+			select {
+			case <-ctx.Done():
+				return false
+			case <-p.Done():
+				return false
+			case <-ticker.C:
+				if time.Since(start) > 3*limit || p.Usage().Time > limit {
+					return true
+				}
+			}
+		}
+	}
+}
+
+func runTest(t *testing.T, mode string, cmd func(*envexec.Cmd)) envexec.Result {
+	t.Helper()
+	b, err := NewBuilder(t.TempDir())
+	if err != nil {
+		t.Skip("environment is not available: ", err)
+	}
+	m, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.Destroy()
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &envexec.Cmd{
+		Environment: m,
+		CopyIn:      map[string]envexec.File{"t.exe": envexec.NewFileInput(exe)},
+		Args:        []string{"t.exe"},
+		Env:         []string{testModeEnv + "=" + mode, "SystemRoot=" + os.Getenv("SystemRoot")},
+		Files: []envexec.File{
+			envexec.NewFileReader(strings.NewReader(""), false),
+			envexec.NewFileCollector("stdout", 1024, false),
+			envexec.NewFileCollector("stderr", 1024, false),
+		},
+		TimeLimit:   time.Second,
+		MemoryLimit: 256 << 20,
+		ProcLimit:   16,
+		Waiter:      limitWaiter(time.Second),
+	}
+	if cmd != nil {
+		cmd(c)
+	}
+	dir := t.TempDir()
+	r, err := (&envexec.Single{
+		Cmd:          c,
+		NewStoreFile: func() (*os.File, error) { return os.CreateTemp(dir, "") },
+	}).Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		for _, f := range r.Files {
+			f.Close()
+		}
+	})
+	return r
+}
+
+func readResultFile(t *testing.T, r envexec.Result, name string) string {
+	t.Helper()
+	f, ok := r.Files[name]
+	if !ok {
+		t.Fatalf("%s is not collected: %v", name, r.Files)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+func TestRun(t *testing.T) {
+	r := runTest(t, "echo", nil)
+	if r.Status != envexec.StatusAccepted {
+		t.Fatalf("status %v: %s", r.Status, r.Error)
+	}
+	if out := readResultFile(t, r, "stdout"); out != "hello" {
+		t.Fatalf("stdout %q, want hello", out)
+	}
+}
+
+func TestRunTimeLimitExceeded(t *testing.T) {
+	const limit = 200 * time.Millisecond
+	start := time.Now()
+	r := runTest(t, "spin", func(c *envexec.Cmd) {
+		c.TimeLimit = limit
+		c.Waiter = limitWaiter(limit)
+	})
+	if r.Status != envexec.StatusTimeLimitExceeded {
+		t.Fatalf("status %v, want %v: %s", r.Status, envexec.StatusTimeLimitExceeded, r.Error)
+	}
+	// killed instead of running till the end of the test
+	if d := time.Since(start); d > 10*time.Second {
+		t.Fatalf("killed after %v", d)
+	}
+}
+
+func TestRunCopyOut(t *testing.T) {
+	r := runTest(t, "write", func(c *envexec.Cmd) {
+		c.CopyOut = []envexec.CmdCopyOutFile{{Name: "out"}}
+		c.CopyOutMax = 1024
+	})
+	if r.Status != envexec.StatusAccepted {
+		t.Fatalf("status %v: %s", r.Status, r.Error)
+	}
+	if out := readResultFile(t, r, "out"); out != "copied" {
+		t.Fatalf("out %q, want copied", out)
+	}
+}
@@ -22,6 +22,14 @@ type Cmd struct {
 	// file contents to copyin before exec
 	CopyIn map[string]File
 
+	// Snapshot is restored into the work directory before the copy in, so
+	// that the copy in files override the files of it
+	Snapshot *Snapshot
+
+	// limits for archive extraction in copyin
+	ArchiveMaxCount int
+	ArchiveMaxSize  Size
+
 	// symbolic link to be created before exec
 	SymLinks map[string]string
 
@@ -45,6 +53,45 @@ type Cmd struct {
 	StrictMemoryLimit bool
 	CPUSetLimit       string
 
+	// DetailedMemory collects memory statistics breakdown after exec
+	DetailedMemory bool
+
+	// IOLimit limits the bytes written to block devices, 0 for unlimited
+	IOLimit Size
+
+	// CoreLimit limits the size of the core file (RLIMIT_CORE) and the core
+	// file dumped into the work directory is collected on signalled exit, 0
+	// disables core dump
+	CoreLimit Size
+
+	// Trace counts the syscalls made by the process group, the program is
+	// not run if the environment could not trace it
+	Trace bool
+
+	// ReportTiming measures the cpu time of the environment overhead during
+	// the run into OverheadTime
+	ReportTiming bool
+
+	// ProcInfo replaces /proc/meminfo and /proc/cpuinfo with the synthetic
+	// ones, the environment must be built with them mounted
+	ProcInfo *ProcInfo
+
+	// Random replaces /dev/urandom and /dev/random with the deterministic
+	// stream of the seed, the environment must be built with them mounted
+	Random *Random
+
+	// Arch specifies the architecture of the program, ArchAuto detects it
+	// from the executable and empty skips the detection
+	Arch string
+
+	// ShmLimit limits the bytes left in /dev/shm after the run, 0 skips the
+	// check. The shm pages are charged to the memory of the process group.
+	ShmLimit Size
+
+	// DiskLimit limits the bytes left in the work directory backed by disk
+	// after the run, 0 skips the check
+	DiskLimit Size
+
 	// Waiter is called after cmd starts and it should return
 	// once time limit exceeded.
 	// return true to as TLE and false as normal exits (context finished)
@@ -54,6 +101,10 @@ type Cmd struct {
 	CopyOut    []CmdCopyOutFile
 	CopyOutMax Size // file size limit
 
+	// limits on all copy out files, none of them is copied out if exceeded
+	CopyOutMaxFiles int  // file count limit
+	CopyOutMaxTotal Size // total file size limit
+
 	// CopyOutDir specifies a dir to dump all /w contnet
 	CopyOutDir string
 }
@@ -62,13 +113,25 @@ type Cmd struct {
 type CmdCopyOutFile struct {
 	Name     string // Name is the file out to copyOut
 	Optional bool   // Optional ignores the file if not exists
+	StatOnly bool   // StatOnly reports the FileStat without copying the content
+	Glob     bool   // Glob copies out the regular files matching Name as pattern of path.Match
+}
+
+// FileStat defines the metadata of the copy out file
+type FileStat struct {
+	Size    int64
+	Mode    os.FileMode
+	ModTime time.Time
 }
 
 // Result defines the running result for single Cmd
 type Result struct {
 	Status Status
 
-	ExitStatus int
+	ExitStatus int // exit status (signal number if signalled)
+
+	Signal       int               // signal that terminated the program, 0 if none
+	TerminatedBy TerminationReason // how the program was terminated
 
 	Error string // error
 
@@ -76,11 +139,102 @@ type Result struct {
 	RunTime time.Duration
 	Memory  Size // byte
 
+	// MemoryStat stores memory statistics breakdown if DetailedMemory is set
+	MemoryStat *MemoryStat
+
+	// ProcPeak stores the peak number of processes / threads if available
+	ProcPeak uint64
+
+	// StrayProcs stores the number of processes left running after the
+	// process exited and killed after the run if available
+	StrayProcs uint64
+
+	// IOStat stores the bytes read and written to block devices if available
+	IOStat *IOStat
+
+	// Throttling stores the cpu throttling of the run if available
+	Throttling *CPUThrottling
+
+	// Arch stores the architecture of the program if Arch is set and it is
+	// known, Emulated reports it ran under an emulator
+	Arch     string
+	Emulated bool
+
+	// RandomOpened reports the program opened /dev/urandom or /dev/random
+	// if Random is set
+	RandomOpened bool
+
+	// ShmUsage stores the bytes left in /dev/shm after the run if ShmLimit
+	// is set and the environment has /dev/shm mounted
+	ShmUsage Size
+
+	// DiskUsage stores the bytes left in the work directory backed by disk
+	// after the run if DiskLimit is set
+	DiskUsage Size
+
+	// WorkDirUsage and WorkDirSize store the bytes used and the size of the
+	// tmpfs work directory after the run if ReportTiming is set or the
+	// program exited abnormally
+	WorkDirUsage Size
+	WorkDirSize  Size
+
+	// InitLog stores the stderr of the container init of the environment if
+	// the status is Internal Error and it is kept
+	InitLog string
+
+	// EnvironmentID and CgroupID identify the pooled environment and the
+	// cgroup that ran the program, 0 if not available
+	EnvironmentID uint64
+	CgroupID      uint64
+
+	// Core stores the collected core file if CoreLimit is set and CoreError
+	// explains why core dump is not available if so
+	Core      *os.File
+	CoreError string
+
+	// SyscallCount stores the number of calls of each syscall if Trace is set
+	SyscallCount map[string]uint64
+
+	// OverheadTime stores the cpu time spent by the environment for the run,
+	// which is not included in Time, if ReportTiming is set and available
+	OverheadTime time.Duration
+
 	// Files stores copy out files
 	Files map[string]*os.File
 
+	// FileStat stores the metadata of copy out files found, including the
+	// ones with StatOnly
+	FileStat map[string]FileStat
+
 	// FileError stores file errors details
 	FileError []FileError
+
+	// Extracted stores number of entries (files, directories and symbolic
+	// links) extracted from copyin archives
+	Extracted map[string]int
+
+	// CopyInBytes stores bytes copied in by CopyIn, the archives are counted
+	// by the bytes extracted
+	CopyInBytes Size
+
+	// PipeBytes stores bytes forwarded by the proxy from the fd of the Cmd
+	// to the other end of the pipe
+	PipeBytes map[int]int64
+
+	// OutputStat stores bytes and lines written to the pipe and discard
+	// collectors, including the content over the limit
+	OutputStat map[string]OutputStat
+
+	// CopyOutTime is the wall time spent on copy out and pipe collecting
+	CopyOutTime time.Duration
+
+	// StartedAt is the time when the command is about to be executed (after
+	// copy in), zero if not executed
+	StartedAt time.Time
+
+	// AttachRetries stores the number of retries on transient failures of
+	// creating the cgroup or attaching the process to it before it started
+	AttachRetries int
 }
 
 type FileErrorType int
@@ -97,6 +251,15 @@ const (
 	ErrCopyOutCopyContent
 	ErrCollectSizeExceeded
 	ErrSymlink
+	ErrCopyInArchive
+	ErrCopyOutLimitExceeded
+	ErrCopyInCorrupted
+	ErrExecNotFound
+	ErrExecMissingInterpreter
+	ErrExecNotExecutable
+	ErrExecInvalidFormat
+	ErrCopyInSnapshot
+	ErrExecNeedsEmulation
 )
 
 type FileError struct {
@@ -116,6 +279,16 @@ var fileErrorString = []string{
 	"CopyOutCreateFile",
 	"CopyOutCopyContent",
 	"CollectSizeExceeded",
+	"Symlink",
+	"CopyInArchive",
+	"CopyOutLimitExceeded",
+	"CopyInCorrupted",
+	"ExecNotFound",
+	"ExecMissingInterpreter",
+	"ExecNotExecutable",
+	"ExecInvalidFormat",
+	"CopyInSnapshot",
+	"ExecNeedsEmulation",
 }
 
 var fileErrorStringReverse = make(map[string]FileErrorType)
@@ -1,5 +1,8 @@
 package envexec
 
 const (
-	defaultExtraMemoryLimit = Size(16 << 10) // 16k more memory
+	defaultExtraMemoryLimit = Size(16 << 10)  // 16k more memory
+	defaultArchiveMaxCount  = 4096            // 4k entries for copyIn archive
+	defaultArchiveMaxSize   = Size(128 << 20) // 128m for copyIn archive
+	archiveMaxSymlinkDepth  = 40              // links followed to resolve a link target, as MAXSYMLINKS
 )
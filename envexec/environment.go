@@ -0,0 +1,94 @@
+package envexec
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// ProfileMinimal is the builtin mount profile with only the tmpfs work
+// directory, /proc and /dev/null mounted, used to run static binaries
+const ProfileMinimal = "minimal"
+
+// Credential defines the host uid / gid the container runs as
+type Credential struct {
+	UID uint32
+	GID uint32
+}
+
+// EnvironmentReason classifies the failure of creating a new environment
+type EnvironmentReason string
+
+// EnvironmentReason
+const (
+	EnvironmentNamespaceLimit EnvironmentReason = "namespaceLimit" // namespace count limit reached
+	EnvironmentMountFailed    EnvironmentReason = "mountFailed"    // container root filesystem mount failed
+	EnvironmentForkFailed     EnvironmentReason = "forkFailed"     // container init process could not be created
+	EnvironmentCgroupFailed   EnvironmentReason = "cgroupFailed"   // cgroup for the environment could not be created
+	EnvironmentUnknown        EnvironmentReason = "unknown"
+)
+
+// EnvironmentError is returned by the environment builder when it failed to
+// create a new environment, it indicates the server could not run any request
+// (until it recovers) rather than a problem of the request
+type EnvironmentError struct {
+	Reason EnvironmentReason
+	Errno  syscall.Errno // 0 if not known
+	Err    error
+}
+
+func (e *EnvironmentError) Error() string {
+	if e.Errno != 0 {
+		return fmt.Sprintf("environment: %s (errno %d): %v", e.Reason, uintptr(e.Errno), e.Err)
+	}
+	return fmt.Sprintf("environment: %s: %v", e.Reason, e.Err)
+}
+
+func (e *EnvironmentError) Unwrap() error {
+	return e.Err
+}
+
+// EnvironmentOptions describes the environment acquired for a command. The
+// zero value is the environment with the configured mounts running as the
+// generated credential. Besides Credential, at most one of the options is set
+// since each of them builds the container with its own mounts.
+type EnvironmentOptions struct {
+	// Credential pins the host uid / gid the container runs as
+	Credential *Credential
+
+	// RandomWorkDir mounts the work directory at a randomized path
+	RandomWorkDir bool
+
+	// Datasets are the named read-only datasets mounted at /data/<name>
+	Datasets []string
+
+	// Profile is the named mount profile used instead of the configured mounts
+	Profile string
+
+	// MountFiles is the host directory mounted read-only at /files
+	MountFiles string
+
+	// HostExec maps the path in the container to the host file mounted
+	// read-only there
+	HostExec map[string]string
+
+	// DiskWorkDir is the host directory whose subdirectories back the work
+	// directory and /tmp instead of tmpfs
+	DiskWorkDir string
+
+	// ScratchFile is the host file mounted writable at ScratchName in the
+	// work directory
+	ScratchFile string
+	ScratchName string
+
+	// ProcInfo mounts the synthetic /proc/meminfo and /proc/cpuinfo
+	ProcInfo bool
+
+	// Random mounts the deterministic /dev/urandom and /dev/random
+	Random bool
+}
+
+// Plain returns whether no option other than Credential is set
+func (o *EnvironmentOptions) Plain() bool {
+	return !o.RandomWorkDir && len(o.Datasets) == 0 && o.Profile == "" && o.MountFiles == "" &&
+		len(o.HostExec) == 0 && o.DiskWorkDir == "" && o.ScratchFile == "" && !o.ProcInfo && !o.Random
+}
@@ -35,6 +35,10 @@ type ReaderTTY interface {
 // FileInput represent file input which will be opened in read-only mode
 type FileInput struct {
 	Path string
+
+	// SHA256 is the hex encoded digest of the content if known. It is verified
+	// while the file is read through FileToReader.
+	SHA256 string
 }
 
 func (*FileInput) isFile() {}
@@ -49,10 +53,35 @@ type FileCollector struct {
 	Name  string
 	Limit Size
 	Pipe  bool
+	Cache bool // collects up to CopyOutMax so that full output could be cached
+
+	// Discard reads and discards the content and only counts the output,
+	// the count is reported in Result.OutputStat
+	Discard bool
+
+	// NewBuffer creates the file the pipe collector writes into instead of
+	// NewStoreFile of the command if not nil, e.g. for outputs never cached
+	NewBuffer NewStoreFile
 }
 
 func (*FileCollector) isFile() {}
 
+// newBuffer returns the function creating the buffer of the pipe collector
+func (f *FileCollector) newBuffer(newStoreFile NewStoreFile) NewStoreFile {
+	if f.NewBuffer != nil {
+		return f.NewBuffer
+	}
+	return newStoreFile
+}
+
+// collectLimit returns the max size to be collected
+func (f *FileCollector) collectLimit(c *Cmd) Size {
+	if f.Cache && c.CopyOutMax > f.Limit {
+		return c.CopyOutMax
+	}
+	return f.Limit
+}
+
 // NewFileCollector creates file output which will be collected through pipe
 func NewFileCollector(name string, limit Size, pipe bool) File {
 	return &FileCollector{Name: name, Limit: limit, Pipe: pipe}
@@ -83,6 +112,42 @@ func NewFileOpened(f *os.File) File {
 	return &FileOpened{File: f}
 }
 
+// ArchiveFormat defines the format of the archive file
+type ArchiveFormat int
+
+// Defines supported archive formats
+const (
+	ArchiveTar ArchiveFormat = iota + 1
+	ArchiveTarGz
+	ArchiveZip
+)
+
+// FileArchive represent archive file which will be extracted into the
+// directory when copy in
+type FileArchive struct {
+	File   File
+	Format ArchiveFormat
+}
+
+func (*FileArchive) isFile() {}
+
+// NewFileArchive creates archive file which will be extracted when copy in
+func NewFileArchive(f File, format ArchiveFormat) File {
+	return &FileArchive{File: f, Format: format}
+}
+
+// FileFifo represent named pipe created when copy in, it is opened by the
+// program and connected to the other Cmd of the group through Pipe with
+// PipeIndex.Fifo
+type FileFifo struct{}
+
+func (*FileFifo) isFile() {}
+
+// NewFileFifo creates named pipe which will be created when copy in
+func NewFileFifo() File {
+	return &FileFifo{}
+}
+
 // FileToReader get a Reader from underlying file
 // the reader need to be closed by caller explicitly
 func FileToReader(f File) (io.ReadCloser, error) {
@@ -98,6 +163,9 @@ func FileToReader(f File) (io.ReadCloser, error) {
 		if err != nil {
 			return nil, err
 		}
+		if f.SHA256 != "" {
+			return newDigestReader(file, f.SHA256), nil
+		}
 		return file, nil
 
 	default:
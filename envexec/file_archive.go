@@ -0,0 +1,339 @@
+package envexec
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// archiveExtractor extracts archive entries into directory inside the container
+type archiveExtractor struct {
+	m        Environment
+	dir      string
+	maxCount int
+	maxSize  Size
+
+	count    int
+	size     Size
+	symlinks map[string]string // path -> target of the links created
+}
+
+func newArchiveExtractor(m Environment, dir string, maxCount int, maxSize Size) *archiveExtractor {
+	if maxCount <= 0 {
+		maxCount = defaultArchiveMaxCount
+	}
+	if maxSize <= 0 {
+		maxSize = defaultArchiveMaxSize
+	}
+	return &archiveExtractor{
+		m:        m,
+		dir:      dir,
+		maxCount: maxCount,
+		maxSize:  maxSize,
+		symlinks: make(map[string]string),
+	}
+}
+
+// extractArchive extracts the archive into dir and returns number of entries
+// (files, directories and symbolic links) and bytes extracted
+func extractArchive(m Environment, dir string, f *FileArchive, maxCount int, maxSize Size) (int, Size, error) {
+	e := newArchiveExtractor(m, dir, maxCount, maxSize)
+	if err := m.MkdirAll(dir, 0777); err != nil {
+		return 0, 0, fmt.Errorf("failed to create dir %v", err)
+	}
+
+	var err error
+	switch f.Format {
+	case ArchiveTar, ArchiveTarGz:
+		err = e.extractTar(f)
+	case ArchiveZip:
+		err = e.extractZip(f)
+	default:
+		err = fmt.Errorf("unknown archive format %v", f.Format)
+	}
+	return e.count, e.size, err
+}
+
+func (e *archiveExtractor) extractTar(f *FileArchive) error {
+	r, err := FileToReader(f.File)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	var tr *tar.Reader
+	if f.Format == ArchiveTarGz {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		tr = tar.NewReader(gr)
+	} else {
+		tr = tar.NewReader(r)
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			err = e.mkdir(hdr.Name)
+		case tar.TypeReg:
+			err = e.writeFile(hdr.Name, os.FileMode(hdr.Mode).Perm(), tr)
+		case tar.TypeSymlink:
+			err = e.symlink(hdr.Name, hdr.Linkname)
+		case tar.TypeXGlobalHeader:
+		default:
+			err = fmt.Errorf("%s: entry type %q is not allowed", hdr.Name, hdr.Typeflag)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (e *archiveExtractor) extractZip(f *FileArchive) error {
+	ra, size, closer, err := fileToReaderAt(f.File)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return err
+	}
+	for _, zf := range zr.File {
+		mode := zf.Mode()
+		switch {
+		case mode.IsDir():
+			err = e.mkdir(zf.Name)
+		case mode.IsRegular():
+			err = e.extractZipFile(zf)
+		case mode&fs.ModeSymlink != 0:
+			err = e.extractZipSymlink(zf)
+		default:
+			err = fmt.Errorf("%s: entry type %v is not allowed", zf.Name, mode.Type())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *archiveExtractor) extractZipFile(zf *zip.File) error {
+	r, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return e.writeFile(zf.Name, zf.Mode().Perm(), r)
+}
+
+func (e *archiveExtractor) extractZipSymlink(zf *zip.File) error {
+	r, err := zf.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	target, err := io.ReadAll(io.LimitReader(r, 4096))
+	if err != nil {
+		return err
+	}
+	return e.symlink(zf.Name, string(target))
+}
+
+// entryPath checks the entry name and returns the cleaned relative path
+func (e *archiveExtractor) entryPath(name string) (string, error) {
+	if path.IsAbs(name) {
+		return "", fmt.Errorf("%s: absolute path is not allowed", name)
+	}
+	for _, p := range strings.Split(name, "/") {
+		if p == ".." {
+			return "", fmt.Errorf("%s: path contains '..'", name)
+		}
+	}
+	p := path.Clean(name)
+	if p == "." {
+		return p, nil
+	}
+	// do not write through symbolic links created by the archive
+	for d := path.Dir(p); d != "."; d = path.Dir(d) {
+		if _, ok := e.symlinks[d]; ok {
+			return "", fmt.Errorf("%s: path contains symbolic link", name)
+		}
+	}
+	if _, ok := e.symlinks[p]; ok {
+		return "", fmt.Errorf("%s: path is a symbolic link", name)
+	}
+	return p, nil
+}
+
+func (e *archiveExtractor) addCount(name string) error {
+	e.count++
+	if e.count > e.maxCount {
+		return fmt.Errorf("%s: number of entries exceeded the limit (%d)", name, e.maxCount)
+	}
+	return nil
+}
+
+func (e *archiveExtractor) mkdir(name string) error {
+	p, err := e.entryPath(name)
+	if err != nil {
+		return err
+	}
+	if err := e.addCount(name); err != nil {
+		return err
+	}
+	return e.m.MkdirAll(path.Join(e.dir, p), 0777)
+}
+
+func (e *archiveExtractor) writeFile(name string, perm os.FileMode, r io.Reader) error {
+	p, err := e.entryPath(name)
+	if err != nil {
+		return err
+	}
+	if err := e.addCount(name); err != nil {
+		return err
+	}
+	target := path.Join(e.dir, p)
+	if err := e.m.MkdirAll(path.Dir(target), 0777); err != nil {
+		return err
+	}
+	cf, err := e.m.Open(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm|0600)
+	if err != nil {
+		return err
+	}
+	defer cf.Close()
+
+	remain := e.maxSize - e.size
+	n, err := io.Copy(cf, io.LimitReader(r, int64(remain)+1))
+	e.size += Size(n)
+	if err != nil {
+		return err
+	}
+	if e.size > e.maxSize {
+		return fmt.Errorf("%s: extracted size exceeded the limit (%d)", name, e.maxSize)
+	}
+	return nil
+}
+
+func (e *archiveExtractor) symlink(name, target string) error {
+	p, err := e.entryPath(name)
+	if err != nil {
+		return err
+	}
+	if path.IsAbs(target) {
+		return fmt.Errorf("%s: symbolic link to absolute path %s is not allowed", name, target)
+	}
+	// the links created before are followed as the kernel does
+	depth := 0
+	if _, ok := e.resolve(path.Dir(p), target, &depth); !ok {
+		return fmt.Errorf("%s: symbolic link to %s is outside of the directory", name, target)
+	}
+	if err := e.addCount(name); err != nil {
+		return err
+	}
+	linkPath := path.Join(e.dir, p)
+	if err := e.m.MkdirAll(path.Dir(linkPath), 0777); err != nil {
+		return err
+	}
+	if err := e.m.Symlink(target, linkPath); err != nil {
+		return err
+	}
+	e.symlinks[p] = target
+	return nil
+}
+
+// resolve resolves the relative target from dir through the symbolic links
+// created by the archive, it fails if the target is outside of the directory
+// at any step or too many links are followed
+func (e *archiveExtractor) resolve(dir, target string, depth *int) (string, bool) {
+	var cur []string
+	if dir != "." {
+		cur = strings.Split(dir, "/")
+	}
+	for _, c := range strings.Split(target, "/") {
+		switch c {
+		case "", ".":
+			continue
+		case "..":
+			if len(cur) == 0 {
+				return "", false
+			}
+			cur = cur[:len(cur)-1]
+			continue
+		}
+		p := path.Join(append(cur, c)...)
+		t, ok := e.symlinks[p]
+		if !ok {
+			cur = append(cur, c)
+			continue
+		}
+		if *depth++; *depth > archiveMaxSymlinkDepth {
+			return "", false
+		}
+		r, ok := e.resolve(path.Join(append([]string{"."}, cur...)...), t, depth)
+		if !ok {
+			return "", false
+		}
+		cur = nil
+		if r != "." {
+			cur = strings.Split(r, "/")
+		}
+	}
+	return path.Join(append([]string{"."}, cur...)...), true
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// fileToReaderAt get a ReaderAt with its size for random access archive
+func fileToReaderAt(f File) (io.ReaderAt, int64, io.Closer, error) {
+	switch f := f.(type) {
+	case *FileReader:
+		if r, ok := f.Reader.(*bytes.Reader); ok {
+			return r, r.Size(), nopCloser{}, nil
+		}
+	case *FileInput, *FileOpened:
+		file, err := openFile(f)
+		if err != nil {
+			return nil, 0, nil, err
+		}
+		fi, err := file.Stat()
+		if err != nil {
+			file.Close()
+			return nil, 0, nil, err
+		}
+		return file, fi.Size(), file, nil
+	}
+	return nil, 0, nil, fmt.Errorf("file cannot be opened as zip archive %v", f)
+}
+
+// openFile opens the file for random access, digest is not verified since
+// the content is not read sequentially
+func openFile(f File) (*os.File, error) {
+	switch f := f.(type) {
+	case *FileInput:
+		return os.Open(f.Path)
+	case *FileOpened:
+		return f.File, nil
+	default:
+		return nil, fmt.Errorf("file cannot be opened %v", f)
+	}
+}
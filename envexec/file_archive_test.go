@@ -0,0 +1,157 @@
+package envexec
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// archiveEntry is the entry of the test archive, a directory if its name ends
+// with / and a symbolic link if link is set
+type archiveEntry struct {
+	name    string
+	content string
+	link    string
+}
+
+func tarArchive(t *testing.T, ents []archiveEntry) *FileArchive {
+	t.Helper()
+	var b bytes.Buffer
+	tw := tar.NewWriter(&b)
+	for _, e := range ents {
+		hdr := &tar.Header{Name: e.name, Mode: 0644, Typeflag: tar.TypeReg, Size: int64(len(e.content))}
+		switch {
+		case strings.HasSuffix(e.name, "/"):
+			hdr.Typeflag, hdr.Mode = tar.TypeDir, 0755
+		case e.link != "":
+			hdr.Typeflag, hdr.Linkname = tar.TypeSymlink, e.link
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(e.content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &FileArchive{File: NewFileReader(&b, false), Format: ArchiveTar}
+}
+
+func zipArchive(t *testing.T, ents []archiveEntry) *FileArchive {
+	t.Helper()
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for _, e := range ents {
+		hdr := &zip.FileHeader{Name: e.name}
+		content := e.content
+		switch {
+		case strings.HasSuffix(e.name, "/"):
+			hdr.SetMode(os.ModeDir | 0755)
+		case e.link != "":
+			hdr.SetMode(os.ModeSymlink | 0777)
+			content = e.link
+		default:
+			hdr.SetMode(0644)
+		}
+		w, err := zw.CreateHeader(hdr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// the zip archive is read at random offsets
+	f := filepath.Join(t.TempDir(), "a.zip")
+	if err := os.WriteFile(f, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return &FileArchive{File: NewFileInput(f), Format: ArchiveZip}
+}
+
+func TestExtractArchiveCount(t *testing.T) {
+	ents := []archiveEntry{
+		{name: "d/"},
+		{name: "d/e/"},
+		{name: "d/a", content: "a"},
+		{name: "d/l", link: "a"},
+	}
+	dirs := []archiveEntry{{name: "a/"}, {name: "b/"}, {name: "c/"}, {name: "d/"}, {name: "e/"}}
+	for _, c := range []struct {
+		name    string
+		archive func(*testing.T, []archiveEntry) *FileArchive
+	}{
+		{"tar", tarArchive},
+		{"zip", zipArchive},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			m := newTestEnv(t, nil)
+			count, size, err := extractArchive(m, "x", c.archive(t, ents), 0, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count != len(ents) || size != 1 {
+				t.Errorf("extracted %d entries of %d bytes, want %d entries of 1 byte", count, size, len(ents))
+			}
+			if fi, err := os.Stat(filepath.Join(m.dir.Name(), "x/d/e")); err != nil || !fi.IsDir() {
+				t.Errorf("directory is not extracted: %v", err)
+			}
+
+			// the directories are limited as the files
+			_, _, err = extractArchive(m, "y", c.archive(t, dirs), len(dirs)-1, 0)
+			if err == nil || !strings.Contains(err.Error(), "number of entries exceeded") {
+				t.Fatalf("got %v, want the number of entries exceeded", err)
+			}
+			if _, _, err := extractArchive(m, "z", c.archive(t, dirs), len(dirs), 0); err != nil {
+				t.Fatalf("got %v within the limit", err)
+			}
+		})
+	}
+}
+
+func TestExtractArchiveSymlinkChain(t *testing.T) {
+	for _, c := range []struct {
+		name    string
+		ents    []archiveEntry
+		outside bool
+	}{
+		{"parent", []archiveEntry{{name: "d/"}, {name: "d/l", link: ".."}}, false},
+		{"through link", []archiveEntry{{name: "d/"}, {name: "a", link: "d"}, {name: "b", link: "a/.."}}, false},
+		{"outside", []archiveEntry{{name: "a", link: "../x"}}, true},
+		// a resolves to the directory itself, so b is its parent although
+		// the path text a/a/a/.. is inside
+		{"chained", []archiveEntry{{name: "a", link: "."}, {name: "b", link: "a/a/a/.."}}, true},
+		{"chained in sub directory", []archiveEntry{{name: "d/"}, {name: "d/a", link: ".."}, {name: "d/b", link: "a/d/a/.."}}, true},
+	} {
+		for _, f := range []struct {
+			name    string
+			archive func(*testing.T, []archiveEntry) *FileArchive
+		}{
+			{"tar", tarArchive},
+			{"zip", zipArchive},
+		} {
+			t.Run(c.name+"/"+f.name, func(t *testing.T) {
+				m := newTestEnv(t, nil)
+				_, _, err := extractArchive(m, "x", f.archive(t, c.ents), 0, 0)
+				if c.outside {
+					if err == nil || !strings.Contains(err.Error(), "outside of the directory") {
+						t.Fatalf("got %v, want the link outside of the directory", err)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+			})
+		}
+	}
+}
@@ -6,15 +6,15 @@ import (
 	"io"
 	"os"
 	"sync"
+	"syscall"
 
 	"github.com/criyle/go-sandbox/runner"
-	"golang.org/x/sync/errgroup"
 )
 
 // copyOutAndCollect reads file and pipes in parallel from container
-func copyOutAndCollect(m Environment, c *Cmd, ptc []pipeCollector, newStoreFile NewStoreFile) (map[string]*os.File, []FileError, error) {
+func copyOutAndCollect(m Environment, c *Cmd, ptc []pipeCollector, newStoreFile NewStoreFile) (map[string]*os.File, map[string]FileStat, []FileError, error) {
 	var (
-		g         errgroup.Group
+		g         panicGroup
 		l, le     sync.Mutex
 		fileError []FileError
 	)
@@ -31,65 +31,49 @@ func copyOutAndCollect(m Environment, c *Cmd, ptc []pipeCollector, newStoreFile
 	}
 
 	// copy out
-	for _, n := range c.CopyOut {
-		n := n
+	cf, stats, fe, openErr := openCopyOut(m, c)
+	fileError = append(fileError, fe...)
+	if openErr != nil {
+		g.Go(func() error { return openErr })
+	}
+	for _, f := range cf {
+		f := f
 		g.Go(func() (err error) {
-			t := ErrCopyOutOpen
+			t := ErrCopyOutCopyContent
 			defer func() {
 				if err != nil {
 					addError(FileError{
-						Name:    n.Name,
+						Name:    f.name,
 						Type:    t,
 						Message: err.Error(),
 					})
 				}
 			}()
+			defer f.file.Close()
 
-			cf, err := m.Open(n.Name, os.O_RDONLY, 0777)
-			if err != nil {
-				if errors.Is(err, os.ErrNotExist) && n.Optional {
-					return nil
-				}
-				return err
-			}
-			defer cf.Close()
-
-			stat, err := cf.Stat()
-			if err != nil {
-				return err
-			}
-			// check regular file
-			if stat.Mode()&os.ModeType != 0 {
-				t = ErrCopyOutNotRegularFile
-				return fmt.Errorf("%s: not a regular file: %v", n.Name, stat.Mode())
-			}
-			// check size limit
-			s := stat.Size()
-			if c.CopyOutMax > 0 && s > int64(c.CopyOutMax) {
-				t = ErrCopyOutSizeExceeded
-				return fmt.Errorf("%s: size (%d) exceeded the limit (%d)", n.Name, s, c.CopyOutMax)
-			}
 			// create store file
 			buf, err := newStoreFile()
 			if err != nil {
 				t = ErrCopyOutCreateFile
-				return fmt.Errorf("%s: failed to create store file %v", n.Name, err)
+				return fmt.Errorf("%s: failed to create store file %v", f.name, err)
 			}
 
 			// Ensure not copy over file size
-			_, err = buf.ReadFrom(io.LimitReader(cf, s))
+			_, err = buf.ReadFrom(io.LimitReader(f.file, f.size))
 			if err != nil {
-				t = ErrCopyOutCopyContent
-				buf.Close()
+				removeStoreFile(buf)
 				return err
 			}
-			put(buf, n.Name)
+			put(buf, f.name)
 			return nil
 		})
 	}
 
 	// collect pipe
 	for _, p := range ptc {
+		if p.discard {
+			continue
+		}
 		p := p
 		g.Go(func() (err error) {
 			errType := ErrCopyOutOpen
@@ -121,7 +105,7 @@ func copyOutAndCollect(m Environment, c *Cmd, ptc []pipeCollector, newStoreFile
 				_, err = buf.ReadFrom(io.LimitReader(p.buffer, int64(p.limit)+1))
 				if err != nil {
 					errType = ErrCopyOutCopyContent
-					buf.Close()
+					removeStoreFile(buf)
 					return err
 				}
 				put(buf, p.name)
@@ -142,5 +126,100 @@ func copyOutAndCollect(m Environment, c *Cmd, ptc []pipeCollector, newStoreFile
 	}
 
 	err := g.Wait()
-	return rt, fileError, err
+	return rt, stats, fileError, err
+}
+
+type copyOutFile struct {
+	name string
+	file *os.File
+	size int64
+}
+
+// openCopyOut opens and checks copy out files. The limits of file count and
+// total size are checked against the files found, and none of the files is
+// copied out if any of them exceeded. Files with StatOnly are not copied and
+// not counted to the limits.
+func openCopyOut(m Environment, c *Cmd) ([]copyOutFile, map[string]FileStat, []FileError, error) {
+	var (
+		rt        []copyOutFile
+		stats     map[string]FileStat
+		fileError []FileError
+		firstErr  error
+		total     int64
+	)
+	addError := func(n string, t FileErrorType, err error) {
+		fileError = append(fileError, FileError{
+			Name:    n,
+			Type:    t,
+			Message: err.Error(),
+		})
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	entries, globError := expandCopyOut(m, c.CopyOut)
+	for _, e := range globError {
+		addError(e.Name, e.Type, errors.New(e.Message))
+	}
+	for _, n := range entries {
+		// fifos are reported as not regular file rather than blocked on open
+		cf, err := m.Open(n.Name, os.O_RDONLY|syscall.O_NONBLOCK, 0777)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) || !(n.Optional || n.matched) {
+				addError(n.Name, ErrCopyOutOpen, err)
+			}
+			continue
+		}
+		stat, err := cf.Stat()
+		if err != nil {
+			cf.Close()
+			addError(n.Name, ErrCopyOutOpen, err)
+			continue
+		}
+		// check regular file, optional fifo and the ones matched by glob are skipped
+		if stat.Mode()&os.ModeType != 0 {
+			cf.Close()
+			if stat.Mode()&os.ModeNamedPipe != 0 && n.Optional || n.matched {
+				continue
+			}
+			addError(n.Name, ErrCopyOutNotRegularFile, fmt.Errorf("%s: not a regular file: %v", n.Name, stat.Mode()))
+			continue
+		}
+		if stats == nil {
+			stats = make(map[string]FileStat)
+		}
+		stats[n.Name] = FileStat{Size: stat.Size(), Mode: stat.Mode(), ModTime: stat.ModTime()}
+		if n.StatOnly {
+			cf.Close()
+			continue
+		}
+		// check size limit
+		s := stat.Size()
+		if c.CopyOutMax > 0 && s > int64(c.CopyOutMax) {
+			cf.Close()
+			addError(n.Name, ErrCopyOutSizeExceeded, fmt.Errorf("%s: size (%d) exceeded the limit (%d)", n.Name, s, c.CopyOutMax))
+			continue
+		}
+		total += s
+		rt = append(rt, copyOutFile{name: n.Name, file: cf, size: s})
+	}
+
+	var err error
+	if c.CopyOutMaxFiles > 0 && len(rt) > c.CopyOutMaxFiles {
+		err = fmt.Errorf("copy out file count (%d) exceeded the limit (%d) by %d", len(rt), c.CopyOutMaxFiles, len(rt)-c.CopyOutMaxFiles)
+	} else if c.CopyOutMaxTotal > 0 && total > int64(c.CopyOutMaxTotal) {
+		err = fmt.Errorf("copy out total size (%d) exceeded the limit (%d) by %d", total, c.CopyOutMaxTotal, total-int64(c.CopyOutMaxTotal))
+	}
+	if err != nil {
+		for _, f := range rt {
+			f.file.Close()
+		}
+		fileError = append(fileError, FileError{
+			Type:    ErrCopyOutLimitExceeded,
+			Message: err.Error(),
+		})
+		return nil, stats, fileError, err
+	}
+	return rt, stats, fileError, firstErr
 }
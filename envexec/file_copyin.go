@@ -1,28 +1,44 @@
 package envexec
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
 	"sync"
-
-	"golang.org/x/sync/errgroup"
+	"sync/atomic"
 )
 
+// copyInStat is reported by the copy in
+type copyInStat struct {
+	extracted map[string]int // files extracted from each archive
+	bytes     Size           // copied, archives counted by the bytes extracted
+}
+
 // copyIn copied file from host to container in parallel
-func copyIn(m Environment, copyIn map[string]File) ([]FileError, error) {
+func copyIn(m Environment, c *Cmd) (copyInStat, []FileError, error) {
 	var (
-		g         errgroup.Group
+		g         panicGroup
 		fileError []FileError
-		l         sync.Mutex
+		extracted map[string]int
+		bytes     int64
+		l, le     sync.Mutex
 	)
 	addError := func(e FileError) {
+		le.Lock()
+		defer le.Unlock()
+		fileError = append(fileError, e)
+	}
+	addExtracted := func(n string, count int) {
 		l.Lock()
 		defer l.Unlock()
-		fileError = append(fileError, e)
+		if extracted == nil {
+			extracted = make(map[string]int)
+		}
+		extracted[n] = count
 	}
-	for n, f := range copyIn {
+	for n, f := range c.CopyIn {
 		n, f := n, f
 		g.Go(func() (err error) {
 			t := ErrCopyInOpenFile
@@ -36,6 +52,26 @@ func copyIn(m Environment, copyIn map[string]File) ([]FileError, error) {
 				}
 			}()
 
+			if a, ok := f.(*FileArchive); ok {
+				t = ErrCopyInArchive
+				count, size, err := extractArchive(m, n, a, c.ArchiveMaxCount, c.ArchiveMaxSize)
+				addExtracted(n, count)
+				atomic.AddInt64(&bytes, int64(size))
+				if err != nil {
+					return fmt.Errorf("failed to extract archive %v", err)
+				}
+				return nil
+			}
+
+			if _, ok := f.(*FileFifo); ok {
+				if err := m.MkdirAll(filepath.Dir(n), 0777); err != nil {
+					t = ErrCopyInCreateDir
+					return fmt.Errorf("failed to create dir %v", err)
+				}
+				t = ErrCopyInCreateFile
+				return mkfifo(m, n)
+			}
+
 			hf, err := FileToReader(f)
 			if err != nil {
 				return fmt.Errorf("failed to copyIn %v", err)
@@ -54,15 +90,20 @@ func copyIn(m Environment, copyIn map[string]File) ([]FileError, error) {
 			}
 			defer cf.Close()
 
-			_, err = io.Copy(cf, hf)
+			written, err := io.Copy(cf, hf)
+			atomic.AddInt64(&bytes, written)
 			if err != nil {
 				t = ErrCopyInCopyContent
+				if errors.Is(err, ErrCacheCorrupt) {
+					t = ErrCopyInCorrupted
+				}
 				return err
 			}
 			return nil
 		})
 	}
-	return fileError, g.Wait()
+	err := g.Wait()
+	return copyInStat{extracted: extracted, bytes: Size(atomic.LoadInt64(&bytes))}, fileError, err
 }
 
 func symlink(m Environment, symlinks map[string]string) (*FileError, error) {
@@ -0,0 +1,88 @@
+package envexec
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"syscall"
+	"time"
+)
+
+var errCoreNotSupported = errors.New("core dump is not supported by the environment")
+
+// coreDumpAvailable checks whether the core file could be collected from the
+// work directory of the environment
+func coreDumpAvailable(m Environment) error {
+	e, ok := m.(CoreEnvironment)
+	if !ok {
+		return errCoreNotSupported
+	}
+	return e.CoreDumpAvailable()
+}
+
+// collectCore copies the latest core file dumped into the work directory after
+// since into a store file, nil if there is none
+func collectCore(m Environment, since time.Time, limit Size, newStoreFile NewStoreFile) (*os.File, error) {
+	names, err := m.WorkDir().Readdirnames(-1)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		core    *os.File
+		coreMod time.Time
+	)
+	for _, n := range names {
+		// fifos (e.g. copied in) does not block
+		f, err := m.Open(n, os.O_RDONLY|syscall.O_NONBLOCK, 0)
+		if err != nil {
+			continue
+		}
+		fi, err := f.Stat()
+		if err != nil || !fi.Mode().IsRegular() || fi.ModTime().Before(since) || !isCoreFile(f) ||
+			(core != nil && !fi.ModTime().After(coreMod)) {
+			f.Close()
+			continue
+		}
+		if core != nil {
+			core.Close()
+		}
+		core, coreMod = f, fi.ModTime()
+	}
+	if core == nil {
+		return nil, nil
+	}
+	defer core.Close()
+
+	buf, err := newStoreFile()
+	if err != nil {
+		return nil, err
+	}
+	if _, err := core.Seek(0, io.SeekStart); err != nil {
+		removeStoreFile(buf)
+		return nil, err
+	}
+	if _, err := buf.ReadFrom(io.LimitReader(core, int64(limit))); err != nil {
+		removeStoreFile(buf)
+		return nil, err
+	}
+	return buf, nil
+}
+
+// isCoreFile checks the ELF magic and the e_type of ET_CORE (4)
+func isCoreFile(f *os.File) bool {
+	var h [18]byte
+	if _, err := io.ReadFull(f, h[:]); err != nil {
+		return false
+	}
+	if !bytes.Equal(h[:4], []byte("\x7fELF")) {
+		return false
+	}
+	switch h[5] {
+	case 1: // little endian
+		return h[16] == 4 && h[17] == 0
+	case 2: // big endian
+		return h[16] == 0 && h[17] == 4
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package envexec
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// ErrCacheCorrupt is wrapped by the read error when the content of file does
+// not match its recorded digest
+var ErrCacheCorrupt = errors.New("cache corrupt")
+
+// digestReader hashes the content while reading and verifies it at EOF
+type digestReader struct {
+	io.ReadCloser
+	h        hash.Hash
+	expected string
+}
+
+func newDigestReader(r io.ReadCloser, expected string) io.ReadCloser {
+	return &digestReader{
+		ReadCloser: r,
+		h:          sha256.New(),
+		expected:   strings.ToLower(expected),
+	}
+}
+
+func (r *digestReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.h.Write(p[:n])
+	if err == io.EOF {
+		if digest := hex.EncodeToString(r.h.Sum(nil)); digest != r.expected {
+			return n, fmt.Errorf("%w: sha256 mismatch, expected %s, got %s", ErrCacheCorrupt, r.expected, digest)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,152 @@
+package envexec
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+var errFifoNotSupported = errors.New("fifo is not supported by the environment")
+
+// mkfifo creates the named pipe of copy in
+func mkfifo(m Environment, name string) error {
+	e, ok := m.(FifoEnvironment)
+	if !ok {
+		return errFifoNotSupported
+	}
+	if err := e.Mkfifo(name); err != nil {
+		return fmt.Errorf("failed to create fifo %v", err)
+	}
+	return nil
+}
+
+// fifoBridge connects the named pipe opened by the program with the pipe end
+// of the other Cmd. The host opens the fifo in background and the open returns
+// once the program opened the other side, so that the pipe end is only
+// copied after the program joined. The fifo is reopened through the path fd
+// taken before the program starts in case the program removed it.
+type fifoBridge struct {
+	m     Environment
+	name  string   // name of the fifo in copy in
+	file  *os.File // pipe end of the other Cmd
+	write bool     // program reads the fifo written by the host
+	tr    *tracker
+
+	path     *os.File
+	opened   chan struct{} // closed after the open of the host returned
+	stopping int32
+	peer     bool // the program opened the fifo
+}
+
+func newFifoBridge(m Environment, name string, f *os.File, write bool, tr *tracker) *fifoBridge {
+	return &fifoBridge{m: m, name: name, file: f, write: write, tr: tr, opened: make(chan struct{})}
+}
+
+// startFifos starts the bridges after the fifos are created
+func startFifos(bs []*fifoBridge) {
+	for _, b := range bs {
+		p, err := openFifoPath(b.m, b.name)
+		if err != nil {
+			b.file.Close()
+			close(b.opened)
+			continue
+		}
+		b.tr.file(p)
+		b.path = p
+		b.tr.goFunc(b.run)
+	}
+}
+
+// stopFifos unblocks the bridges whose fifo was never opened after the
+// program exited
+func stopFifos(bs []*fifoBridge) {
+	for _, b := range bs {
+		b.stop()
+	}
+}
+
+// closeFifos releases the pipe ends if the bridges were never started
+func closeFifos(bs []*fifoBridge) {
+	for _, b := range bs {
+		b.file.Close()
+	}
+}
+
+func (b *fifoBridge) run() {
+	flag := os.O_RDONLY
+	if b.write {
+		flag = os.O_WRONLY
+	}
+	// blocks until the program opens the other side, or stop does
+	f, err := reopenFifo(b.path, flag)
+	b.peer = err == nil && atomic.LoadInt32(&b.stopping) == 0
+	close(b.opened)
+	if err != nil {
+		b.file.Close()
+		return
+	}
+	b.tr.file(f)
+	if !b.peer {
+		closeFiles(f, b.file)
+		return
+	}
+
+	// closing the pipe ends let the program and the other Cmd see EOF or
+	// EPIPE just like a pipe between them
+	if b.write {
+		io.Copy(f, b.file)
+	} else {
+		io.Copy(b.file, f)
+	}
+	closeFiles(f, b.file)
+}
+
+func (b *fifoBridge) stop() {
+	if b.path == nil {
+		return
+	}
+	defer b.path.Close()
+
+	atomic.StoreInt32(&b.stopping, 1)
+	// opens the other side without blocking to unblock the open of the host,
+	// the write side fails with ENXIO until the host is reading
+	flag := os.O_WRONLY
+	if b.write {
+		flag = os.O_RDONLY
+	}
+	for {
+		select {
+		case <-b.opened:
+			return
+		default:
+		}
+		if f, err := reopenFifo(b.path, flag|syscall.O_NONBLOCK); err == nil {
+			<-b.opened
+			f.Close()
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// fifoDeadlock returns the fifos never opened by the program, the Cmd
+// exceeded the time limit was likely waiting for the other side
+func fifoDeadlock(fifos [][]*fifoBridge) string {
+	var msg string
+	for i, bs := range fifos {
+		for _, b := range bs {
+			if b.peer {
+				continue
+			}
+			if msg != "" {
+				msg += ", "
+			}
+			msg += fmt.Sprintf("fifo %s of cmd %d was never opened", b.name, i)
+		}
+	}
+	return msg
+}
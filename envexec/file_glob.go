@@ -0,0 +1,103 @@
+package envexec
+
+import (
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"syscall"
+)
+
+// copyOutEntry is a copy out file with the glob patterns expanded into the
+// names they matched
+type copyOutEntry struct {
+	CmdCopyOutFile
+	matched bool // matched by glob, skipped if not a regular file
+}
+
+// expandCopyOut expands the copy out files with Glob into the names of the
+// files matched in the work directory
+func expandCopyOut(m Environment, copyOut []CmdCopyOutFile) ([]copyOutEntry, []FileError) {
+	rt := make([]copyOutEntry, 0, len(copyOut))
+	var fileError []FileError
+	for _, n := range copyOut {
+		if !n.Glob {
+			rt = append(rt, copyOutEntry{CmdCopyOutFile: n})
+			continue
+		}
+		names, err := globWorkDir(m, n.Name)
+		if err == nil && len(names) == 0 && !n.Optional {
+			err = &os.PathError{Op: "glob", Path: n.Name, Err: os.ErrNotExist}
+		}
+		if err != nil {
+			fileError = append(fileError, FileError{Name: n.Name, Type: ErrCopyOutOpen, Message: err.Error()})
+			continue
+		}
+		for _, name := range names {
+			f := n
+			f.Name, f.Glob = name, false
+			rt = append(rt, copyOutEntry{CmdCopyOutFile: f, matched: true})
+		}
+	}
+	return rt, fileError
+}
+
+// globWorkDir returns the sorted names in the work directory matching the
+// pattern of path.Match, each path element is matched against the entries of
+// the directories matched by the previous ones
+func globWorkDir(m Environment, pattern string) ([]string, error) {
+	elems := strings.Split(path.Clean(pattern), "/")
+	dirs := []string{""}
+	for _, elem := range elems {
+		var next []string
+		for _, dir := range dirs {
+			if !hasGlobMeta(elem) {
+				next = append(next, path.Join(dir, elem))
+				continue
+			}
+			names, err := readDirNames(m, dir)
+			if err != nil {
+				continue
+			}
+			for _, n := range names {
+				ok, err := path.Match(elem, n)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					next = append(next, path.Join(dir, n))
+				}
+			}
+		}
+		dirs = next
+	}
+	// the names joined without matching are not known to exist
+	if !hasGlobMeta(elems[len(elems)-1]) {
+		rt := dirs[:0]
+		for _, n := range dirs {
+			if f, err := m.Open(n, os.O_RDONLY|syscall.O_NONBLOCK, 0); err == nil {
+				f.Close()
+				rt = append(rt, n)
+			}
+		}
+		dirs = rt
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+func readDirNames(m Environment, dir string) ([]string, error) {
+	if dir == "" {
+		dir = "."
+	}
+	d, err := m.Open(dir, os.O_RDONLY, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+	return d.Readdirnames(-1)
+}
+
+func hasGlobMeta(s string) bool {
+	return strings.ContainsAny(s, `*?[\`)
+}
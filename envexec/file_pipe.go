@@ -1,15 +1,17 @@
 package envexec
 
 import (
+	"bytes"
 	"io"
 	"os"
 )
 
 type pipeBuffer struct {
-	W      *os.File
-	Buffer *os.File
-	Done   <-chan struct{}
-	Limit  Size
+	W       *os.File
+	Buffer  *os.File
+	Done    <-chan struct{}
+	Drained <-chan struct{}
+	Limit   Size
 }
 
 type pipeCollector struct {
@@ -18,38 +20,112 @@ type pipeCollector struct {
 	limit   Size
 	name    string
 	storage bool
+
+	// stat counts the output if not nil, it is complete after drained
+	stat    *OutputStat
+	drained <-chan struct{}
+	discard bool // content is not collected
+}
+
+// OutputStat counts the bytes and newline terminated lines of an output
+type OutputStat struct {
+	Bytes int64
+	Lines int64
+}
+
+func (s *OutputStat) Write(p []byte) (int, error) {
+	s.Bytes += int64(len(p))
+	s.Lines += int64(bytes.Count(p, []byte{'\n'}))
+	return len(p), nil
+}
+
+func newPipe(writer io.Writer, limit Size, tr *tracker) (<-chan struct{}, *os.File, error) {
+	done, _, w, err := newStatPipe(writer, limit, nil, tr)
+	return done, w, err
 }
 
-func newPipe(writer io.Writer, limit Size) (<-chan struct{}, *os.File, error) {
+// newStatPipe copies up to limit bytes from the pipe into writer and keeps
+// reading the rest so that the other end never blocks on a full pipe. done is
+// closed after limit bytes copied and drained is closed after EOF, stat counts
+// all the bytes read if not nil.
+func newStatPipe(writer io.Writer, limit Size, stat *OutputStat, tr *tracker) (<-chan struct{}, <-chan struct{}, *os.File, error) {
 	r, w, err := os.Pipe()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+	tr.file(r, w)
+	var src io.Reader = r
+	if stat != nil {
+		src = io.TeeReader(r, stat)
 	}
 	done := make(chan struct{})
-	go func() {
-		io.CopyN(writer, r, int64(limit))
+	drained := make(chan struct{})
+	tr.goFunc(func() {
+		io.CopyN(writer, src, int64(limit))
 		close(done)
 		// ensure no blocking / SIGPIPE on the other end
-		io.Copy(io.Discard, r)
+		io.Copy(io.Discard, src)
 		r.Close()
-	}()
-	return done, w, nil
+		close(drained)
+	})
+	return done, drained, w, nil
 }
 
-func newPipeBuffer(limit Size, newFile NewStoreFile) (*pipeBuffer, error) {
+func newPipeBuffer(limit Size, newFile NewStoreFile, stat *OutputStat, tr *tracker) (*pipeBuffer, error) {
 	buffer, err := newFile()
 	if err != nil {
 		return nil, err
 	}
-	done, w, err := newPipe(buffer, limit+1)
+	done, drained, w, err := newStatPipe(buffer, limit+1, stat, tr)
 	if err != nil {
-		buffer.Close()
+		removeStoreFile(buffer)
 		return nil, err
 	}
 	return &pipeBuffer{
-		W:      w,
-		Buffer: buffer,
-		Done:   done,
-		Limit:  limit,
+		W:       w,
+		Buffer:  buffer,
+		Done:    done,
+		Drained: drained,
+		Limit:   limit,
 	}, nil
 }
+
+// collectOutputStat waits for the collectors with stat and returns them
+func collectOutputStat(ptc []pipeCollector) map[string]OutputStat {
+	var rt map[string]OutputStat
+	for _, p := range ptc {
+		if p.stat == nil {
+			continue
+		}
+		<-p.drained
+		if rt == nil {
+			rt = make(map[string]OutputStat)
+		}
+		rt[p.name] = *p.stat
+	}
+	return rt
+}
+
+// discardCollectors waits for the collectors that are not going to be
+// collected (e.g. the run failed before exec) and releases their buffers, the
+// write ends of the pipes must be closed before
+func discardCollectors(ptc []pipeCollector) {
+	for _, p := range ptc {
+		<-p.done
+		if p.buffer == nil {
+			continue
+		}
+		if p.storage {
+			removeStoreFile(p.buffer)
+		} else {
+			p.buffer.Close()
+		}
+	}
+}
+
+// removeStoreFile closes and removes the file created by NewStoreFile, it
+// is never added to the file store
+func removeStoreFile(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
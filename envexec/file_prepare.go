@@ -5,6 +5,7 @@ import (
 	"io"
 	"os"
 	"sync"
+	"sync/atomic"
 
 	"github.com/creack/pty"
 )
@@ -17,7 +18,7 @@ func init() {
 }
 
 // prepare Files for tty input / output
-func prepareCmdFdTTY(c *Cmd, count int, newStoreFile NewStoreFile) (f []*os.File, p []pipeCollector, err error) {
+func prepareCmdFdTTY(c *Cmd, count int, newStoreFile NewStoreFile, tr *tracker) (f []*os.File, p []pipeCollector, err error) {
 	var wg sync.WaitGroup
 	var hasInput, hasOutput bool
 
@@ -26,6 +27,7 @@ func prepareCmdFdTTY(c *Cmd, count int, newStoreFile NewStoreFile) (f []*os.File
 		err = fmt.Errorf("failed to open tty %v", err)
 		return nil, nil, err
 	}
+	tr.file(fPty, fTty)
 
 	files := make([]*os.File, count)
 	pipeToCollect := make([]pipeCollector, 0)
@@ -35,6 +37,7 @@ func prepareCmdFdTTY(c *Cmd, count int, newStoreFile NewStoreFile) (f []*os.File
 			closeFiles(files...)
 			closeFiles(fTty, fPty)
 			wg.Wait()
+			discardCollectors(pipeToCollect)
 		}
 	}()
 
@@ -54,10 +57,10 @@ func prepareCmdFdTTY(c *Cmd, count int, newStoreFile NewStoreFile) (f []*os.File
 
 			// copy input
 			wg.Add(1)
-			go func() {
+			tr.goFunc(func() {
 				defer wg.Done()
 				io.Copy(fPty, t.Reader)
-			}()
+			})
 
 			// provide TTY
 			if tty, ok := t.Reader.(ReaderTTY); ok {
@@ -68,8 +71,9 @@ func prepareCmdFdTTY(c *Cmd, count int, newStoreFile NewStoreFile) (f []*os.File
 			var f *os.File
 			f, err = os.Open(t.Path)
 			if err != nil {
-				return nil, nil, fmt.Errorf("failed to open file %v", t.Path)
+				return nil, nil, fmt.Errorf("failed to open file %v: %v", t.Path, err)
 			}
+			tr.file(f)
 			files[j] = f
 
 		case *FileCollector:
@@ -79,19 +83,46 @@ func prepareCmdFdTTY(c *Cmd, count int, newStoreFile NewStoreFile) (f []*os.File
 			}
 			hasOutput = true
 
+			if t.Discard {
+				stat := new(OutputStat)
+				drained := make(chan struct{})
+				pipeToCollect = append(pipeToCollect, pipeCollector{
+					done:    closedChan,
+					name:    t.Name,
+					stat:    stat,
+					drained: drained,
+					discard: true,
+				})
+
+				wg.Add(1)
+				tr.goFunc(func() {
+					defer close(drained)
+					defer wg.Done()
+					io.Copy(stat, fPty)
+				})
+				break
+			}
+
 			done := make(chan struct{})
-			buf, err := newStoreFile()
+			buf, err := t.newBuffer(newStoreFile)()
 			if err != nil {
 				return nil, nil, fmt.Errorf("filed to create store file %v", err)
 			}
-			pipeToCollect = append(pipeToCollect, pipeCollector{done, buf, t.Limit, t.Name, true})
+			limit := t.collectLimit(c)
+			pipeToCollect = append(pipeToCollect, pipeCollector{
+				done:    done,
+				buffer:  buf,
+				limit:   limit,
+				name:    t.Name,
+				storage: true,
+			})
 
 			wg.Add(1)
-			go func() {
+			tr.goFunc(func() {
 				defer close(done)
 				defer wg.Done()
-				io.CopyN(buf, fPty, int64(t.Limit)+1)
-			}()
+				io.CopyN(buf, fPty, int64(limit)+1)
+			})
 
 		case *FileWriter:
 			files[j] = fTty
@@ -101,10 +132,10 @@ func prepareCmdFdTTY(c *Cmd, count int, newStoreFile NewStoreFile) (f []*os.File
 			hasOutput = true
 
 			wg.Add(1)
-			go func() {
+			tr.goFunc(func() {
 				defer wg.Done()
 				io.Copy(t.Writer, fPty)
-			}()
+			})
 
 		default:
 			return nil, nil, fmt.Errorf("unknown file type %v %t", t, t)
@@ -112,26 +143,23 @@ func prepareCmdFdTTY(c *Cmd, count int, newStoreFile NewStoreFile) (f []*os.File
 	}
 
 	// ensure pty close after use
-	go func() {
+	tr.goFunc(func() {
 		wg.Wait()
 		fPty.Close()
-	}()
+	})
 	return files, pipeToCollect, nil
 }
 
-func prepareCmdFd(c *Cmd, count int, newFileStore NewStoreFile) (f []*os.File, p []pipeCollector, err error) {
+func prepareCmdFd(c *Cmd, count int, newFileStore NewStoreFile, tr *tracker) (f []*os.File, p []pipeCollector, err error) {
 	if c.TTY {
-		return prepareCmdFdTTY(c, count, newFileStore)
+		return prepareCmdFdTTY(c, count, newFileStore, tr)
 	}
 	files := make([]*os.File, count)
 	pipeToCollect := make([]pipeCollector, 0)
 	defer func() {
 		if err != nil {
 			closeFiles(files...)
-			for _, p := range pipeToCollect {
-				<-p.done
-				p.buffer.Close()
-			}
+			discardCollectors(pipeToCollect)
 		}
 	}()
 	// record the same file to avoid multiple file open
@@ -149,7 +177,12 @@ func prepareCmdFd(c *Cmd, count int, newFileStore NewStoreFile) (f []*os.File, p
 				if err != nil {
 					return nil, nil, fmt.Errorf("failed to create pipe %v", err)
 				}
-				go w.ReadFrom(t.Reader)
+				tr.file(r, w)
+				// close the write end so that the process gets EOF
+				tr.goFunc(func() {
+					defer w.Close()
+					w.ReadFrom(t.Reader)
+				})
 
 				files[j] = r
 			} else {
@@ -157,14 +190,16 @@ func prepareCmdFd(c *Cmd, count int, newFileStore NewStoreFile) (f []*os.File, p
 				if err != nil {
 					return nil, nil, fmt.Errorf("failed to open reader %v", err)
 				}
+				tr.file(f)
 				files[j] = f
 			}
 
 		case *FileInput:
 			f, err := os.Open(t.Path)
 			if err != nil {
-				return nil, nil, fmt.Errorf("failed to open file %v", t.Path)
+				return nil, nil, fmt.Errorf("failed to open file %v: %v", t.Path, err)
 			}
+			tr.file(f)
 			files[j] = f
 
 		case *FileCollector:
@@ -173,20 +208,50 @@ func prepareCmdFd(c *Cmd, count int, newFileStore NewStoreFile) (f []*os.File, p
 				break
 			}
 
-			if t.Pipe {
-				b, err := newPipeBuffer(t.Limit, newFileStore)
+			limit := t.collectLimit(c)
+			switch {
+			case t.Discard:
+				stat := new(OutputStat)
+				_, drained, w, err := newStatPipe(io.Discard, 0, stat, tr)
+				if err != nil {
+					return nil, nil, fmt.Errorf("failed to create pipe %v", err)
+				}
+				cf[t.Name] = w
+
+				files[j] = w
+				pipeToCollect = append(pipeToCollect, pipeCollector{
+					done:    closedChan,
+					name:    t.Name,
+					stat:    stat,
+					drained: drained,
+					discard: true,
+				})
+
+			case t.Pipe:
+				stat := new(OutputStat)
+				b, err := newPipeBuffer(limit, t.newBuffer(newFileStore), stat, tr)
 				if err != nil {
 					return nil, nil, fmt.Errorf("failed to create pipe %v", err)
 				}
 				cf[t.Name] = b.W
 
 				files[j] = b.W
-				pipeToCollect = append(pipeToCollect, pipeCollector{b.Done, b.Buffer, t.Limit, t.Name, true})
-			} else {
+				pipeToCollect = append(pipeToCollect, pipeCollector{
+					done:    b.Done,
+					buffer:  b.Buffer,
+					limit:   limit,
+					name:    t.Name,
+					storage: true,
+					stat:    stat,
+					drained: b.Drained,
+				})
+
+			default:
 				f, err := c.Environment.Open(t.Name, os.O_CREATE|os.O_WRONLY, 0777)
 				if err != nil {
 					return nil, nil, fmt.Errorf("filed to create container file %v", err)
 				}
+				tr.file(f)
 				cf[t.Name] = f
 
 				buffer, err := c.Environment.Open(t.Name, os.O_RDWR, 0777)
@@ -195,11 +260,16 @@ func prepareCmdFd(c *Cmd, count int, newFileStore NewStoreFile) (f []*os.File, p
 				}
 
 				files[j] = f
-				pipeToCollect = append(pipeToCollect, pipeCollector{closedChan, buffer, t.Limit, t.Name, false})
+				pipeToCollect = append(pipeToCollect, pipeCollector{
+					done:   closedChan,
+					buffer: buffer,
+					limit:  limit,
+					name:   t.Name,
+				})
 			}
 
 		case *FileWriter:
-			_, w, err := newPipe(t.Writer, t.Limit)
+			_, w, err := newPipe(t.Writer, t.Limit, tr)
 			if err != nil {
 				return nil, nil, fmt.Errorf("failed to create pipe %v", err)
 			}
@@ -212,17 +282,18 @@ func prepareCmdFd(c *Cmd, count int, newFileStore NewStoreFile) (f []*os.File, p
 	return files, pipeToCollect, nil
 }
 
-// prepareFd returns fds, pipeToCollect fileToClose, error
-func prepareFds(r *Group, newStoreFile NewStoreFile) (f [][]*os.File, p [][]pipeCollector, err error) {
+// prepareFd returns fds, pipeToCollect, fifo bridges, pipe proxy counters, error
+func prepareFds(r *Group, newStoreFile NewStoreFile, tr *tracker) (f [][]*os.File, p [][]pipeCollector, fb [][]*fifoBridge, pc []*pipeCounter, err error) {
 	// prepare fd count
 	fdCount, err := countFd(r)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, nil, err
 	}
 
 	// prepare files
 	files := make([][]*os.File, len(fdCount))
 	pipeToCollect := make([][]pipeCollector, len(fdCount))
+	fifos := make([][]*fifoBridge, len(fdCount))
 
 	// newly opened files need to be closed
 	defer func() {
@@ -230,31 +301,51 @@ func prepareFds(r *Group, newStoreFile NewStoreFile) (f [][]*os.File, p [][]pipe
 			for _, fs := range files {
 				closeFiles(fs...)
 			}
+			for _, p := range pipeToCollect {
+				discardCollectors(p)
+			}
+			for _, bs := range fifos {
+				closeFifos(bs)
+			}
 		}
 	}()
 
 	// prepare cmd fd
 	for i, c := range r.Cmd {
-		files[i], pipeToCollect[i], err = prepareCmdFd(c, fdCount[i], newStoreFile)
+		files[i], pipeToCollect[i], err = prepareCmdFd(c, fdCount[i], newStoreFile, tr)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, nil, err
 		}
 	}
 
 	// prepare pipes
+	var counters []*pipeCounter
 	for _, p := range r.Pipes {
-		out, in, pc, err := pipe(p, newStoreFile)
+		out, in, pc, cnt, err := pipe(p, newStoreFile, tr)
 		if err != nil {
-			return nil, nil, err
+			return nil, nil, nil, nil, err
+		}
+		if p.Out.Fifo != "" {
+			b := newFifoBridge(r.Cmd[p.Out.Index].Environment, p.Out.Fifo, out, true, tr)
+			fifos[p.Out.Index] = append(fifos[p.Out.Index], b)
+		} else {
+			files[p.Out.Index][p.Out.Fd] = out
+		}
+		if p.In.Fifo != "" {
+			b := newFifoBridge(r.Cmd[p.In.Index].Environment, p.In.Fifo, in, false, tr)
+			fifos[p.In.Index] = append(fifos[p.In.Index], b)
+		} else {
+			files[p.In.Index][p.In.Fd] = in
 		}
-		files[p.Out.Index][p.Out.Fd] = out
-		files[p.In.Index][p.In.Fd] = in
 
 		if pc != nil {
 			pipeToCollect[p.In.Index] = append(pipeToCollect[p.In.Index], *pc)
 		}
+		if cnt != nil {
+			counters = append(counters, cnt)
+		}
 	}
-	return files, pipeToCollect, nil
+	return files, pipeToCollect, fifos, counters, nil
 }
 
 func countFd(r *Group) ([]int, error) {
@@ -262,11 +353,31 @@ func countFd(r *Group) ([]int, error) {
 	for i, c := range r.Cmd {
 		fdCount[i] = len(c.Files)
 	}
+	type fifoIndex struct {
+		index int
+		name  string
+	}
+	fifos := make(map[fifoIndex]bool)
 	for _, pi := range r.Pipes {
+		if pi.TransferLimit > 0 && !pi.Proxy {
+			return nil, fmt.Errorf("pipe transfer limit requires proxy %v %v", pi.In.Index, pi.In.Fd)
+		}
 		for _, p := range []PipeIndex{pi.In, pi.Out} {
 			if p.Index < 0 || p.Index >= len(r.Cmd) {
 				return nil, fmt.Errorf("pipe index out of range %v", p.Index)
 			}
+			// fifo does not take a fd and could be connected only once
+			if p.Fifo != "" {
+				if _, ok := r.Cmd[p.Index].CopyIn[p.Fifo].(*FileFifo); !ok {
+					return nil, fmt.Errorf("pipe fifo is not a fifo in copy in %v %v", p.Index, p.Fifo)
+				}
+				k := fifoIndex{index: p.Index, name: p.Fifo}
+				if fifos[k] {
+					return nil, fmt.Errorf("pipe fifo have been occupied %v %v", p.Index, p.Fifo)
+				}
+				fifos[k] = true
+				continue
+			}
 			if p.Fd < len(r.Cmd[p.Index].Files) && r.Cmd[p.Index].Files[p.Fd] != nil {
 				return nil, fmt.Errorf("pipe fd have been occupied %v %v", p.Index, p.Fd)
 			}
@@ -278,27 +389,57 @@ func countFd(r *Group) ([]int, error) {
 	return fdCount, nil
 }
 
-func pipe(p Pipe, newStoreFile NewStoreFile) (out *os.File, in *os.File, pc *pipeCollector, err error) {
+func pipe(p Pipe, newStoreFile NewStoreFile, tr *tracker) (out *os.File, in *os.File, pc *pipeCollector, cnt *pipeCounter, err error) {
 	if p.Proxy {
-		buffer, err := newStoreFile()
-		if err != nil {
-			return nil, nil, nil, err
-		}
 		out1, in1, out2, in2, err := pipe2()
 		if err != nil {
-			buffer.Close()
-			return nil, nil, nil, err
+			return nil, nil, nil, nil, err
+		}
+		tr.file(out1, in1, out2, in2)
+		if err := setPipeSizes(p.BufferSize, in1, in2); err != nil {
+			closeFiles(out1, in1, out2, in2)
+			return nil, nil, nil, nil, err
+		}
+		// buffer is only used to collect the named proxy
+		var buffer *os.File
+		if p.Name != "" {
+			if buffer, err = newStoreFile(); err != nil {
+				closeFiles(out1, in1, out2, in2)
+				return nil, nil, nil, nil, err
+			}
 		}
 
-		pc := pipeProxy(p, out1, in2, buffer)
-		return out2, in1, pc, nil
+		cnt := &pipeCounter{index: p.In.Index, fd: p.In.Fd, outIndex: p.Out.Index, done: make(chan struct{})}
+		if p.TransferLimit > 0 {
+			cnt.limit = int64(p.TransferLimit)
+			cnt.exceeded = make(chan struct{})
+		}
+		pc := pipeProxy(p, out1, in2, buffer, cnt, tr)
+		return out2, in1, pc, cnt, nil
 	}
 
 	out, in, err = os.Pipe()
 	if err != nil {
-		return nil, nil, nil, err
+		return nil, nil, nil, nil, err
 	}
-	return out, in, nil, nil
+	tr.file(out, in)
+	if err := setPipeSizes(p.BufferSize, in); err != nil {
+		closeFiles(out, in)
+		return nil, nil, nil, nil, err
+	}
+	return out, in, nil, nil, nil
+}
+
+func setPipeSizes(size Size, fs ...*os.File) error {
+	if size == 0 {
+		return nil
+	}
+	for _, f := range fs {
+		if err := setPipeSize(f, size); err != nil {
+			return fmt.Errorf("failed to set pipe size to %d %v", size, err)
+		}
+	}
+	return nil
 }
 
 func pipe2() (out1 *os.File, in1 *os.File, out2 *os.File, in2 *os.File, err error) {
@@ -315,17 +456,23 @@ func pipe2() (out1 *os.File, in1 *os.File, out2 *os.File, in2 *os.File, err erro
 	return
 }
 
-func pipeProxy(p Pipe, out1 *os.File, in2 *os.File, buffer *os.File) *pipeCollector {
+func pipeProxy(p Pipe, out1 *os.File, in2 *os.File, buffer *os.File, cnt *pipeCounter, tr *tracker) *pipeCollector {
+	w := &countWriter{Writer: in2, cnt: cnt}
+	var src io.Reader = out1
+	if cnt.limit > 0 {
+		src = &limitReader{Reader: out1, cnt: cnt}
+	}
 	copyAndClose := func() {
-		io.Copy(in2, out1)
+		io.Copy(w, src)
 		in2.Close()
-		io.Copy(io.Discard, out1)
+		io.Copy(io.Discard, src)
 		out1.Close()
+		close(cnt.done)
 	}
 
 	// if no name, simply copy data
 	if p.Name == "" {
-		go copyAndClose()
+		tr.goFunc(copyAndClose)
 		return nil
 	}
 
@@ -333,15 +480,15 @@ func pipeProxy(p Pipe, out1 *os.File, in2 *os.File, buffer *os.File) *pipeCollec
 	limit := p.Limit
 
 	// out1 -> in2
-	go func() {
+	tr.goFunc(func() {
 		// copy with limit
-		r := io.TeeReader(io.LimitReader(out1, int64(limit)), buffer)
-		io.Copy(in2, r)
+		r := io.TeeReader(io.LimitReader(src, int64(limit)), buffer)
+		io.Copy(w, r)
 		close(done)
 
 		// copy without limit
 		copyAndClose()
-	}()
+	})
 
 	return &pipeCollector{
 		done:    done,
@@ -351,3 +498,72 @@ func pipeProxy(p Pipe, out1 *os.File, in2 *os.File, buffer *os.File) *pipeCollec
 		storage: true,
 	}
 }
+
+// pipeCounter counts bytes forwarded by the pipe proxy from the fd of the Cmd
+// to the Cmd of outIndex
+type pipeCounter struct {
+	index, fd int
+	outIndex  int
+	n         int64
+	done      chan struct{} // closed after the proxy finished
+
+	// exceeded is closed once more than limit bytes are read from the fd if
+	// limit is set, including the ones discarded after the other end closed
+	limit    int64
+	read     int64 // only accessed by the proxy
+	exceeded chan struct{}
+}
+
+func (c *pipeCounter) count() int64 {
+	return atomic.LoadInt64(&c.n)
+}
+
+func (c *pipeCounter) isExceeded() bool {
+	if c.exceeded == nil {
+		return false
+	}
+	select {
+	case <-c.exceeded:
+		return true
+	default:
+		return false
+	}
+}
+
+// limitReader reads up to the limit of the counter, the reads after the limit
+// exceeded fail with errPipeLimitExceeded
+type limitReader struct {
+	io.Reader
+	cnt *pipeCounter
+}
+
+func (r *limitReader) Read(p []byte) (int, error) {
+	c := r.cnt
+	if c.isExceeded() {
+		return 0, errPipeLimitExceeded
+	}
+	// reads one more byte to tell whether the limit is exceeded
+	left := c.limit - c.read
+	if int64(len(p)) > left+1 {
+		p = p[:left+1]
+	}
+	n, err := r.Reader.Read(p)
+	if int64(n) > left {
+		c.read = c.limit
+		close(c.exceeded)
+		return int(left), errPipeLimitExceeded
+	}
+	c.read += int64(n)
+	return n, err
+}
+
+type countWriter struct {
+	io.Writer
+	cnt *pipeCounter
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	atomic.AddInt64(&w.cnt.n, int64(n))
+	return n, err
+}
@@ -0,0 +1,112 @@
+package envexec
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+)
+
+// ErrSnapshotTooLarge is returned by SaveSnapshot when the regular files in
+// the work directory exceed the max size
+var ErrSnapshotTooLarge = errors.New("snapshot exceeds the max size")
+
+// Snapshot is the copy of the work directory saved by SaveSnapshot after a
+// run, it is restored into the work directory of the later runs through
+// Cmd.Snapshot. The content of the regular files is held by the host (in
+// memory backed files if available) until it is released.
+type Snapshot struct {
+	Name string // reported as the name of the file error of restore
+	Size Size   // total bytes of the regular files
+
+	entries []snapshotEntry
+}
+
+type snapshotEntry struct {
+	path string
+	mode os.FileMode // permission bits with os.ModeDir / os.ModeSymlink
+	link string      // target of the symlink
+	file *os.File    // content of the regular file
+	size int64
+}
+
+// snapshotWalkFunc is called for each entry in the work directory with the
+// path relative to it, f is the regular file opened read-only
+type snapshotWalkFunc func(p string, mode os.FileMode, link string, f *os.File, size int64) error
+
+// SaveSnapshot copies the directories, regular files and symlinks in the work
+// directory of m, others (e.g. fifos) are skipped. It fails with
+// ErrSnapshotTooLarge if the regular files exceed max bytes in total (0 for
+// unlimited).
+func SaveSnapshot(m Environment, name string, max Size) (s *Snapshot, err error) {
+	s = &Snapshot{Name: name}
+	defer func() {
+		if err != nil {
+			s.Release()
+		}
+	}()
+	err = walkWorkDir(m, func(p string, mode os.FileMode, link string, f *os.File, size int64) error {
+		e := snapshotEntry{path: p, mode: mode, link: link, size: size}
+		if f != nil {
+			if max > 0 && s.Size+Size(size) > max {
+				return fmt.Errorf("%w: %s exceeds %v", ErrSnapshotTooLarge, p, max)
+			}
+			c, err := snapshotFile(f, size)
+			if err != nil {
+				return fmt.Errorf("failed to copy %s %v", p, err)
+			}
+			e.file = c
+			s.Size += Size(size)
+		}
+		s.entries = append(s.entries, e)
+		return nil
+	})
+	return s, err
+}
+
+// Release closes the files held by the snapshot
+func (s *Snapshot) Release() {
+	for _, e := range s.entries {
+		if e.file != nil {
+			releaseSnapshotFile(e.file)
+		}
+	}
+	s.entries = nil
+}
+
+// restore creates the entries in the work directory of m, the parents are
+// visited before their children
+func (s *Snapshot) restore(m Environment) error {
+	for _, e := range s.entries {
+		switch {
+		case e.mode.IsDir():
+			if err := m.MkdirAll(e.path, 0777); err != nil {
+				return err
+			}
+
+		case e.mode&os.ModeSymlink != 0:
+			if err := m.Symlink(e.link, e.path); err != nil {
+				return err
+			}
+
+		default:
+			if err := m.MkdirAll(path.Dir(e.path), 0777); err != nil {
+				return err
+			}
+			f, err := m.Open(e.path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0777)
+			if err != nil {
+				return err
+			}
+			err = copySnapshotFile(f, e.file, e.size)
+			if err == nil {
+				// the mode of creation is masked by umask
+				err = f.Chmod(e.mode.Perm())
+			}
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("%s: %v", e.path, err)
+			}
+		}
+	}
+	return nil
+}
@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path"
+	"sort"
+	"strconv"
 	"sync/atomic"
 	"syscall"
 
 	"github.com/criyle/go-sandbox/pkg/memfd"
+	"golang.org/x/sys/unix"
 )
 
 const memfdName = "input"
@@ -56,8 +60,8 @@ func copyDir(src *os.File, dst string) error {
 }
 
 func copyFileDir(srcDirFd, dstDirFd int, name string) error {
-	// open the source file
-	fd, err := syscall.Openat(srcDirFd, name, syscall.O_CLOEXEC|syscall.O_RDONLY, 0777)
+	// open the source file, fifos does not block and are skipped
+	fd, err := syscall.Openat(srcDirFd, name, syscall.O_CLOEXEC|syscall.O_RDONLY|syscall.O_NONBLOCK, 0777)
 	if err != nil {
 		return err
 	}
@@ -67,6 +71,9 @@ func copyFileDir(srcDirFd, dstDirFd int, name string) error {
 	if err := syscall.Fstat(fd, &st); err != nil {
 		return err
 	}
+	if st.Mode&syscall.S_IFMT == syscall.S_IFIFO {
+		return nil
+	}
 	if st.Mode&syscall.S_IFREG == 0 {
 		return fmt.Errorf("%s is not a regular file", name)
 	}
@@ -84,3 +91,146 @@ func copyFileDir(srcDirFd, dstDirFd int, name string) error {
 	}
 	return nil
 }
+
+// openFifoPath opens the fifo in the container as O_PATH so that it could be
+// reopened even if the program removed or replaced the name
+func openFifoPath(m Environment, name string) (*os.File, error) {
+	f, err := m.Open(name, unix.O_PATH|unix.O_NOFOLLOW, 0)
+	if err != nil {
+		return nil, err
+	}
+	var st unix.Stat_t
+	if err := unix.Fstat(int(f.Fd()), &st); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if st.Mode&unix.S_IFMT != unix.S_IFIFO {
+		f.Close()
+		return nil, fmt.Errorf("%s is not a fifo", name)
+	}
+	return f, nil
+}
+
+// reopenFifo opens the fifo referred by the O_PATH fd through procfs
+func reopenFifo(p *os.File, flag int) (*os.File, error) {
+	return os.OpenFile("/proc/self/fd/"+strconv.Itoa(int(p.Fd())), flag, 0)
+}
+
+// setPipeSize sets the capacity of the pipe by F_SETPIPE_SZ, size over
+// /proc/sys/fs/pipe-max-size requires CAP_SYS_RESOURCE
+func setPipeSize(f *os.File, size Size) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var serr error
+	if err := rc.Control(func(fd uintptr) {
+		_, serr = unix.FcntlInt(fd, unix.F_SETPIPE_SZ, int(size))
+	}); err != nil {
+		return err
+	}
+	return serr
+}
+
+// walkWorkDir visits the entries in the work directory of m through the
+// directory fds, so that the symlinks are never followed
+func walkWorkDir(m Environment, fn snapshotWalkFunc) error {
+	fd, err := unix.Openat(int(m.WorkDir().Fd()), ".", unix.O_RDONLY|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err != nil {
+		return err
+	}
+	return walkDirFd(fd, "", fn)
+}
+
+// walkDirFd walks the directory of fd, which is closed after, with the names
+// prefixed by prefix
+func walkDirFd(fd int, prefix string, fn snapshotWalkFunc) error {
+	d := os.NewFile(uintptr(fd), prefix)
+	defer d.Close()
+
+	names, err := d.Readdirnames(-1)
+	if err != nil {
+		return err
+	}
+	sort.Strings(names)
+	for _, n := range names {
+		p := path.Join(prefix, n)
+		var st unix.Stat_t
+		if err := unix.Fstatat(fd, n, &st, unix.AT_SYMLINK_NOFOLLOW); err != nil {
+			return err
+		}
+		perm := os.FileMode(st.Mode & 0777)
+		switch st.Mode & unix.S_IFMT {
+		case unix.S_IFDIR:
+			if err := fn(p, os.ModeDir|perm, "", nil, 0); err != nil {
+				return err
+			}
+			sub, err := unix.Openat(fd, n, unix.O_RDONLY|unix.O_DIRECTORY|unix.O_NOFOLLOW|unix.O_CLOEXEC, 0)
+			if err != nil {
+				return err
+			}
+			if err := walkDirFd(sub, p, fn); err != nil {
+				return err
+			}
+
+		case unix.S_IFLNK:
+			buf := make([]byte, unix.PathMax)
+			l, err := unix.Readlinkat(fd, n, buf)
+			if err != nil {
+				return err
+			}
+			if err := fn(p, os.ModeSymlink|perm, string(buf[:l]), nil, 0); err != nil {
+				return err
+			}
+
+		case unix.S_IFREG:
+			rfd, err := unix.Openat(fd, n, unix.O_RDONLY|unix.O_NOFOLLOW|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+			if err != nil {
+				return err
+			}
+			f := os.NewFile(uintptr(rfd), p)
+			err = fn(p, perm, "", f, st.Size)
+			f.Close()
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// snapshotFile copies the content into a memfd
+func snapshotFile(f *os.File, size int64) (*os.File, error) {
+	fd, err := unix.MemfdCreate("snapshot", unix.MFD_CLOEXEC)
+	if err != nil {
+		return nil, err
+	}
+	c := os.NewFile(uintptr(fd), "snapshot")
+	if err := copySnapshotFile(c, f, size); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// copySnapshotFile copies size bytes from the start of src by sendfile, which
+// is done by the kernel and keeps the offset of src
+func copySnapshotFile(dst, src *os.File, size int64) error {
+	var off int64
+	for off < size {
+		n, err := unix.Sendfile(int(dst.Fd()), int(src.Fd()), &off, int(size-off))
+		switch {
+		case err == unix.EINTR || err == unix.EAGAIN:
+		case err != nil:
+			return err
+		case n == 0:
+			// truncated after stat
+			return nil
+		}
+	}
+	return nil
+}
+
+func releaseSnapshotFile(f *os.File) {
+	f.Close()
+}
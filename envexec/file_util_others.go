@@ -5,8 +5,10 @@ package envexec
 import (
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
 )
 
 func readerToFile(reader io.Reader) (*os.File, error) {
@@ -49,6 +51,10 @@ func copyDir(src *os.File, dst string) error {
 }
 
 func copyDirFile(src, dst, name string) error {
+	// fifos would block on open
+	if fi, err := os.Lstat(path.Join(src, name)); err == nil && fi.Mode()&os.ModeNamedPipe != 0 {
+		return nil
+	}
 	s, err := os.Open(path.Join(src, name))
 	if err != nil {
 		return err
@@ -77,3 +83,81 @@ func copyDirFile(src, dst, name string) error {
 	}
 	return nil
 }
+
+func openFifoPath(m Environment, name string) (*os.File, error) {
+	return nil, errFifoNotSupported
+}
+
+func reopenFifo(p *os.File, flag int) (*os.File, error) {
+	return nil, errFifoNotSupported
+}
+
+// setPipeSize is not supported, the pipe keeps its default capacity
+func setPipeSize(f *os.File, size Size) error {
+	return nil
+}
+
+// walkWorkDir visits the entries in the work directory of m by its path
+func walkWorkDir(m Environment, fn snapshotWalkFunc) error {
+	root := m.WorkDir().Name()
+	return filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == root {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return fn(rel, os.ModeDir|fi.Mode().Perm(), "", nil, 0)
+
+		case d.Type()&os.ModeSymlink != 0:
+			link, err := os.Readlink(p)
+			if err != nil {
+				return err
+			}
+			return fn(rel, os.ModeSymlink|fi.Mode().Perm(), link, nil, 0)
+
+		case d.Type().IsRegular():
+			f, err := os.Open(p)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			return fn(rel, fi.Mode().Perm(), "", f, fi.Size())
+		}
+		return nil
+	})
+}
+
+// snapshotFile copies the content into a temporary file
+func snapshotFile(f *os.File, size int64) (*os.File, error) {
+	c, err := os.CreateTemp("", "es-snapshot")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(c, io.LimitReader(f, size)); err != nil {
+		releaseSnapshotFile(c)
+		return nil, err
+	}
+	return c, nil
+}
+
+func copySnapshotFile(dst, src *os.File, size int64) error {
+	_, err := io.Copy(dst, io.NewSectionReader(src, 0, size))
+	return err
+}
+
+func releaseSnapshotFile(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
@@ -2,8 +2,8 @@ package envexec
 
 import (
 	"context"
-
-	"golang.org/x/sync/errgroup"
+	"fmt"
+	"sync"
 )
 
 // Group defines the running instruction to run multiple
@@ -18,12 +18,25 @@ type Group struct {
 
 	// NewStoreFile defines interface to create stored file
 	NewStoreFile NewStoreFile
+
+	// KillOnLimit kills all the other Cmd once any of them exceeded its
+	// limits (e.g. program interacting with another could not continue)
+	KillOnLimit bool
+
+	// OnLeak is called in background if the fds or goroutines created for the
+	// run are not released after it finished
+	OnLeak func(Leak)
 }
 
 // PipeIndex defines the index of cmd and the fd of the that cmd
 type PipeIndex struct {
 	Index int
 	Fd    int
+
+	// Fifo connects the named pipe (FileFifo) of copy in with the name
+	// instead of the fd, the program opens it for reading as Out or for
+	// writing as In
+	Fifo string
 }
 
 // Pipe defines the pipe between parallel Cmd
@@ -40,32 +53,137 @@ type Pipe struct {
 
 	// Proxy creates 2 pipe and connects them by copying data
 	Proxy bool
+
+	// TransferLimit kills both ends with StatusPipeLimitExceeded once more
+	// bytes than it are written into the proxy, 0 for unlimited
+	TransferLimit Size
+
+	// BufferSize sets the capacity of the pipe (both pipes if proxy is
+	// enabled) if not 0 (Linux only)
+	BufferSize Size
+}
+
+// Validate checks the pipe mapping between Cmd without running them
+func (r *Group) Validate() error {
+	_, err := countFd(r)
+	return err
 }
 
 // Run starts the cmd and returns exec results
 func (r *Group) Run(ctx context.Context) ([]Result, error) {
+	tr := newTracker()
+	defer tr.check(r.OnLeak)
+
 	// prepare files
-	fds, pipeToCollect, err := prepareFds(r, r.NewStoreFile)
+	fds, pipeToCollect, fifos, counters, err := prepareFds(r, r.NewStoreFile, tr)
 	if err != nil {
 		return nil, err
 	}
 
+	// cmd killed by the limit of the other cmd is reported as cancelled
+	groupCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	// both ends of the proxy exceeded its transfer limit are killed, the ones
+	// still running then are reported as exceeded whatever their status
+	cmdCtx := make([]context.Context, len(r.Cmd))
+	cmdCancel := make([]context.CancelCauseFunc, len(r.Cmd))
+	for i := range r.Cmd {
+		cmdCtx[i], cmdCancel[i] = context.WithCancelCause(groupCtx)
+		defer cmdCancel[i](nil)
+	}
+	var killedMu sync.Mutex
+	finished := make([]bool, len(r.Cmd))
+	killed := make([]bool, len(r.Cmd))
+	killPipe := func(i int) {
+		killedMu.Lock()
+		defer killedMu.Unlock()
+		if !finished[i] {
+			killed[i] = true
+			cmdCancel[i](errPipeLimitExceeded)
+		}
+	}
+	for _, c := range counters {
+		if c.exceeded == nil {
+			continue
+		}
+		c := c
+		tr.goFunc(func() {
+			select {
+			case <-c.exceeded:
+				killPipe(c.index)
+				killPipe(c.outIndex)
+				if r.KillOnLimit {
+					cancel(errKilledOnLimit)
+				}
+			case <-c.done:
+			}
+		})
+	}
+
 	// wait all cmd to finish
-	var g errgroup.Group
+	var g panicGroup
 	result := make([]Result, len(r.Cmd))
 	for i, c := range r.Cmd {
 		i, c := i, c
 		g.Go(func() error {
-			r, err := runSingle(ctx, c, fds[i], pipeToCollect[i], r.NewStoreFile)
-			result[i] = r
+			rt, err := runSingle(cmdCtx[i], c, fds[i], pipeToCollect[i], fifos[i], r.NewStoreFile)
+			killedMu.Lock()
+			finished[i] = true
+			killedMu.Unlock()
+			result[i] = rt
 			if err != nil {
 				result[i].Status = StatusInternalError
 				result[i].Error = err.Error()
 				return err
 			}
+			if r.KillOnLimit && isLimitExceeded(rt.Status) {
+				cancel(errKilledOnLimit)
+			}
 			return nil
 		})
 	}
 	err = g.Wait()
+
+	// proxies finish once both ends are closed
+	for _, c := range counters {
+		<-c.done
+		if result[c.index].PipeBytes == nil {
+			result[c.index].PipeBytes = make(map[int]int64)
+		}
+		result[c.index].PipeBytes[c.fd] = c.count()
+		if c.isExceeded() {
+			msg := fmt.Sprintf("pipe from cmd %d fd %d exceeded the transfer limit %v", c.index, c.fd, Size(c.limit))
+			// the ones failed by their own limits before are kept
+			for _, i := range []int{c.index, c.outIndex} {
+				if result[i].Status == StatusInternalError || (!killed[i] && isLimitExceeded(result[i].Status)) {
+					continue
+				}
+				result[i].Status = StatusPipeLimitExceeded
+				result[i].TerminatedBy = TerminatedByLimitExceeded
+				if result[i].Error == "" {
+					result[i].Error = msg
+				}
+			}
+		}
+	}
+
+	// the wall clock limit ends the wait on the fifo never opened
+	if msg := fifoDeadlock(fifos); msg != "" {
+		for i := range result {
+			if result[i].Status == StatusTimeLimitExceeded && result[i].Error == "" {
+				result[i].Error = "deadlock: " + msg
+			}
+		}
+	}
 	return result, err
 }
+
+func isLimitExceeded(s Status) bool {
+	switch s {
+	case StatusTimeLimitExceeded, StatusMemoryLimitExceeded, StatusOutputLimitExceeded, StatusProcessLimitExceeded,
+		StatusPipeLimitExceeded, StatusDiskLimitExceeded:
+		return true
+	}
+	return false
+}
@@ -25,6 +25,64 @@ type ExecveParam struct {
 
 	// Process Limitations
 	Limit Limit
+
+	// DetailedMemory specifies whether to collect memory statistics breakdown
+	DetailedMemory bool
+
+	// Trace specifies whether to count the syscalls, TraceEnvironment must
+	// be implemented
+	Trace bool
+
+	// ProcInfo specifies the synthetic /proc/meminfo and /proc/cpuinfo, nil
+	// for the real ones
+	ProcInfo *ProcInfo
+
+	// Random specifies the deterministic /dev/urandom and /dev/random, nil
+	// for the real ones
+	Random *Random
+
+	// Arch specifies the architecture of the program, ArchAuto to detect it
+	Arch string
+
+	// Overhead specifies whether to measure the cpu time spent by the
+	// environment itself during the run, which is not charged to the process
+	Overhead bool
+}
+
+// Arch names of the programs
+const (
+	ArchAuto    = "auto" // detect from the ELF header of the executable
+	ArchX86     = "x86"
+	ArchX64     = "x86_64"
+	ArchX32     = "x32"
+	ArchARM     = "arm"
+	ArchARM64   = "arm64"
+	ArchRISCV64 = "riscv64"
+)
+
+// KnownArch reports whether the architecture name is defined
+func KnownArch(arch string) bool {
+	switch arch {
+	case ArchX86, ArchX64, ArchX32, ArchARM, ArchARM64, ArchRISCV64:
+		return true
+	}
+	return false
+}
+
+// Arch32 reports whether the architecture has 32-bit address space
+func Arch32(arch string) bool {
+	return arch == ArchX86 || arch == ArchX32 || arch == ArchARM
+}
+
+// ProcInfo defines the synthetic /proc/meminfo and /proc/cpuinfo
+type ProcInfo struct {
+	Memory Size   // total memory in /proc/meminfo
+	CPUs   uint64 // number of processors in /proc/cpuinfo
+}
+
+// Random defines the deterministic stream of /dev/urandom and /dev/random
+type Random struct {
+	Seed uint64
 }
 
 // Limit defines the process running resource limits
@@ -38,12 +96,130 @@ type Limit struct {
 	OpenFile     uint64        // Number of open files
 	CPUSet       string        // CPU set limit
 	StrictMemory bool          // Use stricter memory limit (e.g. rlimit)
+	Core         Size          // Core file size limit, 0 disables core dump
 }
 
 // Usage defines the peak process resource usage
 type Usage struct {
-	Time   time.Duration
-	Memory Size
+	Time    time.Duration
+	Memory  Size
+	IOWrite Size // bytes written to block devices, 0 if not available
+}
+
+// MemoryStat defines the memory statistics breakdown of the run, the sizes
+// are the largest sampled during the run
+type MemoryStat struct {
+	Cache          Size   // page cache
+	RSS            Size   // anonymous memory
+	MappedFile     Size   // memory mapped files
+	PageFault      uint64 // number of page faults
+	MajorPageFault uint64 // number of major page faults
+}
+
+// MemoryStatProcess will be asserts when DetailedMemory is set and the
+// MemoryStat will be called after the process group exited
+type MemoryStatProcess interface {
+	MemoryStat() (MemoryStat, bool)
+}
+
+// ProcStat defines the number of processes / threads in the process group
+type ProcStat struct {
+	Peak     uint64 // peak number of processes / threads
+	LimitHit bool   // whether creating process / thread failed due to the proc limit
+	Stray    uint64 // processes left running after the process exited and were killed
+}
+
+// ProcStatProcess will be asserts and the ProcStat will be called after the
+// process group exited
+type ProcStatProcess interface {
+	ProcStat() (ProcStat, bool)
+}
+
+// IOStat defines the bytes read and written to block devices by the process
+// group. Writes to tmpfs (e.g. the work directory) are charged to memory
+// instead.
+type IOStat struct {
+	Read  Size
+	Write Size
+}
+
+// IOStatProcess will be asserts and the IOStat will be called after the
+// process group exited
+type IOStatProcess interface {
+	IOStat() (IOStat, bool)
+}
+
+// CPUThrottling defines the cfs bandwidth throttling of the process group,
+// the counters are zero if no cpu rate limit is applied
+type CPUThrottling struct {
+	Periods       uint64        // enforcement periods elapsed
+	Throttled     uint64        // periods the process group was throttled
+	ThrottledTime time.Duration // total time the process group was throttled
+}
+
+// CPUThrottlingProcess will be asserted after the process group exited, it
+// returns the throttling of the run if available
+type CPUThrottlingProcess interface {
+	CPUThrottling() (CPUThrottling, bool)
+}
+
+// ArchProcess will be asserted after the process group exited if Arch is
+// set, it returns the architecture of the program (empty if unknown) and
+// whether the host failed to execute it for the architecture
+type ArchProcess interface {
+	Arch() (arch string, unsupported bool)
+}
+
+// EmulatedProcess will be asserted after the process group exited if Arch is
+// set, it reports whether the program of the foreign architecture ran under
+// an emulator
+type EmulatedProcess interface {
+	Emulated() bool
+}
+
+// RandomProcess will be asserted after the process group exited if Random is
+// set, it reports whether the program opened /dev/urandom or /dev/random
+type RandomProcess interface {
+	RandomOpened() bool
+}
+
+// ExecErrorProcess will be asserted after the process group exited, it
+// returns why the program could not be executed, nil if it was executed
+type ExecErrorProcess interface {
+	ExecError() *FileError
+}
+
+// SyscallTraceProcess will be asserted after the process group exited if
+// Trace is set, it returns the number of calls of each syscall
+type SyscallTraceProcess interface {
+	SyscallCount() (map[string]uint64, bool)
+}
+
+// RetryProcess will be asserted after the process group exited (or on the
+// error returned by Execve) to report the number of retries on transient
+// failures (e.g. cgroup attach returns EBUSY) before the process started
+type RetryProcess interface {
+	AttachRetries() int
+}
+
+// CgroupProcess will be asserted after the process group exited, it returns
+// the id of the cgroup the process group ran in, false if not run in cgroup
+type CgroupProcess interface {
+	CgroupID() (uint64, bool)
+}
+
+// OverheadProcess will be asserted after the process group exited if
+// Overhead is set, it returns the cpu time spent by the environment (e.g. the
+// container init) for the run that is not charged to the process group
+type OverheadProcess interface {
+	OverheadTime() (time.Duration, bool)
+}
+
+// PeekUsageProcess defines process that could read its current usage for
+// display without updating the statistics collected during the run (e.g. the
+// peak number of processes)
+type PeekUsageProcess interface {
+	PeekUsage() Usage
 }
 
 // Process reference to the running process group
@@ -65,5 +241,60 @@ type Environment interface {
 	Symlink(oldName, newName string) error
 }
 
+// CoreEnvironment will be asserted when CoreLimit is set, it returns error if
+// the core file of the process is not dumped into the work directory (e.g. the
+// core_pattern of the host is a pipe handler)
+type CoreEnvironment interface {
+	CoreDumpAvailable() error
+}
+
+// ShmEnvironment will be asserted after the run when ShmLimit is set, it
+// returns the bytes used by the tmpfs mounted at /dev/shm, false if not
+// mounted
+type ShmEnvironment interface {
+	ShmUsage() (Size, bool)
+}
+
+// DiskEnvironment will be asserted after the run when DiskLimit is set, it
+// returns the bytes allocated by the work directory backed by disk, false if
+// the work directory is not backed by disk
+type DiskEnvironment interface {
+	DiskUsage() (Size, bool)
+}
+
+// WorkDirEnvironment will be asserted after the run when the program exited
+// abnormally or ReportTiming is set, it returns the bytes used and the size of
+// the tmpfs mounted at the work directory, false if it is not tmpfs
+type WorkDirEnvironment interface {
+	WorkDirUsage() (used, size Size, ok bool)
+}
+
+// InitLogEnvironment will be asserted when the run failed with Internal Error,
+// it returns the stderr of the container init since the environment was
+// created or recycled
+type InitLogEnvironment interface {
+	InitLog() []byte
+}
+
+// IDEnvironment will be asserted after the run, it returns the id of the
+// environment which is unique among the environments created by the pool
+type IDEnvironment interface {
+	EnvironmentID() uint64
+}
+
+// TraceEnvironment will be asserted when Trace is set, it returns error if the
+// syscalls of the process could not be traced (e.g. the syscall numbers of the
+// architecture are not mapped)
+type TraceEnvironment interface {
+	TraceAvailable() error
+}
+
+// FifoEnvironment will be asserted when a FileFifo is copied in, it creates
+// the named pipe readable and writable by the program at the path in the
+// work directory
+type FifoEnvironment interface {
+	Mkfifo(path string) error
+}
+
 // NewStoreFile creates a new file in storage
 type NewStoreFile func() (*os.File, error)
@@ -0,0 +1,64 @@
+package envexec
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"golang.org/x/sync/errgroup"
+)
+
+// Panic is the value re-panicked in the goroutine running the cmd when one of
+// the goroutines it started (e.g. copy in, copy out, cmds of the group)
+// panicked, so that the panic could be recovered by the caller instead of
+// crashing the process. Stack is the stack of the goroutine panicked.
+type Panic struct {
+	Value any
+	Stack []byte
+}
+
+func (p *Panic) Error() string {
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+var errPanicked = errors.New("panicked")
+
+// panicGroup is errgroup.Group that recovers the panic of its goroutines and
+// panics with the first of them in Wait
+type panicGroup struct {
+	errgroup.Group
+	mu sync.Mutex
+	p  *Panic
+}
+
+func (g *panicGroup) Go(f func() error) {
+	g.Group.Go(func() (err error) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			// nested groups keep the original stack
+			p, ok := r.(*Panic)
+			if !ok {
+				p = &Panic{Value: r, Stack: debug.Stack()}
+			}
+			g.mu.Lock()
+			if g.p == nil {
+				g.p = p
+			}
+			g.mu.Unlock()
+			err = errPanicked
+		}()
+		return f()
+	})
+}
+
+func (g *panicGroup) Wait() error {
+	err := g.Group.Wait()
+	if g.p != nil {
+		panic(g.p)
+	}
+	return err
+}
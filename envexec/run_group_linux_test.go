@@ -0,0 +1,46 @@
+package envexec
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-sandbox/runner"
+)
+
+// TestRunGroupPipeLimit covers both ends of the proxy killed by its transfer
+// limit
+func TestRunGroupPipeLimit(t *testing.T) {
+	writer := newTestEnv(t, func(p ExecveParam) (*testProcess, error) {
+		syscall.Write(int(p.Files[1]), make([]byte, 100))
+		return exited(runner.StatusNormal, 0), nil
+	})
+	reader := newTestEnv(t, func(ExecveParam) (*testProcess, error) {
+		return exited(runner.StatusNormal, 0), nil
+	})
+	g := &Group{
+		Cmd: []*Cmd{
+			{Environment: writer, Args: []string{"w"}, Files: []File{nil, nil}, TimeLimit: time.Second, MemoryLimit: 64 << 20, Waiter: testWaiter},
+			{Environment: reader, Args: []string{"r"}, Files: []File{nil, nil}, TimeLimit: time.Second, MemoryLimit: 64 << 20, Waiter: testWaiter},
+		},
+		Pipes: []Pipe{{
+			In:            PipeIndex{Index: 0, Fd: 1},
+			Out:           PipeIndex{Index: 1, Fd: 0},
+			Proxy:         true,
+			TransferLimit: 10,
+		}},
+	}
+	rt, err := g.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range rt {
+		if r.Status != StatusPipeLimitExceeded || int(r.Status) != 18 {
+			t.Errorf("cmd[%d]: got %v (%d), want %v (18): %s", i, r.Status, int(r.Status), StatusPipeLimitExceeded, r.Error)
+		}
+		if r.TerminatedBy != TerminatedByLimitExceeded {
+			t.Errorf("cmd[%d]: terminated by %v, want %v", i, r.TerminatedBy, TerminatedByLimitExceeded)
+		}
+	}
+}
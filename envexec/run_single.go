@@ -2,20 +2,27 @@ package envexec
 
 import (
 	"context"
+	"fmt"
 	"os"
+	"time"
 
 	"github.com/criyle/go-sandbox/runner"
 )
 
 // runSingle runs Cmd inside the given environment and cgroup
-func runSingle(pc context.Context, c *Cmd, fds []*os.File, ptc []pipeCollector, newStoreFile NewStoreFile) (result Result, err error) {
+func runSingle(pc context.Context, c *Cmd, fds []*os.File, ptc []pipeCollector, fifos []*fifoBridge, newStoreFile NewStoreFile) (result Result, err error) {
 	m := c.Environment
 	// copyin
-	if fe, err := runSingleCopyIn(m, c.CopyIn); err != nil {
+	cs, fe, err := runSingleCopyIn(m, c)
+	if err != nil {
 		result.Status = StatusFileError
 		result.Error = err.Error()
 		result.FileError = fe
+		result.Extracted = cs.extracted
+		result.CopyInBytes = cs.bytes
 		closeFiles(fds...)
+		closeFifos(fifos)
+		discardCollectors(ptc)
 		return result, nil
 	}
 	// symlink
@@ -24,23 +31,106 @@ func runSingle(pc context.Context, c *Cmd, fds []*os.File, ptc []pipeCollector,
 		result.Error = err.Error()
 		result.FileError = []FileError{*fe}
 		closeFiles(fds...)
+		closeFifos(fifos)
+		discardCollectors(ptc)
 		return result, nil
 	}
 
+	// core is not dumped unless it could be collected from the work directory
+	var coreLimit Size
+	var coreErr error
+	if c.CoreLimit > 0 {
+		if coreErr = coreDumpAvailable(m); coreErr == nil {
+			coreLimit = c.CoreLimit
+		}
+	}
+
+	// traced program is refused if its syscalls could not be counted
+	if c.Trace {
+		if err := traceAvailable(m); err != nil {
+			result.Status = StatusInternalError
+			result.Error = err.Error()
+			closeFiles(fds...)
+			closeFifos(fifos)
+			discardCollectors(ptc)
+			return result, nil
+		}
+	}
+
 	// run cmd and wait for result
-	rt := runSingleWait(pc, m, c, fds)
+	startFifos(fifos)
+	startedAt := time.Now()
+	rt, st := runSingleWait(pc, m, c, fds, coreLimit)
+	stopFifos(fifos)
+	var shmUsage Size
+	if e, ok := m.(ShmEnvironment); ok && c.ShmLimit > 0 {
+		shmUsage, _ = e.ShmUsage()
+	}
+	var diskUsage Size
+	if e, ok := m.(DiskEnvironment); ok && c.DiskLimit > 0 {
+		diskUsage, _ = e.DiskUsage()
+	}
+	var workDirUsage, workDirSize Size
+	abnormal := rt.Status == runner.StatusNonzeroExitStatus || rt.Status == runner.StatusSignalled
+	if e, ok := m.(WorkDirEnvironment); ok && (c.ReportTiming || abnormal) {
+		workDirUsage, workDirSize, _ = e.WorkDirUsage()
+	}
 
-	// collect result
-	files, fe, err := copyOutAndCollect(m, c, ptc, newStoreFile)
+	// collect result, nobody waits for the outputs of abandoned run
+	copyOutStart := time.Now()
+	var (
+		files map[string]*os.File
+		stats map[string]FileStat
+	)
+	abandoned := isAbandoned(pc)
+	if abandoned {
+		discardCollectors(ptc)
+	} else {
+		files, stats, fe, err = copyOutAndCollect(m, c, ptc, newStoreFile)
+	}
 	result = Result{
-		Status:     convertStatus(rt.Status),
-		ExitStatus: rt.ExitStatus,
-		Error:      rt.Error,
-		Time:       rt.Time,
-		RunTime:    rt.RunningTime,
-		Memory:     rt.Memory,
-		Files:      files,
-		FileError:  fe,
+		Status:       convertStatus(rt.Status),
+		ExitStatus:   rt.ExitStatus,
+		Error:        rt.Error,
+		Time:         rt.Time,
+		RunTime:      rt.RunningTime,
+		Memory:       rt.Memory,
+		MemoryStat:   st.memory,
+		ProcPeak:     st.proc.Peak,
+		StrayProcs:   st.proc.Stray,
+		IOStat:       st.io,
+		Throttling:   st.throttle,
+		Arch:         st.arch,
+		Emulated:     st.emulated,
+		RandomOpened: st.random,
+		ShmUsage:     shmUsage,
+		DiskUsage:    diskUsage,
+		WorkDirUsage: workDirUsage,
+		WorkDirSize:  workDirSize,
+		SyscallCount: st.syscalls,
+		OverheadTime: st.overhead,
+		Files:        files,
+		FileStat:     stats,
+		FileError:    fe,
+		Extracted:    cs.extracted,
+		CopyInBytes:  cs.bytes,
+		CopyOutTime:  time.Since(copyOutStart),
+		OutputStat:   collectOutputStat(ptc),
+		StartedAt:    startedAt,
+	}
+	result.AttachRetries = st.retries
+	result.CgroupID = st.cgroupID
+	if e, ok := m.(IDEnvironment); ok {
+		result.EnvironmentID = e.EnvironmentID()
+	}
+	if coreErr != nil {
+		result.CoreError = coreErr.Error()
+	} else if coreLimit > 0 && rt.Status == runner.StatusSignalled && !abandoned {
+		core, err := collectCore(m, startedAt, coreLimit, newStoreFile)
+		if err != nil {
+			result.CoreError = "failed to collect core file: " + err.Error()
+		}
+		result.Core = core
 	}
 	// collect error (only if the process exits normally)
 	if rt.Status == runner.StatusNormal && err != nil && result.Error == "" {
@@ -55,30 +145,92 @@ func runSingle(pc context.Context, c *Cmd, fds []*os.File, ptc []pipeCollector,
 	if result.Time > c.TimeLimit {
 		result.Status = StatusTimeLimitExceeded
 	}
-	if result.Memory > c.MemoryLimit {
+	if result.Memory > c.MemoryLimit || (c.ShmLimit > 0 && shmUsage > c.ShmLimit) {
 		result.Status = StatusMemoryLimitExceeded
 	}
+	if (c.IOLimit > 0 && st.io != nil && st.io.Write > c.IOLimit) || (c.DiskLimit > 0 && diskUsage > c.DiskLimit) {
+		result.Status = StatusOutputLimitExceeded
+	}
+	if st.archErr {
+		result.Status = StatusUnsupportedArchitecture
+		if st.execErr != nil {
+			result.Error = st.execErr.Message
+		}
+	} else if st.execErr != nil {
+		result.Status = StatusFileError
+		result.Error = st.execErr.Message
+		result.FileError = append(result.FileError, *st.execErr)
+	}
+	// failed after the proc limit was hit (e.g. fork returns EAGAIN)
+	if st.proc.LimitHit && (result.Status == StatusNonzeroExitStatus || result.Status == StatusSignalled) {
+		result.Status = StatusProcessLimitExceeded
+	}
+	// failed after the writes to the full work directory returned ENOSPC
+	if workDirFull(workDirUsage, workDirSize) && (result.Status == StatusNonzeroExitStatus || result.Status == StatusSignalled) {
+		result.Status = StatusDiskLimitExceeded
+		result.Error = fmt.Sprintf("work directory is full: %v of %v used", workDirUsage, workDirSize)
+	}
+	// the container init may report why it failed to run the program
+	if e, ok := m.(InitLogEnvironment); ok && result.Status == StatusInternalError {
+		result.InitLog = string(e.InitLog())
+	}
+	result.Signal, result.TerminatedBy = convertTermination(pc, rt, result.Status)
 	return result, nil
 }
 
-func runSingleCopyIn(m Environment, copyInFiles map[string]File) ([]FileError, error) {
-	if len(copyInFiles) == 0 {
-		return nil, nil
+// workDirFull reports whether the tmpfs work directory is at least 99% used,
+// the last blocks may be too small for the write that failed
+func workDirFull(used, size Size) bool {
+	return size > 0 && used*100 >= size*99
+}
+
+func runSingleCopyIn(m Environment, c *Cmd) (copyInStat, []FileError, error) {
+	if c.Snapshot != nil {
+		if err := c.Snapshot.restore(m); err != nil {
+			err = fmt.Errorf("failed to restore snapshot %v", err)
+			return copyInStat{}, []FileError{{Name: c.Snapshot.Name, Type: ErrCopyInSnapshot, Message: err.Error()}}, err
+		}
 	}
-	return copyIn(m, copyInFiles)
+	if len(c.CopyIn) == 0 {
+		return copyInStat{}, nil, nil
+	}
+	return copyIn(m, c)
+}
+
+// processStat is collected from the optional interfaces of the process after
+// it exited
+type processStat struct {
+	memory   *MemoryStat
+	proc     ProcStat
+	io       *IOStat
+	throttle *CPUThrottling
+	arch     string
+	archErr  bool // host could not execute the architecture
+	emulated bool
+	random   bool // opened the deterministic random devices
+	execErr  *FileError
+	retries  int
+	syscalls map[string]uint64
+	overhead time.Duration
+	cgroupID uint64
 }
 
-func runSingleWait(pc context.Context, m Environment, c *Cmd, fds []*os.File) RunnerResult {
+func runSingleWait(pc context.Context, m Environment, c *Cmd, fds []*os.File, coreLimit Size) (RunnerResult, processStat) {
 	// start the cmd (they will be canceled in other goroutines)
 	ctx, cancel := context.WithCancel(pc)
 	defer cancel()
 
-	process, err := runSingleExecve(ctx, m, c, fds)
+	process, err := runSingleExecve(ctx, m, c, fds, coreLimit)
 	if err != nil {
+		// error could report the retries as well
+		var st processStat
+		if p, ok := err.(RetryProcess); ok {
+			st.retries = p.AttachRetries()
+		}
 		return runner.Result{
 			Status: runner.StatusRunnerError,
 			Error:  err.Error(),
-		}
+		}, st
 	}
 
 	// starts waiter to periodically check cpu usage
@@ -86,12 +238,66 @@ func runSingleWait(pc context.Context, m Environment, c *Cmd, fds []*os.File) Ru
 	// cancel the process as waiter exits
 	cancel()
 
-	return process.Result()
+	rt := process.Result()
+	var st processStat
+	if p, ok := process.(ProcStatProcess); ok {
+		st.proc, _ = p.ProcStat()
+	}
+	if p, ok := process.(RetryProcess); ok {
+		st.retries = p.AttachRetries()
+	}
+	if p, ok := process.(IOStatProcess); ok {
+		if s, ok := p.IOStat(); ok {
+			st.io = &s
+		}
+	}
+	if p, ok := process.(CPUThrottlingProcess); ok {
+		if s, ok := p.CPUThrottling(); ok {
+			st.throttle = &s
+		}
+	}
+	if p, ok := process.(ArchProcess); ok && c.Arch != "" {
+		st.arch, st.archErr = p.Arch()
+	}
+	if p, ok := process.(EmulatedProcess); ok && c.Arch != "" {
+		st.emulated = p.Emulated()
+	}
+	if p, ok := process.(RandomProcess); ok && c.Random != nil {
+		st.random = p.RandomOpened()
+	}
+	if p, ok := process.(ExecErrorProcess); ok {
+		st.execErr = p.ExecError()
+	}
+	if p, ok := process.(SyscallTraceProcess); ok && c.Trace {
+		st.syscalls, _ = p.SyscallCount()
+	}
+	if p, ok := process.(CgroupProcess); ok {
+		st.cgroupID, _ = p.CgroupID()
+	}
+	if p, ok := process.(OverheadProcess); ok && c.ReportTiming {
+		st.overhead, _ = p.OverheadTime()
+	}
+	if !c.DetailedMemory {
+		return rt, st
+	}
+	if p, ok := process.(MemoryStatProcess); ok {
+		if ms, ok := p.MemoryStat(); ok {
+			st.memory = &ms
+		}
+	}
+	return rt, st
 }
 
-func runSingleExecve(ctx context.Context, m Environment, c *Cmd, fds []*os.File) (Process, error) {
+func runSingleExecve(ctx context.Context, m Environment, c *Cmd, fds []*os.File, coreLimit Size) (Process, error) {
 	defer closeFiles(fds...)
 
+	// fds not bound (e.g. the gaps below the extra files) are /dev/null
+	fds, null, err := fillNullFds(fds)
+	if err != nil {
+		return nil, err
+	}
+	defer closeFiles(null)
+
 	extraMemoryLimit := c.ExtraMemoryLimit
 	if extraMemoryLimit == 0 {
 		extraMemoryLimit = defaultExtraMemoryLimit
@@ -123,7 +329,14 @@ func runSingleExecve(ctx context.Context, m Environment, c *Cmd, fds []*os.File)
 			OpenFile:     c.OpenFileLimit,
 			CPUSet:       c.CPUSetLimit,
 			StrictMemory: c.StrictMemoryLimit,
+			Core:         coreLimit,
 		},
+		DetailedMemory: c.DetailedMemory,
+		Trace:          c.Trace,
+		ProcInfo:       c.ProcInfo,
+		Random:         c.Random,
+		Arch:           c.Arch,
+		Overhead:       c.ReportTiming,
 	}
 	return m.Execve(ctx, execParam)
 }
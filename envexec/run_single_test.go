@@ -0,0 +1,218 @@
+package envexec
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-sandbox/runner"
+)
+
+// testEnv runs the process scripted by run in the temp work directory
+type testEnv struct {
+	dir      *os.File
+	run      func(ExecveParam) (*testProcess, error)
+	workUsed Size // bytes used of the tmpfs work directory, 0 if not tmpfs
+	workSize Size
+}
+
+func newTestEnv(t *testing.T, run func(ExecveParam) (*testProcess, error)) *testEnv {
+	dir, err := os.Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { dir.Close() })
+	return &testEnv{dir: dir, run: run}
+}
+
+func (e *testEnv) Execve(_ context.Context, p ExecveParam) (Process, error) {
+	tp, err := e.run(p)
+	if err != nil {
+		return nil, err
+	}
+	tp.done = make(chan struct{})
+	close(tp.done)
+	return tp, nil
+}
+
+func (e *testEnv) WorkDir() *os.File {
+	return e.dir
+}
+
+func (e *testEnv) Open(path string, flags int, perm os.FileMode) (*os.File, error) {
+	return os.OpenFile(filepath.Join(e.dir.Name(), path), flags, perm)
+}
+
+func (e *testEnv) MkdirAll(path string, perm os.FileMode) error {
+	return os.MkdirAll(filepath.Join(e.dir.Name(), path), perm)
+}
+
+func (e *testEnv) Symlink(oldName, newName string) error {
+	return os.Symlink(oldName, filepath.Join(e.dir.Name(), newName))
+}
+
+func (e *testEnv) WorkDirUsage() (Size, Size, bool) {
+	return e.workUsed, e.workSize, e.workSize > 0
+}
+
+// testProcess has exited with the result and the stats
+type testProcess struct {
+	done    chan struct{}
+	rt      RunnerResult
+	proc    ProcStat
+	arch    string
+	archErr bool
+	execErr *FileError
+}
+
+func (p *testProcess) Done() <-chan struct{} {
+	return p.done
+}
+
+func (p *testProcess) Result() RunnerResult {
+	return p.rt
+}
+
+func (p *testProcess) Usage() Usage {
+	return Usage{Time: p.rt.Time, Memory: p.rt.Memory}
+}
+
+func (p *testProcess) ProcStat() (ProcStat, bool) {
+	return p.proc, true
+}
+
+func (p *testProcess) Arch() (string, bool) {
+	return p.arch, p.archErr
+}
+
+func (p *testProcess) ExecError() *FileError {
+	return p.execErr
+}
+
+func testWaiter(ctx context.Context, p Process) bool {
+	select {
+	case <-ctx.Done():
+	case <-p.Done():
+	}
+	return false
+}
+
+func exited(s runner.Status, code int) *testProcess {
+	return &testProcess{rt: RunnerResult{Status: s, ExitStatus: code}}
+}
+
+// TestRunSingleStatus covers the ways a single run finishes and the status
+// with its stable code reported for each of them
+func TestRunSingleStatus(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		proc   func(ExecveParam) (*testProcess, error)
+		cmd    func(*Cmd)
+		env    func(*testEnv)
+		status Status
+		code   int
+		by     TerminationReason
+	}{
+		{name: "exited", status: StatusAccepted, code: 1, by: TerminatedByExited,
+			proc: func(ExecveParam) (*testProcess, error) { return exited(runner.StatusNormal, 0), nil }},
+		{name: "nonzero exit", status: StatusNonzeroExitStatus, code: 8, by: TerminatedByExited,
+			proc: func(ExecveParam) (*testProcess, error) { return exited(runner.StatusNonzeroExitStatus, 1), nil }},
+		{name: "signalled", status: StatusSignalled, code: 9, by: TerminatedBySignaled,
+			proc: func(ExecveParam) (*testProcess, error) { return exited(runner.StatusSignalled, 11), nil }},
+		{name: "memory limit", status: StatusMemoryLimitExceeded, code: 4, by: TerminatedByLimitExceeded,
+			proc: func(ExecveParam) (*testProcess, error) { return exited(runner.StatusMemoryLimitExceeded, 0), nil }},
+		{name: "time limit", status: StatusTimeLimitExceeded, code: 5, by: TerminatedByLimitExceeded,
+			proc: func(ExecveParam) (*testProcess, error) { return exited(runner.StatusTimeLimitExceeded, 0), nil }},
+		{name: "output limit", status: StatusOutputLimitExceeded, code: 6, by: TerminatedByLimitExceeded,
+			proc: func(ExecveParam) (*testProcess, error) { return exited(runner.StatusOutputLimitExceeded, 0), nil }},
+		{name: "disallowed syscall", status: StatusDangerousSyscall, code: 10, by: TerminatedBySignaled,
+			proc: func(ExecveParam) (*testProcess, error) { return exited(runner.StatusDisallowedSyscall, 0), nil }},
+		{name: "runner error", status: StatusInternalError, code: 13, by: TerminatedByInternal,
+			proc: func(ExecveParam) (*testProcess, error) { return exited(runner.StatusRunnerError, 0), nil }},
+		{name: "execve failed", status: StatusInternalError, code: 13, by: TerminatedByInternal,
+			proc: func(ExecveParam) (*testProcess, error) { return nil, errors.New("failed to start container") }},
+		{name: "cpu time over limit", status: StatusTimeLimitExceeded, code: 5, by: TerminatedByLimitExceeded,
+			proc: func(ExecveParam) (*testProcess, error) {
+				p := exited(runner.StatusNormal, 0)
+				p.rt.Time = 2 * time.Second
+				return p, nil
+			}},
+		{name: "memory over limit", status: StatusMemoryLimitExceeded, code: 4, by: TerminatedByLimitExceeded,
+			proc: func(ExecveParam) (*testProcess, error) {
+				p := exited(runner.StatusNonzeroExitStatus, 1)
+				p.rt.Memory = 128 << 20
+				return p, nil
+			}},
+		{name: "proc limit hit then nonzero exit", status: StatusProcessLimitExceeded, code: 16, by: TerminatedByExited,
+			proc: func(ExecveParam) (*testProcess, error) {
+				p := exited(runner.StatusNonzeroExitStatus, 1)
+				p.proc = ProcStat{Peak: 4, LimitHit: true}
+				return p, nil
+			}},
+		{name: "proc limit hit then signalled", status: StatusProcessLimitExceeded, code: 16, by: TerminatedBySignaled,
+			proc: func(ExecveParam) (*testProcess, error) {
+				p := exited(runner.StatusSignalled, 6)
+				p.proc = ProcStat{Peak: 4, LimitHit: true}
+				return p, nil
+			}},
+		{name: "proc limit hit then exited", status: StatusAccepted, code: 1, by: TerminatedByExited,
+			proc: func(ExecveParam) (*testProcess, error) {
+				p := exited(runner.StatusNormal, 0)
+				p.proc = ProcStat{Peak: 4, LimitHit: true}
+				return p, nil
+			}},
+		{name: "unsupported architecture", status: StatusUnsupportedArchitecture, code: 17, by: TerminatedByExited,
+			cmd: func(c *Cmd) { c.Arch = ArchAuto },
+			proc: func(ExecveParam) (*testProcess, error) {
+				p := exited(runner.StatusNonzeroExitStatus, 1)
+				p.arch, p.archErr = "riscv64", true
+				return p, nil
+			}},
+		{name: "exec error", status: StatusFileError, code: 7, by: TerminatedByExited,
+			proc: func(ExecveParam) (*testProcess, error) {
+				p := exited(runner.StatusNonzeroExitStatus, 1)
+				p.execErr = &FileError{Name: "a", Type: ErrExecNotFound, Message: "not found"}
+				return p, nil
+			}},
+		{name: "work dir full", status: StatusDiskLimitExceeded, code: 19, by: TerminatedByExited,
+			env:  func(e *testEnv) { e.workUsed, e.workSize = 1000, 1000 },
+			proc: func(ExecveParam) (*testProcess, error) { return exited(runner.StatusNonzeroExitStatus, 1), nil }},
+		{name: "copy in failed", status: StatusFileError, code: 7, by: TerminatedByInternal,
+			cmd: func(c *Cmd) { c.CopyIn = map[string]File{"a": NewFileInput("/nonexistent/a")} }},
+		{name: "trace not supported", status: StatusInternalError, code: 13, by: TerminatedByInternal,
+			cmd: func(c *Cmd) { c.Trace = true }},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			env := newTestEnv(t, func(p ExecveParam) (*testProcess, error) {
+				if c.proc == nil {
+					t.Fatal("program is executed")
+				}
+				return c.proc(p)
+			})
+			if c.env != nil {
+				c.env(env)
+			}
+			cmd := &Cmd{
+				Environment: env,
+				Args:        []string{"a"},
+				TimeLimit:   time.Second,
+				MemoryLimit: 64 << 20,
+				Waiter:      testWaiter,
+			}
+			if c.cmd != nil {
+				c.cmd(cmd)
+			}
+			s := &Single{Cmd: cmd}
+			r, _ := s.Run(context.Background())
+			if r.Status != c.status || int(r.Status) != c.code {
+				t.Fatalf("got %v (%d), want %v (%d): %s", r.Status, int(r.Status), c.status, c.code, r.Error)
+			}
+			if r.TerminatedBy != c.by {
+				t.Fatalf("terminated by %v, want %v", r.TerminatedBy, c.by)
+			}
+		})
+	}
+}
@@ -7,7 +7,8 @@ import (
 // Status defines run task Status return status
 type Status int
 
-// Defines run task Status result status
+// Defines run task Status result status, the integer codes are stable across
+// versions and shared with the gRPC StatusType, new statuses are only appended
 const (
 	// not initialized status (as error)
 	StatusInvalid Status = iota
@@ -36,6 +37,29 @@ const (
 	StatusInternalError
 )
 
+// Statuses appended after the codes of CGroup Error (14) and Container Error
+// (15), which are only kept as names
+const (
+	// exit with error after the process limit was hit
+	StatusProcessLimitExceeded Status = iota + 16 // PLE
+
+	// the host could not execute the architecture of the program
+	StatusUnsupportedArchitecture
+
+	// the pipe proxy between the programs exceeded its transfer limit
+	StatusPipeLimitExceeded
+
+	// exited with error after the tmpfs work directory was full
+	StatusDiskLimitExceeded
+
+	// not executed since a command it depends on did not finish as accepted
+	StatusSkipped
+)
+
+// StatusUnknown is the status code or name not known by this version, it is
+// never returned by the executor but decoded by clients from a newer server
+const StatusUnknown Status = -1
+
 var statusToString = []string{
 	"Invalid",
 	"Accepted",
@@ -53,12 +77,20 @@ var statusToString = []string{
 	"Internal Error",
 	"CGroup Error",
 	"Container Error",
+	"Process Limit Exceeded",
+	"Unsupported Architecture",
+	"Pipe Limit Exceeded",
+	"Disk Limit Exceeded",
+	"Skipped",
 }
 
 // stringToStatus map string to corresponding Status
 var stringToStatus = make(map[string]Status)
 
 func (s Status) String() string {
+	if s == StatusUnknown {
+		return "Unknown"
+	}
 	si := int(s)
 	if si < 0 || si >= len(statusToString) {
 		return statusToString[0] // invalid
@@ -75,8 +107,61 @@ func StringToStatus(s string) (Status, error) {
 	return v, nil
 }
 
+// Known reports whether the status is defined by this version
+func (s Status) Known() bool {
+	return s >= 0 && int(s) < len(statusToString)
+}
+
+// StatusFromCode converts the integer code into Status, StatusUnknown if the
+// code is not defined by this version
+func StatusFromCode(code int) Status {
+	s := Status(code)
+	if !s.Known() {
+		return StatusUnknown
+	}
+	return s
+}
+
+// StatusFromName converts the name returned by String into Status,
+// StatusUnknown if the name is not defined by this version
+func StatusFromName(name string) Status {
+	s, ok := stringToStatus["\""+name+"\""]
+	if !ok {
+		return StatusUnknown
+	}
+	return s
+}
+
 func init() {
 	for i, v := range statusToString {
 		stringToStatus["\""+v+"\""] = Status(i)
 	}
 }
+
+// TerminationReason defines how the program was terminated
+type TerminationReason int
+
+// Defines termination reasons
+const (
+	TerminatedByInternal      TerminationReason = iota // not started or internal error
+	TerminatedByExited                                 // exited by itself
+	TerminatedBySignaled                               // killed by signal not sent by executor
+	TerminatedByLimitExceeded                          // killed by executor due to resource limit
+	TerminatedByCancelled                              // killed by executor due to cancellation
+)
+
+var terminationReasonToString = []string{
+	"internal",
+	"exited",
+	"signaled",
+	"limitExceeded",
+	"cancelled",
+}
+
+func (t TerminationReason) String() string {
+	ti := int(t)
+	if ti < 0 || ti >= len(terminationReasonToString) {
+		return terminationReasonToString[0]
+	}
+	return terminationReasonToString[ti]
+}
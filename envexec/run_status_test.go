@@ -0,0 +1,59 @@
+package envexec
+
+import "testing"
+
+// statusCodes are the integer codes of the statuses, which are stable across
+// versions and shared with the gRPC StatusType
+var statusCodes = []struct {
+	status Status
+	code   int
+	name   string
+}{
+	{StatusInvalid, 0, "Invalid"},
+	{StatusAccepted, 1, "Accepted"},
+	{StatusWrongAnswer, 2, "Wrong Answer"},
+	{StatusPartiallyCorrect, 3, "Partially Correct"},
+	{StatusMemoryLimitExceeded, 4, "Memory Limit Exceeded"},
+	{StatusTimeLimitExceeded, 5, "Time Limit Exceeded"},
+	{StatusOutputLimitExceeded, 6, "Output Limit Exceeded"},
+	{StatusFileError, 7, "File Error"},
+	{StatusNonzeroExitStatus, 8, "Nonzero Exit Status"},
+	{StatusSignalled, 9, "Signalled"},
+	{StatusDangerousSyscall, 10, "Dangerous Syscall"},
+	{StatusJudgementFailed, 11, "Judgement Failed"},
+	{StatusInvalidInteraction, 12, "Invalid Interaction"},
+	{StatusInternalError, 13, "Internal Error"},
+	{StatusProcessLimitExceeded, 16, "Process Limit Exceeded"},
+	{StatusUnsupportedArchitecture, 17, "Unsupported Architecture"},
+	{StatusPipeLimitExceeded, 18, "Pipe Limit Exceeded"},
+	{StatusDiskLimitExceeded, 19, "Disk Limit Exceeded"},
+	{StatusSkipped, 20, "Skipped"},
+}
+
+func TestStatusCode(t *testing.T) {
+	for _, c := range statusCodes {
+		if int(c.status) != c.code {
+			t.Errorf("%s: code %d, want %d", c.name, int(c.status), c.code)
+		}
+		if s := StatusFromCode(c.code); s != c.status {
+			t.Errorf("%d: from code %v, want %v", c.code, s, c.status)
+		}
+		if c.status.String() != c.name {
+			t.Errorf("%d: name %q, want %q", c.code, c.status.String(), c.name)
+		}
+		if s := StatusFromName(c.name); s != c.status {
+			t.Errorf("%q: from name %v, want %v", c.name, s, c.status)
+		}
+	}
+	// the codes of the removed statuses are kept as names only
+	for code, name := range map[int]string{14: "CGroup Error", 15: "Container Error"} {
+		if s := StatusFromCode(code); s.String() != name {
+			t.Errorf("%d: name %q, want %q", code, s.String(), name)
+		}
+	}
+	for _, code := range []int{-2, len(statusToString), 100} {
+		if s := StatusFromCode(code); s != StatusUnknown {
+			t.Errorf("%d: from code %v, want Unknown", code, s)
+		}
+	}
+}
@@ -12,17 +12,24 @@ type Single struct {
 
 	// NewStoreFile defines interface to create stored file
 	NewStoreFile NewStoreFile
+
+	// OnLeak is called in background if the fds or goroutines created for the
+	// run are not released after it finished
+	OnLeak func(Leak)
 }
 
 // Run starts the cmd and returns exec results
 func (s *Single) Run(ctx context.Context) (result Result, err error) {
+	tr := newTracker()
+	defer tr.check(s.OnLeak)
+
 	// prepare files
-	fd, pipeToCollect, err := prepareCmdFd(s.Cmd, len(s.Cmd.Files), s.NewStoreFile)
+	fd, pipeToCollect, err := prepareCmdFd(s.Cmd, len(s.Cmd.Files), s.NewStoreFile, tr)
 	if err != nil {
 		return result, err
 	}
 
-	result, err = runSingle(ctx, s.Cmd, fd, pipeToCollect, s.NewStoreFile)
+	result, err = runSingle(ctx, s.Cmd, fd, pipeToCollect, nil, s.NewStoreFile)
 	if err != nil {
 		result.Status = StatusInternalError
 		result.Error = err.Error()
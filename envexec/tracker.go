@@ -0,0 +1,187 @@
+package envexec
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// leakCheckTimeout is the grace period for the fds and goroutines of a
+	// run to be released after the run finished
+	leakCheckTimeout  = 5 * time.Second
+	leakCheckInterval = 10 * time.Millisecond
+	leakStackDepth    = 16
+)
+
+var (
+	trackedFiles      int64
+	trackedGoroutines int64
+)
+
+// ResourceUsage returns the number of fds and goroutines created by the runs
+// that are not released yet
+func ResourceUsage() (files, goroutines int64) {
+	return atomic.LoadInt64(&trackedFiles), atomic.LoadInt64(&trackedGoroutines)
+}
+
+// Leak describes the fds and goroutines created by a run that are not
+// released after the run finished, each entry contains the stack where it was
+// created
+type Leak struct {
+	Files      []string
+	Goroutines []string
+}
+
+func (l Leak) String() string {
+	var sb strings.Builder
+	for _, f := range l.Files {
+		sb.WriteString("file ")
+		sb.WriteString(f)
+	}
+	for _, g := range l.Goroutines {
+		sb.WriteString("goroutine ")
+		sb.WriteString(g)
+	}
+	return sb.String()
+}
+
+// tracker records the internal fds and goroutines created for a run, files
+// returned as the result (e.g. collected output) are not tracked
+type tracker struct {
+	mu         sync.Mutex
+	files      []*trackedFile
+	goroutines map[*trackedGoroutine]struct{}
+}
+
+type trackedFile struct {
+	f     *os.File
+	stack []uintptr
+}
+
+type trackedGoroutine struct {
+	stack []uintptr
+}
+
+func newTracker() *tracker {
+	return &tracker{goroutines: make(map[*trackedGoroutine]struct{})}
+}
+
+// file records the files, nil files are ignored
+func (t *tracker) file(fs ...*os.File) {
+	stack := callers()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, f := range fs {
+		if f == nil {
+			continue
+		}
+		t.files = append(t.files, &trackedFile{f: f, stack: stack})
+		atomic.AddInt64(&trackedFiles, 1)
+	}
+}
+
+// goFunc runs f in a new goroutine which is released after f returns
+func (t *tracker) goFunc(f func()) {
+	g := &trackedGoroutine{stack: callers()}
+	t.mu.Lock()
+	t.goroutines[g] = struct{}{}
+	t.mu.Unlock()
+	atomic.AddInt64(&trackedGoroutines, 1)
+
+	go func() {
+		defer func() {
+			t.mu.Lock()
+			delete(t.goroutines, g)
+			t.mu.Unlock()
+			atomic.AddInt64(&trackedGoroutines, -1)
+		}()
+		f()
+	}()
+}
+
+// check waits in background for the recorded fds and goroutines to be
+// released and calls report if they are not released within
+// leakCheckTimeout. The totals from ResourceUsage are kept up to date until
+// all of them are released eventually.
+func (t *tracker) check(report func(Leak)) {
+	go func() {
+		deadline := time.Now().Add(leakCheckTimeout)
+		interval := leakCheckInterval
+		reported := false
+		for !t.released() {
+			if !reported && time.Now().After(deadline) {
+				reported = true
+				interval = time.Second
+				if report != nil {
+					report(t.leak())
+				}
+			}
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// released removes the closed files and reports whether everything recorded
+// is released
+func (t *tracker) released() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	open := t.files[:0]
+	for _, f := range t.files {
+		if isClosed(f.f) {
+			atomic.AddInt64(&trackedFiles, -1)
+			continue
+		}
+		open = append(open, f)
+	}
+	t.files = open
+	return len(t.files) == 0 && len(t.goroutines) == 0
+}
+
+func (t *tracker) leak() Leak {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var l Leak
+	for _, f := range t.files {
+		l.Files = append(l.Files, f.f.Name()+"\n"+formatStack(f.stack))
+	}
+	for g := range t.goroutines {
+		l.Goroutines = append(l.Goroutines, formatStack(g.stack))
+	}
+	return l
+}
+
+func isClosed(f *os.File) bool {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return true
+	}
+	return rc.Control(func(uintptr) {}) != nil
+}
+
+// callers returns the stack of the caller of the tracker method
+func callers() []uintptr {
+	pc := make([]uintptr, leakStackDepth)
+	return pc[:runtime.Callers(3, pc)]
+}
+
+func formatStack(pc []uintptr) string {
+	var sb strings.Builder
+	frames := runtime.CallersFrames(pc)
+	for {
+		f, more := frames.Next()
+		fmt.Fprintf(&sb, "%s\n\t%s:%d\n", f.Function, f.File, f.Line)
+		if !more {
+			break
+		}
+	}
+	return sb.String()
+}
@@ -1,11 +1,34 @@
 package envexec
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
 
 	"github.com/criyle/go-sandbox/runner"
 )
 
+// errKilledOnLimit is the cause of killing the other cmds of the group after
+// one of them exceeded its limit
+var errKilledOnLimit = errors.New("killed by the limit of the other cmd")
+
+// errPipeLimitExceeded is the cause of killing both ends of the pipe proxy
+// exceeded its transfer limit, it is also returned by the proxy writer
+var errPipeLimitExceeded = errors.New("pipe transfer limit exceeded")
+
+// isAbandoned reports whether the context was cancelled or timed out without
+// a cause, which means the caller is gone (e.g. the client disconnected) and
+// the outputs are not needed. Cancelling with a cause (e.g. kill on limit)
+// still collects the outputs.
+func isAbandoned(ctx context.Context) bool {
+	if ctx.Err() == nil {
+		return false
+	}
+	cause := context.Cause(ctx)
+	return cause == context.Canceled || cause == context.DeadlineExceeded
+}
+
 func convertStatus(s runner.Status) Status {
 	switch s {
 	case runner.StatusNormal:
@@ -29,6 +52,32 @@ func convertStatus(s runner.Status) Status {
 	}
 }
 
+// convertTermination returns the terminating signal and the termination
+// reason from the runner result and the final status
+func convertTermination(pc context.Context, rt RunnerResult, s Status) (int, TerminationReason) {
+	var sig int
+	if rt.Status == runner.StatusSignalled {
+		sig = rt.ExitStatus
+	}
+	switch s {
+	case StatusMemoryLimitExceeded, StatusTimeLimitExceeded, StatusOutputLimitExceeded:
+		return sig, TerminatedByLimitExceeded
+	}
+	switch rt.Status {
+	case runner.StatusNormal, runner.StatusNonzeroExitStatus:
+		return sig, TerminatedByExited
+	case runner.StatusSignalled:
+		if pc.Err() != nil {
+			return sig, TerminatedByCancelled
+		}
+		return sig, TerminatedBySignaled
+	case runner.StatusDisallowedSyscall:
+		return sig, TerminatedBySignaled
+	default:
+		return sig, TerminatedByInternal
+	}
+}
+
 func getFdArray(fd []*os.File) []uintptr {
 	r := make([]uintptr, 0, len(fd))
 	for _, f := range fd {
@@ -37,6 +86,27 @@ func getFdArray(fd []*os.File) []uintptr {
 	return r
 }
 
+// fillNullFds returns fds with the nil ones replaced by /dev/null opened
+// once, null is nil if there is none to replace
+func fillNullFds(fds []*os.File) (rt []*os.File, null *os.File, err error) {
+	for i, f := range fds {
+		if f != nil {
+			continue
+		}
+		if null == nil {
+			if null, err = os.OpenFile(os.DevNull, os.O_RDWR, 0); err != nil {
+				return nil, nil, fmt.Errorf("failed to open %v: %v", os.DevNull, err)
+			}
+			rt = append([]*os.File(nil), fds...)
+		}
+		rt[i] = null
+	}
+	if null == nil {
+		return fds, nil, nil
+	}
+	return rt, null, nil
+}
+
 func closeFiles(files ...*os.File) {
 	for _, f := range files {
 		if f == nil {
@@ -45,3 +115,15 @@ func closeFiles(files ...*os.File) {
 		f.Close()
 	}
 }
+
+var errTraceNotSupported = errors.New("syscall trace is not supported by the environment")
+
+// traceAvailable checks whether the syscalls of the process could be counted
+// by the environment
+func traceAvailable(m Environment) error {
+	e, ok := m.(TraceEnvironment)
+	if !ok {
+		return errTraceNotSupported
+	}
+	return e.TraceAvailable()
+}
@@ -1,70 +1,323 @@
 package filestore
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"path"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"sync"
 
 	"github.com/criyle/go-judge/envexec"
 )
 
+// prefixes of files that are not added, they never conflict with generated ids
+const (
+	checkFilePrefix      = ".check"      // created by Check
+	tmpFilePrefix        = ".tmp"        // created by New, renamed to id when added
+	quarantineFilePrefix = ".quarantine" // failed the sanity check
+	metaFilePrefix       = ".meta"       // expected size and metadata of the added file
+)
+
+// StateFilePrefix is the prefix of files kept by the server in the directory
+// of the local file store (e.g. the usage), they are not visible as added files
+const StateFilePrefix = ".state"
+
+// renameOnAdd is false on windows where opened file could not be renamed, the
+// files are created with their ids there
+const renameOnAdd = runtime.GOOS != "windows"
+
+var _ Checker = &fileLocalStore{}
+
 type fileLocalStore struct {
-	dir  string            // directory to store file
-	name map[string]string // id to name mapping if exists
-	mu   sync.RWMutex
+	dir  string              // directory to store file
+	sync bool                // fsync file and directory when added
+	meta map[string]FileMeta // id to metadata mapping if exists
+	size map[string]int64    // id to size when added
+	// created by New with the id but not added yet (!renameOnAdd)
+	pending map[string]bool
+	mu      sync.RWMutex
+}
+
+// localMeta is the content of the metadata file written before the file is
+// added, so that the size is checked after restart as well
+type localMeta struct {
+	FileMeta
+	Size int64 `json:"size"`
 }
 
-// NewFileLocalStore create new local file store
+// NewFileLocalStore create new local file store, files are fsynced when added
 func NewFileLocalStore(dir string) FileStore {
-	return &fileLocalStore{
-		dir:  filepath.Clean(dir),
-		name: make(map[string]string),
+	return NewFileLocalStoreSync(dir, true)
+}
+
+// NewFileLocalStoreSync create new local file store, files are written to
+// temp files and renamed to their ids when added so that partially written
+// files are never visible. If sync is true, the file and the directory are
+// fsynced so that added files survive power loss. Temp files left by previous
+// run are removed and the files added before are loaded with their metadata,
+// the ones mismatching their recorded size are quarantined.
+func NewFileLocalStoreSync(dir string, sync bool) FileStore {
+	s := &fileLocalStore{
+		dir:     filepath.Clean(dir),
+		sync:    sync,
+		meta:    make(map[string]FileMeta),
+		size:    make(map[string]int64),
+		pending: make(map[string]bool),
+	}
+	s.load()
+	return s
+}
+
+// load removes leftover temp files and loads the metadata files. Without
+// rename on add, the files without metadata file were not completely written
+// and are removed. Otherwise they are kept without size check as added by the
+// previous versions.
+func (s *fileLocalStore) load() {
+	fi, err := os.ReadDir(s.dir)
+	if err != nil {
+		return
+	}
+	files := make(map[string]os.DirEntry, len(fi))
+	for _, f := range fi {
+		files[f.Name()] = f
+	}
+	for name := range files {
+		switch {
+		case strings.HasPrefix(name, tmpFilePrefix) || isCheckFile(name):
+			os.Remove(filepath.Join(s.dir, name))
+
+		case strings.HasPrefix(name, metaFilePrefix):
+			id := strings.TrimPrefix(name, metaFilePrefix)
+			if _, ok := files[id]; !ok || isInternalFile(id) {
+				// the file was never added
+				os.Remove(filepath.Join(s.dir, name))
+				continue
+			}
+			m, err := s.readMeta(id)
+			if err != nil {
+				s.quarantine(id)
+				continue
+			}
+			if st, err := os.Stat(filepath.Join(s.dir, id)); err != nil || !st.Mode().IsRegular() || st.Size() != m.Size {
+				s.quarantine(id)
+				continue
+			}
+			s.meta[id] = m.FileMeta
+			s.size[id] = m.Size
+
+		case !renameOnAdd && !isInternalFile(name):
+			if _, ok := files[metaFilePrefix+name]; !ok {
+				os.Remove(filepath.Join(s.dir, name))
+			}
+		}
+	}
+}
+
+func (s *fileLocalStore) readMeta(id string) (localMeta, error) {
+	var m localMeta
+	b, err := os.ReadFile(filepath.Join(s.dir, metaFilePrefix+id))
+	if err != nil {
+		return m, err
+	}
+	err = json.Unmarshal(b, &m)
+	return m, err
+}
+
+// writeMeta writes the metadata file through a temp file
+func (s *fileLocalStore) writeMeta(id string, meta FileMeta, size int64) error {
+	b, err := json.Marshal(localMeta{FileMeta: meta, Size: size})
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(s.dir, tmpFilePrefix+metaFilePrefix+id)
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(b)
+	if err == nil && s.sync {
+		err = f.Sync()
+	}
+	if err1 := f.Close(); err == nil {
+		err = err1
+	}
+	if err == nil {
+		err = os.Rename(tmp, filepath.Join(s.dir, metaFilePrefix+id))
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if s.sync {
+		return syncDir(s.dir)
 	}
+	return nil
 }
 
 func (s *fileLocalStore) Add(name, path string) (string, error) {
+	return s.AddMeta(FileMeta{Name: name}, path)
+}
+
+func (s *fileLocalStore) AddMeta(meta FileMeta, path string) (string, error) {
+	if s.dir != filepath.Dir(path) {
+		return "", fmt.Errorf("add: %s does not have prefix %s", path, s.dir)
+	}
+	id := strings.TrimPrefix(filepath.Base(path), tmpFilePrefix)
+	size, err := s.commit(path, id, meta)
+	if err != nil {
+		return "", fmt.Errorf("add: %v", err)
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
+	s.meta[id] = meta
+	s.size[id] = size
+	delete(s.pending, id)
+	return id, nil
+}
+
+// commit fsyncs the file, writes its metadata file and renames it to id if it
+// is a temp file, returns the file size. The metadata file is written first,
+// so that the file of the id always has its expected size recorded.
+func (s *fileLocalStore) commit(p, id string, meta FileMeta) (int64, error) {
+	// windows requires write access to flush
+	f, err := os.OpenFile(p, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if s.sync {
+		if err := f.Sync(); err != nil {
+			return 0, err
+		}
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if err := s.writeMeta(id, meta, fi.Size()); err != nil {
+		return 0, err
+	}
+	if filepath.Base(p) == id {
+		return fi.Size(), nil
+	}
+	if err := os.Rename(p, filepath.Join(s.dir, id)); err != nil {
+		return 0, err
+	}
+	if s.sync {
+		if err := syncDir(s.dir); err != nil {
+			return 0, err
+		}
+	}
+	return fi.Size(), nil
+}
+
+// syncDir fsyncs the directory so that the renamed entry is persisted
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
 
-	if s.dir == filepath.Dir(path) {
-		id := filepath.Base(path)
-		s.name[id] = name
-		return id, nil
+// stat checks the file of id exists and passes the sanity check, the file is
+// quarantined if it failed
+func (s *fileLocalStore) stat(id string) bool {
+	if isInternalFile(id) {
+		return false
 	}
-	return "", fmt.Errorf("add: %s does not have prefix %s", path, s.dir)
+	p := path.Join(s.dir, id)
+	s.mu.RLock()
+	fi, err := os.Stat(p)
+	size, sized := s.size[id]
+	pending := s.pending[id]
+	s.mu.RUnlock()
+
+	switch {
+	case pending || os.IsNotExist(err):
+		return false
+	case err != nil: // reported by the reader
+		return true
+	case fi.Mode().IsRegular() && (!sized || fi.Size() == size):
+		return true
+	}
+	s.quarantine(id)
+	return false
+}
+
+// quarantine moves the file out of the store but keeps it for inspection
+func (s *fileLocalStore) quarantine(id string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.meta, id)
+	delete(s.size, id)
+	os.Rename(path.Join(s.dir, id), path.Join(s.dir, quarantineFilePrefix+id))
+	os.Remove(path.Join(s.dir, metaFilePrefix+id))
 }
 
 func (s *fileLocalStore) Get(id string) (string, envexec.File) {
+	if !s.stat(id) {
+		return "", nil
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	p := path.Join(s.dir, id)
-	if _, err := os.Stat(p); os.IsNotExist(err) {
-		return "", nil
+	name, digest := id, ""
+	if m, ok := s.meta[id]; ok {
+		name, digest = m.Name, m.SHA256
 	}
-	name, ok := s.name[id]
-	if !ok {
-		name = id
+	return name, &envexec.FileInput{Path: path.Join(s.dir, id), SHA256: digest}
+}
+
+func (s *fileLocalStore) GetMeta(id string) (FileMeta, bool) {
+	s.mu.RLock()
+	m, ok := s.meta[id]
+	s.mu.RUnlock()
+
+	if !ok || !s.stat(id) {
+		return FileMeta{}, false
 	}
-	return name, envexec.NewFileInput(p)
+	return m, true
 }
 
 func (s *fileLocalStore) Remove(id string) bool {
+	if isInternalFile(id) {
+		return false
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	delete(s.name, id)
+	delete(s.meta, id)
+	delete(s.size, id)
+	delete(s.pending, id)
 	p := path.Join(s.dir, id)
 	if _, err := os.Stat(p); os.IsNotExist(err) {
 		return false
 	}
 	os.Remove(p)
+	os.Remove(path.Join(s.dir, metaFilePrefix+id))
 	return true
 }
 
+// Check checks the directory is able to create files
+func (s *fileLocalStore) Check() error {
+	f, err := os.CreateTemp(s.dir, checkFilePrefix+"*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
 func (s *fileLocalStore) List() map[string]string {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -76,19 +329,54 @@ func (s *fileLocalStore) List() map[string]string {
 
 	names := make(map[string]string, len(fi))
 	for _, f := range fi {
-		names[f.Name()] = s.name[f.Name()]
+		if isInternalFile(f.Name()) || s.pending[f.Name()] {
+			continue
+		}
+		names[f.Name()] = s.meta[f.Name()].Name
 	}
 	return names
 }
 
+func (s *fileLocalStore) ListMeta() map[string]FileMeta {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	fi, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil
+	}
+
+	metas := make(map[string]FileMeta, len(fi))
+	for _, f := range fi {
+		if isInternalFile(f.Name()) || s.pending[f.Name()] {
+			continue
+		}
+		metas[f.Name()] = s.meta[f.Name()]
+	}
+	return metas
+}
+
 func (s *fileLocalStore) New() (*os.File, error) {
 	for range [50]struct{}{} {
 		id, err := generateID()
 		if err != nil {
 			return nil, err
 		}
-		f, err := os.OpenFile(path.Join(s.dir, id), os.O_CREATE|os.O_RDWR|os.O_EXCL, 0644)
+		if _, err := os.Stat(path.Join(s.dir, id)); !os.IsNotExist(err) {
+			continue
+		}
+		name := id
+		if renameOnAdd {
+			name = tmpFilePrefix + id
+		}
+		f, err := os.OpenFile(path.Join(s.dir, name), os.O_CREATE|os.O_RDWR|os.O_EXCL, 0644)
 		if err == nil {
+			if !renameOnAdd {
+				// hidden until added
+				s.mu.Lock()
+				s.pending[id] = true
+				s.mu.Unlock()
+			}
 			return f, nil
 		}
 		if !errors.Is(err, os.ErrExist) {
@@ -97,3 +385,14 @@ func (s *fileLocalStore) New() (*os.File, error) {
 	}
 	return nil, errUniqueIDNotGenerated
 }
+
+func isCheckFile(name string) bool {
+	return strings.HasPrefix(name, checkFilePrefix)
+}
+
+// isInternalFile reports whether the file is not an added file
+func isInternalFile(name string) bool {
+	return isCheckFile(name) || strings.HasPrefix(name, tmpFilePrefix) ||
+		strings.HasPrefix(name, quarantineFilePrefix) || strings.HasPrefix(name, metaFilePrefix) ||
+		strings.HasPrefix(name, StateFilePrefix)
+}
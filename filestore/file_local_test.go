@@ -0,0 +1,96 @@
+package filestore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func addFile(t *testing.T, s FileStore, name, content string) string {
+	t.Helper()
+	f, err := s.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	id, err := s.AddMeta(FileMeta{Name: name}, f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestFileLocalStoreRestart(t *testing.T) {
+	dir := t.TempDir()
+	s := NewFileLocalStore(dir)
+	truncated := addFile(t, s, "a", "hello")
+	intact := addFile(t, s, "b", "world")
+
+	// the power loss leaves a truncated file, a temp file and a metadata file
+	// of the file never added
+	if err := os.Truncate(filepath.Join(dir, truncated), 2); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{tmpFilePrefix + "x", metaFilePrefix + "y"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s = NewFileLocalStore(dir)
+	if _, f := s.Get(truncated); f != nil {
+		t.Fatal("truncated file is returned after restart")
+	}
+	if _, err := os.Stat(filepath.Join(dir, quarantineFilePrefix+truncated)); err != nil {
+		t.Fatalf("truncated file is not quarantined: %v", err)
+	}
+	if name, f := s.Get(intact); f == nil || name != "b" {
+		t.Fatalf("got %q %v, want the intact file with its name", name, f)
+	}
+	if l := s.List(); len(l) != 1 || l[intact] != "b" {
+		t.Fatalf("list %v, want only the intact file", l)
+	}
+	for _, name := range []string{tmpFilePrefix + "x", metaFilePrefix + "y"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); !os.IsNotExist(err) {
+			t.Errorf("leftover %s is not removed: %v", name, err)
+		}
+	}
+
+	// removed with its metadata file
+	if !s.Remove(intact) {
+		t.Fatal("intact file is not removed")
+	}
+	if _, err := os.Stat(filepath.Join(dir, metaFilePrefix+intact)); !os.IsNotExist(err) {
+		t.Fatalf("metadata file is not removed: %v", err)
+	}
+}
+
+func TestFileLocalStoreHidesUnfinished(t *testing.T) {
+	s := NewFileLocalStore(t.TempDir())
+	f, err := s.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString("partial"); err != nil {
+		t.Fatal(err)
+	}
+	id := strings.TrimPrefix(filepath.Base(f.Name()), tmpFilePrefix)
+	if l, m := s.List(), s.ListMeta(); len(l) != 0 || len(m) != 0 {
+		t.Fatalf("unfinished file is listed: %v %v", l, m)
+	}
+	if _, f := s.Get(id); f != nil {
+		t.Fatal("unfinished file is returned")
+	}
+
+	if _, err := s.AddMeta(FileMeta{Name: "a"}, f.Name()); err != nil {
+		t.Fatal(err)
+	}
+	if l := s.List(); len(l) != 1 || l[id] != "a" {
+		t.Fatalf("list %v, want the added file", l)
+	}
+}
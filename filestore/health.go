@@ -0,0 +1,121 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// ErrUnavailable is wrapped by errors caused by the broken backing storage of
+// the file store
+var ErrUnavailable = errors.New("file store is unavailable")
+
+// Checker defines file store that could check its backing storage
+type Checker interface {
+	Check() error
+}
+
+// Health defines file store that tracks the availability of its backing storage
+type Health interface {
+	// Available returns error wraps ErrUnavailable if the backing storage is broken
+	Available() error
+}
+
+// Available returns the availability of the file store, file store that does
+// not track its availability is always available
+func Available(fs FileStore) error {
+	if h, ok := fs.(Health); ok {
+		return h.Available()
+	}
+	return nil
+}
+
+var (
+	_ FileStore = &healthStore{}
+	_ Health    = &healthStore{}
+)
+
+type healthStore struct {
+	FileStore
+	checker  Checker
+	interval time.Duration
+
+	mu        sync.Mutex
+	err       error
+	lastCheck time.Time
+}
+
+// NewHealth creates file store that marks itself unavailable once the backing
+// storage failed, and checks it again at most once per interval until it
+// recovers
+func NewHealth(fs FileStore, checker Checker, interval time.Duration) FileStore {
+	return &healthStore{
+		FileStore: fs,
+		checker:   checker,
+		interval:  interval,
+	}
+}
+
+func (s *healthStore) Available() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err == nil || time.Since(s.lastCheck) < s.interval {
+		return s.err
+	}
+	s.check()
+	return s.err
+}
+
+// check updates the availability, mu must be held
+func (s *healthStore) check() {
+	s.lastCheck = time.Now()
+	if err := s.checker.Check(); err != nil {
+		s.err = fmt.Errorf("%w: %v", ErrUnavailable, err)
+	} else {
+		s.err = nil
+	}
+}
+
+func (s *healthStore) report(err error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastCheck = time.Now()
+	s.err = fmt.Errorf("%w: %v", ErrUnavailable, err)
+	return s.err
+}
+
+func (s *healthStore) Get(id string) (string, envexec.File) {
+	name, f := s.FileStore.Get(id)
+	if f != nil {
+		return name, f
+	}
+	// distinguish missing file from broken storage
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err == nil {
+		s.check()
+	}
+	return name, f
+}
+
+func (s *healthStore) Pin(id string, until time.Time) {
+	Pin(s.FileStore, id, until)
+}
+
+func (s *healthStore) ImportMeta(id string, meta FileMeta, size int64) error {
+	return ImportMeta(s.FileStore, id, meta, size)
+}
+
+func (s *healthStore) New() (*os.File, error) {
+	f, err := s.FileStore.New()
+	if err != nil {
+		return nil, s.report(err)
+	}
+	return f, nil
+}
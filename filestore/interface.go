@@ -13,13 +13,24 @@ const randIDLength = 5
 
 var errUniqueIDNotGenerated = errors.New("unique id does not exists after tried 50 times")
 
+// FileMeta defines metadata of a stored file
+type FileMeta struct {
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256,omitempty"`
+	Owner  string `json:"owner,omitempty"` // owner namespace, empty for shared files
+	Group  bool   `json:"group,omitempty"` // tar of the files cached by copyOutCachedGroup
+}
+
 // FileStore defines interface to store file
 type FileStore interface {
-	Add(name, path string) (string, error) // Add creates a file with path to the storage, returns id
-	Remove(string) bool                    // Remove deletes a file by id
-	Get(string) (string, envexec.File)     // Get file by id, nil if not exists
-	List() map[string]string               // List return all file ids to original name
-	New() (*os.File, error)                // Create a temporary file to the file store, can be added through Add to save it
+	Add(name, path string) (string, error)              // Add creates a file with path to the storage, returns id
+	AddMeta(meta FileMeta, path string) (string, error) // AddMeta is Add with file metadata recorded at the same time
+	Remove(string) bool                                 // Remove deletes a file by id
+	Get(string) (string, envexec.File)                  // Get file by id, nil if not exists
+	GetMeta(string) (FileMeta, bool)                    // GetMeta returns metadata of an added file by id
+	List() map[string]string                            // List return all file ids to original name
+	ListMeta() map[string]FileMeta                      // ListMeta return all file ids to file metadata
+	New() (*os.File, error)                             // Create a temporary file to the file store, can be added through Add to save it
 }
 
 func generateID() (string, error) {
@@ -0,0 +1,122 @@
+package filestore
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+var _ FileStore = &namespaceStore{}
+
+// namespaceStore is a view of the file store that only exposes files owned
+// by the owner and shared files (files without owner)
+type namespaceStore struct {
+	FileStore
+	owner string
+}
+
+// NewNamespace creates a view of file store restricted to the owner namespace.
+// Files added through the view is tagged with the owner. Shared files are
+// readable but cannot be removed through the view.
+func NewNamespace(fs FileStore, owner string) FileStore {
+	return &namespaceStore{
+		FileStore: fs,
+		owner:     owner,
+	}
+}
+
+type namespaceKey struct{}
+
+// NewNamespaceContext returns a context carrying the file store owner namespace
+func NewNamespaceContext(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, owner)
+}
+
+// NamespaceFromContext returns the file store owner namespace carried by the context
+func NamespaceFromContext(ctx context.Context) (string, bool) {
+	owner, ok := ctx.Value(namespaceKey{}).(string)
+	return owner, ok
+}
+
+// FromContext returns the namespace view of fs if the context carries a
+// namespace, otherwise fs itself
+func FromContext(ctx context.Context, fs FileStore) FileStore {
+	owner, ok := NamespaceFromContext(ctx)
+	if !ok {
+		return fs
+	}
+	return NewNamespace(fs, owner)
+}
+
+func (s *namespaceStore) visible(id string) bool {
+	m, ok := s.FileStore.GetMeta(id)
+	return ok && (m.Owner == "" || m.Owner == s.owner)
+}
+
+func (s *namespaceStore) Add(name, path string) (string, error) {
+	return s.AddMeta(FileMeta{Name: name}, path)
+}
+
+func (s *namespaceStore) AddMeta(meta FileMeta, path string) (string, error) {
+	meta.Owner = s.owner
+	return s.FileStore.AddMeta(meta, path)
+}
+
+func (s *namespaceStore) Remove(id string) bool {
+	m, ok := s.FileStore.GetMeta(id)
+	if !ok || m.Owner != s.owner {
+		return false
+	}
+	return s.FileStore.Remove(id)
+}
+
+func (s *namespaceStore) Get(id string) (string, envexec.File) {
+	if !s.visible(id) {
+		return "", nil
+	}
+	return s.FileStore.Get(id)
+}
+
+func (s *namespaceStore) GetMeta(id string) (FileMeta, bool) {
+	if !s.visible(id) {
+		return FileMeta{}, false
+	}
+	return s.FileStore.GetMeta(id)
+}
+
+func (s *namespaceStore) List() map[string]string {
+	metas := s.ListMeta()
+	names := make(map[string]string, len(metas))
+	for id, m := range metas {
+		names[id] = m.Name
+	}
+	return names
+}
+
+func (s *namespaceStore) ListMeta() map[string]FileMeta {
+	all := s.FileStore.ListMeta()
+	metas := make(map[string]FileMeta, len(all))
+	for id := range all {
+		// files not yet added do not have metadata and are not visible
+		if m, ok := s.FileStore.GetMeta(id); ok && (m.Owner == "" || m.Owner == s.owner) {
+			metas[id] = m
+		}
+	}
+	return metas
+}
+
+func (s *namespaceStore) New() (*os.File, error) {
+	return s.FileStore.New()
+}
+
+func (s *namespaceStore) Available() error {
+	return Available(s.FileStore)
+}
+
+func (s *namespaceStore) Pin(id string, until time.Time) {
+	if s.visible(id) {
+		Pin(s.FileStore, id, until)
+	}
+}
@@ -0,0 +1,50 @@
+package filestore
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+)
+
+var errImportNotSupported = errors.New("file store does not support importing metadata")
+
+// MetaImporter defines file store that could restore the metadata of a file
+// already in its directory, e.g. exported by the previous instance sharing
+// the directory
+type MetaImporter interface {
+	// ImportMeta records the metadata of the file, it fails if the file does
+	// not exist or its size is not size
+	ImportMeta(id string, meta FileMeta, size int64) error
+}
+
+// ImportMeta restores the metadata of the file already in the directory of
+// the file store
+func ImportMeta(fs FileStore, id string, meta FileMeta, size int64) error {
+	if i, ok := fs.(MetaImporter); ok {
+		return i.ImportMeta(id, meta, size)
+	}
+	return errImportNotSupported
+}
+
+func (s *fileLocalStore) ImportMeta(id string, meta FileMeta, size int64) error {
+	if isInternalFile(id) || path.Base(id) != id {
+		return fmt.Errorf("import: invalid file id %q", id)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fi, err := os.Stat(path.Join(s.dir, id))
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	if !fi.Mode().IsRegular() || fi.Size() != size {
+		return fmt.Errorf("import: file %s is %d bytes, expected %d", id, fi.Size(), size)
+	}
+	if err := s.writeMeta(id, meta, size); err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	s.meta[id] = meta
+	s.size[id] = size
+	return nil
+}
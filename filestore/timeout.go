@@ -9,18 +9,36 @@ import (
 	"github.com/criyle/go-judge/envexec"
 )
 
+// Pinner defines file store that removes files on its own (e.g. by TTL)
+type Pinner interface {
+	// Pin keeps the file at least until the time
+	Pin(id string, until time.Time)
+}
+
+// Pin keeps the file at least until the time if the file store removes files
+// on its own, files could still be removed explicitly
+func Pin(fs FileStore, id string, until time.Time) {
+	if p, ok := fs.(Pinner); ok {
+		p.Pin(id, until)
+	}
+}
+
 var (
 	_ FileStore      = &Timeout{}
+	_ Pinner         = &Timeout{}
+	_ MetaImporter   = &Timeout{}
 	_ heap.Interface = &Timeout{}
 )
 
-// Timeout is a file system with a maximun TTL
+// Timeout is a file system with a maximun TTL, pinned files are kept until
+// they are pinned
 type Timeout struct {
 	mu sync.Mutex
 	FileStore
 	timeout   time.Duration
 	files     []timeoutFile
 	idToIndex map[string]int
+	pinned    map[string]time.Time // id to the time it is kept until
 }
 
 type timeoutFile struct {
@@ -35,6 +53,7 @@ func NewTimeout(fs FileStore, timeout time.Duration, checkInterval time.Duration
 		timeout:   timeout,
 		files:     make([]timeoutFile, 0),
 		idToIndex: make(map[string]int),
+		pinned:    make(map[string]time.Time),
 	}
 	go t.checkTimeoutLoop(checkInterval)
 	return t
@@ -55,6 +74,15 @@ func (t *Timeout) checkTimeoutAndRemove() {
 	now := time.Now()
 	for len(t.files) > 0 && t.files[0].time.Add(t.timeout).Before(now) {
 		f := t.files[0]
+		// pinned file expires no earlier than it is pinned until
+		if until, ok := t.pinned[f.id]; ok {
+			delete(t.pinned, f.id)
+			if until.After(now) {
+				t.files[0].time = until.Add(-t.timeout)
+				heap.Fix(t, 0)
+				continue
+			}
+		}
 		t.FileStore.Remove(f.id)
 		heap.Pop(t)
 	}
@@ -88,8 +116,12 @@ func (t *Timeout) Pop() interface{} {
 }
 
 func (t *Timeout) Add(name, path string) (string, error) {
+	return t.AddMeta(FileMeta{Name: name}, path)
+}
+
+func (t *Timeout) AddMeta(meta FileMeta, path string) (string, error) {
 	// try add to file store underlying
-	id, err := t.FileStore.Add(name, path)
+	id, err := t.FileStore.AddMeta(meta, path)
 	if err != nil {
 		return "", err
 	}
@@ -103,12 +135,29 @@ func (t *Timeout) Add(name, path string) (string, error) {
 	return id, nil
 }
 
+// ImportMeta forwards the import and expires the file after timeout since
+// imported
+func (t *Timeout) ImportMeta(id string, meta FileMeta, size int64) error {
+	if err := ImportMeta(t.FileStore, id, meta, size); err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.idToIndex[id]; !ok {
+		heap.Push(t, timeoutFile{id, time.Now()})
+	}
+	return nil
+}
+
 func (t *Timeout) Remove(id string) bool {
 	success := t.FileStore.Remove(id)
 
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
+	delete(t.pinned, id)
 	index, ok := t.idToIndex[id]
 	if !ok {
 		return success
@@ -117,6 +166,18 @@ func (t *Timeout) Remove(id string) bool {
 	return success
 }
 
+func (t *Timeout) Pin(id string, until time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.idToIndex[id]; !ok {
+		return
+	}
+	if until.After(t.pinned[id]) {
+		t.pinned[id] = until
+	}
+}
+
 func (t *Timeout) Get(id string) (string, envexec.File) {
 	name, file := t.FileStore.Get(id)
 
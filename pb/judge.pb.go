@@ -21,46 +21,166 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type Request_RunMode int32
+
+const (
+	Request_Parallel   Request_RunMode = 0
+	Request_Sequential Request_RunMode = 1
+)
+
+// Enum value maps for Request_RunMode.
+var (
+	Request_RunMode_name = map[int32]string{
+		0: "Parallel",
+		1: "Sequential",
+	}
+	Request_RunMode_value = map[string]int32{
+		"Parallel":   0,
+		"Sequential": 1,
+	}
+)
+
+func (x Request_RunMode) Enum() *Request_RunMode {
+	p := new(Request_RunMode)
+	*p = x
+	return p
+}
+
+func (x Request_RunMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Request_RunMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_judge_proto_enumTypes[0].Descriptor()
+}
+
+func (Request_RunMode) Type() protoreflect.EnumType {
+	return &file_judge_proto_enumTypes[0]
+}
+
+func (x Request_RunMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Request_RunMode.Descriptor instead.
+func (Request_RunMode) EnumDescriptor() ([]byte, []int) {
+	return file_judge_proto_rawDescGZIP(), []int{3, 0}
+}
+
+// hint of the order in which the waiting requests are taken
+type Request_Priority int32
+
+const (
+	Request_Normal Request_Priority = 0
+	Request_Low    Request_Priority = 1 // e.g. compile
+	Request_High   Request_Priority = 2 // e.g. run
+)
+
+// Enum value maps for Request_Priority.
+var (
+	Request_Priority_name = map[int32]string{
+		0: "Normal",
+		1: "Low",
+		2: "High",
+	}
+	Request_Priority_value = map[string]int32{
+		"Normal": 0,
+		"Low":    1,
+		"High":   2,
+	}
+)
+
+func (x Request_Priority) Enum() *Request_Priority {
+	p := new(Request_Priority)
+	*p = x
+	return p
+}
+
+func (x Request_Priority) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Request_Priority) Descriptor() protoreflect.EnumDescriptor {
+	return file_judge_proto_enumTypes[1].Descriptor()
+}
+
+func (Request_Priority) Type() protoreflect.EnumType {
+	return &file_judge_proto_enumTypes[1]
+}
+
+func (x Request_Priority) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Request_Priority.Descriptor instead.
+func (Request_Priority) EnumDescriptor() ([]byte, []int) {
+	return file_judge_proto_rawDescGZIP(), []int{3, 1}
+}
+
 type Response_FileError_ErrorType int32
 
 const (
-	Response_FileError_CopyInOpenFile        Response_FileError_ErrorType = 0
-	Response_FileError_CopyInCreateFile      Response_FileError_ErrorType = 1
-	Response_FileError_CopyInCopyContent     Response_FileError_ErrorType = 2
-	Response_FileError_CopyOutOpen           Response_FileError_ErrorType = 3
-	Response_FileError_CopyOutNotRegularFile Response_FileError_ErrorType = 4
-	Response_FileError_CopyOutSizeExceeded   Response_FileError_ErrorType = 5
-	Response_FileError_CopyOutCreateFile     Response_FileError_ErrorType = 6
-	Response_FileError_CopyOutCopyContent    Response_FileError_ErrorType = 7
-	Response_FileError_CollectSizeExceeded   Response_FileError_ErrorType = 8
-	Response_FileError_Symlink               Response_FileError_ErrorType = 9
+	Response_FileError_CopyInOpenFile         Response_FileError_ErrorType = 0
+	Response_FileError_CopyInCreateFile       Response_FileError_ErrorType = 1
+	Response_FileError_CopyInCopyContent      Response_FileError_ErrorType = 2
+	Response_FileError_CopyOutOpen            Response_FileError_ErrorType = 3
+	Response_FileError_CopyOutNotRegularFile  Response_FileError_ErrorType = 4
+	Response_FileError_CopyOutSizeExceeded    Response_FileError_ErrorType = 5
+	Response_FileError_CopyOutCreateFile      Response_FileError_ErrorType = 6
+	Response_FileError_CopyOutCopyContent     Response_FileError_ErrorType = 7
+	Response_FileError_CollectSizeExceeded    Response_FileError_ErrorType = 8
+	Response_FileError_Symlink                Response_FileError_ErrorType = 9
+	Response_FileError_CopyOutLimitExceeded   Response_FileError_ErrorType = 12
+	Response_FileError_CopyInCorrupted        Response_FileError_ErrorType = 13
+	Response_FileError_ExecNotFound           Response_FileError_ErrorType = 14
+	Response_FileError_ExecMissingInterpreter Response_FileError_ErrorType = 15
+	Response_FileError_ExecNotExecutable      Response_FileError_ErrorType = 16
+	Response_FileError_ExecInvalidFormat      Response_FileError_ErrorType = 17
+	Response_FileError_CopyInSnapshot         Response_FileError_ErrorType = 18
+	Response_FileError_ExecNeedsEmulation     Response_FileError_ErrorType = 19
 )
 
 // Enum value maps for Response_FileError_ErrorType.
 var (
 	Response_FileError_ErrorType_name = map[int32]string{
-		0: "CopyInOpenFile",
-		1: "CopyInCreateFile",
-		2: "CopyInCopyContent",
-		3: "CopyOutOpen",
-		4: "CopyOutNotRegularFile",
-		5: "CopyOutSizeExceeded",
-		6: "CopyOutCreateFile",
-		7: "CopyOutCopyContent",
-		8: "CollectSizeExceeded",
-		9: "Symlink",
+		0:  "CopyInOpenFile",
+		1:  "CopyInCreateFile",
+		2:  "CopyInCopyContent",
+		3:  "CopyOutOpen",
+		4:  "CopyOutNotRegularFile",
+		5:  "CopyOutSizeExceeded",
+		6:  "CopyOutCreateFile",
+		7:  "CopyOutCopyContent",
+		8:  "CollectSizeExceeded",
+		9:  "Symlink",
+		12: "CopyOutLimitExceeded",
+		13: "CopyInCorrupted",
+		14: "ExecNotFound",
+		15: "ExecMissingInterpreter",
+		16: "ExecNotExecutable",
+		17: "ExecInvalidFormat",
+		18: "CopyInSnapshot",
+		19: "ExecNeedsEmulation",
 	}
 	Response_FileError_ErrorType_value = map[string]int32{
-		"CopyInOpenFile":        0,
-		"CopyInCreateFile":      1,
-		"CopyInCopyContent":     2,
-		"CopyOutOpen":           3,
-		"CopyOutNotRegularFile": 4,
-		"CopyOutSizeExceeded":   5,
-		"CopyOutCreateFile":     6,
-		"CopyOutCopyContent":    7,
-		"CollectSizeExceeded":   8,
-		"Symlink":               9,
+		"CopyInOpenFile":         0,
+		"CopyInCreateFile":       1,
+		"CopyInCopyContent":      2,
+		"CopyOutOpen":            3,
+		"CopyOutNotRegularFile":  4,
+		"CopyOutSizeExceeded":    5,
+		"CopyOutCreateFile":      6,
+		"CopyOutCopyContent":     7,
+		"CollectSizeExceeded":    8,
+		"Symlink":                9,
+		"CopyOutLimitExceeded":   12,
+		"CopyInCorrupted":        13,
+		"ExecNotFound":           14,
+		"ExecMissingInterpreter": 15,
+		"ExecNotExecutable":      16,
+		"ExecInvalidFormat":      17,
+		"CopyInSnapshot":         18,
+		"ExecNeedsEmulation":     19,
 	}
 )
 
@@ -75,11 +195,11 @@ func (x Response_FileError_ErrorType) String() string {
 }
 
 func (Response_FileError_ErrorType) Descriptor() protoreflect.EnumDescriptor {
-	return file_judge_proto_enumTypes[0].Descriptor()
+	return file_judge_proto_enumTypes[2].Descriptor()
 }
 
 func (Response_FileError_ErrorType) Type() protoreflect.EnumType {
-	return &file_judge_proto_enumTypes[0]
+	return &file_judge_proto_enumTypes[2]
 }
 
 func (x Response_FileError_ErrorType) Number() protoreflect.EnumNumber {
@@ -94,20 +214,25 @@ func (Response_FileError_ErrorType) EnumDescriptor() ([]byte, []int) {
 type Response_Result_StatusType int32
 
 const (
-	Response_Result_Invalid             Response_Result_StatusType = 0
-	Response_Result_Accepted            Response_Result_StatusType = 1
-	Response_Result_WrongAnswer         Response_Result_StatusType = 2 // Not used
-	Response_Result_PartiallyCorrect    Response_Result_StatusType = 3 // Not used
-	Response_Result_MemoryLimitExceeded Response_Result_StatusType = 4
-	Response_Result_TimeLimitExceeded   Response_Result_StatusType = 5
-	Response_Result_OutputLimitExceeded Response_Result_StatusType = 6
-	Response_Result_FileError           Response_Result_StatusType = 7
-	Response_Result_NonZeroExitStatus   Response_Result_StatusType = 8
-	Response_Result_Signalled           Response_Result_StatusType = 9
-	Response_Result_DangerousSyscall    Response_Result_StatusType = 10
-	Response_Result_JudgementFailed     Response_Result_StatusType = 11 // Not used
-	Response_Result_InvalidInteraction  Response_Result_StatusType = 12 // Not used
-	Response_Result_InternalError       Response_Result_StatusType = 13
+	Response_Result_Invalid                 Response_Result_StatusType = 0
+	Response_Result_Accepted                Response_Result_StatusType = 1
+	Response_Result_WrongAnswer             Response_Result_StatusType = 2 // set by verdict rules only
+	Response_Result_PartiallyCorrect        Response_Result_StatusType = 3 // set by verdict rules only
+	Response_Result_MemoryLimitExceeded     Response_Result_StatusType = 4
+	Response_Result_TimeLimitExceeded       Response_Result_StatusType = 5
+	Response_Result_OutputLimitExceeded     Response_Result_StatusType = 6
+	Response_Result_FileError               Response_Result_StatusType = 7
+	Response_Result_NonZeroExitStatus       Response_Result_StatusType = 8
+	Response_Result_Signalled               Response_Result_StatusType = 9
+	Response_Result_DangerousSyscall        Response_Result_StatusType = 10
+	Response_Result_JudgementFailed         Response_Result_StatusType = 11 // set by verdict rules only
+	Response_Result_InvalidInteraction      Response_Result_StatusType = 12 // set by verdict rules only
+	Response_Result_InternalError           Response_Result_StatusType = 13
+	Response_Result_ProcessLimitExceeded    Response_Result_StatusType = 16
+	Response_Result_UnsupportedArchitecture Response_Result_StatusType = 17
+	Response_Result_PipeLimitExceeded       Response_Result_StatusType = 18
+	Response_Result_DiskLimitExceeded       Response_Result_StatusType = 19
+	Response_Result_Skipped                 Response_Result_StatusType = 20 // relative limit referenced a command not accepted
 )
 
 // Enum value maps for Response_Result_StatusType.
@@ -127,22 +252,32 @@ var (
 		11: "JudgementFailed",
 		12: "InvalidInteraction",
 		13: "InternalError",
+		16: "ProcessLimitExceeded",
+		17: "UnsupportedArchitecture",
+		18: "PipeLimitExceeded",
+		19: "DiskLimitExceeded",
+		20: "Skipped",
 	}
 	Response_Result_StatusType_value = map[string]int32{
-		"Invalid":             0,
-		"Accepted":            1,
-		"WrongAnswer":         2,
-		"PartiallyCorrect":    3,
-		"MemoryLimitExceeded": 4,
-		"TimeLimitExceeded":   5,
-		"OutputLimitExceeded": 6,
-		"FileError":           7,
-		"NonZeroExitStatus":   8,
-		"Signalled":           9,
-		"DangerousSyscall":    10,
-		"JudgementFailed":     11,
-		"InvalidInteraction":  12,
-		"InternalError":       13,
+		"Invalid":                 0,
+		"Accepted":                1,
+		"WrongAnswer":             2,
+		"PartiallyCorrect":        3,
+		"MemoryLimitExceeded":     4,
+		"TimeLimitExceeded":       5,
+		"OutputLimitExceeded":     6,
+		"FileError":               7,
+		"NonZeroExitStatus":       8,
+		"Signalled":               9,
+		"DangerousSyscall":        10,
+		"JudgementFailed":         11,
+		"InvalidInteraction":      12,
+		"InternalError":           13,
+		"ProcessLimitExceeded":    16,
+		"UnsupportedArchitecture": 17,
+		"PipeLimitExceeded":       18,
+		"DiskLimitExceeded":       19,
+		"Skipped":                 20,
 	}
 )
 
@@ -157,11 +292,11 @@ func (x Response_Result_StatusType) String() string {
 }
 
 func (Response_Result_StatusType) Descriptor() protoreflect.EnumDescriptor {
-	return file_judge_proto_enumTypes[1].Descriptor()
+	return file_judge_proto_enumTypes[3].Descriptor()
 }
 
 func (Response_Result_StatusType) Type() protoreflect.EnumType {
-	return &file_judge_proto_enumTypes[1]
+	return &file_judge_proto_enumTypes[3]
 }
 
 func (x Response_Result_StatusType) Number() protoreflect.EnumNumber {
@@ -170,7 +305,7 @@ func (x Response_Result_StatusType) Number() protoreflect.EnumNumber {
 
 // Deprecated: Use Response_Result_StatusType.Descriptor instead.
 func (Response_Result_StatusType) EnumDescriptor() ([]byte, []int) {
-	return file_judge_proto_rawDescGZIP(), []int{4, 1, 0}
+	return file_judge_proto_rawDescGZIP(), []int{4, 9, 0}
 }
 
 type FileID struct {
@@ -330,6 +465,28 @@ type Request struct {
 	RequestID   string             `protobuf:"bytes,1,opt,name=requestID,proto3" json:"requestID,omitempty"`
 	Cmd         []*Request_CmdType `protobuf:"bytes,2,rep,name=cmd,proto3" json:"cmd,omitempty"`
 	PipeMapping []*Request_PipeMap `protobuf:"bytes,3,rep,name=pipeMapping,proto3" json:"pipeMapping,omitempty"`
+	// commands connected by pipes are always run in parallel
+	RunMode Request_RunMode `protobuf:"varint,4,opt,name=runMode,proto3,enum=pb.Request_RunMode" json:"runMode,omitempty"`
+	// skips the sha256 verification of cached files on copyIn
+	SkipVerify bool `protobuf:"varint,5,opt,name=skipVerify,proto3" json:"skipVerify,omitempty"`
+	// kills the other commands running in parallel once any of them exceeded its limits
+	KillOnLimit bool             `protobuf:"varint,6,opt,name=killOnLimit,proto3" json:"killOnLimit,omitempty"`
+	Priority    Request_Priority `protobuf:"varint,7,opt,name=priority,proto3,enum=pb.Request_Priority" json:"priority,omitempty"`
+	// retries with the same key (per auth token) return the response of the
+	// first run instead of running again
+	IdempotencyKey string `protobuf:"bytes,8,opt,name=idempotencyKey,proto3" json:"idempotencyKey,omitempty"`
+	// applies statOnly to all copy out files of the commands
+	CopyOutStat bool `protobuf:"varint,9,opt,name=copyOutStat,proto3" json:"copyOutStat,omitempty"`
+	// pins the commands to the NUMA node when -numa-aware is enabled
+	NumaNode *int32 `protobuf:"varint,10,opt,name=numaNode,proto3,oneof" json:"numaNode,omitempty"`
+	// names the verdict rules of -verdict-conf rewriting the results, the
+	// default rules apply if empty
+	Verdict string `protobuf:"bytes,11,opt,name=verdict,proto3" json:"verdict,omitempty"`
+	// keeps the result before rewritten by the verdict rules in raw
+	IncludeRaw bool `protobuf:"varint,12,opt,name=includeRaw,proto3" json:"includeRaw,omitempty"`
+	// hint of the wall time of the request in ns, the shorter ones are taken
+	// first within the same priority by -scheduler sjf
+	EstimatedDuration uint64 `protobuf:"varint,13,opt,name=estimatedDuration,proto3" json:"estimatedDuration,omitempty"`
 }
 
 func (x *Request) Reset() {
@@ -385,6 +542,76 @@ func (x *Request) GetPipeMapping() []*Request_PipeMap {
 	return nil
 }
 
+func (x *Request) GetRunMode() Request_RunMode {
+	if x != nil {
+		return x.RunMode
+	}
+	return Request_Parallel
+}
+
+func (x *Request) GetSkipVerify() bool {
+	if x != nil {
+		return x.SkipVerify
+	}
+	return false
+}
+
+func (x *Request) GetKillOnLimit() bool {
+	if x != nil {
+		return x.KillOnLimit
+	}
+	return false
+}
+
+func (x *Request) GetPriority() Request_Priority {
+	if x != nil {
+		return x.Priority
+	}
+	return Request_Normal
+}
+
+func (x *Request) GetIdempotencyKey() string {
+	if x != nil {
+		return x.IdempotencyKey
+	}
+	return ""
+}
+
+func (x *Request) GetCopyOutStat() bool {
+	if x != nil {
+		return x.CopyOutStat
+	}
+	return false
+}
+
+func (x *Request) GetNumaNode() int32 {
+	if x != nil && x.NumaNode != nil {
+		return *x.NumaNode
+	}
+	return 0
+}
+
+func (x *Request) GetVerdict() string {
+	if x != nil {
+		return x.Verdict
+	}
+	return ""
+}
+
+func (x *Request) GetIncludeRaw() bool {
+	if x != nil {
+		return x.IncludeRaw
+	}
+	return false
+}
+
+func (x *Request) GetEstimatedDuration() uint64 {
+	if x != nil {
+		return x.EstimatedDuration
+	}
+	return 0
+}
+
 type Response struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -393,6 +620,16 @@ type Response struct {
 	RequestID string             `protobuf:"bytes,1,opt,name=requestID,proto3" json:"requestID,omitempty"`
 	Results   []*Response_Result `protobuf:"bytes,2,rep,name=results,proto3" json:"results,omitempty"`
 	Error     string             `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	// note explains the deviation from the requested run mode if any
+	Note string `protobuf:"bytes,4,opt,name=note,proto3" json:"note,omitempty"`
+	// set if the request was terminated by the server before or while running
+	CancelReason string `protobuf:"bytes,5,opt,name=cancelReason,proto3" json:"cancelReason,omitempty"`
+	// estimatedDuration of the request and the wall time it ran in ns, only
+	// set if estimatedDuration is given
+	EstimatedDuration uint64 `protobuf:"varint,6,opt,name=estimatedDuration,proto3" json:"estimatedDuration,omitempty"`
+	Duration          uint64 `protobuf:"varint,7,opt,name=duration,proto3" json:"duration,omitempty"`
+	// usage of the request executed, accounted to the token
+	Usage *Response_Usage `protobuf:"bytes,8,opt,name=usage,proto3" json:"usage,omitempty"`
 }
 
 func (x *Response) Reset() {
@@ -448,6 +685,41 @@ func (x *Response) GetError() string {
 	return ""
 }
 
+func (x *Response) GetNote() string {
+	if x != nil {
+		return x.Note
+	}
+	return ""
+}
+
+func (x *Response) GetCancelReason() string {
+	if x != nil {
+		return x.CancelReason
+	}
+	return ""
+}
+
+func (x *Response) GetEstimatedDuration() uint64 {
+	if x != nil {
+		return x.EstimatedDuration
+	}
+	return 0
+}
+
+func (x *Response) GetDuration() uint64 {
+	if x != nil {
+		return x.Duration
+	}
+	return 0
+}
+
+func (x *Response) GetUsage() *Response_Usage {
+	if x != nil {
+		return x.Usage
+	}
+	return nil
+}
+
 type StreamRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -765,6 +1037,53 @@ func (x *Request_CachedFile) GetFileID() string {
 	return ""
 }
 
+type Request_CachedGroup struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	GroupID string `protobuf:"bytes,1,opt,name=groupID,proto3" json:"groupID,omitempty"`
+}
+
+func (x *Request_CachedGroup) Reset() {
+	*x = Request_CachedGroup{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_judge_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Request_CachedGroup) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Request_CachedGroup) ProtoMessage() {}
+
+func (x *Request_CachedGroup) ProtoReflect() protoreflect.Message {
+	mi := &file_judge_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Request_CachedGroup.ProtoReflect.Descriptor instead.
+func (*Request_CachedGroup) Descriptor() ([]byte, []int) {
+	return file_judge_proto_rawDescGZIP(), []int{3, 3}
+}
+
+func (x *Request_CachedGroup) GetGroupID() string {
+	if x != nil {
+		return x.GroupID
+	}
+	return ""
+}
+
 type Request_PipeCollector struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -773,12 +1092,20 @@ type Request_PipeCollector struct {
 	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Max  int64  `protobuf:"varint,2,opt,name=max,proto3" json:"max,omitempty"`
 	Pipe bool   `protobuf:"varint,3,opt,name=pipe,proto3" json:"pipe,omitempty"`
+	// cache the full output into file store and keep max bytes inline
+	Cache bool `protobuf:"varint,4,opt,name=cache,proto3" json:"cache,omitempty"`
+	// discard the content and only count bytes and lines into outputStat
+	Discard bool `protobuf:"varint,5,opt,name=discard,proto3" json:"discard,omitempty"`
+	// cache the full output into file store only when it exceeds max, unset
+	// uses the server default for stderr. max of stderr (0) defaults to the
+	// server config
+	CacheTruncated *bool `protobuf:"varint,6,opt,name=cacheTruncated,proto3,oneof" json:"cacheTruncated,omitempty"`
 }
 
 func (x *Request_PipeCollector) Reset() {
 	*x = Request_PipeCollector{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_judge_proto_msgTypes[11]
+		mi := &file_judge_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -791,7 +1118,7 @@ func (x *Request_PipeCollector) String() string {
 func (*Request_PipeCollector) ProtoMessage() {}
 
 func (x *Request_PipeCollector) ProtoReflect() protoreflect.Message {
-	mi := &file_judge_proto_msgTypes[11]
+	mi := &file_judge_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -804,7 +1131,7 @@ func (x *Request_PipeCollector) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Request_PipeCollector.ProtoReflect.Descriptor instead.
 func (*Request_PipeCollector) Descriptor() ([]byte, []int) {
-	return file_judge_proto_rawDescGZIP(), []int{3, 3}
+	return file_judge_proto_rawDescGZIP(), []int{3, 4}
 }
 
 func (x *Request_PipeCollector) GetName() string {
@@ -828,6 +1155,27 @@ func (x *Request_PipeCollector) GetPipe() bool {
 	return false
 }
 
+func (x *Request_PipeCollector) GetCache() bool {
+	if x != nil {
+		return x.Cache
+	}
+	return false
+}
+
+func (x *Request_PipeCollector) GetDiscard() bool {
+	if x != nil {
+		return x.Discard
+	}
+	return false
+}
+
+func (x *Request_PipeCollector) GetCacheTruncated() bool {
+	if x != nil && x.CacheTruncated != nil {
+		return *x.CacheTruncated
+	}
+	return false
+}
+
 type Request_StreamInput struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -839,7 +1187,7 @@ type Request_StreamInput struct {
 func (x *Request_StreamInput) Reset() {
 	*x = Request_StreamInput{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_judge_proto_msgTypes[12]
+		mi := &file_judge_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -852,7 +1200,7 @@ func (x *Request_StreamInput) String() string {
 func (*Request_StreamInput) ProtoMessage() {}
 
 func (x *Request_StreamInput) ProtoReflect() protoreflect.Message {
-	mi := &file_judge_proto_msgTypes[12]
+	mi := &file_judge_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -865,7 +1213,7 @@ func (x *Request_StreamInput) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Request_StreamInput.ProtoReflect.Descriptor instead.
 func (*Request_StreamInput) Descriptor() ([]byte, []int) {
-	return file_judge_proto_rawDescGZIP(), []int{3, 4}
+	return file_judge_proto_rawDescGZIP(), []int{3, 5}
 }
 
 func (x *Request_StreamInput) GetName() string {
@@ -886,7 +1234,7 @@ type Request_StreamOutput struct {
 func (x *Request_StreamOutput) Reset() {
 	*x = Request_StreamOutput{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_judge_proto_msgTypes[13]
+		mi := &file_judge_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -899,7 +1247,7 @@ func (x *Request_StreamOutput) String() string {
 func (*Request_StreamOutput) ProtoMessage() {}
 
 func (x *Request_StreamOutput) ProtoReflect() protoreflect.Message {
-	mi := &file_judge_proto_msgTypes[13]
+	mi := &file_judge_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -912,7 +1260,7 @@ func (x *Request_StreamOutput) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Request_StreamOutput.ProtoReflect.Descriptor instead.
 func (*Request_StreamOutput) Descriptor() ([]byte, []int) {
-	return file_judge_proto_rawDescGZIP(), []int{3, 5}
+	return file_judge_proto_rawDescGZIP(), []int{3, 6}
 }
 
 func (x *Request_StreamOutput) GetName() string {
@@ -935,13 +1283,21 @@ type Request_File struct {
 	//	*Request_File_Pipe
 	//	*Request_File_StreamIn
 	//	*Request_File_StreamOut
+	//	*Request_File_Group
 	File isRequest_File_File `protobuf_oneof:"file"`
+	// archive extracts the file into directory, only valid in copyIn
+	// (tar / tar.gz / zip)
+	Archive string `protobuf:"bytes,7,opt,name=archive,proto3" json:"archive,omitempty"`
+	// fifo creates named pipe connected by pipeMapping, only valid in copyIn
+	Fifo bool `protobuf:"varint,8,opt,name=fifo,proto3" json:"fifo,omitempty"`
+	// extract extracts the group into directory, only valid in copyIn
+	Extract bool `protobuf:"varint,10,opt,name=extract,proto3" json:"extract,omitempty"`
 }
 
 func (x *Request_File) Reset() {
 	*x = Request_File{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_judge_proto_msgTypes[14]
+		mi := &file_judge_proto_msgTypes[15]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -954,7 +1310,7 @@ func (x *Request_File) String() string {
 func (*Request_File) ProtoMessage() {}
 
 func (x *Request_File) ProtoReflect() protoreflect.Message {
-	mi := &file_judge_proto_msgTypes[14]
+	mi := &file_judge_proto_msgTypes[15]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -967,7 +1323,7 @@ func (x *Request_File) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Request_File.ProtoReflect.Descriptor instead.
 func (*Request_File) Descriptor() ([]byte, []int) {
-	return file_judge_proto_rawDescGZIP(), []int{3, 6}
+	return file_judge_proto_rawDescGZIP(), []int{3, 7}
 }
 
 func (m *Request_File) GetFile() isRequest_File_File {
@@ -1019,6 +1375,34 @@ func (x *Request_File) GetStreamOut() *Request_StreamOutput {
 	return nil
 }
 
+func (x *Request_File) GetGroup() *Request_CachedGroup {
+	if x, ok := x.GetFile().(*Request_File_Group); ok {
+		return x.Group
+	}
+	return nil
+}
+
+func (x *Request_File) GetArchive() string {
+	if x != nil {
+		return x.Archive
+	}
+	return ""
+}
+
+func (x *Request_File) GetFifo() bool {
+	if x != nil {
+		return x.Fifo
+	}
+	return false
+}
+
+func (x *Request_File) GetExtract() bool {
+	if x != nil {
+		return x.Extract
+	}
+	return false
+}
+
 type isRequest_File_File interface {
 	isRequest_File_File()
 }
@@ -1049,6 +1433,11 @@ type Request_File_StreamOut struct {
 	StreamOut *Request_StreamOutput `protobuf:"bytes,6,opt,name=streamOut,proto3,oneof"`
 }
 
+type Request_File_Group struct {
+	// group cached by copyOutCachedGroup, only valid in copyIn with extract
+	Group *Request_CachedGroup `protobuf:"bytes,9,opt,name=group,proto3,oneof"`
+}
+
 func (*Request_File_Local) isRequest_File_File() {}
 
 func (*Request_File_Memory) isRequest_File_File() {}
@@ -1061,35 +1450,97 @@ func (*Request_File_StreamIn) isRequest_File_File() {}
 
 func (*Request_File_StreamOut) isRequest_File_File() {}
 
+func (*Request_File_Group) isRequest_File_File() {}
+
 type Request_CmdType struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Args              []string                  `protobuf:"bytes,1,rep,name=args,proto3" json:"args,omitempty"`
-	Env               []string                  `protobuf:"bytes,2,rep,name=env,proto3" json:"env,omitempty"`
-	Files             []*Request_File           `protobuf:"bytes,3,rep,name=files,proto3" json:"files,omitempty"`
-	Tty               bool                      `protobuf:"varint,13,opt,name=tty,proto3" json:"tty,omitempty"`
-	CpuTimeLimit      uint64                    `protobuf:"varint,4,opt,name=cpuTimeLimit,proto3" json:"cpuTimeLimit,omitempty"`
-	ClockTimeLimit    uint64                    `protobuf:"varint,5,opt,name=clockTimeLimit,proto3" json:"clockTimeLimit,omitempty"`
-	MemoryLimit       uint64                    `protobuf:"varint,6,opt,name=memoryLimit,proto3" json:"memoryLimit,omitempty"`
-	StackLimit        uint64                    `protobuf:"varint,12,opt,name=stackLimit,proto3" json:"stackLimit,omitempty"`
-	ProcLimit         uint64                    `protobuf:"varint,7,opt,name=procLimit,proto3" json:"procLimit,omitempty"`
-	CpuRateLimit      uint64                    `protobuf:"varint,15,opt,name=cpuRateLimit,proto3" json:"cpuRateLimit,omitempty"`
-	CpuSetLimit       string                    `protobuf:"bytes,17,opt,name=cpuSetLimit,proto3" json:"cpuSetLimit,omitempty"`
-	StrictMemoryLimit bool                      `protobuf:"varint,16,opt,name=strictMemoryLimit,proto3" json:"strictMemoryLimit,omitempty"`
-	CopyIn            map[string]*Request_File  `protobuf:"bytes,8,rep,name=copyIn,proto3" json:"copyIn,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	Symlinks          map[string]string         `protobuf:"bytes,18,rep,name=symlinks,proto3" json:"symlinks,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	CopyOut           []*Request_CmdCopyOutFile `protobuf:"bytes,9,rep,name=copyOut,proto3" json:"copyOut,omitempty"`
-	CopyOutCached     []*Request_CmdCopyOutFile `protobuf:"bytes,10,rep,name=copyOutCached,proto3" json:"copyOutCached,omitempty"`
-	CopyOutDir        string                    `protobuf:"bytes,11,opt,name=copyOutDir,proto3" json:"copyOutDir,omitempty"`
-	CopyOutMax        uint64                    `protobuf:"varint,14,opt,name=copyOutMax,proto3" json:"copyOutMax,omitempty"`
+	Args  []string        `protobuf:"bytes,1,rep,name=args,proto3" json:"args,omitempty"`
+	Env   []string        `protobuf:"bytes,2,rep,name=env,proto3" json:"env,omitempty"`
+	Files []*Request_File `protobuf:"bytes,3,rep,name=files,proto3" json:"files,omitempty"`
+	Tty   bool            `protobuf:"varint,13,opt,name=tty,proto3" json:"tty,omitempty"`
+	// files bound to fds from 3 by fd number, the fds not bound in between
+	// are /dev/null
+	ExtraFiles        map[int32]*Request_File `protobuf:"bytes,42,rep,name=extraFiles,proto3" json:"extraFiles,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	CpuTimeLimit      uint64                  `protobuf:"varint,4,opt,name=cpuTimeLimit,proto3" json:"cpuTimeLimit,omitempty"`
+	ClockTimeLimit    uint64                  `protobuf:"varint,5,opt,name=clockTimeLimit,proto3" json:"clockTimeLimit,omitempty"`
+	MemoryLimit       uint64                  `protobuf:"varint,6,opt,name=memoryLimit,proto3" json:"memoryLimit,omitempty"`
+	StackLimit        uint64                  `protobuf:"varint,12,opt,name=stackLimit,proto3" json:"stackLimit,omitempty"`
+	ProcLimit         uint64                  `protobuf:"varint,7,opt,name=procLimit,proto3" json:"procLimit,omitempty"`
+	CpuRateLimit      uint64                  `protobuf:"varint,15,opt,name=cpuRateLimit,proto3" json:"cpuRateLimit,omitempty"`
+	CpuSetLimit       string                  `protobuf:"bytes,17,opt,name=cpuSetLimit,proto3" json:"cpuSetLimit,omitempty"`
+	StrictMemoryLimit bool                    `protobuf:"varint,16,opt,name=strictMemoryLimit,proto3" json:"strictMemoryLimit,omitempty"`
+	// collect memory statistics breakdown (Linux cgroup only)
+	DetailedMemory bool `protobuf:"varint,19,opt,name=detailedMemory,proto3" json:"detailedMemory,omitempty"`
+	// pin host uid / gid of the container within the configured range (0 unset)
+	Uid           uint32                    `protobuf:"varint,20,opt,name=uid,proto3" json:"uid,omitempty"`
+	Gid           uint32                    `protobuf:"varint,21,opt,name=gid,proto3" json:"gid,omitempty"`
+	CopyIn        map[string]*Request_File  `protobuf:"bytes,8,rep,name=copyIn,proto3" json:"copyIn,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Symlinks      map[string]string         `protobuf:"bytes,18,rep,name=symlinks,proto3" json:"symlinks,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	CopyOut       []*Request_CmdCopyOutFile `protobuf:"bytes,9,rep,name=copyOut,proto3" json:"copyOut,omitempty"`
+	CopyOutCached []*Request_CmdCopyOutFile `protobuf:"bytes,10,rep,name=copyOutCached,proto3" json:"copyOutCached,omitempty"`
+	CopyOutDir    string                    `protobuf:"bytes,11,opt,name=copyOutDir,proto3" json:"copyOutDir,omitempty"`
+	CopyOutMax    uint64                    `protobuf:"varint,14,opt,name=copyOutMax,proto3" json:"copyOutMax,omitempty"`
+	// limits on all copy out files, could only lower the server limits
+	CopyOutMaxFiles uint32 `protobuf:"varint,22,opt,name=copyOutMaxFiles,proto3" json:"copyOutMaxFiles,omitempty"`
+	CopyOutMaxTotal uint64 `protobuf:"varint,23,opt,name=copyOutMaxTotal,proto3" json:"copyOutMaxTotal,omitempty"`
+	// mount work directory at randomized path (Linux only)
+	RandomizeWorkDir bool `protobuf:"varint,24,opt,name=randomizeWorkDir,proto3" json:"randomizeWorkDir,omitempty"`
+	// read-only datasets configured by the server mounted at /data/<name>
+	Datasets []string `protobuf:"bytes,25,rep,name=datasets,proto3" json:"datasets,omitempty"`
+	// mount profile instead of the configured mounts (Linux only), "minimal"
+	// mounts only the tmpfs work directory, /proc and /dev/null
+	Profile string `protobuf:"bytes,26,opt,name=profile,proto3" json:"profile,omitempty"`
+	// limit on bytes written to block devices (Linux io / blkio cgroup only)
+	IoLimit uint64 `protobuf:"varint,27,opt,name=ioLimit,proto3" json:"ioLimit,omitempty"`
+	// collect the core file into the file store on signalled exit, enabled by
+	// the server with core file max
+	CollectCore bool `protobuf:"varint,28,opt,name=collectCore,proto3" json:"collectCore,omitempty"`
+	// file name -> fileID mounted read-only at /files/<name> (Linux only)
+	MountFiles map[string]string `protobuf:"bytes,29,rep,name=mountFiles,proto3" json:"mountFiles,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// count the syscalls into syscallCount, enabled by the server with enable
+	// trace. Time and memory are not accurate (Linux amd64 only)
+	Trace bool `protobuf:"varint,30,opt,name=trace,proto3" json:"trace,omitempty"`
+	// substitute {workdir}, {file:<name>} of copyIn / symlinks and {{ (a
+	// literal {) in args and env, unknown variables are rejected
+	Expand bool `protobuf:"varint,31,opt,name=expand,proto3" json:"expand,omitempty"`
+	// mount synthetic /proc/meminfo and /proc/cpuinfo reporting memoryLimit
+	// and procInfoCpus (server default if 0) processors (Linux only)
+	ProcInfo     bool   `protobuf:"varint,32,opt,name=procInfo,proto3" json:"procInfo,omitempty"`
+	ProcInfoCpus uint64 `protobuf:"varint,33,opt,name=procInfoCpus,proto3" json:"procInfoCpus,omitempty"`
+	// architecture of the program (x86, x86_64, x32, arm, arm64, riscv64) or
+	// auto to detect it from the executable for the limits and the result (Linux only)
+	Arch string `protobuf:"bytes,34,opt,name=arch,proto3" json:"arch,omitempty"`
+	// limit on bytes left in /dev/shm, the server default if 0 (Linux only)
+	ShmSize uint64 `protobuf:"varint,35,opt,name=shmSize,proto3" json:"shmSize,omitempty"`
+	// back /w and /tmp by a directory on the host disk instead of tmpfs (Linux only)
+	DiskWorkDir bool `protobuf:"varint,36,opt,name=diskWorkDir,proto3" json:"diskWorkDir,omitempty"`
+	// report the cpu time spent by the container for the run, which is not
+	// included in time, as overheadCpuTime (Linux only)
+	ReportTiming bool `protobuf:"varint,37,opt,name=reportTiming,proto3" json:"reportTiming,omitempty"`
+	// save[:<label>] saves the work directory after the command finished,
+	// restore[:<label>] restores it before the copy in of a later command,
+	// runMode must be sequential
+	SnapshotWorkDir string `protobuf:"bytes,38,opt,name=snapshotWorkDir,proto3" json:"snapshotWorkDir,omitempty"`
+	// replace /dev/urandom and /dev/random with the deterministic stream of
+	// the seed (Linux only)
+	RandomSeed *uint64 `protobuf:"varint,39,opt,name=randomSeed,proto3,oneof" json:"randomSeed,omitempty"`
+	// mount an empty file on the host disk with scratchSize (the server max
+	// if 0) allocated at the name in /w, so that fsync and O_DIRECT reach
+	// the device (Linux only)
+	ScratchFile string `protobuf:"bytes,40,opt,name=scratchFile,proto3" json:"scratchFile,omitempty"`
+	ScratchSize uint64 `protobuf:"varint,41,opt,name=scratchSize,proto3" json:"scratchSize,omitempty"`
+	// cache the regular files matching the patterns (e.g. *.class) as one
+	// group returned in groupID
+	CopyOutCachedGroup []*Request_CmdCopyOutFile `protobuf:"bytes,43,rep,name=copyOutCachedGroup,proto3" json:"copyOutCachedGroup,omitempty"`
 }
 
 func (x *Request_CmdType) Reset() {
 	*x = Request_CmdType{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_judge_proto_msgTypes[15]
+		mi := &file_judge_proto_msgTypes[16]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1102,7 +1553,7 @@ func (x *Request_CmdType) String() string {
 func (*Request_CmdType) ProtoMessage() {}
 
 func (x *Request_CmdType) ProtoReflect() protoreflect.Message {
-	mi := &file_judge_proto_msgTypes[15]
+	mi := &file_judge_proto_msgTypes[16]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1115,7 +1566,7 @@ func (x *Request_CmdType) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Request_CmdType.ProtoReflect.Descriptor instead.
 func (*Request_CmdType) Descriptor() ([]byte, []int) {
-	return file_judge_proto_rawDescGZIP(), []int{3, 7}
+	return file_judge_proto_rawDescGZIP(), []int{3, 8}
 }
 
 func (x *Request_CmdType) GetArgs() []string {
@@ -1146,6 +1597,13 @@ func (x *Request_CmdType) GetTty() bool {
 	return false
 }
 
+func (x *Request_CmdType) GetExtraFiles() map[int32]*Request_File {
+	if x != nil {
+		return x.ExtraFiles
+	}
+	return nil
+}
+
 func (x *Request_CmdType) GetCpuTimeLimit() uint64 {
 	if x != nil {
 		return x.CpuTimeLimit
@@ -1202,6 +1660,27 @@ func (x *Request_CmdType) GetStrictMemoryLimit() bool {
 	return false
 }
 
+func (x *Request_CmdType) GetDetailedMemory() bool {
+	if x != nil {
+		return x.DetailedMemory
+	}
+	return false
+}
+
+func (x *Request_CmdType) GetUid() uint32 {
+	if x != nil {
+		return x.Uid
+	}
+	return 0
+}
+
+func (x *Request_CmdType) GetGid() uint32 {
+	if x != nil {
+		return x.Gid
+	}
+	return 0
+}
+
 func (x *Request_CmdType) GetCopyIn() map[string]*Request_File {
 	if x != nil {
 		return x.CopyIn
@@ -1244,19 +1723,169 @@ func (x *Request_CmdType) GetCopyOutMax() uint64 {
 	return 0
 }
 
-type Request_CmdCopyOutFile struct {
-	state         protoimpl.MessageState
+func (x *Request_CmdType) GetCopyOutMaxFiles() uint32 {
+	if x != nil {
+		return x.CopyOutMaxFiles
+	}
+	return 0
+}
+
+func (x *Request_CmdType) GetCopyOutMaxTotal() uint64 {
+	if x != nil {
+		return x.CopyOutMaxTotal
+	}
+	return 0
+}
+
+func (x *Request_CmdType) GetRandomizeWorkDir() bool {
+	if x != nil {
+		return x.RandomizeWorkDir
+	}
+	return false
+}
+
+func (x *Request_CmdType) GetDatasets() []string {
+	if x != nil {
+		return x.Datasets
+	}
+	return nil
+}
+
+func (x *Request_CmdType) GetProfile() string {
+	if x != nil {
+		return x.Profile
+	}
+	return ""
+}
+
+func (x *Request_CmdType) GetIoLimit() uint64 {
+	if x != nil {
+		return x.IoLimit
+	}
+	return 0
+}
+
+func (x *Request_CmdType) GetCollectCore() bool {
+	if x != nil {
+		return x.CollectCore
+	}
+	return false
+}
+
+func (x *Request_CmdType) GetMountFiles() map[string]string {
+	if x != nil {
+		return x.MountFiles
+	}
+	return nil
+}
+
+func (x *Request_CmdType) GetTrace() bool {
+	if x != nil {
+		return x.Trace
+	}
+	return false
+}
+
+func (x *Request_CmdType) GetExpand() bool {
+	if x != nil {
+		return x.Expand
+	}
+	return false
+}
+
+func (x *Request_CmdType) GetProcInfo() bool {
+	if x != nil {
+		return x.ProcInfo
+	}
+	return false
+}
+
+func (x *Request_CmdType) GetProcInfoCpus() uint64 {
+	if x != nil {
+		return x.ProcInfoCpus
+	}
+	return 0
+}
+
+func (x *Request_CmdType) GetArch() string {
+	if x != nil {
+		return x.Arch
+	}
+	return ""
+}
+
+func (x *Request_CmdType) GetShmSize() uint64 {
+	if x != nil {
+		return x.ShmSize
+	}
+	return 0
+}
+
+func (x *Request_CmdType) GetDiskWorkDir() bool {
+	if x != nil {
+		return x.DiskWorkDir
+	}
+	return false
+}
+
+func (x *Request_CmdType) GetReportTiming() bool {
+	if x != nil {
+		return x.ReportTiming
+	}
+	return false
+}
+
+func (x *Request_CmdType) GetSnapshotWorkDir() string {
+	if x != nil {
+		return x.SnapshotWorkDir
+	}
+	return ""
+}
+
+func (x *Request_CmdType) GetRandomSeed() uint64 {
+	if x != nil && x.RandomSeed != nil {
+		return *x.RandomSeed
+	}
+	return 0
+}
+
+func (x *Request_CmdType) GetScratchFile() string {
+	if x != nil {
+		return x.ScratchFile
+	}
+	return ""
+}
+
+func (x *Request_CmdType) GetScratchSize() uint64 {
+	if x != nil {
+		return x.ScratchSize
+	}
+	return 0
+}
+
+func (x *Request_CmdType) GetCopyOutCachedGroup() []*Request_CmdCopyOutFile {
+	if x != nil {
+		return x.CopyOutCachedGroup
+	}
+	return nil
+}
+
+type Request_CmdCopyOutFile struct {
+	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
 	Name     string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
 	Optional bool   `protobuf:"varint,2,opt,name=optional,proto3" json:"optional,omitempty"`
+	// report the size, mode and mtime into fileStat instead of the content,
+	// copyOutCached files are still cached
+	StatOnly bool `protobuf:"varint,3,opt,name=statOnly,proto3" json:"statOnly,omitempty"`
 }
 
 func (x *Request_CmdCopyOutFile) Reset() {
 	*x = Request_CmdCopyOutFile{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_judge_proto_msgTypes[16]
+		mi := &file_judge_proto_msgTypes[17]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1269,7 +1898,7 @@ func (x *Request_CmdCopyOutFile) String() string {
 func (*Request_CmdCopyOutFile) ProtoMessage() {}
 
 func (x *Request_CmdCopyOutFile) ProtoReflect() protoreflect.Message {
-	mi := &file_judge_proto_msgTypes[16]
+	mi := &file_judge_proto_msgTypes[17]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1282,7 +1911,7 @@ func (x *Request_CmdCopyOutFile) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Request_CmdCopyOutFile.ProtoReflect.Descriptor instead.
 func (*Request_CmdCopyOutFile) Descriptor() ([]byte, []int) {
-	return file_judge_proto_rawDescGZIP(), []int{3, 8}
+	return file_judge_proto_rawDescGZIP(), []int{3, 9}
 }
 
 func (x *Request_CmdCopyOutFile) GetName() string {
@@ -1299,6 +1928,13 @@ func (x *Request_CmdCopyOutFile) GetOptional() bool {
 	return false
 }
 
+func (x *Request_CmdCopyOutFile) GetStatOnly() bool {
+	if x != nil {
+		return x.StatOnly
+	}
+	return false
+}
+
 type Request_PipeMap struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -1309,12 +1945,17 @@ type Request_PipeMap struct {
 	Proxy bool                       `protobuf:"varint,3,opt,name=proxy,proto3" json:"proxy,omitempty"`
 	Name  string                     `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
 	Max   uint64                     `protobuf:"varint,5,opt,name=max,proto3" json:"max,omitempty"`
+	// capacity of the pipe (Linux only)
+	BufferSize uint64 `protobuf:"varint,6,opt,name=bufferSize,proto3" json:"bufferSize,omitempty"`
+	// kills both commands with PipeLimitExceeded once more bytes than it
+	// are written into the proxy, requires proxy
+	TransferLimit uint64 `protobuf:"varint,7,opt,name=transferLimit,proto3" json:"transferLimit,omitempty"`
 }
 
 func (x *Request_PipeMap) Reset() {
 	*x = Request_PipeMap{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_judge_proto_msgTypes[17]
+		mi := &file_judge_proto_msgTypes[18]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1327,7 +1968,7 @@ func (x *Request_PipeMap) String() string {
 func (*Request_PipeMap) ProtoMessage() {}
 
 func (x *Request_PipeMap) ProtoReflect() protoreflect.Message {
-	mi := &file_judge_proto_msgTypes[17]
+	mi := &file_judge_proto_msgTypes[18]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1340,7 +1981,7 @@ func (x *Request_PipeMap) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use Request_PipeMap.ProtoReflect.Descriptor instead.
 func (*Request_PipeMap) Descriptor() ([]byte, []int) {
-	return file_judge_proto_rawDescGZIP(), []int{3, 9}
+	return file_judge_proto_rawDescGZIP(), []int{3, 10}
 }
 
 func (x *Request_PipeMap) GetIn() *Request_PipeMap_PipeIndex {
@@ -1378,6 +2019,20 @@ func (x *Request_PipeMap) GetMax() uint64 {
 	return 0
 }
 
+func (x *Request_PipeMap) GetBufferSize() uint64 {
+	if x != nil {
+		return x.BufferSize
+	}
+	return 0
+}
+
+func (x *Request_PipeMap) GetTransferLimit() uint64 {
+	if x != nil {
+		return x.TransferLimit
+	}
+	return 0
+}
+
 type Request_PipeMap_PipeIndex struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -1385,12 +2040,14 @@ type Request_PipeMap_PipeIndex struct {
 
 	Index int32 `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
 	Fd    int32 `protobuf:"varint,2,opt,name=fd,proto3" json:"fd,omitempty"`
+	// name of the fifo in copyIn instead of the fd
+	Fifo string `protobuf:"bytes,3,opt,name=fifo,proto3" json:"fifo,omitempty"`
 }
 
 func (x *Request_PipeMap_PipeIndex) Reset() {
 	*x = Request_PipeMap_PipeIndex{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_judge_proto_msgTypes[20]
+		mi := &file_judge_proto_msgTypes[23]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1403,7 +2060,135 @@ func (x *Request_PipeMap_PipeIndex) String() string {
 func (*Request_PipeMap_PipeIndex) ProtoMessage() {}
 
 func (x *Request_PipeMap_PipeIndex) ProtoReflect() protoreflect.Message {
-	mi := &file_judge_proto_msgTypes[20]
+	mi := &file_judge_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Request_PipeMap_PipeIndex.ProtoReflect.Descriptor instead.
+func (*Request_PipeMap_PipeIndex) Descriptor() ([]byte, []int) {
+	return file_judge_proto_rawDescGZIP(), []int{3, 10, 0}
+}
+
+func (x *Request_PipeMap_PipeIndex) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *Request_PipeMap_PipeIndex) GetFd() int32 {
+	if x != nil {
+		return x.Fd
+	}
+	return 0
+}
+
+func (x *Request_PipeMap_PipeIndex) GetFifo() string {
+	if x != nil {
+		return x.Fifo
+	}
+	return ""
+}
+
+type Response_FileError struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Name    string                       `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Type    Response_FileError_ErrorType `protobuf:"varint,2,opt,name=type,proto3,enum=pb.Response_FileError_ErrorType" json:"type,omitempty"`
+	Message string                       `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+}
+
+func (x *Response_FileError) Reset() {
+	*x = Response_FileError{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_judge_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Response_FileError) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response_FileError) ProtoMessage() {}
+
+func (x *Response_FileError) ProtoReflect() protoreflect.Message {
+	mi := &file_judge_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response_FileError.ProtoReflect.Descriptor instead.
+func (*Response_FileError) Descriptor() ([]byte, []int) {
+	return file_judge_proto_rawDescGZIP(), []int{4, 0}
+}
+
+func (x *Response_FileError) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Response_FileError) GetType() Response_FileError_ErrorType {
+	if x != nil {
+		return x.Type
+	}
+	return Response_FileError_CopyInOpenFile
+}
+
+func (x *Response_FileError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type Response_MemoryStat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Cache      uint64 `protobuf:"varint,1,opt,name=cache,proto3" json:"cache,omitempty"`
+	Rss        uint64 `protobuf:"varint,2,opt,name=rss,proto3" json:"rss,omitempty"`
+	MappedFile uint64 `protobuf:"varint,3,opt,name=mappedFile,proto3" json:"mappedFile,omitempty"`
+	Pgfault    uint64 `protobuf:"varint,4,opt,name=pgfault,proto3" json:"pgfault,omitempty"`
+	Pgmajfault uint64 `protobuf:"varint,5,opt,name=pgmajfault,proto3" json:"pgmajfault,omitempty"`
+}
+
+func (x *Response_MemoryStat) Reset() {
+	*x = Response_MemoryStat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_judge_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Response_MemoryStat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response_MemoryStat) ProtoMessage() {}
+
+func (x *Response_MemoryStat) ProtoReflect() protoreflect.Message {
+	mi := &file_judge_proto_msgTypes[25]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1411,198 +2196,951 @@ func (x *Request_PipeMap_PipeIndex) ProtoReflect() protoreflect.Message {
 		}
 		return ms
 	}
-	return mi.MessageOf(x)
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response_MemoryStat.ProtoReflect.Descriptor instead.
+func (*Response_MemoryStat) Descriptor() ([]byte, []int) {
+	return file_judge_proto_rawDescGZIP(), []int{4, 1}
+}
+
+func (x *Response_MemoryStat) GetCache() uint64 {
+	if x != nil {
+		return x.Cache
+	}
+	return 0
+}
+
+func (x *Response_MemoryStat) GetRss() uint64 {
+	if x != nil {
+		return x.Rss
+	}
+	return 0
+}
+
+func (x *Response_MemoryStat) GetMappedFile() uint64 {
+	if x != nil {
+		return x.MappedFile
+	}
+	return 0
+}
+
+func (x *Response_MemoryStat) GetPgfault() uint64 {
+	if x != nil {
+		return x.Pgfault
+	}
+	return 0
+}
+
+func (x *Response_MemoryStat) GetPgmajfault() uint64 {
+	if x != nil {
+		return x.Pgmajfault
+	}
+	return 0
+}
+
+type Response_IOStat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Read  uint64 `protobuf:"varint,1,opt,name=read,proto3" json:"read,omitempty"`
+	Write uint64 `protobuf:"varint,2,opt,name=write,proto3" json:"write,omitempty"`
+}
+
+func (x *Response_IOStat) Reset() {
+	*x = Response_IOStat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_judge_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Response_IOStat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response_IOStat) ProtoMessage() {}
+
+func (x *Response_IOStat) ProtoReflect() protoreflect.Message {
+	mi := &file_judge_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response_IOStat.ProtoReflect.Descriptor instead.
+func (*Response_IOStat) Descriptor() ([]byte, []int) {
+	return file_judge_proto_rawDescGZIP(), []int{4, 2}
+}
+
+func (x *Response_IOStat) GetRead() uint64 {
+	if x != nil {
+		return x.Read
+	}
+	return 0
+}
+
+func (x *Response_IOStat) GetWrite() uint64 {
+	if x != nil {
+		return x.Write
+	}
+	return 0
+}
+
+type Response_Throttling struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Periods   uint64 `protobuf:"varint,1,opt,name=periods,proto3" json:"periods,omitempty"`
+	Throttled uint64 `protobuf:"varint,2,opt,name=throttled,proto3" json:"throttled,omitempty"`
+	// ns
+	ThrottledTime uint64 `protobuf:"varint,3,opt,name=throttledTime,proto3" json:"throttledTime,omitempty"`
+}
+
+func (x *Response_Throttling) Reset() {
+	*x = Response_Throttling{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_judge_proto_msgTypes[27]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Response_Throttling) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response_Throttling) ProtoMessage() {}
+
+func (x *Response_Throttling) ProtoReflect() protoreflect.Message {
+	mi := &file_judge_proto_msgTypes[27]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response_Throttling.ProtoReflect.Descriptor instead.
+func (*Response_Throttling) Descriptor() ([]byte, []int) {
+	return file_judge_proto_rawDescGZIP(), []int{4, 3}
+}
+
+func (x *Response_Throttling) GetPeriods() uint64 {
+	if x != nil {
+		return x.Periods
+	}
+	return 0
+}
+
+func (x *Response_Throttling) GetThrottled() uint64 {
+	if x != nil {
+		return x.Throttled
+	}
+	return 0
+}
+
+func (x *Response_Throttling) GetThrottledTime() uint64 {
+	if x != nil {
+		return x.ThrottledTime
+	}
+	return 0
+}
+
+type Response_OutputStat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Bytes int64 `protobuf:"varint,1,opt,name=bytes,proto3" json:"bytes,omitempty"`
+	Lines int64 `protobuf:"varint,2,opt,name=lines,proto3" json:"lines,omitempty"`
+}
+
+func (x *Response_OutputStat) Reset() {
+	*x = Response_OutputStat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_judge_proto_msgTypes[28]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Response_OutputStat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response_OutputStat) ProtoMessage() {}
+
+func (x *Response_OutputStat) ProtoReflect() protoreflect.Message {
+	mi := &file_judge_proto_msgTypes[28]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response_OutputStat.ProtoReflect.Descriptor instead.
+func (*Response_OutputStat) Descriptor() ([]byte, []int) {
+	return file_judge_proto_rawDescGZIP(), []int{4, 4}
+}
+
+func (x *Response_OutputStat) GetBytes() int64 {
+	if x != nil {
+		return x.Bytes
+	}
+	return 0
+}
+
+func (x *Response_OutputStat) GetLines() int64 {
+	if x != nil {
+		return x.Lines
+	}
+	return 0
+}
+
+type Response_FileStat struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Size int64 `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	// permission bits
+	Mode uint32 `protobuf:"varint,2,opt,name=mode,proto3" json:"mode,omitempty"`
+	// RFC3339Nano
+	Mtime string `protobuf:"bytes,3,opt,name=mtime,proto3" json:"mtime,omitempty"`
+}
+
+func (x *Response_FileStat) Reset() {
+	*x = Response_FileStat{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_judge_proto_msgTypes[29]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Response_FileStat) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response_FileStat) ProtoMessage() {}
+
+func (x *Response_FileStat) ProtoReflect() protoreflect.Message {
+	mi := &file_judge_proto_msgTypes[29]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response_FileStat.ProtoReflect.Descriptor instead.
+func (*Response_FileStat) Descriptor() ([]byte, []int) {
+	return file_judge_proto_rawDescGZIP(), []int{4, 5}
+}
+
+func (x *Response_FileStat) GetSize() int64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *Response_FileStat) GetMode() uint32 {
+	if x != nil {
+		return x.Mode
+	}
+	return 0
+}
+
+func (x *Response_FileStat) GetMtime() string {
+	if x != nil {
+		return x.Mtime
+	}
+	return ""
+}
+
+type Response_FileType struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// detected from the first 512 bytes
+	ContentType string `protobuf:"bytes,1,opt,name=contentType,proto3" json:"contentType,omitempty"`
+	IsBinary    bool   `protobuf:"varint,2,opt,name=isBinary,proto3" json:"isBinary,omitempty"`
+}
+
+func (x *Response_FileType) Reset() {
+	*x = Response_FileType{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_judge_proto_msgTypes[30]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Response_FileType) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response_FileType) ProtoMessage() {}
+
+func (x *Response_FileType) ProtoReflect() protoreflect.Message {
+	mi := &file_judge_proto_msgTypes[30]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response_FileType.ProtoReflect.Descriptor instead.
+func (*Response_FileType) Descriptor() ([]byte, []int) {
+	return file_judge_proto_rawDescGZIP(), []int{4, 6}
+}
+
+func (x *Response_FileType) GetContentType() string {
+	if x != nil {
+		return x.ContentType
+	}
+	return ""
+}
+
+func (x *Response_FileType) GetIsBinary() bool {
+	if x != nil {
+		return x.IsBinary
+	}
+	return false
+}
+
+// resources consumed by the commands of the request, durations in ns and
+// sizes in bytes. memory is the max of the commands run one after another
+// and the sum of the ones run at the same time, the others are summed
+type Response_Usage struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	CpuTime       uint64  `protobuf:"varint,1,opt,name=cpuTime,proto3" json:"cpuTime,omitempty"`
+	Memory        uint64  `protobuf:"varint,2,opt,name=memory,proto3" json:"memory,omitempty"`
+	MemorySeconds float64 `protobuf:"fixed64,3,opt,name=memorySeconds,proto3" json:"memorySeconds,omitempty"`
+	// from submitted to the response, including the wait in queue
+	WallTime uint64 `protobuf:"varint,4,opt,name=wallTime,proto3" json:"wallTime,omitempty"`
+	CopyIn   uint64 `protobuf:"varint,5,opt,name=copyIn,proto3" json:"copyIn,omitempty"`
+	CopyOut  uint64 `protobuf:"varint,6,opt,name=copyOut,proto3" json:"copyOut,omitempty"`
+	// added to the file store by copyOutCached, cached collectors and core
+	Cached uint64 `protobuf:"varint,7,opt,name=cached,proto3" json:"cached,omitempty"`
+}
+
+func (x *Response_Usage) Reset() {
+	*x = Response_Usage{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_judge_proto_msgTypes[31]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Response_Usage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response_Usage) ProtoMessage() {}
+
+func (x *Response_Usage) ProtoReflect() protoreflect.Message {
+	mi := &file_judge_proto_msgTypes[31]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response_Usage.ProtoReflect.Descriptor instead.
+func (*Response_Usage) Descriptor() ([]byte, []int) {
+	return file_judge_proto_rawDescGZIP(), []int{4, 7}
+}
+
+func (x *Response_Usage) GetCpuTime() uint64 {
+	if x != nil {
+		return x.CpuTime
+	}
+	return 0
+}
+
+func (x *Response_Usage) GetMemory() uint64 {
+	if x != nil {
+		return x.Memory
+	}
+	return 0
+}
+
+func (x *Response_Usage) GetMemorySeconds() float64 {
+	if x != nil {
+		return x.MemorySeconds
+	}
+	return 0
+}
+
+func (x *Response_Usage) GetWallTime() uint64 {
+	if x != nil {
+		return x.WallTime
+	}
+	return 0
+}
+
+func (x *Response_Usage) GetCopyIn() uint64 {
+	if x != nil {
+		return x.CopyIn
+	}
+	return 0
+}
+
+func (x *Response_Usage) GetCopyOut() uint64 {
+	if x != nil {
+		return x.CopyOut
+	}
+	return 0
+}
+
+func (x *Response_Usage) GetCached() uint64 {
+	if x != nil {
+		return x.Cached
+	}
+	return 0
+}
+
+type Response_RawResult struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Status  Response_Result_StatusType `protobuf:"varint,1,opt,name=status,proto3,enum=pb.Response_Result_StatusType" json:"status,omitempty"`
+	Time    uint64                     `protobuf:"varint,2,opt,name=time,proto3" json:"time,omitempty"`
+	RunTime uint64                     `protobuf:"varint,3,opt,name=runTime,proto3" json:"runTime,omitempty"`
+}
+
+func (x *Response_RawResult) Reset() {
+	*x = Response_RawResult{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_judge_proto_msgTypes[32]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Response_RawResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response_RawResult) ProtoMessage() {}
+
+func (x *Response_RawResult) ProtoReflect() protoreflect.Message {
+	mi := &file_judge_proto_msgTypes[32]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response_RawResult.ProtoReflect.Descriptor instead.
+func (*Response_RawResult) Descriptor() ([]byte, []int) {
+	return file_judge_proto_rawDescGZIP(), []int{4, 8}
+}
+
+func (x *Response_RawResult) GetStatus() Response_Result_StatusType {
+	if x != nil {
+		return x.Status
+	}
+	return Response_Result_Invalid
+}
+
+func (x *Response_RawResult) GetTime() uint64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *Response_RawResult) GetRunTime() uint64 {
+	if x != nil {
+		return x.RunTime
+	}
+	return 0
+}
+
+type Response_Result struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Status     Response_Result_StatusType `protobuf:"varint,1,opt,name=status,proto3,enum=pb.Response_Result_StatusType" json:"status,omitempty"`
+	ExitStatus int32                      `protobuf:"varint,2,opt,name=exitStatus,proto3" json:"exitStatus,omitempty"`
+	Error      string                     `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
+	Time       uint64                     `protobuf:"varint,4,opt,name=time,proto3" json:"time,omitempty"`
+	RunTime    uint64                     `protobuf:"varint,8,opt,name=runTime,proto3" json:"runTime,omitempty"`
+	Memory     uint64                     `protobuf:"varint,5,opt,name=memory,proto3" json:"memory,omitempty"`
+	Files      map[string][]byte          `protobuf:"bytes,6,rep,name=files,proto3" json:"files,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	FileIDs    map[string]string          `protobuf:"bytes,7,rep,name=fileIDs,proto3" json:"fileIDs,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	FileError  []*Response_FileError      `protobuf:"bytes,9,rep,name=fileError,proto3" json:"fileError,omitempty"`
+	Extracted  map[string]uint32          `protobuf:"bytes,10,rep,name=extracted,proto3" json:"extracted,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	// signal number if the process was signalled
+	Signal int32 `protobuf:"varint,11,opt,name=signal,proto3" json:"signal,omitempty"`
+	// terminatedBy is one of exited, signaled, limitExceeded, cancelled, internal
+	TerminatedBy string               `protobuf:"bytes,12,opt,name=terminatedBy,proto3" json:"terminatedBy,omitempty"`
+	MemoryStat   *Response_MemoryStat `protobuf:"bytes,13,opt,name=memoryStat,proto3" json:"memoryStat,omitempty"`
+	// total size of outputs cached by collectors
+	FileSize map[string]int64 `protobuf:"bytes,14,rep,name=fileSize,proto3" json:"fileSize,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	Warning  string           `protobuf:"bytes,15,opt,name=warning,proto3" json:"warning,omitempty"`
+	// peak number of processes / threads (Linux cgroup only)
+	ProcPeak uint64 `protobuf:"varint,16,opt,name=procPeak,proto3" json:"procPeak,omitempty"`
+	// randomized work directory
+	WorkDir string `protobuf:"bytes,17,opt,name=workDir,proto3" json:"workDir,omitempty"`
+	// fd -> bytes forwarded by proxy from the fd to the other end of the pipe
+	PipeBytes map[int32]int64 `protobuf:"bytes,18,rep,name=pipeBytes,proto3" json:"pipeBytes,omitempty" protobuf_key:"varint,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	// bytes and newline terminated lines written to pipe / discard collectors
+	OutputStat map[string]*Response_OutputStat `protobuf:"bytes,19,rep,name=outputStat,proto3" json:"outputStat,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// RFC3339Nano time when the command started, empty if not executed
+	StartedAt  string `protobuf:"bytes,20,opt,name=startedAt,proto3" json:"startedAt,omitempty"`
+	Hostname   string `protobuf:"bytes,21,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	InstanceID string `protobuf:"bytes,22,opt,name=instanceID,proto3" json:"instanceID,omitempty"`
+	// bytes read and written to block devices (Linux io / blkio cgroup only)
+	IoStat *Response_IOStat `protobuf:"bytes,23,opt,name=ioStat,proto3" json:"ioStat,omitempty"`
+	// collected core file in the file store if collectCore is set
+	CoreFileID string `protobuf:"bytes,24,opt,name=coreFileID,proto3" json:"coreFileID,omitempty"`
+	CoreSize   int64  `protobuf:"varint,25,opt,name=coreSize,proto3" json:"coreSize,omitempty"`
+	// syscall name -> number of calls if trace is set
+	SyscallCount map[string]uint64 `protobuf:"bytes,26,rep,name=syscallCount,proto3" json:"syscallCount,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"varint,2,opt,name=value,proto3"`
+	// copy out files with statOnly
+	FileStat map[string]*Response_FileStat `protobuf:"bytes,27,rep,name=fileStat,proto3" json:"fileStat,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// processes left running after the process exited and killed (Linux cgroup only)
+	StrayProcs uint64 `protobuf:"varint,28,opt,name=strayProcs,proto3" json:"strayProcs,omitempty"`
+	// architecture of the program if arch is set and it is known (Linux only)
+	Arch string `protobuf:"bytes,29,opt,name=arch,proto3" json:"arch,omitempty"`
+	// bytes left in /dev/shm if mounted and checked (Linux only)
+	ShmUsage uint64 `protobuf:"varint,30,opt,name=shmUsage,proto3" json:"shmUsage,omitempty"`
+	// stderr of the container init on Internal Error (Linux only)
+	InitLog string `protobuf:"bytes,31,opt,name=initLog,proto3" json:"initLog,omitempty"`
+	// bytes left in /w and /tmp backed by disk if diskWorkDir is set, or the
+	// size of the scratch file if scratchFile is set (Linux only)
+	DiskUsage uint64 `protobuf:"varint,32,opt,name=diskUsage,proto3" json:"diskUsage,omitempty"`
+	// cpu time spent by the container for the run in ns if reportTiming is set
+	OverheadCpuTime uint64 `protobuf:"varint,33,opt,name=overheadCpuTime,proto3" json:"overheadCpuTime,omitempty"`
+	// set if terminatedBy is cancelled, one of shutdown, clientGone,
+	// cancelled, queueTimeout
+	CancelReason string `protobuf:"bytes,34,opt,name=cancelReason,proto3" json:"cancelReason,omitempty"`
+	// cfs throttling of cpuRateLimit, zero if not applied (Linux cgroup only)
+	Throttling *Response_Throttling `protobuf:"bytes,35,opt,name=throttling,proto3" json:"throttling,omitempty"`
+	// the program of arch ran under an emulator (Linux only)
+	Emulated bool `protobuf:"varint,36,opt,name=emulated,proto3" json:"emulated,omitempty"`
+	// attached by the verdict rule
+	Message string `protobuf:"bytes,37,opt,name=message,proto3" json:"message,omitempty"`
+	// result before rewritten by the verdict rules if includeRaw is set
+	Raw *Response_RawResult `protobuf:"bytes,38,opt,name=raw,proto3" json:"raw,omitempty"`
+	// seed of the deterministic /dev/urandom and /dev/random if set, and
+	// whether the program opened them (Linux only)
+	RandomSeed   *uint64 `protobuf:"varint,39,opt,name=randomSeed,proto3,oneof" json:"randomSeed,omitempty"`
+	RandomOpened bool    `protobuf:"varint,40,opt,name=randomOpened,proto3" json:"randomOpened,omitempty"`
+	// detected type of the files and cached files copied out
+	FileType map[string]*Response_FileType `protobuf:"bytes,41,rep,name=fileType,proto3" json:"fileType,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	// group of the files cached by copyOutCachedGroup
+	GroupID string `protobuf:"bytes,42,opt,name=groupID,proto3" json:"groupID,omitempty"`
+	// pooled environment and cgroup that ran the command, 0 if not available
+	EnvironmentID uint64 `protobuf:"varint,43,opt,name=environmentID,proto3" json:"environmentID,omitempty"`
+	CgroupID      uint64 `protobuf:"varint,44,opt,name=cgroupID,proto3" json:"cgroupID,omitempty"`
+	// bytes used and size of the tmpfs work directory after the run if
+	// reportTiming is set or the program exited abnormally (Linux only)
+	WorkDirUsage uint64 `protobuf:"varint,45,opt,name=workDirUsage,proto3" json:"workDirUsage,omitempty"`
+	WorkDirSize  uint64 `protobuf:"varint,46,opt,name=workDirSize,proto3" json:"workDirSize,omitempty"`
+	// -noisy-neighbor-* thresholds exceeded by the run (majorFaults, writeback)
+	NoisyNeighbor []string `protobuf:"bytes,47,rep,name=noisyNeighbor,proto3" json:"noisyNeighbor,omitempty"`
+}
+
+func (x *Response_Result) Reset() {
+	*x = Response_Result{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_judge_proto_msgTypes[33]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *Response_Result) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Response_Result) ProtoMessage() {}
+
+func (x *Response_Result) ProtoReflect() protoreflect.Message {
+	mi := &file_judge_proto_msgTypes[33]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Response_Result.ProtoReflect.Descriptor instead.
+func (*Response_Result) Descriptor() ([]byte, []int) {
+	return file_judge_proto_rawDescGZIP(), []int{4, 9}
+}
+
+func (x *Response_Result) GetStatus() Response_Result_StatusType {
+	if x != nil {
+		return x.Status
+	}
+	return Response_Result_Invalid
+}
+
+func (x *Response_Result) GetExitStatus() int32 {
+	if x != nil {
+		return x.ExitStatus
+	}
+	return 0
+}
+
+func (x *Response_Result) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *Response_Result) GetTime() uint64 {
+	if x != nil {
+		return x.Time
+	}
+	return 0
+}
+
+func (x *Response_Result) GetRunTime() uint64 {
+	if x != nil {
+		return x.RunTime
+	}
+	return 0
+}
+
+func (x *Response_Result) GetMemory() uint64 {
+	if x != nil {
+		return x.Memory
+	}
+	return 0
+}
+
+func (x *Response_Result) GetFiles() map[string][]byte {
+	if x != nil {
+		return x.Files
+	}
+	return nil
+}
+
+func (x *Response_Result) GetFileIDs() map[string]string {
+	if x != nil {
+		return x.FileIDs
+	}
+	return nil
+}
+
+func (x *Response_Result) GetFileError() []*Response_FileError {
+	if x != nil {
+		return x.FileError
+	}
+	return nil
+}
+
+func (x *Response_Result) GetExtracted() map[string]uint32 {
+	if x != nil {
+		return x.Extracted
+	}
+	return nil
+}
+
+func (x *Response_Result) GetSignal() int32 {
+	if x != nil {
+		return x.Signal
+	}
+	return 0
+}
+
+func (x *Response_Result) GetTerminatedBy() string {
+	if x != nil {
+		return x.TerminatedBy
+	}
+	return ""
+}
+
+func (x *Response_Result) GetMemoryStat() *Response_MemoryStat {
+	if x != nil {
+		return x.MemoryStat
+	}
+	return nil
+}
+
+func (x *Response_Result) GetFileSize() map[string]int64 {
+	if x != nil {
+		return x.FileSize
+	}
+	return nil
+}
+
+func (x *Response_Result) GetWarning() string {
+	if x != nil {
+		return x.Warning
+	}
+	return ""
+}
+
+func (x *Response_Result) GetProcPeak() uint64 {
+	if x != nil {
+		return x.ProcPeak
+	}
+	return 0
+}
+
+func (x *Response_Result) GetWorkDir() string {
+	if x != nil {
+		return x.WorkDir
+	}
+	return ""
+}
+
+func (x *Response_Result) GetPipeBytes() map[int32]int64 {
+	if x != nil {
+		return x.PipeBytes
+	}
+	return nil
+}
+
+func (x *Response_Result) GetOutputStat() map[string]*Response_OutputStat {
+	if x != nil {
+		return x.OutputStat
+	}
+	return nil
+}
+
+func (x *Response_Result) GetStartedAt() string {
+	if x != nil {
+		return x.StartedAt
+	}
+	return ""
+}
+
+func (x *Response_Result) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *Response_Result) GetInstanceID() string {
+	if x != nil {
+		return x.InstanceID
+	}
+	return ""
+}
+
+func (x *Response_Result) GetIoStat() *Response_IOStat {
+	if x != nil {
+		return x.IoStat
+	}
+	return nil
 }
 
-// Deprecated: Use Request_PipeMap_PipeIndex.ProtoReflect.Descriptor instead.
-func (*Request_PipeMap_PipeIndex) Descriptor() ([]byte, []int) {
-	return file_judge_proto_rawDescGZIP(), []int{3, 9, 0}
+func (x *Response_Result) GetCoreFileID() string {
+	if x != nil {
+		return x.CoreFileID
+	}
+	return ""
 }
 
-func (x *Request_PipeMap_PipeIndex) GetIndex() int32 {
+func (x *Response_Result) GetCoreSize() int64 {
 	if x != nil {
-		return x.Index
+		return x.CoreSize
 	}
 	return 0
 }
 
-func (x *Request_PipeMap_PipeIndex) GetFd() int32 {
+func (x *Response_Result) GetSyscallCount() map[string]uint64 {
 	if x != nil {
-		return x.Fd
+		return x.SyscallCount
 	}
-	return 0
+	return nil
 }
 
-type Response_FileError struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
-	Name    string                       `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
-	Type    Response_FileError_ErrorType `protobuf:"varint,2,opt,name=type,proto3,enum=pb.Response_FileError_ErrorType" json:"type,omitempty"`
-	Message string                       `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+func (x *Response_Result) GetFileStat() map[string]*Response_FileStat {
+	if x != nil {
+		return x.FileStat
+	}
+	return nil
 }
 
-func (x *Response_FileError) Reset() {
-	*x = Response_FileError{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_judge_proto_msgTypes[21]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+func (x *Response_Result) GetStrayProcs() uint64 {
+	if x != nil {
+		return x.StrayProcs
 	}
+	return 0
 }
 
-func (x *Response_FileError) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *Response_Result) GetArch() string {
+	if x != nil {
+		return x.Arch
+	}
+	return ""
 }
 
-func (*Response_FileError) ProtoMessage() {}
-
-func (x *Response_FileError) ProtoReflect() protoreflect.Message {
-	mi := &file_judge_proto_msgTypes[21]
-	if protoimpl.UnsafeEnabled && x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+func (x *Response_Result) GetShmUsage() uint64 {
+	if x != nil {
+		return x.ShmUsage
 	}
-	return mi.MessageOf(x)
+	return 0
 }
 
-// Deprecated: Use Response_FileError.ProtoReflect.Descriptor instead.
-func (*Response_FileError) Descriptor() ([]byte, []int) {
-	return file_judge_proto_rawDescGZIP(), []int{4, 0}
+func (x *Response_Result) GetInitLog() string {
+	if x != nil {
+		return x.InitLog
+	}
+	return ""
 }
 
-func (x *Response_FileError) GetName() string {
+func (x *Response_Result) GetDiskUsage() uint64 {
 	if x != nil {
-		return x.Name
+		return x.DiskUsage
 	}
-	return ""
+	return 0
 }
 
-func (x *Response_FileError) GetType() Response_FileError_ErrorType {
+func (x *Response_Result) GetOverheadCpuTime() uint64 {
 	if x != nil {
-		return x.Type
+		return x.OverheadCpuTime
 	}
-	return Response_FileError_CopyInOpenFile
+	return 0
 }
 
-func (x *Response_FileError) GetMessage() string {
+func (x *Response_Result) GetCancelReason() string {
 	if x != nil {
-		return x.Message
+		return x.CancelReason
 	}
 	return ""
 }
 
-type Response_Result struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
-	Status     Response_Result_StatusType `protobuf:"varint,1,opt,name=status,proto3,enum=pb.Response_Result_StatusType" json:"status,omitempty"`
-	ExitStatus int32                      `protobuf:"varint,2,opt,name=exitStatus,proto3" json:"exitStatus,omitempty"`
-	Error      string                     `protobuf:"bytes,3,opt,name=error,proto3" json:"error,omitempty"`
-	Time       uint64                     `protobuf:"varint,4,opt,name=time,proto3" json:"time,omitempty"`
-	RunTime    uint64                     `protobuf:"varint,8,opt,name=runTime,proto3" json:"runTime,omitempty"`
-	Memory     uint64                     `protobuf:"varint,5,opt,name=memory,proto3" json:"memory,omitempty"`
-	Files      map[string][]byte          `protobuf:"bytes,6,rep,name=files,proto3" json:"files,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	FileIDs    map[string]string          `protobuf:"bytes,7,rep,name=fileIDs,proto3" json:"fileIDs,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-	FileError  []*Response_FileError      `protobuf:"bytes,9,rep,name=fileError,proto3" json:"fileError,omitempty"`
+func (x *Response_Result) GetThrottling() *Response_Throttling {
+	if x != nil {
+		return x.Throttling
+	}
+	return nil
 }
 
-func (x *Response_Result) Reset() {
-	*x = Response_Result{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_judge_proto_msgTypes[22]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+func (x *Response_Result) GetEmulated() bool {
+	if x != nil {
+		return x.Emulated
 	}
+	return false
 }
 
-func (x *Response_Result) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *Response_Result) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
 }
 
-func (*Response_Result) ProtoMessage() {}
-
-func (x *Response_Result) ProtoReflect() protoreflect.Message {
-	mi := &file_judge_proto_msgTypes[22]
-	if protoimpl.UnsafeEnabled && x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+func (x *Response_Result) GetRaw() *Response_RawResult {
+	if x != nil {
+		return x.Raw
 	}
-	return mi.MessageOf(x)
+	return nil
 }
 
-// Deprecated: Use Response_Result.ProtoReflect.Descriptor instead.
-func (*Response_Result) Descriptor() ([]byte, []int) {
-	return file_judge_proto_rawDescGZIP(), []int{4, 1}
+func (x *Response_Result) GetRandomSeed() uint64 {
+	if x != nil && x.RandomSeed != nil {
+		return *x.RandomSeed
+	}
+	return 0
 }
 
-func (x *Response_Result) GetStatus() Response_Result_StatusType {
+func (x *Response_Result) GetRandomOpened() bool {
 	if x != nil {
-		return x.Status
+		return x.RandomOpened
 	}
-	return Response_Result_Invalid
+	return false
 }
 
-func (x *Response_Result) GetExitStatus() int32 {
+func (x *Response_Result) GetFileType() map[string]*Response_FileType {
 	if x != nil {
-		return x.ExitStatus
+		return x.FileType
 	}
-	return 0
+	return nil
 }
 
-func (x *Response_Result) GetError() string {
+func (x *Response_Result) GetGroupID() string {
 	if x != nil {
-		return x.Error
+		return x.GroupID
 	}
 	return ""
 }
 
-func (x *Response_Result) GetTime() uint64 {
+func (x *Response_Result) GetEnvironmentID() uint64 {
 	if x != nil {
-		return x.Time
+		return x.EnvironmentID
 	}
 	return 0
 }
 
-func (x *Response_Result) GetRunTime() uint64 {
+func (x *Response_Result) GetCgroupID() uint64 {
 	if x != nil {
-		return x.RunTime
+		return x.CgroupID
 	}
 	return 0
 }
 
-func (x *Response_Result) GetMemory() uint64 {
+func (x *Response_Result) GetWorkDirUsage() uint64 {
 	if x != nil {
-		return x.Memory
+		return x.WorkDirUsage
 	}
 	return 0
 }
 
-func (x *Response_Result) GetFiles() map[string][]byte {
-	if x != nil {
-		return x.Files
-	}
-	return nil
-}
-
-func (x *Response_Result) GetFileIDs() map[string]string {
+func (x *Response_Result) GetWorkDirSize() uint64 {
 	if x != nil {
-		return x.FileIDs
+		return x.WorkDirSize
 	}
-	return nil
+	return 0
 }
 
-func (x *Response_Result) GetFileError() []*Response_FileError {
+func (x *Response_Result) GetNoisyNeighbor() []string {
 	if x != nil {
-		return x.FileError
+		return x.NoisyNeighbor
 	}
 	return nil
 }
@@ -1619,7 +3157,7 @@ type StreamRequest_Input struct {
 func (x *StreamRequest_Input) Reset() {
 	*x = StreamRequest_Input{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_judge_proto_msgTypes[25]
+		mi := &file_judge_proto_msgTypes[43]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1632,7 +3170,7 @@ func (x *StreamRequest_Input) String() string {
 func (*StreamRequest_Input) ProtoMessage() {}
 
 func (x *StreamRequest_Input) ProtoReflect() protoreflect.Message {
-	mi := &file_judge_proto_msgTypes[25]
+	mi := &file_judge_proto_msgTypes[43]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1677,7 +3215,7 @@ type StreamRequest_Resize struct {
 func (x *StreamRequest_Resize) Reset() {
 	*x = StreamRequest_Resize{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_judge_proto_msgTypes[26]
+		mi := &file_judge_proto_msgTypes[44]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1690,7 +3228,7 @@ func (x *StreamRequest_Resize) String() string {
 func (*StreamRequest_Resize) ProtoMessage() {}
 
 func (x *StreamRequest_Resize) ProtoReflect() protoreflect.Message {
-	mi := &file_judge_proto_msgTypes[26]
+	mi := &file_judge_proto_msgTypes[44]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1753,7 +3291,7 @@ type StreamResponse_Output struct {
 func (x *StreamResponse_Output) Reset() {
 	*x = StreamResponse_Output{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_judge_proto_msgTypes[27]
+		mi := &file_judge_proto_msgTypes[45]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1766,7 +3304,7 @@ func (x *StreamResponse_Output) String() string {
 func (*StreamResponse_Output) ProtoMessage() {}
 
 func (x *StreamResponse_Output) ProtoReflect() protoreflect.Message {
-	mi := &file_judge_proto_msgTypes[27]
+	mi := &file_judge_proto_msgTypes[45]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1816,7 +3354,7 @@ var file_judge_proto_rawDesc = []byte{
 	0x44, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01,
 	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c,
 	0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a,
-	0x02, 0x38, 0x01, 0x22, 0xad, 0x0e, 0x0a, 0x07, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x02, 0x38, 0x01, 0x22, 0xb5, 0x1d, 0x0a, 0x07, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
 	0x1c, 0x0a, 0x09, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01,
 	0x28, 0x09, 0x52, 0x09, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x44, 0x12, 0x25, 0x0a,
 	0x03, 0x63, 0x6d, 0x64, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x70, 0x62, 0x2e,
@@ -1824,23 +3362,56 @@ var file_judge_proto_rawDesc = []byte{
 	0x03, 0x63, 0x6d, 0x64, 0x12, 0x35, 0x0a, 0x0b, 0x70, 0x69, 0x70, 0x65, 0x4d, 0x61, 0x70, 0x70,
 	0x69, 0x6e, 0x67, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x52,
 	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x50, 0x69, 0x70, 0x65, 0x4d, 0x61, 0x70, 0x52, 0x0b,
-	0x70, 0x69, 0x70, 0x65, 0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x1a, 0x1d, 0x0a, 0x09, 0x4c,
-	0x6f, 0x63, 0x61, 0x6c, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x73, 0x72, 0x63, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x73, 0x72, 0x63, 0x1a, 0x26, 0x0a, 0x0a, 0x4d, 0x65,
-	0x6d, 0x6f, 0x72, 0x79, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74,
-	0x65, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65,
-	0x6e, 0x74, 0x1a, 0x24, 0x0a, 0x0a, 0x43, 0x61, 0x63, 0x68, 0x65, 0x64, 0x46, 0x69, 0x6c, 0x65,
-	0x12, 0x16, 0x0a, 0x06, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x06, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x44, 0x1a, 0x49, 0x0a, 0x0d, 0x50, 0x69, 0x70, 0x65,
+	0x70, 0x69, 0x70, 0x65, 0x4d, 0x61, 0x70, 0x70, 0x69, 0x6e, 0x67, 0x12, 0x2d, 0x0a, 0x07, 0x72,
+	0x75, 0x6e, 0x4d, 0x6f, 0x64, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x13, 0x2e, 0x70,
+	0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x52, 0x75, 0x6e, 0x4d, 0x6f, 0x64,
+	0x65, 0x52, 0x07, 0x72, 0x75, 0x6e, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x73, 0x6b,
+	0x69, 0x70, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a,
+	0x73, 0x6b, 0x69, 0x70, 0x56, 0x65, 0x72, 0x69, 0x66, 0x79, 0x12, 0x20, 0x0a, 0x0b, 0x6b, 0x69,
+	0x6c, 0x6c, 0x4f, 0x6e, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x0b, 0x6b, 0x69, 0x6c, 0x6c, 0x4f, 0x6e, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x30, 0x0a, 0x08,
+	0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x14,
+	0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x50, 0x72, 0x69, 0x6f,
+	0x72, 0x69, 0x74, 0x79, 0x52, 0x08, 0x70, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x12, 0x26,
+	0x0a, 0x0e, 0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x4b, 0x65, 0x79,
+	0x18, 0x08, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x69, 0x64, 0x65, 0x6d, 0x70, 0x6f, 0x74, 0x65,
+	0x6e, 0x63, 0x79, 0x4b, 0x65, 0x79, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75,
+	0x74, 0x53, 0x74, 0x61, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x63, 0x6f, 0x70,
+	0x79, 0x4f, 0x75, 0x74, 0x53, 0x74, 0x61, 0x74, 0x12, 0x1f, 0x0a, 0x08, 0x6e, 0x75, 0x6d, 0x61,
+	0x4e, 0x6f, 0x64, 0x65, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x05, 0x48, 0x00, 0x52, 0x08, 0x6e, 0x75,
+	0x6d, 0x61, 0x4e, 0x6f, 0x64, 0x65, 0x88, 0x01, 0x01, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72,
+	0x64, 0x69, 0x63, 0x74, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x76, 0x65, 0x72, 0x64,
+	0x69, 0x63, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x6e, 0x63, 0x6c, 0x75, 0x64, 0x65, 0x52, 0x61,
+	0x77, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0a, 0x69, 0x6e, 0x63, 0x6c, 0x75, 0x64, 0x65,
+	0x52, 0x61, 0x77, 0x12, 0x2c, 0x0a, 0x11, 0x65, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x64,
+	0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x04, 0x52, 0x11,
+	0x65, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x64, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x1a, 0x1d, 0x0a, 0x09, 0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x10,
+	0x0a, 0x03, 0x73, 0x72, 0x63, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x73, 0x72, 0x63,
+	0x1a, 0x26, 0x0a, 0x0a, 0x4d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x18,
+	0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52,
+	0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x1a, 0x24, 0x0a, 0x0a, 0x43, 0x61, 0x63, 0x68,
+	0x65, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x44,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x44, 0x1a, 0x27,
+	0x0a, 0x0b, 0x43, 0x61, 0x63, 0x68, 0x65, 0x64, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x12, 0x18, 0x0a,
+	0x07, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x67, 0x72, 0x6f, 0x75, 0x70, 0x49, 0x44, 0x1a, 0xb9, 0x01, 0x0a, 0x0d, 0x50, 0x69, 0x70, 0x65,
 	0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d,
 	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x10, 0x0a,
 	0x03, 0x6d, 0x61, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x03, 0x6d, 0x61, 0x78, 0x12,
 	0x12, 0x0a, 0x04, 0x70, 0x69, 0x70, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x04, 0x70,
-	0x69, 0x70, 0x65, 0x1a, 0x21, 0x0a, 0x0b, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x49, 0x6e, 0x70,
+	0x69, 0x70, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x61, 0x63, 0x68, 0x65, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x05, 0x63, 0x61, 0x63, 0x68, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x64, 0x69, 0x73,
+	0x63, 0x61, 0x72, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x64, 0x69, 0x73, 0x63,
+	0x61, 0x72, 0x64, 0x12, 0x2b, 0x0a, 0x0e, 0x63, 0x61, 0x63, 0x68, 0x65, 0x54, 0x72, 0x75, 0x6e,
+	0x63, 0x61, 0x74, 0x65, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x08, 0x48, 0x00, 0x52, 0x0e, 0x63,
+	0x61, 0x63, 0x68, 0x65, 0x54, 0x72, 0x75, 0x6e, 0x63, 0x61, 0x74, 0x65, 0x64, 0x88, 0x01, 0x01,
+	0x42, 0x11, 0x0a, 0x0f, 0x5f, 0x63, 0x61, 0x63, 0x68, 0x65, 0x54, 0x72, 0x75, 0x6e, 0x63, 0x61,
+	0x74, 0x65, 0x64, 0x1a, 0x21, 0x0a, 0x0b, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x49, 0x6e, 0x70,
 	0x75, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
 	0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x1a, 0x22, 0x0a, 0x0c, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
 	0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x1a, 0xc3, 0x02, 0x0a, 0x04, 0x46,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x1a, 0xbc, 0x03, 0x0a, 0x04, 0x46,
 	0x69, 0x6c, 0x65, 0x12, 0x2d, 0x0a, 0x05, 0x6c, 0x6f, 0x63, 0x61, 0x6c, 0x18, 0x01, 0x20, 0x01,
 	0x28, 0x0b, 0x32, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e,
 	0x4c, 0x6f, 0x63, 0x61, 0x6c, 0x46, 0x69, 0x6c, 0x65, 0x48, 0x00, 0x52, 0x05, 0x6c, 0x6f, 0x63,
@@ -1860,209 +3431,505 @@ var file_judge_proto_rawDesc = []byte{
 	0x0a, 0x09, 0x73, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x4f, 0x75, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28,
 	0x0b, 0x32, 0x18, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x53,
 	0x74, 0x72, 0x65, 0x61, 0x6d, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x48, 0x00, 0x52, 0x09, 0x73,
-	0x74, 0x72, 0x65, 0x61, 0x6d, 0x4f, 0x75, 0x74, 0x42, 0x06, 0x0a, 0x04, 0x66, 0x69, 0x6c, 0x65,
-	0x1a, 0xc3, 0x06, 0x0a, 0x07, 0x43, 0x6d, 0x64, 0x54, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x04,
-	0x61, 0x72, 0x67, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x09, 0x52, 0x04, 0x61, 0x72, 0x67, 0x73,
-	0x12, 0x10, 0x0a, 0x03, 0x65, 0x6e, 0x76, 0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x03, 0x65,
-	0x6e, 0x76, 0x12, 0x26, 0x0a, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28,
-	0x0b, 0x32, 0x10, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x46,
-	0x69, 0x6c, 0x65, 0x52, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x10, 0x0a, 0x03, 0x74, 0x74,
-	0x79, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x08, 0x52, 0x03, 0x74, 0x74, 0x79, 0x12, 0x22, 0x0a, 0x0c,
-	0x63, 0x70, 0x75, 0x54, 0x69, 0x6d, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x04, 0x20, 0x01,
-	0x28, 0x04, 0x52, 0x0c, 0x63, 0x70, 0x75, 0x54, 0x69, 0x6d, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74,
-	0x12, 0x26, 0x0a, 0x0e, 0x63, 0x6c, 0x6f, 0x63, 0x6b, 0x54, 0x69, 0x6d, 0x65, 0x4c, 0x69, 0x6d,
-	0x69, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0e, 0x63, 0x6c, 0x6f, 0x63, 0x6b, 0x54,
-	0x69, 0x6d, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x20, 0x0a, 0x0b, 0x6d, 0x65, 0x6d, 0x6f,
-	0x72, 0x79, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x6d,
-	0x65, 0x6d, 0x6f, 0x72, 0x79, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x73, 0x74,
-	0x61, 0x63, 0x6b, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a,
-	0x73, 0x74, 0x61, 0x63, 0x6b, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72,
-	0x6f, 0x63, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x70,
-	0x72, 0x6f, 0x63, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x70, 0x75, 0x52,
-	0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c,
-	0x63, 0x70, 0x75, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x20, 0x0a, 0x0b,
-	0x63, 0x70, 0x75, 0x53, 0x65, 0x74, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x11, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x0b, 0x63, 0x70, 0x75, 0x53, 0x65, 0x74, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x2c,
-	0x0a, 0x11, 0x73, 0x74, 0x72, 0x69, 0x63, 0x74, 0x4d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x4c, 0x69,
-	0x6d, 0x69, 0x74, 0x18, 0x10, 0x20, 0x01, 0x28, 0x08, 0x52, 0x11, 0x73, 0x74, 0x72, 0x69, 0x63,
-	0x74, 0x4d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x37, 0x0a, 0x06,
-	0x63, 0x6f, 0x70, 0x79, 0x49, 0x6e, 0x18, 0x08, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x70,
+	0x74, 0x72, 0x65, 0x61, 0x6d, 0x4f, 0x75, 0x74, 0x12, 0x2f, 0x0a, 0x05, 0x67, 0x72, 0x6f, 0x75,
+	0x70, 0x18, 0x09, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x2e, 0x43, 0x61, 0x63, 0x68, 0x65, 0x64, 0x47, 0x72, 0x6f, 0x75, 0x70,
+	0x48, 0x00, 0x52, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x72, 0x63,
+	0x68, 0x69, 0x76, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x61, 0x72, 0x63, 0x68,
+	0x69, 0x76, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x69, 0x66, 0x6f, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x04, 0x66, 0x69, 0x66, 0x6f, 0x12, 0x18, 0x0a, 0x07, 0x65, 0x78, 0x74, 0x72, 0x61,
+	0x63, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x65, 0x78, 0x74, 0x72, 0x61, 0x63,
+	0x74, 0x42, 0x06, 0x0a, 0x04, 0x66, 0x69, 0x6c, 0x65, 0x1a, 0xeb, 0x0e, 0x0a, 0x07, 0x43, 0x6d,
+	0x64, 0x54, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x72, 0x67, 0x73, 0x18, 0x01, 0x20,
+	0x03, 0x28, 0x09, 0x52, 0x04, 0x61, 0x72, 0x67, 0x73, 0x12, 0x10, 0x0a, 0x03, 0x65, 0x6e, 0x76,
+	0x18, 0x02, 0x20, 0x03, 0x28, 0x09, 0x52, 0x03, 0x65, 0x6e, 0x76, 0x12, 0x26, 0x0a, 0x05, 0x66,
+	0x69, 0x6c, 0x65, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x70, 0x62, 0x2e,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x05, 0x66, 0x69,
+	0x6c, 0x65, 0x73, 0x12, 0x10, 0x0a, 0x03, 0x74, 0x74, 0x79, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x03, 0x74, 0x74, 0x79, 0x12, 0x43, 0x0a, 0x0a, 0x65, 0x78, 0x74, 0x72, 0x61, 0x46, 0x69,
+	0x6c, 0x65, 0x73, 0x18, 0x2a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x70, 0x62, 0x2e, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x43, 0x6d, 0x64, 0x54, 0x79, 0x70, 0x65, 0x2e, 0x45,
+	0x78, 0x74, 0x72, 0x61, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0a,
+	0x65, 0x78, 0x74, 0x72, 0x61, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x70,
+	0x75, 0x54, 0x69, 0x6d, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x0c, 0x63, 0x70, 0x75, 0x54, 0x69, 0x6d, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x26,
+	0x0a, 0x0e, 0x63, 0x6c, 0x6f, 0x63, 0x6b, 0x54, 0x69, 0x6d, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0e, 0x63, 0x6c, 0x6f, 0x63, 0x6b, 0x54, 0x69, 0x6d,
+	0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x20, 0x0a, 0x0b, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79,
+	0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x6d, 0x65, 0x6d,
+	0x6f, 0x72, 0x79, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x73, 0x74, 0x61, 0x63,
+	0x6b, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x73, 0x74,
+	0x61, 0x63, 0x6b, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x70, 0x72, 0x6f, 0x63,
+	0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x70, 0x72, 0x6f,
+	0x63, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x70, 0x75, 0x52, 0x61, 0x74,
+	0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x63, 0x70,
+	0x75, 0x52, 0x61, 0x74, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x70,
+	0x75, 0x53, 0x65, 0x74, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x11, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0b, 0x63, 0x70, 0x75, 0x53, 0x65, 0x74, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x2c, 0x0a, 0x11,
+	0x73, 0x74, 0x72, 0x69, 0x63, 0x74, 0x4d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x4c, 0x69, 0x6d, 0x69,
+	0x74, 0x18, 0x10, 0x20, 0x01, 0x28, 0x08, 0x52, 0x11, 0x73, 0x74, 0x72, 0x69, 0x63, 0x74, 0x4d,
+	0x65, 0x6d, 0x6f, 0x72, 0x79, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x26, 0x0a, 0x0e, 0x64, 0x65,
+	0x74, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x4d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x18, 0x13, 0x20, 0x01,
+	0x28, 0x08, 0x52, 0x0e, 0x64, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x4d, 0x65, 0x6d, 0x6f,
+	0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x75, 0x69, 0x64, 0x18, 0x14, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x03, 0x75, 0x69, 0x64, 0x12, 0x10, 0x0a, 0x03, 0x67, 0x69, 0x64, 0x18, 0x15, 0x20, 0x01, 0x28,
+	0x0d, 0x52, 0x03, 0x67, 0x69, 0x64, 0x12, 0x37, 0x0a, 0x06, 0x63, 0x6f, 0x70, 0x79, 0x49, 0x6e,
+	0x18, 0x08, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1f, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x2e, 0x43, 0x6d, 0x64, 0x54, 0x79, 0x70, 0x65, 0x2e, 0x43, 0x6f, 0x70, 0x79,
+	0x49, 0x6e, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x63, 0x6f, 0x70, 0x79, 0x49, 0x6e, 0x12,
+	0x3d, 0x0a, 0x08, 0x73, 0x79, 0x6d, 0x6c, 0x69, 0x6e, 0x6b, 0x73, 0x18, 0x12, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x21, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x43,
+	0x6d, 0x64, 0x54, 0x79, 0x70, 0x65, 0x2e, 0x53, 0x79, 0x6d, 0x6c, 0x69, 0x6e, 0x6b, 0x73, 0x45,
+	0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x73, 0x79, 0x6d, 0x6c, 0x69, 0x6e, 0x6b, 0x73, 0x12, 0x34,
+	0x0a, 0x07, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x43, 0x6d, 0x64,
+	0x43, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x07, 0x63, 0x6f, 0x70,
+	0x79, 0x4f, 0x75, 0x74, 0x12, 0x40, 0x0a, 0x0d, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x43,
+	0x61, 0x63, 0x68, 0x65, 0x64, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x70, 0x62,
+	0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x43, 0x6d, 0x64, 0x43, 0x6f, 0x70, 0x79,
+	0x4f, 0x75, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x0d, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74,
+	0x43, 0x61, 0x63, 0x68, 0x65, 0x64, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75,
+	0x74, 0x44, 0x69, 0x72, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x63, 0x6f, 0x70, 0x79,
+	0x4f, 0x75, 0x74, 0x44, 0x69, 0x72, 0x12, 0x1e, 0x0a, 0x0a, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75,
+	0x74, 0x4d, 0x61, 0x78, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x63, 0x6f, 0x70, 0x79,
+	0x4f, 0x75, 0x74, 0x4d, 0x61, 0x78, 0x12, 0x28, 0x0a, 0x0f, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75,
+	0x74, 0x4d, 0x61, 0x78, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x16, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x0f, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x4d, 0x61, 0x78, 0x46, 0x69, 0x6c, 0x65, 0x73,
+	0x12, 0x28, 0x0a, 0x0f, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x4d, 0x61, 0x78, 0x54, 0x6f,
+	0x74, 0x61, 0x6c, 0x18, 0x17, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0f, 0x63, 0x6f, 0x70, 0x79, 0x4f,
+	0x75, 0x74, 0x4d, 0x61, 0x78, 0x54, 0x6f, 0x74, 0x61, 0x6c, 0x12, 0x2a, 0x0a, 0x10, 0x72, 0x61,
+	0x6e, 0x64, 0x6f, 0x6d, 0x69, 0x7a, 0x65, 0x57, 0x6f, 0x72, 0x6b, 0x44, 0x69, 0x72, 0x18, 0x18,
+	0x20, 0x01, 0x28, 0x08, 0x52, 0x10, 0x72, 0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x69, 0x7a, 0x65, 0x57,
+	0x6f, 0x72, 0x6b, 0x44, 0x69, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65,
+	0x74, 0x73, 0x18, 0x19, 0x20, 0x03, 0x28, 0x09, 0x52, 0x08, 0x64, 0x61, 0x74, 0x61, 0x73, 0x65,
+	0x74, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x18, 0x1a, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x07, 0x70, 0x72, 0x6f, 0x66, 0x69, 0x6c, 0x65, 0x12, 0x18, 0x0a, 0x07,
+	0x69, 0x6f, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x1b, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x69,
+	0x6f, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f, 0x6c, 0x6c, 0x65, 0x63,
+	0x74, 0x43, 0x6f, 0x72, 0x65, 0x18, 0x1c, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x63, 0x6f, 0x6c,
+	0x6c, 0x65, 0x63, 0x74, 0x43, 0x6f, 0x72, 0x65, 0x12, 0x43, 0x0a, 0x0a, 0x6d, 0x6f, 0x75, 0x6e,
+	0x74, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x1d, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x70,
 	0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x43, 0x6d, 0x64, 0x54, 0x79, 0x70,
-	0x65, 0x2e, 0x43, 0x6f, 0x70, 0x79, 0x49, 0x6e, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x06, 0x63,
-	0x6f, 0x70, 0x79, 0x49, 0x6e, 0x12, 0x3d, 0x0a, 0x08, 0x73, 0x79, 0x6d, 0x6c, 0x69, 0x6e, 0x6b,
-	0x73, 0x18, 0x12, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x2e, 0x43, 0x6d, 0x64, 0x54, 0x79, 0x70, 0x65, 0x2e, 0x53, 0x79, 0x6d,
-	0x6c, 0x69, 0x6e, 0x6b, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x73, 0x79, 0x6d, 0x6c,
-	0x69, 0x6e, 0x6b, 0x73, 0x12, 0x34, 0x0a, 0x07, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x18,
-	0x09, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x2e, 0x43, 0x6d, 0x64, 0x43, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x46, 0x69, 0x6c,
-	0x65, 0x52, 0x07, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x12, 0x40, 0x0a, 0x0d, 0x63, 0x6f,
-	0x70, 0x79, 0x4f, 0x75, 0x74, 0x43, 0x61, 0x63, 0x68, 0x65, 0x64, 0x18, 0x0a, 0x20, 0x03, 0x28,
-	0x0b, 0x32, 0x1a, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x43,
-	0x6d, 0x64, 0x43, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x0d, 0x63,
-	0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x43, 0x61, 0x63, 0x68, 0x65, 0x64, 0x12, 0x1e, 0x0a, 0x0a,
-	0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x44, 0x69, 0x72, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x0a, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x44, 0x69, 0x72, 0x12, 0x1e, 0x0a, 0x0a,
-	0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x4d, 0x61, 0x78, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x04,
-	0x52, 0x0a, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x4d, 0x61, 0x78, 0x1a, 0x4b, 0x0a, 0x0b,
-	0x43, 0x6f, 0x70, 0x79, 0x49, 0x6e, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b,
-	0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x26, 0x0a,
-	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x10, 0x2e, 0x70,
-	0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x05,
-	0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3b, 0x0a, 0x0d, 0x53, 0x79, 0x6d,
-	0x6c, 0x69, 0x6e, 0x6b, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65,
-	0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05,
-	0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76, 0x61, 0x6c,
-	0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x40, 0x0a, 0x0e, 0x43, 0x6d, 0x64, 0x43, 0x6f, 0x70,
-	0x79, 0x4f, 0x75, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a, 0x08,
-	0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08,
-	0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x1a, 0xd8, 0x01, 0x0a, 0x07, 0x50, 0x69, 0x70,
-	0x65, 0x4d, 0x61, 0x70, 0x12, 0x2d, 0x0a, 0x02, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b,
-	0x32, 0x1d, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x50, 0x69,
-	0x70, 0x65, 0x4d, 0x61, 0x70, 0x2e, 0x50, 0x69, 0x70, 0x65, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x52,
-	0x02, 0x69, 0x6e, 0x12, 0x2f, 0x0a, 0x03, 0x6f, 0x75, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
-	0x32, 0x1d, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x50, 0x69,
-	0x70, 0x65, 0x4d, 0x61, 0x70, 0x2e, 0x50, 0x69, 0x70, 0x65, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x52,
-	0x03, 0x6f, 0x75, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x18, 0x03, 0x20,
-	0x01, 0x28, 0x08, 0x52, 0x05, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
-	0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x10,
-	0x0a, 0x03, 0x6d, 0x61, 0x78, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x6d, 0x61, 0x78,
-	0x1a, 0x31, 0x0a, 0x09, 0x50, 0x69, 0x70, 0x65, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x14, 0x0a,
-	0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x69, 0x6e,
-	0x64, 0x65, 0x78, 0x12, 0x0e, 0x0a, 0x02, 0x66, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52,
-	0x02, 0x66, 0x64, 0x22, 0xca, 0x09, 0x0a, 0x08, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
-	0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x44, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x09, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x49, 0x44, 0x12, 0x2d,
-	0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
-	0x13, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52, 0x65,
-	0x73, 0x75, 0x6c, 0x74, 0x52, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x73, 0x12, 0x14, 0x0a,
-	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72,
-	0x72, 0x6f, 0x72, 0x1a, 0xd8, 0x02, 0x0a, 0x09, 0x46, 0x69, 0x6c, 0x65, 0x45, 0x72, 0x72, 0x6f,
-	0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x34, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x0e, 0x32, 0x20, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x2e, 0x45, 0x72, 0x72, 0x6f,
-	0x72, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x6d,
-	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65,
-	0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0xe6, 0x01, 0x0a, 0x09, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x54,
-	0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x0e, 0x43, 0x6f, 0x70, 0x79, 0x49, 0x6e, 0x4f, 0x70, 0x65,
-	0x6e, 0x46, 0x69, 0x6c, 0x65, 0x10, 0x00, 0x12, 0x14, 0x0a, 0x10, 0x43, 0x6f, 0x70, 0x79, 0x49,
-	0x6e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x10, 0x01, 0x12, 0x15, 0x0a,
-	0x11, 0x43, 0x6f, 0x70, 0x79, 0x49, 0x6e, 0x43, 0x6f, 0x70, 0x79, 0x43, 0x6f, 0x6e, 0x74, 0x65,
-	0x6e, 0x74, 0x10, 0x02, 0x12, 0x0f, 0x0a, 0x0b, 0x43, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x4f,
-	0x70, 0x65, 0x6e, 0x10, 0x03, 0x12, 0x19, 0x0a, 0x15, 0x43, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74,
-	0x4e, 0x6f, 0x74, 0x52, 0x65, 0x67, 0x75, 0x6c, 0x61, 0x72, 0x46, 0x69, 0x6c, 0x65, 0x10, 0x04,
-	0x12, 0x17, 0x0a, 0x13, 0x43, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x53, 0x69, 0x7a, 0x65, 0x45,
-	0x78, 0x63, 0x65, 0x65, 0x64, 0x65, 0x64, 0x10, 0x05, 0x12, 0x15, 0x0a, 0x11, 0x43, 0x6f, 0x70,
-	0x79, 0x4f, 0x75, 0x74, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x10, 0x06,
-	0x12, 0x16, 0x0a, 0x12, 0x43, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x43, 0x6f, 0x70, 0x79, 0x43,
-	0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x10, 0x07, 0x12, 0x17, 0x0a, 0x13, 0x43, 0x6f, 0x6c, 0x6c,
-	0x65, 0x63, 0x74, 0x53, 0x69, 0x7a, 0x65, 0x45, 0x78, 0x63, 0x65, 0x65, 0x64, 0x65, 0x64, 0x10,
-	0x08, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x79, 0x6d, 0x6c, 0x69, 0x6e, 0x6b, 0x10, 0x09, 0x1a, 0xff,
-	0x05, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x36, 0x0a, 0x06, 0x73, 0x74, 0x61,
-	0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1e, 0x2e, 0x70, 0x62, 0x2e, 0x52,
-	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x53,
-	0x74, 0x61, 0x74, 0x75, 0x73, 0x54, 0x79, 0x70, 0x65, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75,
-	0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x65, 0x78, 0x69, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x65, 0x78, 0x69, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75,
-	0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18,
-	0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x72,
-	0x75, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x72, 0x75,
-	0x6e, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x18,
-	0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x12, 0x34, 0x0a,
-	0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1e, 0x2e, 0x70,
-	0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c,
-	0x74, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x05, 0x66, 0x69,
-	0x6c, 0x65, 0x73, 0x12, 0x3a, 0x0a, 0x07, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x44, 0x73, 0x18, 0x07,
-	0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e,
-	0x73, 0x65, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x44,
-	0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x44, 0x73, 0x12,
-	0x34, 0x0a, 0x09, 0x66, 0x69, 0x6c, 0x65, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x09, 0x20, 0x03,
-	0x28, 0x0b, 0x32, 0x16, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
-	0x2e, 0x46, 0x69, 0x6c, 0x65, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x09, 0x66, 0x69, 0x6c, 0x65,
-	0x45, 0x72, 0x72, 0x6f, 0x72, 0x1a, 0x38, 0x0a, 0x0a, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x45, 0x6e,
-	0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02,
-	0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a,
-	0x3a, 0x0a, 0x0c, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x44, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x65, 0x2e, 0x4d, 0x6f, 0x75, 0x6e, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x52, 0x0a, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x14, 0x0a,
+	0x05, 0x74, 0x72, 0x61, 0x63, 0x65, 0x18, 0x1e, 0x20, 0x01, 0x28, 0x08, 0x52, 0x05, 0x74, 0x72,
+	0x61, 0x63, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x65, 0x78, 0x70, 0x61, 0x6e, 0x64, 0x18, 0x1f, 0x20,
+	0x01, 0x28, 0x08, 0x52, 0x06, 0x65, 0x78, 0x70, 0x61, 0x6e, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x70,
+	0x72, 0x6f, 0x63, 0x49, 0x6e, 0x66, 0x6f, 0x18, 0x20, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x70,
+	0x72, 0x6f, 0x63, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x22, 0x0a, 0x0c, 0x70, 0x72, 0x6f, 0x63, 0x49,
+	0x6e, 0x66, 0x6f, 0x43, 0x70, 0x75, 0x73, 0x18, 0x21, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x70,
+	0x72, 0x6f, 0x63, 0x49, 0x6e, 0x66, 0x6f, 0x43, 0x70, 0x75, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x61,
+	0x72, 0x63, 0x68, 0x18, 0x22, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x61, 0x72, 0x63, 0x68, 0x12,
+	0x18, 0x0a, 0x07, 0x73, 0x68, 0x6d, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x23, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x07, 0x73, 0x68, 0x6d, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x64, 0x69, 0x73,
+	0x6b, 0x57, 0x6f, 0x72, 0x6b, 0x44, 0x69, 0x72, 0x18, 0x24, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b,
+	0x64, 0x69, 0x73, 0x6b, 0x57, 0x6f, 0x72, 0x6b, 0x44, 0x69, 0x72, 0x12, 0x22, 0x0a, 0x0c, 0x72,
+	0x65, 0x70, 0x6f, 0x72, 0x74, 0x54, 0x69, 0x6d, 0x69, 0x6e, 0x67, 0x18, 0x25, 0x20, 0x01, 0x28,
+	0x08, 0x52, 0x0c, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x54, 0x69, 0x6d, 0x69, 0x6e, 0x67, 0x12,
+	0x28, 0x0a, 0x0f, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x57, 0x6f, 0x72, 0x6b, 0x44,
+	0x69, 0x72, 0x18, 0x26, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0f, 0x73, 0x6e, 0x61, 0x70, 0x73, 0x68,
+	0x6f, 0x74, 0x57, 0x6f, 0x72, 0x6b, 0x44, 0x69, 0x72, 0x12, 0x23, 0x0a, 0x0a, 0x72, 0x61, 0x6e,
+	0x64, 0x6f, 0x6d, 0x53, 0x65, 0x65, 0x64, 0x18, 0x27, 0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52,
+	0x0a, 0x72, 0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x53, 0x65, 0x65, 0x64, 0x88, 0x01, 0x01, 0x12, 0x20,
+	0x0a, 0x0b, 0x73, 0x63, 0x72, 0x61, 0x74, 0x63, 0x68, 0x46, 0x69, 0x6c, 0x65, 0x18, 0x28, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0b, 0x73, 0x63, 0x72, 0x61, 0x74, 0x63, 0x68, 0x46, 0x69, 0x6c, 0x65,
+	0x12, 0x20, 0x0a, 0x0b, 0x73, 0x63, 0x72, 0x61, 0x74, 0x63, 0x68, 0x53, 0x69, 0x7a, 0x65, 0x18,
+	0x29, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x73, 0x63, 0x72, 0x61, 0x74, 0x63, 0x68, 0x53, 0x69,
+	0x7a, 0x65, 0x12, 0x4a, 0x0a, 0x12, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x43, 0x61, 0x63,
+	0x68, 0x65, 0x64, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x18, 0x2b, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a,
+	0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x43, 0x6d, 0x64, 0x43,
+	0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x52, 0x12, 0x63, 0x6f, 0x70, 0x79,
+	0x4f, 0x75, 0x74, 0x43, 0x61, 0x63, 0x68, 0x65, 0x64, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x1a, 0x4f,
+	0x0a, 0x0f, 0x45, 0x78, 0x74, 0x72, 0x61, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x26, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x10, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e,
+	0x46, 0x69, 0x6c, 0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a,
+	0x4b, 0x0a, 0x0b, 0x43, 0x6f, 0x70, 0x79, 0x49, 0x6e, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10,
+	0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79,
+	0x12, 0x26, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x10, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x46, 0x69, 0x6c,
+	0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3b, 0x0a, 0x0d,
+	0x53, 0x79, 0x6d, 0x6c, 0x69, 0x6e, 0x6b, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a,
+	0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12,
+	0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3d, 0x0a, 0x0f, 0x4d, 0x6f, 0x75,
+	0x6e, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03,
+	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x76,
+	0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x72, 0x61, 0x6e,
+	0x64, 0x6f, 0x6d, 0x53, 0x65, 0x65, 0x64, 0x1a, 0x5c, 0x0a, 0x0e, 0x43, 0x6d, 0x64, 0x43, 0x6f,
+	0x70, 0x79, 0x4f, 0x75, 0x74, 0x46, 0x69, 0x6c, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x1a, 0x0a,
+	0x08, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52,
+	0x08, 0x6f, 0x70, 0x74, 0x69, 0x6f, 0x6e, 0x61, 0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x74, 0x61,
+	0x74, 0x4f, 0x6e, 0x6c, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x73, 0x74, 0x61,
+	0x74, 0x4f, 0x6e, 0x6c, 0x79, 0x1a, 0xb2, 0x02, 0x0a, 0x07, 0x50, 0x69, 0x70, 0x65, 0x4d, 0x61,
+	0x70, 0x12, 0x2d, 0x0a, 0x02, 0x69, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e,
+	0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x50, 0x69, 0x70, 0x65, 0x4d,
+	0x61, 0x70, 0x2e, 0x50, 0x69, 0x70, 0x65, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x52, 0x02, 0x69, 0x6e,
+	0x12, 0x2f, 0x0a, 0x03, 0x6f, 0x75, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e,
+	0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x50, 0x69, 0x70, 0x65, 0x4d,
+	0x61, 0x70, 0x2e, 0x50, 0x69, 0x70, 0x65, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x52, 0x03, 0x6f, 0x75,
+	0x74, 0x12, 0x14, 0x0a, 0x05, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x05, 0x70, 0x72, 0x6f, 0x78, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x6d,
+	0x61, 0x78, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x6d, 0x61, 0x78, 0x12, 0x1e, 0x0a,
+	0x0a, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x0a, 0x62, 0x75, 0x66, 0x66, 0x65, 0x72, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x24, 0x0a,
+	0x0d, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x0d, 0x74, 0x72, 0x61, 0x6e, 0x73, 0x66, 0x65, 0x72, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x1a, 0x45, 0x0a, 0x09, 0x50, 0x69, 0x70, 0x65, 0x49, 0x6e, 0x64, 0x65, 0x78,
+	0x12, 0x14, 0x0a, 0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x05, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x12, 0x0e, 0x0a, 0x02, 0x66, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x05, 0x52, 0x02, 0x66, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x69, 0x66, 0x6f, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x69, 0x66, 0x6f, 0x22, 0x27, 0x0a, 0x07, 0x52, 0x75,
+	0x6e, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x0c, 0x0a, 0x08, 0x50, 0x61, 0x72, 0x61, 0x6c, 0x6c, 0x65,
+	0x6c, 0x10, 0x00, 0x12, 0x0e, 0x0a, 0x0a, 0x53, 0x65, 0x71, 0x75, 0x65, 0x6e, 0x74, 0x69, 0x61,
+	0x6c, 0x10, 0x01, 0x22, 0x29, 0x0a, 0x08, 0x50, 0x72, 0x69, 0x6f, 0x72, 0x69, 0x74, 0x79, 0x12,
+	0x0a, 0x0a, 0x06, 0x4e, 0x6f, 0x72, 0x6d, 0x61, 0x6c, 0x10, 0x00, 0x12, 0x07, 0x0a, 0x03, 0x4c,
+	0x6f, 0x77, 0x10, 0x01, 0x12, 0x08, 0x0a, 0x04, 0x48, 0x69, 0x67, 0x68, 0x10, 0x02, 0x42, 0x0b,
+	0x0a, 0x09, 0x5f, 0x6e, 0x75, 0x6d, 0x61, 0x4e, 0x6f, 0x64, 0x65, 0x22, 0xc7, 0x23, 0x0a, 0x08,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x72, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x49, 0x44, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x72, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x49, 0x44, 0x12, 0x2d, 0x0a, 0x07, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74,
+	0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x07, 0x72, 0x65,
+	0x73, 0x75, 0x6c, 0x74, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e,
+	0x6f, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x6f, 0x74, 0x65, 0x12,
+	0x22, 0x0a, 0x0c, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x52, 0x65, 0x61,
+	0x73, 0x6f, 0x6e, 0x12, 0x2c, 0x0a, 0x11, 0x65, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x64,
+	0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x11,
+	0x65, 0x73, 0x74, 0x69, 0x6d, 0x61, 0x74, 0x65, 0x64, 0x44, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x08, 0x64, 0x75, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x28, 0x0a,
+	0x05, 0x75, 0x73, 0x61, 0x67, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x12, 0x2e, 0x70,
+	0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x55, 0x73, 0x61, 0x67, 0x65,
+	0x52, 0x05, 0x75, 0x73, 0x61, 0x67, 0x65, 0x1a, 0x8f, 0x04, 0x0a, 0x09, 0x46, 0x69, 0x6c, 0x65,
+	0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x34, 0x0a, 0x04, 0x74, 0x79, 0x70,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x20, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x2e,
+	0x45, 0x72, 0x72, 0x6f, 0x72, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12,
+	0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x9d, 0x03, 0x0a, 0x09, 0x45, 0x72,
+	0x72, 0x6f, 0x72, 0x54, 0x79, 0x70, 0x65, 0x12, 0x12, 0x0a, 0x0e, 0x43, 0x6f, 0x70, 0x79, 0x49,
+	0x6e, 0x4f, 0x70, 0x65, 0x6e, 0x46, 0x69, 0x6c, 0x65, 0x10, 0x00, 0x12, 0x14, 0x0a, 0x10, 0x43,
+	0x6f, 0x70, 0x79, 0x49, 0x6e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x10,
+	0x01, 0x12, 0x15, 0x0a, 0x11, 0x43, 0x6f, 0x70, 0x79, 0x49, 0x6e, 0x43, 0x6f, 0x70, 0x79, 0x43,
+	0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x10, 0x02, 0x12, 0x0f, 0x0a, 0x0b, 0x43, 0x6f, 0x70, 0x79,
+	0x4f, 0x75, 0x74, 0x4f, 0x70, 0x65, 0x6e, 0x10, 0x03, 0x12, 0x19, 0x0a, 0x15, 0x43, 0x6f, 0x70,
+	0x79, 0x4f, 0x75, 0x74, 0x4e, 0x6f, 0x74, 0x52, 0x65, 0x67, 0x75, 0x6c, 0x61, 0x72, 0x46, 0x69,
+	0x6c, 0x65, 0x10, 0x04, 0x12, 0x17, 0x0a, 0x13, 0x43, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x53,
+	0x69, 0x7a, 0x65, 0x45, 0x78, 0x63, 0x65, 0x65, 0x64, 0x65, 0x64, 0x10, 0x05, 0x12, 0x15, 0x0a,
+	0x11, 0x43, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x46, 0x69,
+	0x6c, 0x65, 0x10, 0x06, 0x12, 0x16, 0x0a, 0x12, 0x43, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x43,
+	0x6f, 0x70, 0x79, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x10, 0x07, 0x12, 0x17, 0x0a, 0x13,
+	0x43, 0x6f, 0x6c, 0x6c, 0x65, 0x63, 0x74, 0x53, 0x69, 0x7a, 0x65, 0x45, 0x78, 0x63, 0x65, 0x65,
+	0x64, 0x65, 0x64, 0x10, 0x08, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x79, 0x6d, 0x6c, 0x69, 0x6e, 0x6b,
+	0x10, 0x09, 0x12, 0x18, 0x0a, 0x14, 0x43, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x4c, 0x69, 0x6d,
+	0x69, 0x74, 0x45, 0x78, 0x63, 0x65, 0x65, 0x64, 0x65, 0x64, 0x10, 0x0c, 0x12, 0x13, 0x0a, 0x0f,
+	0x43, 0x6f, 0x70, 0x79, 0x49, 0x6e, 0x43, 0x6f, 0x72, 0x72, 0x75, 0x70, 0x74, 0x65, 0x64, 0x10,
+	0x0d, 0x12, 0x10, 0x0a, 0x0c, 0x45, 0x78, 0x65, 0x63, 0x4e, 0x6f, 0x74, 0x46, 0x6f, 0x75, 0x6e,
+	0x64, 0x10, 0x0e, 0x12, 0x1a, 0x0a, 0x16, 0x45, 0x78, 0x65, 0x63, 0x4d, 0x69, 0x73, 0x73, 0x69,
+	0x6e, 0x67, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x70, 0x72, 0x65, 0x74, 0x65, 0x72, 0x10, 0x0f, 0x12,
+	0x15, 0x0a, 0x11, 0x45, 0x78, 0x65, 0x63, 0x4e, 0x6f, 0x74, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74,
+	0x61, 0x62, 0x6c, 0x65, 0x10, 0x10, 0x12, 0x15, 0x0a, 0x11, 0x45, 0x78, 0x65, 0x63, 0x49, 0x6e,
+	0x76, 0x61, 0x6c, 0x69, 0x64, 0x46, 0x6f, 0x72, 0x6d, 0x61, 0x74, 0x10, 0x11, 0x12, 0x12, 0x0a,
+	0x0e, 0x43, 0x6f, 0x70, 0x79, 0x49, 0x6e, 0x53, 0x6e, 0x61, 0x70, 0x73, 0x68, 0x6f, 0x74, 0x10,
+	0x12, 0x12, 0x16, 0x0a, 0x12, 0x45, 0x78, 0x65, 0x63, 0x4e, 0x65, 0x65, 0x64, 0x73, 0x45, 0x6d,
+	0x75, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x10, 0x13, 0x1a, 0x8e, 0x01, 0x0a, 0x0a, 0x4d, 0x65,
+	0x6d, 0x6f, 0x72, 0x79, 0x53, 0x74, 0x61, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x63, 0x61, 0x63, 0x68,
+	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x63, 0x61, 0x63, 0x68, 0x65, 0x12, 0x10,
+	0x0a, 0x03, 0x72, 0x73, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x03, 0x72, 0x73, 0x73,
+	0x12, 0x1e, 0x0a, 0x0a, 0x6d, 0x61, 0x70, 0x70, 0x65, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x6d, 0x61, 0x70, 0x70, 0x65, 0x64, 0x46, 0x69, 0x6c, 0x65,
+	0x12, 0x18, 0x0a, 0x07, 0x70, 0x67, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x07, 0x70, 0x67, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x70, 0x67,
+	0x6d, 0x61, 0x6a, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a,
+	0x70, 0x67, 0x6d, 0x61, 0x6a, 0x66, 0x61, 0x75, 0x6c, 0x74, 0x1a, 0x32, 0x0a, 0x06, 0x49, 0x4f,
+	0x53, 0x74, 0x61, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x65, 0x61, 0x64, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x04, 0x72, 0x65, 0x61, 0x64, 0x12, 0x14, 0x0a, 0x05, 0x77, 0x72, 0x69, 0x74,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x77, 0x72, 0x69, 0x74, 0x65, 0x1a, 0x6a,
+	0x0a, 0x0a, 0x54, 0x68, 0x72, 0x6f, 0x74, 0x74, 0x6c, 0x69, 0x6e, 0x67, 0x12, 0x18, 0x0a, 0x07,
+	0x70, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x70,
+	0x65, 0x72, 0x69, 0x6f, 0x64, 0x73, 0x12, 0x1c, 0x0a, 0x09, 0x74, 0x68, 0x72, 0x6f, 0x74, 0x74,
+	0x6c, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x74, 0x68, 0x72, 0x6f, 0x74,
+	0x74, 0x6c, 0x65, 0x64, 0x12, 0x24, 0x0a, 0x0d, 0x74, 0x68, 0x72, 0x6f, 0x74, 0x74, 0x6c, 0x65,
+	0x64, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0d, 0x74, 0x68, 0x72,
+	0x6f, 0x74, 0x74, 0x6c, 0x65, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x1a, 0x38, 0x0a, 0x0a, 0x4f, 0x75,
+	0x74, 0x70, 0x75, 0x74, 0x53, 0x74, 0x61, 0x74, 0x12, 0x14, 0x0a, 0x05, 0x62, 0x79, 0x74, 0x65,
+	0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x62, 0x79, 0x74, 0x65, 0x73, 0x12, 0x14,
+	0x0a, 0x05, 0x6c, 0x69, 0x6e, 0x65, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x6c,
+	0x69, 0x6e, 0x65, 0x73, 0x1a, 0x48, 0x0a, 0x08, 0x46, 0x69, 0x6c, 0x65, 0x53, 0x74, 0x61, 0x74,
+	0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x03, 0x52, 0x04,
+	0x73, 0x69, 0x7a, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6d, 0x74, 0x69, 0x6d,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6d, 0x74, 0x69, 0x6d, 0x65, 0x1a, 0x48,
+	0x0a, 0x08, 0x46, 0x69, 0x6c, 0x65, 0x54, 0x79, 0x70, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x63, 0x6f,
+	0x6e, 0x74, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0b, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x1a, 0x0a, 0x08,
+	0x69, 0x73, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08,
+	0x69, 0x73, 0x42, 0x69, 0x6e, 0x61, 0x72, 0x79, 0x1a, 0xc5, 0x01, 0x0a, 0x05, 0x55, 0x73, 0x61,
+	0x67, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x70, 0x75, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x07, 0x63, 0x70, 0x75, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06,
+	0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6d, 0x65,
+	0x6d, 0x6f, 0x72, 0x79, 0x12, 0x24, 0x0a, 0x0d, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x53, 0x65,
+	0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x01, 0x52, 0x0d, 0x6d, 0x65, 0x6d,
+	0x6f, 0x72, 0x79, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x1a, 0x0a, 0x08, 0x77, 0x61,
+	0x6c, 0x6c, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x77, 0x61,
+	0x6c, 0x6c, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x6f, 0x70, 0x79, 0x49, 0x6e,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x63, 0x6f, 0x70, 0x79, 0x49, 0x6e, 0x12, 0x18,
+	0x0a, 0x07, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x07, 0x63, 0x6f, 0x70, 0x79, 0x4f, 0x75, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x61, 0x63, 0x68,
+	0x65, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x63, 0x61, 0x63, 0x68, 0x65, 0x64,
+	0x1a, 0x71, 0x0a, 0x09, 0x52, 0x61, 0x77, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x36, 0x0a,
+	0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1e, 0x2e,
+	0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x54, 0x79, 0x70, 0x65, 0x52, 0x06, 0x73,
+	0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x75, 0x6e,
+	0x54, 0x69, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x72, 0x75, 0x6e, 0x54,
+	0x69, 0x6d, 0x65, 0x1a, 0xdf, 0x16, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x36,
+	0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x1e,
+	0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x2e, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x54, 0x79, 0x70, 0x65, 0x52, 0x06,
+	0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x1e, 0x0a, 0x0a, 0x65, 0x78, 0x69, 0x74, 0x53, 0x74,
+	0x61, 0x74, 0x75, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x65, 0x78, 0x69, 0x74,
+	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04,
+	0x74, 0x69, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x04, 0x74, 0x69, 0x6d, 0x65,
+	0x12, 0x18, 0x0a, 0x07, 0x72, 0x75, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x07, 0x72, 0x75, 0x6e, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x6d, 0x65,
+	0x6d, 0x6f, 0x72, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x06, 0x6d, 0x65, 0x6d, 0x6f,
+	0x72, 0x79, 0x12, 0x34, 0x0a, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x18, 0x06, 0x20, 0x03, 0x28,
+	0x0b, 0x32, 0x1e, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e,
+	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x52, 0x05, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x12, 0x3a, 0x0a, 0x07, 0x66, 0x69, 0x6c, 0x65,
+	0x49, 0x44, 0x73, 0x18, 0x07, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x20, 0x2e, 0x70, 0x62, 0x2e, 0x52,
+	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x46,
+	0x69, 0x6c, 0x65, 0x49, 0x44, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x07, 0x66, 0x69, 0x6c,
+	0x65, 0x49, 0x44, 0x73, 0x12, 0x34, 0x0a, 0x09, 0x66, 0x69, 0x6c, 0x65, 0x45, 0x72, 0x72, 0x6f,
+	0x72, 0x18, 0x09, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x52,
+	0x09, 0x66, 0x69, 0x6c, 0x65, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x40, 0x0a, 0x09, 0x65, 0x78,
+	0x74, 0x72, 0x61, 0x63, 0x74, 0x65, 0x64, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e,
+	0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52, 0x65, 0x73, 0x75,
+	0x6c, 0x74, 0x2e, 0x45, 0x78, 0x74, 0x72, 0x61, 0x63, 0x74, 0x65, 0x64, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x52, 0x09, 0x65, 0x78, 0x74, 0x72, 0x61, 0x63, 0x74, 0x65, 0x64, 0x12, 0x16, 0x0a, 0x06,
+	0x73, 0x69, 0x67, 0x6e, 0x61, 0x6c, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x05, 0x52, 0x06, 0x73, 0x69,
+	0x67, 0x6e, 0x61, 0x6c, 0x12, 0x22, 0x0a, 0x0c, 0x74, 0x65, 0x72, 0x6d, 0x69, 0x6e, 0x61, 0x74,
+	0x65, 0x64, 0x42, 0x79, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x74, 0x65, 0x72, 0x6d,
+	0x69, 0x6e, 0x61, 0x74, 0x65, 0x64, 0x42, 0x79, 0x12, 0x37, 0x0a, 0x0a, 0x6d, 0x65, 0x6d, 0x6f,
+	0x72, 0x79, 0x53, 0x74, 0x61, 0x74, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x70,
+	0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x4d, 0x65, 0x6d, 0x6f, 0x72,
+	0x79, 0x53, 0x74, 0x61, 0x74, 0x52, 0x0a, 0x6d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x53, 0x74, 0x61,
+	0x74, 0x12, 0x3d, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x0e, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x53, 0x69, 0x7a,
+	0x65, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x53, 0x69, 0x7a, 0x65,
+	0x12, 0x18, 0x0a, 0x07, 0x77, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x18, 0x0f, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x07, 0x77, 0x61, 0x72, 0x6e, 0x69, 0x6e, 0x67, 0x12, 0x1a, 0x0a, 0x08, 0x70, 0x72,
+	0x6f, 0x63, 0x50, 0x65, 0x61, 0x6b, 0x18, 0x10, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x70, 0x72,
+	0x6f, 0x63, 0x50, 0x65, 0x61, 0x6b, 0x12, 0x18, 0x0a, 0x07, 0x77, 0x6f, 0x72, 0x6b, 0x44, 0x69,
+	0x72, 0x18, 0x11, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x77, 0x6f, 0x72, 0x6b, 0x44, 0x69, 0x72,
+	0x12, 0x40, 0x0a, 0x09, 0x70, 0x69, 0x70, 0x65, 0x42, 0x79, 0x74, 0x65, 0x73, 0x18, 0x12, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x22, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x50, 0x69, 0x70, 0x65, 0x42, 0x79, 0x74,
+	0x65, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x09, 0x70, 0x69, 0x70, 0x65, 0x42, 0x79, 0x74,
+	0x65, 0x73, 0x12, 0x43, 0x0a, 0x0a, 0x6f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x53, 0x74, 0x61, 0x74,
+	0x18, 0x13, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x23, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x4f, 0x75, 0x74, 0x70,
+	0x75, 0x74, 0x53, 0x74, 0x61, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0a, 0x6f, 0x75, 0x74,
+	0x70, 0x75, 0x74, 0x53, 0x74, 0x61, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x73, 0x74, 0x61, 0x72, 0x74,
+	0x65, 0x64, 0x41, 0x74, 0x18, 0x14, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x73, 0x74, 0x61, 0x72,
+	0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x68, 0x6f, 0x73, 0x74, 0x6e, 0x61, 0x6d,
+	0x65, 0x18, 0x15, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x68, 0x6f, 0x73, 0x74, 0x6e, 0x61, 0x6d,
+	0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x49, 0x44, 0x18,
+	0x16, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63, 0x65, 0x49,
+	0x44, 0x12, 0x2b, 0x0a, 0x06, 0x69, 0x6f, 0x53, 0x74, 0x61, 0x74, 0x18, 0x17, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x13, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e,
+	0x49, 0x4f, 0x53, 0x74, 0x61, 0x74, 0x52, 0x06, 0x69, 0x6f, 0x53, 0x74, 0x61, 0x74, 0x12, 0x1e,
+	0x0a, 0x0a, 0x63, 0x6f, 0x72, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x44, 0x18, 0x18, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0a, 0x63, 0x6f, 0x72, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x44, 0x12, 0x1a,
+	0x0a, 0x08, 0x63, 0x6f, 0x72, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x19, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x08, 0x63, 0x6f, 0x72, 0x65, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x49, 0x0a, 0x0c, 0x73, 0x79,
+	0x73, 0x63, 0x61, 0x6c, 0x6c, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x1a, 0x20, 0x03, 0x28, 0x0b,
+	0x32, 0x25, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52,
+	0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x53, 0x79, 0x73, 0x63, 0x61, 0x6c, 0x6c, 0x43, 0x6f, 0x75,
+	0x6e, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0c, 0x73, 0x79, 0x73, 0x63, 0x61, 0x6c, 0x6c,
+	0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x3d, 0x0a, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x53, 0x74, 0x61,
+	0x74, 0x18, 0x1b, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x2e, 0x46, 0x69, 0x6c,
+	0x65, 0x53, 0x74, 0x61, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65,
+	0x53, 0x74, 0x61, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x73, 0x74, 0x72, 0x61, 0x79, 0x50, 0x72, 0x6f,
+	0x63, 0x73, 0x18, 0x1c, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x73, 0x74, 0x72, 0x61, 0x79, 0x50,
+	0x72, 0x6f, 0x63, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x61, 0x72, 0x63, 0x68, 0x18, 0x1d, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x04, 0x61, 0x72, 0x63, 0x68, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x68, 0x6d, 0x55,
+	0x73, 0x61, 0x67, 0x65, 0x18, 0x1e, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x73, 0x68, 0x6d, 0x55,
+	0x73, 0x61, 0x67, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x69, 0x6e, 0x69, 0x74, 0x4c, 0x6f, 0x67, 0x18,
+	0x1f, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x69, 0x6e, 0x69, 0x74, 0x4c, 0x6f, 0x67, 0x12, 0x1c,
+	0x0a, 0x09, 0x64, 0x69, 0x73, 0x6b, 0x55, 0x73, 0x61, 0x67, 0x65, 0x18, 0x20, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x09, 0x64, 0x69, 0x73, 0x6b, 0x55, 0x73, 0x61, 0x67, 0x65, 0x12, 0x28, 0x0a, 0x0f,
+	0x6f, 0x76, 0x65, 0x72, 0x68, 0x65, 0x61, 0x64, 0x43, 0x70, 0x75, 0x54, 0x69, 0x6d, 0x65, 0x18,
+	0x21, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0f, 0x6f, 0x76, 0x65, 0x72, 0x68, 0x65, 0x61, 0x64, 0x43,
+	0x70, 0x75, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x22, 0x0a, 0x0c, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c,
+	0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x22, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x63, 0x61,
+	0x6e, 0x63, 0x65, 0x6c, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x37, 0x0a, 0x0a, 0x74, 0x68,
+	0x72, 0x6f, 0x74, 0x74, 0x6c, 0x69, 0x6e, 0x67, 0x18, 0x23, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17,
+	0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x54, 0x68, 0x72,
+	0x6f, 0x74, 0x74, 0x6c, 0x69, 0x6e, 0x67, 0x52, 0x0a, 0x74, 0x68, 0x72, 0x6f, 0x74, 0x74, 0x6c,
+	0x69, 0x6e, 0x67, 0x12, 0x1a, 0x0a, 0x08, 0x65, 0x6d, 0x75, 0x6c, 0x61, 0x74, 0x65, 0x64, 0x18,
+	0x24, 0x20, 0x01, 0x28, 0x08, 0x52, 0x08, 0x65, 0x6d, 0x75, 0x6c, 0x61, 0x74, 0x65, 0x64, 0x12,
+	0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x25, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x28, 0x0a, 0x03, 0x72, 0x61, 0x77,
+	0x18, 0x26, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52, 0x61, 0x77, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x52, 0x03,
+	0x72, 0x61, 0x77, 0x12, 0x23, 0x0a, 0x0a, 0x72, 0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x53, 0x65, 0x65,
+	0x64, 0x18, 0x27, 0x20, 0x01, 0x28, 0x04, 0x48, 0x00, 0x52, 0x0a, 0x72, 0x61, 0x6e, 0x64, 0x6f,
+	0x6d, 0x53, 0x65, 0x65, 0x64, 0x88, 0x01, 0x01, 0x12, 0x22, 0x0a, 0x0c, 0x72, 0x61, 0x6e, 0x64,
+	0x6f, 0x6d, 0x4f, 0x70, 0x65, 0x6e, 0x65, 0x64, 0x18, 0x28, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0c,
+	0x72, 0x61, 0x6e, 0x64, 0x6f, 0x6d, 0x4f, 0x70, 0x65, 0x6e, 0x65, 0x64, 0x12, 0x3d, 0x0a, 0x08,
+	0x66, 0x69, 0x6c, 0x65, 0x54, 0x79, 0x70, 0x65, 0x18, 0x29, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x21,
+	0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x52, 0x65, 0x73,
+	0x75, 0x6c, 0x74, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x54, 0x79, 0x70, 0x65, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x52, 0x08, 0x66, 0x69, 0x6c, 0x65, 0x54, 0x79, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x67,
+	0x72, 0x6f, 0x75, 0x70, 0x49, 0x44, 0x18, 0x2a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x67, 0x72,
+	0x6f, 0x75, 0x70, 0x49, 0x44, 0x12, 0x24, 0x0a, 0x0d, 0x65, 0x6e, 0x76, 0x69, 0x72, 0x6f, 0x6e,
+	0x6d, 0x65, 0x6e, 0x74, 0x49, 0x44, 0x18, 0x2b, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0d, 0x65, 0x6e,
+	0x76, 0x69, 0x72, 0x6f, 0x6e, 0x6d, 0x65, 0x6e, 0x74, 0x49, 0x44, 0x12, 0x1a, 0x0a, 0x08, 0x63,
+	0x67, 0x72, 0x6f, 0x75, 0x70, 0x49, 0x44, 0x18, 0x2c, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x63,
+	0x67, 0x72, 0x6f, 0x75, 0x70, 0x49, 0x44, 0x12, 0x22, 0x0a, 0x0c, 0x77, 0x6f, 0x72, 0x6b, 0x44,
+	0x69, 0x72, 0x55, 0x73, 0x61, 0x67, 0x65, 0x18, 0x2d, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x77,
+	0x6f, 0x72, 0x6b, 0x44, 0x69, 0x72, 0x55, 0x73, 0x61, 0x67, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x77,
+	0x6f, 0x72, 0x6b, 0x44, 0x69, 0x72, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x2e, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x0b, 0x77, 0x6f, 0x72, 0x6b, 0x44, 0x69, 0x72, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x24, 0x0a,
+	0x0d, 0x6e, 0x6f, 0x69, 0x73, 0x79, 0x4e, 0x65, 0x69, 0x67, 0x68, 0x62, 0x6f, 0x72, 0x18, 0x2f,
+	0x20, 0x03, 0x28, 0x09, 0x52, 0x0d, 0x6e, 0x6f, 0x69, 0x73, 0x79, 0x4e, 0x65, 0x69, 0x67, 0x68,
+	0x62, 0x6f, 0x72, 0x1a, 0x38, 0x0a, 0x0a, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x45, 0x6e, 0x74, 0x72,
+	0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03,
+	0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3a, 0x0a,
+	0x0c, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x44, 0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a,
+	0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12,
+	0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05,
+	0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3c, 0x0a, 0x0e, 0x45, 0x78, 0x74,
+	0x72, 0x61, 0x63, 0x74, 0x65, 0x64, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b,
+	0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3b, 0x0a, 0x0d, 0x46, 0x69, 0x6c, 0x65, 0x53,
+	0x69, 0x7a, 0x65, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61,
+	0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3c, 0x0a, 0x0e, 0x50, 0x69, 0x70, 0x65, 0x42, 0x79, 0x74, 0x65,
+	0x73, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75,
+	0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02,
+	0x38, 0x01, 0x1a, 0x56, 0x0a, 0x0f, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x53, 0x74, 0x61, 0x74,
+	0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x2d, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x53, 0x74, 0x61, 0x74, 0x52,
+	0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x3f, 0x0a, 0x11, 0x53, 0x79,
+	0x73, 0x63, 0x61, 0x6c, 0x6c, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
 	0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65,
-	0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x22, 0xa2, 0x02, 0x0a, 0x0a,
-	0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x54, 0x79, 0x70, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x49, 0x6e,
-	0x76, 0x61, 0x6c, 0x69, 0x64, 0x10, 0x00, 0x12, 0x0c, 0x0a, 0x08, 0x41, 0x63, 0x63, 0x65, 0x70,
-	0x74, 0x65, 0x64, 0x10, 0x01, 0x12, 0x0f, 0x0a, 0x0b, 0x57, 0x72, 0x6f, 0x6e, 0x67, 0x41, 0x6e,
-	0x73, 0x77, 0x65, 0x72, 0x10, 0x02, 0x12, 0x14, 0x0a, 0x10, 0x50, 0x61, 0x72, 0x74, 0x69, 0x61,
-	0x6c, 0x6c, 0x79, 0x43, 0x6f, 0x72, 0x72, 0x65, 0x63, 0x74, 0x10, 0x03, 0x12, 0x17, 0x0a, 0x13,
-	0x4d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x45, 0x78, 0x63, 0x65, 0x65,
-	0x64, 0x65, 0x64, 0x10, 0x04, 0x12, 0x15, 0x0a, 0x11, 0x54, 0x69, 0x6d, 0x65, 0x4c, 0x69, 0x6d,
-	0x69, 0x74, 0x45, 0x78, 0x63, 0x65, 0x65, 0x64, 0x65, 0x64, 0x10, 0x05, 0x12, 0x17, 0x0a, 0x13,
-	0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x45, 0x78, 0x63, 0x65, 0x65,
-	0x64, 0x65, 0x64, 0x10, 0x06, 0x12, 0x0d, 0x0a, 0x09, 0x46, 0x69, 0x6c, 0x65, 0x45, 0x72, 0x72,
-	0x6f, 0x72, 0x10, 0x07, 0x12, 0x15, 0x0a, 0x11, 0x4e, 0x6f, 0x6e, 0x5a, 0x65, 0x72, 0x6f, 0x45,
-	0x78, 0x69, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x10, 0x08, 0x12, 0x0d, 0x0a, 0x09, 0x53,
-	0x69, 0x67, 0x6e, 0x61, 0x6c, 0x6c, 0x65, 0x64, 0x10, 0x09, 0x12, 0x14, 0x0a, 0x10, 0x44, 0x61,
-	0x6e, 0x67, 0x65, 0x72, 0x6f, 0x75, 0x73, 0x53, 0x79, 0x73, 0x63, 0x61, 0x6c, 0x6c, 0x10, 0x0a,
-	0x12, 0x13, 0x0a, 0x0f, 0x4a, 0x75, 0x64, 0x67, 0x65, 0x6d, 0x65, 0x6e, 0x74, 0x46, 0x61, 0x69,
-	0x6c, 0x65, 0x64, 0x10, 0x0b, 0x12, 0x16, 0x0a, 0x12, 0x49, 0x6e, 0x76, 0x61, 0x6c, 0x69, 0x64,
-	0x49, 0x6e, 0x74, 0x65, 0x72, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x10, 0x0c, 0x12, 0x11, 0x0a,
-	0x0d, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61, 0x6c, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x10, 0x0d,
-	0x22, 0xd9, 0x02, 0x0a, 0x0d, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65,
-	0x73, 0x74, 0x12, 0x2f, 0x0a, 0x0b, 0x65, 0x78, 0x65, 0x63, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
-	0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x48, 0x00, 0x52, 0x0b, 0x65, 0x78, 0x65, 0x63, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x12, 0x37, 0x0a, 0x09, 0x65, 0x78, 0x65, 0x63, 0x49, 0x6e, 0x70, 0x75, 0x74,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x70, 0x62, 0x2e, 0x53, 0x74, 0x72, 0x65,
-	0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x48,
-	0x00, 0x52, 0x09, 0x65, 0x78, 0x65, 0x63, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x3a, 0x0a, 0x0a,
-	0x65, 0x78, 0x65, 0x63, 0x52, 0x65, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b,
-	0x32, 0x18, 0x2e, 0x70, 0x62, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x2e, 0x52, 0x65, 0x73, 0x69, 0x7a, 0x65, 0x48, 0x00, 0x52, 0x0a, 0x65, 0x78,
-	0x65, 0x63, 0x52, 0x65, 0x73, 0x69, 0x7a, 0x65, 0x1a, 0x35, 0x0a, 0x05, 0x49, 0x6e, 0x70, 0x75,
-	0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x1a,
-	0x60, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d,
-	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x12, 0x0a,
-	0x04, 0x72, 0x6f, 0x77, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x72, 0x6f, 0x77,
-	0x73, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x6c, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0d, 0x52,
-	0x04, 0x63, 0x6f, 0x6c, 0x73, 0x12, 0x0c, 0x0a, 0x01, 0x78, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d,
-	0x52, 0x01, 0x78, 0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x01,
-	0x79, 0x42, 0x09, 0x0a, 0x07, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xc5, 0x01, 0x0a,
-	0x0e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12,
-	0x32, 0x0a, 0x0c, 0x65, 0x78, 0x65, 0x63, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x48, 0x00, 0x52, 0x0c, 0x65, 0x78, 0x65, 0x63, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x12, 0x3b, 0x0a, 0x0a, 0x65, 0x78, 0x65, 0x63, 0x4f, 0x75, 0x74, 0x70, 0x75,
-	0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x70, 0x62, 0x2e, 0x53, 0x74, 0x72,
-	0x65, 0x61, 0x6d, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x4f, 0x75, 0x74, 0x70,
-	0x75, 0x74, 0x48, 0x00, 0x52, 0x0a, 0x65, 0x78, 0x65, 0x63, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74,
-	0x1a, 0x36, 0x0a, 0x06, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61,
-	0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18,
-	0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52,
-	0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x42, 0x0a, 0x0a, 0x08, 0x72, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x32, 0x9e, 0x02, 0x0a, 0x08, 0x45, 0x78, 0x65, 0x63, 0x75, 0x74, 0x6f,
-	0x72, 0x12, 0x21, 0x0a, 0x04, 0x45, 0x78, 0x65, 0x63, 0x12, 0x0b, 0x2e, 0x70, 0x62, 0x2e, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0c, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x37, 0x0a, 0x0a, 0x45, 0x78, 0x65, 0x63, 0x53, 0x74, 0x72, 0x65,
-	0x61, 0x6d, 0x12, 0x11, 0x2e, 0x70, 0x62, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x70, 0x62, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61,
-	0x6d, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28, 0x01, 0x30, 0x01, 0x12, 0x34, 0x0a,
-	0x08, 0x46, 0x69, 0x6c, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67,
-	0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74,
-	0x79, 0x1a, 0x10, 0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x54,
-	0x79, 0x70, 0x65, 0x12, 0x26, 0x0a, 0x07, 0x46, 0x69, 0x6c, 0x65, 0x47, 0x65, 0x74, 0x12, 0x0a,
-	0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x44, 0x1a, 0x0f, 0x2e, 0x70, 0x62, 0x2e,
-	0x46, 0x69, 0x6c, 0x65, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x12, 0x26, 0x0a, 0x07, 0x46,
-	0x69, 0x6c, 0x65, 0x41, 0x64, 0x64, 0x12, 0x0f, 0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x6c, 0x65,
-	0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x1a, 0x0a, 0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x6c,
-	0x65, 0x49, 0x44, 0x12, 0x30, 0x0a, 0x0a, 0x46, 0x69, 0x6c, 0x65, 0x44, 0x65, 0x6c, 0x65, 0x74,
-	0x65, 0x12, 0x0a, 0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x44, 0x1a, 0x16, 0x2e,
-	0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e,
-	0x45, 0x6d, 0x70, 0x74, 0x79, 0x42, 0x1f, 0x5a, 0x1d, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e,
-	0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x72, 0x69, 0x79, 0x6c, 0x65, 0x2f, 0x67, 0x6f, 0x2d, 0x6a, 0x75,
-	0x64, 0x67, 0x65, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x79, 0x12, 0x14, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a, 0x52, 0x0a, 0x0d, 0x46,
+	0x69, 0x6c, 0x65, 0x53, 0x74, 0x61, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12, 0x10, 0x0a, 0x03,
+	0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b, 0x65, 0x79, 0x12, 0x2b,
+	0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e,
+	0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e, 0x46, 0x69, 0x6c, 0x65,
+	0x53, 0x74, 0x61, 0x74, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a, 0x02, 0x38, 0x01, 0x1a,
+	0x52, 0x0a, 0x0d, 0x46, 0x69, 0x6c, 0x65, 0x54, 0x79, 0x70, 0x65, 0x45, 0x6e, 0x74, 0x72, 0x79,
+	0x12, 0x10, 0x0a, 0x03, 0x6b, 0x65, 0x79, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x6b,
+	0x65, 0x79, 0x12, 0x2b, 0x0a, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x15, 0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x2e,
+	0x46, 0x69, 0x6c, 0x65, 0x54, 0x79, 0x70, 0x65, 0x52, 0x05, 0x76, 0x61, 0x6c, 0x75, 0x65, 0x3a,
+	0x02, 0x38, 0x01, 0x22, 0xa0, 0x03, 0x0a, 0x0a, 0x53, 0x74, 0x61, 0x74, 0x75, 0x73, 0x54, 0x79,
+	0x70, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x49, 0x6e, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x10, 0x00, 0x12,
+	0x0c, 0x0a, 0x08, 0x41, 0x63, 0x63, 0x65, 0x70, 0x74, 0x65, 0x64, 0x10, 0x01, 0x12, 0x0f, 0x0a,
+	0x0b, 0x57, 0x72, 0x6f, 0x6e, 0x67, 0x41, 0x6e, 0x73, 0x77, 0x65, 0x72, 0x10, 0x02, 0x12, 0x14,
+	0x0a, 0x10, 0x50, 0x61, 0x72, 0x74, 0x69, 0x61, 0x6c, 0x6c, 0x79, 0x43, 0x6f, 0x72, 0x72, 0x65,
+	0x63, 0x74, 0x10, 0x03, 0x12, 0x17, 0x0a, 0x13, 0x4d, 0x65, 0x6d, 0x6f, 0x72, 0x79, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x45, 0x78, 0x63, 0x65, 0x65, 0x64, 0x65, 0x64, 0x10, 0x04, 0x12, 0x15, 0x0a,
+	0x11, 0x54, 0x69, 0x6d, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x45, 0x78, 0x63, 0x65, 0x65, 0x64,
+	0x65, 0x64, 0x10, 0x05, 0x12, 0x17, 0x0a, 0x13, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x45, 0x78, 0x63, 0x65, 0x65, 0x64, 0x65, 0x64, 0x10, 0x06, 0x12, 0x0d, 0x0a,
+	0x09, 0x46, 0x69, 0x6c, 0x65, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x10, 0x07, 0x12, 0x15, 0x0a, 0x11,
+	0x4e, 0x6f, 0x6e, 0x5a, 0x65, 0x72, 0x6f, 0x45, 0x78, 0x69, 0x74, 0x53, 0x74, 0x61, 0x74, 0x75,
+	0x73, 0x10, 0x08, 0x12, 0x0d, 0x0a, 0x09, 0x53, 0x69, 0x67, 0x6e, 0x61, 0x6c, 0x6c, 0x65, 0x64,
+	0x10, 0x09, 0x12, 0x14, 0x0a, 0x10, 0x44, 0x61, 0x6e, 0x67, 0x65, 0x72, 0x6f, 0x75, 0x73, 0x53,
+	0x79, 0x73, 0x63, 0x61, 0x6c, 0x6c, 0x10, 0x0a, 0x12, 0x13, 0x0a, 0x0f, 0x4a, 0x75, 0x64, 0x67,
+	0x65, 0x6d, 0x65, 0x6e, 0x74, 0x46, 0x61, 0x69, 0x6c, 0x65, 0x64, 0x10, 0x0b, 0x12, 0x16, 0x0a,
+	0x12, 0x49, 0x6e, 0x76, 0x61, 0x6c, 0x69, 0x64, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x10, 0x0c, 0x12, 0x11, 0x0a, 0x0d, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x6e, 0x61,
+	0x6c, 0x45, 0x72, 0x72, 0x6f, 0x72, 0x10, 0x0d, 0x12, 0x18, 0x0a, 0x14, 0x50, 0x72, 0x6f, 0x63,
+	0x65, 0x73, 0x73, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x45, 0x78, 0x63, 0x65, 0x65, 0x64, 0x65, 0x64,
+	0x10, 0x10, 0x12, 0x1b, 0x0a, 0x17, 0x55, 0x6e, 0x73, 0x75, 0x70, 0x70, 0x6f, 0x72, 0x74, 0x65,
+	0x64, 0x41, 0x72, 0x63, 0x68, 0x69, 0x74, 0x65, 0x63, 0x74, 0x75, 0x72, 0x65, 0x10, 0x11, 0x12,
+	0x15, 0x0a, 0x11, 0x50, 0x69, 0x70, 0x65, 0x4c, 0x69, 0x6d, 0x69, 0x74, 0x45, 0x78, 0x63, 0x65,
+	0x65, 0x64, 0x65, 0x64, 0x10, 0x12, 0x12, 0x15, 0x0a, 0x11, 0x44, 0x69, 0x73, 0x6b, 0x4c, 0x69,
+	0x6d, 0x69, 0x74, 0x45, 0x78, 0x63, 0x65, 0x65, 0x64, 0x65, 0x64, 0x10, 0x13, 0x12, 0x0b, 0x0a,
+	0x07, 0x53, 0x6b, 0x69, 0x70, 0x70, 0x65, 0x64, 0x10, 0x14, 0x22, 0x04, 0x08, 0x0e, 0x10, 0x0e,
+	0x22, 0x04, 0x08, 0x0f, 0x10, 0x0f, 0x42, 0x0d, 0x0a, 0x0b, 0x5f, 0x72, 0x61, 0x6e, 0x64, 0x6f,
+	0x6d, 0x53, 0x65, 0x65, 0x64, 0x22, 0xd9, 0x02, 0x0a, 0x0d, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x2f, 0x0a, 0x0b, 0x65, 0x78, 0x65, 0x63, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x70,
+	0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x48, 0x00, 0x52, 0x0b, 0x65, 0x78, 0x65,
+	0x63, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x37, 0x0a, 0x09, 0x65, 0x78, 0x65, 0x63,
+	0x49, 0x6e, 0x70, 0x75, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x70, 0x62,
+	0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x49,
+	0x6e, 0x70, 0x75, 0x74, 0x48, 0x00, 0x52, 0x09, 0x65, 0x78, 0x65, 0x63, 0x49, 0x6e, 0x70, 0x75,
+	0x74, 0x12, 0x3a, 0x0a, 0x0a, 0x65, 0x78, 0x65, 0x63, 0x52, 0x65, 0x73, 0x69, 0x7a, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x70, 0x62, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61,
+	0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x2e, 0x52, 0x65, 0x73, 0x69, 0x7a, 0x65, 0x48,
+	0x00, 0x52, 0x0a, 0x65, 0x78, 0x65, 0x63, 0x52, 0x65, 0x73, 0x69, 0x7a, 0x65, 0x1a, 0x35, 0x0a,
+	0x05, 0x49, 0x6e, 0x70, 0x75, 0x74, 0x12, 0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f,
+	0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x63, 0x6f, 0x6e,
+	0x74, 0x65, 0x6e, 0x74, 0x1a, 0x60, 0x0a, 0x06, 0x52, 0x65, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x12,
+	0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e, 0x61,
+	0x6d, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d,
+	0x52, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x6c, 0x73, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x63, 0x6f, 0x6c, 0x73, 0x12, 0x0c, 0x0a, 0x01, 0x78, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x01, 0x78, 0x12, 0x0c, 0x0a, 0x01, 0x79, 0x18, 0x05, 0x20,
+	0x01, 0x28, 0x0d, 0x52, 0x01, 0x79, 0x42, 0x09, 0x0a, 0x07, 0x72, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x22, 0xc5, 0x01, 0x0a, 0x0e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x32, 0x0a, 0x0c, 0x65, 0x78, 0x65, 0x63, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0c, 0x2e, 0x70, 0x62, 0x2e,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x48, 0x00, 0x52, 0x0c, 0x65, 0x78, 0x65, 0x63,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3b, 0x0a, 0x0a, 0x65, 0x78, 0x65, 0x63,
+	0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x19, 0x2e, 0x70,
+	0x62, 0x2e, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x2e, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x48, 0x00, 0x52, 0x0a, 0x65, 0x78, 0x65, 0x63, 0x4f,
+	0x75, 0x74, 0x70, 0x75, 0x74, 0x1a, 0x36, 0x0a, 0x06, 0x4f, 0x75, 0x74, 0x70, 0x75, 0x74, 0x12,
+	0x12, 0x0a, 0x04, 0x6e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x6e,
+	0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x42, 0x0a, 0x0a,
+	0x08, 0x72, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x32, 0x9e, 0x02, 0x0a, 0x08, 0x45, 0x78,
+	0x65, 0x63, 0x75, 0x74, 0x6f, 0x72, 0x12, 0x21, 0x0a, 0x04, 0x45, 0x78, 0x65, 0x63, 0x12, 0x0b,
+	0x2e, 0x70, 0x62, 0x2e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0c, 0x2e, 0x70, 0x62,
+	0x2e, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x37, 0x0a, 0x0a, 0x45, 0x78, 0x65,
+	0x63, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x11, 0x2e, 0x70, 0x62, 0x2e, 0x53, 0x74, 0x72,
+	0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x70, 0x62, 0x2e,
+	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x28, 0x01,
+	0x30, 0x01, 0x12, 0x34, 0x0a, 0x08, 0x46, 0x69, 0x6c, 0x65, 0x4c, 0x69, 0x73, 0x74, 0x12, 0x16,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x10, 0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x6c, 0x65,
+	0x4c, 0x69, 0x73, 0x74, 0x54, 0x79, 0x70, 0x65, 0x12, 0x26, 0x0a, 0x07, 0x46, 0x69, 0x6c, 0x65,
+	0x47, 0x65, 0x74, 0x12, 0x0a, 0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x44, 0x1a,
+	0x0f, 0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74,
+	0x12, 0x26, 0x0a, 0x07, 0x46, 0x69, 0x6c, 0x65, 0x41, 0x64, 0x64, 0x12, 0x0f, 0x2e, 0x70, 0x62,
+	0x2e, 0x46, 0x69, 0x6c, 0x65, 0x43, 0x6f, 0x6e, 0x74, 0x65, 0x6e, 0x74, 0x1a, 0x0a, 0x2e, 0x70,
+	0x62, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x44, 0x12, 0x30, 0x0a, 0x0a, 0x46, 0x69, 0x6c, 0x65,
+	0x44, 0x65, 0x6c, 0x65, 0x74, 0x65, 0x12, 0x0a, 0x2e, 0x70, 0x62, 0x2e, 0x46, 0x69, 0x6c, 0x65,
+	0x49, 0x44, 0x1a, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x42, 0x1f, 0x5a, 0x1d, 0x67, 0x69,
+	0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x63, 0x72, 0x69, 0x79, 0x6c, 0x65, 0x2f,
+	0x67, 0x6f, 0x2d, 0x6a, 0x75, 0x64, 0x67, 0x65, 0x2f, 0x70, 0x62, 0x62, 0x06, 0x70, 0x72, 0x6f,
+	0x74, 0x6f, 0x33,
 }
 
 var (
@@ -2077,87 +3944,130 @@ func file_judge_proto_rawDescGZIP() []byte {
 	return file_judge_proto_rawDescData
 }
 
-var file_judge_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_judge_proto_msgTypes = make([]protoimpl.MessageInfo, 28)
+var file_judge_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_judge_proto_msgTypes = make([]protoimpl.MessageInfo, 46)
 var file_judge_proto_goTypes = []interface{}{
-	(Response_FileError_ErrorType)(0), // 0: pb.Response.FileError.ErrorType
-	(Response_Result_StatusType)(0),   // 1: pb.Response.Result.StatusType
-	(*FileID)(nil),                    // 2: pb.FileID
-	(*FileContent)(nil),               // 3: pb.FileContent
-	(*FileListType)(nil),              // 4: pb.FileListType
-	(*Request)(nil),                   // 5: pb.Request
-	(*Response)(nil),                  // 6: pb.Response
-	(*StreamRequest)(nil),             // 7: pb.StreamRequest
-	(*StreamResponse)(nil),            // 8: pb.StreamResponse
-	nil,                               // 9: pb.FileListType.FileIDsEntry
-	(*Request_LocalFile)(nil),         // 10: pb.Request.LocalFile
-	(*Request_MemoryFile)(nil),        // 11: pb.Request.MemoryFile
-	(*Request_CachedFile)(nil),        // 12: pb.Request.CachedFile
-	(*Request_PipeCollector)(nil),     // 13: pb.Request.PipeCollector
-	(*Request_StreamInput)(nil),       // 14: pb.Request.StreamInput
-	(*Request_StreamOutput)(nil),      // 15: pb.Request.StreamOutput
-	(*Request_File)(nil),              // 16: pb.Request.File
-	(*Request_CmdType)(nil),           // 17: pb.Request.CmdType
-	(*Request_CmdCopyOutFile)(nil),    // 18: pb.Request.CmdCopyOutFile
-	(*Request_PipeMap)(nil),           // 19: pb.Request.PipeMap
-	nil,                               // 20: pb.Request.CmdType.CopyInEntry
-	nil,                               // 21: pb.Request.CmdType.SymlinksEntry
-	(*Request_PipeMap_PipeIndex)(nil), // 22: pb.Request.PipeMap.PipeIndex
-	(*Response_FileError)(nil),        // 23: pb.Response.FileError
-	(*Response_Result)(nil),           // 24: pb.Response.Result
-	nil,                               // 25: pb.Response.Result.FilesEntry
-	nil,                               // 26: pb.Response.Result.FileIDsEntry
-	(*StreamRequest_Input)(nil),       // 27: pb.StreamRequest.Input
-	(*StreamRequest_Resize)(nil),      // 28: pb.StreamRequest.Resize
-	(*StreamResponse_Output)(nil),     // 29: pb.StreamResponse.Output
-	(*emptypb.Empty)(nil),             // 30: google.protobuf.Empty
+	(Request_RunMode)(0),              // 0: pb.Request.RunMode
+	(Request_Priority)(0),             // 1: pb.Request.Priority
+	(Response_FileError_ErrorType)(0), // 2: pb.Response.FileError.ErrorType
+	(Response_Result_StatusType)(0),   // 3: pb.Response.Result.StatusType
+	(*FileID)(nil),                    // 4: pb.FileID
+	(*FileContent)(nil),               // 5: pb.FileContent
+	(*FileListType)(nil),              // 6: pb.FileListType
+	(*Request)(nil),                   // 7: pb.Request
+	(*Response)(nil),                  // 8: pb.Response
+	(*StreamRequest)(nil),             // 9: pb.StreamRequest
+	(*StreamResponse)(nil),            // 10: pb.StreamResponse
+	nil,                               // 11: pb.FileListType.FileIDsEntry
+	(*Request_LocalFile)(nil),         // 12: pb.Request.LocalFile
+	(*Request_MemoryFile)(nil),        // 13: pb.Request.MemoryFile
+	(*Request_CachedFile)(nil),        // 14: pb.Request.CachedFile
+	(*Request_CachedGroup)(nil),       // 15: pb.Request.CachedGroup
+	(*Request_PipeCollector)(nil),     // 16: pb.Request.PipeCollector
+	(*Request_StreamInput)(nil),       // 17: pb.Request.StreamInput
+	(*Request_StreamOutput)(nil),      // 18: pb.Request.StreamOutput
+	(*Request_File)(nil),              // 19: pb.Request.File
+	(*Request_CmdType)(nil),           // 20: pb.Request.CmdType
+	(*Request_CmdCopyOutFile)(nil),    // 21: pb.Request.CmdCopyOutFile
+	(*Request_PipeMap)(nil),           // 22: pb.Request.PipeMap
+	nil,                               // 23: pb.Request.CmdType.ExtraFilesEntry
+	nil,                               // 24: pb.Request.CmdType.CopyInEntry
+	nil,                               // 25: pb.Request.CmdType.SymlinksEntry
+	nil,                               // 26: pb.Request.CmdType.MountFilesEntry
+	(*Request_PipeMap_PipeIndex)(nil), // 27: pb.Request.PipeMap.PipeIndex
+	(*Response_FileError)(nil),        // 28: pb.Response.FileError
+	(*Response_MemoryStat)(nil),       // 29: pb.Response.MemoryStat
+	(*Response_IOStat)(nil),           // 30: pb.Response.IOStat
+	(*Response_Throttling)(nil),       // 31: pb.Response.Throttling
+	(*Response_OutputStat)(nil),       // 32: pb.Response.OutputStat
+	(*Response_FileStat)(nil),         // 33: pb.Response.FileStat
+	(*Response_FileType)(nil),         // 34: pb.Response.FileType
+	(*Response_Usage)(nil),            // 35: pb.Response.Usage
+	(*Response_RawResult)(nil),        // 36: pb.Response.RawResult
+	(*Response_Result)(nil),           // 37: pb.Response.Result
+	nil,                               // 38: pb.Response.Result.FilesEntry
+	nil,                               // 39: pb.Response.Result.FileIDsEntry
+	nil,                               // 40: pb.Response.Result.ExtractedEntry
+	nil,                               // 41: pb.Response.Result.FileSizeEntry
+	nil,                               // 42: pb.Response.Result.PipeBytesEntry
+	nil,                               // 43: pb.Response.Result.OutputStatEntry
+	nil,                               // 44: pb.Response.Result.SyscallCountEntry
+	nil,                               // 45: pb.Response.Result.FileStatEntry
+	nil,                               // 46: pb.Response.Result.FileTypeEntry
+	(*StreamRequest_Input)(nil),       // 47: pb.StreamRequest.Input
+	(*StreamRequest_Resize)(nil),      // 48: pb.StreamRequest.Resize
+	(*StreamResponse_Output)(nil),     // 49: pb.StreamResponse.Output
+	(*emptypb.Empty)(nil),             // 50: google.protobuf.Empty
 }
 var file_judge_proto_depIdxs = []int32{
-	9,  // 0: pb.FileListType.fileIDs:type_name -> pb.FileListType.FileIDsEntry
-	17, // 1: pb.Request.cmd:type_name -> pb.Request.CmdType
-	19, // 2: pb.Request.pipeMapping:type_name -> pb.Request.PipeMap
-	24, // 3: pb.Response.results:type_name -> pb.Response.Result
-	5,  // 4: pb.StreamRequest.execRequest:type_name -> pb.Request
-	27, // 5: pb.StreamRequest.execInput:type_name -> pb.StreamRequest.Input
-	28, // 6: pb.StreamRequest.execResize:type_name -> pb.StreamRequest.Resize
-	6,  // 7: pb.StreamResponse.execResponse:type_name -> pb.Response
-	29, // 8: pb.StreamResponse.execOutput:type_name -> pb.StreamResponse.Output
-	10, // 9: pb.Request.File.local:type_name -> pb.Request.LocalFile
-	11, // 10: pb.Request.File.memory:type_name -> pb.Request.MemoryFile
-	12, // 11: pb.Request.File.cached:type_name -> pb.Request.CachedFile
-	13, // 12: pb.Request.File.pipe:type_name -> pb.Request.PipeCollector
-	14, // 13: pb.Request.File.streamIn:type_name -> pb.Request.StreamInput
-	15, // 14: pb.Request.File.streamOut:type_name -> pb.Request.StreamOutput
-	16, // 15: pb.Request.CmdType.files:type_name -> pb.Request.File
-	20, // 16: pb.Request.CmdType.copyIn:type_name -> pb.Request.CmdType.CopyInEntry
-	21, // 17: pb.Request.CmdType.symlinks:type_name -> pb.Request.CmdType.SymlinksEntry
-	18, // 18: pb.Request.CmdType.copyOut:type_name -> pb.Request.CmdCopyOutFile
-	18, // 19: pb.Request.CmdType.copyOutCached:type_name -> pb.Request.CmdCopyOutFile
-	22, // 20: pb.Request.PipeMap.in:type_name -> pb.Request.PipeMap.PipeIndex
-	22, // 21: pb.Request.PipeMap.out:type_name -> pb.Request.PipeMap.PipeIndex
-	16, // 22: pb.Request.CmdType.CopyInEntry.value:type_name -> pb.Request.File
-	0,  // 23: pb.Response.FileError.type:type_name -> pb.Response.FileError.ErrorType
-	1,  // 24: pb.Response.Result.status:type_name -> pb.Response.Result.StatusType
-	25, // 25: pb.Response.Result.files:type_name -> pb.Response.Result.FilesEntry
-	26, // 26: pb.Response.Result.fileIDs:type_name -> pb.Response.Result.FileIDsEntry
-	23, // 27: pb.Response.Result.fileError:type_name -> pb.Response.FileError
-	5,  // 28: pb.Executor.Exec:input_type -> pb.Request
-	7,  // 29: pb.Executor.ExecStream:input_type -> pb.StreamRequest
-	30, // 30: pb.Executor.FileList:input_type -> google.protobuf.Empty
-	2,  // 31: pb.Executor.FileGet:input_type -> pb.FileID
-	3,  // 32: pb.Executor.FileAdd:input_type -> pb.FileContent
-	2,  // 33: pb.Executor.FileDelete:input_type -> pb.FileID
-	6,  // 34: pb.Executor.Exec:output_type -> pb.Response
-	8,  // 35: pb.Executor.ExecStream:output_type -> pb.StreamResponse
-	4,  // 36: pb.Executor.FileList:output_type -> pb.FileListType
-	3,  // 37: pb.Executor.FileGet:output_type -> pb.FileContent
-	2,  // 38: pb.Executor.FileAdd:output_type -> pb.FileID
-	30, // 39: pb.Executor.FileDelete:output_type -> google.protobuf.Empty
-	34, // [34:40] is the sub-list for method output_type
-	28, // [28:34] is the sub-list for method input_type
-	28, // [28:28] is the sub-list for extension type_name
-	28, // [28:28] is the sub-list for extension extendee
-	0,  // [0:28] is the sub-list for field type_name
+	11, // 0: pb.FileListType.fileIDs:type_name -> pb.FileListType.FileIDsEntry
+	20, // 1: pb.Request.cmd:type_name -> pb.Request.CmdType
+	22, // 2: pb.Request.pipeMapping:type_name -> pb.Request.PipeMap
+	0,  // 3: pb.Request.runMode:type_name -> pb.Request.RunMode
+	1,  // 4: pb.Request.priority:type_name -> pb.Request.Priority
+	37, // 5: pb.Response.results:type_name -> pb.Response.Result
+	35, // 6: pb.Response.usage:type_name -> pb.Response.Usage
+	7,  // 7: pb.StreamRequest.execRequest:type_name -> pb.Request
+	47, // 8: pb.StreamRequest.execInput:type_name -> pb.StreamRequest.Input
+	48, // 9: pb.StreamRequest.execResize:type_name -> pb.StreamRequest.Resize
+	8,  // 10: pb.StreamResponse.execResponse:type_name -> pb.Response
+	49, // 11: pb.StreamResponse.execOutput:type_name -> pb.StreamResponse.Output
+	12, // 12: pb.Request.File.local:type_name -> pb.Request.LocalFile
+	13, // 13: pb.Request.File.memory:type_name -> pb.Request.MemoryFile
+	14, // 14: pb.Request.File.cached:type_name -> pb.Request.CachedFile
+	16, // 15: pb.Request.File.pipe:type_name -> pb.Request.PipeCollector
+	17, // 16: pb.Request.File.streamIn:type_name -> pb.Request.StreamInput
+	18, // 17: pb.Request.File.streamOut:type_name -> pb.Request.StreamOutput
+	15, // 18: pb.Request.File.group:type_name -> pb.Request.CachedGroup
+	19, // 19: pb.Request.CmdType.files:type_name -> pb.Request.File
+	23, // 20: pb.Request.CmdType.extraFiles:type_name -> pb.Request.CmdType.ExtraFilesEntry
+	24, // 21: pb.Request.CmdType.copyIn:type_name -> pb.Request.CmdType.CopyInEntry
+	25, // 22: pb.Request.CmdType.symlinks:type_name -> pb.Request.CmdType.SymlinksEntry
+	21, // 23: pb.Request.CmdType.copyOut:type_name -> pb.Request.CmdCopyOutFile
+	21, // 24: pb.Request.CmdType.copyOutCached:type_name -> pb.Request.CmdCopyOutFile
+	26, // 25: pb.Request.CmdType.mountFiles:type_name -> pb.Request.CmdType.MountFilesEntry
+	21, // 26: pb.Request.CmdType.copyOutCachedGroup:type_name -> pb.Request.CmdCopyOutFile
+	27, // 27: pb.Request.PipeMap.in:type_name -> pb.Request.PipeMap.PipeIndex
+	27, // 28: pb.Request.PipeMap.out:type_name -> pb.Request.PipeMap.PipeIndex
+	19, // 29: pb.Request.CmdType.ExtraFilesEntry.value:type_name -> pb.Request.File
+	19, // 30: pb.Request.CmdType.CopyInEntry.value:type_name -> pb.Request.File
+	2,  // 31: pb.Response.FileError.type:type_name -> pb.Response.FileError.ErrorType
+	3,  // 32: pb.Response.RawResult.status:type_name -> pb.Response.Result.StatusType
+	3,  // 33: pb.Response.Result.status:type_name -> pb.Response.Result.StatusType
+	38, // 34: pb.Response.Result.files:type_name -> pb.Response.Result.FilesEntry
+	39, // 35: pb.Response.Result.fileIDs:type_name -> pb.Response.Result.FileIDsEntry
+	28, // 36: pb.Response.Result.fileError:type_name -> pb.Response.FileError
+	40, // 37: pb.Response.Result.extracted:type_name -> pb.Response.Result.ExtractedEntry
+	29, // 38: pb.Response.Result.memoryStat:type_name -> pb.Response.MemoryStat
+	41, // 39: pb.Response.Result.fileSize:type_name -> pb.Response.Result.FileSizeEntry
+	42, // 40: pb.Response.Result.pipeBytes:type_name -> pb.Response.Result.PipeBytesEntry
+	43, // 41: pb.Response.Result.outputStat:type_name -> pb.Response.Result.OutputStatEntry
+	30, // 42: pb.Response.Result.ioStat:type_name -> pb.Response.IOStat
+	44, // 43: pb.Response.Result.syscallCount:type_name -> pb.Response.Result.SyscallCountEntry
+	45, // 44: pb.Response.Result.fileStat:type_name -> pb.Response.Result.FileStatEntry
+	31, // 45: pb.Response.Result.throttling:type_name -> pb.Response.Throttling
+	36, // 46: pb.Response.Result.raw:type_name -> pb.Response.RawResult
+	46, // 47: pb.Response.Result.fileType:type_name -> pb.Response.Result.FileTypeEntry
+	32, // 48: pb.Response.Result.OutputStatEntry.value:type_name -> pb.Response.OutputStat
+	33, // 49: pb.Response.Result.FileStatEntry.value:type_name -> pb.Response.FileStat
+	34, // 50: pb.Response.Result.FileTypeEntry.value:type_name -> pb.Response.FileType
+	7,  // 51: pb.Executor.Exec:input_type -> pb.Request
+	9,  // 52: pb.Executor.ExecStream:input_type -> pb.StreamRequest
+	50, // 53: pb.Executor.FileList:input_type -> google.protobuf.Empty
+	4,  // 54: pb.Executor.FileGet:input_type -> pb.FileID
+	5,  // 55: pb.Executor.FileAdd:input_type -> pb.FileContent
+	4,  // 56: pb.Executor.FileDelete:input_type -> pb.FileID
+	8,  // 57: pb.Executor.Exec:output_type -> pb.Response
+	10, // 58: pb.Executor.ExecStream:output_type -> pb.StreamResponse
+	6,  // 59: pb.Executor.FileList:output_type -> pb.FileListType
+	5,  // 60: pb.Executor.FileGet:output_type -> pb.FileContent
+	4,  // 61: pb.Executor.FileAdd:output_type -> pb.FileID
+	50, // 62: pb.Executor.FileDelete:output_type -> google.protobuf.Empty
+	57, // [57:63] is the sub-list for method output_type
+	51, // [51:57] is the sub-list for method input_type
+	51, // [51:51] is the sub-list for extension type_name
+	51, // [51:51] is the sub-list for extension extendee
+	0,  // [0:51] is the sub-list for field type_name
 }
 
 func init() { file_judge_proto_init() }
@@ -2287,7 +4197,7 @@ func file_judge_proto_init() {
 			}
 		}
 		file_judge_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Request_PipeCollector); i {
+			switch v := v.(*Request_CachedGroup); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2299,7 +4209,7 @@ func file_judge_proto_init() {
 			}
 		}
 		file_judge_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Request_StreamInput); i {
+			switch v := v.(*Request_PipeCollector); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2311,7 +4221,7 @@ func file_judge_proto_init() {
 			}
 		}
 		file_judge_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Request_StreamOutput); i {
+			switch v := v.(*Request_StreamInput); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2323,7 +4233,7 @@ func file_judge_proto_init() {
 			}
 		}
 		file_judge_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Request_File); i {
+			switch v := v.(*Request_StreamOutput); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2335,7 +4245,7 @@ func file_judge_proto_init() {
 			}
 		}
 		file_judge_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Request_CmdType); i {
+			switch v := v.(*Request_File); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2347,7 +4257,7 @@ func file_judge_proto_init() {
 			}
 		}
 		file_judge_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*Request_CmdCopyOutFile); i {
+			switch v := v.(*Request_CmdType); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2359,6 +4269,18 @@ func file_judge_proto_init() {
 			}
 		}
 		file_judge_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Request_CmdCopyOutFile); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_judge_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Request_PipeMap); i {
 			case 0:
 				return &v.state
@@ -2370,7 +4292,7 @@ func file_judge_proto_init() {
 				return nil
 			}
 		}
-		file_judge_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+		file_judge_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Request_PipeMap_PipeIndex); i {
 			case 0:
 				return &v.state
@@ -2382,7 +4304,7 @@ func file_judge_proto_init() {
 				return nil
 			}
 		}
-		file_judge_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+		file_judge_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Response_FileError); i {
 			case 0:
 				return &v.state
@@ -2394,7 +4316,103 @@ func file_judge_proto_init() {
 				return nil
 			}
 		}
-		file_judge_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+		file_judge_proto_msgTypes[25].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Response_MemoryStat); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_judge_proto_msgTypes[26].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Response_IOStat); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_judge_proto_msgTypes[27].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Response_Throttling); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_judge_proto_msgTypes[28].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Response_OutputStat); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_judge_proto_msgTypes[29].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Response_FileStat); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_judge_proto_msgTypes[30].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Response_FileType); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_judge_proto_msgTypes[31].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Response_Usage); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_judge_proto_msgTypes[32].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*Response_RawResult); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_judge_proto_msgTypes[33].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*Response_Result); i {
 			case 0:
 				return &v.state
@@ -2406,7 +4424,7 @@ func file_judge_proto_init() {
 				return nil
 			}
 		}
-		file_judge_proto_msgTypes[25].Exporter = func(v interface{}, i int) interface{} {
+		file_judge_proto_msgTypes[43].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*StreamRequest_Input); i {
 			case 0:
 				return &v.state
@@ -2418,7 +4436,7 @@ func file_judge_proto_init() {
 				return nil
 			}
 		}
-		file_judge_proto_msgTypes[26].Exporter = func(v interface{}, i int) interface{} {
+		file_judge_proto_msgTypes[44].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*StreamRequest_Resize); i {
 			case 0:
 				return &v.state
@@ -2430,7 +4448,7 @@ func file_judge_proto_init() {
 				return nil
 			}
 		}
-		file_judge_proto_msgTypes[27].Exporter = func(v interface{}, i int) interface{} {
+		file_judge_proto_msgTypes[45].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*StreamResponse_Output); i {
 			case 0:
 				return &v.state
@@ -2443,6 +4461,7 @@ func file_judge_proto_init() {
 			}
 		}
 	}
+	file_judge_proto_msgTypes[3].OneofWrappers = []interface{}{}
 	file_judge_proto_msgTypes[5].OneofWrappers = []interface{}{
 		(*StreamRequest_ExecRequest)(nil),
 		(*StreamRequest_ExecInput)(nil),
@@ -2452,21 +4471,25 @@ func file_judge_proto_init() {
 		(*StreamResponse_ExecResponse)(nil),
 		(*StreamResponse_ExecOutput)(nil),
 	}
-	file_judge_proto_msgTypes[14].OneofWrappers = []interface{}{
+	file_judge_proto_msgTypes[12].OneofWrappers = []interface{}{}
+	file_judge_proto_msgTypes[15].OneofWrappers = []interface{}{
 		(*Request_File_Local)(nil),
 		(*Request_File_Memory)(nil),
 		(*Request_File_Cached)(nil),
 		(*Request_File_Pipe)(nil),
 		(*Request_File_StreamIn)(nil),
 		(*Request_File_StreamOut)(nil),
+		(*Request_File_Group)(nil),
 	}
+	file_judge_proto_msgTypes[16].OneofWrappers = []interface{}{}
+	file_judge_proto_msgTypes[33].OneofWrappers = []interface{}{}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_judge_proto_rawDesc,
-			NumEnums:      2,
-			NumMessages:   28,
+			NumEnums:      4,
+			NumMessages:   46,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
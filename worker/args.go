@@ -0,0 +1,51 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"unsafe"
+)
+
+// ErrArgsTooLarge is returned when the args and env of the command exceed the
+// ArgLimit, which would fail the exec with E2BIG
+var ErrArgsTooLarge = errors.New("args and env too large")
+
+// ArgLimit defines the limits of the args and env passed to exec, which are
+// queried from the host since they depend on the kernel and the environment.
+// 0 for no limit.
+type ArgLimit struct {
+	// Max is the max total size of the args and env including their
+	// pointers (i.e. ARG_MAX)
+	Max Size
+	// StrMax is the max size of each arg or env (i.e. MAX_ARG_STRLEN)
+	StrMax Size
+	// EnvMax is the max number of env of each command
+	EnvMax int
+}
+
+// check measures the args and env of the command against the limits
+func (l ArgLimit) check(c *Cmd) error {
+	if l.EnvMax > 0 && len(c.Env) > l.EnvMax {
+		return fmt.Errorf("%w: %d env exceeds the max %d", ErrArgsTooLarge, len(c.Env), l.EnvMax)
+	}
+	var size Size
+	for _, s := range []struct {
+		name string
+		v    []string
+	}{{"args", c.Args}, {"env", c.Env}} {
+		for i, v := range s.v {
+			// the null terminated string and its pointer
+			n := Size(len(v) + 1)
+			if l.StrMax > 0 && n > l.StrMax {
+				return fmt.Errorf("%w: %s[%d] is %v which exceeds the max %v of each one, pass large data as copyIn files instead",
+					ErrArgsTooLarge, s.name, i, n, l.StrMax)
+			}
+			size += n + Size(unsafe.Sizeof(uintptr(0)))
+		}
+	}
+	if l.Max > 0 && size > l.Max {
+		return fmt.Errorf("%w: args and env are %v in total which exceeds the max %v, pass large data as copyIn files instead",
+			ErrArgsTooLarge, size, l.Max)
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package worker_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/env/fake"
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+)
+
+// newAutoWorker starts the worker with AutoParallelism re-evaluated every
+// 100ms in the fake sandbox while the host cpu utilization is reported as cpu,
+// the changes of the worker loops are sent to the returned channel
+func newAutoWorker(t *testing.T, parallelism, min, max int, cpu float64) (worker.Worker, <-chan [2]int) {
+	t.Helper()
+	changes := make(chan [2]int, 16)
+	w := worker.New(worker.Config{
+		FileStore:       filestore.NewFileLocalStore(t.TempDir()),
+		EnvironmentPool: pool.NewPool(fake.NewBuilder(fake.Config{Dir: t.TempDir()})),
+		Parallelism:     parallelism,
+		WorkDir:         "/w",
+		AutoParallelism: worker.AutoParallelism{Min: min, Max: max, Interval: 100 * time.Millisecond},
+		ParallelismObserver: func(from, to int, _ worker.Contention, _ float64) {
+			changes <- [2]int{from, to}
+		},
+	})
+	worker.SetCPUSampler(w, func() (float64, bool) { return cpu, true })
+	w.Start()
+	t.Cleanup(w.Shutdown)
+	return w, changes
+}
+
+// load keeps n requests sleeping for 50ms submitted until the test finishes
+func load(t *testing.T, w worker.Worker, n int) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	t.Cleanup(func() {
+		cancel()
+		wg.Wait()
+	})
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ctx.Err() == nil {
+				// the request is modified by Submit
+				ch, _ := w.Submit(ctx, &worker.Request{Cmd: []worker.Cmd{shCmd("sleep 0.05")}})
+				closeFiles(<-ch)
+			}
+		}()
+	}
+}
+
+// waitChanges waits for the changes of the worker loops in order
+func waitChanges(t *testing.T, changes <-chan [2]int, want ...[2]int) {
+	t.Helper()
+	for _, c := range want {
+		select {
+		case got := <-changes:
+			if got != c {
+				t.Fatalf("changed %d -> %d, want %d -> %d", got[0], got[1], c[0], c[1])
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("not changed %d -> %d", c[0], c[1])
+		}
+	}
+}
+
+// noChange checks the worker loops are not changed for d
+func noChange(t *testing.T, changes <-chan [2]int, d time.Duration) {
+	t.Helper()
+	select {
+	case got := <-changes:
+		t.Fatalf("changed %d -> %d, want unchanged", got[0], got[1])
+	case <-time.After(d):
+	}
+}
+
+func TestAutoParallelismGrows(t *testing.T) {
+	w, changes := newAutoWorker(t, 1, 1, 3, 0.1)
+	load(t, w, 8)
+	waitChanges(t, changes, [2]int{1, 2}, [2]int{2, 3})
+	// bounded by max
+	noChange(t, changes, time.Second)
+	if c := w.Contention(); c.Parallelism != 3 || c.Runs == 0 || c.QueueIndex() == 0 {
+		t.Fatalf("contention %+v, want 3 loops with the queue wait", c)
+	}
+}
+
+func TestAutoParallelismShrinks(t *testing.T) {
+	// the host is saturated by others, more loops would not help the queue
+	w, changes := newAutoWorker(t, 3, 1, 4, 0.99)
+	load(t, w, 8)
+	waitChanges(t, changes, [2]int{3, 2}, [2]int{2, 1})
+	// bounded by min
+	noChange(t, changes, time.Second)
+	if c := w.Contention(); c.Parallelism != 1 {
+		t.Fatalf("contention %+v, want 1 loop", c)
+	}
+}
+
+func TestAutoParallelismSteady(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		cpu  float64
+		load int
+	}{
+		// between the cpu thresholds to grow and to shrink
+		{"hysteresis", 0.9, 8},
+		{"not contended", 0.1, 1},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			w, changes := newAutoWorker(t, 2, 1, 4, c.cpu)
+			load(t, w, c.load)
+			noChange(t, changes, time.Second)
+		})
+	}
+}
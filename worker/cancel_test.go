@@ -0,0 +1,176 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+)
+
+// sleepRequest runs until it is terminated
+func sleepRequest(id string) *worker.Request {
+	return &worker.Request{RequestID: id, Cmd: []worker.Cmd{shCmd("exec sleep 10")}}
+}
+
+// waitJob waits for the job of the request to be waiting, or running for a
+// while so that its command has started
+func waitJob(t *testing.T, w worker.Worker, requestID string, running bool) worker.Job {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, j := range w.Jobs() {
+			if j.RequestID == requestID && j.Running == running && (!running || j.Elapsed > 50*time.Millisecond) {
+				return j
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job of %s is not found with running %v", requestID, running)
+	return worker.Job{}
+}
+
+// checkReason checks the reason of the response and its retry guidance
+func checkReason(t *testing.T, rt worker.Response, want worker.CancelReason, retryable bool) {
+	t.Helper()
+	if rt.CancelReason != want {
+		t.Fatalf("cancel reason %q, want %q (error %v)", rt.CancelReason, want, rt.Error)
+	}
+	if want.Retryable() != retryable {
+		t.Fatalf("%q retryable %v, want %v", want, want.Retryable(), retryable)
+	}
+}
+
+func closeFiles(rt worker.Response) {
+	for _, r := range rt.Results {
+		for _, f := range r.Files {
+			f.Close()
+		}
+	}
+}
+
+// terminate terminates the job by the worker or by the submitter context
+type terminate func(w worker.Worker, j worker.Job, cancel context.CancelCauseFunc)
+
+var (
+	clientGone      terminate = func(_ worker.Worker, _ worker.Job, cancel context.CancelCauseFunc) { cancel(context.Canceled) }
+	jobKilled       terminate = func(w worker.Worker, j worker.Job, _ context.CancelCauseFunc) { w.Kill(j.ID) }
+	cancelRequested terminate = func(_ worker.Worker, _ worker.Job, cancel context.CancelCauseFunc) { cancel(worker.ErrCancelRequested) }
+	aborted         terminate = func(w worker.Worker, _ worker.Job, _ context.CancelCauseFunc) { w.Abort() }
+	expired         terminate = func(worker.Worker, worker.Job, context.CancelCauseFunc) {} // by the timeout of the context
+)
+
+func TestCancelReasonRunning(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		term      terminate
+		timeout   time.Duration
+		want      worker.CancelReason
+		retryable bool
+	}{
+		{"client gone", clientGone, 0, worker.CancelClientGone, false},
+		// the deadline expired after started is the client gone
+		{"deadline", expired, 200 * time.Millisecond, worker.CancelClientGone, false},
+		{"job killed", jobKilled, 0, worker.CancelCancelled, false},
+		{"cancel requested", cancelRequested, 0, worker.CancelCancelled, false},
+		{"shutdown", aborted, 0, worker.CancelShutdown, true},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			w := newTestWorker(t, nil)
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+			if c.timeout > 0 {
+				var cancelTimeout context.CancelFunc
+				ctx, cancelTimeout = context.WithTimeout(ctx, c.timeout)
+				defer cancelTimeout()
+			}
+			ch, _ := w.Submit(ctx, sleepRequest("r"))
+			c.term(w, waitJob(t, w, "r", true), cancel)
+
+			rt := <-ch
+			defer closeFiles(rt)
+			if rt.Error != nil {
+				t.Fatal(rt.Error)
+			}
+			checkReason(t, rt, c.want, c.retryable)
+			r := rt.Results[0]
+			if r.TerminatedBy != envexec.TerminatedByCancelled || r.CancelReason != c.want {
+				t.Fatalf("result terminated by %v with %q, want cancelled with %q", r.TerminatedBy, r.CancelReason, c.want)
+			}
+		})
+	}
+}
+
+func TestCancelReasonWaiting(t *testing.T) {
+	for _, c := range []struct {
+		name      string
+		term      terminate
+		timeout   time.Duration
+		err       error
+		want      worker.CancelReason
+		retryable bool
+	}{
+		{"client gone", clientGone, 0, worker.ErrCancelled, worker.CancelClientGone, false},
+		{"queue timeout", expired, 200 * time.Millisecond, worker.ErrCancelled, worker.CancelQueueTimeout, true},
+		{"job killed", jobKilled, 0, worker.ErrCancelled, worker.CancelCancelled, false},
+		{"cancel requested", cancelRequested, 0, worker.ErrCancelled, worker.CancelCancelled, false},
+		{"aborted", aborted, 0, worker.ErrCancelled, worker.CancelShutdown, true},
+		{
+			"shutdown", func(w worker.Worker, _ worker.Job, _ context.CancelCauseFunc) { go w.Shutdown() },
+			0, worker.ErrShutdown, worker.CancelShutdown, true,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			w := newTestWorker(t, nil)
+			// the only worker loop is busy
+			busyCtx, busyCancel := context.WithCancel(context.Background())
+			busy, _ := w.Submit(busyCtx, sleepRequest("busy"))
+			waitJob(t, w, "busy", true)
+			defer func() { closeFiles(<-busy) }()
+			defer busyCancel()
+
+			ctx, cancel := context.WithCancelCause(context.Background())
+			defer cancel(nil)
+			if c.timeout > 0 {
+				var cancelTimeout context.CancelFunc
+				ctx, cancelTimeout = context.WithTimeout(ctx, c.timeout)
+				defer cancelTimeout()
+			}
+			ch, _ := w.Submit(ctx, sleepRequest("r"))
+			c.term(w, waitJob(t, w, "r", false), cancel)
+
+			rt := <-ch
+			defer closeFiles(rt)
+			if !errors.Is(rt.Error, c.err) {
+				t.Fatalf("error %v, want %v", rt.Error, c.err)
+			}
+			checkReason(t, rt, c.want, c.retryable)
+		})
+	}
+}
+
+func TestCancelReasonShutdown(t *testing.T) {
+	w := newTestWorker(t, nil)
+	w.Shutdown()
+	ch, _ := w.Submit(context.Background(), sleepRequest("r"))
+	rt := <-ch
+	if !errors.Is(rt.Error, worker.ErrShutdown) {
+		t.Fatalf("error %v, want %v", rt.Error, worker.ErrShutdown)
+	}
+	checkReason(t, rt, worker.CancelShutdown, true)
+}
+
+// TestCancelReasonLimit covers the limit exceeded kills, which are reported
+// by the status without a reason
+func TestCancelReasonLimit(t *testing.T) {
+	w := newTestWorker(t, nil)
+	c := shCmd("exec sleep 10")
+	c.ClockLimit = 100 * time.Millisecond
+	rt := run(t, w, &worker.Request{Cmd: []worker.Cmd{c}})
+	checkReason(t, rt, "", false)
+	if r := rt.Results[0]; r.Status != envexec.StatusTimeLimitExceeded || r.CancelReason != "" {
+		t.Fatalf("status %v with %q, want time limit exceeded without a reason", r.Status, r.CancelReason)
+	}
+}
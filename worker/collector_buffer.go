@@ -0,0 +1,39 @@
+package worker
+
+import (
+	"os"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// setCollectorBuffers creates the buffers of the pipe collectors that are not
+// cached into the file store in Config.CollectorDir
+func (w *worker) setCollectorBuffers(files []envexec.File, copyOutCached []CmdCopyOutFile) {
+	if w.collectorDir == "" {
+		return
+	}
+	cached := make(map[string]bool, len(copyOutCached))
+	for _, f := range copyOutCached {
+		cached[f.Name] = true
+	}
+	for _, f := range files {
+		if t, ok := f.(*envexec.FileCollector); ok && !t.Cache && !t.Discard && !cached[t.Name] {
+			t.NewBuffer = w.newCollectorBuffer
+		}
+	}
+}
+
+// newCollectorBuffer creates an anonymous file in the collector directory,
+// it is unlinked at once so that the space is released once closed on every
+// exit path, including the crash of the server
+func (w *worker) newCollectorBuffer() (*os.File, error) {
+	if w.collectorDirErr != nil {
+		return nil, w.collectorDirErr
+	}
+	f, err := os.CreateTemp(w.collectorDir, "collector")
+	if err != nil {
+		return nil, err
+	}
+	os.Remove(f.Name())
+	return f, nil
+}
@@ -0,0 +1,140 @@
+package worker_test
+
+import (
+	"context"
+	"io"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+)
+
+// pipeCmd writes size bytes to stdout collected by pipe without stderr
+func pipeCmd(size int) worker.Cmd {
+	c := shCmd("head -c " + strconv.Itoa(size) + " /dev/zero")
+	c.Files = []worker.CmdFile{c.Files[0], &worker.Collector{Name: "stdout", Max: envexec.Size(size), Pipe: true}}
+	c.CopyOut = []worker.CmdCopyOutFile{{Name: "stdout"}}
+	return c
+}
+
+// dirEntries returns the names in the directory
+func dirEntries(t testing.TB, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rt := make([]string, 0, len(entries))
+	for _, e := range entries {
+		rt = append(rt, e.Name())
+	}
+	return rt
+}
+
+func TestCollectorDir(t *testing.T) {
+	storeDir, collectorDir := t.TempDir(), t.TempDir()
+	w := newTestWorker(t, func(c *worker.Config) {
+		c.FileStore = filestore.NewFileLocalStore(storeDir)
+		c.CollectorDir = collectorDir
+	})
+	const size = 1 << 20
+	rt := run(t, w, &worker.Request{Cmd: []worker.Cmd{pipeCmd(size)}})
+	r := rt.Results[0]
+	if r.Status != envexec.StatusAccepted {
+		t.Fatalf("%v: %s", r.Status, r.Error)
+	}
+	if out := readFile(t, r, "stdout"); len(out) != size {
+		t.Fatalf("stdout %d bytes, want %d", len(out), size)
+	}
+	// the buffer is an anonymous file in the collector directory
+	if l := dirEntries(t, collectorDir); len(l) != 0 {
+		t.Fatalf("collector dir %v, want the buffer unlinked", l)
+	}
+	if l := dirEntries(t, storeDir); len(l) != 0 {
+		t.Fatalf("file store dir %v, want the buffer not in the store", l)
+	}
+
+	// the cached collector is still stored in the file store
+	c := pipeCmd(size)
+	c.CopyOut = nil
+	c.CopyOutCached = []worker.CmdCopyOutFile{{Name: "stdout"}}
+	rt = run(t, w, &worker.Request{Cmd: []worker.Cmd{c}})
+	if id := rt.Results[0].FileIDs["stdout"]; id == "" {
+		t.Fatalf("stdout is not cached: %+v", rt.Results[0])
+	}
+}
+
+// peakHeap samples the heap in use until stopped and returns the peak
+func peakHeap() (stop func() uint64) {
+	var (
+		peak uint64
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(10 * time.Millisecond)
+		defer ticker.Stop()
+		var m runtime.MemStats
+		for {
+			runtime.ReadMemStats(&m)
+			if m.HeapInuse > peak {
+				peak = m.HeapInuse
+			}
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return func() uint64 {
+		close(done)
+		wg.Wait()
+		return peak
+	}
+}
+
+// BenchmarkCollectorDir collects the outputs up to 512 MiB by pipe into the
+// collector dir, the peak heap of the server stays flat regardless of the size
+func BenchmarkCollectorDir(b *testing.B) {
+	w := newTestWorker(b, func(c *worker.Config) {
+		c.CollectorDir = b.TempDir()
+	})
+	for _, size := range []int{64 << 20, 512 << 20} {
+		b.Run(strconv.Itoa(size>>20)+"MiB", func(b *testing.B) {
+			b.SetBytes(int64(size))
+			runtime.GC()
+			stop := peakHeap()
+			for i := 0; i < b.N; i++ {
+				c := pipeCmd(size)
+				c.ClockLimit = time.Minute
+				ch, _ := w.Submit(context.Background(), &worker.Request{Cmd: []worker.Cmd{c}})
+				rt := <-ch
+				if rt.Error != nil {
+					b.Fatal(rt.Error)
+				}
+				r := rt.Results[0]
+				if r.Status != envexec.StatusAccepted {
+					b.Fatalf("%v: %s", r.Status, r.Error)
+				}
+				// read as the response is built
+				f := r.Files["stdout"]
+				f.Seek(0, io.SeekStart)
+				n, err := io.Copy(io.Discard, f)
+				closeFiles(rt)
+				if err != nil || n != int64(size) {
+					b.Fatalf("read %d bytes: %v, want %d", n, err, size)
+				}
+			}
+			b.ReportMetric(float64(stop())/(1<<20), "peak-heap-MiB")
+		})
+	}
+}
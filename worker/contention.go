@@ -0,0 +1,132 @@
+package worker
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// contentionWindow is the period the Contention is computed over, which is
+// also the longest AutoParallelism.Interval considered
+const contentionWindow = time.Minute
+
+// Contention describes how long the requests run by the worker loops waited
+// for a loop and for the environments within the window
+type Contention struct {
+	Parallelism int // current number of worker loops
+	Busy        int // worker loops running requests
+	Waiting     int // requests waiting in the queue
+
+	Runs      int           // requests finished within the window
+	QueueWait time.Duration // waited in the queue for a worker loop
+	EnvWait   time.Duration // waited for the environments from the pool, including building new ones
+	RunTime   time.Duration // taken by the worker loops, including EnvWait
+
+	// QueueWaitTotal and EnvWaitTotal are summed since the worker started
+	QueueWaitTotal time.Duration
+	EnvWaitTotal   time.Duration
+}
+
+// Index is the share of the latency of the requests within the window spent
+// waiting for a worker loop or for the environments, 0 if none of them waited
+// and close to 1 if they mostly waited
+func (c Contention) Index() float64 {
+	return c.share(c.QueueWait + c.EnvWait)
+}
+
+// QueueIndex is the share of the latency spent waiting for a worker loop,
+// which more worker loops could reduce if the host is not saturated
+func (c Contention) QueueIndex() float64 {
+	return c.share(c.QueueWait)
+}
+
+// EnvIndex is the share of the latency spent waiting for the environments,
+// mostly building new ones, which more worker loops could not reduce
+func (c Contention) EnvIndex() float64 {
+	return c.share(c.EnvWait)
+}
+
+func (c Contention) share(d time.Duration) float64 {
+	latency := c.QueueWait + c.RunTime
+	if latency <= 0 {
+		return 0
+	}
+	return float64(d) / float64(latency)
+}
+
+// contentionBucket sums the runs finished within a second
+type contentionBucket struct {
+	sec       int64
+	runs      int
+	queueWait time.Duration
+	envWait   time.Duration
+	runTime   time.Duration
+}
+
+// contentionTracker records the waits of the runs in buckets of a second over
+// the window, so that the memory is bounded regardless of the throughput
+type contentionTracker struct {
+	mu             sync.Mutex
+	buckets        [int(contentionWindow / time.Second)]contentionBucket
+	queueWaitTotal time.Duration
+	envWaitTotal   time.Duration
+}
+
+func (t *contentionTracker) observe(now time.Time, queueWait, envWait, runTime time.Duration) {
+	sec := now.Unix()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b := &t.buckets[sec%int64(len(t.buckets))]
+	if b.sec != sec {
+		*b = contentionBucket{sec: sec}
+	}
+	b.runs++
+	b.queueWait += queueWait
+	b.envWait += envWait
+	b.runTime += runTime
+	t.queueWaitTotal += queueWait
+	t.envWaitTotal += envWait
+}
+
+// sum returns the runs finished within d before now at the resolution of a
+// second, d is capped by the window
+func (t *contentionTracker) sum(now time.Time, d time.Duration) Contention {
+	if d > contentionWindow-time.Second {
+		d = contentionWindow - time.Second
+	}
+	since := now.Add(-d).Unix()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rt := Contention{
+		QueueWaitTotal: t.queueWaitTotal,
+		EnvWaitTotal:   t.envWaitTotal,
+	}
+	for _, b := range t.buckets {
+		if b.sec < since || b.sec > now.Unix() {
+			continue
+		}
+		rt.Runs += b.runs
+		rt.QueueWait += b.queueWait
+		rt.EnvWait += b.envWait
+		rt.RunTime += b.runTime
+	}
+	return rt
+}
+
+// Contention returns the waits of the requests finished within the last
+// minute with the current worker loops
+func (w *worker) Contention() Contention {
+	return w.contention(contentionWindow)
+}
+
+func (w *worker) contention(d time.Duration) Contention {
+	rt := w.contentionTracker.sum(time.Now(), d)
+	rt.Parallelism = w.currentParallelism()
+	rt.Busy = int(atomic.LoadInt64(&w.busy))
+	for _, n := range w.queue.depth() {
+		rt.Waiting += n
+	}
+	return rt
+}
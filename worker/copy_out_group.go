@@ -0,0 +1,183 @@
+package worker
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+)
+
+// ErrInvalidCopyOutGroup is returned when the patterns of CopyOutCachedGroup
+// are malformed or not relative to the work directory
+var ErrInvalidCopyOutGroup = errors.New("invalid copy out cached group")
+
+// groupFileName is the name recorded in the file store for the groups
+const groupFileName = "group"
+
+// validCopyOutGroup checks the patterns of CopyOutCachedGroup
+func validCopyOutGroup(rc []Cmd) error {
+	for i, c := range rc {
+		for _, f := range c.CopyOutCachedGroup {
+			if _, err := path.Match(f.Name, ""); err != nil {
+				return fmt.Errorf("cmd[%d]: %w: %s: %v", i, ErrInvalidCopyOutGroup, f.Name, err)
+			}
+			if path.IsAbs(f.Name) {
+				return fmt.Errorf("cmd[%d]: %w: %s: absolute path is not allowed", i, ErrInvalidCopyOutGroup, f.Name)
+			}
+			for _, p := range strings.Split(f.Name, "/") {
+				if p == ".." {
+					return fmt.Errorf("cmd[%d]: %w: %s: path contains '..'", i, ErrInvalidCopyOutGroup, f.Name)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// GroupFile defines the files cached as a group by CopyOutCachedGroup, they
+// are extracted into the directory when copy in
+type GroupFile struct {
+	GroupID string
+}
+
+// EnvFile prepares file for envexec file
+func (f *GroupFile) EnvFile(fs filestore.FileStore) (envexec.File, error) {
+	meta, ok := fs.GetMeta(f.GroupID)
+	if !ok {
+		if err := filestore.Available(fs); err != nil {
+			return nil, fmt.Errorf("failed to get group with id %v: %w", f.GroupID, err)
+		}
+		return nil, fmt.Errorf("group not exists with id %v", f.GroupID)
+	}
+	if !meta.Group {
+		return nil, fmt.Errorf("file with id %v is not a group", f.GroupID)
+	}
+	_, fd := fs.Get(f.GroupID)
+	if fd == nil {
+		return nil, fmt.Errorf("group not exists with id %v", f.GroupID)
+	}
+	return envexec.NewFileArchive(fd, envexec.ArchiveTar), nil
+}
+
+func (f *GroupFile) String() string {
+	return fmt.Sprintf("group:(groupId:%s)", f.GroupID)
+}
+
+// groupCopyOut returns the copy out files of the patterns of CopyOutCachedGroup
+func groupCopyOut(group []CmdCopyOutFile) []envexec.CmdCopyOutFile {
+	rt := make([]envexec.CmdCopyOutFile, 0, len(group))
+	for _, f := range group {
+		f.Glob, f.StatOnly = true, false
+		rt = append(rt, f)
+	}
+	return rt
+}
+
+// groupFileNames returns the sorted names of the files copied out matching
+// the patterns of the group, the collected outputs are never matched
+func groupFileNames(group []CmdCopyOutFile, files map[string]*os.File, collected map[string]bool) []string {
+	var rt []string
+	for name := range files {
+		if collected[name] {
+			continue
+		}
+		for _, f := range group {
+			if ok, _ := path.Match(path.Clean(f.Name), name); ok {
+				rt = append(rt, name)
+				break
+			}
+		}
+	}
+	sort.Strings(rt)
+	return rt
+}
+
+// groupFailed reports whether any of the files or patterns of the group
+// failed to copy out, so that a partial group is never cached
+func groupFailed(group []CmdCopyOutFile, fileError []envexec.FileError) bool {
+	for _, e := range fileError {
+		switch e.Type {
+		case envexec.ErrCopyOutLimitExceeded:
+			return true
+		case envexec.ErrCopyOutOpen, envexec.ErrCopyOutNotRegularFile, envexec.ErrCopyOutSizeExceeded,
+			envexec.ErrCopyOutCreateFile, envexec.ErrCopyOutCopyContent:
+		default:
+			continue
+		}
+		for _, f := range group {
+			if ok, _ := path.Match(path.Clean(f.Name), e.Name); ok || f.Name == e.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// addCachedGroup writes the files into one tar archive added to the file
+// store, so that the group is collected by the file store as a whole. The
+// modes are the ones in the work directory reported by stats.
+func addCachedGroup(fs filestore.FileStore, names []string, files map[string]*os.File, stats map[string]envexec.FileStat) (string, int64, error) {
+	f, err := fs.New()
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if err := writeGroup(io.MultiWriter(f, h), names, files, stats); err != nil {
+		os.Remove(f.Name())
+		return "", 0, err
+	}
+	var size int64
+	if fi, err := f.Stat(); err == nil {
+		size = fi.Size()
+	}
+	meta := filestore.FileMeta{Name: groupFileName, SHA256: hex.EncodeToString(h.Sum(nil)), Group: true}
+	id, err := fs.AddMeta(meta, f.Name())
+	if err != nil {
+		os.Remove(f.Name())
+		return "", 0, err
+	}
+	return id, size, nil
+}
+
+func writeGroup(w io.Writer, names []string, files map[string]*os.File, stats map[string]envexec.FileStat) error {
+	tw := tar.NewWriter(w)
+	for _, name := range names {
+		f := files[name]
+		fi, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		mode, modTime := fi.Mode(), fi.ModTime()
+		if s, ok := stats[name]; ok {
+			mode, modTime = s.Mode, s.ModTime
+		}
+		hdr := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     name,
+			Mode:     int64(mode.Perm()),
+			Size:     fi.Size(),
+			ModTime:  modTime,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, f); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
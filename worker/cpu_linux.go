@@ -0,0 +1,62 @@
+package worker
+
+import (
+	"bufio"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// cpuSampler reads the cpu utilization of the host from /proc/stat
+type cpuSampler struct {
+	idle, total uint64
+}
+
+// sample returns the share of the cpu time not idle since the last sample,
+// false if it could not be read or it is the first sample
+func (s *cpuSampler) sample() (float64, bool) {
+	idle, total, ok := readProcStat()
+	if !ok {
+		return 0, false
+	}
+	prevIdle, prevTotal := s.idle, s.total
+	s.idle, s.total = idle, total
+	if prevTotal == 0 || total <= prevTotal {
+		return 0, false
+	}
+	return 1 - float64(idle-prevIdle)/float64(total-prevTotal), true
+}
+
+// readProcStat returns the idle (including iowait) and total cpu time of the
+// host in ticks from the cpu line of /proc/stat
+func readProcStat() (idle, total uint64, ok bool) {
+	f, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, 0, false
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	if !s.Scan() {
+		return 0, 0, false
+	}
+	fields := strings.Fields(s.Text())
+	if len(fields) < 5 || fields[0] != "cpu" {
+		return 0, 0, false
+	}
+	// user nice system idle iowait irq softirq steal, guest is counted in user
+	for i, v := range fields[1:] {
+		if i >= 8 {
+			break
+		}
+		n, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		total += n
+		if i == 3 || i == 4 {
+			idle += n
+		}
+	}
+	return idle, total, true
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package worker
+
+// cpuSampler does not read the cpu utilization outside of linux, so that
+// AutoParallelism is decided by the queue wait only
+type cpuSampler struct{}
+
+func (s *cpuSampler) sample() (float64, bool) {
+	return 0, false
+}
@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// newDiskWorkDir creates the directory where the per-run directories of
+// DiskWorkDir are created under dir, the ones left by the previous process
+// (e.g. killed during a run) are removed
+func newDiskWorkDir(dir string) (string, error) {
+	run := filepath.Join(dir, "run")
+	os.RemoveAll(run)
+	if err := os.MkdirAll(run, 0755); err != nil {
+		return "", err
+	}
+	return run, nil
+}
+
+// acquireDiskWorkDir creates the directory of a run, release removes it with
+// everything written by the run
+func acquireDiskWorkDir(root string) (dir string, release func(), err error) {
+	dir, err = os.MkdirTemp(root, "")
+	if err != nil {
+		return "", nil, err
+	}
+	// the subdirectories are created for the container credential by the
+	// environment builder
+	if err := os.Chmod(dir, 0755); err != nil {
+		os.RemoveAll(dir)
+		return "", nil, err
+	}
+	return dir, func() { os.RemoveAll(dir) }, nil
+}
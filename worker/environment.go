@@ -0,0 +1,226 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+)
+
+type EnvironmentReason = envexec.EnvironmentReason
+type EnvironmentError = envexec.EnvironmentError
+
+// EnvironmentReason
+const (
+	EnvironmentNamespaceLimit = envexec.EnvironmentNamespaceLimit
+	EnvironmentMountFailed    = envexec.EnvironmentMountFailed
+	EnvironmentForkFailed     = envexec.EnvironmentForkFailed
+	EnvironmentCgroupFailed   = envexec.EnvironmentCgroupFailed
+	EnvironmentUnknown        = envexec.EnvironmentUnknown
+)
+
+// EnvironmentOptions describes the environment acquired for a command
+type EnvironmentOptions = envexec.EnvironmentOptions
+
+// envOption is an option of the environment of the command. Besides the pinned
+// credential, the options are mutually exclusive since each of them builds the
+// container with its own mounts.
+type envOption struct {
+	name string
+	err  error // wrapped by the errors of the option
+	// valid checks the option is enabled by the server for the command
+	valid func(w *worker, fs filestore.FileStore, c Cmd) error
+}
+
+var (
+	optHostExec      = envOption{name: "host exec", err: ErrInvalidHostExec}
+	optMountFiles    = envOption{name: "mount files", err: ErrInvalidMountFiles, valid: (*worker).validMountFiles}
+	optDiskWorkDir   = envOption{name: "disk work dir", err: ErrInvalidDiskWorkDir, valid: (*worker).validDiskWorkDir}
+	optScratch       = envOption{name: "scratch file", err: ErrInvalidScratch, valid: (*worker).validScratch}
+	optRandom        = envOption{name: "random seed", err: ErrInvalidRandom}
+	optProcInfo      = envOption{name: "proc info", err: ErrInvalidProcInfo, valid: (*worker).validProcInfo}
+	optProfile       = envOption{name: "profile", err: ErrInvalidProfile}
+	optDatasets      = envOption{name: "datasets", err: ErrInvalidDataset}
+	optRandomWorkDir = envOption{name: "randomized work directory", err: ErrInvalidRandomWorkDir}
+)
+
+// cmdEnvOptions returns the options of the environment requested by the
+// command in the order of precedence, files are the host files referenced
+func (w *worker) cmdEnvOptions(c Cmd, files map[string]string) []envOption {
+	var rt []envOption
+	add := func(set bool, o envOption) {
+		if set {
+			rt = append(rt, o)
+		}
+	}
+	add(len(files) > 0, optHostExec)
+	add(len(c.MountFiles) > 0, optMountFiles)
+	add(c.DiskWorkDir, optDiskWorkDir)
+	add(c.ScratchFile != "", optScratch)
+	add(c.RandomSeed != nil, optRandom)
+	add(c.ProcInfo, optProcInfo)
+	add(c.Profile != "", optProfile)
+	add(len(c.Datasets) > 0, optDatasets)
+	add(w.randomWorkDir(c), optRandomWorkDir)
+	return rt
+}
+
+// environmentOptions returns the options of the environment of the command,
+// except the files of the run (mount files, disk work dir and scratch file)
+// which are created when the environment is acquired
+func (w *worker) environmentOptions(c Cmd, files map[string]string) EnvironmentOptions {
+	return EnvironmentOptions{
+		Credential:    c.Credential,
+		RandomWorkDir: w.randomWorkDir(c),
+		Datasets:      c.Datasets,
+		Profile:       c.Profile,
+		HostExec:      files,
+		ProcInfo:      c.ProcInfo,
+		Random:        c.RandomSeed != nil,
+	}
+}
+
+// validEnvironment checks the environments of the commands could be acquired:
+// the pinned credential is allowed, at most one option is requested, and the
+// option is enabled by the server and supported by the pool
+func (w *worker) validEnvironment(fs filestore.FileStore, rc []Cmd) error {
+	for i, c := range rc {
+		if c.Credential != nil {
+			if err := w.envPool.Valid(EnvironmentOptions{Credential: c.Credential}); err != nil {
+				return fmt.Errorf("cmd[%d]: %w: %v", i, ErrInvalidCredential, err)
+			}
+		}
+		files, err := w.hostExecFiles(c)
+		if err != nil {
+			return fmt.Errorf("cmd[%d]: %w: %v", i, ErrInvalidHostExec, err)
+		}
+		opts := w.cmdEnvOptions(c, files)
+		if len(opts) == 0 {
+			continue
+		}
+		o := opts[0]
+		if len(opts) > 1 {
+			names := make([]string, 0, len(opts)-1)
+			for _, p := range opts[1:] {
+				names = append(names, p.name)
+			}
+			return fmt.Errorf("cmd[%d]: %w: %s could not be used with %s", i, o.err, o.name, strings.Join(names, ", "))
+		}
+		if o.valid != nil {
+			if err := o.valid(w, fs, c); err != nil {
+				return fmt.Errorf("cmd[%d]: %w: %v", i, o.err, err)
+			}
+		}
+		eo := w.environmentOptions(c, files)
+		eo.Credential = nil
+		if err := w.envPool.Valid(eo); err != nil {
+			return fmt.Errorf("cmd[%d]: %w: %v", i, o.err, err)
+		}
+	}
+	return nil
+}
+
+// validMountFiles checks the files exist and could be mounted
+func (w *worker) validMountFiles(fs filestore.FileStore, c Cmd) error {
+	if w.mountFiles == nil {
+		return fmt.Errorf("mount files are not enabled")
+	}
+	if w.mountFilesErr != nil {
+		return w.mountFilesErr
+	}
+	for name, id := range c.MountFiles {
+		if err := validMountFileName(name); err != nil {
+			return err
+		}
+		if _, f := fs.Get(id); f == nil {
+			return fmt.Errorf("file %s (%s) not found", name, id)
+		}
+	}
+	return nil
+}
+
+// validDiskWorkDir checks the work directory could be backed by disk
+func (w *worker) validDiskWorkDir(_ filestore.FileStore, _ Cmd) error {
+	if w.diskWorkDirErr != nil {
+		return w.diskWorkDirErr
+	}
+	if w.diskWorkDir == "" {
+		return fmt.Errorf("disk work dir is not enabled")
+	}
+	return nil
+}
+
+// validScratch checks the scratch file could be created
+func (w *worker) validScratch(_ filestore.FileStore, c Cmd) error {
+	if w.scratchErr != nil {
+		return w.scratchErr
+	}
+	if w.scratchDir == "" {
+		return fmt.Errorf("scratch file is not enabled")
+	}
+	if n := c.ScratchFile; n == "." || n == ".." || strings.ContainsAny(n, "/\x00") {
+		return fmt.Errorf("%q is not a file name", n)
+	}
+	if c.ScratchSize > w.scratchMax {
+		return fmt.Errorf("scratchSize %v exceeds the max %v", c.ScratchSize, w.scratchMax)
+	}
+	return nil
+}
+
+// validProcInfo checks the memory limit reported by the synthetic proc info
+// is set
+func (w *worker) validProcInfo(_ filestore.FileStore, c Cmd) error {
+	if c.MemoryLimit == 0 || c.MemoryLimit == UnlimitedSize {
+		return fmt.Errorf("proc info requires memoryLimit")
+	}
+	return nil
+}
+
+// getPoolEnvironment gets the environment with the options of the command
+// from the pool of the NUMA node of the request. The files of the run mounted
+// in the environment are created here and released after it is put back
+// (also when the run is cancelled).
+func (w *worker) getPoolEnvironment(ctx context.Context, fs filestore.FileStore, rc Cmd) (envexec.Environment, error) {
+	files, err := w.hostExecFiles(rc)
+	if err != nil {
+		return nil, err
+	}
+	opts := w.environmentOptions(rc, files)
+	var release func()
+	switch {
+	case len(rc.MountFiles) > 0:
+		if w.mountFiles == nil {
+			return nil, fmt.Errorf("mount files are not enabled")
+		}
+		opts.MountFiles, release, err = w.mountFiles.acquire(fs, rc.MountFiles)
+	case rc.DiskWorkDir:
+		if w.diskWorkDir == "" {
+			return nil, fmt.Errorf("disk work dir is not enabled")
+		}
+		opts.DiskWorkDir, release, err = acquireDiskWorkDir(w.diskWorkDir)
+	case rc.ScratchFile != "":
+		if w.scratchDir == "" {
+			return nil, fmt.Errorf("scratch file is not enabled")
+		}
+		opts.ScratchName = rc.ScratchFile
+		opts.ScratchFile, release, err = acquireScratch(w.scratchDir, w.scratchSize(rc))
+	}
+	if err != nil {
+		return nil, err
+	}
+	env, err := w.requestEnvPool(ctx).Get(ctx, opts)
+	if err != nil {
+		if release != nil {
+			release()
+		}
+		return nil, err
+	}
+	if release != nil {
+		w.mountMu.Lock()
+		w.mountRelease[env] = release
+		w.mountMu.Unlock()
+	}
+	return env, nil
+}
@@ -0,0 +1,90 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ErrInvalidExpansion is returned when the variables in args and env could not
+// be expanded by Expand
+var ErrInvalidExpansion = errors.New("invalid expansion")
+
+// Expand substitutes the variables in args and env of the command:
+//
+//	{workdir}      the work directory in the container
+//	{file:<name>}  the path in the container of the copyIn or symlink <name>
+//	{{             a literal {
+//
+// Unknown variables and unterminated braces are rejected. The work directory
+// is substituted when the command runs since it might be randomized.
+func Expand(c *Cmd) error {
+	args, err := expandAll(c.Args, c)
+	if err != nil {
+		return fmt.Errorf("args: %w", err)
+	}
+	env, err := expandAll(c.Env, c)
+	if err != nil {
+		return fmt.Errorf("env: %w", err)
+	}
+	c.Args, c.Env = args, env
+	return nil
+}
+
+func expandAll(s []string, c *Cmd) ([]string, error) {
+	rt := make([]string, 0, len(s))
+	for _, v := range s {
+		e, err := expandString(v, c)
+		if err != nil {
+			return nil, err
+		}
+		rt = append(rt, e)
+	}
+	return rt, nil
+}
+
+func expandString(s string, c *Cmd) (string, error) {
+	var b strings.Builder
+	for {
+		i := strings.IndexByte(s, '{')
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		b.WriteString(s[:i])
+		s = s[i+1:]
+		if strings.HasPrefix(s, "{") {
+			b.WriteByte('{')
+			s = s[1:]
+			continue
+		}
+		j := strings.IndexByte(s, '}')
+		if j < 0 {
+			return "", fmt.Errorf("%w: unterminated variable {%s", ErrInvalidExpansion, s)
+		}
+		v, err := expandVar(s[:j], c)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+		s = s[j+1:]
+	}
+}
+
+func expandVar(name string, c *Cmd) (string, error) {
+	if name == "workdir" {
+		return workDirVar, nil
+	}
+	if f, ok := strings.CutPrefix(name, "file:"); ok {
+		_, isFile := c.CopyIn[f]
+		if _, isLink := c.Symlinks[f]; !isFile && !isLink {
+			return "", fmt.Errorf("%w: {%s}: %s is not in copyIn", ErrInvalidExpansion, name, f)
+		}
+		if path.IsAbs(f) {
+			return path.Clean(f), nil
+		}
+		return workDirVar + "/" + path.Clean(f), nil
+	}
+	return "", fmt.Errorf("%w: unknown variable {%s}", ErrInvalidExpansion, name)
+}
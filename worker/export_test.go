@@ -0,0 +1,7 @@
+package worker
+
+// SetCPUSampler replaces the host cpu utilization read by AutoParallelism to
+// simulate the load, it must be called before Start
+func SetCPUSampler(w Worker, sample func() (float64, bool)) {
+	w.(*worker).sampleCPU = sample
+}
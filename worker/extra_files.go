@@ -0,0 +1,67 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrInvalidExtraFiles is returned when the fds of ExtraFiles are taken or
+// out of range
+var ErrInvalidExtraFiles = errors.New("invalid extra files")
+
+// minExtraFd is the first fd ExtraFiles could bind, the ones below are stdio
+const minExtraFd = 3
+
+// validExtraFiles checks the fds of ExtraFiles are above stdio, not taken by
+// Files and below the open file limit so that they could be duplicated to
+func (w *worker) validExtraFiles(rc []Cmd) error {
+	for i, c := range rc {
+		openFileLimit := c.OpenFileLimit
+		if openFileLimit == 0 {
+			openFileLimit = w.openFileLimit
+		}
+		for _, fd := range sortedFds(c.ExtraFiles) {
+			switch {
+			case c.ExtraFiles[fd] == nil:
+				return fmt.Errorf("cmd[%d]: %w: fd %d is null", i, ErrInvalidExtraFiles, fd)
+			case fd < minExtraFd:
+				return fmt.Errorf("cmd[%d]: %w: fd %d is below 3, use files for stdio", i, ErrInvalidExtraFiles, fd)
+			case fd < len(c.Files) && c.Files[fd] != nil:
+				return fmt.Errorf("cmd[%d]: %w: fd %d is already bound by files", i, ErrInvalidExtraFiles, fd)
+			case openFileLimit > 0 && uint64(fd) >= openFileLimit:
+				return fmt.Errorf("cmd[%d]: %w: fd %d exceeds the open file limit %d", i, ErrInvalidExtraFiles, fd, openFileLimit)
+			}
+		}
+	}
+	return nil
+}
+
+// fdFiles returns the files of the command indexed by fd with ExtraFiles
+// merged into Files, the fds not bound are nil and opened as /dev/null
+func (c *Cmd) fdFiles() []CmdFile {
+	if len(c.ExtraFiles) == 0 {
+		return c.Files
+	}
+	fds := sortedFds(c.ExtraFiles)
+	n := len(c.Files)
+	if last := fds[len(fds)-1]; last >= n {
+		n = last + 1
+	}
+	rt := make([]CmdFile, n)
+	copy(rt, c.Files)
+	for _, fd := range fds {
+		rt[fd] = c.ExtraFiles[fd]
+	}
+	return rt
+}
+
+// sortedFds returns the fds of ExtraFiles in ascending order
+func sortedFds(m map[int]CmdFile) []int {
+	fds := make([]int, 0, len(m))
+	for fd := range m {
+		fds = append(fds, fd)
+	}
+	sort.Ints(fds)
+	return fds
+}
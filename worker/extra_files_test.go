@@ -0,0 +1,65 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+)
+
+func TestExtraFiles(t *testing.T) {
+	w := newTestWorker(t, nil)
+	c := shCmd("echo contestant; echo protocol >&3; echo gap >&4 && echo five >&5")
+	c.ExtraFiles = map[int]worker.CmdFile{
+		3: &worker.Collector{Name: "fd3", Max: 1 << 10},
+		5: &worker.Collector{Name: "fd5", Max: 1 << 10},
+	}
+	c.CopyOut = append(c.CopyOut, worker.CmdCopyOutFile{Name: "fd3"}, worker.CmdCopyOutFile{Name: "fd5"})
+	rt := run(t, w, &worker.Request{Cmd: []worker.Cmd{c}})
+	r := rt.Results[0]
+	if r.Status != envexec.StatusAccepted {
+		t.Fatalf("%v: %s (stderr %q)", r.Status, r.Error, readFile(t, r, "stderr"))
+	}
+	// fd 4 in the gap is /dev/null
+	for name, want := range map[string]string{
+		"stdout": "contestant\n",
+		"fd3":    "protocol\n",
+		"fd5":    "five\n",
+		"stderr": "",
+	} {
+		if got := readFile(t, r, name); got != want {
+			t.Errorf("%s: got %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestExtraFilesInvalid(t *testing.T) {
+	w := newTestWorker(t, nil)
+	collector := &worker.Collector{Name: "fd", Max: 1 << 10}
+	for _, c := range []struct {
+		name string
+		cmd  func(*worker.Cmd)
+	}{
+		{"stdio", func(c *worker.Cmd) { c.ExtraFiles = map[int]worker.CmdFile{2: collector} }},
+		{"null", func(c *worker.Cmd) { c.ExtraFiles = map[int]worker.CmdFile{3: nil} }},
+		{"bound by files", func(c *worker.Cmd) {
+			c.Files = append(c.Files, &worker.Collector{Name: "files", Max: 1 << 10})
+			c.ExtraFiles = map[int]worker.CmdFile{3: collector}
+		}},
+		{"open file limit", func(c *worker.Cmd) {
+			c.OpenFileLimit = 64
+			c.ExtraFiles = map[int]worker.CmdFile{64: collector}
+		}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			cmd := shCmd("true")
+			c.cmd(&cmd)
+			ch, _ := w.Submit(context.Background(), &worker.Request{Cmd: []worker.Cmd{cmd}})
+			if rt := <-ch; !errors.Is(rt.Error, worker.ErrInvalidExtraFiles) {
+				t.Fatalf("error %v, want %v", rt.Error, worker.ErrInvalidExtraFiles)
+			}
+		})
+	}
+}
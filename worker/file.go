@@ -2,12 +2,17 @@ package worker
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 
 	"github.com/criyle/go-judge/envexec"
 	"github.com/criyle/go-judge/filestore"
 )
 
+// ErrFileNotFound is returned when the file referenced by fileId is not in the
+// file store
+var ErrFileNotFound = errors.New("file not found")
+
 // CmdFile defines file used in the cmd
 type CmdFile interface {
 	// EnvFile prepares file for envexec file
@@ -21,6 +26,8 @@ var (
 	_ CmdFile = &MemoryFile{}
 	_ CmdFile = &CachedFile{}
 	_ CmdFile = &Collector{}
+	_ CmdFile = &ArchiveFile{}
+	_ CmdFile = &FifoFile{}
 )
 
 // LocalFile defines file stores on the local file system
@@ -60,7 +67,10 @@ type CachedFile struct {
 func (f *CachedFile) EnvFile(fs filestore.FileStore) (envexec.File, error) {
 	_, fd := fs.Get(f.FileID)
 	if fd == nil {
-		return nil, fmt.Errorf("file not exists with id %v", f.FileID)
+		if err := filestore.Available(fs); err != nil {
+			return nil, fmt.Errorf("failed to get file with id %v: %w", f.FileID, err)
+		}
+		return nil, fmt.Errorf("%w with id %v", ErrFileNotFound, f.FileID)
 	}
 	return fd, nil
 }
@@ -71,16 +81,58 @@ func (f *CachedFile) String() string {
 
 // Collector defines on the output (stdout / stderr) to be collected over pipe
 type Collector struct {
-	Name string       // pseudo name generated into copyOut
-	Max  envexec.Size // max size to be collected
-	Pipe bool
+	Name    string       // pseudo name generated into copyOut
+	Max     envexec.Size // max size to be collected
+	Pipe    bool
+	Cache   bool // cache the full output into file store and keep max size inline
+	Discard bool // discard the content and only count bytes and lines
+
+	// CacheTruncated caches the full output into file store only when it
+	// exceeds max, nil uses the server default for stderr (fd 2)
+	CacheTruncated *bool
 }
 
 // EnvFile prepares file for envexec file
 func (f *Collector) EnvFile(fs filestore.FileStore) (envexec.File, error) {
-	return envexec.NewFileCollector(f.Name, f.Max, f.Pipe), nil
+	cache := f.Cache || f.CacheTruncated != nil && *f.CacheTruncated
+	return &envexec.FileCollector{Name: f.Name, Limit: f.Max, Pipe: f.Pipe, Cache: cache, Discard: f.Discard}, nil
 }
 
 func (f *Collector) String() string {
-	return fmt.Sprintf("collector:(name:%s,max:%d,pipe:%v)", f.Name, f.Max, f.Pipe)
+	return fmt.Sprintf("collector:(name:%s,max:%d,pipe:%v,cache:%v,discard:%v)", f.Name, f.Max, f.Pipe, f.Cache, f.Discard)
+}
+
+// ArchiveFile defines archive file to be extracted into directory when copy in
+type ArchiveFile struct {
+	File   CmdFile
+	Format envexec.ArchiveFormat
+}
+
+// EnvFile prepares file for envexec file
+func (f *ArchiveFile) EnvFile(fs filestore.FileStore) (envexec.File, error) {
+	if f.File == nil {
+		return nil, fmt.Errorf("nil type cannot be used as archive")
+	}
+	ef, err := f.File.EnvFile(fs)
+	if err != nil {
+		return nil, err
+	}
+	return envexec.NewFileArchive(ef, f.Format), nil
+}
+
+func (f *ArchiveFile) String() string {
+	return fmt.Sprintf("archive:(format:%d,file:%v)", f.Format, f.File)
+}
+
+// FifoFile defines named pipe created when copy in, which is connected to the
+// other cmd through pipe mapping
+type FifoFile struct{}
+
+// EnvFile prepares file for envexec file
+func (f *FifoFile) EnvFile(fs filestore.FileStore) (envexec.File, error) {
+	return envexec.NewFileFifo(), nil
+}
+
+func (f *FifoFile) String() string {
+	return "fifo"
 }
@@ -0,0 +1,214 @@
+package worker
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+)
+
+// fileMountCache keeps the files of MountFiles in a host directory so that
+// each of them is fetched from the file store once. The files are hard linked
+// into a staging directory for each run which is mounted read-only into the
+// container. Files not in use are evicted in least recently used order once
+// the total size exceeds max.
+type fileMountCache struct {
+	dir string
+	max envexec.Size
+
+	mu      sync.Mutex
+	entries map[string]*fileMountEntry // by file id
+	lru     *list.List                 // entries not in use, least recently used first
+	size    envexec.Size
+}
+
+type fileMountEntry struct {
+	id    string
+	path  string
+	size  envexec.Size
+	refs  int
+	elem  *list.Element // in lru if refs is 0
+	ready chan struct{} // closed once fetched
+	err   error
+}
+
+// newFileMountCache creates the cache at dir, files left by the previous
+// process are removed since they are not indexed
+func newFileMountCache(dir string, max envexec.Size) (*fileMountCache, error) {
+	for _, d := range []string{"cache", "run"} {
+		os.RemoveAll(filepath.Join(dir, d))
+		if err := os.MkdirAll(filepath.Join(dir, d), 0755); err != nil {
+			return nil, err
+		}
+	}
+	return &fileMountCache{
+		dir:     dir,
+		max:     max,
+		entries: make(map[string]*fileMountEntry),
+		lru:     list.New(),
+	}, nil
+}
+
+// validMountFileName checks the name is a single path element
+func validMountFileName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\x00") {
+		return fmt.Errorf("invalid file name %q", name)
+	}
+	return nil
+}
+
+// acquire fetches the files by id into the cache and links them into a new
+// staging directory by name, release removes the staging directory and
+// allows the files to be evicted
+func (c *fileMountCache) acquire(fs filestore.FileStore, files map[string]string) (dir string, release func(), err error) {
+	dir, err = os.MkdirTemp(filepath.Join(c.dir, "run"), "")
+	if err != nil {
+		return "", nil, err
+	}
+	var entries []*fileMountEntry
+	release = func() {
+		os.RemoveAll(dir)
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		for _, e := range entries {
+			c.put(e)
+		}
+		c.evict()
+	}
+	defer func() {
+		if err != nil {
+			release()
+		}
+	}()
+	// the container user reads the files through the directory
+	if err := os.Chmod(dir, 0755); err != nil {
+		return "", nil, err
+	}
+	for name, id := range files {
+		e, err := c.get(fs, id)
+		if err != nil {
+			return "", nil, fmt.Errorf("mount file %s: %w", name, err)
+		}
+		entries = append(entries, e)
+		if err := os.Link(e.path, filepath.Join(dir, name)); err != nil {
+			return "", nil, fmt.Errorf("mount file %s: %w", name, err)
+		}
+	}
+	return dir, release, nil
+}
+
+// get returns the referenced entry of the file, fetched if not cached. The
+// file store is always consulted so that removed files and files of the other
+// namespaces are not served from the cache.
+func (c *fileMountCache) get(fs filestore.FileStore, id string) (*fileMountEntry, error) {
+	_, f := fs.Get(id)
+	if f == nil {
+		return nil, fmt.Errorf("%w: %s", ErrFileNotFound, id)
+	}
+
+	c.mu.Lock()
+	e, ok := c.entries[id]
+	if !ok {
+		e = &fileMountEntry{
+			id:    id,
+			path:  filepath.Join(c.dir, "cache", id),
+			ready: make(chan struct{}),
+		}
+		c.entries[id] = e
+	}
+	if e.elem != nil {
+		c.lru.Remove(e.elem)
+		e.elem = nil
+	}
+	e.refs++
+	c.mu.Unlock()
+
+	if !ok {
+		e.size, e.err = fetchMountFile(f, e.path)
+		c.mu.Lock()
+		c.size += e.size
+		c.mu.Unlock()
+		close(e.ready)
+	}
+	<-e.ready
+	if e.err != nil {
+		c.mu.Lock()
+		c.put(e)
+		c.mu.Unlock()
+		return nil, e.err
+	}
+	return e, nil
+}
+
+// put drops the reference of the entry, failed entries are removed once they
+// are not referenced so that they could be fetched again
+func (c *fileMountCache) put(e *fileMountEntry) {
+	e.refs--
+	if e.refs > 0 {
+		return
+	}
+	if e.err != nil {
+		delete(c.entries, e.id)
+		return
+	}
+	e.elem = c.lru.PushBack(e)
+}
+
+// evict removes the least recently used files not in use until the total
+// size is within max
+func (c *fileMountCache) evict() {
+	for c.size > c.max && c.lru.Len() > 0 {
+		e := c.lru.Remove(c.lru.Front()).(*fileMountEntry)
+		os.Remove(e.path)
+		delete(c.entries, e.id)
+		c.size -= e.size
+	}
+}
+
+// fetchMountFile saves the file from the file store at path readable by the
+// container user. Local files are hard linked if possible (the stored file
+// becomes read-only for everyone as well), otherwise copied.
+func fetchMountFile(f envexec.File, path string) (envexec.Size, error) {
+	if fi, ok := f.(*envexec.FileInput); ok && os.Link(fi.Path, path) == nil {
+		if err := os.Chmod(path, 0444); err != nil {
+			os.Remove(path)
+			return 0, err
+		}
+		st, err := os.Stat(path)
+		if err != nil {
+			os.Remove(path)
+			return 0, err
+		}
+		return envexec.Size(st.Size()), nil
+	}
+
+	r, err := envexec.FileToReader(f)
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+
+	tmp := path + ".tmp"
+	w, err := os.OpenFile(tmp, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0444)
+	if err != nil {
+		return 0, err
+	}
+	n, err := io.Copy(w, r)
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp, path)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return 0, fmt.Errorf("failed to fetch file: %w", err)
+	}
+	return envexec.Size(n), nil
+}
@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// sniffLen is the bytes read to detect the content type, which is the most
+// http.DetectContentType considers
+const sniffLen = 512
+
+// FileType is the content type of a copy out file detected from its head
+type FileType struct {
+	ContentType string // by http.DetectContentType
+	Binary      bool   // not text or not valid UTF-8, which could not be kept in a JSON string
+}
+
+// detectFileType detects the type of the copy out file from its first bytes
+// without moving its offset, so that the file is read in whole afterward
+func detectFileType(f *os.File) FileType {
+	buf := make([]byte, sniffLen)
+	n, err := f.ReadAt(buf, 0)
+	if err != nil && err != io.EOF {
+		return FileType{ContentType: "application/octet-stream", Binary: true}
+	}
+	return fileTypeOf(buf[:n], n == sniffLen)
+}
+
+// fileTypeOf returns the type of the head of the file, a rune cut at the end
+// of truncated head is not counted as invalid
+func fileTypeOf(b []byte, truncated bool) FileType {
+	ct := http.DetectContentType(b)
+	if truncated {
+		b = trimPartialRune(b)
+	}
+	return FileType{
+		ContentType: ct,
+		Binary:      !strings.HasPrefix(ct, "text/") || !utf8.Valid(b),
+	}
+}
+
+// trimPartialRune removes the incomplete rune at the end of b
+func trimPartialRune(b []byte) []byte {
+	for i := 1; i < utf8.UTFMax && i <= len(b); i++ {
+		c := b[len(b)-i]
+		if !utf8.RuneStart(c) {
+			continue
+		}
+		if !utf8.FullRune(b[len(b)-i:]) {
+			return b[:len(b)-i]
+		}
+		break
+	}
+	return b
+}
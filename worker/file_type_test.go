@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemp(t *testing.T, content string) *os.File {
+	t.Helper()
+	f, err := os.Create(filepath.Join(t.TempDir(), "f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	if _, err := f.WriteString(content); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	return f
+}
+
+func TestDetectFileType(t *testing.T) {
+	const png = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\x00\x00\x00\x01\x00\x00\x00\x01\x08\x06\x00\x00\x00"
+	for _, c := range []struct {
+		name    string
+		content string
+		want    FileType
+	}{
+		{"utf-8 text", "héllo 世界\n", FileType{ContentType: "text/plain; charset=utf-8"}},
+		{"png", png, FileType{ContentType: "image/png", Binary: true}},
+		{"empty", "", FileType{ContentType: "text/plain; charset=utf-8"}},
+		{"invalid utf-8", "ab\xffcd", FileType{ContentType: "text/plain; charset=utf-8", Binary: true}},
+		{
+			// the rune cut by the head is not counted as invalid
+			"rune cut at the head", strings.Repeat("a", sniffLen-1) + "世界",
+			FileType{ContentType: "text/plain; charset=utf-8"},
+		},
+		{
+			// only the head is read
+			"binary after the head", strings.Repeat("a", sniffLen) + png,
+			FileType{ContentType: "text/plain; charset=utf-8"},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			f := writeTemp(t, c.content)
+			if got := detectFileType(f); got != c.want {
+				t.Fatalf("got %+v, want %+v", got, c.want)
+			}
+
+			// the offset is not moved so that the file is read in whole
+			b, err := io.ReadAll(f)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != c.content {
+				t.Fatalf("read %d bytes after detection, want %d", len(b), len(c.content))
+			}
+		})
+	}
+}
+
+func TestDetectFileTypeReadError(t *testing.T) {
+	f := writeTemp(t, "hello")
+	f.Close()
+	if got, want := detectFileType(f), (FileType{ContentType: "application/octet-stream", Binary: true}); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
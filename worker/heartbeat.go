@@ -0,0 +1,40 @@
+package worker
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// loopState is the state of a worker loop reported by its heartbeat
+type loopState int32
+
+const (
+	loopBusy    loopState = iota // between waiting and running, e.g. sending the result
+	loopWaiting                  // blocked in waiting for the requests
+	loopRunning                  // running a request
+)
+
+// heartbeat records the state of a worker loop and when it was entered
+type heartbeat struct {
+	state int32
+	at    int64 // unix nano
+}
+
+func newHeartbeat() *heartbeat {
+	h := new(heartbeat)
+	h.beat(loopBusy)
+	return h
+}
+
+func (h *heartbeat) beat(s loopState) {
+	atomic.StoreInt64(&h.at, time.Now().UnixNano())
+	atomic.StoreInt32(&h.state, int32(s))
+}
+
+// stuck reports whether the loop has been busy for timeout
+func (h *heartbeat) stuck(timeout time.Duration) bool {
+	if loopState(atomic.LoadInt32(&h.state)) != loopBusy {
+		return false
+	}
+	return time.Since(time.Unix(0, atomic.LoadInt64(&h.at))) >= timeout
+}
@@ -0,0 +1,32 @@
+package worker
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAlive(t *testing.T) {
+	const timeout = time.Second
+	past := time.Now().Add(-time.Minute).UnixNano()
+	for _, c := range []struct {
+		name  string
+		state loopState
+		at    int64
+		alive bool
+	}{
+		{"waiting long", loopWaiting, past, true},
+		{"running long", loopRunning, past, true},
+		{"busy", loopBusy, time.Now().UnixNano(), true},
+		{"busy long", loopBusy, past, false},
+	} {
+		waiting, h := newHeartbeat(), newHeartbeat()
+		waiting.beat(loopWaiting)
+		atomic.StoreInt32(&h.state, int32(c.state))
+		atomic.StoreInt64(&h.at, c.at)
+		w := &worker{loopAlive: map[int]*heartbeat{0: waiting, 1: h}}
+		if alive := w.Alive(timeout); alive != c.alive {
+			t.Errorf("%s: alive %v, want %v", c.name, alive, c.alive)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrInvalidHostExec is returned when the args reference host files which are
+// not allowed by HostExecDirs or could not be mounted for the command
+var ErrInvalidHostExec = errors.New("invalid host exec")
+
+// hostExecDir is the directory inside the container where the host files
+// referenced by args are mounted at their host paths
+const hostExecDir = "/host"
+
+// hostExec whitelists the host directories whose files could be referenced by
+// their absolute paths in args
+type hostExec struct {
+	dirs     []string // cleaned as configured
+	resolved []string // with symlinks resolved, empty if failed
+}
+
+// newHostExec resolves the whitelisted directories, the directories are kept
+// on error so that the references are still rejected
+func newHostExec(dirs []string) (*hostExec, error) {
+	h := &hostExec{}
+	for _, d := range dirs {
+		h.dirs = append(h.dirs, filepath.Clean(d))
+	}
+	resolved := make([]string, 0, len(dirs))
+	for _, d := range h.dirs {
+		if !filepath.IsAbs(d) {
+			return h, fmt.Errorf("host exec dir %q is not absolute", d)
+		}
+		r, err := filepath.EvalSymlinks(d)
+		if err != nil {
+			return h, fmt.Errorf("host exec dir: %w", err)
+		}
+		resolved = append(resolved, r)
+	}
+	h.resolved = resolved
+	return h, nil
+}
+
+// references reports whether the arg is a path under the directories, also
+// the ones escaping them by .. which are rejected by resolve
+func (h *hostExec) references(arg string) bool {
+	if !filepath.IsAbs(arg) {
+		return false
+	}
+	return underDirs(arg, h.dirs) || underDirs(filepath.Clean(arg), h.dirs)
+}
+
+// resolve returns the host path of the referenced file, which must be a
+// regular file within the directories after the symlinks are resolved
+func (h *hostExec) resolve(arg string) (string, error) {
+	p := filepath.Clean(arg)
+	if !underDirs(p, h.dirs) {
+		return "", fmt.Errorf("%s is outside the allowed directories", arg)
+	}
+	r, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		return "", err
+	}
+	if !underDirs(r, h.resolved) {
+		return "", fmt.Errorf("%s resolves to %s outside the allowed directories", arg, r)
+	}
+	fi, err := os.Stat(r)
+	if err != nil {
+		return "", err
+	}
+	if !fi.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a regular file", arg)
+	}
+	return r, nil
+}
+
+func underDirs(p string, dirs []string) bool {
+	for _, d := range dirs {
+		if d == "/" || strings.HasPrefix(p, d+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// hostExecFiles resolves the host files referenced by the args of the command
+// at the moment, mapping the path in the container to the host path
+func (w *worker) hostExecFiles(c Cmd) (map[string]string, error) {
+	if w.hostExec == nil {
+		return nil, nil
+	}
+	var files map[string]string
+	for _, a := range c.Args {
+		if !w.hostExec.references(a) {
+			continue
+		}
+		if w.hostExecErr != nil {
+			return nil, w.hostExecErr
+		}
+		r, err := w.hostExec.resolve(a)
+		if err != nil {
+			return nil, err
+		}
+		if files == nil {
+			files = make(map[string]string)
+		}
+		files[hostExecPath(a)] = r
+	}
+	return files, nil
+}
+
+// hostExecArgs rewrites the args referencing host files to their paths in the
+// container
+func (w *worker) hostExecArgs(args []string) []string {
+	if w.hostExec == nil {
+		return args
+	}
+	var rt []string
+	for i, a := range args {
+		if !w.hostExec.references(a) {
+			continue
+		}
+		if rt == nil {
+			rt = append(make([]string, 0, len(args)), args...)
+		}
+		rt[i] = hostExecPath(a)
+	}
+	if rt == nil {
+		return args
+	}
+	return rt
+}
+
+func hostExecPath(arg string) string {
+	return hostExecDir + filepath.Clean(arg)
+}
@@ -0,0 +1,368 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+)
+
+// argv of commands reported by Jobs is truncated to avoid huge responses
+const (
+	maxJobArgs   = 16
+	maxJobArgLen = 64
+)
+
+// Job describes a request submitted to the worker that is waiting or running
+type Job struct {
+	ID        string // assigned by the worker, used to kill the job
+	RequestID string
+	Owner     string        // file store namespace of the client, empty if not restricted
+	Worker    int           // index of the worker loop running the job, -1 if waiting or executed directly
+	Running   bool          // false if waiting in the queue
+	Wait      time.Duration // time waited in the queue
+	Elapsed   time.Duration // wall time since started running
+	Cmd       []JobCmd
+}
+
+// JobCmd describes a command of the job with its limits
+type JobCmd struct {
+	Args        []string      // truncated argv
+	CPUTime     time.Duration // cpu time consumed so far, 0 if not started
+	CPULimit    time.Duration
+	ClockLimit  time.Duration
+	MemoryLimit Size
+	ProcLimit   uint64
+}
+
+type jobKey struct{}
+
+var (
+	errKilled  = errors.New("killed")
+	errAborted = errors.New("aborted by shutdown")
+)
+
+// ErrCancelRequested is the cause to cancel the context of the submitter when
+// the cancel is requested by the client other than the client is gone (e.g.
+// the cancel message of WebSocket), so that it is reported as CancelCancelled
+var ErrCancelRequested = errors.New("cancel requested")
+
+// CancelReason tells why the server terminated the request, the client could
+// decide whether to retry by it
+type CancelReason string
+
+// CancelReason values, empty if the request is not terminated by the server
+// (e.g. the limit exceeded kills, which are reported by the status)
+const (
+	CancelShutdown     CancelReason = "shutdown"     // the server is shutting down, could retry elsewhere
+	CancelClientGone   CancelReason = "clientGone"   // the submitter disconnected
+	CancelCancelled    CancelReason = "cancelled"    // killed by the job API or cancelled by the client
+	CancelQueueTimeout CancelReason = "queueTimeout" // the deadline of the submitter expired in the queue
+)
+
+// Retryable reports whether the request terminated by the reason could be
+// submitted again, e.g. to another server on shutdown or later on the queue
+// timeout. The requests cancelled on purpose or by the client gone are not.
+func (r CancelReason) Retryable() bool {
+	return r == CancelShutdown || r == CancelQueueTimeout
+}
+
+type job struct {
+	id        string
+	req       *Request
+	owner     string
+	submitted time.Time
+	ctx       context.Context
+	cancel    context.CancelCauseFunc
+	parent    context.Context // context of the submitter
+
+	mu      sync.Mutex
+	worker  int
+	started time.Time
+	procs   []envexec.Process // running process of each command
+	cpuTime []time.Duration   // cpu time of finished commands
+	envWait time.Duration     // waited for the environments from the pool
+}
+
+// addJob registers the request as waiting job, the returned context is
+// cancelled when the job is killed or removed
+func (w *worker) addJob(ctx context.Context, req *Request) (context.Context, *job) {
+	parent := ctx
+	ctx, cancel := context.WithCancelCause(ctx)
+	owner, _ := filestore.NamespaceFromContext(ctx)
+	j := &job{
+		req:       req,
+		owner:     owner,
+		submitted: time.Now(),
+		ctx:       ctx,
+		cancel:    cancel,
+		parent:    parent,
+		worker:    -1,
+		procs:     make([]envexec.Process, len(req.Cmd)),
+		cpuTime:   make([]time.Duration, len(req.Cmd)),
+	}
+
+	w.jobMu.Lock()
+	defer w.jobMu.Unlock()
+	w.jobSeq++
+	j.id = strconv.FormatUint(w.jobSeq, 10)
+	w.jobs[j.id] = j
+	return context.WithValue(ctx, jobKey{}, j), j
+}
+
+// DetachContext returns a context that keeps the values of ctx but is never
+// cancelled, so that the request runs to completion after the submitter is
+// gone
+func DetachContext(ctx context.Context) context.Context {
+	return detachedContext{ctx}
+}
+
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool)         { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}               { return nil }
+func (detachedContext) Err() error                          { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+func (w *worker) removeJob(j *job) {
+	w.jobMu.Lock()
+	delete(w.jobs, j.id)
+	w.jobMu.Unlock()
+	j.cancel(nil)
+}
+
+// Jobs returns the waiting and running jobs in the order of submission
+func (w *worker) Jobs() []Job {
+	w.jobMu.Lock()
+	jobs := make([]*job, 0, len(w.jobs))
+	for _, j := range w.jobs {
+		jobs = append(jobs, j)
+	}
+	w.jobMu.Unlock()
+
+	sort.Slice(jobs, func(i, k int) bool {
+		return jobs[i].submitted.Before(jobs[k].submitted)
+	})
+	rt := make([]Job, 0, len(jobs))
+	for _, j := range jobs {
+		rt = append(rt, j.snapshot())
+	}
+	return rt
+}
+
+// Kill cancels the job, waiting job finishes without running and running
+// commands are killed
+func (w *worker) Kill(id string) bool {
+	w.jobMu.Lock()
+	j, ok := w.jobs[id]
+	w.jobMu.Unlock()
+	if ok {
+		// with a cause the outputs of the killed commands are still collected
+		j.cancel(errKilled)
+	}
+	return ok
+}
+
+// Abort kills all the jobs since the server is shutting down, their
+// results are reported with CancelShutdown
+func (w *worker) Abort() {
+	w.jobMu.Lock()
+	defer w.jobMu.Unlock()
+	for _, j := range w.jobs {
+		j.cancel(errAborted)
+	}
+}
+
+// cancelReason returns the reason of the job terminated by the server, empty
+// if the job is not cancelled. The cause of the job context is the cause of
+// the submitter context if the submitter is gone first.
+func (j *job) cancelReason() CancelReason {
+	return cancelReasonOf(context.Cause(j.ctx), j.isStarted())
+}
+
+// cancelReasonOf returns the reason by the cause of the cancelled context
+func cancelReasonOf(cause error, started bool) CancelReason {
+	switch {
+	case cause == nil:
+		return ""
+	case errors.Is(cause, errKilled), errors.Is(cause, ErrCancelRequested):
+		return CancelCancelled
+	case errors.Is(cause, errAborted):
+		return CancelShutdown
+	case errors.Is(cause, context.DeadlineExceeded) && !started:
+		return CancelQueueTimeout
+	}
+	return CancelClientGone
+}
+
+// markCancelled sets the reason on the response and the results killed if
+// the job was cancelled
+func (j *job) markCancelled(rt *Response) {
+	reason := j.cancelReason()
+	if reason == "" {
+		return
+	}
+	for i := range rt.Results {
+		if rt.Results[i].TerminatedBy == envexec.TerminatedByCancelled {
+			rt.Results[i].CancelReason = reason
+			rt.CancelReason = reason
+		}
+	}
+	if rt.Error != nil {
+		rt.CancelReason = reason
+	}
+}
+
+// cancelledBySubmitter reports whether the context of the submitter is done
+// (e.g. the client disconnected), other than killed by Kill
+func (j *job) cancelledBySubmitter() bool {
+	return j.parent.Err() != nil
+}
+
+func (j *job) start(worker int) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.worker = worker
+	j.started = time.Now()
+}
+
+// addEnvWait records the time waited for an environment from the pool
+func (j *job) addEnvWait(d time.Duration) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.envWait += d
+}
+
+// waits returns the time the started job waited in the queue and for the
+// environments, and the time it has run until now
+func (j *job) waits(now time.Time) (queueWait, envWait, runTime time.Duration) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.started.Sub(j.submitted), j.envWait, now.Sub(j.started)
+}
+
+func (j *job) workerIndex() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.worker
+}
+
+func (j *job) isStarted() bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return !j.started.IsZero()
+}
+
+func (j *job) snapshot() Job {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	rt := Job{
+		ID:        j.id,
+		RequestID: j.req.RequestID,
+		Owner:     j.owner,
+		Worker:    j.worker,
+		Running:   !j.started.IsZero(),
+		Cmd:       make([]JobCmd, 0, len(j.req.Cmd)),
+	}
+	if rt.Running {
+		rt.Wait = j.started.Sub(j.submitted)
+		rt.Elapsed = time.Since(j.started)
+	} else {
+		rt.Wait = time.Since(j.submitted)
+	}
+	for i, c := range j.req.Cmd {
+		cpuTime := j.cpuTime[i]
+		if p := j.procs[i]; p != nil {
+			cpuTime = peekUsage(p).Time
+		}
+		rt.Cmd = append(rt.Cmd, JobCmd{
+			Args:        truncateArgs(c.Args),
+			CPUTime:     cpuTime,
+			CPULimit:    c.CPULimit,
+			ClockLimit:  c.ClockLimit,
+			MemoryLimit: c.MemoryLimit,
+			ProcLimit:   c.ProcLimit,
+		})
+	}
+	return rt
+}
+
+// memoryUsed returns the memory used by the running commands of the job
+func (j *job) memoryUsed() Size {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var rt Size
+	for _, p := range j.procs {
+		if p != nil {
+			rt += peekUsage(p).Memory
+		}
+	}
+	return rt
+}
+
+// watchJob records the process of c as the i-th command of the job in ctx
+// while it is running
+func watchJob(ctx context.Context, i int, c *envexec.Cmd) {
+	j, ok := ctx.Value(jobKey{}).(*job)
+	if !ok {
+		return
+	}
+	wait := c.Waiter
+	c.Waiter = func(ctx context.Context, p envexec.Process) bool {
+		j.setProcess(i, p)
+		defer j.finishProcess(i, p)
+		return wait(ctx, p)
+	}
+}
+
+func (j *job) setProcess(i int, p envexec.Process) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.procs[i] = p
+}
+
+// finishProcess keeps the last cpu time since the cgroup of the process could
+// be reused after the command finished
+func (j *job) finishProcess(i int, p envexec.Process) {
+	t := peekUsage(p).Time
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.procs[i] = nil
+	j.cpuTime[i] = t
+}
+
+// peekUsage reads the usage without affecting the statistics of the run
+func peekUsage(p envexec.Process) envexec.Usage {
+	if pp, ok := p.(envexec.PeekUsageProcess); ok {
+		return pp.PeekUsage()
+	}
+	return p.Usage()
+}
+
+func truncateArgs(args []string) []string {
+	n := len(args)
+	if n > maxJobArgs {
+		n = maxJobArgs
+	}
+	rt := make([]string, 0, n+1)
+	for _, a := range args[:n] {
+		if len(a) > maxJobArgLen {
+			a = a[:maxJobArgLen] + "..."
+		}
+		rt = append(rt, a)
+	}
+	if len(args) > n {
+		rt = append(rt, "...")
+	}
+	return rt
+}
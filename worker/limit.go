@@ -0,0 +1,201 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// Unlimited is the value of the cpu, clock, memory, stack and proc limits of
+// the commands requesting no limit, allowed only with Config.AllowUnlimited
+// and FeatureUnlimited of the policy. The max limits still apply to it.
+const Unlimited = math.MaxInt64
+
+// Unlimited in the types of the limits
+const (
+	UnlimitedDuration = time.Duration(Unlimited)
+	UnlimitedSize     = Size(Unlimited)
+	UnlimitedProc     = uint64(Unlimited)
+)
+
+// unlimitedPids is the proc limit of the environment for UnlimitedProc, the
+// max accepted by pids.max (PID_MAX_LIMIT)
+const unlimitedPids = 1 << 22
+
+// ErrLimitExceeded is returned when the limit of the command exceeds the
+// LimitMax of the server and the limits are not clamped
+var ErrLimitExceeded = errors.New("limit exceeds the server max")
+
+// ErrUnlimitedDisabled is returned when the command requests an unlimited
+// limit while Config.AllowUnlimited is not set
+var ErrUnlimitedDisabled = errors.New("unlimited limit is not allowed")
+
+// LimitError is returned (wrapping ErrLimitExceeded) by LimitMax.Apply when
+// the limit of the command exceeds the max
+type LimitError struct {
+	Limit string // name of the limit, e.g. cpuLimit
+	Value string
+	Max   string
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s: %s %s exceeds the max %s", ErrLimitExceeded, e.Limit, e.Value, e.Max)
+}
+
+func (e *LimitError) Is(target error) bool {
+	return target == ErrLimitExceeded
+}
+
+// LimitMax defines the max limits that a command could request, 0 for no max
+type LimitMax struct {
+	CPU    time.Duration
+	Clock  time.Duration
+	Memory Size
+	Proc   uint64
+}
+
+// Apply checks the limits of the command against the max. The limits above
+// the max are lowered to the max if clamp, otherwise LimitError is returned. The unset memory and proc limits, which are unlimited, are set to
+// the max. Since the program waits for the cpu limit at least, the clock max
+// also applies to the cpu limit.
+func (m LimitMax) Apply(c *Cmd, clamp bool) error {
+	clockMax := m.Clock
+	cpuMax := m.CPU
+	if clockMax > 0 && (cpuMax == 0 || clockMax < cpuMax) {
+		cpuMax = clockMax
+	}
+	clock := c.ClockLimit
+	if clock < c.CPULimit {
+		clock = c.CPULimit
+	}
+	switch {
+	case clamp:
+	case cpuMax > 0 && c.CPULimit > cpuMax:
+		return &LimitError{Limit: "cpuLimit", Value: limitString(uint64(c.CPULimit), c.CPULimit.String()), Max: cpuMax.String()}
+	case clockMax > 0 && clock > clockMax:
+		return &LimitError{Limit: "clockLimit", Value: limitString(uint64(clock), clock.String()), Max: clockMax.String()}
+	case m.Memory > 0 && c.MemoryLimit > m.Memory:
+		return &LimitError{Limit: "memoryLimit", Value: limitString(uint64(c.MemoryLimit), c.MemoryLimit.String()), Max: m.Memory.String()}
+	case m.Proc > 0 && c.ProcLimit > m.Proc:
+		return &LimitError{Limit: "procLimit", Value: limitString(c.ProcLimit, strconv.FormatUint(c.ProcLimit, 10)), Max: strconv.FormatUint(m.Proc, 10)}
+	}
+	if cpuMax > 0 && c.CPULimit > cpuMax {
+		c.CPULimit = cpuMax
+	}
+	if clockMax > 0 && c.ClockLimit > clockMax {
+		c.ClockLimit = clockMax
+	}
+	if m.Memory > 0 && (c.MemoryLimit == 0 || c.MemoryLimit > m.Memory) {
+		c.MemoryLimit = m.Memory
+	}
+	if m.Proc > 0 && (c.ProcLimit == 0 || c.ProcLimit > m.Proc) {
+		c.ProcLimit = m.Proc
+	}
+	return nil
+}
+
+func limitString(v uint64, s string) string {
+	if v == Unlimited {
+		return "unlimited"
+	}
+	return s
+}
+
+// LimitDefault defines the limits of the commands not setting them, 0 for no
+// default. The clock limit not set follows the cpu limit, and the stack
+// limit not set (or above the memory limit) follows the memory limit.
+type LimitDefault struct {
+	CPU    time.Duration
+	Clock  time.Duration
+	Memory Size
+	Stack  Size
+	Proc   uint64
+}
+
+// apply sets the limits not set by the command to the defaults lowered to the
+// max, so that the defaults never get the command rejected
+func (d LimitDefault) apply(c *Cmd, max ...LimitMax) {
+	dc := Cmd{CPULimit: d.CPU, ClockLimit: d.Clock, MemoryLimit: d.Memory, ProcLimit: d.Proc}
+	for _, m := range max {
+		m.Apply(&dc, true)
+	}
+	if c.CPULimit == 0 {
+		c.CPULimit = dc.CPULimit
+	}
+	if c.ClockLimit == 0 {
+		c.ClockLimit = dc.ClockLimit
+	}
+	if c.MemoryLimit == 0 {
+		c.MemoryLimit = dc.MemoryLimit
+	}
+	if c.ProcLimit == 0 {
+		c.ProcLimit = dc.ProcLimit
+	}
+	if c.StackLimit == 0 {
+		c.StackLimit = d.Stack
+	}
+}
+
+// capStack lowers the stack limit to the memory limit applied, as the
+// environment does, and sets the stack limit not set to it
+func (c *Cmd) capStack() {
+	if c.MemoryLimit > 0 && (c.StackLimit == 0 || c.StackLimit > c.MemoryLimit) {
+		c.StackLimit = c.MemoryLimit
+	}
+}
+
+// unlimited reports whether the command requests any unlimited limit
+func (c *Cmd) unlimited() bool {
+	return c.CPULimit == UnlimitedDuration || c.ClockLimit == UnlimitedDuration ||
+		c.MemoryLimit == UnlimitedSize || c.StackLimit == UnlimitedSize || c.ProcLimit == UnlimitedProc
+}
+
+// applyLimitMax applies the policy of the submitter and the max limits to the
+// commands in place, so that the submitter sees the limits applied, and
+// checks the repeat, the args, the memory reservation and the verdict against
+// the config
+func (w *worker) applyLimitMax(ctx context.Context, req *Request) error {
+	for i := range req.Cmd {
+		if req.Cmd[i].unlimited() && !w.allowUnlimited {
+			return fmt.Errorf("cmd[%d]: %w", i, ErrUnlimitedDisabled)
+		}
+	}
+	maxes := []LimitMax{w.limitMax}
+	if p := w.policy(ctx); p != nil {
+		maxes = append(maxes, p.LimitMax)
+	}
+	for i := range req.Cmd {
+		w.limitDefault.apply(&req.Cmd[i], maxes...)
+	}
+	if err := w.applyPolicy(ctx, req); err != nil {
+		return err
+	}
+	if req.Repeat != 0 {
+		switch {
+		case w.repeatMax == 0:
+			return fmt.Errorf("%w: repeat is disabled", ErrInvalidRepeat)
+		case len(req.Cmd) != 1:
+			return fmt.Errorf("%w: repeat requires exactly one cmd, got %d", ErrInvalidRepeat, len(req.Cmd))
+		case req.Repeat < 0:
+			return fmt.Errorf("%w: negative repeat %d", ErrInvalidRepeat, req.Repeat)
+		case req.Repeat > w.repeatMax:
+			return fmt.Errorf("%w: repeat %d exceeds the max %d", ErrLimitExceeded, req.Repeat, w.repeatMax)
+		}
+	}
+	for i := range req.Cmd {
+		if err := w.limitMax.Apply(&req.Cmd[i], w.limitClamp); err != nil {
+			return fmt.Errorf("cmd[%d]: %w", i, err)
+		}
+		req.Cmd[i].capStack()
+		if err := w.argLimit.check(&req.Cmd[i]); err != nil {
+			return fmt.Errorf("cmd[%d]: %w", i, err)
+		}
+	}
+	if err := w.validReservation(req); err != nil {
+		return err
+	}
+	return w.validVerdict(req)
+}
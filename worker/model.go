@@ -13,6 +13,7 @@ type Size = envexec.Size
 type CmdCopyOutFile = envexec.CmdCopyOutFile
 type PipeMap = envexec.Pipe
 type PipeIndex = envexec.PipeIndex
+type Credential = envexec.Credential
 
 // Cmd defines command and limits to start a program using in envexec
 type Cmd struct {
@@ -21,6 +22,10 @@ type Cmd struct {
 	Files []CmdFile
 	TTY   bool
 
+	// ExtraFiles binds files to fds from 3 by fd number, e.g. fd 3 used by
+	// the interactor protocol, the fds not bound in between are /dev/null
+	ExtraFiles map[int]CmdFile
+
 	CPULimit          time.Duration
 	ClockLimit        time.Duration
 	MemoryLimit       Size
@@ -31,6 +36,54 @@ type Cmd struct {
 	CPURateLimit      uint64
 	CPUSetLimit       string
 	StrictMemoryLimit bool
+	DetailedMemory    bool
+	Credential        *Credential // pinned uid / gid, nil to use generated one
+	RandomizeWorkDir  bool        // mount work directory at randomized path
+	Datasets          []string    // names of read-only datasets mounted at /data/<name>
+	Profile           string      // mount profile instead of the configured mounts, empty for default
+	IOLimit           Size        // bytes written to block devices, 0 for unlimited
+	CollectCore       bool        // collect the core file into the file store on signalled exit
+	Trace             bool        // count the syscalls, time and memory are not accurate
+
+	// ProcInfo mounts synthetic /proc/meminfo and /proc/cpuinfo reporting
+	// the memory limit and ProcInfoCPUs (the server default if 0) processors
+	ProcInfo     bool
+	ProcInfoCPUs uint64
+
+	// RandomSeed replaces /dev/urandom and /dev/random with the
+	// deterministic stream of the seed if not nil
+	RandomSeed *uint64
+
+	// Arch specifies the architecture of the program (e.g. x86) for the
+	// limits and the result, auto to detect it from the executable
+	Arch string
+
+	// ShmSize limits the bytes left in /dev/shm, the server default if 0
+	ShmSize Size
+
+	// DiskWorkDir backs the work directory and /tmp by a directory created on
+	// the host disk for the run instead of tmpfs
+	DiskWorkDir bool
+
+	// ScratchFile mounts an empty file on the host disk with ScratchSize (the
+	// server max if 0) allocated at the name in the work directory, so that
+	// fsync and O_DIRECT of the program reach the device
+	ScratchFile string
+	ScratchSize Size
+
+	// SnapshotWorkDir is save[:<label>] to save the work directory after the
+	// command finished, or restore[:<label>] to restore the one saved by a
+	// previous command of the request before the copy in. The label is
+	// default if not specified. It requires RunModeSequential.
+	SnapshotWorkDir string
+
+	// ReportTiming reports the cpu time spent by the container for the run
+	// (not charged to the program) into OverheadCPUTime
+	ReportTiming bool
+
+	// MountFiles maps the file name under /files to the file id mounted
+	// read-only from the file store
+	MountFiles map[string]string
 
 	CopyIn   map[string]CmdFile
 	Symlinks map[string]string
@@ -39,6 +92,77 @@ type Cmd struct {
 	CopyOutCached []CmdCopyOutFile
 	CopyOutMax    uint64
 	CopyOutDir    string
+
+	// CopyOutMaxFiles and CopyOutMaxTotal could only lower the server limits
+	CopyOutMaxFiles int
+	CopyOutMaxTotal uint64
+
+	// CopyOutCachedGroup caches the regular files matching the patterns of
+	// path.Match as one group in the file store, which is extracted by
+	// GroupFile as a whole
+	CopyOutCachedGroup []CmdCopyOutFile
+
+	// RelativeLimits replaces the limits by the ones resolved from the usage
+	// of the previous commands before the command runs. It requires
+	// RunModeSequential and the command is Skipped if a command referenced is
+	// not accepted.
+	RelativeLimits RelativeLimits
+}
+
+// NewCredential creates pinned credential, the unset one of uid / gid follows
+// the other and nil is returned if both of them are unset (0)
+func NewCredential(uid, gid uint32) *Credential {
+	switch {
+	case uid == 0 && gid == 0:
+		return nil
+	case uid == 0:
+		uid = gid
+	case gid == 0:
+		gid = uid
+	}
+	return &Credential{UID: uid, GID: gid}
+}
+
+// RunMode defines how the commands of a request are executed
+type RunMode int
+
+// RunMode
+const (
+	RunModeParallel   RunMode = iota // run all commands simultaneously (default)
+	RunModeSequential                // run commands one after another
+)
+
+// Priority is the hint of the order in which the waiting requests are taken
+type Priority int
+
+// Priority
+const (
+	PriorityNormal Priority = iota // default
+	PriorityLow                    // e.g. compile
+	PriorityHigh                   // e.g. run
+)
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+// rank orders the priorities, higher is taken first
+func (p Priority) rank() int {
+	switch p {
+	case PriorityLow:
+		return -1
+	case PriorityHigh:
+		return 1
+	default:
+		return 0
+	}
 }
 
 // Request defines single worker request
@@ -46,19 +170,118 @@ type Request struct {
 	RequestID   string
 	Cmd         []Cmd
 	PipeMapping []PipeMap
+	RunMode     RunMode // commands connected by pipes are always run in parallel
+	SkipVerify  bool    // skips the digest verification of cached files
+	KillOnLimit bool    // kills the other commands running in parallel once any of them exceeded its limits
+	Priority    Priority
+
+	// EstimatedDuration is the hint of the wall time of the request given by
+	// the client, the shorter ones are taken first among the waiting requests
+	// of the same priority by SchedulerSJF, 0 if not known
+	EstimatedDuration time.Duration
+
+	// NUMANode pins the commands to the NUMA node if not nil, otherwise the
+	// least loaded node is chosen when the environments are sharded by node
+	NUMANode *int
+
+	// IdempotencyKey identifies the retries of the request within the
+	// namespace of the submitter, the response of the first run is returned
+	// to all of them
+	IdempotencyKey string
+
+	// Repeat runs the single command Repeat times back-to-back in the same
+	// environment and returns the result of each repetition in order, e.g.
+	// to calibrate the time limits. It is capped by Config.RepeatMax.
+	Repeat int
+
+	// Verdict names the verdict rules rewriting the results, the rules of
+	// VerdictDefault apply if empty. IncludeRaw keeps the result before
+	// rewritten in Result.Raw.
+	Verdict    string
+	IncludeRaw bool
 }
 
 // Result defines single command response
 type Result struct {
-	Status     envexec.Status
-	ExitStatus int
-	Error      string
-	Time       time.Duration
-	RunTime    time.Duration
-	Memory     envexec.Size
-	Files      map[string]*os.File
-	FileIDs    map[string]string
-	FileError  []envexec.FileError
+	Status       envexec.Status
+	ExitStatus   int
+	Signal       int
+	TerminatedBy envexec.TerminationReason
+	CancelReason CancelReason // set if TerminatedBy is cancelled
+	Error        string
+	Time         time.Duration
+	RunTime      time.Duration
+	Memory       envexec.Size
+	MemoryStat   *envexec.MemoryStat
+	ProcPeak     uint64
+	StrayProcs   uint64          // processes left after the exit and killed
+	Arch         string          // architecture of the program if Arch is set and known
+	Emulated     bool            // the program of Arch ran under an emulator
+	RandomSeed   *uint64         // seed of the deterministic random devices if set
+	RandomOpened bool            // the program opened the deterministic random devices
+	ShmUsage     envexec.Size    // bytes left in /dev/shm if mounted
+	DiskUsage    envexec.Size    // bytes left in the work directory backed by disk
+	WorkDirUsage envexec.Size    // bytes used in the tmpfs work directory
+	WorkDirSize  envexec.Size    // size of the tmpfs work directory
+	InitLog      string          // stderr of the container init on Internal Error
+	IOStat       *envexec.IOStat // bytes read and written to block devices if accounted
+	Files        map[string]*os.File
+	FileIDs      map[string]string
+	GroupID      string              // group of the files cached by CopyOutCachedGroup
+	FileSize     map[string]int64    // total size of outputs cached by collectors
+	FileType     map[string]FileType // detected type of the files and cached files copied out
+	FileError    []envexec.FileError
+	Extracted    map[string]int
+	CopyInBytes  envexec.Size // copied in, including the extracted archives
+	CachedBytes  envexec.Size // added to the file store by the cached outputs and core file
+	Warning      string
+	Message      string                        // attached by the verdict rule
+	WorkDir      string                        // randomized work directory
+	PipeBytes    map[int]int64                 // bytes forwarded by proxy from fd to the other end
+	OutputStat   map[string]envexec.OutputStat // bytes and lines written to pipe / discard collectors
+
+	// Throttling is the cfs throttling of the cpu rate limit if available
+	Throttling *envexec.CPUThrottling
+
+	// EnvironmentID and CgroupID identify the pooled environment and the
+	// cgroup that ran the command, 0 if not available
+	EnvironmentID uint64
+	CgroupID      uint64
+
+	// CoreFileID and CoreSize are the collected core file in the file store
+	CoreFileID string
+	CoreSize   int64
+
+	// SyscallCount is the number of calls of each syscall if Trace is set
+	SyscallCount map[string]uint64
+
+	// OverheadCPUTime is the cpu time spent by the container for the run if
+	// ReportTiming is set and it is measured
+	OverheadCPUTime time.Duration
+
+	// FileStat is the metadata of the copy out files with StatOnly
+	FileStat map[string]envexec.FileStat
+
+	// CopyOutTime is the wall time spent on copy out
+	CopyOutTime time.Duration
+
+	StartedAt  time.Time // zero if not executed
+	Hostname   string    // host executed the command
+	InstanceID string    // Config.InstanceID
+
+	// AttachRetries is the number of retries on transient cgroup failures
+	// before the command started
+	AttachRetries int
+
+	// RelativeLimits are the limits resolved from the previous commands
+	RelativeLimits []ResolvedLimit
+
+	// NoisyNeighbor are the Config.NoisyNeighbor thresholds exceeded by the
+	// run, e.g. NoisyMajorFaults
+	NoisyNeighbor []string
+
+	// Raw is the result before rewritten by the verdict rules if IncludeRaw
+	Raw *RawResult
 }
 
 // Response defines worker response for single request
@@ -66,30 +289,69 @@ type Response struct {
 	RequestID string
 	Results   []Result
 	Error     error
+	Note      string // Note explains the deviation from the requested run mode if any
+
+	// CancelReason is set if the request was terminated by the server before
+	// or while running, the results killed are also marked
+	CancelReason CancelReason
+
+	// Worker is the index of the worker loop that ran the repetitions of the
+	// request with Repeat, -1 if executed directly
+	Worker int
+
+	// EstimatedDuration echoes the hint of the request and Duration is the
+	// wall time the request ran (not including the time waited in the
+	// queue), so that the client could calibrate its hints. Both are only set
+	// if the request has the hint.
+	EstimatedDuration time.Duration
+	Duration          time.Duration
+
+	// Usage sums the commands of the executed request, it is the usage
+	// accounted to the client. Zero if Error is set.
+	Usage RequestUsage
+
+	// EnvWait is the time waited for the environments from the pool, set if
+	// the request was taken by a worker loop
+	EnvWait time.Duration
+}
+
+// AuditRecord defines the request executed by the worker with its response
+type AuditRecord struct {
+	Client     string // file store namespace of the submitter, empty if not restricted
+	Request    *Request
+	Response   Response
+	StartedAt  time.Time // taken by the worker
+	FinishedAt time.Time
 }
 
 func (r Result) String() string {
 	type Result struct {
-		Status     envexec.Status
-		ExitStatus int
-		Error      string
-		Time       time.Duration
-		RunTime    time.Duration
-		Memory     envexec.Size
-		Files      map[string]string
-		FileIDs    map[string]string
-		FileError  []envexec.FileError
+		Status       envexec.Status
+		ExitStatus   int
+		Signal       int
+		TerminatedBy envexec.TerminationReason
+		Error        string
+		Time         time.Duration
+		RunTime      time.Duration
+		Memory       envexec.Size
+		Files        map[string]string
+		FileIDs      map[string]string
+		FileError    []envexec.FileError
+		Extracted    map[string]int
 	}
 	d := Result{
-		Status:     r.Status,
-		ExitStatus: r.ExitStatus,
-		Error:      r.Error,
-		Time:       r.Time,
-		RunTime:    r.RunTime,
-		Memory:     r.Memory,
-		Files:      make(map[string]string),
-		FileIDs:    r.FileIDs,
-		FileError:  r.FileError,
+		Status:       r.Status,
+		ExitStatus:   r.ExitStatus,
+		Signal:       r.Signal,
+		TerminatedBy: r.TerminatedBy,
+		Error:        r.Error,
+		Time:         r.Time,
+		RunTime:      r.RunTime,
+		Memory:       r.Memory,
+		Files:        make(map[string]string),
+		FileIDs:      r.FileIDs,
+		FileError:    r.FileError,
+		Extracted:    r.Extracted,
 	}
 	for k, v := range r.Files {
 		d.Files[k] = filepath.Base(v.Name())
@@ -0,0 +1,43 @@
+package worker
+
+import (
+	"fmt"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// Usages exceeding the NoisyNeighbor thresholds, reported in
+// Result.NoisyNeighbor
+const (
+	NoisyMajorFaults = "majorFaults"
+	NoisyWriteback   = "writeback"
+)
+
+// NoisyNeighbor defines the thresholds of the usage of a run which degrades
+// the timing of the co-located runs through memory bandwidth and writeback
+// even within its limits, 0 to disable. The runs exceeding them are flagged
+// only.
+type NoisyNeighbor struct {
+	MajorFaults uint64       // major page faults of the run
+	Writeback   envexec.Size // bytes written to block devices (i.e. writeback of dirty pages) by the run
+}
+
+// check returns the thresholds exceeded by the result and the warning
+// telling the usage
+func (n NoisyNeighbor) check(r envexec.Result) ([]string, string) {
+	var (
+		rt      []string
+		warning string
+	)
+	if n.MajorFaults > 0 && r.MemoryStat != nil && r.MemoryStat.MajorPageFault > n.MajorFaults {
+		rt = append(rt, NoisyMajorFaults)
+		warning = joinWarning(warning, fmt.Sprintf("noisy neighbor: %d major page faults exceed %d",
+			r.MemoryStat.MajorPageFault, n.MajorFaults))
+	}
+	if n.Writeback > 0 && r.IOStat != nil && r.IOStat.Write > n.Writeback {
+		rt = append(rt, NoisyWriteback)
+		warning = joinWarning(warning, fmt.Sprintf("noisy neighbor: %s written back exceeds %s",
+			r.IOStat.Write, n.Writeback))
+	}
+	return rt, warning
+}
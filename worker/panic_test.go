@@ -0,0 +1,108 @@
+package worker_test
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/criyle/go-judge/env/fake"
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+)
+
+// panicBuilder builds the fake environments that panic on executing the
+// program "panic", and counts the environments alive
+type panicBuilder struct {
+	*fake.Builder
+	alive int32
+}
+
+func (b *panicBuilder) Build() (pool.Environment, error) {
+	e, err := b.Builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	atomic.AddInt32(&b.alive, 1)
+	return &panicEnv{Environment: e, alive: &b.alive}, nil
+}
+
+type panicEnv struct {
+	pool.Environment
+	alive     *int32
+	destroyed sync.Once
+}
+
+func (e *panicEnv) Execve(ctx context.Context, param envexec.ExecveParam) (envexec.Process, error) {
+	if len(param.Args) > 0 && param.Args[0] == "panic" {
+		panic("injected")
+	}
+	return e.Environment.Execve(ctx, param)
+}
+
+func (e *panicEnv) Destroy() error {
+	e.destroyed.Do(func() { atomic.AddInt32(e.alive, -1) })
+	return e.Environment.Destroy()
+}
+
+func TestPanicDiscardsEnvironment(t *testing.T) {
+	b := &panicBuilder{Builder: fake.NewBuilder(fake.Config{Dir: t.TempDir()})}
+	p := pool.NewPool(b)
+	var panicked []string
+	w := newTestWorker(t, func(c *worker.Config) {
+		c.EnvironmentPool = p
+		c.PanicObserver = func(requestID string, value any, stack []byte) {
+			if value != "injected" || len(stack) == 0 {
+				t.Errorf("panic %v with stack %q", value, stack)
+			}
+			panicked = append(panicked, requestID)
+		}
+	})
+
+	panicCmd := shCmd("")
+	panicCmd.Args = []string{"panic"}
+	for _, c := range []struct {
+		name string
+		cmd  []worker.Cmd
+	}{
+		{"single", []worker.Cmd{panicCmd}},
+		// the panic of the command goroutine destroys every environment of
+		// the group
+		{"group", []worker.Cmd{shCmd("echo 1"), panicCmd}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			panicked = nil
+			rt := run(t, w, &worker.Request{RequestID: c.name, Cmd: c.cmd})
+			if len(rt.Results) != len(c.cmd) {
+				t.Fatalf("got %d results, want %d", len(rt.Results), len(c.cmd))
+			}
+			for i, r := range rt.Results {
+				if r.Status != envexec.StatusInternalError || !strings.Contains(r.Error, "panic: injected") {
+					t.Fatalf("cmd[%d]: %v: %s, want internal error by the panic", i, r.Status, r.Error)
+				}
+			}
+			if len(panicked) != 1 || panicked[0] != c.name {
+				t.Fatalf("panics observed %v, want [%s]", panicked, c.name)
+			}
+			if n := atomic.LoadInt32(&b.alive); n != 0 {
+				t.Fatalf("%d environments alive after the panic, want destroyed", n)
+			}
+			if n := p.Idle(); n != 0 {
+				t.Fatalf("%d idle environments after the panic, want 0", n)
+			}
+
+			// the pool builds a new environment for the next run and keeps it
+			rt = run(t, w, &worker.Request{Cmd: []worker.Cmd{shCmd("echo ok")}})
+			if r := rt.Results[0]; r.Status != envexec.StatusAccepted || readFile(t, r, "stdout") != "ok\n" {
+				t.Fatalf("run after the panic: %v: %s", r.Status, r.Error)
+			}
+			if n, idle := atomic.LoadInt32(&b.alive), p.Idle(); n != 1 || idle != 1 {
+				t.Fatalf("%d environments alive and %d idle, want 1 reused", n, idle)
+			}
+			// drained for the next case
+			p.Rebuild()
+		})
+	}
+}
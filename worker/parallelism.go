@@ -0,0 +1,160 @@
+package worker
+
+import (
+	"time"
+)
+
+// AutoParallelism adjusts the number of worker loops within [Min, Max] by the
+// time the requests waited for a loop and the cpu utilization of the host,
+// re-evaluated every Interval. It is disabled if Max is 0.
+type AutoParallelism struct {
+	Min      int
+	Max      int
+	Interval time.Duration // 10s if 0
+}
+
+const defaultAutoParallelismInterval = 10 * time.Second
+
+// The thresholds of AutoParallelism, the gap between the cpu utilizations to
+// grow and to shrink and the agreeing evaluations required keep it from
+// flapping
+const (
+	autoGrowQueueIndex = 0.2  // grows if the requests waited this share of their latency for a loop
+	autoGrowCPU        = 0.8  // and the host cpu utilization is below
+	autoShrinkCPU      = 0.95 // shrinks if the host cpu utilization is above
+	autoStreak         = 2    // consecutive evaluations voting for the change
+)
+
+// autoTuner decides the number of worker loops of AutoParallelism
+type autoTuner struct {
+	conf   AutoParallelism
+	streak int // consecutive votes, positive to grow and negative to shrink
+}
+
+// next returns the number of worker loops following cur by the contention of
+// the last interval and the cpu utilization of the host, which is ignored if
+// it is unknown (!cpuOK). The loops are added or removed one at a time.
+func (a *autoTuner) next(cur int, c Contention, cpu float64, cpuOK bool) int {
+	vote := 0
+	switch {
+	case cpuOK && cpu >= autoShrinkCPU:
+		vote = -1
+	case c.QueueIndex() >= autoGrowQueueIndex && (!cpuOK || cpu < autoGrowCPU):
+		vote = 1
+	}
+	if vote == 0 || (vote > 0) != (a.streak > 0) {
+		a.streak = vote
+	} else {
+		a.streak += vote
+	}
+
+	switch {
+	case a.streak >= autoStreak && cur < a.conf.Max:
+		a.streak = 0
+		return cur + 1
+	case a.streak <= -autoStreak && cur > a.conf.Min:
+		a.streak = 0
+		return cur - 1
+	}
+	return cur
+}
+
+// autoTune re-evaluates the number of worker loops every interval until the
+// worker is shut down
+func (w *worker) autoTune() {
+	interval := w.autoParallelism.Interval
+	if interval <= 0 {
+		interval = defaultAutoParallelismInterval
+	}
+	tuner := &autoTuner{conf: w.autoParallelism}
+	w.sampleCPU()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case <-ticker.C:
+		}
+		c := w.contention(interval)
+		u, ok := w.sampleCPU()
+		cur := w.wantParallelism()
+		if n := tuner.next(cur, c, u, ok); n != cur {
+			w.setParallelism(n)
+			if w.parallelismObserver != nil {
+				w.parallelismObserver(cur, n, c, u)
+			}
+		}
+	}
+}
+
+// setParallelism changes the number of worker loops, the loops beyond it exit
+// after the request they are running, or the next one they take if idle
+func (w *worker) setParallelism(n int) {
+	w.loopMu.Lock()
+	defer w.loopMu.Unlock()
+
+	w.loopWant = n
+	w.applyParallelism()
+}
+
+// SetParallelismCap caps the number of worker loops to n until it is reset
+// by 0, the loops beyond it exit like the ones removed by AutoParallelism
+func (w *worker) SetParallelismCap(n int) {
+	w.loopMu.Lock()
+	defer w.loopMu.Unlock()
+
+	w.loopCap = n
+	w.applyParallelism()
+}
+
+// applyParallelism starts the worker loops up to the parallelism within the
+// cap, loopMu must be held
+func (w *worker) applyParallelism() {
+	select {
+	case <-w.done:
+		return
+	default:
+	}
+	n := w.loopWant
+	if w.loopCap > 0 && w.loopCap < n {
+		n = w.loopCap
+	}
+	w.loopTarget = n
+	for i := 0; i < n; i++ {
+		if w.loopAlive[i] != nil {
+			continue
+		}
+		h := newHeartbeat()
+		w.loopAlive[i] = h
+		w.wg.Add(1)
+		go w.loop(i, h)
+	}
+}
+
+// retireLoop reports whether the loop is beyond the parallelism and removes it
+func (w *worker) retireLoop(index int) bool {
+	w.loopMu.Lock()
+	defer w.loopMu.Unlock()
+
+	if index < w.loopTarget {
+		return false
+	}
+	delete(w.loopAlive, index)
+	return true
+}
+
+// currentParallelism returns the number of worker loops
+func (w *worker) currentParallelism() int {
+	w.loopMu.Lock()
+	defer w.loopMu.Unlock()
+	return w.loopTarget
+}
+
+// wantParallelism returns the number of worker loops regardless of the cap
+func (w *worker) wantParallelism() int {
+	w.loopMu.Lock()
+	defer w.loopMu.Unlock()
+	return w.loopWant
+}
@@ -0,0 +1,77 @@
+package worker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAutoTunerNext(t *testing.T) {
+	queued := Contention{QueueWait: time.Second, RunTime: time.Second}
+	idle := Contention{RunTime: time.Second}
+
+	type step struct {
+		c     Contention
+		cpu   float64
+		cpuOK bool
+	}
+	grow := step{queued, 0.5, true}
+	saturated := step{queued, 0.99, true}
+	for _, c := range []struct {
+		name  string
+		start int
+		steps []step
+		want  []int
+	}{
+		{"grows by one after two votes", 1, []step{grow, grow, grow, grow}, []int{1, 2, 2, 3}},
+		{"grows up to max", 3, []step{grow, grow, grow, grow}, []int{3, 4, 4, 4}},
+		{"grows without cpu", 1, []step{{queued, 0, false}, {queued, 0, false}}, []int{1, 2}},
+		{"not contended", 2, []step{{idle, 0.1, true}, {idle, 0.1, true}, {idle, 0.1, true}}, []int{2, 2, 2}},
+		{"low queue wait", 2, []step{
+			{Contention{QueueWait: time.Second, RunTime: 9 * time.Second}, 0.1, true},
+			{Contention{QueueWait: time.Second, RunTime: 9 * time.Second}, 0.1, true},
+		}, []int{2, 2}},
+		{"shrinks despite the queue", 3, []step{saturated, saturated, saturated, saturated}, []int{3, 2, 2, 1}},
+		{"shrinks down to min", 2, []step{saturated, saturated, saturated, saturated}, []int{2, 1, 1, 1}},
+		// between the cpu thresholds nothing changes
+		{"hysteresis", 2, []step{{queued, 0.9, true}, {queued, 0.9, true}, {queued, 0.9, true}}, []int{2, 2, 2}},
+		// the votes must agree in a row
+		{"flapping votes", 2, []step{grow, saturated, grow, saturated, {idle, 0.5, true}, grow}, []int{2, 2, 2, 2, 2, 2}},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			a := &autoTuner{conf: AutoParallelism{Min: 1, Max: 4}}
+			cur := c.start
+			got := make([]int, 0, len(c.steps))
+			for _, s := range c.steps {
+				cur = a.next(cur, s.c, s.cpu, s.cpuOK)
+				got = append(got, cur)
+			}
+			if !reflect.DeepEqual(got, c.want) {
+				t.Fatalf("got %v, want %v", got, c.want)
+			}
+		})
+	}
+}
+
+func TestContentionTracker(t *testing.T) {
+	var tr contentionTracker
+	now := time.Unix(1000, 0)
+	tr.observe(now.Add(-30*time.Second), time.Second, 0, time.Second)
+	tr.observe(now.Add(-2*time.Second), 2*time.Second, time.Second, 3*time.Second)
+	tr.observe(now, time.Second, 0, time.Second)
+
+	c := tr.sum(now, 5*time.Second)
+	if c.Runs != 2 || c.QueueWait != 3*time.Second || c.EnvWait != time.Second || c.RunTime != 4*time.Second {
+		t.Fatalf("sum of 5s: %+v", c)
+	}
+	if q := c.QueueIndex(); q != 3.0/7 {
+		t.Fatalf("queue index %v, want 3/7", q)
+	}
+	if c := tr.sum(now, time.Hour); c.Runs != 3 || c.QueueWaitTotal != 4*time.Second {
+		t.Fatalf("sum of the window: %+v", c)
+	}
+	// the buckets out of the window are dropped
+	if c := tr.sum(now.Add(contentionWindow+time.Second), time.Hour); c.Runs != 0 || c.QueueWaitTotal != 4*time.Second {
+		t.Fatalf("sum after the window: %+v", c)
+	}
+}
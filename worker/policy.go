@@ -0,0 +1,178 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/criyle/go-judge/filestore"
+)
+
+// ErrPolicyDenied is returned (wrapped by PolicyError) when the request uses
+// a capability denied by the policy of the client
+var ErrPolicyDenied = errors.New("denied by policy")
+
+// Features of the commands gated by Policy.Features, named as the fields of
+// the requests
+const (
+	FeatureCredential      = "credential"      // pinned uid / gid
+	FeatureTTY             = "tty"             // pseudo terminal
+	FeatureDatasets        = "datasets"        // read-only datasets
+	FeatureMountFiles      = "mountFiles"      // files mounted from the file store
+	FeatureHostExec        = "hostExec"        // host files referenced in args
+	FeatureDiskWorkDir     = "diskWorkDir"     // work directory on the host disk
+	FeatureScratchFile     = "scratchFile"     // file on the host disk
+	FeatureCopyOutDir      = "copyOutDir"      // files copied out into a host directory
+	FeatureCollectCore     = "collectCore"     // core file collected into the file store
+	FeatureTrace           = "trace"           // syscall counting
+	FeatureProcInfo        = "procInfo"        // synthetic /proc files
+	FeatureSnapshotWorkDir = "snapshotWorkDir" // work dir snapshots
+	FeatureRepeat          = "repeat"          // repeated runs of a request
+	FeatureHighPriority    = "highPriority"    // requests of priority high
+	FeatureUnlimited       = "unlimited"       // unlimited cpu, clock, memory, stack or proc limit
+)
+
+// PolicyFeatures are all the features gated by Policy.Features
+var PolicyFeatures = []string{
+	FeatureCredential, FeatureTTY, FeatureDatasets, FeatureMountFiles, FeatureHostExec,
+	FeatureDiskWorkDir, FeatureScratchFile, FeatureCopyOutDir, FeatureCollectCore, FeatureTrace,
+	FeatureProcInfo, FeatureSnapshotWorkDir, FeatureRepeat, FeatureHighPriority, FeatureUnlimited,
+}
+
+// Policy restricts the requests of a client on top of the config of the
+// worker, so that the most restrictive of both applies
+type Policy struct {
+	// Features are the features the client may use, the others are denied
+	Features map[string]bool
+	// Profiles are the mount profiles the client may use, none if empty
+	Profiles map[string]bool
+	// LimitMax caps the limits of the commands as Config.LimitMax does, the
+	// limits above it are clamped if Config.LimitClamp
+	LimitMax LimitMax
+}
+
+// PolicyError is returned when the request uses a capability denied by the
+// policy of the client
+type PolicyError struct {
+	Capability string // the feature, profile or limit denied
+	Reason     string
+}
+
+func (e *PolicyError) Error() string {
+	if e.Reason == "" {
+		return fmt.Sprintf("%s: %s", ErrPolicyDenied, e.Capability)
+	}
+	return fmt.Sprintf("%s: %s: %s", ErrPolicyDenied, e.Capability, e.Reason)
+}
+
+func (e *PolicyError) Is(target error) bool {
+	return target == ErrPolicyDenied
+}
+
+// SetPolicies replaces the policies of the clients (i.e. file store
+// namespace), the requests being executed are not affected
+func (w *worker) SetPolicies(p map[string]Policy) {
+	w.policies.Store(&p)
+}
+
+// policy returns the policy of the submitter of the request, nil for the
+// clients without policy
+func (w *worker) policy(ctx context.Context) *Policy {
+	owner, ok := filestore.NamespaceFromContext(ctx)
+	if !ok {
+		return nil
+	}
+	policies := w.policies.Load()
+	if policies == nil {
+		return nil
+	}
+	p, ok := (*policies)[owner]
+	if !ok {
+		return nil
+	}
+	return &p
+}
+
+// applyPolicy checks the request against the policy of the submitter and
+// applies its limits to the commands in place
+func (w *worker) applyPolicy(ctx context.Context, req *Request) error {
+	p := w.policy(ctx)
+	if p == nil {
+		return nil
+	}
+	if req.Repeat != 0 && !p.Features[FeatureRepeat] {
+		return &PolicyError{Capability: FeatureRepeat}
+	}
+	if req.Priority == PriorityHigh && !p.Features[FeatureHighPriority] {
+		return &PolicyError{Capability: FeatureHighPriority}
+	}
+	for i := range req.Cmd {
+		if err := w.applyCmdPolicy(p, &req.Cmd[i]); err != nil {
+			return fmt.Errorf("cmd[%d]: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (w *worker) applyCmdPolicy(p *Policy, c *Cmd) error {
+	for _, f := range w.cmdFeatures(c) {
+		if !p.Features[f] {
+			return &PolicyError{Capability: f}
+		}
+	}
+	if c.Profile != "" && !p.Profiles[c.Profile] {
+		return &PolicyError{Capability: "profile", Reason: c.Profile}
+	}
+	if err := p.LimitMax.Apply(c, w.limitClamp); err != nil {
+		var le *LimitError
+		if errors.As(err, &le) {
+			return &PolicyError{
+				Capability: le.Limit,
+				Reason:     fmt.Sprintf("%s exceeds the max %s of the policy", le.Value, le.Max),
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+// cmdFeatures returns the features used by the command
+func (w *worker) cmdFeatures(c *Cmd) []string {
+	var rt []string
+	for _, f := range []struct {
+		name string
+		used bool
+	}{
+		{FeatureCredential, c.Credential != nil},
+		{FeatureTTY, c.TTY},
+		{FeatureDatasets, len(c.Datasets) > 0},
+		{FeatureMountFiles, len(c.MountFiles) > 0},
+		{FeatureHostExec, w.usesHostExec(c)},
+		{FeatureDiskWorkDir, c.DiskWorkDir},
+		{FeatureScratchFile, c.ScratchFile != ""},
+		{FeatureCopyOutDir, c.CopyOutDir != ""},
+		{FeatureCollectCore, c.CollectCore},
+		{FeatureTrace, c.Trace},
+		{FeatureProcInfo, c.ProcInfo},
+		{FeatureSnapshotWorkDir, c.SnapshotWorkDir != ""},
+		{FeatureUnlimited, c.unlimited()},
+	} {
+		if f.used {
+			rt = append(rt, f.name)
+		}
+	}
+	return rt
+}
+
+// usesHostExec reports whether the args reference host files
+func (w *worker) usesHostExec(c *Cmd) bool {
+	if w.hostExec == nil {
+		return false
+	}
+	for _, a := range c.Args {
+		if w.hostExec.references(a) {
+			return true
+		}
+	}
+	return false
+}
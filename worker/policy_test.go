@@ -0,0 +1,197 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/filestore"
+)
+
+// withFeatures returns the features of PolicyFeatures but the denied one
+func withFeatures(denied string) map[string]bool {
+	rt := make(map[string]bool)
+	for _, f := range PolicyFeatures {
+		if f != denied {
+			rt[f] = true
+		}
+	}
+	return rt
+}
+
+func TestPolicyCapabilityGates(t *testing.T) {
+	dir := t.TempDir()
+	hostExec, err := newHostExec([]string{dir})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := &worker{hostExec: hostExec}
+	ctx := filestore.NewNamespaceContext(context.Background(), "client")
+
+	cases := []struct {
+		capability string
+		req        Request
+	}{
+		{FeatureCredential, Request{Cmd: []Cmd{{Credential: NewCredential(1000, 1000)}}}},
+		{FeatureTTY, Request{Cmd: []Cmd{{TTY: true}}}},
+		{FeatureDatasets, Request{Cmd: []Cmd{{Datasets: []string{"a"}}}}},
+		{FeatureMountFiles, Request{Cmd: []Cmd{{MountFiles: map[string]string{"a": "id"}}}}},
+		{FeatureHostExec, Request{Cmd: []Cmd{{Args: []string{filepath.Join(dir, "a")}}}}},
+		{FeatureDiskWorkDir, Request{Cmd: []Cmd{{DiskWorkDir: true}}}},
+		{FeatureScratchFile, Request{Cmd: []Cmd{{ScratchFile: "/scratch"}}}},
+		{FeatureCopyOutDir, Request{Cmd: []Cmd{{CopyOutDir: "/out"}}}},
+		{FeatureCollectCore, Request{Cmd: []Cmd{{CollectCore: true}}}},
+		{FeatureTrace, Request{Cmd: []Cmd{{Trace: true}}}},
+		{FeatureProcInfo, Request{Cmd: []Cmd{{ProcInfo: true}}}},
+		{FeatureSnapshotWorkDir, Request{Cmd: []Cmd{{SnapshotWorkDir: "save"}}}},
+		{FeatureRepeat, Request{Cmd: []Cmd{{}}, Repeat: 2}},
+		{FeatureHighPriority, Request{Cmd: []Cmd{{}}, Priority: PriorityHigh}},
+		{FeatureUnlimited, Request{Cmd: []Cmd{{MemoryLimit: UnlimitedSize}}}},
+		{"profile", Request{Cmd: []Cmd{{Profile: "gpu"}}}},
+	}
+	if len(cases) != len(PolicyFeatures)+1 {
+		t.Fatalf("%d cases for %d features and the profile", len(cases), len(PolicyFeatures))
+	}
+	for _, c := range cases {
+		t.Run(c.capability, func(t *testing.T) {
+			// the capability is allowed by the policy
+			w.SetPolicies(map[string]Policy{"client": {
+				Features: withFeatures(""),
+				Profiles: map[string]bool{"gpu": true},
+			}})
+			req := c.req
+			req.Cmd = append([]Cmd(nil), c.req.Cmd...)
+			if err := w.applyPolicy(ctx, &req); err != nil {
+				t.Fatalf("allowed: %v", err)
+			}
+
+			// the capability is denied by the policy
+			w.SetPolicies(map[string]Policy{"client": {
+				Features: withFeatures(c.capability),
+			}})
+			req.Cmd = append([]Cmd(nil), c.req.Cmd...)
+			err := w.applyPolicy(ctx, &req)
+			var pe *PolicyError
+			if !errors.Is(err, ErrPolicyDenied) || !errors.As(err, &pe) {
+				t.Fatalf("denied: got %v, want %v", err, ErrPolicyDenied)
+			}
+			if pe.Capability != c.capability {
+				t.Fatalf("denied %q, want %q", pe.Capability, c.capability)
+			}
+
+			// the clients without policy are not restricted
+			req.Cmd = append([]Cmd(nil), c.req.Cmd...)
+			if err := w.applyPolicy(context.Background(), &req); err != nil {
+				t.Fatalf("no policy: %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicyLimitMax(t *testing.T) {
+	ctx := filestore.NewNamespaceContext(context.Background(), "client")
+	serverMax := LimitMax{CPU: 2 * time.Second, Memory: 256 << 20, Proc: 32}
+
+	for _, c := range []struct {
+		name       string
+		policyMax  LimitMax
+		clamp      bool
+		cmd        Cmd
+		capability string // denied by the policy
+		limit      string // rejected by the server max
+		want       Cmd    // limits applied
+	}{
+		{
+			name:      "within both",
+			policyMax: LimitMax{CPU: time.Second},
+			cmd:       Cmd{CPULimit: time.Second, MemoryLimit: 64 << 20, ProcLimit: 8},
+			want:      Cmd{CPULimit: time.Second, MemoryLimit: 64 << 20, ProcLimit: 8},
+		},
+		{
+			name:       "policy cpu lower",
+			policyMax:  LimitMax{CPU: time.Second},
+			cmd:        Cmd{CPULimit: 1500 * time.Millisecond, MemoryLimit: 64 << 20, ProcLimit: 8},
+			capability: "cpuLimit",
+		},
+		{
+			name:      "server cpu lower",
+			policyMax: LimitMax{CPU: 3 * time.Second},
+			cmd:       Cmd{CPULimit: 2500 * time.Millisecond, MemoryLimit: 64 << 20, ProcLimit: 8},
+			limit:     "cpuLimit",
+		},
+		{
+			name:       "policy memory lower",
+			policyMax:  LimitMax{Memory: 128 << 20},
+			cmd:        Cmd{CPULimit: time.Second, MemoryLimit: 192 << 20, ProcLimit: 8},
+			capability: "memoryLimit",
+		},
+		{
+			name:       "policy proc lower",
+			policyMax:  LimitMax{Proc: 4},
+			cmd:        Cmd{CPULimit: time.Second, MemoryLimit: 64 << 20, ProcLimit: 8},
+			capability: "procLimit",
+		},
+		{
+			name:      "clamped to policy",
+			policyMax: LimitMax{CPU: time.Second, Memory: 128 << 20, Proc: 64},
+			clamp:     true,
+			cmd:       Cmd{CPULimit: 5 * time.Second, MemoryLimit: 512 << 20, ProcLimit: 100},
+			want:      Cmd{CPULimit: time.Second, MemoryLimit: 128 << 20, ProcLimit: 32},
+		},
+		{
+			name:      "unset clamped to policy",
+			policyMax: LimitMax{Memory: 128 << 20, Proc: 4},
+			cmd:       Cmd{CPULimit: time.Second},
+			want:      Cmd{CPULimit: time.Second, MemoryLimit: 128 << 20, ProcLimit: 4},
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			w := &worker{limitMax: serverMax, limitClamp: c.clamp}
+			w.SetPolicies(map[string]Policy{"client": {LimitMax: c.policyMax}})
+			req := &Request{Cmd: []Cmd{c.cmd}}
+			err := w.applyLimitMax(ctx, req)
+
+			var pe *PolicyError
+			var le *LimitError
+			switch {
+			case c.capability != "":
+				if !errors.As(err, &pe) || pe.Capability != c.capability {
+					t.Fatalf("got %v, want %s denied by policy", err, c.capability)
+				}
+			case c.limit != "":
+				if errors.As(err, &pe) || !errors.As(err, &le) || le.Limit != c.limit {
+					t.Fatalf("got %v, want %s rejected by the server max", err, c.limit)
+				}
+			case err != nil:
+				t.Fatal(err)
+			default:
+				got := req.Cmd[0]
+				if got.CPULimit != c.want.CPULimit || got.MemoryLimit != c.want.MemoryLimit || got.ProcLimit != c.want.ProcLimit {
+					t.Fatalf("limits cpu %v memory %v proc %d, want cpu %v memory %v proc %d",
+						got.CPULimit, got.MemoryLimit, got.ProcLimit, c.want.CPULimit, c.want.MemoryLimit, c.want.ProcLimit)
+				}
+			}
+		})
+	}
+}
+
+func TestSetPoliciesReload(t *testing.T) {
+	ctx := filestore.NewNamespaceContext(context.Background(), "client")
+	w := &worker{}
+	req := &Request{Cmd: []Cmd{{TTY: true}}}
+
+	w.SetPolicies(map[string]Policy{"client": {Features: map[string]bool{FeatureTTY: true}}})
+	if err := w.applyPolicy(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	w.SetPolicies(map[string]Policy{"client": {}})
+	if err := w.applyPolicy(ctx, req); !errors.Is(err, ErrPolicyDenied) {
+		t.Fatalf("got %v after reload, want %v", err, ErrPolicyDenied)
+	}
+	w.SetPolicies(nil)
+	if err := w.applyPolicy(ctx, req); err != nil {
+		t.Fatalf("got %v after the policies removed", err)
+	}
+}
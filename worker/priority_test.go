@@ -0,0 +1,73 @@
+package worker
+
+import (
+	"testing"
+	"time"
+)
+
+// queued returns the request of the priority submitted at the time
+func queued(p Priority, submitted time.Time) workRequest {
+	return workRequest{Request: &Request{Priority: p}, job: &job{submitted: submitted}}
+}
+
+func TestQueueHighPriorityFirst(t *testing.T) {
+	for _, scheduler := range []Scheduler{SchedulerFIFO, SchedulerFair} {
+		q := newQueue(scheduler, nil, time.Second, 0)
+		now := time.Now()
+		var reqs []workRequest
+		for _, p := range []Priority{PriorityLow, PriorityLow, PriorityNormal, PriorityHigh} {
+			req := queued(p, now)
+			reqs = append(reqs, req)
+			if err := q.push("", req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		// high, normal and then low in the order of submission
+		for _, want := range []int{3, 2, 0, 1} {
+			req, ok := q.take(now)
+			if !ok || req.job != reqs[want].job {
+				t.Fatalf("scheduler %d: took %v, want request %d", scheduler, req.Priority, want)
+			}
+		}
+	}
+}
+
+func TestQueueLowPriorityNotStarved(t *testing.T) {
+	const aging = time.Second
+	q := newQueue(SchedulerFIFO, nil, aging, 0)
+	now := time.Now()
+	low := queued(PriorityLow, now)
+	q.push("", low)
+
+	// a high priority request arrives whenever a worker frees up, the low
+	// one is raised to high after waiting twice of aging and is taken first
+	// as the earliest
+	for step := 0; ; step++ {
+		now = now.Add(aging / 4)
+		q.push("", queued(PriorityHigh, now))
+		req, _ := q.take(now)
+		if req.job == low.job {
+			if waited := now.Sub(low.job.submitted); waited < 2*aging {
+				t.Fatalf("low priority taken after %v, want at least %v", waited, 2*aging)
+			}
+			return
+		}
+		if step > 100 {
+			t.Fatal("low priority request is starved")
+		}
+	}
+}
+
+func TestQueueWithoutAgingStarves(t *testing.T) {
+	q := newQueue(SchedulerFIFO, nil, 0, 0)
+	now := time.Now()
+	low := queued(PriorityLow, now)
+	q.push("", low)
+	for step := 0; step < 100; step++ {
+		now = now.Add(time.Minute)
+		q.push("", queued(PriorityNormal, now))
+		if req, _ := q.take(now); req.job == low.job {
+			t.Fatal("low priority taken before the normal ones without aging")
+		}
+	}
+}
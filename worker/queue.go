@@ -0,0 +1,351 @@
+package worker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// Scheduler defines the order in which the waiting requests are taken by the
+// worker loops
+type Scheduler int
+
+// Scheduler
+const (
+	SchedulerFIFO Scheduler = iota // requests are taken in the order of submission (default)
+	SchedulerFair                  // requests of each client are taken in turn weighted by its share
+	SchedulerSJF                   // requests with the shortest estimated duration are taken first
+)
+
+// ErrQueueFull is returned when the waiting requests of the client exceeded
+// its queue limit
+var ErrQueueFull = errors.New("worker queue is full")
+
+// queue holds the submitted requests until they are taken by the worker loops.
+// Requests are grouped by the client (the owner of the job). With the fifo
+// scheduler the limit applies to all the clients, with the fair scheduler it
+// applies to each client and the clients with waiting requests are picked by
+// smooth weighted round robin. Within the fifo queue or the queue of the
+// client, the request with the highest priority is taken first and the
+// earliest among ties. With the sjf scheduler, which is the fifo scheduler
+// otherwise, the ties are taken by the shortest estimated duration and the
+// requests without estimation after the estimated ones. The priority is raised
+// by one for every aging waited so that low priority requests and long
+// requests under sjf are not starved. With the total memory, the
+// request to be taken waits until its memory reservation fits into the total
+// with the reservations of the running requests, the requests after it are
+// not taken before it so that large requests are not starved.
+type queue struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	fair   bool
+	sjf    bool
+	shares map[string]int
+	aging  time.Duration
+
+	totalMemory uint64 // 0 does not hold the requests
+	reserved    uint64 // memory reservations of the taken requests
+
+	fifo    []workRequest
+	clients map[string]*clientQueue
+	active  []*clientQueue // clients with waiting requests in the order of arrival
+	count   int
+	closed  bool
+}
+
+type clientQueue struct {
+	share   int
+	current int // credit of smooth weighted round robin
+	waiting []workRequest
+}
+
+func newQueue(scheduler Scheduler, shares map[string]int, aging time.Duration, totalMemory uint64) *queue {
+	q := &queue{
+		fair:        scheduler == SchedulerFair,
+		sjf:         scheduler == SchedulerSJF,
+		shares:      shares,
+		aging:       aging,
+		totalMemory: totalMemory,
+		clients:     make(map[string]*clientQueue),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// push adds the request of the client, fails if the queue is full or closed.
+func (q *queue) push(client string, req workRequest) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	c := q.client(client)
+	switch {
+	case q.closed:
+		return ErrShutdown
+	case q.fair && len(c.waiting) >= maxWaiting, !q.fair && q.count >= maxWaiting:
+		return ErrQueueFull
+	case q.fair && len(c.waiting) == 0:
+		q.active = append(q.active, c)
+	case !q.fair:
+		q.fifo = append(q.fifo, req)
+	}
+	// waiting is also kept in fifo mode for the depth of the client
+	c.waiting = append(c.waiting, req)
+	q.count++
+	q.cond.Signal()
+	return nil
+}
+
+// pop blocks until a request is available and its memory reservation fits,
+// returns false after closed. The reservation is held until release.
+func (q *queue) pop() (workRequest, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for {
+		if q.closed {
+			return workRequest{}, false
+		}
+		if q.count > 0 {
+			if req, ok := q.take(time.Now()); ok {
+				return req, true
+			}
+		}
+		q.cond.Wait()
+	}
+}
+
+// take removes the next request if its memory reservation fits
+func (q *queue) take(now time.Time) (workRequest, bool) {
+	if !q.fair {
+		i := q.best(q.fifo, now)
+		req := q.fifo[i]
+		if !q.fits(req.reservation) {
+			return workRequest{}, false
+		}
+		q.fifo = removeAt(q.fifo, i)
+		c := q.clients[req.job.owner]
+		c.waiting = removeRequest(c.waiting, req.job)
+		q.count--
+		q.reserved += req.reservation
+		return req, true
+	}
+
+	// smooth weighted round robin: every active client earns its share and
+	// the one with the most credit pays the total, the credits are earned
+	// only when the request is taken
+	var (
+		best  int
+		total int
+	)
+	for i, c := range q.active {
+		total += c.share
+		if c.current+c.share > q.active[best].current+q.active[best].share {
+			best = i
+		}
+	}
+	c := q.active[best]
+	i := q.best(c.waiting, now)
+	req := c.waiting[i]
+	if !q.fits(req.reservation) {
+		return workRequest{}, false
+	}
+	for _, a := range q.active {
+		a.current += a.share
+	}
+	c.current -= total
+	c.waiting = removeAt(c.waiting, i)
+	if len(c.waiting) == 0 {
+		c.current = 0
+		q.active = append(q.active[:best], q.active[best+1:]...)
+	}
+	q.count--
+	q.reserved += req.reservation
+	return req, true
+}
+
+// best returns the index of the request with the highest priority level in
+// reqs, the shortest under sjf and the earliest among ties
+func (q *queue) best(reqs []workRequest, now time.Time) int {
+	best, bestLevel := 0, q.level(reqs[0], now)
+	for i := 1; i < len(reqs); i++ {
+		l := q.level(reqs[i], now)
+		if l > bestLevel || l == bestLevel && q.sjf && shorter(reqs[i], reqs[best]) {
+			best, bestLevel = i, l
+		}
+	}
+	return best
+}
+
+// shorter reports whether a is estimated to finish before b, the requests
+// without estimation are the longest
+func shorter(a, b workRequest) bool {
+	ea, eb := a.EstimatedDuration, b.EstimatedDuration
+	switch {
+	case ea <= 0:
+		return false
+	case eb <= 0:
+		return true
+	}
+	return ea < eb
+}
+
+// fits reports whether the memory reservation fits with the taken requests
+func (q *queue) fits(n uint64) bool {
+	return q.totalMemory == 0 || q.reserved+n <= q.totalMemory
+}
+
+// reserve holds the memory reservation of the request not taken from the
+// queue (i.e. executed directly) so that it is counted for the others
+func (q *queue) reserve(n uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.reserved += n
+}
+
+// release returns the memory reservation of the finished request and wakes up
+// the pop waiting for it
+func (q *queue) release(n uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.reserved -= n
+	if q.totalMemory > 0 {
+		q.cond.Broadcast()
+	}
+}
+
+// reservedMemory returns the memory reservations of the taken requests
+func (q *queue) reservedMemory() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.reserved
+}
+
+// level is the priority of the request raised by one for every aging waited
+func (q *queue) level(req workRequest, now time.Time) int {
+	l := req.Priority.rank()
+	if q.aging > 0 {
+		l += int(now.Sub(req.job.submitted) / q.aging)
+	}
+	return l
+}
+
+// removeRequest removes the request of the job from reqs
+func removeRequest(reqs []workRequest, j *job) []workRequest {
+	for i, r := range reqs {
+		if r.job == j {
+			return removeAt(reqs, i)
+		}
+	}
+	return reqs
+}
+
+// removeAt removes the i-th request keeping the order, the head is removed by
+// reslicing so that pop from the head stays cheap
+func removeAt(reqs []workRequest, i int) []workRequest {
+	if i == 0 {
+		reqs[0] = workRequest{}
+		return reqs[1:]
+	}
+	copy(reqs[i:], reqs[i+1:])
+	reqs[len(reqs)-1] = workRequest{}
+	return reqs[:len(reqs)-1]
+}
+
+// remove removes the waiting request of the job, returns false if it is not
+// waiting (i.e. taken by pop)
+func (q *queue) remove(j *job) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	c, ok := q.clients[j.owner]
+	if !ok {
+		return false
+	}
+	n := len(c.waiting)
+	if c.waiting = removeRequest(c.waiting, j); len(c.waiting) == n {
+		return false
+	}
+	q.count--
+	// the request could be the one holding the others by its reservation
+	if q.totalMemory > 0 {
+		q.cond.Broadcast()
+	}
+	if !q.fair {
+		q.fifo = removeRequest(q.fifo, j)
+		return true
+	}
+	if len(c.waiting) == 0 {
+		c.current = 0
+		for i, a := range q.active {
+			if a == c {
+				q.active = append(q.active[:i], q.active[i+1:]...)
+				break
+			}
+		}
+	}
+	return true
+}
+
+// client returns the queue of the client, created on first use
+func (q *queue) client(name string) *clientQueue {
+	c, ok := q.clients[name]
+	if !ok {
+		share := q.shares[name]
+		if share <= 0 {
+			share = 1
+		}
+		c = &clientQueue{share: share}
+		q.clients[name] = c
+	}
+	return c
+}
+
+func (q *queue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.count
+}
+
+// depth returns the number of waiting requests of each client that has
+// submitted requests
+func (q *queue) depth() map[string]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	rt := make(map[string]int, len(q.clients))
+	for name, c := range q.clients {
+		rt[name] = len(c.waiting)
+	}
+	return rt
+}
+
+// priorityDepth returns the number of waiting requests of each priority
+func (q *queue) priorityDepth() map[Priority]int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	rt := map[Priority]int{PriorityLow: 0, PriorityNormal: 0, PriorityHigh: 0}
+	for _, c := range q.clients {
+		for _, r := range c.waiting {
+			rt[r.Priority]++
+		}
+	}
+	return rt
+}
+
+// close wakes up all the blocked pop and returns the waiting requests, which
+// are not taken
+func (q *queue) close() []workRequest {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+
+	var rt []workRequest
+	for _, c := range q.clients {
+		rt = append(rt, c.waiting...)
+		c.waiting = nil
+	}
+	q.fifo, q.active, q.count = nil, nil, 0
+	return rt
+}
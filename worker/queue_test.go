@@ -0,0 +1,202 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/worker"
+)
+
+func TestQueueInOrder(t *testing.T) {
+	w := newTestWorker(t, nil)
+	// the programs of the fake sandbox share the host file system
+	log := filepath.Join(t.TempDir(), "log")
+
+	blocker, started := w.Submit(context.Background(), &worker.Request{Cmd: []worker.Cmd{shCmd("sleep 0.2")}})
+	<-started
+	var chs []<-chan worker.Response
+	for i := 0; i < 3; i++ {
+		ch, _ := w.Submit(context.Background(), &worker.Request{Cmd: []worker.Cmd{shCmd(fmt.Sprintf("echo %d >> %s", i, log))}})
+		chs = append(chs, ch)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled, _ := w.Submit(ctx, &worker.Request{Cmd: []worker.Cmd{shCmd("echo cancelled >> " + log)}})
+
+	if c := w.Contention(); c.Busy != 1 || c.Waiting != 4 {
+		t.Fatalf("busy %d waiting %d, want 1 and 4", c.Busy, c.Waiting)
+	}
+	cancel()
+	select {
+	case rt := <-cancelled:
+		if !errors.Is(rt.Error, worker.ErrCancelled) {
+			t.Fatalf("cancelled request: %v, want %v", rt.Error, worker.ErrCancelled)
+		}
+	case <-time.After(100 * time.Millisecond):
+		t.Fatal("cancelled request is not removed from the queue")
+	}
+
+	waitAll(t, append([]<-chan worker.Response{blocker}, chs...)...)
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "0\n1\n2\n" {
+		t.Fatalf("run in order %q, want %q", b, "0\n1\n2\n")
+	}
+}
+
+func TestHighPriorityServedFirst(t *testing.T) {
+	w := newTestWorker(t, func(c *worker.Config) { c.PriorityAging = time.Minute })
+	log := filepath.Join(t.TempDir(), "log")
+
+	blocker, started := w.Submit(context.Background(), &worker.Request{Cmd: []worker.Cmd{shCmd("sleep 0.1")}})
+	<-started
+	chs := []<-chan worker.Response{blocker}
+	for _, p := range []struct {
+		name     string
+		priority worker.Priority
+	}{{"low0", worker.PriorityLow}, {"low1", worker.PriorityLow}, {"high", worker.PriorityHigh}} {
+		ch, _ := w.Submit(context.Background(), &worker.Request{
+			Priority: p.priority,
+			Cmd:      []worker.Cmd{shCmd("echo " + p.name + " >> " + log)},
+		})
+		chs = append(chs, ch)
+	}
+	waitAll(t, chs...)
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "high\nlow0\nlow1\n"; string(b) != want {
+		t.Fatalf("run in order %q, want %q", b, want)
+	}
+}
+
+// hinted is the request waiting behind the blocker
+type hinted struct {
+	name      string
+	estimated time.Duration
+	priority  worker.Priority
+}
+
+// runHinted occupies the only worker loop, then queues the requests in order
+// and returns the order they ran
+func runHinted(t *testing.T, conf func(*worker.Config), reqs []hinted) string {
+	t.Helper()
+	w := newTestWorker(t, conf)
+	log := filepath.Join(t.TempDir(), "log")
+
+	blocker, started := w.Submit(context.Background(), &worker.Request{Cmd: []worker.Cmd{shCmd("sleep 0.1")}})
+	<-started
+	chs := []<-chan worker.Response{blocker}
+	for _, r := range reqs {
+		ch, _ := w.Submit(context.Background(), &worker.Request{
+			EstimatedDuration: r.estimated,
+			Priority:          r.priority,
+			Cmd:               []worker.Cmd{shCmd("echo " + r.name + " >> " + log)},
+		})
+		chs = append(chs, ch)
+	}
+	waitAll(t, chs...)
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Join(strings.Fields(string(b)), " ")
+}
+
+func TestSJFOrder(t *testing.T) {
+	sjf := func(c *worker.Config) {
+		c.Scheduler = worker.SchedulerSJF
+		c.PriorityAging = time.Minute
+	}
+	reqs := []hinted{
+		{name: "3s", estimated: 3 * time.Second},
+		{name: "none0"},
+		{name: "300ms", estimated: 300 * time.Millisecond},
+		{name: "1s", estimated: time.Second},
+		{name: "none1"},
+	}
+	for _, c := range []struct {
+		name string
+		conf func(*worker.Config)
+		reqs []hinted
+		want string
+	}{
+		// the requests without hint follow in the order of submission
+		{"sjf", sjf, reqs, "300ms 1s 3s none0 none1"},
+		{"fifo", nil, reqs, "3s none0 300ms 1s none1"},
+		{"sjf without hints", sjf, []hinted{{name: "a"}, {name: "b"}, {name: "c"}}, "a b c"},
+		// the shortest is taken within the same priority only
+		{"sjf priority", sjf, []hinted{
+			{name: "low", estimated: time.Millisecond, priority: worker.PriorityLow},
+			{name: "high-long", estimated: 3 * time.Second, priority: worker.PriorityHigh},
+			{name: "high-short", estimated: time.Second, priority: worker.PriorityHigh},
+		}, "high-short high-long low"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if order := runHinted(t, c.conf, c.reqs); order != c.want {
+				t.Fatalf("run in order %q, want %q", order, c.want)
+			}
+		})
+	}
+}
+
+// TestSJFAging covers the long request waited for longer is taken before
+// the short one submitted later once it aged by more levels
+func TestSJFAging(t *testing.T) {
+	for _, c := range []struct {
+		aging time.Duration
+		want  string
+	}{
+		{time.Minute, "short long"},
+		{50 * time.Millisecond, "long short"},
+	} {
+		t.Run(c.aging.String(), func(t *testing.T) {
+			w := newTestWorker(t, func(conf *worker.Config) {
+				conf.Scheduler = worker.SchedulerSJF
+				conf.PriorityAging = c.aging
+			})
+			log := filepath.Join(t.TempDir(), "log")
+			blocker, started := w.Submit(context.Background(), &worker.Request{Cmd: []worker.Cmd{shCmd("sleep 0.4")}})
+			<-started
+			long, _ := w.Submit(context.Background(), &worker.Request{
+				EstimatedDuration: 3 * time.Second,
+				Cmd:               []worker.Cmd{shCmd("echo long >> " + log)},
+			})
+			// long has aged by about 8 levels and short by about 4 once the
+			// blocker finished
+			time.Sleep(200 * time.Millisecond)
+			short, _ := w.Submit(context.Background(), &worker.Request{
+				EstimatedDuration: time.Millisecond,
+				Cmd:               []worker.Cmd{shCmd("echo short >> " + log)},
+			})
+			waitAll(t, blocker, long, short)
+			b, err := os.ReadFile(log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if order := strings.Join(strings.Fields(string(b)), " "); order != c.want {
+				t.Fatalf("run in order %q, want %q", order, c.want)
+			}
+		})
+	}
+}
+
+// TestEstimatedDuration covers the hint is echoed with the duration run
+func TestEstimatedDuration(t *testing.T) {
+	w := newTestWorker(t, nil)
+	rt := run(t, w, &worker.Request{EstimatedDuration: 10 * time.Millisecond, Cmd: []worker.Cmd{shCmd("sleep 0.1")}})
+	if rt.EstimatedDuration != 10*time.Millisecond || rt.Duration < 100*time.Millisecond {
+		t.Fatalf("estimated %v duration %v, want 10ms and at least 100ms", rt.EstimatedDuration, rt.Duration)
+	}
+	if rt := run(t, w, &worker.Request{Cmd: []worker.Cmd{shCmd("true")}}); rt.EstimatedDuration != 0 {
+		t.Fatalf("estimated %v without the hint", rt.EstimatedDuration)
+	}
+}
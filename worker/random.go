@@ -0,0 +1,18 @@
+package worker
+
+import (
+	"errors"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// ErrInvalidRandom is returned when the deterministic random devices could not
+// be mounted for the command
+var ErrInvalidRandom = errors.New("invalid random seed")
+
+func cmdRandom(rc Cmd) *envexec.Random {
+	if rc.RandomSeed == nil {
+		return nil
+	}
+	return &envexec.Random{Seed: *rc.RandomSeed}
+}
@@ -0,0 +1,141 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// ErrInvalidRelativeLimit is returned when the relative limits of the commands
+// reference the commands not finished before them or are malformed
+var ErrInvalidRelativeLimit = errors.New("invalid relative limit")
+
+// RelativeLimit is the limit resolved from the usage of a previous command of
+// the sequential request once it finished: the usage times Multiplier within
+// [Min, Max], 0 for no bound. It is in nanoseconds or bytes as the limit.
+type RelativeLimit struct {
+	FromCmd    int
+	Multiplier float64
+	Min        uint64
+	Max        uint64
+}
+
+// RelativeLimits are the limits of the command resolved from the previous
+// commands, nil for the static limit
+type RelativeLimits struct {
+	CPU    *RelativeLimit // from the cpu time
+	Clock  *RelativeLimit // from the run time
+	Memory *RelativeLimit // from the peak memory
+}
+
+// ResolvedLimit records the relative limit and the value resolved
+type ResolvedLimit struct {
+	Limit string // cpuLimit, clockLimit or memoryLimit
+	RelativeLimit
+	Usage uint64 // usage of the command referenced
+	Value uint64 // limit applied after the server and policy max
+}
+
+func (l *RelativeLimits) set() bool {
+	return l.CPU != nil || l.Clock != nil || l.Memory != nil
+}
+
+// validRelativeLimits checks the relative limits reference the commands run
+// before them, so that the references never form a cycle
+func validRelativeLimits(req *Request) error {
+	for i, c := range req.Cmd {
+		if !c.RelativeLimits.set() {
+			continue
+		}
+		if req.RunMode != RunModeSequential || len(req.PipeMapping) > 0 || req.Repeat != 0 {
+			return fmt.Errorf("%w: relative limits require sequential run mode without pipes or repeat", ErrInvalidRelativeLimit)
+		}
+		for _, l := range []struct {
+			name string
+			r    *RelativeLimit
+		}{
+			{"cpuLimit", c.RelativeLimits.CPU},
+			{"clockLimit", c.RelativeLimits.Clock},
+			{"memoryLimit", c.RelativeLimits.Memory},
+		} {
+			if l.r == nil {
+				continue
+			}
+			switch {
+			case l.r.FromCmd < 0 || l.r.FromCmd >= i:
+				return fmt.Errorf("%w: cmd[%d].%s references cmd[%d] which is not a previous command", ErrInvalidRelativeLimit, i, l.name, l.r.FromCmd)
+			case !(l.r.Multiplier > 0) || math.IsInf(l.r.Multiplier, 0):
+				return fmt.Errorf("%w: cmd[%d].%s multiplier %v is not positive", ErrInvalidRelativeLimit, i, l.name, l.r.Multiplier)
+			case l.r.Max != 0 && l.r.Min > l.r.Max:
+				return fmt.Errorf("%w: cmd[%d].%s min is greater than max", ErrInvalidRelativeLimit, i, l.name)
+			}
+		}
+	}
+	return nil
+}
+
+// resolveRelativeLimits sets the limits of the command from the results of
+// the previous commands. It returns the Skipped result if a command
+// referenced did not finish as accepted.
+func (w *worker) resolveRelativeLimits(ctx context.Context, c *Cmd, results []Result) ([]ResolvedLimit, *Result) {
+	var rt []ResolvedLimit
+	for _, l := range []struct {
+		name  string
+		r     *RelativeLimit
+		usage func(*Result) uint64
+		set   func(uint64)
+	}{
+		{"cpuLimit", c.RelativeLimits.CPU,
+			func(r *Result) uint64 { return uint64(r.Time) },
+			func(v uint64) { c.CPULimit = time.Duration(v) }},
+		{"clockLimit", c.RelativeLimits.Clock,
+			func(r *Result) uint64 { return uint64(r.RunTime) },
+			func(v uint64) { c.ClockLimit = time.Duration(v) }},
+		{"memoryLimit", c.RelativeLimits.Memory,
+			func(r *Result) uint64 { return uint64(r.Memory) },
+			func(v uint64) { c.MemoryLimit = envexec.Size(v) }},
+	} {
+		if l.r == nil {
+			continue
+		}
+		from := &results[l.r.FromCmd]
+		if from.Status != envexec.StatusAccepted {
+			return nil, &Result{
+				Status: envexec.StatusSkipped,
+				Error:  fmt.Sprintf("%s references cmd[%d] which finished as %s", l.name, l.r.FromCmd, from.Status),
+			}
+		}
+		usage := l.usage(from)
+		v := uint64(math.Min(float64(usage)*l.r.Multiplier, math.MaxInt64))
+		if v < l.r.Min {
+			v = l.r.Min
+		}
+		if l.r.Max != 0 && v > l.r.Max {
+			v = l.r.Max
+		}
+		l.set(v)
+		rt = append(rt, ResolvedLimit{Limit: l.name, RelativeLimit: *l.r, Usage: usage})
+	}
+	// the resolved limits are capped by the server and the policy, which never
+	// fails with clamp
+	w.limitMax.Apply(c, true)
+	if p := w.policy(ctx); p != nil {
+		p.LimitMax.Apply(c, true)
+	}
+	c.capStack()
+	for i := range rt {
+		switch rt[i].Limit {
+		case "cpuLimit":
+			rt[i].Value = uint64(c.CPULimit)
+		case "clockLimit":
+			rt[i].Value = uint64(c.ClockLimit)
+		case "memoryLimit":
+			rt[i].Value = uint64(c.MemoryLimit)
+		}
+	}
+	return rt, nil
+}
@@ -0,0 +1,26 @@
+package worker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// TestRelativeLimitSkipped checks the command referencing a command not
+// accepted is skipped with the stable code of Skipped
+func TestRelativeLimitSkipped(t *testing.T) {
+	for _, from := range []envexec.Status{
+		envexec.StatusNonzeroExitStatus, envexec.StatusTimeLimitExceeded,
+		envexec.StatusProcessLimitExceeded, envexec.StatusSkipped,
+	} {
+		c := &Cmd{RelativeLimits: RelativeLimits{CPU: &RelativeLimit{FromCmd: 0, Multiplier: 2}}}
+		_, r := (&worker{}).resolveRelativeLimits(context.Background(), c, []Result{{Status: from}})
+		if r == nil {
+			t.Fatalf("%v: not skipped", from)
+		}
+		if r.Status != envexec.StatusSkipped || int(r.Status) != 20 {
+			t.Fatalf("%v: got %v (%d), want %v (20)", from, r.Status, int(r.Status), envexec.StatusSkipped)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package worker
+
+import "fmt"
+
+// MemoryReservation describes the memory reserved by the running requests
+// against the total memory
+type MemoryReservation struct {
+	Total    Size // 0 if the requests are not held by reservation
+	Reserved Size // reservations of the running requests
+	Used     Size // memory used by the running commands so far
+}
+
+// memoryReservation is the memory that the request could use at the same time.
+// The commands run in parallel (group or pipe) reserve the sum of their
+// limits while the commands run one by one (sequential run mode, more
+// commands than the parallelism, or the repetitions) reserve the largest
+// limit. The command without memory limit (or unlimited) reserves the total
+// memory.
+func (w *worker) memoryReservation(req *Request) uint64 {
+	sequential := w.sequential(req)
+	var n uint64
+	for _, c := range req.Cmd {
+		if c.MemoryLimit == 0 || c.MemoryLimit == UnlimitedSize {
+			return w.totalMemory.Byte()
+		}
+		m := c.MemoryLimit.Byte() + w.extraMemoryLimit.Byte()
+		switch {
+		case !sequential:
+			n += m
+		case m > n:
+			n = m
+		}
+	}
+	return n
+}
+
+// validReservation rejects the request whose memory reservation could never
+// fit into the total memory
+func (w *worker) validReservation(req *Request) error {
+	if w.totalMemory == 0 {
+		return nil
+	}
+	if n := w.memoryReservation(req); n > w.totalMemory.Byte() {
+		return fmt.Errorf("%w: memory reservation %v exceeds the total memory %v", ErrLimitExceeded, Size(n), w.totalMemory)
+	}
+	return nil
+}
+
+// MemoryReservation returns the memory reserved and used by the running
+// requests
+func (w *worker) MemoryReservation() MemoryReservation {
+	rt := MemoryReservation{
+		Total:    w.totalMemory,
+		Reserved: Size(w.queue.reservedMemory()),
+	}
+	w.jobMu.Lock()
+	jobs := make([]*job, 0, len(w.jobs))
+	for _, j := range w.jobs {
+		jobs = append(jobs, j)
+	}
+	w.jobMu.Unlock()
+	for _, j := range jobs {
+		rt.Used += j.memoryUsed()
+	}
+	return rt
+}
@@ -0,0 +1,47 @@
+package worker
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMemoryReservation(t *testing.T) {
+	const mib = 1 << 20
+	w := &worker{parallelism: 2, totalMemory: 1024 * mib, extraMemoryLimit: mib}
+	cmds := func(limits ...Size) []Cmd {
+		rt := make([]Cmd, 0, len(limits))
+		for _, l := range limits {
+			rt = append(rt, Cmd{MemoryLimit: l})
+		}
+		return rt
+	}
+	pipe := []PipeMap{{In: PipeIndex{Index: 0, Fd: 1}, Out: PipeIndex{Index: 1, Fd: 0}}}
+	for _, c := range []struct {
+		name string
+		req  Request
+		want uint64
+	}{
+		{"single", Request{Cmd: cmds(256 * mib)}, 257 * mib},
+		{"group", Request{Cmd: cmds(256*mib, 128*mib)}, 386 * mib},
+		{"sequential", Request{Cmd: cmds(256*mib, 512*mib), RunMode: RunModeSequential}, 513 * mib},
+		{"more than parallelism", Request{Cmd: cmds(128*mib, 512*mib, 256*mib)}, 513 * mib},
+		{"pipe", Request{Cmd: cmds(256*mib, 128*mib), PipeMapping: pipe, RunMode: RunModeSequential}, 386 * mib},
+		{"unlimited", Request{Cmd: cmds(256*mib, UnlimitedSize), RunMode: RunModeSequential}, 1024 * mib},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			if n := w.memoryReservation(&c.req); n != c.want {
+				t.Fatalf("reserved %v, want %v", Size(n), Size(c.want))
+			}
+		})
+	}
+
+	// the sequential commands fit even if their sum does not
+	seq := &Request{Cmd: cmds(600*mib, 600*mib), RunMode: RunModeSequential}
+	if err := w.validReservation(seq); err != nil {
+		t.Fatalf("sequential request rejected: %v", err)
+	}
+	group := &Request{Cmd: cmds(600*mib, 600*mib)}
+	if err := w.validReservation(group); !errors.Is(err, ErrLimitExceeded) {
+		t.Fatalf("got %v, want the limit exceeded", err)
+	}
+}
@@ -0,0 +1,247 @@
+package worker
+
+import (
+	"container/list"
+	"context"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+)
+
+// resultCache keeps the finished responses of the requests with idempotency
+// key for ttl, so that a retried request returns the stored response instead
+// of running again, or waits for the run in flight. Inline files are kept in
+// memory and the stored responses are dropped in least recently used order
+// once the total size exceeds max. Files cached in the file store are pinned
+// for ttl so that their ids in the stored responses stay valid.
+type resultCache struct {
+	fs  filestore.FileStore
+	ttl time.Duration
+	max envexec.Size
+
+	mu      sync.Mutex
+	entries map[resultKey]*resultEntry
+	lru     *list.List // finished entries, least recently used first
+	size    envexec.Size
+}
+
+// resultKey scopes the idempotency key by the file store namespace of the
+// submitter (i.e. the auth token)
+type resultKey struct {
+	owner string
+	key   string
+}
+
+type resultEntry struct {
+	key     resultKey
+	done    chan struct{} // closed once finished
+	resp    Response      // results without inline files
+	files   []map[string][]byte
+	size    envexec.Size
+	expires time.Time
+	elem    *list.Element // in lru once stored
+}
+
+func newResultCache(fs filestore.FileStore, ttl time.Duration, max envexec.Size) *resultCache {
+	return &resultCache{
+		fs:      fs,
+		ttl:     ttl,
+		max:     max,
+		entries: make(map[resultKey]*resultEntry),
+		lru:     list.New(),
+	}
+}
+
+// begin returns the stored or in flight entry of the key if found, otherwise
+// a new in flight entry that should be finished by finish
+func (c *resultCache) begin(key resultKey) (*resultEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		if e.elem == nil {
+			return e, true
+		}
+		if time.Now().Before(e.expires) {
+			c.lru.MoveToBack(e.elem)
+			return e, true
+		}
+		c.remove(e)
+	}
+	e := &resultEntry{
+		key:  key,
+		done: make(chan struct{}),
+	}
+	c.entries[key] = e
+	return e, false
+}
+
+// finish stores the response of the run and sends the replay of it to the
+// submitter. Responses with error are not stored so that the retries run
+// again, while the waiting retries receive the error.
+func (c *resultCache) finish(ctx context.Context, e *resultEntry, ch <-chan Response) <-chan Response {
+	rt := make(chan Response, 1)
+	go func() {
+		resp := <-ch
+		c.store(e, resp)
+		rt <- c.wait(ctx, e, resp.RequestID)
+	}()
+	return rt
+}
+
+// attach waits for the entry found by begin and sends the replay of it
+func (c *resultCache) attach(ctx context.Context, e *resultEntry, requestID string) <-chan Response {
+	rt := make(chan Response, 1)
+	go func() {
+		rt <- c.wait(ctx, e, requestID)
+	}()
+	return rt
+}
+
+func (c *resultCache) wait(ctx context.Context, e *resultEntry, requestID string) Response {
+	select {
+	case <-e.done:
+	case <-ctx.Done():
+	}
+	// files of the replay would be left in the file store if nobody reads it
+	if ctx.Err() != nil {
+		return Response{
+			RequestID:    requestID,
+			Error:        ErrCancelled,
+			CancelReason: cancelReasonOf(context.Cause(ctx), false),
+		}
+	}
+	return c.replay(e, requestID)
+}
+
+func (c *resultCache) store(e *resultEntry, resp Response) {
+	defer close(e.done)
+
+	if resp.Error == nil {
+		resp.Error = e.read(resp.Results)
+	}
+	e.resp = resp
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if resp.Error != nil || e.size > c.max {
+		delete(c.entries, e.key)
+		return
+	}
+	e.expires = time.Now().Add(c.ttl)
+	for _, r := range resp.Results {
+		for _, id := range r.FileIDs {
+			filestore.Pin(c.fs, id, e.expires)
+		}
+		if r.CoreFileID != "" {
+			filestore.Pin(c.fs, r.CoreFileID, e.expires)
+		}
+		if r.GroupID != "" {
+			filestore.Pin(c.fs, r.GroupID, e.expires)
+		}
+	}
+	e.elem = c.lru.PushBack(e)
+	c.size += e.size
+	c.evict()
+}
+
+// read moves the inline files of the results into memory
+func (e *resultEntry) read(results []Result) error {
+	var err error
+	e.files = make([]map[string][]byte, len(results))
+	for i := range results {
+		e.files[i] = make(map[string][]byte, len(results[i].Files))
+		for name, f := range results[i].Files {
+			if err == nil {
+				var b []byte
+				if _, err = f.Seek(0, io.SeekStart); err == nil {
+					b, err = io.ReadAll(f)
+				}
+				e.files[i][name] = b
+				e.size += envexec.Size(len(b))
+			}
+			f.Close()
+			os.Remove(f.Name())
+		}
+		results[i].Files = nil
+	}
+	return err
+}
+
+// replay creates the response of the entry with copies of the inline files
+func (c *resultCache) replay(e *resultEntry, requestID string) Response {
+	resp := e.resp
+	resp.RequestID = requestID
+	if resp.Error != nil {
+		return resp
+	}
+	resp.Results = make([]Result, len(e.resp.Results))
+	for i, r := range e.resp.Results {
+		r.Files = make(map[string]*os.File, len(e.files[i]))
+		for name, b := range e.files[i] {
+			f, err := c.newFile(b)
+			if err != nil {
+				closeFiles(resp.Results[:i])
+				closeFiles([]Result{r})
+				return Response{RequestID: requestID, Error: err}
+			}
+			r.Files[name] = f
+		}
+		resp.Results[i] = r
+	}
+	return resp
+}
+
+func (c *resultCache) newFile(b []byte) (*os.File, error) {
+	f, err := c.fs.New()
+	if err != nil {
+		return nil, err
+	}
+	if _, err = f.Write(b); err == nil {
+		_, err = f.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+	return f, nil
+}
+
+// evict drops the expired entries and then the least recently used ones until
+// the total size is within max, mu must be held
+func (c *resultCache) evict() {
+	now := time.Now()
+	for el := c.lru.Front(); el != nil; {
+		next := el.Next()
+		if e := el.Value.(*resultEntry); !now.Before(e.expires) {
+			c.remove(e)
+		}
+		el = next
+	}
+	for c.size > c.max && c.lru.Len() > 0 {
+		c.remove(c.lru.Front().Value.(*resultEntry))
+	}
+}
+
+// remove drops the stored entry, mu must be held
+func (c *resultCache) remove(e *resultEntry) {
+	c.lru.Remove(e.elem)
+	delete(c.entries, e.key)
+	c.size -= e.size
+	e.elem = nil
+}
+
+func closeFiles(results []Result) {
+	for _, r := range results {
+		for _, f := range r.Files {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}
+}
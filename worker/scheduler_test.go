@@ -0,0 +1,58 @@
+package worker_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+)
+
+// runClients occupies both worker loops, then queues 4 requests of client a
+// followed by 2 of client b and returns the clients in the order their
+// requests started
+func runClients(t *testing.T, scheduler worker.Scheduler) string {
+	w := newTestWorker(t, func(c *worker.Config) {
+		c.Parallelism = 2
+		c.Scheduler = scheduler
+	})
+	// the programs of the fake sandbox share the host file system
+	log := filepath.Join(t.TempDir(), "log")
+	submit := func(client, script string) (<-chan worker.Response, <-chan struct{}) {
+		ctx := filestore.NewNamespaceContext(context.Background(), client)
+		return w.Submit(ctx, &worker.Request{Cmd: []worker.Cmd{shCmd(script)}})
+	}
+
+	var chs []<-chan worker.Response
+	for i := 0; i < 2; i++ {
+		ch, started := submit("a", "sleep 0.2")
+		<-started
+		chs = append(chs, ch)
+	}
+	for _, client := range []string{"a", "a", "a", "a", "b", "b"} {
+		ch, _ := submit(client, "echo "+client+" >> "+log+" && sleep 0.05")
+		chs = append(chs, ch)
+	}
+	waitAll(t, chs...)
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.ReplaceAll(string(b), "\n", "")
+}
+
+func TestFairSchedulerInterleaves(t *testing.T) {
+	// the requests of b wait for all the ones of a submitted before
+	if order := runClients(t, worker.SchedulerFIFO); order != "aaaabb" {
+		t.Fatalf("fifo order %s, want aaaabb", order)
+	}
+	// the clients are taken in turn by the two loops, so that b is not
+	// queued behind the burst of a
+	order := runClients(t, worker.SchedulerFair)
+	if strings.Count(order[:4], "b") != 2 {
+		t.Fatalf("fair order %s, want both b within the first 4", order)
+	}
+}
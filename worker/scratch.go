@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// ErrInvalidScratch is returned when the scratch file could not be created
+// on disk for the command
+var ErrInvalidScratch = errors.New("invalid scratch file")
+
+// scratchAlign is the alignment of the scratch size, which is the logical
+// block size of most devices for O_DIRECT
+const scratchAlign = 4096
+
+// newScratchDir creates the directory where the scratch files are created
+// under dir, the ones left by the previous process are removed. The directory
+// must be backed by disk so that fsync of the programs reaches the device.
+func newScratchDir(dir string) (string, error) {
+	run := filepath.Join(dir, "scratch")
+	os.RemoveAll(run)
+	if err := os.MkdirAll(run, 0755); err != nil {
+		return "", err
+	}
+	if err := checkDiskBacked(run); err != nil {
+		return "", err
+	}
+	return run, nil
+}
+
+// acquireScratch creates the empty scratch file of a run with size allocated,
+// release removes it
+func acquireScratch(root string, size Size) (file string, release func(), err error) {
+	f, err := os.CreateTemp(root, "")
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	file = f.Name()
+	if err := allocateScratch(f, int64(size)); err != nil {
+		os.Remove(file)
+		return "", nil, err
+	}
+	return file, func() { os.Remove(file) }, nil
+}
+
+// scratchSize returns the size of the scratch file of the command, the max if
+// not specified
+func (w *worker) scratchSize(c Cmd) Size {
+	size := c.ScratchSize
+	if size == 0 {
+		size = w.scratchMax
+	}
+	return (size + scratchAlign - 1) / scratchAlign * scratchAlign
+}
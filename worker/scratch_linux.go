@@ -0,0 +1,31 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// checkDiskBacked rejects the directory in memory, where fsync is a no-op
+func checkDiskBacked(dir string) error {
+	var st unix.Statfs_t
+	if err := unix.Statfs(dir, &st); err != nil {
+		return err
+	}
+	switch st.Type {
+	case unix.TMPFS_MAGIC, unix.RAMFS_MAGIC:
+		return fmt.Errorf("scratch dir %s is not backed by disk", dir)
+	}
+	return nil
+}
+
+// allocateScratch allocates the blocks of the file keeping it empty, so that
+// the writes within size do not fail for space
+func allocateScratch(f *os.File, size int64) error {
+	err := unix.Fallocate(int(f.Fd()), unix.FALLOC_FL_KEEP_SIZE, 0, size)
+	if err == unix.EOPNOTSUPP {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,15 @@
+//go:build !linux
+
+package worker
+
+import "os"
+
+// checkDiskBacked is not checked, the scratch file is not supported outside
+// of linux
+func checkDiskBacked(dir string) error {
+	return nil
+}
+
+func allocateScratch(f *os.File, size int64) error {
+	return nil
+}
@@ -0,0 +1,110 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// actions of Cmd.SnapshotWorkDir, followed by :<label>
+const (
+	snapshotSave    = "save"
+	snapshotRestore = "restore"
+
+	snapshotDefaultLabel = "default"
+)
+
+// parseSnapshot returns the action and label of Cmd.SnapshotWorkDir
+func parseSnapshot(s string) (action, label string, err error) {
+	action, label, _ = strings.Cut(s, ":")
+	if action != snapshotSave && action != snapshotRestore {
+		return "", "", fmt.Errorf("%w: %q is not save[:<label>] or restore[:<label>]", ErrInvalidSnapshot, s)
+	}
+	if label == "" {
+		label = snapshotDefaultLabel
+	}
+	return action, label, nil
+}
+
+// validSnapshot checks the snapshots are saved by the previous commands of
+// the request before restored, which requires the commands to run one after
+// another
+func (w *worker) validSnapshot(req *Request) error {
+	saved := make(map[string]bool)
+	for i, c := range req.Cmd {
+		if c.SnapshotWorkDir == "" {
+			continue
+		}
+		if w.snapshotMax == 0 {
+			return fmt.Errorf("%w: snapshotWorkDir is disabled", ErrInvalidSnapshot)
+		}
+		if req.RunMode != RunModeSequential || len(req.PipeMapping) > 0 {
+			return fmt.Errorf("%w: snapshotWorkDir requires sequential run mode without pipes", ErrInvalidSnapshot)
+		}
+		action, label, err := parseSnapshot(c.SnapshotWorkDir)
+		if err != nil {
+			return fmt.Errorf("cmd[%d]: %w", i, err)
+		}
+		switch action {
+		case snapshotSave:
+			saved[label] = true
+		case snapshotRestore:
+			if !saved[label] {
+				return fmt.Errorf("%w: cmd[%d] restores %q not saved by the previous commands", ErrInvalidSnapshot, i, label)
+			}
+		}
+	}
+	return nil
+}
+
+// snapshots holds the work dir snapshots saved by the commands of a request,
+// which are released once the request finished
+type snapshots struct {
+	saved map[string]*envexec.Snapshot
+	size  envexec.Size
+	max   envexec.Size
+}
+
+// snapshotsKey is the context key of the snapshots of the request
+type snapshotsKey struct{}
+
+// withSnapshots keeps the snapshots of the request in the context if any of
+// its commands snapshots the work dir, they are released by the returned func
+func (w *worker) withSnapshots(ctx context.Context, req *Request) (context.Context, func()) {
+	for _, c := range req.Cmd {
+		if c.SnapshotWorkDir != "" {
+			s := &snapshots{saved: make(map[string]*envexec.Snapshot), max: w.snapshotMax}
+			return context.WithValue(ctx, snapshotsKey{}, s), s.release
+		}
+	}
+	return ctx, func() {}
+}
+
+// save replaces the snapshot of label by the work dir of env, the total size
+// of the snapshots is limited by max
+func (s *snapshots) save(env envexec.Environment, label string) error {
+	if old, ok := s.saved[label]; ok {
+		s.size -= old.Size
+		old.Release()
+		delete(s.saved, label)
+	}
+	if s.size >= s.max {
+		return fmt.Errorf("%w: %v used by the other snapshots", envexec.ErrSnapshotTooLarge, s.size)
+	}
+	snap, err := envexec.SaveSnapshot(env, label, s.max-s.size)
+	if err != nil {
+		return err
+	}
+	s.saved[label] = snap
+	s.size += snap.Size
+	return nil
+}
+
+func (s *snapshots) release() {
+	for _, snap := range s.saved {
+		snap.Release()
+	}
+	s.saved = nil
+}
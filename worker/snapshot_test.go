@@ -0,0 +1,77 @@
+package worker_test
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+)
+
+// BenchmarkSnapshotRestore compares giving each test case the compiled
+// binary by restoring the snapshot saved after the compile against caching
+// it by copyOutCached and copying it in by the file id
+func BenchmarkSnapshotRestore(b *testing.B) {
+	const (
+		size  = 16 << 20
+		cases = 8
+	)
+	fs := filestore.NewFileLocalStore(b.TempDir())
+	w := newTestWorker(b, func(c *worker.Config) {
+		c.FileStore = fs
+		c.SnapshotMax = size
+	})
+	// the compile writes the binary and each case checks it
+	compile := shCmd("head -c " + strconv.Itoa(size) + " /dev/zero > bin")
+	test := shCmd("test $(wc -c < bin) -eq " + strconv.Itoa(size))
+	accepted := func(rt worker.Response) {
+		b.Helper()
+		for i, r := range rt.Results {
+			if r.Status != envexec.StatusAccepted {
+				b.Fatalf("cmd %d: %v: %s", i, r.Status, r.Error)
+			}
+		}
+	}
+
+	for _, skipVerify := range []bool{false, true} {
+		name := "copyIn"
+		if skipVerify {
+			name += " skipVerify"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(size * cases)
+			for i := 0; i < b.N; i++ {
+				c := compile
+				c.CopyOutCached = []worker.CmdCopyOutFile{{Name: "bin"}}
+				rt := run(b, w, &worker.Request{Cmd: []worker.Cmd{c}})
+				accepted(rt)
+				id := rt.Results[0].FileIDs["bin"]
+
+				req := &worker.Request{RunMode: worker.RunModeSequential, SkipVerify: skipVerify}
+				for k := 0; k < cases; k++ {
+					c := test
+					c.CopyIn = map[string]worker.CmdFile{"bin": &worker.CachedFile{FileID: id}}
+					req.Cmd = append(req.Cmd, c)
+				}
+				accepted(run(b, w, req))
+				fs.Remove(id)
+			}
+		})
+	}
+
+	b.Run("restore", func(b *testing.B) {
+		b.SetBytes(size * cases)
+		for i := 0; i < b.N; i++ {
+			c := compile
+			c.SnapshotWorkDir = "save"
+			req := &worker.Request{Cmd: []worker.Cmd{c}, RunMode: worker.RunModeSequential}
+			for k := 0; k < cases; k++ {
+				c := test
+				c.SnapshotWorkDir = "restore"
+				req.Cmd = append(req.Cmd, c)
+			}
+			accepted(run(b, w, req))
+		}
+	})
+}
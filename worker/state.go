@@ -0,0 +1,155 @@
+package worker
+
+import (
+	"errors"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+)
+
+// State defines the state of the worker that a new instance sharing the file
+// store directory continues with (e.g. blue/green upgrade)
+type State struct {
+	Usage       map[string]UsageState `json:"usage,omitempty"`
+	Idempotency []IdempotencyState    `json:"idempotency,omitempty"`
+}
+
+// UsageState defines the usage accounted for a client
+type UsageState struct {
+	Total       Usage     `json:"total"`
+	Period      Usage     `json:"period"`
+	PeriodStart time.Time `json:"periodStart"`
+}
+
+// IdempotencyState defines the stored response of an idempotency key, files
+// cached in the file store are referenced by their ids in the results
+type IdempotencyState struct {
+	Owner    string              `json:"owner,omitempty"`
+	Key      string              `json:"key"`
+	Expires  time.Time           `json:"expires"`
+	Response Response            `json:"response"`        // Error is always nil
+	Files    []map[string][]byte `json:"files,omitempty"` // inline files of each result
+}
+
+var errIdempotencyDisabled = errors.New("import: responses of idempotency keys could not be imported since idempotencyKey is disabled")
+
+// ExportState returns the usage of the clients and the stored responses of
+// the idempotency keys
+func (w *worker) ExportState() State {
+	rt := State{Usage: w.usage.export()}
+	if w.results != nil {
+		rt.Idempotency = w.results.export()
+	}
+	return rt
+}
+
+// ImportState replaces the usage of the clients in the state and stores the
+// responses of the idempotency keys that have not expired. The files the
+// responses reference should be imported into the file store before.
+func (w *worker) ImportState(s State) error {
+	if len(s.Idempotency) > 0 && w.results == nil {
+		return errIdempotencyDisabled
+	}
+	w.usage.importState(s.Usage)
+	if w.results != nil {
+		w.results.importState(s.Idempotency)
+	}
+	return nil
+}
+
+func (u *usageTracker) export() map[string]UsageState {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	rt := make(map[string]UsageState, len(u.usage))
+	for owner, e := range u.usage {
+		rt[owner] = UsageState(*e)
+	}
+	return rt
+}
+
+func (u *usageTracker) importState(s map[string]UsageState) {
+	if len(s) == 0 {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	for owner, e := range s {
+		entry := usageEntry(e)
+		u.usage[owner] = &entry
+	}
+	u.dirty = true
+}
+
+func (c *resultCache) export() []IdempotencyState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	rt := make([]IdempotencyState, 0, c.lru.Len())
+	for el := c.lru.Front(); el != nil; el = el.Next() {
+		e := el.Value.(*resultEntry)
+		if !now.Before(e.expires) {
+			continue
+		}
+		rt = append(rt, IdempotencyState{
+			Owner:    e.key.owner,
+			Key:      e.key.key,
+			Expires:  e.expires,
+			Response: e.resp,
+			Files:    e.files,
+		})
+	}
+	return rt
+}
+
+// importState stores the responses in the order exported, i.e. least
+// recently used first, and pins the files they reference
+func (c *resultCache) importState(s []IdempotencyState) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for _, st := range s {
+		if !now.Before(st.Expires) || st.Response.Error != nil {
+			continue
+		}
+		key := resultKey{owner: st.Owner, key: st.Key}
+		if _, ok := c.entries[key]; ok {
+			continue
+		}
+		e := &resultEntry{
+			key:     key,
+			done:    make(chan struct{}),
+			resp:    st.Response,
+			files:   st.Files,
+			expires: st.Expires,
+		}
+		close(e.done)
+		for len(e.files) < len(e.resp.Results) {
+			e.files = append(e.files, nil)
+		}
+		for _, m := range e.files {
+			for _, b := range m {
+				e.size += envexec.Size(len(b))
+			}
+		}
+		for _, r := range e.resp.Results {
+			for _, id := range r.FileIDs {
+				filestore.Pin(c.fs, id, e.expires)
+			}
+			if r.CoreFileID != "" {
+				filestore.Pin(c.fs, r.CoreFileID, e.expires)
+			}
+			if r.GroupID != "" {
+				filestore.Pin(c.fs, r.GroupID, e.expires)
+			}
+		}
+		c.entries[key] = e
+		e.elem = c.lru.PushBack(e)
+		c.size += e.size
+	}
+	c.evict()
+}
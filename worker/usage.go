@@ -0,0 +1,292 @@
+package worker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+)
+
+// ErrQuotaExceeded is returned (wrapped by QuotaError) when the quota of the
+// client is exhausted in the current period
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// Usage defines the resources consumed by the executed requests
+type Usage struct {
+	Runs    uint64        `json:"runs"`
+	CPUTime time.Duration `json:"cpuTime"`
+	// MemorySeconds is the peak memory in bytes times the run time in
+	// seconds of each command, which is approximate
+	MemorySeconds float64 `json:"memorySeconds"`
+}
+
+// RequestUsage sums the resources consumed by the commands of an executed
+// request, the commands skipped (StatusSkipped) are not counted. It is the
+// usage accounted to the client.
+type RequestUsage struct {
+	CPUTime time.Duration // sum of the commands (and repetitions)
+	// Memory is the peak memory of the request: the max of the commands run
+	// one after another (sequential run mode, repetitions) and the sum of the
+	// ones run at the same time (parallel run mode, pipe mapping)
+	Memory        envexec.Size
+	MemorySeconds float64       // sum of the commands as Usage.MemorySeconds
+	WallTime      time.Duration // from submitted to the response, including the wait in queue
+	CopyIn        envexec.Size  // bytes copied in, including the extracted archives
+	CopyOut       envexec.Size  // bytes of the outputs returned, excluding the cached ones
+	Cached        envexec.Size  // bytes added to the file store by the cached outputs and core files
+}
+
+// requestUsage sums the results of the response, parallel is set if the
+// commands ran at the same time
+func requestUsage(rt *Response, parallel bool, submitted time.Time) RequestUsage {
+	u := RequestUsage{WallTime: time.Since(submitted)}
+	for _, r := range rt.Results {
+		if r.Status == envexec.StatusSkipped {
+			continue
+		}
+		u.CPUTime += r.Time
+		u.MemorySeconds += float64(r.Memory) * r.RunTime.Seconds()
+		switch {
+		case parallel:
+			u.Memory += r.Memory
+		case r.Memory > u.Memory:
+			u.Memory = r.Memory
+		}
+		u.CopyIn += r.CopyInBytes
+		u.Cached += r.CachedBytes
+		for _, f := range r.Files {
+			if fi, err := f.Stat(); err == nil {
+				u.CopyOut += envexec.Size(fi.Size())
+			}
+		}
+	}
+	return u
+}
+
+// Quota defines the limits of Usage in each period, 0 for unlimited
+type Quota struct {
+	Runs    uint64
+	CPUTime time.Duration
+}
+
+// ClientUsage defines the usage of a client (i.e. file store namespace)
+type ClientUsage struct {
+	Total       Usage
+	Period      Usage     // usage since PeriodStart, checked against Quota
+	PeriodStart time.Time // zero if there is no period
+	Quota       *Quota    // nil if not limited
+	FileBytes   int64     // size of files owned in the file store
+}
+
+// QuotaError is returned when the quota of the client is exhausted
+type QuotaError struct {
+	Resource string  // runs or cpuTime
+	Limit    float64 // count for runs, seconds for cpuTime
+	Used     float64
+	ResetAt  time.Time // zero if the quota is never reset
+}
+
+func (e *QuotaError) Error() string {
+	if e.ResetAt.IsZero() {
+		return fmt.Sprintf("quota exceeded: %s used %v of %v", e.Resource, e.Used, e.Limit)
+	}
+	return fmt.Sprintf("quota exceeded: %s used %v of %v, resets at %s", e.Resource, e.Used, e.Limit, e.ResetAt.Format(time.RFC3339))
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// usageTracker accounts the usage of each client. The usage is loaded from
+// file if not empty and saved by save so that it survives restarts. The
+// period usage is reset at each multiple of period since the zero time (i.e.
+// UTC midnight for 24h), it is never reset if period is 0.
+type usageTracker struct {
+	file    string
+	period  time.Duration
+	quotas  map[string]Quota
+	loadErr error // the file is not overwritten if it could not be loaded
+
+	saveMu sync.Mutex
+	mu     sync.Mutex
+	usage  map[string]*usageEntry
+	dirty  bool
+}
+
+type usageEntry struct {
+	Total       Usage     `json:"total"`
+	Period      Usage     `json:"period"`
+	PeriodStart time.Time `json:"periodStart"`
+}
+
+func newUsageTracker(file string, period time.Duration, quotas map[string]Quota) *usageTracker {
+	u := &usageTracker{
+		file:   file,
+		period: period,
+		quotas: quotas,
+		usage:  make(map[string]*usageEntry),
+	}
+	if file == "" {
+		return u
+	}
+	b, err := os.ReadFile(file)
+	if err == nil {
+		err = json.Unmarshal(b, &u.usage)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		u.loadErr = fmt.Errorf("failed to load usage %s: %w", file, err)
+		u.usage = make(map[string]*usageEntry)
+	}
+	return u
+}
+
+// entry returns the entry of the owner with the period rolled over, mu must
+// be held
+func (u *usageTracker) entry(owner string, now time.Time) *usageEntry {
+	e, ok := u.usage[owner]
+	if !ok {
+		e = &usageEntry{}
+		u.usage[owner] = e
+	}
+	if u.period > 0 {
+		if start := now.Truncate(u.period); !e.PeriodStart.Equal(start) {
+			e.Period = Usage{}
+			e.PeriodStart = start
+		}
+	}
+	return e
+}
+
+// admit checks the period usage of the owner against its quota
+func (u *usageTracker) admit(owner string) error {
+	q, ok := u.quotas[owner]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	e := u.entry(owner, now)
+	resetAt := e.PeriodStart.Add(u.period)
+	if q.Runs > 0 && e.Period.Runs >= q.Runs {
+		return &QuotaError{Resource: "runs", Limit: float64(q.Runs), Used: float64(e.Period.Runs), ResetAt: resetAt}
+	}
+	if q.CPUTime > 0 && e.Period.CPUTime >= q.CPUTime {
+		return &QuotaError{Resource: "cpuTime", Limit: q.CPUTime.Seconds(), Used: e.Period.CPUTime.Seconds(), ResetAt: resetAt}
+	}
+	return nil
+}
+
+// add accounts the usage of the executed request to the owner
+func (u *usageTracker) add(owner string, r RequestUsage) {
+	d := Usage{
+		Runs:          1,
+		CPUTime:       r.CPUTime,
+		MemorySeconds: r.MemorySeconds,
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	e := u.entry(owner, time.Now())
+	e.Total.add(d)
+	e.Period.add(d)
+	u.dirty = true
+}
+
+func (s *Usage) add(d Usage) {
+	s.Runs += d.Runs
+	s.CPUTime += d.CPUTime
+	s.MemorySeconds += d.MemorySeconds
+}
+
+// get returns the usage of all clients with the size of the files they own
+func (u *usageTracker) get(fs filestore.FileStore) map[string]ClientUsage {
+	now := time.Now()
+	rt := make(map[string]ClientUsage)
+
+	u.mu.Lock()
+	for owner := range u.quotas {
+		u.entry(owner, now)
+	}
+	for owner, e := range u.usage {
+		u.entry(owner, now)
+		c := ClientUsage{
+			Total:       e.Total,
+			Period:      e.Period,
+			PeriodStart: e.PeriodStart,
+		}
+		if q, ok := u.quotas[owner]; ok {
+			c.Quota = &q
+		}
+		rt[owner] = c
+	}
+	u.mu.Unlock()
+
+	for id, m := range fs.ListMeta() {
+		_, f := fs.Get(id)
+		fi, ok := f.(*envexec.FileInput)
+		if !ok {
+			continue
+		}
+		st, err := os.Stat(fi.Path)
+		if err != nil {
+			continue
+		}
+		c := rt[m.Owner]
+		c.FileBytes += st.Size()
+		rt[m.Owner] = c
+	}
+	return rt
+}
+
+// save writes the usage to the file if changed since last saved
+func (u *usageTracker) save() error {
+	if u.file == "" {
+		return nil
+	}
+	if u.loadErr != nil {
+		return u.loadErr
+	}
+	u.saveMu.Lock()
+	defer u.saveMu.Unlock()
+
+	u.mu.Lock()
+	if !u.dirty {
+		u.mu.Unlock()
+		return nil
+	}
+	b, err := json.Marshal(u.usage)
+	u.dirty = false
+	u.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(u.file), filepath.Base(u.file)+"*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(b)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), u.file)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		u.mu.Lock()
+		u.dirty = true
+		u.mu.Unlock()
+	}
+	return err
+}
@@ -0,0 +1,151 @@
+package worker_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/env/fake"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+)
+
+// runAs submits the request as the client and waits for its response
+func runAs(t *testing.T, w worker.Worker, client string, req *worker.Request) worker.Response {
+	t.Helper()
+	ch, _ := w.Submit(filestore.NewNamespaceContext(context.Background(), client), req)
+	rt := <-ch
+	if rt.Error != nil {
+		t.Fatal(rt.Error)
+	}
+	t.Cleanup(func() {
+		for _, r := range rt.Results {
+			for _, f := range r.Files {
+				f.Close()
+			}
+		}
+	})
+	return rt
+}
+
+func TestRequestUsage(t *testing.T) {
+	w := newTestWorker(t, func(c *worker.Config) { c.Parallelism = 2 })
+	cmdSeconds := float64(fake.DefaultMemory) * fake.DefaultTime.Seconds()
+
+	copyIn := shCmd("cat in")
+	copyIn.CopyIn = map[string]worker.CmdFile{"in": &worker.MemoryFile{Content: []byte("0123456789")}}
+	cached := shCmd("printf 01234 > out")
+	cached.CopyOutCached = []worker.CmdCopyOutFile{{Name: "out"}}
+	failed := shCmd("exit 1")
+	skipped := shCmd("true")
+	skipped.RelativeLimits.CPU = &worker.RelativeLimit{FromCmd: 0, Multiplier: 2}
+
+	for _, c := range []struct {
+		name   string
+		req    worker.Request
+		cmds   int          // commands counted
+		mem    envexec.Size // peak memory
+		in     envexec.Size
+		out    envexec.Size
+		cached envexec.Size
+	}{
+		{
+			name: "sequential",
+			req:  worker.Request{Cmd: []worker.Cmd{shCmd("echo a"), shCmd("echo b")}, RunMode: worker.RunModeSequential},
+			cmds: 2, mem: fake.DefaultMemory, out: 4,
+		},
+		{
+			name: "parallel",
+			req:  worker.Request{Cmd: []worker.Cmd{shCmd("echo a"), shCmd("echo b")}},
+			cmds: 2, mem: 2 * fake.DefaultMemory, out: 4,
+		},
+		{
+			name: "more than parallelism",
+			req:  worker.Request{Cmd: []worker.Cmd{shCmd("true"), shCmd("true"), shCmd("true")}},
+			cmds: 3, mem: fake.DefaultMemory,
+		},
+		{
+			name: "skipped",
+			req:  worker.Request{Cmd: []worker.Cmd{failed, skipped}, RunMode: worker.RunModeSequential},
+			cmds: 1, mem: fake.DefaultMemory,
+		},
+		{
+			name: "copy in",
+			req:  worker.Request{Cmd: []worker.Cmd{copyIn}},
+			cmds: 1, mem: fake.DefaultMemory, in: 10, out: 10,
+		},
+		{
+			name: "copy out cached",
+			req:  worker.Request{Cmd: []worker.Cmd{cached}},
+			cmds: 1, mem: fake.DefaultMemory, cached: 5,
+		},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			submitted := time.Now()
+			rt := runAs(t, w, c.name, &c.req)
+			u := rt.Usage
+			if want := time.Duration(c.cmds) * fake.DefaultTime; u.CPUTime != want {
+				t.Errorf("cpu time %v, want %v", u.CPUTime, want)
+			}
+			if want := float64(c.cmds) * cmdSeconds; u.MemorySeconds != want {
+				t.Errorf("memory seconds %v, want %v", u.MemorySeconds, want)
+			}
+			if u.Memory != c.mem {
+				t.Errorf("memory %v, want %v", u.Memory, c.mem)
+			}
+			if u.CopyIn != c.in {
+				t.Errorf("copy in %v, want %v", u.CopyIn, c.in)
+			}
+			if u.CopyOut != c.out {
+				t.Errorf("copy out %v, want %v", u.CopyOut, c.out)
+			}
+			if u.Cached != c.cached {
+				t.Errorf("cached %v, want %v", u.Cached, c.cached)
+			}
+			if u.WallTime <= 0 || u.WallTime > time.Since(submitted) {
+				t.Errorf("wall time %v is not within the submission", u.WallTime)
+			}
+		})
+	}
+}
+
+func TestUsageAccounting(t *testing.T) {
+	w := newTestWorker(t, func(c *worker.Config) { c.Parallelism = 2 })
+
+	requests := map[string][]worker.Request{
+		"a": {
+			{Cmd: []worker.Cmd{shCmd("echo a")}},
+			{Cmd: []worker.Cmd{shCmd("echo a"), shCmd("echo b")}},
+			{Cmd: []worker.Cmd{shCmd("exit 1"), shCmd("true")}, RunMode: worker.RunModeSequential},
+		},
+		"b": {
+			{Cmd: []worker.Cmd{shCmd("true"), shCmd("true"), shCmd("true")}},
+		},
+	}
+	want := make(map[string]worker.Usage)
+	for client, reqs := range requests {
+		for i := range reqs {
+			u := runAs(t, w, client, &reqs[i]).Usage
+			s := want[client]
+			s.Runs++
+			s.CPUTime += u.CPUTime
+			s.MemorySeconds += u.MemorySeconds
+			want[client] = s
+		}
+	}
+
+	usage := w.Usage()
+	for client, u := range want {
+		got, ok := usage[client]
+		if !ok {
+			t.Fatalf("client %s is not accounted", client)
+		}
+		if got.Total != u || got.Period != u {
+			t.Errorf("client %s: total %+v, period %+v, want the sum of the responses %+v", client, got.Total, got.Period, u)
+		}
+	}
+	if _, ok := usage[""]; ok {
+		t.Error("usage accounted to no client")
+	}
+}
@@ -0,0 +1,117 @@
+package worker
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+)
+
+// ErrInvalidVerdict is returned when the verdict rules of the request are not
+// configured
+var ErrInvalidVerdict = errors.New("invalid verdict")
+
+// VerdictDefault is the name of the verdict rules applied to the requests
+// without Verdict if configured
+const VerdictDefault = "default"
+
+// VerdictRule rewrites the results matching all of its conditions, the empty
+// conditions match any result. The rules of a set are evaluated in order and
+// only the first matching one applies.
+type VerdictRule struct {
+	Status     []envexec.Status
+	ExitStatus []int
+	Signal     []int
+
+	SetStatus envexec.Status // StatusInvalid keeps the status
+	ClampTime bool           // lowers time and run time to the cpu and clock limits
+	Message   string         // attached to the result as Message
+}
+
+// RawResult is the result before rewritten by the verdict rules, the fields
+// not rewritten are the same as the result
+type RawResult struct {
+	Status  envexec.Status
+	Time    time.Duration
+	RunTime time.Duration
+}
+
+func (r *VerdictRule) match(res *Result) bool {
+	return matchAny(r.Status, res.Status) && matchAny(r.ExitStatus, res.ExitStatus) && matchAny(r.Signal, res.Signal)
+}
+
+func matchAny[T comparable](s []T, v T) bool {
+	if len(s) == 0 {
+		return true
+	}
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
+
+// validVerdict checks the verdict rules of the request are configured
+func (w *worker) validVerdict(req *Request) error {
+	if req.Verdict == "" {
+		return nil
+	}
+	if _, ok := w.verdicts[req.Verdict]; !ok {
+		return fmt.Errorf("%w: %q is not configured", ErrInvalidVerdict, req.Verdict)
+	}
+	return nil
+}
+
+// applyVerdict rewrites the results by the verdict rules of the request, the
+// results are left untouched if there is no rule
+func (w *worker) applyVerdict(req *Request, rt *Response) {
+	name := req.Verdict
+	if name == "" {
+		name = VerdictDefault
+	}
+	rules := w.verdicts[name]
+	if len(rules) == 0 {
+		return
+	}
+	for i := range rt.Results {
+		// the repetitions share the single command
+		c := req.Cmd[0]
+		if i < len(req.Cmd) {
+			c = req.Cmd[i]
+		}
+		res := &rt.Results[i]
+		raw := RawResult{Status: res.Status, Time: res.Time, RunTime: res.RunTime}
+		for _, r := range rules {
+			if r.match(res) {
+				applyVerdictRule(&r, res, c)
+				break
+			}
+		}
+		if req.IncludeRaw {
+			res.Raw = &raw
+		}
+	}
+}
+
+func applyVerdictRule(r *VerdictRule, res *Result, c Cmd) {
+	if r.SetStatus != envexec.StatusInvalid {
+		res.Status = r.SetStatus
+	}
+	if r.ClampTime {
+		clockLimit := c.ClockLimit
+		if clockLimit < c.CPULimit {
+			clockLimit = c.CPULimit
+		}
+		if c.CPULimit > 0 && res.Time > c.CPULimit {
+			res.Time = c.CPULimit
+		}
+		if clockLimit > 0 && res.RunTime > clockLimit {
+			res.RunTime = clockLimit
+		}
+	}
+	if r.Message != "" {
+		res.Message = r.Message
+	}
+}
@@ -0,0 +1,66 @@
+package worker_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/worker"
+)
+
+// TestVerdictPrecedence covers the first matching rule of the set applies,
+// even if the later rules match as well
+func TestVerdictPrecedence(t *testing.T) {
+	w := newTestWorker(t, func(c *worker.Config) {
+		c.Verdicts = map[string][]worker.VerdictRule{
+			"course": {
+				{ExitStatus: []int{3}, SetStatus: envexec.StatusWrongAnswer, Message: "exit 3"},
+				{Status: []envexec.Status{envexec.StatusNonzeroExitStatus, envexec.StatusSignalled}, SetStatus: envexec.StatusSignalled, Message: "runtime error"},
+				{Message: "any"},
+			},
+		}
+	})
+	for _, c := range []struct {
+		script  string
+		raw     envexec.Status
+		status  envexec.Status
+		message string
+	}{
+		{"exit 3", envexec.StatusNonzeroExitStatus, envexec.StatusWrongAnswer, "exit 3"},
+		{"exit 4", envexec.StatusNonzeroExitStatus, envexec.StatusSignalled, "runtime error"},
+		{"kill -9 $$", envexec.StatusSignalled, envexec.StatusSignalled, "runtime error"},
+		{"true", envexec.StatusAccepted, envexec.StatusAccepted, "any"},
+	} {
+		t.Run(c.script, func(t *testing.T) {
+			rt := run(t, w, &worker.Request{Cmd: []worker.Cmd{shCmd(c.script)}, Verdict: "course", IncludeRaw: true})
+			r := rt.Results[0]
+			if r.Status != c.status || r.Message != c.message {
+				t.Fatalf("status %v message %q, want %v %q", r.Status, r.Message, c.status, c.message)
+			}
+			if r.Raw == nil || r.Raw.Status != c.raw {
+				t.Fatalf("raw %+v, want %v", r.Raw, c.raw)
+			}
+		})
+	}
+}
+
+// TestVerdictClampTime covers the times are lowered to the limits with the
+// raw times kept
+func TestVerdictClampTime(t *testing.T) {
+	w := newTestWorker(t, func(c *worker.Config) {
+		c.Verdicts = map[string][]worker.VerdictRule{
+			worker.VerdictDefault: {{Status: []envexec.Status{envexec.StatusTimeLimitExceeded}, ClampTime: true}},
+		}
+	})
+	c := shCmd("exec sleep 10")
+	c.CPULimit = 100 * time.Millisecond
+	c.ClockLimit = c.CPULimit
+	rt := run(t, w, &worker.Request{Cmd: []worker.Cmd{c}, IncludeRaw: true})
+	r := rt.Results[0]
+	if r.Status != envexec.StatusTimeLimitExceeded || r.RunTime != c.ClockLimit {
+		t.Fatalf("status %v run time %v, want clamped to %v", r.Status, r.RunTime, c.ClockLimit)
+	}
+	if r.Raw == nil || r.Raw.RunTime <= c.ClockLimit {
+		t.Fatalf("raw %+v, want the run time over the limit", r.Raw)
+	}
+}
@@ -14,6 +14,7 @@ type waiter struct {
 	tickInterval   time.Duration
 	timeLimit      time.Duration
 	clockTimeLimit time.Duration
+	ioLimit        envexec.Size // 0 for unlimited
 }
 
 func (w *waiter) Wait(ctx context.Context, u envexec.Process) bool {
@@ -47,6 +48,10 @@ func (w *waiter) Wait(ctx context.Context, u envexec.Process) bool {
 			if u.Time > w.timeLimit {
 				return true
 			}
+			// killed as output limit exceeded by the result io stat
+			if w.ioLimit > 0 && u.IOWrite > w.ioLimit {
+				return true
+			}
 		}
 	}
 }
@@ -2,10 +2,17 @@ package worker
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path"
+	"runtime/debug"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/criyle/go-judge/envexec"
@@ -16,10 +23,124 @@ const maxWaiting = 512
 
 // EnvironmentPool defines pools for environment to be used to execute commands
 type EnvironmentPool interface {
-	Get() (envexec.Environment, error)
+	// Get returns environment with the options. The environment with the
+	// pinned credential is never shared with other running requests even
+	// with the same credential. The environment with the options mounting the
+	// files of the run (randomized work directory, mount files, host exec,
+	// disk work directory and scratch file) is destroyed after put back, the
+	// others are only reused by the runs with the same options.
+	Get(ctx context.Context, opts EnvironmentOptions) (envexec.Environment, error)
 	Put(envexec.Environment)
+	// Valid checks whether the environment with the options could be
+	// provided, e.g. the credential is allowed to be pinned and the datasets
+	// and profile are configured
+	Valid(opts EnvironmentOptions) error
 }
 
+// DiscardEnvironmentPool defines pools that could destroy the environment
+// got from it instead of putting it back
+type DiscardEnvironmentPool interface {
+	EnvironmentPool
+	// Discard destroys the environment, e.g. when the run using it panicked
+	// and its state is unknown
+	Discard(envexec.Environment)
+}
+
+// IOEnvironmentPool defines pools that could report whether the bytes read
+// and written to block devices are accounted, so that IOLimit is enforced
+type IOEnvironmentPool interface {
+	EnvironmentPool
+	IOAccounting() bool
+}
+
+// NUMAEnvironmentPool defines pools that shard the environments by NUMA node,
+// the programs in the environments from the pool of a node run on its cpus
+// and memory
+type NUMAEnvironmentPool interface {
+	EnvironmentPool
+	// NUMANodes returns the ids of the nodes
+	NUMANodes() []int
+	// NUMANode returns the pool of the node, the least loaded node if node
+	// is not found (e.g. negative). The environments are put back to the
+	// sharded pool.
+	NUMANode(node int) EnvironmentPool
+}
+
+// ProfileMinimal is the builtin mount profile with only the tmpfs work
+// directory, /proc and /dev/null mounted, used to run static binaries
+const ProfileMinimal = envexec.ProfileMinimal
+
+// workDirVar in args and env of commands is substituted with the work
+// directory of the environment
+const workDirVar = "{{workDir}}"
+
+// ErrInvalidCredential is returned when the pinned credential is not allowed
+var ErrInvalidCredential = errors.New("invalid credential")
+
+// ErrInvalidDataset is returned when the dataset is not configured or could
+// not be mounted for the command
+var ErrInvalidDataset = errors.New("invalid dataset")
+
+// ErrInvalidProfile is returned when the mount profile is not available or
+// could not be used for the command
+var ErrInvalidProfile = errors.New("invalid profile")
+
+// ErrInvalidMountFiles is returned when the files could not be mounted for the
+// command
+var ErrInvalidMountFiles = errors.New("invalid mount files")
+
+// ErrInvalidRandomWorkDir is returned when the work directory could not be
+// mounted at randomized path for the command
+var ErrInvalidRandomWorkDir = errors.New("invalid randomized work directory")
+
+// ErrIOLimitNotSupported is returned when IOLimit is set but the io is not
+// accounted by the environment and IOLimitStrict is enabled
+var ErrIOLimitNotSupported = errors.New("io limit is not supported")
+
+// ErrCollectCoreDisabled is returned when CollectCore is set but CoreFileMax
+// is 0
+var ErrCollectCoreDisabled = errors.New("core file collection is disabled")
+
+// ErrInvalidTrace is returned when Trace is set but it is not enabled, or it
+// is combined with the limits relying on accurate timing
+var ErrInvalidTrace = errors.New("invalid trace")
+
+// ErrInvalidProcInfo is returned when the synthetic proc info could not be
+// mounted for the command
+var ErrInvalidProcInfo = errors.New("invalid proc info")
+
+// ErrInvalidArch is returned when the architecture name is not known
+var ErrInvalidArch = errors.New("invalid arch")
+
+// ErrInvalidShmSize is returned when the shm size is above ShmSizeMax
+var ErrInvalidShmSize = errors.New("invalid shm size")
+
+// ErrInvalidDiskWorkDir is returned when the work directory could not be
+// backed by disk for the command
+var ErrInvalidDiskWorkDir = errors.New("invalid disk work dir")
+
+// ErrInvalidNUMANode is returned when the pinned NUMA node is not available
+var ErrInvalidNUMANode = errors.New("invalid numa node")
+
+// ErrInvalidSnapshot is returned when the snapshotWorkDir of the commands is
+// invalid, disabled or restores a snapshot not saved by the previous commands
+var ErrInvalidSnapshot = errors.New("invalid snapshot")
+
+// ErrInvalidRepeat is returned when Repeat is set for the request of more than
+// one command or Repeat is disabled by RepeatMax
+var ErrInvalidRepeat = errors.New("invalid repeat")
+
+// ErrCancelled is returned when the request is cancelled before it is executed
+var ErrCancelled = errors.New("cancelled before execute")
+
+// ErrShutdown is returned when the request is submitted to or still waiting
+// in the worker after it is shut down
+var ErrShutdown = errors.New("worker is shut down")
+
+// ErrInlineContentTooLarge is returned when the inline content exceeds
+// InlineContentMax
+var ErrInlineContentTooLarge = errors.New("inline content too large")
+
 // Config defines worker configuration
 type Config struct {
 	FileStore             filestore.FileStore
@@ -30,8 +151,138 @@ type Config struct {
 	ExtraMemoryLimit      envexec.Size
 	OutputLimit           envexec.Size
 	CopyOutLimit          envexec.Size
+	CopyOutMaxFiles       int
+	CopyOutMaxTotal       envexec.Size
+	RandomizeWorkDir      bool
 	OpenFileLimit         uint64
+	ArchiveMaxCount       int
+	ArchiveMaxSize        envexec.Size
+	InlineContentMax      envexec.Size // max size of each MemoryFile, 0 unlimited
+	InstanceID            string       // reported in results, hostname if empty
 	ExecObserver          func(Response)
+	// AuditObserver is called with each request taken by the worker and its
+	// response, including the ones rejected by validation. The request must
+	// not be retained or modified.
+	AuditObserver func(AuditRecord)
+	// StderrMax is the max size of the stderr (fd 2) collector if its max
+	// is 0 and StderrCacheTruncated defaults its Collector.CacheTruncated
+	StderrMax            envexec.Size
+	StderrCacheTruncated bool
+	// Scheduler selects the order of waiting requests, SchedulerShares are
+	// the weights of the clients (i.e. file store namespace of the requests)
+	// for the fair scheduler, 1 if not specified
+	Scheduler       Scheduler
+	SchedulerShares map[string]int
+	// PriorityAging raises the priority of the waiting requests by one level
+	// for every PriorityAging waited, 0 disables aging
+	PriorityAging time.Duration
+	// TotalMemory holds the waiting requests until their memory reservations
+	// (the memory limits plus ExtraMemoryLimit of the commands) fit into it
+	// with the ones running, 0 disables the reservation
+	TotalMemory Size
+	// LeakObserver is called in background with the request ID when the fds
+	// or goroutines created for a run are not released after it finished
+	LeakObserver func(requestID string, leak envexec.Leak)
+	// CancelObserver is called when a submitted request is cancelled by the
+	// context of the submitter (e.g. the client disconnected) before it
+	// finished, running reports whether it was taken by a worker loop
+	CancelObserver func(running bool)
+	// PanicObserver is called with the request ID, the value and the stack
+	// when the run of the request panicked. The environments the run got are
+	// destroyed and its commands fail with Internal Error.
+	PanicObserver func(requestID string, value any, stack []byte)
+	// AutoParallelism adjusts the number of worker loops started with
+	// Parallelism within its bounds if its Max is not 0.
+	// ParallelismObserver is called with each change and the contention of
+	// the interval that decided it.
+	AutoParallelism     AutoParallelism
+	ParallelismObserver func(from, to int, c Contention, cpu float64)
+	// IOLimitStrict rejects commands with IOLimit if the io is not accounted
+	// by the environment, otherwise the limit is ignored with warning
+	IOLimitStrict bool
+	// CoreFileMax is the max size of the core file collected for commands
+	// with CollectCore, 0 disables CollectCore
+	CoreFileMax envexec.Size
+	// EnableTrace allows commands with Trace, which are slowed down by the
+	// tracer
+	EnableTrace bool
+	// ProcInfoCPUs is the number of processors reported by the synthetic
+	// /proc/cpuinfo of commands with ProcInfo if not specified
+	ProcInfoCPUs uint64
+	// ShmSizeMax is the size of the tmpfs mounted at /dev/shm and the max
+	// ShmSize of the commands, 0 if not mounted. ShmSizeDefault is used for
+	// commands without ShmSize.
+	ShmSizeMax     envexec.Size
+	ShmSizeDefault envexec.Size
+	// LimitMax caps the cpu, clock, memory and proc limits of the commands.
+	// The limits above it are lowered to the max if LimitClamp, otherwise
+	// the request is rejected with ErrLimitExceeded. The commands of the
+	// request are updated in place to the limits applied.
+	LimitMax   LimitMax
+	LimitClamp bool
+	// LimitDefault sets the limits not set by the commands, lowered to the
+	// max of the server and the policy. AllowUnlimited allows the commands
+	// to request Unlimited limits.
+	LimitDefault   LimitDefault
+	AllowUnlimited bool
+	// MountFilesDir is the host directory caching the files of MountFiles,
+	// empty disables MountFiles. Files not in use are evicted once the total
+	// size exceeds MountFilesCacheMax.
+	MountFilesDir      string
+	MountFilesCacheMax envexec.Size
+	// HostExecDirs whitelists the host directories whose files could be
+	// referenced by their absolute paths in args. The files are bind mounted
+	// read-only under /host in a new environment for each run and the args
+	// are rewritten to them.
+	HostExecDirs []string
+	// DiskWorkDir is the host directory where the per-run directories of
+	// commands with DiskWorkDir are created, empty disables DiskWorkDir. The
+	// bytes left in them after the run are limited by DiskWorkDirMax.
+	DiskWorkDir    string
+	DiskWorkDirMax envexec.Size
+	// ScratchDir is the host directory backed by disk where the per-run files
+	// of commands with ScratchFile are created, empty disables ScratchFile.
+	// ScratchMax is the max and default size of the files.
+	ScratchDir string
+	ScratchMax envexec.Size
+	// NoisyNeighbor flags the runs whose major page faults or writeback
+	// exceed the thresholds in Result.NoisyNeighbor, the major page faults
+	// are collected for all commands if set
+	NoisyNeighbor NoisyNeighbor
+	// IdempotencyTTL is the duration the responses of requests with
+	// IdempotencyKey are kept, 0 disables IdempotencyKey. The least recently
+	// used responses are dropped once the total size of their inline files
+	// exceeds IdempotencyCacheMax.
+	IdempotencyTTL      time.Duration
+	IdempotencyCacheMax envexec.Size
+	// UsageFile keeps the usage of the clients across restarts if not empty,
+	// it is written by SaveUsage. Quotas limit the usage of the clients (i.e.
+	// file store namespace) in each QuotaPeriod, requests over the quota are
+	// rejected with QuotaError.
+	UsageFile   string
+	Quotas      map[string]Quota
+	QuotaPeriod time.Duration
+	// RepeatMax is the max Repeat of the requests, above which the request
+	// is rejected with ErrLimitExceeded, 0 disables Repeat
+	RepeatMax int
+	// SnapshotMax is the max total size of the work dir snapshots saved by
+	// the commands of a request, 0 disables SnapshotWorkDir
+	SnapshotMax envexec.Size
+	// ArgLimit limits the args and env of the commands so that the requests
+	// are rejected before queued rather than failed at exec
+	ArgLimit ArgLimit
+	// CollectorDir is the host directory where the buffers of the pipe
+	// collectors not cached are created as anonymous files instead of the
+	// file store, e.g. on disk when the file store is in memory
+	CollectorDir string
+	// Verdicts are the named verdict rules selected by Request.Verdict, which
+	// rewrite the results after the run. The rules of VerdictDefault apply
+	// to the requests without Verdict.
+	Verdicts map[string][]VerdictRule
+	// Policies restrict the features, mount profiles and limits of the
+	// requests of the clients (i.e. file store namespace), requests denied
+	// are rejected with PolicyError. They are replaced by SetPolicies.
+	Policies map[string]Policy
 }
 
 // Worker defines interface for executor
@@ -39,7 +290,52 @@ type Worker interface {
 	Start()
 	Submit(context.Context, *Request) (<-chan Response, <-chan struct{})
 	Execute(context.Context, *Request) <-chan Response
+	// Validate checks the request as it would be executed, including the
+	// existence of files and the pipe mapping, without running it
+	Validate(context.Context, *Request) error
+	// Alive reports false when a worker loop has been stuck for timeout
+	// outside of waiting for requests and running them
+	Alive(timeout time.Duration) bool
+	// Jobs returns the submitted requests that are waiting or running
+	Jobs() []Job
+	// Kill cancels the job by Job.ID, returns false if it is not found
+	Kill(id string) bool
+	// QueueDepth returns the number of waiting requests of each client that
+	// has submitted requests
+	QueueDepth() map[string]int
+	// PriorityDepth returns the number of waiting requests of each priority
+	PriorityDepth() map[Priority]int
+	// Usage returns the usage of each client that has executed requests or
+	// owns files, the client is the file store namespace
+	Usage() map[string]ClientUsage
+	// SaveUsage writes the usage into Config.UsageFile if it changed
+	SaveUsage() error
+	// ExportState returns the usage and the stored responses of the
+	// idempotency keys, so that a new instance could continue with them
+	ExportState() State
+	// ImportState restores the state returned by ExportState of another
+	// instance, it should be called before serving
+	ImportState(State) error
+	// MemoryReservation returns the memory reserved and used by the running
+	// requests
+	MemoryReservation() MemoryReservation
+	// Contention returns how long the requests finished within the last
+	// minute waited for a worker loop and for the environments
+	Contention() Contention
+	// SetPolicies replaces the policies of the clients, the requests being
+	// executed are not affected
+	SetPolicies(map[string]Policy)
+	// SetParallelismCap caps the number of worker loops to n, e.g. to shed
+	// load, 0 removes the cap. AutoParallelism still applies within it.
+	SetParallelismCap(n int)
+	// Warmup runs the command in the environment borrowed from the pool
+	// outside of the queue so that the following runs hit warm caches, it is
+	// not counted in the usage
+	Warmup(ctx context.Context, env envexec.Environment, rc Cmd) Result
 	Shutdown()
+	// Abort kills the waiting and running requests with CancelShutdown, it is
+	// called when the Shutdown could not wait for them anymore
+	Abort()
 }
 
 // worker defines executor worker
@@ -53,15 +349,96 @@ type worker struct {
 	extraMemoryLimit      envexec.Size
 	outputLimit           envexec.Size
 	copyOutLimit          envexec.Size
+	copyOutMaxFiles       int
+	copyOutMaxTotal       envexec.Size
+	randomizeWorkDir      bool
 	openFileLimit         uint64
+	archiveMaxCount       int
+	archiveMaxSize        envexec.Size
+	inlineContentMax      envexec.Size
+	hostname              string
+	instanceID            string
+
+	execObserver   func(Response)
+	auditObserver  func(AuditRecord)
+	leakObserver   func(string, envexec.Leak)
+	cancelObserver func(bool)
+	panicObserver  func(string, any, []byte)
+
+	autoParallelism     AutoParallelism
+	parallelismObserver func(int, int, Contention, float64)
+	sampleCPU           func() (float64, bool) // host cpu utilization read by AutoParallelism
 
-	execObserver func(Response)
+	scheduler       Scheduler
+	schedulerShares map[string]int
+	priorityAging   time.Duration
+	totalMemory     Size
+
+	stderrMax            envexec.Size
+	stderrCacheTruncated bool
+
+	ioLimitStrict bool
+	coreFileMax   envexec.Size
+	enableTrace   bool
+	procInfoCPUs  uint64
+
+	shmSizeMax     envexec.Size
+	shmSizeDefault envexec.Size
+
+	limitMax   LimitMax
+	limitClamp bool
+	repeatMax  int
+
+	limitDefault   LimitDefault
+	allowUnlimited bool
+
+	snapshotMax envexec.Size
+	verdicts    map[string][]VerdictRule
+	argLimit    ArgLimit
+
+	noisyNeighbor NoisyNeighbor
+
+	policies atomic.Pointer[map[string]Policy]
+
+	collectorDir    string
+	collectorDirErr error // failed to create the directory
+
+	mountFiles    *fileMountCache
+	mountFilesErr error     // failed to create the cache
+	hostExec      *hostExec // nil if HostExecDirs is empty
+	hostExecErr   error     // failed to resolve the directories
+	mountMu       sync.Mutex
+	mountRelease  map[envexec.Environment]func()
+
+	diskWorkDir    string // per-run directories of DiskWorkDir, empty if disabled
+	diskWorkDirMax envexec.Size
+	diskWorkDirErr error // failed to create the directory
+
+	scratchDir string // per-run files of ScratchFile, empty if disabled
+	scratchMax envexec.Size
+	scratchErr error // failed to create the directory or it is in memory
+
+	results *resultCache // nil if IdempotencyKey is disabled
+	usage   *usageTracker
 
 	startOnce sync.Once
 	stopOnce  sync.Once
 	wg        sync.WaitGroup
-	workCh    chan workRequest
+	queue     *queue
 	done      chan struct{}
+
+	loopMu     sync.Mutex
+	loopAlive  map[int]*heartbeat // heartbeats of the running worker loops by index
+	loopTarget int                // loops beyond exit, loopWant within loopCap
+	loopWant   int                // changed by AutoParallelism
+	loopCap    int                // set by SetParallelismCap, 0 if not capped
+	busy       int64              // worker loops running requests
+
+	contentionTracker contentionTracker
+
+	jobMu  sync.Mutex
+	jobs   map[string]*job
+	jobSeq uint64
 }
 
 type workRequest struct {
@@ -69,11 +446,19 @@ type workRequest struct {
 	context.Context
 	started  chan<- struct{}
 	resultCh chan<- Response
+	job      *job
+
+	reservation uint64 // memory reserved while running
 }
 
 // New creates new worker
 func New(conf Config) Worker {
-	return &worker{
+	hostname, _ := os.Hostname()
+	instanceID := conf.InstanceID
+	if instanceID == "" {
+		instanceID = hostname
+	}
+	w := &worker{
 		fs:                    conf.FileStore,
 		envPool:               conf.EnvironmentPool,
 		parallelism:           conf.Parallelism,
@@ -82,119 +467,689 @@ func New(conf Config) Worker {
 		extraMemoryLimit:      conf.ExtraMemoryLimit,
 		outputLimit:           conf.OutputLimit,
 		copyOutLimit:          conf.CopyOutLimit,
+		copyOutMaxFiles:       conf.CopyOutMaxFiles,
+		copyOutMaxTotal:       conf.CopyOutMaxTotal,
+		randomizeWorkDir:      conf.RandomizeWorkDir,
 		openFileLimit:         conf.OpenFileLimit,
+		archiveMaxCount:       conf.ArchiveMaxCount,
+		archiveMaxSize:        conf.ArchiveMaxSize,
+		inlineContentMax:      conf.InlineContentMax,
+		hostname:              hostname,
+		instanceID:            instanceID,
 		execObserver:          conf.ExecObserver,
+		auditObserver:         conf.AuditObserver,
+		leakObserver:          conf.LeakObserver,
+		cancelObserver:        conf.CancelObserver,
+		panicObserver:         conf.PanicObserver,
+		autoParallelism:       conf.AutoParallelism,
+		parallelismObserver:   conf.ParallelismObserver,
+		sampleCPU:             new(cpuSampler).sample,
+		loopAlive:             make(map[int]*heartbeat),
+		scheduler:             conf.Scheduler,
+		schedulerShares:       conf.SchedulerShares,
+		priorityAging:         conf.PriorityAging,
+		totalMemory:           conf.TotalMemory,
+		stderrMax:             conf.StderrMax,
+		stderrCacheTruncated:  conf.StderrCacheTruncated,
+		ioLimitStrict:         conf.IOLimitStrict,
+		coreFileMax:           conf.CoreFileMax,
+		enableTrace:           conf.EnableTrace,
+		procInfoCPUs:          conf.ProcInfoCPUs,
+		shmSizeMax:            conf.ShmSizeMax,
+		shmSizeDefault:        conf.ShmSizeDefault,
+		limitMax:              conf.LimitMax,
+		limitClamp:            conf.LimitClamp,
+		limitDefault:          conf.LimitDefault,
+		allowUnlimited:        conf.AllowUnlimited,
+		repeatMax:             conf.RepeatMax,
+		snapshotMax:           conf.SnapshotMax,
+		noisyNeighbor:         conf.NoisyNeighbor,
+		verdicts:              conf.Verdicts,
+		argLimit:              conf.ArgLimit,
+		jobs:                  make(map[string]*job),
+		mountRelease:          make(map[envexec.Environment]func()),
+	}
+	w.SetPolicies(conf.Policies)
+	if conf.CollectorDir != "" {
+		w.collectorDir = conf.CollectorDir
+		w.collectorDirErr = os.MkdirAll(conf.CollectorDir, 0755)
+	}
+	if conf.MountFilesDir != "" {
+		w.mountFiles, w.mountFilesErr = newFileMountCache(conf.MountFilesDir, conf.MountFilesCacheMax)
+	}
+	if len(conf.HostExecDirs) > 0 {
+		w.hostExec, w.hostExecErr = newHostExec(conf.HostExecDirs)
 	}
+	if conf.DiskWorkDir != "" {
+		w.diskWorkDir, w.diskWorkDirErr = newDiskWorkDir(conf.DiskWorkDir)
+		w.diskWorkDirMax = conf.DiskWorkDirMax
+	}
+	if conf.ScratchDir != "" {
+		w.scratchDir, w.scratchErr = newScratchDir(conf.ScratchDir)
+		w.scratchMax = conf.ScratchMax
+	}
+	if conf.IdempotencyTTL > 0 {
+		w.results = newResultCache(conf.FileStore, conf.IdempotencyTTL, conf.IdempotencyCacheMax)
+	}
+	w.usage = newUsageTracker(conf.UsageFile, conf.QuotaPeriod, conf.Quotas)
+	return w
 }
 
 // Start starts worker loops with given parallelism
 func (w *worker) Start() {
 	w.startOnce.Do(func() {
-		w.workCh = make(chan workRequest, maxWaiting)
+		w.queue = newQueue(w.scheduler, w.schedulerShares, w.priorityAging, w.totalMemory.Byte())
 		w.done = make(chan struct{})
-		w.wg.Add(w.parallelism)
-		for i := 0; i < w.parallelism; i++ {
-			go w.loop()
+		w.setParallelism(w.parallelism)
+		if w.autoParallelism.Max > 0 {
+			go w.autoTune()
 		}
 	})
 }
 
 // Submit submits a single request
 func (w *worker) Submit(ctx context.Context, req *Request) (<-chan Response, <-chan struct{}) {
+	if err := w.applyLimitMax(ctx, req); err != nil {
+		ch := make(chan Response, 1)
+		started := make(chan struct{})
+		close(started)
+		ch <- Response{RequestID: req.RequestID, Error: err}
+		return ch, started
+	}
+	e, found := w.beginIdempotent(ctx, req)
+	switch {
+	case e == nil:
+		return w.submit(ctx, req)
+	case found:
+		started := make(chan struct{})
+		close(started)
+		return w.results.attach(ctx, e, req.RequestID), started
+	}
+	// the run is kept for the retries even if the submitter is gone
+	ch, started := w.submit(DetachContext(ctx), req)
+	return w.results.finish(ctx, e, ch), started
+}
+
+func (w *worker) submit(ctx context.Context, req *Request) (<-chan Response, <-chan struct{}) {
 	ch := make(chan Response, 1)
 	started := make(chan struct{})
-	select {
-	case w.workCh <- workRequest{
-		Request:  req,
-		Context:  ctx,
-		started:  started,
-		resultCh: ch,
-	}:
-	default:
+	if err := w.admit(ctx); err != nil {
 		close(started)
 		ch <- Response{
 			RequestID: req.RequestID,
-			Error:     fmt.Errorf("worker queue is full"),
+			Error:     err,
+		}
+		return ch, started
+	}
+	ctx, j := w.addJob(ctx, req)
+	if err := w.queue.push(j.owner, workRequest{
+		Request:     req,
+		Context:     ctx,
+		started:     started,
+		resultCh:    ch,
+		job:         j,
+		reservation: w.memoryReservation(req),
+	}); err != nil {
+		w.removeJob(j)
+		close(started)
+		rt := Response{
+			RequestID: req.RequestID,
+			Error:     err,
+		}
+		if errors.Is(err, ErrShutdown) {
+			rt.CancelReason = CancelShutdown
 		}
+		ch <- rt
+		return ch, started
 	}
+	// removes the request from the queue once the submitter is gone
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-started:
+			return
+		}
+		if !w.queue.remove(j) {
+			return
+		}
+		reason := j.cancelReason()
+		w.removeJob(j)
+		close(started)
+		w.observeCancel(j, false)
+		ch <- Response{
+			RequestID:    req.RequestID,
+			Error:        ErrCancelled,
+			CancelReason: reason,
+		}
+	}()
 	return ch, started
 }
 
 // Execute will execute the request in new goroutine (bypass the parallelism limit)
 func (w *worker) Execute(ctx context.Context, req *Request) <-chan Response {
+	if err := w.applyLimitMax(ctx, req); err != nil {
+		ch := make(chan Response, 1)
+		ch <- Response{RequestID: req.RequestID, Error: err}
+		return ch
+	}
+	e, found := w.beginIdempotent(ctx, req)
+	switch {
+	case e == nil:
+		return w.execute(ctx, req)
+	case found:
+		return w.results.attach(ctx, e, req.RequestID)
+	}
+	return w.results.finish(ctx, e, w.execute(DetachContext(ctx), req))
+}
+
+func (w *worker) execute(ctx context.Context, req *Request) <-chan Response {
 	ch := make(chan Response, 1)
+	if err := w.admit(ctx); err != nil {
+		ch <- Response{
+			RequestID: req.RequestID,
+			Error:     err,
+		}
+		return ch
+	}
+	ctx, j := w.addJob(ctx, req)
+	j.start(-1)
+	// not held by the reservation but counted for the queued requests
+	n := w.memoryReservation(req)
+	w.queue.reserve(n)
 	w.wg.Add(1)
 	go func() {
 		defer w.wg.Done()
-		ch <- w.workDoCmd(ctx, req)
+		defer w.removeJob(j)
+		defer w.queue.release(n)
+		rt := w.workDoCmd(ctx, req)
+		if ctx.Err() != nil {
+			j.markCancelled(&rt)
+			w.observeCancel(j, true)
+		}
+		ch <- rt
 	}()
 	return ch
 }
 
+// beginIdempotent returns the stored or in flight entry of the idempotency key
+// of the request if found, nil if the request has no key
+func (w *worker) beginIdempotent(ctx context.Context, req *Request) (*resultEntry, bool) {
+	if w.results == nil || req.IdempotencyKey == "" {
+		return nil, false
+	}
+	owner, _ := filestore.NamespaceFromContext(ctx)
+	return w.results.begin(resultKey{owner: owner, key: req.IdempotencyKey})
+}
+
+// Validate checks the request without touching the environment pool
+func (w *worker) Validate(ctx context.Context, req *Request) error {
+	if len(req.Cmd) == 0 {
+		return fmt.Errorf("no cmd provided")
+	}
+	if err := w.applyLimitMax(ctx, req); err != nil {
+		return err
+	}
+	if err := w.validIOLimit(req.Cmd); err != nil {
+		return err
+	}
+	if err := w.validCore(req.Cmd); err != nil {
+		return err
+	}
+	if err := w.validTrace(req.Cmd); err != nil {
+		return err
+	}
+	if err := validArch(req.Cmd); err != nil {
+		return err
+	}
+	if err := w.validShmSize(req.Cmd); err != nil {
+		return err
+	}
+	if err := w.validNUMANode(req); err != nil {
+		return err
+	}
+	fs := filestore.FromContext(ctx, w.fs)
+	if err := w.validEnvironment(fs, req.Cmd); err != nil {
+		return err
+	}
+	if err := validCopyOutGroup(req.Cmd); err != nil {
+		return err
+	}
+	if err := w.validExtraFiles(req.Cmd); err != nil {
+		return err
+	}
+	if err := w.validSnapshot(req); err != nil {
+		return err
+	}
+	if err := validRelativeLimits(req); err != nil {
+		return err
+	}
+
+	cs := make([]*envexec.Cmd, 0, len(req.Cmd))
+	pipeFileNames := preparePipeNames(req.PipeMapping, len(req.Cmd))
+	for i, rc := range req.Cmd {
+		c, err := w.prepareCmd(fs, rc, pipeFileNames[i])
+		if err != nil {
+			return fmt.Errorf("cmd[%d]: %w", i, err)
+		}
+		cs = append(cs, c)
+	}
+	// pipe mapping is only used when running as group
+	if len(req.Cmd) > 1 {
+		g := envexec.Group{
+			Cmd:   cs,
+			Pipes: req.PipeMapping,
+		}
+		if err := g.Validate(); err != nil {
+			return fmt.Errorf("pipeMapping: %w", err)
+		}
+	}
+	return nil
+}
+
+// Alive checks none of the worker loops is stuck. A loop waiting for the
+// requests or running one is alive however long it takes (the run is bounded
+// by its limits), otherwise it should get back to them soon.
+func (w *worker) Alive(timeout time.Duration) bool {
+	w.loopMu.Lock()
+	defer w.loopMu.Unlock()
+
+	for _, h := range w.loopAlive {
+		if h.stuck(timeout) {
+			return false
+		}
+	}
+	return true
+}
+
 // Shutdown waits all worker to finish
 func (w *worker) Shutdown() {
 	w.stopOnce.Do(func() {
+		// no worker loop is started after done
+		w.loopMu.Lock()
 		close(w.done)
+		w.loopMu.Unlock()
+		for _, req := range w.queue.close() {
+			w.removeJob(req.job)
+			close(req.started)
+			req.resultCh <- Response{
+				RequestID:    req.RequestID,
+				Error:        ErrShutdown,
+				CancelReason: CancelShutdown,
+			}
+		}
 		w.wg.Wait()
 	})
 }
 
-func (w *worker) loop() {
+func (w *worker) loop(index int, h *heartbeat) {
 	defer w.wg.Done()
 	for {
+		if w.retireLoop(index) {
+			return
+		}
+		h.beat(loopWaiting)
+		req, ok := w.queue.pop()
+		h.beat(loopBusy)
+		if !ok {
+			return
+		}
+		close(req.started)
+
 		select {
-		case req, ok := <-w.workCh:
-			if !ok {
-				return
+		case <-req.Context.Done():
+			w.observeCancel(req.job, false)
+			req.resultCh <- Response{
+				RequestID:    req.RequestID,
+				Error:        ErrCancelled,
+				CancelReason: req.job.cancelReason(),
 			}
-			close(req.started)
-
-			select {
-			case <-req.Context.Done():
-				req.resultCh <- Response{
-					RequestID: req.RequestID,
-					Error:     fmt.Errorf("cancelled before execute"),
-				}
-			default:
-				req.resultCh <- w.workDoCmd(req.Context, req.Request)
+		default:
+			atomic.AddInt64(&w.busy, 1)
+			req.job.start(index)
+			h.beat(loopRunning)
+			rt := w.workDoCmd(req.Context, req.Request)
+			h.beat(loopBusy)
+			if req.Context.Err() != nil {
+				req.job.markCancelled(&rt)
+				w.observeCancel(req.job, true)
 			}
-
-		case <-w.done:
-			return
+			_, rt.EnvWait, _ = req.job.waits(time.Now())
+			w.observeContention(req.job)
+			atomic.AddInt64(&w.busy, -1)
+			req.resultCh <- rt
 		}
+		w.queue.release(req.reservation)
+		w.removeJob(req.job)
+	}
+}
+
+// observeContention records the waits of the job finished by the worker loop
+func (w *worker) observeContention(j *job) {
+	now := time.Now()
+	queueWait, envWait, runTime := j.waits(now)
+	w.contentionTracker.observe(now, queueWait, envWait, runTime)
+}
+
+// observeCancel reports the job cancelled by the submitter
+func (w *worker) observeCancel(j *job, running bool) {
+	if w.cancelObserver != nil && j.cancelledBySubmitter() {
+		w.cancelObserver(running)
 	}
 }
 
+// QueueDepth returns the number of waiting requests of each client
+func (w *worker) QueueDepth() map[string]int {
+	return w.queue.depth()
+}
+
+// PriorityDepth returns the number of waiting requests of each priority
+func (w *worker) PriorityDepth() map[Priority]int {
+	return w.queue.priorityDepth()
+}
+
+// Usage returns the usage of each client
+func (w *worker) Usage() map[string]ClientUsage {
+	return w.usage.get(w.fs)
+}
+
+// SaveUsage writes the usage into the usage file
+func (w *worker) SaveUsage() error {
+	return w.usage.save()
+}
+
+// admit rejects the request if the quota of the submitter is exhausted
+func (w *worker) admit(ctx context.Context) error {
+	owner, _ := filestore.NamespaceFromContext(ctx)
+	return w.usage.admit(owner)
+}
+
+// requestIDKey is the context key of the request ID reported with leaks
+type requestIDKey struct{}
+
+// onLeak returns the leak handler of the run reporting the request ID of ctx
+func (w *worker) onLeak(ctx context.Context) func(envexec.Leak) {
+	if w.leakObserver == nil {
+		return nil
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return func(l envexec.Leak) {
+		w.leakObserver(id, l)
+	}
+}
+
+// workDoCmd executes the request and reports it to the audit observer
 func (w *worker) workDoCmd(ctx context.Context, req *Request) Response {
+	startedAt := time.Now()
+	rt := w.doCmdRecover(ctx, req)
+	if w.auditObserver != nil {
+		owner, _ := filestore.NamespaceFromContext(ctx)
+		w.auditObserver(AuditRecord{
+			Client:     owner,
+			Request:    req,
+			Response:   rt,
+			StartedAt:  startedAt,
+			FinishedAt: time.Now(),
+		})
+	}
+	return rt
+}
+
+// doCmdRecover executes the request and converts the panic of the run into
+// Internal Error of its commands, so that the worker loop keeps running
+func (w *worker) doCmdRecover(ctx context.Context, req *Request) (rt Response) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		stack := debug.Stack()
+		if p, ok := r.(*envexec.Panic); ok {
+			r, stack = p.Value, p.Stack
+		}
+		if w.panicObserver != nil {
+			w.panicObserver(req.RequestID, r, stack)
+		}
+		rt = Response{RequestID: req.RequestID}
+		for range req.Cmd {
+			rt.Results = append(rt.Results, Result{
+				Status: envexec.StatusInternalError,
+				Error:  fmt.Sprintf("internal error: panic: %v", r),
+			})
+		}
+	}()
+	return w.doCmd(ctx, req)
+}
+
+func (w *worker) doCmd(ctx context.Context, req *Request) Response {
+	start := time.Now()
+	ctx = context.WithValue(ctx, requestIDKey{}, req.RequestID)
+	// restrict file access to the namespace of the request if exists
+	fs := filestore.FromContext(ctx, w.fs)
+	if req.SkipVerify {
+		fs = filestore.NewNoVerify(fs)
+	}
+
 	var rt Response
-	if len(req.Cmd) == 1 {
-		rt = w.workDoSingle(ctx, req.Cmd[0])
-	} else {
-		rt = w.workDoGroup(ctx, req.Cmd, req.PipeMapping)
+	if err := w.validIOLimit(req.Cmd); err != nil {
+		rt.Error = err
+		return rt
+	}
+	if err := w.validCore(req.Cmd); err != nil {
+		rt.Error = err
+		return rt
+	}
+	if err := w.validTrace(req.Cmd); err != nil {
+		rt.Error = err
+		return rt
+	}
+	if err := validArch(req.Cmd); err != nil {
+		rt.Error = err
+		return rt
+	}
+	if err := w.validShmSize(req.Cmd); err != nil {
+		rt.Error = err
+		return rt
+	}
+	if err := w.validNUMANode(req); err != nil {
+		rt.Error = err
+		return rt
+	}
+	if err := w.validEnvironment(fs, req.Cmd); err != nil {
+		rt.Error = err
+		return rt
+	}
+	if err := validCopyOutGroup(req.Cmd); err != nil {
+		rt.Error = err
+		return rt
+	}
+	if err := w.validExtraFiles(req.Cmd); err != nil {
+		rt.Error = err
+		return rt
+	}
+	if err := w.validSnapshot(req); err != nil {
+		rt.Error = err
+		return rt
+	}
+	if err := validRelativeLimits(req); err != nil {
+		rt.Error = err
+		return rt
+	}
+	ctx = w.withNUMANode(ctx, req)
+	ctx, releaseSnapshots := w.withSnapshots(ctx, req)
+	defer releaseSnapshots()
+	parallel := false
+	switch {
+	case req.Repeat > 0:
+		rt = w.workDoRepeat(ctx, fs, req.Cmd[0], req.Repeat)
+	case len(req.Cmd) == 1:
+		rt = w.workDoSingle(ctx, fs, req.Cmd[0], 0)
+	case len(req.PipeMapping) > 0:
+		rt = w.workDoGroup(ctx, fs, req.Cmd, req.PipeMapping, req.KillOnLimit)
+		parallel = true
+	case w.sequential(req):
+		rt = w.workDoSequential(ctx, fs, req.Cmd)
+		if req.RunMode != RunModeSequential {
+			rt.Note = fmt.Sprintf("commands run sequentially since the number of commands (%d) exceeds parallelism (%d)",
+				len(req.Cmd), w.parallelism)
+		}
+	default:
+		rt = w.workDoGroup(ctx, fs, req.Cmd, req.PipeMapping, req.KillOnLimit)
+		parallel = true
 	}
 	rt.RequestID = req.RequestID
+	if req.EstimatedDuration > 0 {
+		rt.EstimatedDuration = req.EstimatedDuration
+		rt.Duration = time.Since(start)
+	}
+	if rt.Error == nil {
+		submitted := start
+		if j, ok := ctx.Value(jobKey{}).(*job); ok {
+			submitted = j.submitted
+		}
+		rt.Usage = requestUsage(&rt, parallel, submitted)
+		owner, _ := filestore.NamespaceFromContext(ctx)
+		w.usage.add(owner, rt.Usage)
+	}
 	if w.execObserver != nil {
 		w.execObserver(rt)
 	}
+	w.applyVerdict(req, &rt)
 	return rt
 }
 
-func (w *worker) workDoSingle(ctx context.Context, rc Cmd) (rt Response) {
-	c, err := w.prepareCmd(rc, make(map[string]bool))
+// workDoSingle runs rc as the index-th command of the request
+func (w *worker) workDoSingle(ctx context.Context, fs filestore.FileStore, rc Cmd, index int) (rt Response) {
+	c, err := w.prepareCmd(fs, rc, make(map[string]bool))
 	if err != nil {
-		rt.Error = err
-		return
+		return prepareErrorResponse(err, 1)
 	}
 	// prepare environment
-	env, err := w.envPool.Get()
+	env, err := w.getEnvironment(ctx, fs, rc)
+	if err != nil {
+		var envErr *EnvironmentError
+		if errors.As(err, &envErr) {
+			return Response{Error: err}
+		}
+		return Response{Results: []Result{{
+			Status: envexec.StatusInternalError,
+			Error:  fmt.Sprintf("failed to get environment %v", err),
+		}}}
+	}
+	defer w.releaseEnvironment(env)
+	rt.Results = []Result{w.runSingle(ctx, fs, env, c, rc, index)}
+	return
+}
+
+// workDoRepeat runs rc n times back-to-back in the same environment. The
+// environment is reset and the copy in files are copied again before each
+// repetition, so that every one runs with the same work dir on warm caches.
+// It stops early once cancelled or a repetition failed to run.
+func (w *worker) workDoRepeat(ctx context.Context, fs filestore.FileStore, rc Cmd, n int) (rt Response) {
+	c, err := w.prepareCmd(fs, rc, make(map[string]bool))
 	if err != nil {
+		return prepareErrorResponse(err, 1)
+	}
+	env, err := w.getEnvironment(ctx, fs, rc)
+	if err != nil {
+		var envErr *EnvironmentError
+		if errors.As(err, &envErr) {
+			return Response{Error: err}
+		}
 		return Response{Results: []Result{{
 			Status: envexec.StatusInternalError,
 			Error:  fmt.Sprintf("failed to get environment %v", err),
 		}}}
 	}
-	defer w.envPool.Put(env)
+	defer w.releaseEnvironment(env)
+
+	rt.Worker = -1
+	if j, ok := ctx.Value(jobKey{}).(*job); ok {
+		rt.Worker = j.workerIndex()
+	}
+	rt.Results = make([]Result, 0, n)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			// each repetition starts from the work dir of the first one, the
+			// files left by the previous one are removed and copied in again
+			if err := resetEnvironment(env); err != nil {
+				rt.Results = append(rt.Results, Result{
+					Status: envexec.StatusInternalError,
+					Error:  fmt.Sprintf("failed to reset environment %v", err),
+				})
+				break
+			}
+			if c, err = w.prepareCmd(fs, rc, make(map[string]bool)); err != nil {
+				rt.Results = append(rt.Results, Result{Status: envexec.StatusInternalError, Error: err.Error()})
+				break
+			}
+		}
+		res := w.runSingle(ctx, fs, env, c, rc, 0)
+		rt.Results = append(rt.Results, res)
+		if ctx.Err() != nil || res.Status == envexec.StatusInternalError || res.Status == envexec.StatusFileError {
+			break
+		}
+	}
+	return rt
+}
+
+// runSingle runs the prepared command c of rc in env as the index-th command,
+// the work dir is restored from or saved into the snapshot of the request
+func (w *worker) runSingle(ctx context.Context, fs filestore.FileStore, env envexec.Environment, c *envexec.Cmd, rc Cmd, index int) Result {
 	c.Environment = env
+	workDir := expandWorkDir(c)
+	watchJob(ctx, index, c)
+
+	snaps, _ := ctx.Value(snapshotsKey{}).(*snapshots)
+	var action, label string
+	if snaps != nil && rc.SnapshotWorkDir != "" {
+		action, label, _ = parseSnapshot(rc.SnapshotWorkDir)
+	}
+	if action == snapshotRestore {
+		if c.Snapshot = snaps.saved[label]; c.Snapshot == nil {
+			// the save of the previous command failed
+			return w.convertResult(fs, envexec.Result{
+				Status: envexec.StatusFileError,
+				Error:  fmt.Sprintf("snapshot %s was not saved", label),
+				FileError: []envexec.FileError{{
+					Name:    label,
+					Type:    envexec.ErrCopyInSnapshot,
+					Message: "snapshot was not saved",
+				}},
+			}, rc)
+		}
+	}
+
+	s := &envexec.Single{
+		Cmd:          c,
+		NewStoreFile: fs.New,
+		OnLeak:       w.onLeak(ctx),
+	}
+	result, err := s.Run(ctx)
+	if err != nil {
+		// reported as the result of the command so that the results of the
+		// other commands in the request are kept
+		result.Status = envexec.StatusInternalError
+		result.Error = err.Error()
+	}
+	res := w.convertResult(fs, result, rc)
+	if w.randomWorkDir(rc) {
+		res.WorkDir = workDir
+	}
+	if action == snapshotSave {
+		if err := snaps.save(env, label); err != nil {
+			res.Warning = joinWarning(res.Warning, fmt.Sprintf("failed to save snapshot %s: %v", label, err))
+		}
+	}
+	return res
+}
+
+func (w *worker) Warmup(ctx context.Context, env envexec.Environment, rc Cmd) Result {
+	c, err := w.prepareCmd(w.fs, rc, make(map[string]bool))
+	if err != nil {
+		return Result{Status: envexec.StatusInternalError, Error: err.Error()}
+	}
+	c.Environment = env
+	expandWorkDir(c)
 
 	s := &envexec.Single{
 		Cmd:          c,
@@ -202,105 +1157,614 @@ func (w *worker) workDoSingle(ctx context.Context, rc Cmd) (rt Response) {
 	}
 	result, err := s.Run(ctx)
 	if err != nil {
-		rt.Error = err
-		return
+		result.Status = envexec.StatusInternalError
+		result.Error = err.Error()
 	}
-	res := w.convertResult(result, rc)
-	rt.Results = []Result{res}
-	return
+	res := w.convertResult(w.fs, result, rc)
+	// the outputs are discarded
+	for _, f := range res.Files {
+		f.Close()
+	}
+	res.Files = nil
+	return res
+}
+
+// sequential returns whether the commands of the request without pipe run one
+// after another, either requested or more than the parallelism
+func (w *worker) sequential(req *Request) bool {
+	return len(req.PipeMapping) == 0 && (req.RunMode == RunModeSequential || len(req.Cmd) > w.parallelism)
 }
 
-func (w *worker) workDoGroup(ctx context.Context, rc []Cmd, pm []PipeMap) (rt Response) {
+// workDoSequential runs commands one after another, each command gets its
+// environment after the previous one finished
+func (w *worker) workDoSequential(ctx context.Context, fs filestore.FileStore, rc []Cmd) (rt Response) {
+	rt.Results = make([]Result, 0, len(rc))
+	for i, c := range rc {
+		var resolved []ResolvedLimit
+		if c.RelativeLimits.set() {
+			var skipped *Result
+			resolved, skipped = w.resolveRelativeLimits(ctx, &c, rt.Results)
+			if skipped != nil {
+				rt.Results = append(rt.Results, *skipped)
+				continue
+			}
+			// the resolved limits are echoed by the request
+			rc[i] = c
+		}
+		r := w.workDoSingle(ctx, fs, c, i)
+		if r.Error != nil {
+			if i == 0 {
+				return Response{Error: r.Error}
+			}
+			// the results of the finished commands are kept
+			r.Results = []Result{{
+				Status: envexec.StatusInternalError,
+				Error:  r.Error.Error(),
+			}}
+		}
+		if resolved != nil {
+			r.Results[0].RelativeLimits = resolved
+		}
+		rt.Results = append(rt.Results, r.Results...)
+	}
+	return rt
+}
+
+func (w *worker) workDoGroup(ctx context.Context, fs filestore.FileStore, rc []Cmd, pm []PipeMap, killOnLimit bool) (rt Response) {
+	envs, err := w.getEnvironments(ctx, fs, rc)
+	if err != nil {
+		// commands without pipe could still run one by one
+		if len(pm) == 0 {
+			rt = w.workDoSequential(ctx, fs, rc)
+			rt.Note = fmt.Sprintf("commands run sequentially since failed to get environments: %v", err)
+			return rt
+		}
+		var envErr *EnvironmentError
+		if errors.As(err, &envErr) {
+			return Response{Error: err}
+		}
+		res := make([]Result, 0, len(rc))
+		for range rc {
+			res = append(res, Result{
+				Status: envexec.StatusInternalError,
+				Error:  fmt.Sprintf("failed to get environment %v", err),
+			})
+		}
+		return Response{Results: res}
+	}
+	for _, env := range envs {
+		defer w.releaseEnvironment(env)
+	}
+
 	var rts []Result
 	cs := make([]*envexec.Cmd, 0, len(rc))
+	workDirs := make([]string, 0, len(rc))
 	pipeFileNames := preparePipeNames(pm, len(rc))
 	for i, cc := range rc {
-		c, err := w.prepareCmd(cc, pipeFileNames[i])
+		c, err := w.prepareCmd(fs, cc, pipeFileNames[i])
 		if err != nil {
-			rt.Error = err
-			return
+			return prepareErrorResponse(err, len(rc))
 		}
+		c.Environment = envs[i]
+		watchJob(ctx, i, c)
 		cs = append(cs, c)
-	}
-	for i := range cs {
-		env, err := w.envPool.Get()
-		if err != nil {
-			res := make([]Result, 0, len(cs))
-			for range cs {
-				res = append(res, Result{
-					Status: envexec.StatusInternalError,
-					Error:  fmt.Sprintf("failed to get environment %v", err),
-				})
-			}
-			return Response{Results: res}
-		}
-		defer w.envPool.Put(env)
-		cs[i].Environment = env
+		workDirs = append(workDirs, expandWorkDir(c))
 	}
 	g := envexec.Group{
 		Cmd:          cs,
 		Pipes:        pm,
-		NewStoreFile: w.fs.New,
+		NewStoreFile: fs.New,
+		KillOnLimit:  killOnLimit,
+		OnLeak:       w.onLeak(ctx),
 	}
 	results, err := g.Run(ctx)
-	if err != nil {
-		rt.Error = err
-		return
+	if err != nil && results == nil {
+		// failed before any command started
+		results = make([]envexec.Result, len(rc))
+		for i := range results {
+			results[i] = envexec.Result{Status: envexec.StatusInternalError, Error: err.Error()}
+		}
 	}
 	rts = make([]Result, 0, len(results))
 	for i, result := range results {
-		res := w.convertResult(result, rc[i])
+		res := w.convertResult(fs, result, rc[i])
+		if w.randomWorkDir(rc[i]) {
+			res.WorkDir = workDirs[i]
+		}
 		rts = append(rts, res)
 	}
 	rt.Results = rts
 	return
 }
 
-func (w *worker) convertResult(result envexec.Result, cmd Cmd) (res Result) {
+// prepareErrorResponse reports file store failures as file error of each
+// command so that they are not mistaken as invalid requests
+func prepareErrorResponse(err error, n int) Response {
+	if !errors.Is(err, filestore.ErrUnavailable) {
+		return Response{Error: err}
+	}
+	res := make([]Result, 0, n)
+	for i := 0; i < n; i++ {
+		res = append(res, Result{
+			Status: envexec.StatusFileError,
+			Error:  err.Error(),
+		})
+	}
+	return Response{Results: res}
+}
+
+// validIOLimit rejects commands with io limit if the io is not accounted and
+// the io limit is strict
+func (w *worker) validIOLimit(rc []Cmd) error {
+	if !w.ioLimitStrict || w.ioAccounting() {
+		return nil
+	}
+	for i, c := range rc {
+		if c.IOLimit > 0 {
+			return fmt.Errorf("cmd[%d]: %w: io accounting is not available", i, ErrIOLimitNotSupported)
+		}
+	}
+	return nil
+}
+
+// validCore rejects commands collecting core file if it is disabled
+func (w *worker) validCore(rc []Cmd) error {
+	if w.coreFileMax > 0 {
+		return nil
+	}
+	for i, c := range rc {
+		if c.CollectCore {
+			return fmt.Errorf("cmd[%d]: %w", i, ErrCollectCoreDisabled)
+		}
+	}
+	return nil
+}
+
+// validTrace rejects traced commands if trace is disabled, or with the cpu
+// rate / set limits which are meant for reproducible timing that the traced
+// commands could not provide
+func (w *worker) validTrace(rc []Cmd) error {
+	for i, c := range rc {
+		switch {
+		case !c.Trace:
+		case !w.enableTrace:
+			return fmt.Errorf("cmd[%d]: %w: trace is not enabled", i, ErrInvalidTrace)
+		case c.CPURateLimit > 0 || c.CPUSetLimit != "":
+			return fmt.Errorf("cmd[%d]: %w: trace could not be used with cpuRateLimit or cpuSetLimit", i, ErrInvalidTrace)
+		}
+	}
+	return nil
+}
+
+// validArch checks the architecture names of the commands
+func validArch(rc []Cmd) error {
+	for i, c := range rc {
+		if c.Arch != "" && c.Arch != envexec.ArchAuto && !envexec.KnownArch(c.Arch) {
+			return fmt.Errorf("cmd[%d]: %w: %q", i, ErrInvalidArch, c.Arch)
+		}
+	}
+	return nil
+}
+
+// validShmSize checks the shm size of the commands is within the mounted one
+func (w *worker) validShmSize(rc []Cmd) error {
+	for i, c := range rc {
+		switch {
+		case c.ShmSize == 0:
+		case w.shmSizeMax == 0:
+			return fmt.Errorf("cmd[%d]: %w: /dev/shm is not mounted", i, ErrInvalidShmSize)
+		case envexec.Size(c.ShmSize) > w.shmSizeMax:
+			return fmt.Errorf("cmd[%d]: %w: %d exceeds the max %d", i, ErrInvalidShmSize, c.ShmSize, w.shmSizeMax)
+		}
+	}
+	return nil
+}
+
+// validNUMANode checks the pinned NUMA node of the request
+func (w *worker) validNUMANode(req *Request) error {
+	if req.NUMANode == nil {
+		return nil
+	}
+	p, ok := w.envPool.(NUMAEnvironmentPool)
+	if !ok {
+		return fmt.Errorf("%w: environments are not sharded by numa node", ErrInvalidNUMANode)
+	}
+	nodes := p.NUMANodes()
+	for _, n := range nodes {
+		if n == *req.NUMANode {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %d is not one of %v", ErrInvalidNUMANode, *req.NUMANode, nodes)
+}
+
+// envPoolKey is the context key of the pool of the NUMA node chosen for the
+// request
+type envPoolKey struct{}
+
+// withNUMANode chooses the NUMA node of the request, the pinned one or the
+// least loaded one, so that all of its commands run within the node
+func (w *worker) withNUMANode(ctx context.Context, req *Request) context.Context {
+	p, ok := w.envPool.(NUMAEnvironmentPool)
+	if !ok {
+		return ctx
+	}
+	node := -1
+	if req.NUMANode != nil {
+		node = *req.NUMANode
+	}
+	return context.WithValue(ctx, envPoolKey{}, p.NUMANode(node))
+}
+
+// requestEnvPool returns the pool of the NUMA node chosen for the request
+func (w *worker) requestEnvPool(ctx context.Context) EnvironmentPool {
+	if p, ok := ctx.Value(envPoolKey{}).(EnvironmentPool); ok {
+		return p
+	}
+	return w.envPool
+}
+
+func (w *worker) ioAccounting() bool {
+	p, ok := w.envPool.(IOEnvironmentPool)
+	return ok && p.IOAccounting()
+}
+
+// getEnvironment gets environment for the command from the pool of the NUMA
+// node of the request, with its pinned credential, datasets, mount profile,
+// mount files, host files, deterministic random and proc info if exists
+// getEnvironment gets the environment for the command and records the time
+// waited for it to the job in ctx
+func (w *worker) getEnvironment(ctx context.Context, fs filestore.FileStore, rc Cmd) (envexec.Environment, error) {
+	if j, ok := ctx.Value(jobKey{}).(*job); ok {
+		start := time.Now()
+		defer func() { j.addEnvWait(time.Since(start)) }()
+	}
+	return w.getPoolEnvironment(ctx, fs, rc)
+}
+
+// putEnvironment puts the environment back to the pool and releases the files
+// mounted for it if any
+func (w *worker) putEnvironment(env envexec.Environment) {
+	w.envPool.Put(env)
+
+	w.mountMu.Lock()
+	release, ok := w.mountRelease[env]
+	delete(w.mountRelease, env)
+	w.mountMu.Unlock()
+	if ok {
+		release()
+	}
+}
+
+// resetEnvironment cleans up the environment in use for the next run as the
+// pool does before reusing it
+func resetEnvironment(env envexec.Environment) error {
+	e, ok := env.(interface{ Reset() error })
+	if !ok {
+		return fmt.Errorf("environment could not be reset")
+	}
+	return e.Reset()
+}
+
+// releaseEnvironment is deferred to put back the environment after the run,
+// the environment is destroyed instead if the run is panicking since it could
+// be left in any state
+func (w *worker) releaseEnvironment(env envexec.Environment) {
+	if r := recover(); r != nil {
+		w.discardEnvironment(env)
+		panic(r)
+	}
+	w.putEnvironment(env)
+}
+
+// discardEnvironment destroys the environment if supported by the pool,
+// otherwise puts it back, and releases the files mounted for it if any
+func (w *worker) discardEnvironment(env envexec.Environment) {
+	p, ok := w.envPool.(DiscardEnvironmentPool)
+	if !ok {
+		w.putEnvironment(env)
+		return
+	}
+	p.Discard(env)
+
+	w.mountMu.Lock()
+	release, ok := w.mountRelease[env]
+	delete(w.mountRelease, env)
+	w.mountMu.Unlock()
+	if ok {
+		release()
+	}
+}
+
+func (w *worker) randomWorkDir(rc Cmd) bool {
+	return w.randomizeWorkDir || rc.RandomizeWorkDir
+}
+
+// expandWorkDir substitutes workDirVar in args and env with the work directory
+// of the environment and returns the work directory
+func expandWorkDir(c *envexec.Cmd) string {
+	workDir := c.Environment.WorkDir().Name()
+	c.Args = replaceAll(c.Args, workDirVar, workDir)
+	c.Env = replaceAll(c.Env, workDirVar, workDir)
+	return workDir
+}
+
+func replaceAll(s []string, old, new string) []string {
+	rt := make([]string, 0, len(s))
+	for _, v := range s {
+		rt = append(rt, strings.ReplaceAll(v, old, new))
+	}
+	return rt
+}
+
+// getEnvironments gets environments for commands from pool, all of them are
+// put back if any of them failed
+func (w *worker) getEnvironments(ctx context.Context, fs filestore.FileStore, rc []Cmd) ([]envexec.Environment, error) {
+	envs := make([]envexec.Environment, 0, len(rc))
+	for _, c := range rc {
+		env, err := w.getEnvironment(ctx, fs, c)
+		if err != nil {
+			for _, e := range envs {
+				w.putEnvironment(e)
+			}
+			return nil, err
+		}
+		envs = append(envs, env)
+	}
+	return envs, nil
+}
+
+func (w *worker) convertResult(fs filestore.FileStore, result envexec.Result, cmd Cmd) (res Result) {
 	res.Status = result.Status
 	res.ExitStatus = result.ExitStatus
+	res.Signal = result.Signal
+	res.TerminatedBy = result.TerminatedBy
 	res.Error = result.Error
 	res.Time = result.Time
 	res.RunTime = result.RunTime
 	res.Memory = result.Memory
+	if cmd.DetailedMemory {
+		res.MemoryStat = result.MemoryStat
+	}
+	res.ProcPeak = result.ProcPeak
+	res.StrayProcs = result.StrayProcs
+	res.Arch = result.Arch
+	res.Emulated = result.Emulated
+	res.RandomSeed = cmd.RandomSeed
+	res.RandomOpened = result.RandomOpened
+	res.ShmUsage = result.ShmUsage
+	res.DiskUsage = result.DiskUsage
+	res.WorkDirUsage = result.WorkDirUsage
+	res.WorkDirSize = result.WorkDirSize
+	res.InitLog = result.InitLog
+	res.OverheadCPUTime = result.OverheadTime
+	res.IOStat = result.IOStat
+	res.Throttling = result.Throttling
+	res.EnvironmentID = result.EnvironmentID
+	res.CgroupID = result.CgroupID
 	res.FileError = result.FileError
+	res.Extracted = result.Extracted
+	res.CopyInBytes = result.CopyInBytes
+	res.PipeBytes = result.PipeBytes
+	res.OutputStat = result.OutputStat
+	res.CopyOutTime = result.CopyOutTime
+	res.StartedAt = result.StartedAt
+	res.AttachRetries = result.AttachRetries
+	res.Hostname = w.hostname
+	res.InstanceID = w.instanceID
 	res.Files = make(map[string]*os.File)
 	res.FileIDs = make(map[string]string)
+	if cmd.IOLimit > 0 && result.IOStat == nil {
+		res.Warning = "ioLimit is ignored since io accounting is not available"
+	}
+	if result.CoreError != "" {
+		res.Warning = joinWarning(res.Warning, "core file collection unavailable: "+result.CoreError)
+	}
+	if result.Core != nil {
+		convertCore(fs, &res, result.Core)
+	}
+	if noisy, warning := w.noisyNeighbor.check(result); noisy != nil {
+		res.NoisyNeighbor = noisy
+		res.Warning = joinWarning(res.Warning, warning)
+	}
+	if cmd.Trace {
+		res.SyscallCount = result.SyscallCount
+		res.Warning = joinWarning(res.Warning, "syscall trace: time and memory are not accurate since the program is slowed down by the tracer")
+	}
 
-	// Fix TLE due to context cancel
+	// Fix TLE due to context cancel, the clock limit is at least the cpu
+	// limit as the waiter does
+	clockLimit := cmd.ClockLimit
+	if clockLimit < cmd.CPULimit {
+		clockLimit = cmd.CPULimit
+	}
 	if res.Status == envexec.StatusTimeLimitExceeded && res.ExitStatus != 0 &&
-		res.Time < cmd.CPULimit && res.RunTime < cmd.ClockLimit {
+		res.Time < cmd.CPULimit && res.RunTime < clockLimit {
 		res.Status = envexec.StatusSignalled
+		res.TerminatedBy = envexec.TerminatedByCancelled
+	}
+
+	// evict corrupted cached files so that they could be populated again
+	for _, e := range result.FileError {
+		if e.Type != envexec.ErrCopyInCorrupted {
+			continue
+		}
+		if f, ok := cmd.CopyIn[e.Name].(*CachedFile); ok {
+			fs.Remove(f.FileID)
+		}
 	}
 
 	copyOutCachedSet := make(map[string]bool, len(cmd.CopyOutCached))
 	for _, f := range cmd.CopyOutCached {
 		copyOutCachedSet[f.Name] = true
 	}
+	for _, f := range append(cmd.CopyOut[:len(cmd.CopyOut):len(cmd.CopyOut)], cmd.CopyOutCached...) {
+		if s, ok := result.FileStat[f.Name]; ok && f.StatOnly {
+			if res.FileStat == nil {
+				res.FileStat = make(map[string]envexec.FileStat)
+			}
+			res.FileStat[f.Name] = s
+		}
+	}
+	cacheCollector := make(map[string]*Collector)
+	collected := make(map[string]bool)
+	for i, f := range cmd.fdFiles() {
+		if c, ok := f.(*Collector); ok {
+			collected[c.Name] = true
+			if c = w.collector(i, c); c.Cache || *c.CacheTruncated {
+				cacheCollector[c.Name] = c
+			}
+		}
+	}
 
+	if len(cmd.CopyOutCachedGroup) > 0 && !groupFailed(cmd.CopyOutCachedGroup, result.FileError) {
+		names := groupFileNames(cmd.CopyOutCachedGroup, result.Files, collected)
+		id, size, err := addCachedGroup(fs, names, result.Files, result.FileStat)
+		if err != nil {
+			res.Status = envexec.StatusFileError
+			res.Error = err.Error()
+			return
+		}
+		res.GroupID = id
+		res.CachedBytes += envexec.Size(size)
+		// the files only in the group are not returned
+		copyOutSet := make(map[string]bool, len(cmd.CopyOut)+len(cmd.CopyOutCached))
+		for _, f := range append(cmd.CopyOut[:len(cmd.CopyOut):len(cmd.CopyOut)], cmd.CopyOutCached...) {
+			copyOutSet[f.Name] = true
+		}
+		for _, name := range names {
+			if !copyOutSet[name] {
+				f := result.Files[name]
+				f.Close()
+				os.Remove(f.Name())
+				delete(result.Files, name)
+			}
+		}
+	}
+
+	if len(result.Files) > 0 {
+		res.FileType = make(map[string]FileType, len(result.Files))
+	}
 	for name, b := range result.Files {
+		res.FileType[name] = detectFileType(b)
+		if c, ok := cacheCollector[name]; ok {
+			convertCacheCollected(fs, &res, name, b, c.Max, !c.Cache)
+			continue
+		}
 		if !copyOutCachedSet[name] {
 			res.Files[name] = b
 			continue
 		}
-		id, err := w.fs.Add(name, b.Name())
+		id, size, err := addCachedFile(fs, name, b)
 		if err != nil {
 			res.Status = envexec.StatusFileError
 			res.Error = err.Error()
 			return
 		}
 		res.FileIDs[name] = id
+		res.CachedBytes += envexec.Size(size)
 		b.Close()
 	}
 	return res
 }
 
-func (w *worker) prepareCmd(rc Cmd, pipeFileName map[string]bool) (*envexec.Cmd, error) {
-	files, err := w.prepareCmdFiles(rc.Files, pipeFileName)
+// convertCore adds the collected core file into the file store
+func convertCore(fs filestore.FileStore, res *Result, f *os.File) {
+	defer f.Close()
+	fi, err := f.Stat()
+	if err == nil {
+		res.CoreSize = fi.Size()
+		res.CoreFileID, _, err = addCachedFile(fs, "core", f)
+	}
+	if err == nil {
+		res.CachedBytes += envexec.Size(res.CoreSize)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		res.Warning = joinWarning(res.Warning, "failed to cache core file: "+err.Error())
+	}
+}
+
+func joinWarning(w, s string) string {
+	if w == "" {
+		return s
+	}
+	return w + "; " + s
+}
+
+// convertCacheCollected adds the full collected output into the file store and
+// keeps the first max bytes inline, only if it exceeds max when truncated is
+// set. It degrades to inline only with warning if the file store failed.
+func convertCacheCollected(fs filestore.FileStore, res *Result, name string, f *os.File, max envexec.Size, truncated bool) {
+	if fi, err := f.Stat(); err == nil {
+		if truncated && fi.Size() <= int64(max) {
+			res.Files[name] = f
+			return
+		}
+		if res.FileSize == nil {
+			res.FileSize = make(map[string]int64)
+		}
+		res.FileSize[name] = fi.Size()
+	}
+	inline, err := fs.New()
+	if err == nil {
+		_, err = f.Seek(0, 0)
+	}
+	if err == nil {
+		_, err = io.CopyN(inline, f, int64(max))
+		if errors.Is(err, io.EOF) {
+			err = nil
+		}
+	}
+	if err != nil {
+		if inline != nil {
+			inline.Close()
+			os.Remove(inline.Name())
+		}
+		f.Truncate(int64(max))
+		res.Files[name] = f
+		res.Warning = fmt.Sprintf("%s: failed to cache collected output: %v", name, err)
+		return
+	}
+	res.Files[name] = inline
+
+	id, size, err := addCachedFile(fs, name, f)
+	f.Close()
+	if err != nil {
+		os.Remove(f.Name())
+		res.Warning = fmt.Sprintf("%s: failed to cache collected output: %v", name, err)
+		return
+	}
+	res.FileIDs[name] = id
+	res.CachedBytes += envexec.Size(size)
+}
+
+// addCachedFile adds the file into the file store with its digest recorded
+// so that corruption could be detected when it is copied in, and returns its
+// id and size
+func addCachedFile(fs filestore.FileStore, name string, f *os.File) (string, int64, error) {
+	meta := filestore.FileMeta{Name: name}
+	var size int64
+	if fi, err := f.Stat(); err == nil {
+		size = fi.Size()
+	}
+	if _, err := f.Seek(0, 0); err == nil {
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err == nil {
+			meta.SHA256 = hex.EncodeToString(h.Sum(nil))
+		}
+	}
+	id, err := fs.AddMeta(meta, f.Name())
+	return id, size, err
+}
+
+func (w *worker) prepareCmd(fs filestore.FileStore, rc Cmd, pipeFileName map[string]bool) (*envexec.Cmd, error) {
+	files, err := w.prepareCmdFiles(fs, rc.fdFiles(), pipeFileName)
 	if err != nil {
 		return nil, err
 	}
-	copyIn, err := w.prepareCopyIn(rc.CopyIn)
+	w.setCollectorBuffers(files, rc.CopyOutCached)
+	copyIn, err := w.prepareCopyIn(fs, rc.CopyIn)
 	if err != nil {
 		return nil, err
 	}
@@ -313,14 +1777,18 @@ func (w *worker) prepareCmd(rc Cmd, pipeFileName map[string]bool) (*envexec.Cmd,
 	}
 	for _, fn := range rc.CopyOutCached {
 		if !pipeFileName[fn.Name] {
+			// the content is needed to be cached, only the inline content is omitted
+			fn.StatOnly = false
 			copyOut = append(copyOut, fn)
 		}
 	}
+	copyOut = append(copyOut, groupCopyOut(rc.CopyOutCachedGroup)...)
 
 	wait := &waiter{
 		tickInterval:   w.timeLimitTickInterval,
 		timeLimit:      rc.CPULimit,
 		clockTimeLimit: rc.ClockLimit,
+		ioLimit:        rc.IOLimit,
 	}
 
 	var copyOutDir string
@@ -337,6 +1805,15 @@ func (w *worker) prepareCmd(rc Cmd, pipeFileName map[string]bool) (*envexec.Cmd,
 	if rc.CopyOutMax > 0 {
 		copyOutMax = envexec.Size(rc.CopyOutMax)
 	}
+	// request could only lower the server limits
+	copyOutMaxFiles := w.copyOutMaxFiles
+	if rc.CopyOutMaxFiles > 0 && (copyOutMaxFiles == 0 || rc.CopyOutMaxFiles < copyOutMaxFiles) {
+		copyOutMaxFiles = rc.CopyOutMaxFiles
+	}
+	copyOutMaxTotal := w.copyOutMaxTotal
+	if rc.CopyOutMaxTotal > 0 && (copyOutMaxTotal == 0 || envexec.Size(rc.CopyOutMaxTotal) < copyOutMaxTotal) {
+		copyOutMaxTotal = envexec.Size(rc.CopyOutMaxTotal)
+	}
 
 	outputLimit := rc.OutputLimit
 	if outputLimit == 0 {
@@ -348,8 +1825,45 @@ func (w *worker) prepareCmd(rc Cmd, pipeFileName map[string]bool) (*envexec.Cmd,
 		openFileLimit = w.openFileLimit
 	}
 
+	var coreLimit envexec.Size
+	if rc.CollectCore {
+		coreLimit = w.coreFileMax
+	}
+
+	var shmLimit envexec.Size
+	if w.shmSizeMax > 0 {
+		shmLimit = envexec.Size(rc.ShmSize)
+		if shmLimit == 0 {
+			shmLimit = w.shmSizeDefault
+		}
+		if shmLimit > w.shmSizeMax {
+			shmLimit = w.shmSizeMax
+		}
+	}
+
+	var diskLimit envexec.Size
+	switch {
+	case rc.DiskWorkDir:
+		diskLimit = w.diskWorkDirMax
+	case rc.ScratchFile != "":
+		diskLimit = w.scratchSize(rc)
+	}
+
+	procLimit := rc.ProcLimit
+	if procLimit == UnlimitedProc {
+		procLimit = unlimitedPids
+	}
+
+	var procInfo *envexec.ProcInfo
+	if rc.ProcInfo {
+		procInfo = &envexec.ProcInfo{Memory: envexec.Size(rc.MemoryLimit), CPUs: rc.ProcInfoCPUs}
+		if procInfo.CPUs == 0 {
+			procInfo.CPUs = w.procInfoCPUs
+		}
+	}
+
 	return &envexec.Cmd{
-		Args:              rc.Args,
+		Args:              w.hostExecArgs(rc.Args),
 		Env:               rc.Env,
 		Files:             files,
 		TTY:               rc.TTY,
@@ -358,27 +1872,44 @@ func (w *worker) prepareCmd(rc Cmd, pipeFileName map[string]bool) (*envexec.Cmd,
 		StackLimit:        envexec.Size(rc.StackLimit),
 		ExtraMemoryLimit:  w.extraMemoryLimit,
 		OutputLimit:       outputLimit,
-		ProcLimit:         rc.ProcLimit,
+		ProcLimit:         procLimit,
 		OpenFileLimit:     openFileLimit,
 		CPURateLimit:      rc.CPURateLimit,
 		CPUSetLimit:       rc.CPUSetLimit,
 		StrictMemoryLimit: rc.StrictMemoryLimit,
+		DetailedMemory:    rc.DetailedMemory || w.noisyNeighbor.MajorFaults > 0,
+		IOLimit:           rc.IOLimit,
+		CoreLimit:         coreLimit,
+		Trace:             rc.Trace,
+		ReportTiming:      rc.ReportTiming,
+		ProcInfo:          procInfo,
+		Random:            cmdRandom(rc),
+		Arch:              rc.Arch,
+		ShmLimit:          shmLimit,
+		DiskLimit:         diskLimit,
 		CopyIn:            copyIn,
+		ArchiveMaxCount:   w.archiveMaxCount,
+		ArchiveMaxSize:    w.archiveMaxSize,
 		SymLinks:          rc.Symlinks,
 		CopyOut:           copyOut,
 		CopyOutDir:        copyOutDir,
 		CopyOutMax:        copyOutMax,
+		CopyOutMaxFiles:   copyOutMaxFiles,
+		CopyOutMaxTotal:   copyOutMaxTotal,
 		Waiter:            wait.Wait,
 	}, nil
 }
 
-func (w *worker) prepareCopyIn(cf map[string]CmdFile) (map[string]envexec.File, error) {
+func (w *worker) prepareCopyIn(fs filestore.FileStore, cf map[string]CmdFile) (map[string]envexec.File, error) {
 	rt := make(map[string]envexec.File)
 	for name, f := range cf {
 		if f == nil {
 			return nil, fmt.Errorf("nil type cannot be used for copyIn %s", name)
 		}
-		pcf, err := f.EnvFile(w.fs)
+		if err := w.checkInlineContent("copyIn "+name, f); err != nil {
+			return nil, err
+		}
+		pcf, err := f.EnvFile(fs)
 		if err != nil {
 			return nil, err
 		}
@@ -387,14 +1918,37 @@ func (w *worker) prepareCopyIn(cf map[string]CmdFile) (map[string]envexec.File,
 	return rt, nil
 }
 
-func (w *worker) prepareCmdFiles(files []CmdFile, pipeFileName map[string]bool) ([]envexec.File, error) {
+// checkInlineContent rejects inline content larger than inlineContentMax
+func (w *worker) checkInlineContent(name string, f CmdFile) error {
+	if w.inlineContentMax == 0 {
+		return nil
+	}
+	switch f := f.(type) {
+	case *MemoryFile:
+		if n := envexec.Size(len(f.Content)); n > w.inlineContentMax {
+			return fmt.Errorf("%s: %w: %v exceeds the limit %v, upload it to the file store and reference it by fileId instead",
+				name, ErrInlineContentTooLarge, n, w.inlineContentMax)
+		}
+	case *ArchiveFile:
+		return w.checkInlineContent(name, f.File)
+	}
+	return nil
+}
+
+func (w *worker) prepareCmdFiles(fs filestore.FileStore, files []CmdFile, pipeFileName map[string]bool) ([]envexec.File, error) {
 	rt := make([]envexec.File, 0, len(files))
-	for _, f := range files {
+	for i, f := range files {
 		if f == nil {
 			rt = append(rt, nil)
 			continue
 		}
-		cf, err := f.EnvFile(w.fs)
+		if err := w.checkInlineContent(fmt.Sprintf("files[%d]", i), f); err != nil {
+			return nil, err
+		}
+		if c, ok := f.(*Collector); ok {
+			f = w.collector(i, c)
+		}
+		cf, err := f.EnvFile(fs)
 		if err != nil {
 			return nil, err
 		}
@@ -406,6 +1960,21 @@ func (w *worker) prepareCmdFiles(files []CmdFile, pipeFileName map[string]bool)
 	return rt, nil
 }
 
+// collector returns the collector at files[i] with the server defaults of
+// stderr applied
+func (w *worker) collector(i int, c *Collector) *Collector {
+	rt := *c
+	stderr := i == 2 && !c.Discard
+	if stderr && rt.Max == 0 {
+		rt.Max = w.stderrMax
+	}
+	if rt.CacheTruncated == nil {
+		cacheTruncated := stderr && w.stderrCacheTruncated && !c.Cache
+		rt.CacheTruncated = &cacheTruncated
+	}
+	return &rt
+}
+
 func preparePipeNames(pm []PipeMap, l int) []map[string]bool {
 	rt := make([]map[string]bool, l)
 	for i := range rt {
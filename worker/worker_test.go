@@ -0,0 +1,177 @@
+package worker_test
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/criyle/go-judge/env/fake"
+	"github.com/criyle/go-judge/env/pool"
+	"github.com/criyle/go-judge/envexec"
+	"github.com/criyle/go-judge/filestore"
+	"github.com/criyle/go-judge/worker"
+)
+
+// newTestWorker starts the worker running the programs in the fake sandbox
+func newTestWorker(t testing.TB, conf func(*worker.Config)) worker.Worker {
+	t.Helper()
+	c := worker.Config{
+		FileStore:       filestore.NewFileLocalStore(t.TempDir()),
+		EnvironmentPool: pool.NewPool(fake.NewBuilder(fake.Config{Dir: t.TempDir()})),
+		Parallelism:     1,
+		WorkDir:         "/w",
+	}
+	if conf != nil {
+		conf(&c)
+	}
+	w := worker.New(c)
+	w.Start()
+	t.Cleanup(w.Shutdown)
+	return w
+}
+
+// shCmd runs the script by /bin/sh with stdout collected
+func shCmd(script string) worker.Cmd {
+	return worker.Cmd{
+		Args: []string{"/bin/sh", "-c", script},
+		Env:  []string{"PATH=/usr/bin:/bin"},
+		Files: []worker.CmdFile{
+			&worker.MemoryFile{Content: []byte{}},
+			&worker.Collector{Name: "stdout", Max: 1 << 10},
+			&worker.Collector{Name: "stderr", Max: 1 << 10},
+		},
+		CPULimit:    time.Second,
+		ClockLimit:  5 * time.Second,
+		MemoryLimit: 64 << 20,
+		ProcLimit:   16,
+		CopyOut:     []worker.CmdCopyOutFile{{Name: "stdout"}, {Name: "stderr"}},
+	}
+}
+
+// run submits the request and waits for its response
+func run(t testing.TB, w worker.Worker, req *worker.Request) worker.Response {
+	t.Helper()
+	ch, _ := w.Submit(context.Background(), req)
+	rt := <-ch
+	if rt.Error != nil {
+		t.Fatal(rt.Error)
+	}
+	t.Cleanup(func() {
+		for _, r := range rt.Results {
+			for _, f := range r.Files {
+				f.Close()
+			}
+		}
+	})
+	return rt
+}
+
+func TestRepeatRestoresWorkDir(t *testing.T) {
+	w := newTestWorker(t, func(c *worker.Config) { c.RepeatMax = 3 })
+	c := shCmd("test ! -e out && echo x >> in && cat in > out && cat out")
+	c.CopyIn = map[string]worker.CmdFile{"in": &worker.MemoryFile{Content: []byte("in\n")}}
+
+	rt := run(t, w, &worker.Request{Cmd: []worker.Cmd{c}, Repeat: 3})
+	if len(rt.Results) != 3 {
+		t.Fatalf("got %d results, want 3", len(rt.Results))
+	}
+	for i, r := range rt.Results {
+		if r.Status != envexec.StatusAccepted {
+			t.Fatalf("repetition %d: %v: %s %s", i, r.Status, r.Error, readFile(t, r, "stderr"))
+		}
+		if out := readFile(t, r, "stdout"); out != "in\nx\n" {
+			t.Fatalf("repetition %d: stdout %q, want %q", i, out, "in\nx\n")
+		}
+	}
+}
+
+// waitAll waits for the responses and closes their files
+func waitAll(t *testing.T, chs ...<-chan worker.Response) {
+	t.Helper()
+	for _, ch := range chs {
+		rt := <-ch
+		if rt.Error != nil {
+			t.Fatal(rt.Error)
+		}
+		for _, r := range rt.Results {
+			for _, f := range r.Files {
+				f.Close()
+			}
+		}
+	}
+}
+
+// readFile reads the file copied out by the result
+func readFile(t *testing.T, r worker.Result, name string) string {
+	t.Helper()
+	f, ok := r.Files[name]
+	if !ok {
+		t.Fatalf("%s is not copied out", name)
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// TestSequentialKeepsResults covers the command in the middle failed to
+// start, the results of the ones before and after it are kept
+func TestSequentialKeepsResults(t *testing.T) {
+	w := newTestWorker(t, nil)
+	for _, c := range []struct {
+		name string
+		cmd  func(*worker.Cmd)
+	}{
+		{"missing program", func(c *worker.Cmd) { c.Args = []string{"/nonexistent"} }},
+		{"missing stdin", func(c *worker.Cmd) { c.Files[0] = &worker.LocalFile{Src: "/nonexistent"} }},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			failed := shCmd("true")
+			c.cmd(&failed)
+			rt := run(t, w, &worker.Request{
+				Cmd:     []worker.Cmd{shCmd("echo 1"), failed, shCmd("echo 3")},
+				RunMode: worker.RunModeSequential,
+			})
+			if len(rt.Results) != 3 {
+				t.Fatalf("got %d results, want 3", len(rt.Results))
+			}
+			if r := rt.Results[1]; r.Status == envexec.StatusAccepted || !strings.Contains(r.Error, syscall.ENOENT.Error()) {
+				t.Fatalf("cmd[1]: %v: %s, want failed by %v", r.Status, r.Error, syscall.ENOENT)
+			}
+			for _, i := range []int{0, 2} {
+				r := rt.Results[i]
+				if r.Status != envexec.StatusAccepted {
+					t.Fatalf("cmd[%d]: %v: %s", i, r.Status, r.Error)
+				}
+				if out, want := readFile(t, r, "stdout"), strconv.Itoa(i+1)+"\n"; out != want {
+					t.Fatalf("cmd[%d]: stdout %q, want %q", i, out, want)
+				}
+			}
+		})
+	}
+}
+
+func TestCopyOutFileType(t *testing.T) {
+	w := newTestWorker(t, nil)
+	rt := run(t, w, &worker.Request{Cmd: []worker.Cmd{shCmd(`printf '\211PNG\r\n\032\n'; printf 'héllo' >&2`)}})
+	r := rt.Results[0]
+	if r.Status != envexec.StatusAccepted {
+		t.Fatalf("%v: %s", r.Status, r.Error)
+	}
+	for name, want := range map[string]worker.FileType{
+		"stdout": {ContentType: "image/png", Binary: true},
+		"stderr": {ContentType: "text/plain; charset=utf-8"},
+	} {
+		if got := r.FileType[name]; got != want {
+			t.Errorf("%s: got %+v, want %+v", name, got, want)
+		}
+	}
+}